@@ -0,0 +1,26 @@
+// Build with:
+// gcc -O2 -D_FORTIFY_SOURCE=2 -fstack-protector-strong -fcf-protection=full -fPIE -pie -Wl,-z,relro,-z,now -Wl,-z,noexecstack -Wl,-z,ibt,-z,shstk -o gcc-amd64-linux-hardened hardened.c
+#include <stdio.h>
+#include <string.h>
+#include <unistd.h>
+
+static void copy(char *dst, const char *src)
+{
+	// inlined into main, strcpy becomes __strcpy_chk
+	strcpy(dst, src);
+}
+
+int main(int argc, char *argv[])
+{
+	char buf[64];
+	char name[32];
+
+	// read has no check when the size is known to fit
+	if (read(0, buf, sizeof(buf) - 1) < 0)
+		return 1;
+	buf[63] = 0;
+	memcpy(name, argc > 1 ? argv[1] : "world", 6);
+	copy(buf, name);
+	printf("hello, %s\n", buf);
+	return 0;
+}
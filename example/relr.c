@@ -0,0 +1,20 @@
+// Build with:
+// gcc -fPIE -pie -Wl,-z,pack-relative-relocs -o gcc-amd64-linux-relr relr.c
+// gcc -m32 -fPIC -shared -nostdlib -Wl,-z,pack-relative-relocs -o gcc-386-linux-relr relr.c
+static int a[64];
+static int b;
+
+// 连续的指针压缩成一个地址加若干位图项，后面单独的一个需要新的地址项
+int *table[] = {
+	&a[0], &a[1], &a[2], &a[3], &a[4], &a[5], &a[6], &a[7],
+	&a[8], &a[9], &a[10], &a[11], &a[12], &a[13], &a[14], &a[15],
+	&a[16], &a[17], &a[18], &a[19], &a[20], &a[21], &a[22], &a[23],
+	&a[24], &a[25], &a[26], &a[27], &a[28], &a[29], &a[30], &a[31],
+	&a[32], &a[33], &a[34], &a[35], &a[36], &a[37], &a[38], &a[39],
+};
+int *single = &b;
+
+int main(void)
+{
+	return *table[0] + *single;
+}
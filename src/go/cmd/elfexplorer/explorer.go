@@ -0,0 +1,571 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"parser-elf/elf"
+)
+
+// Panes of the explorer, in the order of the tab bar.
+const (
+	paneHeader = iota
+	paneSections
+	paneSegments
+	paneSymbols
+	paneDynamic
+	paneRelocations
+	paneNotes
+	paneHex
+	paneCount
+)
+
+var paneNames = [paneCount]string{"Header", "Sections", "Segments", "Symbols", "Dynamic", "Relocations", "Notes", "Hex"}
+
+// hexLimit bounds the number of bytes shown by the hex pane.
+const hexLimit = 64 * 1024
+
+// target is a row of a pane.
+type target struct {
+	pane, row int
+}
+
+// row is a line of a pane. The cross reference is followed with Enter and
+// the byte range is shown in the hex pane with x.
+type row struct {
+	cells  []string
+	link   *target
+	hex    bool
+	off    uint64
+	size   uint64
+	search string // Lower case text matched by the filter and the search.
+}
+
+type pane struct {
+	columns []string
+	rows    []row
+	widths  []int
+	visible []int // Rows passing the filter.
+	filter  string
+	cursor  int // Index in visible.
+	top     int
+	title   string // Shown after the tab bar, the range of the hex pane.
+}
+
+func (pn *pane) add(r row) {
+	r.search = strings.ToLower(strings.Join(r.cells, " "))
+	pn.rows = append(pn.rows, r)
+}
+
+// setFilter keeps the rows containing s, the cursor stays on the same row
+// when it still passes the filter.
+func (pn *pane) setFilter(s string) {
+	cur := pn.selected()
+	pn.filter = s
+	pn.visible = pn.visible[:0]
+	pn.cursor, pn.top = 0, 0
+	needle := strings.ToLower(s)
+	for i, r := range pn.rows {
+		if needle == "" || strings.Contains(r.search, needle) {
+			if i == cur {
+				pn.cursor = len(pn.visible)
+			}
+			pn.visible = append(pn.visible, i)
+		}
+	}
+}
+
+// selected returns the index of the row under the cursor, -1 if none.
+func (pn *pane) selected() int {
+	if pn.cursor < 0 || pn.cursor >= len(pn.visible) {
+		return -1
+	}
+	return pn.visible[pn.cursor]
+}
+
+// selectRow moves the cursor to row i, the filter is dropped if it hides it.
+func (pn *pane) selectRow(i int) {
+	for j, v := range pn.visible {
+		if v == i {
+			pn.cursor = j
+			return
+		}
+	}
+	pn.setFilter("")
+	pn.cursor = i
+}
+
+func (pn *pane) move(delta int) {
+	pn.cursor += delta
+	if pn.cursor >= len(pn.visible) {
+		pn.cursor = len(pn.visible) - 1
+	}
+	if pn.cursor < 0 {
+		pn.cursor = 0
+	}
+}
+
+func (pn *pane) computeWidths() {
+	pn.widths = make([]int, len(pn.columns))
+	for c, name := range pn.columns {
+		pn.widths[c] = len(name)
+	}
+	for _, r := range pn.rows {
+		for c, cell := range r.cells {
+			if c < len(pn.widths) && len(cell) > pn.widths[c] {
+				pn.widths[c] = len(cell)
+			}
+		}
+	}
+}
+
+// Input modes of the status line.
+const (
+	modeNormal = iota
+	modeFilter
+	modeSearch
+)
+
+type explorer struct {
+	p       *elf.Parser
+	panes   [paneCount]*pane
+	cur     int
+	history []target
+	mode    int
+	input   string
+	search  string
+	status  string
+	quit    bool
+}
+
+// newExplorer builds the panes from the report views of the parser, the
+// raw structures are walked in the same order to attach the cross
+// references and the byte ranges to the rows.
+func newExplorer(p *elf.Parser) (*explorer, error) {
+	e := &explorer{p: p}
+	for i := range e.panes {
+		e.panes[i] = &pane{}
+	}
+	views, err := p.BuildViews(elf.ViewHeader, elf.ViewSections, elf.ViewSegments, elf.ViewSymbols, elf.ViewDynamic, elf.ViewRelocations)
+	if err != nil {
+		return nil, err
+	}
+	header, sections, segments, symbols, dynamic, relocations := views[0], views[1], views[2], views[3], views[4], views[5]
+
+	pn := e.panes[paneHeader]
+	pn.columns = header.Tables[0].Columns
+	ehsize := uint64(64)
+	if p.F.Class() == elf.ELFCLASS32 {
+		ehsize = 52
+	}
+	for _, cells := range header.Tables[0].Rows {
+		pn.add(row{cells: cells, hex: true, off: 0, size: ehsize})
+	}
+
+	secs := p.F.Sections()
+	if len(sections.Tables) != 0 {
+		pn = e.panes[paneSections]
+		pn.columns = sections.Tables[0].Columns
+		for i, cells := range sections.Tables[0].Rows {
+			s := secs[i]
+			pn.add(row{cells: cells, hex: elf.SectionType(s.Type) != elf.SHT_NOBITS && s.Size != 0, off: s.Off, size: s.Size})
+		}
+	}
+
+	if len(segments.Tables) != 0 {
+		pn = e.panes[paneSegments]
+		pn.columns = segments.Tables[0].Columns
+		for i, ph := range p.F.ProgramHeaders() {
+			r := row{cells: segments.Tables[0].Rows[i], hex: ph.Filesz != 0, off: ph.Off, size: ph.Filesz}
+			// 段的交叉引用指向其包含的第一个节
+			if in := p.F.SegmentSections(ph); len(in) != 0 {
+				r.link = &target{paneSections, sectionIndex(secs, in[0])}
+			}
+			pn.add(r)
+		}
+	}
+
+	// 符号表按.dynsym、.symtab的顺序排列，记录每个符号所在的行供重定位引用
+	symbolRows := map[[2]int]int{} // Section index of the table and symbol index.
+	pn = e.panes[paneSymbols]
+	ti := 0
+	for _, typ := range []elf.SectionType{elf.SHT_DYNSYM, elf.SHT_SYMTAB} {
+		syms, err := p.Symbols(typ)
+		if err != nil || ti >= len(symbols.Tables) {
+			continue
+		}
+		t := symbols.Tables[ti]
+		ti++
+		pn.columns = append([]string{"Table"}, t.Columns...)
+		table := sectionIndex(secs, p.F.SectionByType(typ))
+		for i, cells := range t.Rows {
+			r := row{cells: append([]string{t.Title}, cells...)}
+			if i < len(syms) {
+				e.symbolTarget(&r, syms[i], secs)
+			}
+			symbolRows[[2]int{table, i}] = len(pn.rows)
+			pn.add(r)
+		}
+	}
+
+	if len(dynamic.Tables) != 0 {
+		pn = e.panes[paneDynamic]
+		pn.columns = dynamic.Tables[0].Columns
+		off, entSize := uint64(0), uint64(16)
+		if p.F.Class() == elf.ELFCLASS32 {
+			entSize = 8
+		}
+		for _, ph := range p.F.ProgramHeaders() {
+			if elf.ProgType(ph.Type) == elf.PT_DYNAMIC {
+				off = ph.Off
+			}
+		}
+		for i, cells := range dynamic.Tables[0].Rows {
+			pn.add(row{cells: cells, hex: off != 0, off: off + uint64(i)*entSize, size: entSize})
+		}
+	}
+
+	pn = e.panes[paneRelocations]
+	word := uint64(8)
+	if p.F.Class() == elf.ELFCLASS32 {
+		word = 4
+	}
+	var relocSections []*elf.ELF64Section
+	for _, s := range secs {
+		if t := elf.SectionType(s.Type); t == elf.SHT_REL || t == elf.SHT_RELA {
+			relocSections = append(relocSections, s)
+		}
+	}
+	for ti, t := range relocations.Tables {
+		pn.columns = append([]string{"Section"}, t.Columns...)
+		var relocs []elf.Relocation
+		table, base := -1, -1
+		if ti < len(relocSections) {
+			s := relocSections[ti]
+			relocs, _ = p.SectionRelocations(s)
+			table = int(s.Link)
+			// 目标文件的r_offset是相对于sh_info所指节的偏移
+			if isRelocatable(p) && int(s.Info) < len(secs) {
+				base = int(s.Info)
+			}
+		} else {
+			relocs = p.F.DynRelocations
+			table = sectionIndex(secs, p.F.SectionByType(elf.SHT_DYNSYM))
+		}
+		for i, cells := range t.Rows {
+			r := row{cells: append([]string{t.Title}, cells...)}
+			if i < len(relocs) {
+				rel := relocs[i]
+				if rel.Sym != 0 {
+					if sr, ok := symbolRows[[2]int{table, int(rel.Sym)}]; ok {
+						r.link = &target{paneSymbols, sr}
+					}
+				}
+				if base >= 0 {
+					r.off, r.hex = secs[base].Off+rel.Off, elf.SectionType(secs[base].Type) != elf.SHT_NOBITS
+				} else if off, err := p.F.OffsetForVaddr(rel.Off); err == nil {
+					r.off, r.hex = off, true
+				}
+				r.size = word
+			}
+			pn.add(r)
+		}
+	}
+
+	pn = e.panes[paneNotes]
+	pn.columns = []string{"Owner", "Type", "Desc size", "Offset", "Section"}
+	notes, _ := p.Notes()
+	for _, n := range notes {
+		size := 12 + (uint64(len(n.Name))+1+3)&^3 + uint64(len(n.Desc))
+		pn.add(row{cells: []string{n.Name, n.TypeString(), fmt.Sprint(len(n.Desc)), fmt.Sprintf("0x%x", n.Offset), n.Section}, hex: true, off: n.Offset, size: size})
+	}
+
+	e.panes[paneHex].columns = []string{"Annotated hexdump"}
+	for _, pn := range e.panes {
+		pn.computeWidths()
+		pn.setFilter("")
+	}
+	return e, nil
+}
+
+// symbolTarget sets the cross reference of a symbol row to the section
+// defining it and its byte range to the bytes at its value.
+func (e *explorer) symbolTarget(r *row, sym elf.Symbol, secs []*elf.ELF64Section) {
+	idx := int(sym.Index)
+	if sym.Index == elf.SHN_UNDEF || sym.Index >= elf.SHN_LORESERVE || idx >= len(secs) {
+		return
+	}
+	r.link = &target{paneSections, idx}
+	s := secs[idx]
+	if elf.SectionType(s.Type) == elf.SHT_NOBITS {
+		return
+	}
+	r.size = sym.Size
+	if r.size == 0 {
+		r.size = 16
+	}
+	if isRelocatable(e.p) {
+		r.off, r.hex = s.Off+sym.Value, true
+	} else if off, err := e.p.F.OffsetForVaddr(sym.Value); err == nil {
+		r.off, r.hex = off, true
+	}
+}
+
+// isRelocatable reports whether the file is an ET_REL object, whose symbol
+// values and relocation offsets are relative to a section.
+func isRelocatable(p *elf.Parser) bool {
+	if p.F.Class() == elf.ELFCLASS32 {
+		return elf.Type(p.F.Header32.Type) == elf.ET_REL
+	}
+	return elf.Type(p.F.Header64.Type) == elf.ET_REL
+}
+
+func sectionIndex(secs []*elf.ELF64Section, s *elf.ELF64Section) int {
+	for i, c := range secs {
+		if c == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// showHex fills the hex pane with the annotated dump of a range.
+func (e *explorer) showHex(off, size uint64) {
+	if size > hexLimit {
+		size = hexLimit
+	}
+	var b bytes.Buffer
+	if err := e.p.WriteAnnotatedHexDump(&b, off, size); err != nil {
+		e.status = err.Error()
+		return
+	}
+	pn := e.panes[paneHex]
+	pn.rows = pn.rows[:0]
+	for _, line := range strings.Split(strings.TrimRight(b.String(), "\n"), "\n") {
+		pn.add(row{cells: []string{line}})
+	}
+	pn.title = fmt.Sprintf("0x%x-0x%x", off, off+size)
+	pn.computeWidths()
+	pn.setFilter("")
+	e.jump(target{paneHex, 0})
+}
+
+// jump moves to a row, the current position is pushed on the history.
+func (e *explorer) jump(t target) {
+	e.history = append(e.history, target{e.cur, e.panes[e.cur].selected()})
+	e.cur = t.pane
+	e.panes[t.pane].selectRow(t.row)
+}
+
+func (e *explorer) back() {
+	if len(e.history) == 0 {
+		e.status = "history is empty"
+		return
+	}
+	t := e.history[len(e.history)-1]
+	e.history = e.history[:len(e.history)-1]
+	e.cur = t.pane
+	if t.row >= 0 {
+		e.panes[t.pane].selectRow(t.row)
+	}
+}
+
+// find moves the cursor to the next visible row containing the search
+// text, dir is 1 to search forward and -1 backward.
+func (e *explorer) find(dir int) {
+	pn := e.panes[e.cur]
+	needle := strings.ToLower(e.search)
+	if needle == "" || len(pn.visible) == 0 {
+		return
+	}
+	n := len(pn.visible)
+	for k := 1; k <= n; k++ {
+		j := ((pn.cursor+dir*k)%n + n) % n
+		if strings.Contains(pn.rows[pn.visible[j]].search, needle) {
+			pn.cursor = j
+			return
+		}
+	}
+	e.status = fmt.Sprintf("%q not found", e.search)
+}
+
+// handleKey applies a key read by readKeys.
+func (e *explorer) handleKey(k string, pageSize int) {
+	e.status = ""
+	if e.mode != modeNormal {
+		switch k {
+		case "enter":
+			if e.mode == modeFilter {
+				e.panes[e.cur].setFilter(e.input)
+			} else {
+				e.search = e.input
+				e.find(1)
+			}
+			e.mode = modeNormal
+		case "esc":
+			e.mode = modeNormal
+		case "backspace":
+			if r := []rune(e.input); len(r) != 0 {
+				e.input = string(r[:len(r)-1])
+			}
+		default:
+			if len([]rune(k)) == 1 {
+				e.input += k
+			}
+		}
+		return
+	}
+	pn := e.panes[e.cur]
+	switch k {
+	case "q", "ctrl-c":
+		e.quit = true
+	case "tab", "right", "l":
+		e.cur = (e.cur + 1) % paneCount
+	case "shift-tab", "left", "h":
+		e.cur = (e.cur + paneCount - 1) % paneCount
+	case "1", "2", "3", "4", "5", "6", "7", "8":
+		e.cur = int(k[0] - '1')
+	case "down", "j":
+		pn.move(1)
+	case "up", "k":
+		pn.move(-1)
+	case "pgdn", " ":
+		pn.move(pageSize)
+	case "pgup":
+		pn.move(-pageSize)
+	case "home", "g":
+		pn.cursor = 0
+	case "end", "G":
+		pn.move(len(pn.visible))
+	case "f":
+		e.mode, e.input = modeFilter, pn.filter
+	case "/":
+		e.mode, e.input = modeSearch, ""
+	case "n":
+		e.find(1)
+	case "N":
+		e.find(-1)
+	case "c":
+		pn.setFilter("")
+	case "b", "backspace":
+		e.back()
+	case "x":
+		if i := pn.selected(); i >= 0 && pn.rows[i].hex {
+			e.showHex(pn.rows[i].off, pn.rows[i].size)
+		} else {
+			e.status = "no bytes in the file for this row"
+		}
+	case "enter":
+		i := pn.selected()
+		switch {
+		case i < 0:
+		case pn.rows[i].link != nil:
+			e.jump(*pn.rows[i].link)
+		case pn.rows[i].hex:
+			e.showHex(pn.rows[i].off, pn.rows[i].size)
+		default:
+			e.status = "no cross reference for this row"
+		}
+	}
+}
+
+const (
+	ansiReverse  = "\x1b[7m"
+	ansiBold     = "\x1b[1m"
+	ansiReset    = "\x1b[0m"
+	ansiClearEOL = "\x1b[K"
+)
+
+// clip cuts s to width runes.
+func clip(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	r := []rune(s)
+	if len(r) > width {
+		return string(r[:width])
+	}
+	return s
+}
+
+func (pn *pane) line(cells []string, width int) string {
+	var sb strings.Builder
+	for c, cell := range cells {
+		if c != 0 {
+			sb.WriteByte(' ')
+		}
+		if c < len(pn.widths) && c != len(cells)-1 {
+			fmt.Fprintf(&sb, "%-*s", pn.widths[c], cell)
+		} else {
+			sb.WriteString(cell)
+		}
+	}
+	return clip(sb.String(), width)
+}
+
+// draw writes the whole screen, the terminal is width x height.
+func (e *explorer) draw(w io.Writer, width, height int) error {
+	var b bytes.Buffer
+	b.WriteString("\x1b[H")
+	var tabs strings.Builder
+	for i, name := range paneNames {
+		label := fmt.Sprintf(" %d %s ", i+1, name)
+		if i == e.cur {
+			label = ansiReverse + label + ansiReset
+		}
+		tabs.WriteString(label)
+	}
+	pn := e.panes[e.cur]
+	if pn.title != "" {
+		tabs.WriteString(" " + pn.title)
+	}
+	b.WriteString(tabs.String() + ansiClearEOL + "\r\n")
+	b.WriteString(ansiBold + pn.line(pn.columns, width) + ansiReset + ansiClearEOL + "\r\n")
+
+	rows := height - 3
+	if rows < 1 {
+		rows = 1
+	}
+	// 光标始终保持在可见区域内
+	if pn.cursor < pn.top {
+		pn.top = pn.cursor
+	}
+	if pn.cursor >= pn.top+rows {
+		pn.top = pn.cursor - rows + 1
+	}
+	for i := 0; i < rows; i++ {
+		j := pn.top + i
+		if j < len(pn.visible) {
+			text := pn.line(pn.rows[pn.visible[j]].cells, width)
+			if j == pn.cursor {
+				text = ansiReverse + text + ansiReset
+			}
+			b.WriteString(text)
+		}
+		b.WriteString(ansiClearEOL + "\r\n")
+	}
+
+	status := e.status
+	switch e.mode {
+	case modeFilter:
+		status = "filter: " + e.input
+	case modeSearch:
+		status = "search: " + e.input
+	default:
+		if status == "" {
+			status = fmt.Sprintf("%d/%d", pn.selected()+1, len(pn.rows))
+			if len(pn.visible) == 0 {
+				status = fmt.Sprintf("0/%d", len(pn.rows))
+			}
+			if pn.filter != "" {
+				status += fmt.Sprintf(" (filter %q)", pn.filter)
+			}
+			status += "  Tab/1-8 pane  Enter follow  x hex  / search  f filter  b back  q quit"
+		}
+	}
+	b.WriteString(ansiReverse + clip(status, width) + ansiReset + ansiClearEOL)
+	_, err := w.Write(b.Bytes())
+	return err
+}
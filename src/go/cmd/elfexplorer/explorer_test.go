@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"parser-elf/elf"
+)
+
+const exampleDir = "../../../../example"
+
+// readAll decodes every key of the input.
+func readAll(r io.Reader, timeout time.Duration) []string {
+	kr := newKeyReader(r, timeout)
+	keys := []string{}
+	for {
+		k, ok := kr.key()
+		if !ok {
+			return keys
+		}
+		keys = append(keys, k)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	in := "j\x1b[A\x1bOB\x1b[5~\x1b[6~\x1b[Z\r\n\t\x7f\x08\x03\x1b[99Xq\x01é"
+	assert.Equal(t, []string{"j", "up", "down", "pgup", "pgdn", "shift-tab", "enter", "enter", "tab",
+		"backspace", "backspace", "ctrl-c", "q", "é"}, readAll(strings.NewReader(in), escapeTimeout))
+	// ESC后面跟着普通字符或输入结束时是单独的ESC键
+	assert.Equal(t, []string{"esc", "x", "esc"}, readAll(strings.NewReader("\x1bx\x1b"), escapeTimeout))
+}
+
+func TestKeysSplitSequence(t *testing.T) {
+	// 方向键的序列分两次到达，仍是一个键
+	r, w := io.Pipe()
+	kr := newKeyReader(r, time.Second)
+	go func() {
+		w.Write([]byte("\x1b"))
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("[A"))
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("\x1b[5"))
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("~"))
+		w.Close()
+	}()
+	for _, want := range []string{"up", "pgup"} {
+		k, ok := kr.key()
+		assert.True(t, ok)
+		assert.Equal(t, want, k)
+	}
+	_, ok := kr.key()
+	assert.False(t, ok)
+
+	// 超时之后没有更多输入，ESC单独成为一个键
+	r, w = io.Pipe()
+	kr = newKeyReader(r, 10*time.Millisecond)
+	w.Write([]byte("\x1b"))
+	k, ok := kr.key()
+	assert.True(t, ok)
+	assert.Equal(t, "esc", k)
+	go func() {
+		w.Write([]byte("q"))
+		w.Close()
+	}()
+	k, _ = kr.key()
+	assert.Equal(t, "q", k)
+}
+
+func newTestExplorer(t *testing.T, name string) *explorer {
+	p, err := elf.New(path.Join(exampleDir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.CloseFile() })
+	if err := p.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	e, err := newExplorer(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// press applies the keys one by one.
+func press(e *explorer, keys ...string) {
+	for _, k := range keys {
+		e.handleKey(k, 10)
+	}
+}
+
+// typeText types s in the filter or search input, a key per character.
+func typeText(e *explorer, s string) {
+	for _, c := range s {
+		e.handleKey(string(c), 10)
+	}
+}
+
+// selectedCell returns the cell of the row under the cursor of the current
+// pane.
+func selectedCell(e *explorer, col int) string {
+	pn := e.panes[e.cur]
+	i := pn.selected()
+	if i < 0 {
+		return ""
+	}
+	return pn.rows[i].cells[col]
+}
+
+func TestNavigation(t *testing.T) {
+	e := newTestExplorer(t, "gcc-amd64-linux-exec")
+	assert.Equal(t, paneHeader, e.cur)
+	press(e, "tab")
+	assert.Equal(t, paneSections, e.cur)
+	press(e, "shift-tab", "left")
+	assert.Equal(t, paneHex, e.cur)
+	press(e, "2")
+	assert.Equal(t, paneSections, e.cur)
+
+	// 移动、翻页、首尾
+	pn := e.panes[paneSections]
+	press(e, "j", "down")
+	assert.Equal(t, 2, pn.cursor)
+	press(e, "k")
+	assert.Equal(t, 1, pn.cursor)
+	press(e, "pgdn")
+	assert.Equal(t, 11, pn.cursor)
+	press(e, "G")
+	assert.Equal(t, len(pn.rows)-1, pn.cursor)
+	press(e, "pgdn", "g")
+	assert.Equal(t, 0, pn.cursor)
+
+	// 搜索和过滤
+	press(e, "/")
+	typeText(e, ".tex")
+	press(e, "backspace", "x", "enter")
+	assert.Equal(t, modeNormal, e.mode)
+	assert.Contains(t, pn.rows[pn.selected()].search, ".text")
+	press(e, "f")
+	typeText(e, "debug")
+	press(e, "enter")
+	assert.Equal(t, "debug", pn.filter)
+	assert.NotEmpty(t, pn.visible)
+	for _, i := range pn.visible {
+		assert.Contains(t, pn.rows[i].search, "debug")
+	}
+	press(e, "c")
+	assert.Len(t, pn.visible, len(pn.rows))
+	press(e, "/", "z", "z", "z", "enter")
+	assert.Equal(t, `"zzz" not found`, e.status)
+	press(e, "f", "q", "esc")
+	assert.Equal(t, modeNormal, e.mode)
+	assert.False(t, e.quit)
+	assert.Equal(t, "", pn.filter)
+
+	// 交叉引用：重定位 -> 符号 -> 节，b返回
+	press(e, "6", "f")
+	typeText(e, "gmon")
+	press(e, "enter")
+	assert.Contains(t, selectedCell(e, 0), ".rela.dyn")
+	press(e, "enter")
+	assert.Equal(t, paneSymbols, e.cur)
+	assert.Contains(t, e.panes[paneSymbols].rows[e.panes[paneSymbols].selected()].search, "__gmon_start__")
+	press(e, "enter")
+	assert.Equal(t, "no cross reference for this row", e.status)
+	press(e, "b")
+	assert.Equal(t, paneRelocations, e.cur)
+	assert.Contains(t, selectedCell(e, 0), ".rela.dyn")
+	press(e, "b")
+	assert.Equal(t, "history is empty", e.status)
+
+	// 定义在.text中的符号指向其节，x打开十六进制窗格
+	press(e, "4", "/")
+	typeText(e, "main")
+	press(e, "enter")
+	// __libc_start_main在前，n跳到main本身
+	syms := e.panes[paneSymbols]
+	for i := 0; i < len(syms.rows); i++ {
+		if cells := syms.rows[syms.selected()].cells; cells[len(cells)-1] == "main" {
+			break
+		}
+		press(e, "n")
+	}
+	press(e, "enter")
+	assert.Equal(t, paneSections, e.cur)
+	assert.Equal(t, ".text", strings.TrimSpace(selectedCell(e, 1)))
+	press(e, "x")
+	assert.Equal(t, paneHex, e.cur)
+	assert.NotEmpty(t, e.panes[paneHex].rows)
+	assert.NotEmpty(t, e.panes[paneHex].title)
+
+	var out bytes.Buffer
+	assert.NoError(t, e.draw(&out, 80, 24))
+	assert.Contains(t, out.String(), "Hex")
+
+	press(e, "q")
+	assert.True(t, e.quit)
+}
@@ -0,0 +1,227 @@
+// elfexplorer is an interactive terminal browser of an ELF file: the header,
+// sections, segments, symbols, dynamic entries, relocations and notes are
+// shown in panes that can be filtered and searched, any row with bytes in the
+// file opens an annotated hexdump and the cross references between
+// relocations, symbols and sections can be followed. It only needs a VT100
+// compatible terminal, so it works over SSH.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"parser-elf/elf"
+)
+
+var errNotTerminal = errors.New("standard input is not a terminal")
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: elfexplorer <elf-file>\n")
+	fmt.Fprintf(w, " Keys:\n")
+	fmt.Fprintf(w, "  Tab, Shift-Tab, 1-8     Switch pane\n")
+	fmt.Fprintf(w, "  j k, arrows, PgUp PgDn  Move, g and G go to the first and the last row\n")
+	fmt.Fprintf(w, "  Enter                   Follow the cross reference (relocation -> symbol -> section)\n")
+	fmt.Fprintf(w, "  x                       Show the bytes of the row in the annotated hexdump\n")
+	fmt.Fprintf(w, "  b, Backspace            Go back\n")
+	fmt.Fprintf(w, "  / n N                   Search, next and previous match\n")
+	fmt.Fprintf(w, "  f c                     Filter the rows, clear the filter\n")
+	fmt.Fprintf(w, "  q                       Quit\n")
+}
+
+func main() {
+	if len(os.Args) != 2 || os.Args[1] == "-h" || os.Args[1] == "--help" {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	if err := run(os.Args[1]); err != nil {
+		fmt.Fprintf(os.Stderr, "elfexplorer: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(filename string) error {
+	p, err := elf.New(filename)
+	if err != nil {
+		return err
+	}
+	defer p.CloseFile()
+	if err := p.Parse(); err != nil {
+		return err
+	}
+	e, err := newExplorer(p)
+	if err != nil {
+		return err
+	}
+
+	term, err := openTerminal(os.Stdin)
+	if err != nil {
+		return err
+	}
+	defer term.restore()
+	// 使用备用屏幕并隐藏光标，退出时恢复
+	out := bufio.NewWriter(os.Stdout)
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l\x1b[2J")
+	defer func() {
+		fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+		out.Flush()
+	}()
+
+	keys := make(chan string)
+	go readKeys(os.Stdin, keys)
+	resize := make(chan os.Signal, 1)
+	notifyResize(resize)
+
+	for !e.quit {
+		width, height := term.size()
+		if err := e.draw(out, width, height); err != nil {
+			return err
+		}
+		if err := out.Flush(); err != nil {
+			return err
+		}
+		select {
+		case k, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			e.handleKey(k, height-3)
+		case <-resize:
+			fmt.Fprint(out, "\x1b[2J")
+		}
+	}
+	return nil
+}
+
+// escapeKeys maps the escape sequences of VT100 and xterm to key names.
+var escapeKeys = map[string]string{
+	"[A": "up", "[B": "down", "[C": "right", "[D": "left",
+	"OA": "up", "OB": "down", "OC": "right", "OD": "left",
+	"[H": "home", "[F": "end", "OH": "home", "OF": "end",
+	"[1~": "home", "[4~": "end", "[5~": "pgup", "[6~": "pgdn",
+	"[Z": "shift-tab",
+}
+
+// escapeTimeout is how long the rest of an escape sequence may take to
+// arrive after ESC, a sequence split between two reads is still one key.
+// Nothing following ESC in that time makes it a key of its own.
+const escapeTimeout = 50 * time.Millisecond
+
+// keyReader decodes the keys of a terminal from the runes read from it.
+type keyReader struct {
+	runes   chan rune
+	timeout time.Duration
+	unread  []rune
+}
+
+// newKeyReader starts reading the runes of r, the runes channel is closed
+// at the end of the input.
+func newKeyReader(r io.Reader, timeout time.Duration) *keyReader {
+	kr := &keyReader{runes: make(chan rune, 16), timeout: timeout}
+	go func() {
+		defer close(kr.runes)
+		br := bufio.NewReader(r)
+		for {
+			c, _, err := br.ReadRune()
+			if err != nil {
+				return
+			}
+			kr.runes <- c
+		}
+	}()
+	return kr
+}
+
+// next returns the next rune. With wait unset it gives up after the
+// timeout, ok is false then and at the end of the input.
+func (kr *keyReader) next(wait bool) (rune, bool) {
+	if n := len(kr.unread); n != 0 {
+		c := kr.unread[n-1]
+		kr.unread = kr.unread[:n-1]
+		return c, true
+	}
+	if wait {
+		c, ok := <-kr.runes
+		return c, ok
+	}
+	timer := time.NewTimer(kr.timeout)
+	defer timer.Stop()
+	select {
+	case c, ok := <-kr.runes:
+		return c, ok
+	case <-timer.C:
+		return 0, false
+	}
+}
+
+// key returns the name of the next key, ok is false at the end of the
+// input. Unknown escape sequences and control characters are skipped.
+func (kr *keyReader) key() (string, bool) {
+	for {
+		c, ok := kr.next(true)
+		if !ok {
+			return "", false
+		}
+		switch c {
+		case '\r', '\n':
+			return "enter", true
+		case '\t':
+			return "tab", true
+		case 0x7f, 0x08:
+			return "backspace", true
+		case 0x03:
+			return "ctrl-c", true
+		case 0x1b:
+			if k := kr.escape(); k != "" {
+				return k, true
+			}
+		default:
+			if c >= ' ' {
+				return string(c), true
+			}
+		}
+	}
+}
+
+// escape decodes what follows ESC: a CSI or SS3 sequence ending with a
+// letter or ~, or nothing when ESC was pressed alone.
+func (kr *keyReader) escape() string {
+	c, ok := kr.next(false)
+	if !ok {
+		return "esc"
+	}
+	if c != '[' && c != 'O' {
+		// ESC之后的普通字符是下一个键
+		kr.unread = append(kr.unread, c)
+		return "esc"
+	}
+	seq := string(c)
+	for len(seq) < 8 {
+		c, ok := kr.next(false)
+		if !ok {
+			break
+		}
+		seq += string(c)
+		if c >= 'A' && c <= 'Z' || c == '~' {
+			break
+		}
+	}
+	return escapeKeys[seq]
+}
+
+// readKeys decodes the keys read from r and sends their names on keys, it
+// closes keys at the end of the input.
+func readKeys(r io.Reader, keys chan<- string) {
+	defer close(keys)
+	kr := newKeyReader(r, escapeTimeout)
+	for {
+		k, ok := kr.key()
+		if !ok {
+			return
+		}
+		keys <- k
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+type terminal struct{}
+
+func openTerminal(f *os.File) (*terminal, error) {
+	return nil, errors.New("the explorer is not supported on this system")
+}
+
+func (t *terminal) restore() error { return nil }
+
+func (t *terminal) size() (int, int) { return 80, 24 }
+
+func notifyResize(c chan<- os.Signal) {}
@@ -0,0 +1,208 @@
+// gonm lists the symbols of ELF files, it understands the common part of
+// the GNU nm option set and prints the same letters and layouts.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"parser-elf/elf"
+)
+
+// 退出码与nm一致：0成功，1有文件处理失败或参数错误
+const (
+	exitOK    = 0
+	exitError = 1
+)
+
+type options struct {
+	nm    elf.NMOptions
+	files []string
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, `Usage: gonm [option(s)] [file(s)]
+ List symbols in [file(s)] (a.out by default).
+ The options are:
+  -a, --debug-syms       Display debugger-only symbols
+  -C, --demangle         Decode low-level symbol names into user-level names
+      --no-demangle      Do not demangle low-level symbol names
+  -D, --dynamic          Display dynamic symbols instead of normal symbols
+      --defined-only     Display only defined symbols
+  -f, --format=FORMAT    Use the output format FORMAT.  FORMAT can be 'bsd',
+                           'sysv' or 'posix'.  The default is 'bsd'
+  -g, --extern-only      Display only external symbols
+  -n, --numeric-sort     Sort symbols numerically by address
+  -p, --no-sort          Do not sort the symbols
+  -P, --portability      Same as --format=posix
+  -r, --reverse-sort     Reverse the sense of the sort
+  -S, --print-size       Print size of defined symbols
+      --size-sort        Sort symbols by size
+  -u, --undefined-only   Display only undefined symbols
+  -h, --help             Display this information`)
+}
+
+// setFormat checks the value of -f and --format.
+func setFormat(o *options, value string) error {
+	switch f := elf.NMFormat(value); f {
+	case elf.NMFormatBSD, elf.NMFormatPOSIX, elf.NMFormatSysV:
+		o.nm.Format = f
+		return nil
+	}
+	return fmt.Errorf("%s: invalid output format", value)
+}
+
+// parseArgs parses nm style arguments: grouped short options (-nD), -f with
+// an attached or separate value and long options.
+func parseArgs(args []string) (*options, error) {
+	o := &options{}
+	long := map[string]func(){
+		"debug-syms":     func() { o.nm.All = true },
+		"demangle":       func() { o.nm.Demangle = true },
+		"no-demangle":    func() { o.nm.Demangle = false },
+		"dynamic":        func() { o.nm.Dynamic = true },
+		"defined-only":   func() { o.nm.DefinedOnly = true },
+		"extern-only":    func() { o.nm.ExternalOnly = true },
+		"numeric-sort":   func() { o.nm.Sort = elf.NMSortAddress },
+		"no-sort":        func() { o.nm.Sort = elf.NMSortNone },
+		"portability":    func() { o.nm.Format = elf.NMFormatPOSIX },
+		"reverse-sort":   func() { o.nm.Reverse = true },
+		"print-size":     func() { o.nm.PrintSize = true },
+		"size-sort":      func() { o.nm.Sort = elf.NMSortSize },
+		"undefined-only": func() { o.nm.UndefinedOnly = true },
+	}
+	short := map[byte]func(){
+		'a': long["debug-syms"],
+		'C': long["demangle"],
+		'D': long["dynamic"],
+		'g': long["extern-only"],
+		'n': long["numeric-sort"],
+		'v': long["numeric-sort"],
+		'p': long["no-sort"],
+		'P': long["portability"],
+		'r': long["reverse-sort"],
+		'S': long["print-size"],
+		'u': long["undefined-only"],
+	}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			o.files = append(o.files, args[i+1:]...)
+			return o, nil
+		case strings.HasPrefix(arg, "--"):
+			name, value, hasValue := arg[2:], "", false
+			if eq := strings.IndexByte(name, '='); eq >= 0 {
+				name, value, hasValue = name[:eq], name[eq+1:], true
+			}
+			if name == "format" {
+				if !hasValue {
+					if i+1 >= len(args) {
+						return nil, fmt.Errorf("option '--%s' requires an argument", name)
+					}
+					i++
+					value = args[i]
+				}
+				if err := setFormat(o, value); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if name == "help" {
+				return nil, nil
+			}
+			set, ok := long[name]
+			if !ok || hasValue {
+				return nil, fmt.Errorf("unrecognized option '%s'", arg)
+			}
+			set()
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			for j := 1; j < len(arg); j++ {
+				c := arg[j]
+				if c == 'f' {
+					value := arg[j+1:]
+					if value == "" {
+						if i+1 >= len(args) {
+							return nil, fmt.Errorf("option requires an argument -- '%c'", c)
+						}
+						i++
+						value = args[i]
+					}
+					if err := setFormat(o, value); err != nil {
+						return nil, err
+					}
+					break
+				}
+				if c == 'h' {
+					return nil, nil
+				}
+				set, ok := short[c]
+				if !ok {
+					return nil, fmt.Errorf("invalid option -- '%c'", c)
+				}
+				set()
+			}
+		default:
+			o.files = append(o.files, arg)
+		}
+	}
+	if o.nm.DefinedOnly && o.nm.UndefinedOnly {
+		return nil, errors.New("cannot use --defined-only and --undefined-only together")
+	}
+	return o, nil
+}
+
+// listFile writes the symbols of one file, the BSD and POSIX layouts name
+// the file first when there are several.
+func listFile(o *options, filename string, multiple bool) error {
+	p, err := elf.New(filename)
+	if err != nil {
+		return err
+	}
+	defer p.CloseFile()
+	if err := p.Parse(); err != nil {
+		return err
+	}
+	if multiple {
+		switch o.nm.Format {
+		case elf.NMFormatPOSIX:
+			fmt.Printf("%s:\n", filename)
+		case elf.NMFormatSysV:
+		default:
+			fmt.Printf("\n%s:\n", filename)
+		}
+	}
+	return p.WriteNM(os.Stdout, filename, o.nm)
+}
+
+func main() {
+	o, err := parseArgs(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gonm: %s\n", err)
+		usage(os.Stderr)
+		os.Exit(exitError)
+	}
+	if o == nil {
+		usage(os.Stdout)
+		os.Exit(exitOK)
+	}
+	if len(o.files) == 0 {
+		o.files = []string{"a.out"}
+	}
+	status := exitOK
+	for _, filename := range o.files {
+		err := listFile(o, filename, len(o.files) > 1)
+		switch {
+		case errors.Is(err, elf.ErrNoSymbols):
+			// 没有符号只是警告，nm并不因此报错
+			fmt.Fprintf(os.Stderr, "gonm: %s: no symbols\n", filename)
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "gonm: '%s': %s\n", filename, err)
+			status = exitError
+		}
+	}
+	os.Exit(status)
+}
@@ -0,0 +1,168 @@
+// goobjcopy extracts the raw contents of one section or segment of an ELF
+// file, like objcopy -O binary -j <section>. Compressed sections are
+// written decompressed.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"parser-elf/elf"
+)
+
+// 退出码与objcopy一致：0成功，1失败或参数错误
+const (
+	exitOK    = 0
+	exitError = 1
+)
+
+type options struct {
+	// section is the -j argument, a section name or index.
+	section string
+	// segment is the --only-segment index, -1 when not asked for.
+	segment int
+	files   []string
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, `Usage: goobjcopy -O binary [option(s)] in-file out-file
+ Copies the raw contents of one section or segment of in-file to out-file,
+ out-file may be - for the standard output.
+ The options are:
+  -O --output-target binary
+                         Write a raw binary file, the only supported target
+  -j --only-section <number|name>
+                         Only copy section <number|name> into the output
+     --only-segment <number>
+                         Only copy the file bytes of program header <number>
+  -h --help              Display this information`)
+}
+
+// parseArgs parses objcopy style arguments, the values of -O, -j and
+// --only-segment may be attached (-j.text, --only-section=.text) or separate.
+func parseArgs(args []string) (*options, error) {
+	o := &options{segment: -1}
+	target := ""
+	sections := 0
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := "", "", false
+		switch {
+		case arg == "--":
+			o.files = append(o.files, args[i+1:]...)
+			i = len(args)
+			continue
+		case arg == "-h" || arg == "--help":
+			return nil, nil
+		case strings.HasPrefix(arg, "--"):
+			name = arg[2:]
+			if eq := strings.IndexByte(name, '='); eq >= 0 {
+				name, value, hasValue = name[:eq], name[eq+1:], true
+			}
+		case arg == "-O" || arg == "-j":
+			name = map[string]string{"-O": "output-target", "-j": "only-section"}[arg]
+		case strings.HasPrefix(arg, "-O") || strings.HasPrefix(arg, "-j"):
+			name = map[byte]string{'O': "output-target", 'j': "only-section"}[arg[1]]
+			value, hasValue = arg[2:], true
+		case strings.HasPrefix(arg, "-") && arg != "-":
+			return nil, fmt.Errorf("invalid option -- '%s'", arg[1:])
+		default:
+			o.files = append(o.files, arg)
+			continue
+		}
+		if name != "output-target" && name != "only-section" && name != "only-segment" {
+			return nil, fmt.Errorf("unrecognized option '%s'", arg)
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("option '%s' requires an argument", arg)
+			}
+			i++
+			value = args[i]
+		}
+		switch name {
+		case "output-target":
+			target = value
+		case "only-section":
+			o.section = value
+			sections++
+		case "only-segment":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid segment number '%s'", value)
+			}
+			o.segment = n
+			sections++
+		}
+	}
+	if target != "binary" {
+		return nil, errors.New("only -O binary is supported")
+	}
+	if sections != 1 {
+		return nil, errors.New("exactly one -j or --only-segment is required")
+	}
+	if len(o.files) != 2 {
+		return nil, errors.New("an input and an output file are required")
+	}
+	return o, nil
+}
+
+// extract writes the requested section or segment of in to out.
+func extract(o *options, in, out string) (err error) {
+	p, err := elf.New(in)
+	if err != nil {
+		return err
+	}
+	defer p.CloseFile()
+	if err := p.Parse(); err != nil {
+		return err
+	}
+	// 内容全部取出之后才创建输出文件，失败时不留下空的或不完整的文件
+	var buf bytes.Buffer
+	if o.segment >= 0 {
+		err = p.ExtractSegment(&buf, o.segment)
+	} else {
+		var section int
+		if section, err = p.LookupSection(o.section); err == nil {
+			err = p.ExtractSection(&buf, section)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	w := os.Stdout
+	if out != "-" {
+		if w, err = os.Create(out); err != nil {
+			return err
+		}
+		defer func() {
+			if cerr := w.Close(); err == nil {
+				err = cerr
+			}
+		}()
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+func main() {
+	o, err := parseArgs(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goobjcopy: %s\n", err)
+		usage(os.Stderr)
+		os.Exit(exitError)
+	}
+	if o == nil {
+		usage(os.Stdout)
+		os.Exit(exitOK)
+	}
+	if err := extract(o, o.files[0], o.files[1]); err != nil {
+		fmt.Fprintf(os.Stderr, "goobjcopy: '%s': %s\n", o.files[0], err)
+		os.Exit(exitError)
+	}
+}
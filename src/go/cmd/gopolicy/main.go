@@ -0,0 +1,253 @@
+// gopolicy checks ELF files against a hardening policy to gate releases.
+//
+//	gopolicy check -p policy.yaml [--root dir] file-or-dir...
+//
+// Directories are walked and their ELF files checked, the other files are
+// skipped. Every violation is printed on its own line.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"parser-elf/elf"
+)
+
+// 退出码：0全部通过，1有违反策略的文件，2参数、策略或文件错误
+const (
+	exitOK        = 0
+	exitViolation = 1
+	exitError     = 2
+)
+
+type options struct {
+	policy string
+	// root is stripped from the file names before they are matched, so a
+	// staging tree can be checked against globs like /usr/bin/*.
+	root string
+	// sarif writes the violations as a SARIF log instead of text lines.
+	sarif bool
+	files []string
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, `Usage: gopolicy check -p <policy> [option(s)] file(s)|dir(s)
+ Check ELF files against a hardening policy (YAML or JSON)
+ The options are:
+  -p --policy <file>     The policy to check the files against
+     --root <dir>        Match the files as if <dir> were the root directory
+     --format=<text|sarif>
+                         Print the violations as text lines or as SARIF 2.1.0
+  -h --help              Display this information
+ Exit status: 0 when every file complies, 1 when some do not, 2 on errors`)
+}
+
+// parseArgs parses the arguments following the check command.
+func parseArgs(args []string) (*options, error) {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+		return nil, nil
+	}
+	if args[0] != "check" {
+		return nil, fmt.Errorf("unknown command '%s'", args[0])
+	}
+	o := &options{}
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := "", "", false
+		switch {
+		case arg == "--":
+			o.files = append(o.files, args[i+1:]...)
+			i = len(args)
+			continue
+		case arg == "-h" || arg == "--help":
+			return nil, nil
+		case strings.HasPrefix(arg, "--"):
+			name = arg[2:]
+			if eq := strings.IndexByte(name, '='); eq >= 0 {
+				name, value, hasValue = name[:eq], name[eq+1:], true
+			}
+		case arg == "-p":
+			name = "policy"
+		case strings.HasPrefix(arg, "-p"):
+			name, value, hasValue = "policy", arg[2:], true
+		case strings.HasPrefix(arg, "-") && arg != "-":
+			return nil, fmt.Errorf("invalid option -- '%s'", arg[1:])
+		default:
+			o.files = append(o.files, arg)
+			continue
+		}
+		if name != "policy" && name != "root" && name != "format" {
+			return nil, fmt.Errorf("unrecognized option '%s'", arg)
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("option '%s' requires an argument", arg)
+			}
+			i++
+			value = args[i]
+		}
+		switch name {
+		case "policy":
+			o.policy = value
+		case "root":
+			o.root = value
+		case "format":
+			if value != "text" && value != "sarif" {
+				return nil, fmt.Errorf("invalid format '%s'", value)
+			}
+			o.sarif = value == "sarif"
+		}
+	}
+	if o.policy == "" {
+		return nil, errors.New("a policy is required")
+	}
+	if len(o.files) == 0 {
+		return nil, errors.New("no files to check")
+	}
+	return o, nil
+}
+
+// isELF reports whether the file starts with the ELF magic.
+func isELF(filename string) bool {
+	f, err := os.Open(filename)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	magic := make([]byte, len(elf.ELFMAG))
+	_, err = io.ReadFull(f, magic)
+	return err == nil && string(magic) == elf.ELFMAG
+}
+
+// collect expands the directories of files to the ELF files they hold,
+// the files named explicitly are always kept.
+func collect(files []string) ([]string, error) {
+	var out []string
+	for _, name := range files {
+		fi, err := os.Stat(name)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			out = append(out, name)
+			continue
+		}
+		err = filepath.Walk(name, func(p string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if fi.Mode().IsRegular() && isELF(p) {
+				out = append(out, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// policyName returns the name the globs are matched against: the path
+// below --root with a leading slash, or the path as given.
+func policyName(o *options, filename string) string {
+	if o.root != "" {
+		if rel, err := filepath.Rel(o.root, filename); err == nil && !strings.HasPrefix(rel, "..") {
+			return "/" + filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(filepath.Clean(filename))
+}
+
+// checkFile returns the violations of one file.
+func checkFile(pol *elf.Policy, name, filename string) ([]elf.PolicyViolation, error) {
+	p, err := elf.New(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer p.CloseFile()
+	if err := p.Parse(); err != nil {
+		return nil, err
+	}
+	return p.CheckPolicy(pol, name)
+}
+
+// violates reports whether some of the violations are applicable to the
+// file.
+func violates(violations []elf.PolicyViolation) bool {
+	for _, v := range violations {
+		if !v.NotApplicable {
+			return true
+		}
+	}
+	return false
+}
+
+func main() {
+	o, err := parseArgs(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gopolicy: %s\n", err)
+		usage(os.Stderr)
+		os.Exit(exitError)
+	}
+	if o == nil {
+		usage(os.Stdout)
+		os.Exit(exitOK)
+	}
+	data, err := ioutil.ReadFile(o.policy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gopolicy: %s\n", err)
+		os.Exit(exitError)
+	}
+	pol, err := elf.ParsePolicy(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gopolicy: '%s': %s\n", o.policy, err)
+		os.Exit(exitError)
+	}
+	files, err := collect(o.files)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gopolicy: %s\n", err)
+		os.Exit(exitError)
+	}
+	status, failed := exitOK, 0
+	var findings []elf.FileFindings
+	for _, filename := range files {
+		violations, err := checkFile(pol, policyName(o, filename), filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gopolicy: '%s': %s\n", filename, err)
+			status = exitError
+			continue
+		}
+		if o.sarif {
+			ff := elf.FileFindings{Path: filename}
+			for _, v := range violations {
+				ff.Findings = append(ff.Findings, v.Finding())
+			}
+			findings = append(findings, ff)
+		} else {
+			for _, v := range violations {
+				fmt.Println(v)
+			}
+		}
+		if violates(violations) {
+			failed++
+			if status == exitOK {
+				status = exitViolation
+			}
+		}
+	}
+	if o.sarif {
+		if err := elf.WriteSARIF(os.Stdout, findings); err != nil {
+			fmt.Fprintf(os.Stderr, "gopolicy: %s\n", err)
+			os.Exit(exitError)
+		}
+	} else {
+		fmt.Printf("%d of %d files violate the policy\n", failed, len(files))
+	}
+	os.Exit(status)
+}
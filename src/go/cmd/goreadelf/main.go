@@ -0,0 +1,748 @@
+// goreadelf displays information about ELF files, it understands the
+// readelf option set so it can stand in for readelf on machines without binutils.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"parser-elf/elf"
+)
+
+// 退出码与readelf一致：0成功，1有文件处理失败，2参数错误
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// options records what has been requested on the command line.
+type options struct {
+	header   bool
+	sections bool
+	segments bool
+	dynamic  bool
+	syms     bool
+	dynSyms  bool
+	relocs   bool
+	notes    bool
+	versions bool
+	arch     bool
+	histo    bool
+	wide     bool
+	got      bool
+	// compat selects the byte-exact readelf layout of elf.WriteReadelf.
+	compat bool
+	// format selects an elf.WriteReport renderer instead of the Dump* output,
+	// formatNDJSON streams the whole file with elf.WriteNDJSON.
+	format string
+	// dumps holds the -x/-R/-p requests in command line order.
+	dumps []dumpRequest
+	// decompress shows compressed sections inflated in the dumps (-z).
+	decompress bool
+	// annotate requests an annotated hexdump, nil when not asked for.
+	annotate *annotateRequest
+	// explain is the language of the explain view, empty when not asked for.
+	explain elf.Lang
+	// layout is the format of the --layout map, empty when not asked for.
+	layout string
+	// htmlReport writes the standalone HTML report of each file.
+	htmlReport bool
+	// hardening writes the checksec style hardening facts.
+	hardening bool
+	// anomalies writes the structural anomalies, parsing the file leniently.
+	anomalies bool
+	// entropy writes the entropy and the packer verdict with windows of
+	// entropyWindow bytes, 0 for the default.
+	entropy       bool
+	entropyWindow int
+	// loadability writes the checks the kernel and ld.so would fail.
+	loadability bool
+	// features writes the machine learning feature vector.
+	features bool
+	// sarif collects the findings of every file into one SARIF log.
+	sarif    bool
+	findings []elf.FileFindings
+	files    []string
+}
+
+const formatNDJSON = "ndjson"
+
+// dumpKind is the kind of a section dump, readelf runs the dumps of a
+// section in this order.
+type dumpKind int
+
+const (
+	dumpHex dumpKind = iota
+	dumpRelocated
+	dumpString
+)
+
+type dumpRequest struct {
+	kind    dumpKind
+	section string
+}
+
+// annotateRequest is a --annotate or --annotate-html range, a size of 0
+// runs to the end of the file.
+type annotateRequest struct {
+	html      bool
+	off, size uint64
+}
+
+// parseRange parses <offset>[+<size>], both numbers may be given in hex.
+func parseRange(s string) (uint64, uint64, error) {
+	var off, size uint64
+	var err error
+	if s == "" {
+		return 0, 0, nil
+	}
+	if plus := strings.IndexByte(s, '+'); plus >= 0 {
+		if size, err = strconv.ParseUint(s[plus+1:], 0, 64); err != nil {
+			return 0, 0, fmt.Errorf("invalid range '%s'", s)
+		}
+		s = s[:plus]
+	}
+	if off, err = strconv.ParseUint(s, 0, 64); err != nil {
+		return 0, 0, fmt.Errorf("invalid range '%s'", s)
+	}
+	return off, size, nil
+}
+
+func (o *options) any() bool {
+	return o.header || o.sections || o.segments || o.dynamic || o.syms || o.dynSyms ||
+		o.relocs || o.notes || o.versions || o.arch || o.histo || o.got || len(o.dumps) != 0 ||
+		o.format == formatNDJSON || o.annotate != nil || o.explain != "" || o.layout != "" || o.htmlReport ||
+		o.hardening || o.anomalies || o.loadability || o.entropy || o.features || o.sarif
+}
+
+// checkStandalone rejects --html-report and --sarif next to other options,
+// they write a whole document the other outputs cannot be mixed into. The
+// section dumps have no --format rendering and are rejected next to it too.
+func (o *options) checkStandalone() error {
+	if o.format != "" && len(o.dumps) != 0 {
+		for c, kind := range dumpShortOptions {
+			if kind == o.dumps[0].kind {
+				return fmt.Errorf("option '-%c' cannot be combined with --format", c)
+			}
+		}
+	}
+	var set []string
+	if o.htmlReport {
+		set = append(set, "--html-report")
+	}
+	if o.sarif {
+		set = append(set, "--sarif")
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	rest := *o
+	rest.htmlReport, rest.sarif = false, false
+	if len(set) > 1 || rest.any() || rest.format != "" || rest.wide || rest.compat || rest.decompress {
+		return fmt.Errorf("option '%s' cannot be combined with other options", set[0])
+	}
+	return nil
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, `Usage: goreadelf <option(s)> elf-file(s)
+ Display information about the contents of ELF format files
+ Options are:
+  -a --all               Equivalent to: -h -l -S -s -r -d -V -A -I -n
+  -h --file-header       Display the ELF file header
+  -l --program-headers   Display the program headers
+     --segments          An alias for --program-headers
+  -S --section-headers   Display the sections' header
+     --sections          An alias for --section-headers
+  -s --syms              Display the symbol table
+     --symbols           An alias for --syms
+     --dyn-syms          Display the dynamic symbol table
+  -n --notes             Display the core notes (if present)
+  -r --relocs            Display the relocations (if present)
+  -d --dynamic           Display the dynamic section (if present)
+  -V --version-info      Display the version sections (if present)
+  -A --arch-specific     Display architecture specific information (if any)
+  -x --hex-dump=<number|name>
+                         Dump the contents of section <number|name> as bytes
+  -p --string-dump=<number|name>
+                         Dump the contents of section <number|name> as strings
+  -R --relocated-dump=<number|name>
+                         Dump the relocated contents of section <number|name>
+  -z --decompress        Decompress section before dumping it
+  -I --histogram         Display histogram of bucket list lengths
+  -W --wide              Allow output width to exceed 80 characters
+     --got               Display the .got and .got.plt entries (with --format)
+     --compat            Print -h -S -l -d -s -r -n -V exactly like GNU readelf
+     --format=<text|json|yaml|csv|markdown>
+                         Render -h -S -l -d -s -r and --got in the given format,
+                         cannot be combined with -x -p -R
+     --format=ndjson     Stream every record of the file as one JSON object per line
+     --annotate[=<offset>[+<size>]]
+                         Dump the bytes with the ELF field each one belongs to
+     --annotate-html[=<offset>[+<size>]]
+                         Like --annotate, as an HTML page
+     --explain[=<en|zh>] Explain every header field, section type, segment type,
+                         dynamic tag and symbol attribute (with --format)
+     --layout[=<text|svg|json>]
+                         Display the map of the file and of its PT_LOAD segments
+     --html-report       Write a self-contained HTML report of the file, cannot
+                         be combined with other options
+     --hardening         Display the RELRO, NX, PIE, canary, FORTIFY, RPATH and
+                         CET/BTI facts (--format=json writes the typed report)
+     --anomalies         Display the structural anomalies of malformed files,
+                         parsing what can be parsed of them
+     --entropy[=<window>]
+                         Display the entropy of the sections, segments and
+                         windows of the file and whether it is packed
+     --loadability       Display the checks execve and ld.so would fail on the
+                         file and why
+     --features          Display the feature vector of the malware classifiers
+                         (--format=json writes the named features)
+     --sarif             Write the security findings of the files as SARIF 2.1.0,
+                         cannot be combined with other options
+  -H --help              Display this information`)
+}
+
+// dumpOptions and dumpShortOptions map the dump options to their kind.
+var (
+	dumpOptions = map[string]dumpKind{
+		"hex-dump":       dumpHex,
+		"relocated-dump": dumpRelocated,
+		"string-dump":    dumpString,
+	}
+	dumpShortOptions = map[byte]dumpKind{'x': dumpHex, 'R': dumpRelocated, 'p': dumpString}
+)
+
+// parseArgs parses readelf style arguments: grouped short options (-lW),
+// short options taking a value (-x .text / -x.text) and long options
+// (--hex-dump=.text / --hex-dump .text).
+func parseArgs(args []string) (*options, error) {
+	o := &options{}
+	setAll := func() {
+		o.header, o.segments, o.sections, o.syms = true, true, true, true
+		o.relocs, o.dynamic, o.versions, o.arch, o.histo, o.notes = true, true, true, true, true, true
+	}
+	long := map[string]func(){
+		"all":             setAll,
+		"file-header":     func() { o.header = true },
+		"program-headers": func() { o.segments = true },
+		"segments":        func() { o.segments = true },
+		"section-headers": func() { o.sections = true },
+		"sections":        func() { o.sections = true },
+		"headers":         func() { o.header, o.segments, o.sections = true, true, true },
+		"syms":            func() { o.syms = true },
+		"symbols":         func() { o.syms = true },
+		"dyn-syms":        func() { o.dynSyms = true },
+		"notes":           func() { o.notes = true },
+		"relocs":          func() { o.relocs = true },
+		"dynamic":         func() { o.dynamic = true },
+		"version-info":    func() { o.versions = true },
+		"arch-specific":   func() { o.arch = true },
+		"histogram":       func() { o.histo = true },
+		"wide":            func() { o.wide = true },
+		"compat":          func() { o.compat = true },
+		"got":             func() { o.got = true },
+		"html-report":     func() { o.htmlReport = true },
+		"hardening":       func() { o.hardening = true },
+		"anomalies":       func() { o.anomalies = true },
+		"loadability":     func() { o.loadability = true },
+		"features":        func() { o.features = true },
+		"sarif":           func() { o.sarif = true },
+		"decompress":      func() { o.decompress = true },
+	}
+	short := map[byte]func(){
+		'a': setAll,
+		'h': func() { o.header = true },
+		'l': func() { o.segments = true },
+		'S': func() { o.sections = true },
+		'e': func() { o.header, o.segments, o.sections = true, true, true },
+		's': func() { o.syms = true },
+		'n': func() { o.notes = true },
+		'r': func() { o.relocs = true },
+		'd': func() { o.dynamic = true },
+		'V': func() { o.versions = true },
+		'A': func() { o.arch = true },
+		'I': func() { o.histo = true },
+		'W': func() { o.wide = true },
+		'z': func() { o.decompress = true },
+	}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			o.files = append(o.files, args[i+1:]...)
+			return o, nil
+		case strings.HasPrefix(arg, "--"):
+			name, value, hasValue := arg[2:], "", false
+			if eq := strings.IndexByte(name, '='); eq >= 0 {
+				name, value, hasValue = name[:eq], name[eq+1:], true
+			}
+			if kind, ok := dumpOptions[name]; ok {
+				if !hasValue {
+					if i+1 >= len(args) {
+						return nil, fmt.Errorf("option '--%s' requires an argument", name)
+					}
+					i++
+					value = args[i]
+				}
+				o.dumps = append(o.dumps, dumpRequest{kind: kind, section: value})
+				continue
+			}
+			if name == "format" {
+				if !hasValue {
+					if i+1 >= len(args) {
+						return nil, fmt.Errorf("option '--%s' requires an argument", name)
+					}
+					i++
+					value = args[i]
+				}
+				if value != formatNDJSON {
+					if _, err := elf.NewRenderer(elf.Format(value)); err != nil {
+						return nil, err
+					}
+				}
+				o.format = value
+				continue
+			}
+			if name == "annotate" || name == "annotate-html" {
+				// 范围只能用=给出，否则无法与文件名区分
+				off, size, err := parseRange(value)
+				if err != nil {
+					return nil, err
+				}
+				o.annotate = &annotateRequest{html: name == "annotate-html", off: off, size: size}
+				continue
+			}
+			if name == "explain" {
+				lang := elf.Lang(value)
+				if !hasValue {
+					lang = elf.LangEN
+				}
+				if lang != elf.LangEN && lang != elf.LangZH {
+					return nil, fmt.Errorf("invalid language '%s' for --explain", value)
+				}
+				o.explain = lang
+				continue
+			}
+			if name == "layout" {
+				if !hasValue {
+					value = "text"
+				}
+				if value != "text" && value != "svg" && value != "json" {
+					return nil, fmt.Errorf("invalid format '%s' for --layout", value)
+				}
+				o.layout = value
+				continue
+			}
+			if name == "entropy" {
+				if hasValue {
+					window, err := strconv.Atoi(value)
+					if err != nil || window < 2 {
+						return nil, fmt.Errorf("invalid window '%s' for --entropy", value)
+					}
+					o.entropyWindow = window
+				}
+				o.entropy = true
+				continue
+			}
+			if name == "help" {
+				return nil, nil
+			}
+			set, ok := long[name]
+			if !ok || hasValue {
+				return nil, fmt.Errorf("unrecognized option '%s'", arg)
+			}
+			set()
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			for j := 1; j < len(arg); j++ {
+				c := arg[j]
+				if kind, ok := dumpShortOptions[c]; ok {
+					value := arg[j+1:]
+					if value == "" {
+						if i+1 >= len(args) {
+							return nil, fmt.Errorf("option requires an argument -- '%c'", c)
+						}
+						i++
+						value = args[i]
+					}
+					o.dumps = append(o.dumps, dumpRequest{kind: kind, section: value})
+					break
+				}
+				if c == 'H' {
+					return nil, nil
+				}
+				set, ok := short[c]
+				if !ok {
+					return nil, fmt.Errorf("invalid option -- '%c'", c)
+				}
+				set()
+			}
+		default:
+			o.files = append(o.files, arg)
+		}
+	}
+	return o, nil
+}
+
+// dumpFile runs the requested dumps on one file.
+func dumpFile(o *options, filename string, multiple bool) error {
+	p, err := elf.New(filename)
+	if err != nil {
+		return err
+	}
+	defer p.CloseFile()
+	// 畸形文件也要能报告异常，解析错误记录在ParseErrors中
+	p.Lenient = o.anomalies || o.loadability || o.sarif
+	if err := p.Parse(); err != nil {
+		return err
+	}
+	if o.htmlReport {
+		return p.WriteHTMLReport(os.Stdout, filename)
+	}
+	if o.sarif {
+		// 所有文件的结果写进同一个SARIF日志，由main最后输出
+		findings, err := p.Findings()
+		if err != nil {
+			return err
+		}
+		o.findings = append(o.findings, elf.FileFindings{Path: filename, Findings: findings})
+		return nil
+	}
+	if o.format != "" {
+		if err := writeReport(o, p, filename, multiple); err != nil {
+			return err
+		}
+		if err := writeExplain(o, p); err != nil {
+			return err
+		}
+		if err := writeHardening(o, p); err != nil {
+			return err
+		}
+		if err := writeAnomalies(o, p); err != nil {
+			return err
+		}
+		if err := writeLoadability(o, p); err != nil {
+			return err
+		}
+		if err := writeEntropy(o, p); err != nil {
+			return err
+		}
+		if err := writeFeatures(o, p); err != nil {
+			return err
+		}
+		if err := writeLayout(o, p); err != nil {
+			return err
+		}
+		return writeAnnotated(o, p)
+	}
+	if multiple {
+		fmt.Printf("\nFile: %s\n", filename)
+	}
+	if o.compat {
+		err = p.WriteReadelf(os.Stdout, elf.ReadelfOptions{
+			FileHeader:     o.header,
+			SectionHeaders: o.sections,
+			ProgramHeaders: o.segments,
+			Dynamic:        o.dynamic,
+			Symbols:        o.syms,
+			DynSyms:        o.dynSyms,
+			Relocs:         o.relocs,
+			Notes:          o.notes,
+			VersionInfo:    o.versions,
+			Wide:           o.wide,
+		})
+		if err != nil {
+			return err
+		}
+		// 兼容模式只覆盖上面这些视图，其余的仍交给原来的Dump函数
+		o = &options{histo: o.histo, arch: o.arch, dumps: o.dumps, decompress: o.decompress}
+	}
+	if o.header {
+		p.DumpHeaderIndent()
+		p.DumpHeaderWithoutIndent()
+	}
+	if o.sections {
+		if err := p.DumpSectionHeaders(); err != nil {
+			return err
+		}
+	}
+	if o.segments {
+		p.DumpProgramHeaders()
+	}
+	if o.dynamic {
+		p.DumpDynamicSection()
+	}
+	if o.relocs {
+		p.DumpRelocations()
+	}
+	if o.syms || o.dynSyms {
+		p.DumpSymbols(!o.syms)
+	}
+	if o.histo {
+		p.DumpHistogram()
+	}
+	if o.versions {
+		p.DumpVersionInfo()
+	}
+	if o.arch {
+		p.DumpArchSpecific()
+	}
+	if o.notes {
+		p.DumpNotes()
+	}
+	if err := writeDumps(o, p); err != nil {
+		return err
+	}
+	if err := writeExplain(o, p); err != nil {
+		return err
+	}
+	if err := writeHardening(o, p); err != nil {
+		return err
+	}
+	if err := writeAnomalies(o, p); err != nil {
+		return err
+	}
+	if err := writeLoadability(o, p); err != nil {
+		return err
+	}
+	if err := writeEntropy(o, p); err != nil {
+		return err
+	}
+	if err := writeFeatures(o, p); err != nil {
+		return err
+	}
+	if err := writeLayout(o, p); err != nil {
+		return err
+	}
+	return writeAnnotated(o, p)
+}
+
+// writeDumps runs the -x/-R/-p requests. Like readelf they run in section
+// order, whatever the order on the command line, and a section asked for
+// twice with the same kind is dumped once.
+func writeDumps(o *options, p *elf.Parser) error {
+	type dump struct {
+		section int
+		kind    dumpKind
+	}
+	var dumps []dump
+	seen := map[dump]bool{}
+	for _, d := range o.dumps {
+		i, err := p.LookupSection(d.section)
+		if err != nil {
+			// readelf只对缺失的节给出警告，并不影响退出码
+			fmt.Fprintf(os.Stderr, "goreadelf: Warning: %s\n", err)
+			continue
+		}
+		if k := (dump{i, d.kind}); !seen[k] {
+			seen[k] = true
+			dumps = append(dumps, k)
+		}
+	}
+	sort.Slice(dumps, func(a, b int) bool {
+		if dumps[a].section != dumps[b].section {
+			return dumps[a].section < dumps[b].section
+		}
+		return dumps[a].kind < dumps[b].kind
+	})
+	for _, d := range dumps {
+		opts := elf.DumpOptions{Relocate: d.kind == dumpRelocated, Decompress: o.decompress}
+		var err error
+		if d.kind == dumpString {
+			err = p.WriteStringDump(os.Stdout, d.section, opts)
+		} else {
+			err = p.WriteHexDump(os.Stdout, d.section, opts)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeLayout writes the --layout map.
+func writeLayout(o *options, p *elf.Parser) error {
+	if o.layout == "" {
+		return nil
+	}
+	l, err := p.Layout()
+	if err != nil {
+		return err
+	}
+	switch o.layout {
+	case "svg":
+		return l.WriteSVG(os.Stdout)
+	case "json":
+		return l.WriteJSON(os.Stdout)
+	}
+	return l.WriteText(os.Stdout)
+}
+
+// writeExplain writes the --explain view with the --format renderer, NDJSON
+// has no table form so the view is written as JSON instead.
+func writeExplain(o *options, p *elf.Parser) error {
+	if o.explain == "" {
+		return nil
+	}
+	format := elf.Format(o.format)
+	if o.format == formatNDJSON {
+		format = elf.FormatJSON
+	}
+	return p.WriteExplain(os.Stdout, format, o.explain)
+}
+
+// writeHardening writes the --hardening facts with the --format renderer,
+// JSON and NDJSON get the typed report.
+func writeHardening(o *options, p *elf.Parser) error {
+	if !o.hardening {
+		return nil
+	}
+	format := elf.Format(o.format)
+	if o.format == formatNDJSON {
+		format = elf.FormatJSON
+	}
+	return p.WriteHardening(os.Stdout, format)
+}
+
+// writeAnomalies writes the --anomalies findings with the --format renderer,
+// JSON and NDJSON get the typed findings.
+func writeAnomalies(o *options, p *elf.Parser) error {
+	if !o.anomalies {
+		return nil
+	}
+	format := elf.Format(o.format)
+	if o.format == formatNDJSON {
+		format = elf.FormatJSON
+	}
+	return p.WriteAnomalies(os.Stdout, format)
+}
+
+// writeLoadability writes the --loadability findings with the --format
+// renderer, JSON and NDJSON get the typed findings.
+func writeLoadability(o *options, p *elf.Parser) error {
+	if !o.loadability {
+		return nil
+	}
+	format := elf.Format(o.format)
+	if o.format == formatNDJSON {
+		format = elf.FormatJSON
+	}
+	return p.WriteLoadability(os.Stdout, format)
+}
+
+// writeEntropy writes the --entropy view with the --format renderer, JSON
+// and NDJSON get the typed reports.
+func writeEntropy(o *options, p *elf.Parser) error {
+	if !o.entropy {
+		return nil
+	}
+	format := elf.Format(o.format)
+	if o.format == formatNDJSON {
+		format = elf.FormatJSON
+	}
+	return p.WriteEntropy(os.Stdout, format, o.entropyWindow)
+}
+
+// writeFeatures writes the --features vector with the --format renderer,
+// JSON and NDJSON get the named features.
+func writeFeatures(o *options, p *elf.Parser) error {
+	if !o.features {
+		return nil
+	}
+	format := elf.Format(o.format)
+	if o.format == formatNDJSON {
+		format = elf.FormatJSON
+	}
+	return p.WriteFeatures(os.Stdout, format)
+}
+
+// writeAnnotated writes the --annotate or --annotate-html dump.
+func writeAnnotated(o *options, p *elf.Parser) error {
+	switch {
+	case o.annotate == nil:
+		return nil
+	case o.annotate.html:
+		return p.WriteAnnotatedHTML(os.Stdout, o.annotate.off, o.annotate.size)
+	}
+	return p.WriteAnnotatedHexDump(os.Stdout, o.annotate.off, o.annotate.size)
+}
+
+// writeReport renders the requested views with the --format renderer, each
+// file gets its own document when several files are given.
+func writeReport(o *options, p *elf.Parser, filename string, multiple bool) error {
+	// NDJSON总是输出全部记录，每行自带类型，多个文件直接首尾相接
+	if o.format == formatNDJSON {
+		return p.WriteNDJSON(os.Stdout)
+	}
+	var kinds []elf.ViewKind
+	for _, v := range []struct {
+		set  bool
+		kind elf.ViewKind
+	}{
+		{o.header, elf.ViewHeader},
+		{o.sections, elf.ViewSections},
+		{o.segments, elf.ViewSegments},
+		{o.dynamic, elf.ViewDynamic},
+		{o.syms, elf.ViewSymbols},
+		{o.dynSyms && !o.syms, elf.ViewDynSyms},
+		{o.relocs, elf.ViewRelocations},
+		{o.got, elf.ViewGOT},
+	} {
+		if v.set {
+			kinds = append(kinds, v.kind)
+		}
+	}
+	if len(kinds) == 0 {
+		return nil
+	}
+	if multiple {
+		switch elf.Format(o.format) {
+		case elf.FormatText:
+			fmt.Printf("\nFile: %s\n", filename)
+		case elf.FormatMarkdown:
+			fmt.Printf("# %s\n\n", filename)
+		case elf.FormatYAML:
+			fmt.Println("---")
+		}
+	}
+	return p.WriteReport(os.Stdout, elf.Format(o.format), kinds...)
+}
+
+func main() {
+	o, err := parseArgs(os.Args[1:])
+	if err == nil && o != nil {
+		err = o.checkStandalone()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goreadelf: %s\n", err)
+		usage(os.Stderr)
+		os.Exit(exitUsage)
+	}
+	if o == nil {
+		usage(os.Stdout)
+		os.Exit(exitOK)
+	}
+	if !o.any() || len(o.files) == 0 {
+		usage(os.Stderr)
+		os.Exit(exitUsage)
+	}
+	status := exitOK
+	for _, filename := range o.files {
+		if err := dumpFile(o, filename, len(o.files) > 1); err != nil {
+			fmt.Fprintf(os.Stderr, "goreadelf: Error: '%s': %s\n", filename, err)
+			status = exitError
+		}
+	}
+	if o.sarif {
+		if err := elf.WriteSARIF(os.Stdout, o.findings); err != nil {
+			fmt.Fprintf(os.Stderr, "goreadelf: Error: %s\n", err)
+			status = exitError
+		}
+	}
+	os.Exit(status)
+}
@@ -0,0 +1,129 @@
+// goupx unpacks ELF executables packed by UPX, like upx -d, without the
+// upx tool. Tampered UPX headers are repaired when possible.
+//
+//	goupx -d -o out-file in-file
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"parser-elf/elf"
+)
+
+// 退出码：0成功，1失败或参数错误
+const (
+	exitOK    = 0
+	exitError = 1
+)
+
+type options struct {
+	output string
+	file   string
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, `Usage: goupx -d -o out-file in-file
+ Unpacks a UPX packed ELF executable (NRV2B, NRV2D, NRV2E and LZMA)
+ The options are:
+  -d --decompress        Unpack in-file, the only supported command
+  -o --output <file>     Write the unpacked file to <file>, - for the standard output
+  -h --help              Display this information
+ The repairs made to tampered headers are printed on the standard error`)
+}
+
+// parseArgs parses upx style arguments, -o may be attached (-oout) or
+// separate.
+func parseArgs(args []string) (*options, error) {
+	o := &options{}
+	decompress := false
+	var files []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			files = append(files, args[i+1:]...)
+			i = len(args)
+		case arg == "-h" || arg == "--help":
+			return nil, nil
+		case arg == "-d" || arg == "--decompress":
+			decompress = true
+		case arg == "-o" || arg == "--output":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("option '%s' requires an argument", arg)
+			}
+			i++
+			o.output = args[i]
+		case strings.HasPrefix(arg, "--output="):
+			o.output = arg[len("--output="):]
+		case strings.HasPrefix(arg, "-o"):
+			o.output = arg[2:]
+		case strings.HasPrefix(arg, "-") && arg != "-":
+			return nil, fmt.Errorf("invalid option -- '%s'", strings.TrimLeft(arg, "-"))
+		default:
+			files = append(files, arg)
+		}
+	}
+	if !decompress {
+		return nil, errors.New("only -d is supported")
+	}
+	if o.output == "" {
+		return nil, errors.New("an output file is required")
+	}
+	if len(files) != 1 {
+		return nil, errors.New("exactly one input file is required")
+	}
+	o.file = files[0]
+	return o, nil
+}
+
+// unpack unpacks o.file to o.output.
+func unpack(o *options) error {
+	p, err := elf.New(o.file)
+	if err != nil {
+		return err
+	}
+	defer p.CloseFile()
+	p.Lenient = true
+	if err := p.Parse(); err != nil {
+		return err
+	}
+	res, err := p.UnpackUPX()
+	if err != nil {
+		return err
+	}
+	for _, n := range res.Notes {
+		fmt.Fprintf(os.Stderr, "goupx: '%s': %s\n", o.file, n)
+	}
+	if o.output == "-" {
+		_, err = os.Stdout.Write(res.Data)
+		return err
+	}
+	if err := ioutil.WriteFile(o.output, res.Data, 0755); err != nil {
+		return err
+	}
+	fmt.Printf("Unpacked '%s' (%s, %s, %d blocks) to '%s', %d bytes\n",
+		o.file, res.Format, strings.Join(res.Methods, " "), res.Blocks, o.output, len(res.Data))
+	return nil
+}
+
+func main() {
+	o, err := parseArgs(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goupx: %s\n", err)
+		usage(os.Stderr)
+		os.Exit(exitError)
+	}
+	if o == nil {
+		usage(os.Stdout)
+		os.Exit(exitOK)
+	}
+	if err := unpack(o); err != nil {
+		fmt.Fprintf(os.Stderr, "goupx: '%s': %s\n", o.file, err)
+		os.Exit(exitError)
+	}
+}
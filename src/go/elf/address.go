@@ -0,0 +1,212 @@
+// Package elf : address.go implements the translation between virtual
+// addresses and file offsets through the program and section headers.
+package elf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// mapping is a virtual range backed (partially) by a file range, it is
+// either a PT_LOAD segment or, for files without program headers, an
+// allocated section.
+type mapping struct {
+	vaddr, memsz uint64
+	off, filesz  uint64
+}
+
+// mappings returns the virtual memory image of the file.
+// 可执行文件与共享库按PT_LOAD映射；没有程序头的文件（如.o）按SHF_ALLOC节映射
+func (f *File) mappings() []mapping {
+	var maps []mapping
+	for _, ph := range f.ProgramHeaders() {
+		if ProgType(ph.Type) != PT_LOAD {
+			continue
+		}
+		maps = append(maps, mapping{ph.Vaddr, ph.Memsz, ph.Off, ph.Filesz})
+	}
+	if len(f.ProgramHeaders()) != 0 {
+		return maps
+	}
+	for _, s := range f.Sections() {
+		if s.Flags&uint64(SHF_ALLOC) == 0 || s.Flags&uint64(SHF_TLS) != 0 {
+			continue
+		}
+		filesz := s.ELF64SectionHeader.Size
+		if SectionType(s.Type) == SHT_NOBITS {
+			filesz = 0
+		}
+		maps = append(maps, mapping{s.Addr, s.ELF64SectionHeader.Size, s.Off, filesz})
+	}
+	return maps
+}
+
+// lookup returns the mapping covering vaddr, overlapping segments are
+// resolved like the loader does: a later mapping replaces an earlier one.
+func (f *File) lookup(vaddr uint64) (mapping, bool) {
+	maps := f.mappings()
+	for i := len(maps) - 1; i >= 0; i-- {
+		m := maps[i]
+		if m.vaddr <= vaddr && vaddr-m.vaddr < m.memsz {
+			return m, true
+		}
+	}
+	return mapping{}, false
+}
+
+// SegmentForVaddr returns the PT_LOAD program header mapping vaddr.
+// PT_TLS is never returned, its template lives inside a PT_LOAD and the
+// .tbss part has no address of its own in the process image.
+func (f *File) SegmentForVaddr(vaddr uint64) (*ELF64ProgramHeader, error) {
+	progs := f.ProgramHeaders()
+	for i := len(progs) - 1; i >= 0; i-- {
+		ph := progs[i]
+		if ProgType(ph.Type) != PT_LOAD {
+			continue
+		}
+		if ph.Vaddr <= vaddr && vaddr-ph.Vaddr < ph.Memsz {
+			return &ph, nil
+		}
+	}
+	return nil, fmt.Errorf("vaddr %#x: %w", vaddr, ErrUnmapped)
+}
+
+// SectionForVaddr returns the allocated section containing vaddr.
+// .tbss is skipped because it overlaps the sections that follow it.
+func (f *File) SectionForVaddr(vaddr uint64) (*ELF64Section, error) {
+	for _, s := range f.Sections() {
+		if s.Flags&uint64(SHF_ALLOC) == 0 {
+			continue
+		}
+		if s.Flags&uint64(SHF_TLS) != 0 && SectionType(s.Type) == SHT_NOBITS {
+			continue
+		}
+		size := s.ELF64SectionHeader.Size
+		if s.Addr <= vaddr && vaddr-s.Addr < size {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("vaddr %#x: no section: %w", vaddr, ErrUnmapped)
+}
+
+// SectionForOffset returns the section whose file bytes contain off,
+// SHT_NOBITS and empty sections occupy no bytes and are skipped.
+func (f *File) SectionForOffset(off uint64) (*ELF64Section, error) {
+	for i, s := range f.Sections() {
+		if i == 0 || SectionType(s.Type) == SHT_NOBITS {
+			continue
+		}
+		if s.Off <= off && off-s.Off < s.ELF64SectionHeader.Size {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("offset %#x: no section", off)
+}
+
+// OffsetForVaddr translates a virtual address into a file offset.
+func (f *File) OffsetForVaddr(vaddr uint64) (uint64, error) {
+	m, ok := f.lookup(vaddr)
+	if !ok {
+		return 0, fmt.Errorf("vaddr %#x: %w", vaddr, ErrUnmapped)
+	}
+	delta := vaddr - m.vaddr
+	if delta >= m.filesz {
+		// 落在p_filesz与p_memsz之间，即.bss这类只在内存中存在的区域
+		return 0, fmt.Errorf("vaddr %#x: %w", vaddr, ErrNotInFile)
+	}
+	return m.off + delta, nil
+}
+
+// VaddrForOffset translates a file offset into the virtual address it is
+// loaded at, the first mapping containing the offset wins.
+func (f *File) VaddrForOffset(off uint64) (uint64, error) {
+	for _, m := range f.mappings() {
+		if m.off <= off && off-m.off < m.filesz {
+			return m.vaddr + off - m.off, nil
+		}
+	}
+	return 0, fmt.Errorf("offset %#x: not loaded by any segment: %w", off, ErrUnmapped)
+}
+
+// ReadVirtual reads n bytes of the process image starting at vaddr.
+// Bytes past p_filesz are zero-filled like the loader does for .bss, the
+// range may span contiguous segments but not unmapped holes.
+func (f *File) ReadVirtual(vaddr uint64, n int) ([]byte, error) {
+	if f.r == nil {
+		return nil, errors.New("file has no backing reader")
+	}
+	if n < 0 {
+		return nil, errors.New("negative read size")
+	}
+	// n可能来自文件中未经校验的字段，先确认整个范围都有映射再分配
+	chunks, err := f.virtualChunks(vaddr, uint64(n))
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, n)
+	done := uint64(0)
+	for _, c := range chunks {
+		if c.fileSize != 0 {
+			read, err := f.r.ReadAt(data[done:done+c.fileSize], int64(c.off))
+			if err != nil && !(err == io.EOF && uint64(read) == c.fileSize) {
+				return nil, fmt.Errorf("vaddr %#x: %w", vaddr+done, err)
+			}
+		}
+		// 剩余部分保持为0
+		done += c.size
+	}
+	return data, nil
+}
+
+// virtualChunk is the part of a virtual range inside one mapping, its first
+// fileSize bytes are read from off and the rest is zero-filled.
+type virtualChunk struct {
+	size, fileSize, off uint64
+}
+
+// virtualChunks splits the n bytes at vaddr into the mappings holding them.
+// It fails when a byte is unmapped or when file backed bytes lie past the
+// end of the file.
+func (f *File) virtualChunks(vaddr, n uint64) ([]virtualChunk, error) {
+	var chunks []virtualChunk
+	for done := uint64(0); done < n; {
+		addr := vaddr + done
+		m, ok := f.lookup(addr)
+		if !ok {
+			return nil, fmt.Errorf("vaddr %#x: %w", addr, ErrUnmapped)
+		}
+		delta := addr - m.vaddr
+		c := virtualChunk{size: m.memsz - delta}
+		if n-done < c.size {
+			c.size = n - done
+		}
+		if delta < m.filesz {
+			c.fileSize = m.filesz - delta
+			if c.fileSize > c.size {
+				c.fileSize = c.size
+			}
+			c.off = m.off + delta
+			if c.off < m.off || c.off > uint64(f.size) || c.fileSize > uint64(f.size)-c.off {
+				return nil, fmt.Errorf("vaddr %#x: segment contents past the end of the file", addr)
+			}
+		}
+		chunks = append(chunks, c)
+		done += c.size
+	}
+	return chunks, nil
+}
+
+// fileImage returns the file backed bytes from vaddr up to the end of the
+// file image of its mapping.
+func (f *File) fileImage(vaddr uint64) ([]byte, error) {
+	m, ok := f.lookup(vaddr)
+	if !ok {
+		return nil, fmt.Errorf("vaddr %#x: %w", vaddr, ErrUnmapped)
+	}
+	delta := vaddr - m.vaddr
+	if delta >= m.filesz {
+		return nil, fmt.Errorf("vaddr %#x: %w", vaddr, ErrNotInFile)
+	}
+	return f.ReadVirtual(vaddr, int(m.filesz-delta))
+}
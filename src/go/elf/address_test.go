@@ -0,0 +1,65 @@
+package elf
+
+import (
+	"errors"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddressTranslation(t *testing.T) {
+	p, err := New(path.Join(exampleDir, "gcc-amd64-linux-exec"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.CloseFile()
+	if err := p.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	f := p.F
+
+	off, err := f.OffsetForVaddr(0x4003e0)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0x3e0, off, ".text entry point")
+
+	vaddr, err := f.VaddrForOffset(0x880)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0x600880, vaddr, ".data")
+
+	sec, err := f.SectionForVaddr(0x600898)
+	assert.NoError(t, err)
+	assert.Equal(t, ".bss", sec.SectionName)
+
+	sec, err = f.SectionForOffset(0x3e0)
+	assert.NoError(t, err)
+	assert.Equal(t, ".text", sec.SectionName)
+	_, err = f.SectionForOffset(0x40)
+	assert.Error(t, err, "program headers")
+
+	seg, err := f.SegmentForVaddr(0x600898)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0x600688, seg.Vaddr)
+
+	_, err = f.OffsetForVaddr(0x60089c)
+	assert.True(t, errors.Is(err, ErrNotInFile), ".bss has no file bytes")
+	_, err = f.OffsetForVaddr(0x1000)
+	assert.True(t, errors.Is(err, ErrUnmapped))
+	_, err = f.ReadVirtual(0x6008a0-4, 8)
+	assert.True(t, errors.Is(err, ErrUnmapped), "read past the end of the RW segment")
+	text := f.SectionByName(".text")
+	// 长度在分配之前就对照映射检查
+	_, err = f.ReadVirtual(text.Addr, 0x2700000000000018)
+	assert.True(t, errors.Is(err, ErrUnmapped), "read larger than the address space")
+
+	want, err := text.Data()
+	assert.NoError(t, err)
+	got, err := f.ReadVirtual(text.Addr, len(want))
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	// .data followed by the zero-filled .bss
+	data, err := f.ReadVirtual(0x600880, 0x20)
+	assert.NoError(t, err)
+	assert.Equal(t, make([]byte, 8), data[0x18:])
+}
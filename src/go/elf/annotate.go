@@ -0,0 +1,651 @@
+// Package elf : annotate.go implements an annotated hexdump. Every byte of
+// the ELF header, of the program and section headers, of the symbol,
+// dynamic and relocation tables and of the notes is labelled with the field
+// it belongs to and its decoded value, in text or in HTML.
+package elf
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AnnotatedField is one field of an annotated structure.
+type AnnotatedField struct {
+	Name   string // Field name, e.g. e_phoff or st_info.
+	Offset uint64 // File offset of the field.
+	Size   uint64
+	Value  string // Decoded value.
+}
+
+// AnnotatedStruct is one ELF structure of the file and its fields.
+type AnnotatedStruct struct {
+	Name   string // Structure name, e.g. Elf64_Shdr[3].
+	Label  string // What the structure describes, e.g. the section name.
+	Offset uint64
+	Size   uint64
+	Fields []AnnotatedField
+}
+
+// ErrBadRange is returned when the range of an annotated dump is not
+// inside the file.
+var ErrBadRange = errors.New("range is outside the file")
+
+// fieldSpec describes a field of a structure, size 0 stands for the word
+// size of the class (Elf32_Addr/Elf64_Addr and friends).
+type fieldSpec struct {
+	name   string
+	size   uint64
+	format func(v uint64) string
+}
+
+// annotator collects the structures overlapping [lo, hi).
+type annotator struct {
+	p       *Parser
+	lo, hi  uint64
+	word    uint64
+	structs []AnnotatedStruct
+}
+
+func (a *annotator) overlaps(off, size uint64) bool {
+	return off < a.hi && off+size > a.lo
+}
+
+func (a *annotator) read(off, size uint64) []byte {
+	b := make([]byte, size)
+	n, _ := a.p.F.r.ReadAt(b, int64(off))
+	return b[:n]
+}
+
+// uint decodes an integer field of 1, 2, 4 or 8 bytes.
+func (a *annotator) uint(b []byte) uint64 {
+	bo := a.p.F.ByteOrder()
+	switch len(b) {
+	case 1:
+		return uint64(b[0])
+	case 2:
+		return uint64(bo.Uint16(b))
+	case 4:
+		return uint64(bo.Uint32(b))
+	case 8:
+		return bo.Uint64(b)
+	}
+	return 0
+}
+
+// add decodes the structure at off following specs, the fields are laid
+// out one after the other.
+func (a *annotator) add(name, label string, off uint64, specs []fieldSpec) {
+	size := uint64(0)
+	for _, f := range specs {
+		if f.size == 0 {
+			size += a.word
+		} else {
+			size += f.size
+		}
+	}
+	data := a.read(off, size)
+	values := make([]uint64, len(specs))
+	s := AnnotatedStruct{Name: name, Label: label, Offset: off, Size: size}
+	pos := uint64(0)
+	for i, f := range specs {
+		n := f.size
+		if n == 0 {
+			n = a.word
+		}
+		if pos+n > uint64(len(data)) {
+			break
+		}
+		values[i] = a.uint(data[pos : pos+n])
+		s.Fields = append(s.Fields, AnnotatedField{Name: f.name, Offset: off + pos, Size: n})
+		pos += n
+	}
+	// 值的格式化可能依赖同一结构的其他字段，所以放在解码之后
+	for i := range s.Fields {
+		if specs[i].format != nil {
+			s.Fields[i].Value = specs[i].format(values[i])
+		}
+	}
+	if a.overlaps(off, size) {
+		a.structs = append(a.structs, s)
+	}
+}
+
+func annotateHex(v uint64) string { return hexString(v) }
+
+func annotateDec(v uint64) string { return decString(v) }
+
+// annotateEnum names v from a stringer table, unknown values stay numeric.
+func annotateEnum(names []flagName) func(uint64) string {
+	return func(v uint64) string {
+		for _, n := range names {
+			if uint64(n.flag) == v {
+				return constantName(n.name) + " (" + hexString(v) + ")"
+			}
+		}
+		return hexString(v)
+	}
+}
+
+func annotateFlags(names []flagName) func(uint64) string {
+	return func(v uint64) string {
+		f := jsonFlags(v, names)
+		if len(f.Names) == 0 {
+			return hexString(v)
+		}
+		return strings.Join(f.Names, "|") + " (" + hexString(v) + ")"
+	}
+}
+
+// Annotate returns the structures overlapping the size bytes at offset off,
+// sorted by offset. A size of 0 stands for the rest of the file.
+func (p *Parser) Annotate(off, size uint64) ([]AnnotatedStruct, error) {
+	if !IsValidELFClass(p.F.Class()) {
+		return nil, ErrBadELFClass
+	}
+	fileSize := uint64(p.F.size)
+	if off > fileSize || size > fileSize-off {
+		return nil, ErrBadRange
+	}
+	if size == 0 {
+		size = fileSize - off
+	}
+	a := &annotator{p: p, lo: off, hi: off + size, word: 8}
+	if p.F.Class() == ELFCLASS32 {
+		a.word = 4
+	}
+	a.header()
+	a.programHeaders()
+	a.sectionHeaders()
+	a.symbols()
+	a.dynamic()
+	a.relocations()
+	a.notes()
+	a.contents()
+	sort.SliceStable(a.structs, func(i, j int) bool { return a.structs[i].Offset < a.structs[j].Offset })
+	return a.structs, nil
+}
+
+// prefix returns Elf32_ or Elf64_.
+func (a *annotator) prefix() string {
+	if a.word == 4 {
+		return "Elf32_"
+	}
+	return "Elf64_"
+}
+
+func (a *annotator) header() {
+	h := a.p.F.rawHeader()
+	ident := AnnotatedStruct{Name: "e_ident", Offset: 0, Size: EI_NIDENT}
+	identFields := []struct {
+		name  string
+		off   uint64
+		size  uint64
+		value string
+	}{
+		{"EI_MAG", 0, 4, fmt.Sprintf("%q", h.Ident[:4])},
+		{"EI_CLASS", EI_CLASS, 1, annotateEnum(classStrings)(uint64(h.Ident[EI_CLASS]))},
+		{"EI_DATA", EI_DATA, 1, annotateEnum(dataStrings)(uint64(h.Ident[EI_DATA]))},
+		{"EI_VERSION", EI_VERSION, 1, "EV_" + Version(h.Ident[EI_VERSION]).String()},
+		{"EI_OSABI", EI_OSABI, 1, annotateEnum(osABIStrings)(uint64(h.Ident[EI_OSABI]))},
+		{"EI_ABIVERSION", EI_ABIVERSION, 1, annotateDec(uint64(h.Ident[EI_ABIVERSION]))},
+		{"EI_PAD", EI_PAD, EI_NIDENT - EI_PAD, "padding"},
+	}
+	for _, f := range identFields {
+		ident.Fields = append(ident.Fields, AnnotatedField{Name: "e_ident[" + f.name + "]", Offset: f.off, Size: f.size, Value: f.value})
+	}
+	if a.overlaps(0, EI_NIDENT) {
+		a.structs = append(a.structs, ident)
+	}
+	a.add(a.prefix()+"Ehdr", "", EI_NIDENT, []fieldSpec{
+		{"e_type", 2, annotateEnum(typeStrings)},
+		{"e_machine", 2, annotateEnum(machineStrings)},
+		{"e_version", 4, annotateDec},
+		{"e_entry", 0, annotateHex},
+		{"e_phoff", 0, annotateDec},
+		{"e_shoff", 0, annotateDec},
+		{"e_flags", 4, annotateHex},
+		{"e_ehsize", 2, annotateDec},
+		{"e_phentsize", 2, annotateDec},
+		{"e_phnum", 2, annotateDec},
+		{"e_shentsize", 2, annotateDec},
+		{"e_shnum", 2, annotateDec},
+		{"e_shstrndx", 2, annotateDec},
+	})
+}
+
+// tableRange returns the indexes of the entries of a table at off that
+// overlap the dumped range.
+func (a *annotator) tableRange(off, entSize uint64, count int) (int, int) {
+	if entSize == 0 || count == 0 || !a.overlaps(off, entSize*uint64(count)) {
+		return 0, 0
+	}
+	first, last := 0, count
+	if a.lo > off {
+		first = int((a.lo - off) / entSize)
+	}
+	if end := (a.hi - off + entSize - 1) / entSize; end < uint64(count) {
+		last = int(end)
+	}
+	return first, last
+}
+
+func (a *annotator) programHeaders() {
+	h := a.p.F.rawHeader()
+	typ := fieldSpec{"p_type", 4, annotateEnum(programTypeStrings)}
+	flags := fieldSpec{"p_flags", 4, annotateFlags(programFlagStrings)}
+	rest := []fieldSpec{
+		{"p_offset", 0, annotateHex},
+		{"p_vaddr", 0, annotateHex},
+		{"p_paddr", 0, annotateHex},
+		{"p_filesz", 0, annotateHex},
+		{"p_memsz", 0, annotateHex},
+	}
+	align := fieldSpec{"p_align", 0, annotateHex}
+	// 32位的p_flags位于p_memsz之后，64位的紧跟p_type以保证8字节对齐
+	var specs []fieldSpec
+	if a.word == 4 {
+		specs = append(append([]fieldSpec{typ}, rest...), flags, align)
+	} else {
+		specs = append(append([]fieldSpec{typ, flags}, rest...), align)
+	}
+	first, last := a.tableRange(h.Phoff, uint64(h.Phentsize), int(h.Phnum))
+	for i := first; i < last; i++ {
+		a.add(fmt.Sprintf("%sPhdr[%d]", a.prefix(), i), "", h.Phoff+uint64(i)*uint64(h.Phentsize), specs)
+	}
+}
+
+func (a *annotator) sectionHeaders() {
+	h := a.p.F.rawHeader()
+	sections := a.p.F.Sections()
+	first, last := a.tableRange(h.Shoff, uint64(h.Shentsize), int(h.Shnum))
+	for i := first; i < last; i++ {
+		label := ""
+		if i < len(sections) {
+			label = sections[i].SectionName
+		}
+		a.add(fmt.Sprintf("%sShdr[%d]", a.prefix(), i), label, h.Shoff+uint64(i)*uint64(h.Shentsize), []fieldSpec{
+			{"sh_name", 4, func(v uint64) string { return fmt.Sprintf("%d %q", v, label) }},
+			{"sh_type", 4, annotateEnum(sectionTypeStrings)},
+			{"sh_flags", 0, annotateFlags(sectionFlagStrings)},
+			{"sh_addr", 0, annotateHex},
+			{"sh_offset", 0, annotateHex},
+			{"sh_size", 0, annotateHex},
+			{"sh_link", 4, annotateDec},
+			{"sh_info", 4, annotateDec},
+			{"sh_addralign", 0, annotateDec},
+			{"sh_entsize", 0, annotateDec},
+		})
+	}
+}
+
+// stringField formats an offset into the string table strtab.
+func (a *annotator) stringField(strtab *ELF64Section) func(uint64) string {
+	return func(v uint64) string {
+		return fmt.Sprintf("%d %q", v, a.p.readString(strtab, uint32(v)))
+	}
+}
+
+func (a *annotator) symbols() {
+	entSize := uint64(a.p.symbolEntrySize())
+	name := fieldSpec{"st_name", 4, nil}
+	info := fieldSpec{"st_info", 1, func(v uint64) string {
+		return constantName(ST_TYPE(uint8(v)).String()) + " " + constantName(ST_BIND(uint8(v)).String())
+	}}
+	other := fieldSpec{"st_other", 1, func(v uint64) string { return constantName(ST_VISIBILITY(uint8(v)).String()) }}
+	shndx := fieldSpec{"st_shndx", 2, func(v uint64) string {
+		return fmt.Sprintf("%d %s", v, a.p.symbolSection(SectionIndex(v)))
+	}}
+	value := fieldSpec{"st_value", 0, annotateHex}
+	size := fieldSpec{"st_size", 0, annotateDec}
+	for _, s := range a.p.F.Sections() {
+		typ := SectionType(s.Type)
+		if typ != SHT_SYMTAB && typ != SHT_DYNSYM {
+			continue
+		}
+		name.format = a.stringField(a.p.linkedSection(s))
+		// Elf32_Sym与Elf64_Sym的字段顺序不同
+		specs := []fieldSpec{name, value, size, info, other, shndx}
+		if a.word == 8 {
+			specs = []fieldSpec{name, info, other, shndx, value, size}
+		}
+		first, last := a.tableRange(s.Off, entSize, int(s.Size/entSize))
+		for i := first; i < last; i++ {
+			a.add(fmt.Sprintf("%sSym[%d]", a.prefix(), i), s.SectionName, s.Off+uint64(i)*entSize, specs)
+		}
+	}
+}
+
+func (a *annotator) dynamic() {
+	off, size, label := uint64(0), uint64(0), ""
+	for _, ph := range a.p.F.ProgramHeaders() {
+		if ProgType(ph.Type) == PT_DYNAMIC {
+			off, size = ph.Off, ph.Filesz
+			break
+		}
+	}
+	if s := a.p.F.SectionByType(SHT_DYNAMIC); s != nil {
+		if size == 0 {
+			off, size = s.Off, s.Size
+		}
+		if s.Off == off {
+			label = s.SectionName
+		}
+	}
+	strtab, _ := a.p.dynamicStringTable()
+	entSize := 2 * a.word
+	first, last := a.tableRange(off, entSize, int(size/entSize))
+	for i := first; i < last; i++ {
+		var tag DynTag
+		a.add(fmt.Sprintf("%sDyn[%d]", a.prefix(), i), label, off+uint64(i)*entSize, []fieldSpec{
+			{"d_tag", 0, func(v uint64) string {
+				tag = DynTag(v)
+				return annotateEnum(dtStrings)(v)
+			}},
+			{"d_val", 0, func(v uint64) string {
+				switch tag {
+				case DT_NEEDED, DT_SONAME, DT_RPATH, DT_RUNPATH:
+					name, _ := getString(strtab, int(v))
+					return fmt.Sprintf("%s %q", hexString(v), name)
+				}
+				return hexString(v)
+			}},
+		})
+	}
+}
+
+func (a *annotator) relocations() {
+	for _, s := range a.p.F.Sections() {
+		typ := SectionType(s.Type)
+		if typ != SHT_REL && typ != SHT_RELA {
+			continue
+		}
+		rela := typ == SHT_RELA
+		entSize := uint64(a.p.relocationEntrySize(rela))
+		first, last := a.tableRange(s.Off, entSize, int(s.Size/entSize))
+		for i := first; i < last; i++ {
+			off := s.Off + uint64(i)*entSize
+			// r_info的拆分依赖机器类型（MIPS64有三个类型字段），交给decodeRelocations
+			var r Relocation
+			if rs := a.p.decodeRelocations(a.read(off, entSize), rela, DT_NULL); len(rs) == 1 {
+				r = rs[0]
+			}
+			name := a.prefix() + "Rel"
+			specs := []fieldSpec{
+				{"r_offset", 0, annotateHex},
+				{"r_info", 0, func(v uint64) string {
+					return fmt.Sprintf("sym %d, %s", r.Sym, a.p.F.RelocTypeString(r.Type))
+				}},
+			}
+			if rela {
+				name = a.prefix() + "Rela"
+				specs = append(specs, fieldSpec{"r_addend", 0, func(v uint64) string { return strconv.FormatInt(r.Addend, 10) }})
+			}
+			a.add(fmt.Sprintf("%s[%d]", name, i), s.SectionName, off, specs)
+		}
+	}
+}
+
+func (a *annotator) notes() {
+	for _, r := range a.p.noteRanges() {
+		if r.dataErr != nil || !a.overlaps(r.off, r.size) {
+			continue
+		}
+		notes, _ := a.p.decodeNotes(r)
+		align := uint64(4)
+		if r.align == 8 {
+			align = 8
+		}
+		for i, n := range notes {
+			pos := n.Offset - r.off
+			namesz := uint64(a.p.F.ByteOrder().Uint32(r.data[pos:]))
+			descOff := pos + alignUp(12+namesz, align)
+			end := alignUp(descOff+uint64(len(n.Desc)), align)
+			if end > uint64(len(r.data)) {
+				end = uint64(len(r.data))
+			}
+			if !a.overlaps(n.Offset, end-pos) {
+				continue
+			}
+			s := AnnotatedStruct{Name: fmt.Sprintf("%sNhdr[%d]", a.prefix(), i), Label: r.section, Offset: n.Offset, Size: end - pos}
+			s.Fields = []AnnotatedField{
+				{Name: "n_namesz", Offset: n.Offset, Size: 4, Value: decString(namesz)},
+				{Name: "n_descsz", Offset: n.Offset + 4, Size: 4, Value: decString(uint64(len(n.Desc)))},
+				{Name: "n_type", Offset: n.Offset + 8, Size: 4, Value: n.TypeString()},
+			}
+			if namesz > 0 {
+				s.Fields = append(s.Fields, AnnotatedField{Name: "name", Offset: n.Offset + 12, Size: namesz, Value: strconv.Quote(n.Name)})
+			}
+			if pad := descOff - (pos + 12 + namesz); pad > 0 {
+				s.Fields = append(s.Fields, AnnotatedField{Name: "padding", Offset: n.Offset + 12 + namesz, Size: pad})
+			}
+			if len(n.Desc) > 0 {
+				s.Fields = append(s.Fields, AnnotatedField{Name: "desc", Offset: r.off + descOff, Size: uint64(len(n.Desc)), Value: fmt.Sprintf("%d bytes", len(n.Desc))})
+			}
+			if pad := end - (descOff + uint64(len(n.Desc))); pad > 0 {
+				s.Fields = append(s.Fields, AnnotatedField{Name: "padding", Offset: r.off + descOff + uint64(len(n.Desc)), Size: pad})
+			}
+			a.structs = append(a.structs, s)
+		}
+	}
+}
+
+// contents marks the start of the sections whose entries are not decoded,
+// their bytes are dumped without labels.
+func (a *annotator) contents() {
+	for i, s := range a.p.F.Sections() {
+		switch SectionType(s.Type) {
+		case SHT_NULL, SHT_NOBITS, SHT_SYMTAB, SHT_DYNSYM, SHT_REL, SHT_RELA, SHT_DYNAMIC, SHT_NOTE:
+			continue
+		}
+		if s.Size != 0 && a.overlaps(s.Off, s.Size) {
+			a.structs = append(a.structs, AnnotatedStruct{Name: fmt.Sprintf("section[%d]", i), Label: s.SectionName, Offset: s.Off, Size: s.Size})
+		}
+	}
+}
+
+// annotatedLine is one line of the dump: up to 16 bytes, the field they
+// belong to on the first line of a field and nothing for unlabelled bytes.
+type annotatedLine struct {
+	off   uint64
+	data  []byte
+	field *AnnotatedField
+	first bool // First line of the field.
+}
+
+// annotatedLines walks the range and calls fn with the start of every
+// structure (line nil) and every line of bytes.
+func (p *Parser) annotatedLines(off, size uint64, fn func(s *AnnotatedStruct, l *annotatedLine) error) error {
+	structs, err := p.Annotate(off, size)
+	if err != nil {
+		return err
+	}
+	if size == 0 {
+		size = uint64(p.F.size) - off
+	}
+	end := off + size
+	pos := off
+	buf := make([]byte, 16)
+	// 未被任何字段覆盖的字节按16字节一行输出，行首与16对齐
+	raw := func(to uint64) error {
+		for pos < to {
+			n := 16 - pos%16
+			if pos+n > to {
+				n = to - pos
+			}
+			got, _ := p.F.r.ReadAt(buf[:n], int64(pos))
+			if err := fn(nil, &annotatedLine{off: pos, data: buf[:got]}); err != nil {
+				return err
+			}
+			pos += n
+		}
+		return nil
+	}
+	for i := range structs {
+		s := &structs[i]
+		if err := raw(s.Offset); err != nil {
+			return err
+		}
+		if err := fn(s, nil); err != nil {
+			return err
+		}
+		for j := range s.Fields {
+			f := &s.Fields[j]
+			// 结构体可能部分落在范围之外，只输出范围内的字节
+			lo, hi := f.Offset, f.Offset+f.Size
+			if hi <= off || lo >= end {
+				continue
+			}
+			if lo < off {
+				lo = off
+			}
+			if hi > end {
+				hi = end
+			}
+			if err := raw(lo); err != nil {
+				return err
+			}
+			for q := lo; q < hi; q += 16 {
+				n := hi - q
+				if n > 16 {
+					n = 16
+				}
+				got, _ := p.F.r.ReadAt(buf[:n], int64(q))
+				if err := fn(nil, &annotatedLine{off: q, data: buf[:got], field: f, first: q == lo}); err != nil {
+					return err
+				}
+			}
+			if hi > pos {
+				pos = hi
+			}
+		}
+	}
+	return raw(end)
+}
+
+func hexBytes(b []byte) string {
+	var sb strings.Builder
+	for i, c := range b {
+		if i != 0 {
+			sb.WriteByte(' ')
+		}
+		fmt.Fprintf(&sb, "%02x", c)
+	}
+	return sb.String()
+}
+
+func printableBytes(b []byte) string {
+	s := make([]byte, len(b))
+	for i, c := range b {
+		if c < 32 || c > 126 {
+			c = '.'
+		}
+		s[i] = c
+	}
+	return string(s)
+}
+
+// structTitle is the line printed at the start of a structure.
+func structTitle(s *AnnotatedStruct) string {
+	t := fmt.Sprintf("%s @ %#x, %d bytes", s.Name, s.Offset, s.Size)
+	if s.Label != "" {
+		t = fmt.Sprintf("%s (%s) @ %#x, %d bytes", s.Name, s.Label, s.Offset, s.Size)
+	}
+	return t
+}
+
+// WriteAnnotatedHexDump writes the size bytes at offset off (the rest of
+// the file when size is 0) as a hexdump where every line is labelled with
+// the field it holds and its value:
+//
+//	-- Elf64_Ehdr @ 0x10, 48 bytes
+//	00000010  02 00                    e_type        ET_EXEC (0x2)
+func (p *Parser) WriteAnnotatedHexDump(w io.Writer, off, size uint64) error {
+	ew := &errWriter{w: w}
+	err := p.annotatedLines(off, size, func(s *AnnotatedStruct, l *annotatedLine) error {
+		if s != nil {
+			ew.printf("-- %s\n", structTitle(s))
+			return ew.err
+		}
+		switch {
+		case l.field == nil:
+			ew.printf("%08x  %-47s  |%s|\n", l.off, hexBytes(l.data), printableBytes(l.data))
+		case l.first:
+			ew.printf("%08x  %-47s  %-22s %s\n", l.off, hexBytes(l.data), l.field.Name, l.field.Value)
+		default:
+			ew.printf("%08x  %s\n", l.off, hexBytes(l.data))
+		}
+		return ew.err
+	})
+	if err != nil {
+		return err
+	}
+	return ew.err
+}
+
+const annotatedHTMLHead = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { font-family: monospace; }
+table { border-collapse: collapse; }
+td { padding: 0 0.8em 0 0; white-space: pre; vertical-align: top; }
+tr.struct td { padding-top: 0.6em; font-weight: bold; color: #036; }
+tr.raw td { color: #888; }
+tr.f0 td.bytes { background: #eef4ff; }
+tr.f1 td.bytes { background: #fff4e6; }
+td.off { color: #888; }
+td.name { color: #905; }
+</style>
+</head>
+<body>
+<h1>%s</h1>
+<table>
+`
+
+// WriteAnnotatedHTML writes the same dump as WriteAnnotatedHexDump as a
+// standalone HTML page, the bytes of consecutive fields use alternating
+// backgrounds and carry the field and its value as a tooltip.
+func (p *Parser) WriteAnnotatedHTML(w io.Writer, off, size uint64) error {
+	ew := &errWriter{w: w}
+	end := off + size
+	if size == 0 {
+		end = uint64(p.F.size)
+	}
+	title := fmt.Sprintf("Annotated hexdump %#x-%#x", off, end)
+	ew.printf(annotatedHTMLHead, title, title)
+	field := 0
+	err := p.annotatedLines(off, size, func(s *AnnotatedStruct, l *annotatedLine) error {
+		if s != nil {
+			ew.printf("<tr class=\"struct\" id=\"off-%x\"><td colspan=\"4\">%s</td></tr>\n", s.Offset, html.EscapeString(structTitle(s)))
+			return ew.err
+		}
+		if l.field == nil {
+			ew.printf("<tr class=\"raw\"><td class=\"off\">%08x</td><td class=\"bytes\">%s</td><td></td><td>%s</td></tr>\n",
+				l.off, hexBytes(l.data), html.EscapeString(printableBytes(l.data)))
+			return ew.err
+		}
+		if l.first {
+			field++
+		}
+		name, value := "", ""
+		if l.first {
+			name, value = l.field.Name, l.field.Value
+		}
+		tip := html.EscapeString(l.field.Name + " = " + l.field.Value)
+		ew.printf("<tr class=\"f%d\"><td class=\"off\">%08x</td><td class=\"bytes\" title=\"%s\">%s</td><td class=\"name\">%s</td><td class=\"value\">%s</td></tr>\n",
+			field%2, l.off, tip, hexBytes(l.data), html.EscapeString(name), html.EscapeString(value))
+		return ew.err
+	})
+	if err != nil {
+		return err
+	}
+	ew.printf("</table>\n</body>\n</html>\n")
+	return ew.err
+}
@@ -0,0 +1,85 @@
+package elf
+
+import (
+	"bytes"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnotate(t *testing.T) {
+	for _, name := range []string{"gcc-amd64-linux-exec", "gcc-386-freebsd-exec", "go-relocation-test-gcc492-mips64.obj"} {
+		t.Run(name, func(t *testing.T) {
+			p, err := New(path.Join(exampleDir, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer p.CloseFile()
+			if err := p.Parse(); err != nil {
+				t.Fatal(err)
+			}
+			structs, err := p.Annotate(0, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sections := p.F.Sections()
+			shdrs := 0
+			for _, s := range structs {
+				// 字段首尾相接，正好铺满整个结构
+				pos := s.Offset
+				for _, f := range s.Fields {
+					assert.Equal(t, pos, f.Offset, "%s.%s", s.Name, f.Name)
+					pos += f.Size
+				}
+				if len(s.Fields) != 0 {
+					assert.Equal(t, s.Offset+s.Size, pos, s.Name)
+				}
+				if strings.Contains(s.Name, "_Shdr[") {
+					sec := sections[shdrs]
+					assert.Equal(t, sec.SectionName, s.Label)
+					assert.Equal(t, hexString(sec.Off), s.Fields[4].Value)
+					shdrs++
+				}
+			}
+			assert.Equal(t, len(sections), shdrs)
+		})
+	}
+}
+
+func TestWriteAnnotatedHexDump(t *testing.T) {
+	p, err := New(path.Join(exampleDir, "gcc-amd64-linux-exec"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.CloseFile()
+	if err := p.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	assert.NoError(t, p.WriteAnnotatedHexDump(&out, 0x20, 0x28))
+	assert.Equal(t, `-- Elf64_Ehdr @ 0x10, 48 bytes
+00000020  40 00 00 00 00 00 00 00                          e_phoff                64
+00000028  60 10 00 00 00 00 00 00                          e_shoff                4192
+00000030  00 00 00 00                                      e_flags                0x0
+00000034  40 00                                            e_ehsize               64
+00000036  38 00                                            e_phentsize            56
+00000038  08 00                                            e_phnum                8
+0000003a  40 00                                            e_shentsize            64
+0000003c  25 00                                            e_shnum                37
+0000003e  22 00                                            e_shstrndx             34
+-- Elf64_Phdr[0] @ 0x40, 56 bytes
+00000040  06 00 00 00                                      p_type                 PT_PHDR (0x6)
+00000044  05 00 00 00                                      p_flags                PF_X|PF_R (0x5)
+`, out.String())
+
+	out.Reset()
+	assert.NoError(t, p.WriteAnnotatedHTML(&out, 0x2a0, 4))
+	assert.Contains(t, out.String(), `title="st_name = 1 &#34;__gmon_start__&#34;"`)
+	assert.True(t, strings.HasSuffix(out.String(), "</html>\n"))
+
+	assert.ErrorIs(t, p.WriteAnnotatedHexDump(&out, uint64(p.F.size), 1), ErrBadRange)
+	_, err = p.Annotate(uint64(p.F.size)+1, 0)
+	assert.ErrorIs(t, err, ErrBadRange)
+}
@@ -0,0 +1,418 @@
+// Package elf : anomaly.go implements the anomaly engine used to triage
+// malformed and suspicious files. Each rule of the catalogue has an ID and
+// a severity and reports findings located in the file, it is best run on a
+// file parsed with Parser.Lenient set.
+package elf
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// ViewAnomalies is the kind of the view built by AnomaliesView, it is not
+// part of AllViews.
+const ViewAnomalies ViewKind = "anomalies"
+
+// anomalyReport adds a finding of the rule being run.
+type anomalyReport func(off, size uint64, section string, format string, args ...interface{})
+
+type anomalyRule struct {
+	FindingRule
+	check func(p *Parser, report anomalyReport)
+}
+
+// anomalyRules is the catalogue of the anomaly engine, in the order the
+// rules are run.
+var anomalyRules = []anomalyRule{
+	{FindingRule{"anomaly/parse-error", SeverityError, "Part of the file could not be parsed."}, (*Parser).checkParseErrors},
+	{FindingRule{"anomaly/ident-padding", SeverityWarning, "The padding bytes of e_ident are not zero."}, (*Parser).checkIdentPadding},
+	{FindingRule{"anomaly/phoff-past-eof", SeverityError, "The program header table extends past the end of the file."}, (*Parser).checkPhoff},
+	{FindingRule{"anomaly/shoff-past-eof", SeverityError, "The section header table extends past the end of the file."}, (*Parser).checkShoff},
+	{FindingRule{"anomaly/entry-not-executable", SeverityError, "The entry point is outside of any executable PT_LOAD segment."}, (*Parser).checkEntrySegment},
+	{FindingRule{"anomaly/entry-outside-text", SeverityNote, "The entry point is outside of .text."}, (*Parser).checkEntryText},
+	{FindingRule{"anomaly/overlapping-segments", SeverityWarning, "Two PT_LOAD segments overlap in memory."}, (*Parser).checkOverlappingSegments},
+	{FindingRule{"anomaly/inflated-memsz", SeverityWarning, "The memory size of a PT_LOAD segment is inconsistent with its file size."}, (*Parser).checkMemsz},
+	{FindingRule{"anomaly/interp-in-static", SeverityWarning, "The file has a PT_INTERP segment but no PT_DYNAMIC segment."}, (*Parser).checkInterpStatic},
+	{FindingRule{"anomaly/section-segment-mismatch", SeverityWarning, "A section header disagrees with the segments loading it."}, (*Parser).checkSectionSegments},
+	{FindingRule{"anomaly/wx-section", SeverityWarning, "A section is both writable and executable."}, (*Parser).checkWXSections},
+	{FindingRule{"anomaly/nonstandard-section-name", SeverityNote, "A section has a name the toolchains do not use."}, (*Parser).checkSectionNames},
+	{FindingRule{"anomaly/oversized-note", SeverityWarning, "A note or .comment section is much larger than the toolchains make them."}, (*Parser).checkOversizedNotes},
+	{FindingRule{"anomaly/symbol-outside-section", SeverityWarning, "A symbol points outside of the section it is defined in."}, (*Parser).checkSymbols},
+}
+
+// AnomalyRules returns the catalogue of the anomaly rules.
+func AnomalyRules() []FindingRule {
+	rules := make([]FindingRule, len(anomalyRules))
+	for i, r := range anomalyRules {
+		rules[i] = r.FindingRule
+	}
+	return rules
+}
+
+// Anomalies runs the anomaly rules on the file, the findings are returned
+// in the order of the catalogue.
+func (p *Parser) Anomalies() []Finding {
+	findings := []Finding{}
+	if p.F == nil || !IsValidELFClass(p.F.Class()) {
+		return findings
+	}
+	for _, r := range anomalyRules {
+		r.check(p, func(off, size uint64, section string, format string, args ...interface{}) {
+			f := p.finding(r.ID, off, size, format, args...)
+			if section != "" {
+				f.Section = section
+			}
+			findings = append(findings, f)
+		})
+	}
+	return findings
+}
+
+// headerFieldLocation returns the file bytes of an address or offset field
+// of the ELF header, at off32 in ELF32 files and off64 in ELF64 files.
+func (p *Parser) headerFieldLocation(off32, off64 uint64) (uint64, uint64) {
+	if p.F.Class() == ELFCLASS32 {
+		return off32, 4
+	}
+	return off64, 8
+}
+
+// sectionHeaderLocation returns the file bytes of section header i.
+func (p *Parser) sectionHeaderLocation(i int) (uint64, uint64) {
+	h := p.F.rawHeader()
+	return h.Shoff + uint64(i)*uint64(h.Shentsize), uint64(h.Shentsize)
+}
+
+// linkedImage reports whether the file is an executable or a shared object
+// with loadable segments, the entry point and section address checks only
+// make sense for them.
+func (p *Parser) linkedImage() bool {
+	switch Type(p.F.rawHeader().Type) {
+	case ET_EXEC, ET_DYN:
+	default:
+		return false
+	}
+	for _, ph := range p.F.ProgramHeaders() {
+		if ProgType(ph.Type) == PT_LOAD {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Parser) checkParseErrors(report anomalyReport) {
+	for _, err := range p.ParseErrors {
+		report(0, 0, "", "%v", err)
+	}
+}
+
+func (p *Parser) checkIdentPadding(report anomalyReport) {
+	ident := p.F.rawHeader().Ident
+	for _, b := range ident[EI_PAD:] {
+		if b != 0 {
+			report(EI_PAD, EI_NIDENT-EI_PAD, "", "e_ident padding is % x", ident[EI_PAD:])
+			return
+		}
+	}
+}
+
+func (p *Parser) checkPhoff(report anomalyReport) {
+	h := p.F.rawHeader()
+	end := h.Phoff + uint64(h.Phnum)*uint64(h.Phentsize)
+	if h.Phnum != 0 && (end < h.Phoff || end > uint64(p.F.size)) {
+		off, size := p.headerFieldLocation(0x1c, 0x20)
+		report(off, size, "", "e_phoff %#x + %d entries of %d bytes ends at %#x, the file is %#x bytes", h.Phoff, h.Phnum, h.Phentsize, end, p.F.size)
+	}
+}
+
+func (p *Parser) checkShoff(report anomalyReport) {
+	h := p.F.rawHeader()
+	end := h.Shoff + uint64(h.Shnum)*uint64(h.Shentsize)
+	if h.Shoff != 0 && (end < h.Shoff || end > uint64(p.F.size) || h.Shoff >= uint64(p.F.size)) {
+		off, size := p.headerFieldLocation(0x20, 0x28)
+		report(off, size, "", "e_shoff %#x + %d entries of %d bytes ends at %#x, the file is %#x bytes", h.Shoff, h.Shnum, h.Shentsize, end, p.F.size)
+	}
+}
+
+func (p *Parser) checkEntrySegment(report anomalyReport) {
+	entry := p.F.rawHeader().Entry
+	if entry == 0 || !p.linkedImage() {
+		return
+	}
+	for _, ph := range p.F.ProgramHeaders() {
+		if ProgType(ph.Type) == PT_LOAD && ProgFlag(ph.Flags)&PF_X != 0 && ph.Vaddr <= entry && entry-ph.Vaddr < ph.Memsz {
+			return
+		}
+	}
+	off, size := p.headerFieldLocation(0x18, 0x18)
+	report(off, size, "", "entry point %#x is not in an executable PT_LOAD segment", entry)
+}
+
+func (p *Parser) checkEntryText(report anomalyReport) {
+	entry := p.F.rawHeader().Entry
+	text := p.F.SectionByName(".text")
+	if entry == 0 || text == nil || !p.linkedImage() {
+		return
+	}
+	if entry < text.Addr || entry-text.Addr >= text.ELF64SectionHeader.Size {
+		off, size := p.headerFieldLocation(0x18, 0x18)
+		where := "outside of any section"
+		if s, err := p.F.SectionForVaddr(entry); err == nil {
+			where = "in " + s.SectionName
+		}
+		report(off, size, "", "entry point %#x is %s", entry, where)
+	}
+}
+
+func (p *Parser) checkOverlappingSegments(report anomalyReport) {
+	progs := p.F.ProgramHeaders()
+	for j, b := range progs {
+		if ProgType(b.Type) != PT_LOAD || b.Memsz == 0 {
+			continue
+		}
+		for i, a := range progs[:j] {
+			if ProgType(a.Type) != PT_LOAD || a.Memsz == 0 {
+				continue
+			}
+			if a.Vaddr < b.Vaddr+b.Memsz && b.Vaddr < a.Vaddr+a.Memsz {
+				off, size := p.programHeaderLocation(j)
+				report(off, size, "", "segment %d [%#x, %#x) overlaps segment %d [%#x, %#x)",
+					j, b.Vaddr, b.Vaddr+b.Memsz, i, a.Vaddr, a.Vaddr+a.Memsz)
+			}
+		}
+	}
+}
+
+// inflatedMemsz is how much larger than p_filesz p_memsz may be before the
+// zero-fill is suspicious, far above any real .bss.
+const inflatedMemsz = 0x10000000
+
+func (p *Parser) checkMemsz(report anomalyReport) {
+	for i, ph := range p.F.ProgramHeaders() {
+		if ProgType(ph.Type) != PT_LOAD {
+			continue
+		}
+		off, size := p.programHeaderLocation(i)
+		switch {
+		case ph.Memsz < ph.Filesz:
+			report(off, size, "", "segment %d p_memsz %#x is smaller than p_filesz %#x", i, ph.Memsz, ph.Filesz)
+		case ph.Memsz-ph.Filesz > inflatedMemsz:
+			report(off, size, "", "segment %d p_memsz %#x is %#x bytes larger than p_filesz %#x", i, ph.Memsz, ph.Memsz-ph.Filesz, ph.Filesz)
+		}
+	}
+}
+
+func (p *Parser) checkInterpStatic(report anomalyReport) {
+	interp, dynamic := -1, false
+	for i, ph := range p.F.ProgramHeaders() {
+		switch ProgType(ph.Type) {
+		case PT_INTERP:
+			interp = i
+		case PT_DYNAMIC:
+			dynamic = true
+		}
+	}
+	if interp >= 0 && !dynamic {
+		off, size := p.programHeaderLocation(interp)
+		report(off, size, "", "segment %d requests an interpreter but the file is statically linked", interp)
+	}
+}
+
+func (p *Parser) checkSectionSegments(report anomalyReport) {
+	if !p.linkedImage() {
+		return
+	}
+	progs := p.F.ProgramHeaders()
+	for i, s := range p.F.Sections() {
+		size := s.ELF64SectionHeader.Size
+		if s.Flags&uint64(SHF_ALLOC) == 0 || size == 0 {
+			continue
+		}
+		// .tbss只占TLS模板的空间，不占地址空间
+		nobits := SectionType(s.Type) == SHT_NOBITS
+		if nobits && s.Flags&uint64(SHF_TLS) != 0 {
+			continue
+		}
+		// 后面的PT_LOAD映射会覆盖前面的，以最后一个包含该节的段为准
+		loaded := false
+		for j := len(progs) - 1; j >= 0; j-- {
+			ph := progs[j]
+			if ProgType(ph.Type) != PT_LOAD || s.Addr < ph.Vaddr || s.Addr+size > ph.Vaddr+ph.Memsz {
+				continue
+			}
+			loaded = true
+			if !nobits && s.Off-ph.Off != s.Addr-ph.Vaddr {
+				off, hsize := p.sectionHeaderLocation(i)
+				report(off, hsize, s.SectionName, "section %d is at offset %#x but its address %#x is loaded from offset %#x",
+					i, s.Off, s.Addr, ph.Off+s.Addr-ph.Vaddr)
+			}
+			break
+		}
+		if !loaded {
+			off, hsize := p.sectionHeaderLocation(i)
+			report(off, hsize, s.SectionName, "allocated section %d [%#x, %#x) is not loaded by any PT_LOAD segment", i, s.Addr, s.Addr+size)
+		}
+	}
+}
+
+func (p *Parser) checkWXSections(report anomalyReport) {
+	for i, s := range p.F.Sections() {
+		if flags := SectionFlag(s.Flags); flags&SHF_WRITE != 0 && flags&SHF_EXECINSTR != 0 {
+			off, size := p.sectionHeaderLocation(i)
+			report(off, size, s.SectionName, "section %d is writable and executable", i)
+		}
+	}
+}
+
+// standardSectionNames are the section names of the toolchains, a name
+// ending in "." or "_" also accepts the names it prefixes.
+var standardSectionNames = []string{
+	".interp", ".note.", ".hash", ".gnu.hash", ".dynsym", ".dynstr", ".gnu.version", ".gnu.version_d",
+	".gnu.version_r", ".rel.", ".rela.", ".relr.dyn", ".init", ".fini", ".plt", ".plt.got", ".plt.sec",
+	".text", ".text.", ".rodata", ".rodata.", ".rodata1", ".eh_frame", ".eh_frame_hdr", ".gcc_except_table",
+	".gcc_except_table.", ".tdata", ".tdata.", ".tbss", ".tbss.", ".preinit_array", ".init_array",
+	".init_array.", ".fini_array", ".fini_array.", ".ctors", ".ctors.", ".dtors", ".dtors.", ".jcr",
+	".data.rel.ro", ".data.rel.ro.", ".dynamic", ".got", ".got.plt", ".data", ".data.", ".data1", ".bss",
+	".bss.", ".sdata", ".sdata.", ".sbss", ".sbss.", ".comment", ".debug_", ".zdebug_", ".debug", ".line",
+	".stab", ".stabstr", ".symtab", ".symtab_shndx", ".strtab", ".shstrtab", ".group", ".gnu.", ".gnu_debuglink",
+	".gnu_debugaltlink", ".note", ".ARM.", ".MIPS.", ".mdebug.", ".reginfo", ".rld_map", ".riscv.", ".sdata2",
+	".sbss2", ".toc", ".opd", ".branch_lt", ".glink", ".llvm_addrsig", ".llvm.", ".BTF", ".BTF.", ".go.", ".gosymtab",
+	".gopclntab", ".noptrdata", ".noptrbss", ".typelink", ".itablink", ".tm_clone_table", ".stapsdt.base",
+	".init.", ".exit.", ".pdr", ".ldata", ".lbss", ".lrodata", ".IA_64.", ".PARISC.", ".alpha.", ".SUNW_",
+}
+
+// isStandardSectionName reports whether name is used by the toolchains.
+func isStandardSectionName(name string) bool {
+	for _, n := range standardSectionNames {
+		last := n[len(n)-1]
+		if name == n || (last == '.' || last == '_') && strings.HasPrefix(name, n) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Parser) checkSectionNames(report anomalyReport) {
+	sections := p.F.Sections()
+	for i, s := range sections {
+		if i == 0 || isStandardSectionName(s.SectionName) {
+			continue
+		}
+		// 重定位节也可以直接以目标节名接在.rel/.rela后面，如.relxdp
+		if typ := SectionType(s.Type); (typ == SHT_REL || typ == SHT_RELA) && int(s.Info) < len(sections) {
+			target := sections[s.Info].SectionName
+			if s.SectionName == ".rel"+target || s.SectionName == ".rela"+target {
+				continue
+			}
+		}
+		off, size := p.sectionHeaderLocation(i)
+		report(off, size, s.SectionName, "section %d has the non-standard name %q", i, s.SectionName)
+	}
+}
+
+// Sizes above which note and .comment sections are suspicious, the build
+// ID, ABI tag and property notes take less than a hundred bytes and a
+// .comment a few compiler version strings.
+const (
+	oversizedNote    = 0x10000
+	oversizedComment = 0x1000
+)
+
+func (p *Parser) checkOversizedNotes(report anomalyReport) {
+	sections := p.F.Sections()
+	for _, s := range sections {
+		limit := uint64(oversizedNote)
+		switch {
+		case s.SectionName == ".comment":
+			limit = oversizedComment
+		case SectionType(s.Type) != SHT_NOTE:
+			continue
+		}
+		if size := s.ELF64SectionHeader.Size; size > limit {
+			report(s.Off, size, s.SectionName, "section %s is %#x bytes", s.SectionName, size)
+		}
+	}
+	if len(sections) != 0 {
+		return
+	}
+	for i, ph := range p.F.ProgramHeaders() {
+		if ProgType(ph.Type) == PT_NOTE && ph.Filesz > oversizedNote {
+			report(ph.Off, ph.Filesz, "", "note segment %d is %#x bytes", i, ph.Filesz)
+		}
+	}
+}
+
+func (p *Parser) checkSymbols(report anomalyReport) {
+	sections := p.F.Sections()
+	rel := Type(p.F.rawHeader().Type) == ET_REL
+	entsize := uint64(p.symbolEntrySize())
+	for _, typ := range []SectionType{SHT_SYMTAB, SHT_DYNSYM} {
+		table := p.F.SectionByType(typ)
+		syms, err := p.Symbols(typ)
+		if table == nil || err != nil {
+			continue
+		}
+		for i, sym := range syms {
+			if i == 0 || sym.Index == SHN_UNDEF || sym.Index >= SHN_LORESERVE {
+				continue
+			}
+			switch ST_TYPE(sym.Info) {
+			case STT_FILE, STT_TLS:
+				// STT_TLS的值是TLS模板内的偏移，不是地址
+				continue
+			case STT_NOTYPE:
+				// 链接器定义的0大小标记符号（__init_array_end等）只是挂在相邻的节上
+				if sym.Size == 0 {
+					continue
+				}
+			}
+			off := table.Off + uint64(i)*entsize
+			if int(sym.Index) >= len(sections) {
+				report(off, entsize, table.SectionName, "symbol %d %q is defined in section %d which does not exist", i, sym.Name, sym.Index)
+				continue
+			}
+			s := sections[sym.Index]
+			start, end := s.Addr, s.Addr+s.ELF64SectionHeader.Size
+			if rel {
+				// 目标文件的符号值是节内偏移
+				start, end = 0, s.ELF64SectionHeader.Size
+			}
+			// 允许指向节末尾的0大小符号，如_end、__init_array_end
+			if sym.Value < start || sym.Value > end || sym.Size > end-sym.Value {
+				report(off, entsize, table.SectionName, "symbol %d %q [%#x, %#x) is outside of section %s [%#x, %#x)",
+					i, sym.Name, sym.Value, sym.Value+sym.Size, s.SectionName, start, end)
+			}
+		}
+	}
+}
+
+// AnomaliesView builds the view of the anomalies.
+func (p *Parser) AnomaliesView() *View {
+	v := &View{Kind: ViewAnomalies}
+	findings := p.Anomalies()
+	if len(findings) == 0 {
+		v.Note = "No anomalies found."
+		return v
+	}
+	t := v.addTable("Anomalies", "Rule", "Severity", "Offset", "Section", "Message")
+	for _, f := range findings {
+		t.addRow(f.RuleID, string(f.Severity), hexString(f.Offset), f.Section, f.Message)
+	}
+	return v
+}
+
+// WriteAnomalies writes the anomalies to w. FormatJSON writes the findings
+// themselves, the other formats render AnomaliesView.
+func (p *Parser) WriteAnomalies(w io.Writer, format Format) error {
+	if format == FormatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(p.Anomalies())
+	}
+	r, err := NewRenderer(format)
+	if err != nil {
+		return err
+	}
+	return r.Render(w, []*View{p.AnomaliesView()})
+}
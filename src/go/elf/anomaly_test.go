@@ -0,0 +1,173 @@
+package elf
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mutatedExec parses gcc-amd64-linux-exec leniently after mutate changed
+// its bytes, f is the unmodified file to look the offsets up in.
+func mutatedExec(t *testing.T, mutate func(data []byte, f *Parser)) *Parser {
+	name := path.Join(exampleDir, "gcc-amd64-linux-exec")
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := parseFile(t, name)
+	defer orig.CloseFile()
+	mutate(data, orig)
+	p, err := NewBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Lenient = true
+	if err := p.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func ruleIDs(findings []Finding) []string {
+	ids := []string{}
+	for _, f := range findings {
+		ids = append(ids, f.RuleID)
+	}
+	return ids
+}
+
+func TestAnomaliesClean(t *testing.T) {
+	for _, name := range []string{"gcc-amd64-linux-exec", "gcc-amd64-linux-hardened", "gcc-386-freebsd-exec", "go-relocation-test-gcc441-x86-64.obj"} {
+		p := parseFile(t, path.Join(exampleDir, name))
+		assert.Empty(t, p.Anomalies(), name)
+		p.CloseFile()
+	}
+}
+
+func TestAnomaliesSectionNames(t *testing.T) {
+	p := parseFile(t, path.Join(exampleDir, "xdp_fw.elf"))
+	defer p.CloseFile()
+	assert.Equal(t, []Finding{
+		{RuleID: "anomaly/nonstandard-section-name", Severity: SeverityNote, Message: "section 3 has the non-standard name \"xdp\"", Offset: 0x440, Size: 64, Section: "xdp"},
+		{RuleID: "anomaly/nonstandard-section-name", Severity: SeverityNote, Message: "section 5 has the non-standard name \"maps\"", Offset: 0x4c0, Size: 64, Section: "maps"},
+		{RuleID: "anomaly/nonstandard-section-name", Severity: SeverityNote, Message: "section 6 has the non-standard name \"license\"", Offset: 0x500, Size: 64, Section: "license"},
+	}, p.Anomalies())
+}
+
+func TestAnomalies(t *testing.T) {
+	le := binary.LittleEndian
+	for _, tc := range []struct {
+		name   string
+		mutate func(data []byte, f *Parser)
+		want   []Finding
+	}{
+		{"ident padding", func(data []byte, f *Parser) { data[12] = 'A' }, []Finding{
+			{RuleID: "anomaly/ident-padding", Severity: SeverityWarning, Message: "e_ident padding is 00 00 00 41 00 00 00", Offset: 9, Size: 7},
+		}},
+		{"entry in .data", func(data []byte, f *Parser) { le.PutUint64(data[0x18:], 0x600880) }, []Finding{
+			{RuleID: "anomaly/entry-not-executable", Severity: SeverityError, Message: "entry point 0x600880 is not in an executable PT_LOAD segment", Offset: 0x18, Size: 8},
+			{RuleID: "anomaly/entry-outside-text", Severity: SeverityNote, Message: "entry point 0x600880 is in .data", Offset: 0x18, Size: 8},
+		}},
+		{"overlapping segments", func(data []byte, f *Parser) {
+			off, _ := f.programHeaderLocation(2)
+			le.PutUint64(data[off+40:], 0x200700) // p_memsz
+		}, []Finding{
+			{RuleID: "anomaly/overlapping-segments", Severity: SeverityWarning, Message: "segment 3 [0x600688, 0x6008a0) overlaps segment 2 [0x400000, 0x600700)", Offset: 0xe8, Size: 56},
+		}},
+		{"inflated memsz", func(data []byte, f *Parser) {
+			off, _ := f.programHeaderLocation(3)
+			le.PutUint64(data[off+40:], 0x40000000) // p_memsz
+		}, []Finding{
+			{RuleID: "anomaly/inflated-memsz", Severity: SeverityWarning, Message: "segment 3 p_memsz 0x40000000 is 0x3ffffdf0 bytes larger than p_filesz 0x210", Offset: 0xe8, Size: 56},
+		}},
+		{"interp in static", func(data []byte, f *Parser) {
+			off, _ := f.programHeaderLocation(4)
+			le.PutUint32(data[off:], uint32(PT_NULL))
+		}, []Finding{
+			{RuleID: "anomaly/interp-in-static", Severity: SeverityWarning, Message: "segment 1 requests an interpreter but the file is statically linked", Offset: 0x78, Size: 56},
+		}},
+		{"moved .text", func(data []byte, f *Parser) {
+			i, _ := f.LookupSection(".text")
+			off, _ := f.sectionHeaderLocation(i)
+			le.PutUint64(data[off+24:], 0x3f0) // sh_offset
+		}, []Finding{
+			{RuleID: "anomaly/section-segment-mismatch", Severity: SeverityWarning, Message: "section 13 is at offset 0x3f0 but its address 0x4003e0 is loaded from offset 0x3e0", Offset: 0x13a0, Size: 64, Section: ".text"},
+		}},
+		{"writable .text", func(data []byte, f *Parser) {
+			i, _ := f.LookupSection(".text")
+			off, _ := f.sectionHeaderLocation(i)
+			le.PutUint64(data[off+8:], uint64(SHF_ALLOC|SHF_EXECINSTR|SHF_WRITE))
+		}, []Finding{
+			{RuleID: "anomaly/wx-section", Severity: SeverityWarning, Message: "section 13 is writable and executable", Offset: 0x13a0, Size: 64, Section: ".text"},
+		}},
+		{"big .comment", func(data []byte, f *Parser) {
+			i, _ := f.LookupSection(".comment")
+			off, _ := f.sectionHeaderLocation(i)
+			le.PutUint64(data[off+32:], 0x2000) // sh_size
+		}, []Finding{
+			{RuleID: "anomaly/oversized-note", Severity: SeverityWarning, Message: "section .comment is 0x2000 bytes", Offset: 0x898, Size: 0x2000, Section: ".comment"},
+		}},
+		{"main out of .text", func(data []byte, f *Parser) {
+			syms, _ := f.Symbols(SHT_SYMTAB)
+			for i, s := range syms {
+				if s.Name == "main" {
+					off := f.F.SectionByType(SHT_SYMTAB).Off + uint64(i)*24
+					le.PutUint64(data[off+8:], 0x400000) // st_value
+				}
+			}
+		}, []Finding{
+			{RuleID: "anomaly/symbol-outside-section", Severity: SeverityWarning, Message: "symbol 72 \"main\" [0x400000, 0x40001b) is outside of section .text [0x4003e0, 0x400594)", Offset: 0x2060, Size: 24, Section: ".symtab"},
+		}},
+	} {
+		p := mutatedExec(t, tc.mutate)
+		assert.Equal(t, tc.want, p.Anomalies(), tc.name)
+	}
+}
+
+func TestAnomaliesLenient(t *testing.T) {
+	// 节头表被截断：e_shoff指向文件末尾之后
+	p := mutatedExec(t, func(data []byte, f *Parser) { le := binary.LittleEndian; le.PutUint64(data[0x28:], 0x10000) })
+	assert.Len(t, p.ParseErrors, 1)
+	assert.Equal(t, []string{"anomaly/parse-error", "anomaly/shoff-past-eof"}, ruleIDs(p.Anomalies()))
+	assert.Equal(t, "e_shoff 0x10000 + 37 entries of 64 bytes ends at 0x10940, the file is 0x228c bytes", p.Anomalies()[1].Message)
+
+	// 程序头表越过文件末尾时，只有宽松模式能继续解析
+	data, err := ioutil.ReadFile(path.Join(exampleDir, "gcc-amd64-linux-exec"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary.LittleEndian.PutUint16(data[0x38:], 200) // e_phnum
+	p, err = NewBytes(data)
+	assert.NoError(t, err)
+	assert.Error(t, p.Parse())
+	p, _ = NewBytes(data)
+	p.Lenient = true
+	assert.NoError(t, p.Parse())
+	assert.Len(t, p.F.ProgramHeaders(), 156)
+	ids := ruleIDs(p.Anomalies())
+	assert.Contains(t, ids, "anomaly/parse-error")
+	assert.Contains(t, ids, "anomaly/phoff-past-eof")
+
+	// PT_DYNAMIC的p_filesz越过文件末尾：宽松模式记录错误后继续
+	data, err = ioutil.ReadFile(path.Join(exampleDir, "gcc-amd64-linux-exec"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	setDynamicFilesz(t, data, 0x7fffffffffffffff)
+	p, _ = NewBytes(data)
+	p.Lenient = true
+	assert.NoError(t, p.Parse())
+	if assert.Len(t, p.ParseErrors, 1) {
+		assert.Contains(t, p.ParseErrors[0].Error(), "dynamic section: PT_DYNAMIC segment")
+	}
+	assert.Len(t, p.F.ProgramHeaders(), 8)
+	assert.Contains(t, ruleIDs(p.Anomalies()), "anomaly/parse-error")
+
+	r, ok := LookupFindingRule("anomaly/wx-section")
+	assert.True(t, ok)
+	assert.Equal(t, SeverityWarning, r.Severity)
+	assert.Len(t, AnomalyRules(), len(anomalyRules))
+}
@@ -1719,5 +1719,45 @@ var rsparcStrings = []flagName{
 func (i R_SPARC) String() string   { return stringify(uint32(i), rsparcStrings, false) }
 func (i R_SPARC) GoString() string { return stringify(uint32(i), rsparcStrings, true) }
 
+// Relocation types for BPF, as named by binutils.
+type R_BPF int
+
+const (
+	R_BPF_NONE           R_BPF = 0  // No reloc
+	R_BPF_INSN_64        R_BPF = 1  // 64 bit immediate of a ld_imm64 instruction
+	R_BPF_INSN_32        R_BPF = 2  // 32 bit immediate
+	R_BPF_INSN_16        R_BPF = 3  // 16 bit offset
+	R_BPF_INSN_DISP16    R_BPF = 4  // PC relative 16 bit jump offset
+	R_BPF_DATA_8_PCREL   R_BPF = 5  // PC relative 8 bit data
+	R_BPF_DATA_16_PCREL  R_BPF = 6  // PC relative 16 bit data
+	R_BPF_DATA_32_PCREL  R_BPF = 7  // PC relative 32 bit data
+	R_BPF_DATA_8         R_BPF = 8  // Direct 8 bit
+	R_BPF_DATA_16        R_BPF = 9  // Direct 16 bit
+	R_BPF_INSN_DISP32    R_BPF = 10 // PC relative 32 bit call offset
+	R_BPF_DATA_32        R_BPF = 11 // Direct 32 bit
+	R_BPF_DATA_64_PCREL  R_BPF = 12 // PC relative 64 bit data
+	R_BPF_DATA_64        R_BPF = 13 // Direct 64 bit
+)
+
+var rbpfStrings = []flagName{
+	{0, "R_BPF_NONE"},
+	{1, "R_BPF_INSN_64"},
+	{2, "R_BPF_INSN_32"},
+	{3, "R_BPF_INSN_16"},
+	{4, "R_BPF_INSN_DISP16"},
+	{5, "R_BPF_DATA_8_PCREL"},
+	{6, "R_BPF_DATA_16_PCREL"},
+	{7, "R_BPF_DATA_32_PCREL"},
+	{8, "R_BPF_DATA_8"},
+	{9, "R_BPF_DATA_16"},
+	{10, "R_BPF_INSN_DISP32"},
+	{11, "R_BPF_DATA_32"},
+	{12, "R_BPF_DATA_64_PCREL"},
+	{13, "R_BPF_DATA_64"},
+}
+
+func (i R_BPF) String() string   { return stringify(uint32(i), rbpfStrings, false) }
+func (i R_BPF) GoString() string { return stringify(uint32(i), rbpfStrings, true) }
+
 // Magic number for the elf trampoline, chosen wisely to be an immediate value.
 const ARM_MAGIC_TRAMP_NUMBER = 0x5c000003
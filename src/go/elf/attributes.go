@@ -0,0 +1,111 @@
+// Package elf : attributes.go decodes the build attribute sections
+// (.ARM.attributes, .riscv.attributes, .gnu.attributes).
+package elf
+
+import (
+	"errors"
+)
+
+// Attribute is a single tag/value pair of a build attributes subsection.
+type Attribute struct {
+	Tag      uint64 `json:"tag"`
+	Value    uint64 `json:"value"`
+	String   string `json:"string,omitempty"`
+	IsString bool   `json:"is_string"`
+}
+
+// AttributeSection is a vendor subsection of a build attributes section.
+type AttributeSection struct {
+	Section string      `json:"section"`
+	Vendor  string      `json:"vendor"`
+	Tags    []Attribute `json:"tags"`
+}
+
+// BuildAttributes decodes the build attribute sections of the file.
+// 格式为 'A' + [长度, 厂商名, (Tag_File, 长度, 属性...)]...
+func (p *Parser) BuildAttributes() ([]AttributeSection, error) {
+	var sections []AttributeSection
+	for _, s := range p.F.Sections() {
+		typ := SectionType(s.Type)
+		if typ != SHT_ARM_ATTRIBUTES && typ != SHT_GNU_ATTRIBUTES {
+			continue
+		}
+		data, err := s.Data()
+		if err != nil {
+			return sections, err
+		}
+		if len(data) == 0 || data[0] != 'A' {
+			return sections, errors.New("unknown attributes format version in " + s.SectionName)
+		}
+		bo := p.F.ByteOrder()
+		for pos := 1; pos+4 <= len(data); {
+			length := int(bo.Uint32(data[pos:]))
+			if length < 4 || pos+length > len(data) {
+				return sections, errors.New("bad subsection length in " + s.SectionName)
+			}
+			sub := data[pos+4 : pos+length]
+			vendor, ok := getString(sub, 0)
+			if !ok {
+				return sections, errors.New("bad vendor name in " + s.SectionName)
+			}
+			as := AttributeSection{Section: s.SectionName, Vendor: vendor}
+			as.Tags = decodeAttributes(sub[len(vendor)+1:])
+			sections = append(sections, as)
+			pos += length
+		}
+	}
+	return sections, nil
+}
+
+// decodeAttributes decodes the Tag_File sub-subsections of a vendor subsection.
+// Tags 4, 5 and 67 and the odd tags above 32 carry NUL terminated strings,
+// every other tag carries an uleb128.
+func decodeAttributes(data []byte) []Attribute {
+	var attrs []Attribute
+	for pos := 0; pos+5 <= len(data); {
+		// Tag_File (1) / Tag_Section (2) / Tag_Symbol (3) + uint32 size
+		size := int(uint32(data[pos+1]) | uint32(data[pos+2])<<8 | uint32(data[pos+3])<<16 | uint32(data[pos+4])<<24)
+		if size < 5 || pos+size > len(data) {
+			return attrs
+		}
+		body := data[pos+5 : pos+size]
+		for i := 0; i < len(body); {
+			tag, n := uleb128(body[i:])
+			if n == 0 {
+				break
+			}
+			i += n
+			a := Attribute{Tag: tag}
+			if tag == 4 || tag == 5 || tag == 67 || (tag > 32 && tag%2 == 1) {
+				a.IsString = true
+				a.String, _ = getString(body, i)
+				i += len(a.String) + 1
+			} else {
+				v, n := uleb128(body[i:])
+				if n == 0 {
+					break
+				}
+				a.Value = v
+				i += n
+			}
+			attrs = append(attrs, a)
+		}
+		pos += size
+	}
+	return attrs
+}
+
+// uleb128 decodes an unsigned LEB128 value, it returns the number of bytes consumed.
+func uleb128(b []byte) (uint64, int) {
+	var v uint64
+	for i, c := range b {
+		if i == 10 {
+			break
+		}
+		v |= uint64(c&0x7f) << (7 * uint(i))
+		if c&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	return 0, 0
+}
@@ -2,24 +2,32 @@ package main
 
 import (
 	"fmt"
+	"os"
 
-	"github.com/saferwall/elf"
+	"parser-elf/elf"
 )
 
+// elfdump 将ELF文件解析结果以JSON格式输出
 func main() {
-
-	p, err := elf.New("/bin/ls")
-	defer p.CloseFile()
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: elfdump <elf-file>")
+		os.Exit(2)
+	}
+	p, err := elf.New(os.Args[1])
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
+	defer p.CloseFile()
 	err = p.Parse()
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 	jsonFile, err := p.DumpJSON()
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 	fmt.Println(jsonFile)
 }
@@ -0,0 +1,950 @@
+// Package elf : demangle.go decodes the C++ symbol names of the Itanium ABI
+// used by GCC and Clang, printing them the way nm -C does. Only the common
+// part of the grammar is understood (nested and template names, operators,
+// constructors, the builtin and compound types, substitutions and the
+// special vtable/typeinfo/guard names); a name using anything else, such as
+// expressions or lambdas, is left mangled.
+package elf
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var errMangled = errors.New("unsupported mangled name")
+
+// Demangle returns the demangled form of a C++ symbol name, a name which
+// is not mangled or cannot be decoded is returned unchanged. A symbol
+// version suffix (@VER or @@VER) is kept. The abbreviations of std such as
+// Ss stay std::string the way nm -C prints them.
+func Demangle(name string) string {
+	return demangle(name, false)
+}
+
+// DemangleVerbose is Demangle with the abbreviations of std expanded to
+// their full template form, std::basic_string<char, std::char_traits<char>,
+// std::allocator<char> > for Ss, the way c++filt prints them.
+func DemangleVerbose(name string) string {
+	return demangle(name, true)
+}
+
+func demangle(name string, verbose bool) string {
+	sym, version := name, ""
+	if at := strings.IndexByte(name, '@'); at > 0 {
+		sym, version = name[:at], name[at:]
+	}
+	if !strings.HasPrefix(sym, "_Z") {
+		return name
+	}
+	// GCC给克隆出的函数加上 .constprop.0、.isra.0 之类的后缀
+	var clones []string
+	if dot := strings.IndexByte(sym, '.'); dot > 0 {
+		for _, c := range strings.Split(sym[dot+1:], ".") {
+			if c == "" {
+				return name
+			}
+			if len(clones) != 0 && c[0] >= '0' && c[0] <= '9' {
+				clones[len(clones)-1] += "." + c
+				continue
+			}
+			clones = append(clones, "."+c)
+		}
+		sym = sym[:dot]
+	}
+	d := &demangler{s: sym[2:], packIndex: -1, verbose: verbose}
+	out, err := d.special()
+	if err != nil || d.pos != len(d.s) {
+		return name
+	}
+	for _, c := range clones {
+		out += " [clone " + c + "]"
+	}
+	return out + version
+}
+
+type demangler struct {
+	s    string
+	pos  int
+	subs []ctype
+	// templateArgs are the arguments of the template function, referenced
+	// by T_ in its type. nameArgs are the last arguments read outside of
+	// another argument list.
+	templateArgs []ctype
+	nameArgs     []ctype
+	depth        int
+	// packIndex selects the element of an argument pack while a Dp pack
+	// expansion is decoded, packProbe asks T_ for the length of the pack
+	// in packLen instead.
+	packIndex int
+	packProbe bool
+	packLen   int
+	// verbose expands the abbreviations of std.
+	verbose bool
+}
+
+func (d *demangler) peek() byte {
+	if d.pos < len(d.s) {
+		return d.s[d.pos]
+	}
+	return 0
+}
+
+func (d *demangler) peekAt(i int) byte {
+	if d.pos+i < len(d.s) {
+		return d.s[d.pos+i]
+	}
+	return 0
+}
+
+func (d *demangler) consume(prefix string) bool {
+	if strings.HasPrefix(d.s[d.pos:], prefix) {
+		d.pos += len(prefix)
+		return true
+	}
+	return false
+}
+
+func (d *demangler) number() (int, error) {
+	start := d.pos
+	for d.peek() >= '0' && d.peek() <= '9' {
+		d.pos++
+	}
+	if start == d.pos {
+		return 0, errMangled
+	}
+	return strconv.Atoi(d.s[start:d.pos])
+}
+
+// special decodes the encoding of a function or a data object, or one of
+// the special names generated by the compiler.
+func (d *demangler) special() (string, error) {
+	prefixes := []struct{ code, text string }{
+		{"TV", "vtable for "}, {"TT", "VTT for "}, {"TI", "typeinfo for "}, {"TS", "typeinfo name for "},
+	}
+	for _, p := range prefixes {
+		if d.consume(p.code) {
+			t, err := d.typeString()
+			return p.text + t, err
+		}
+	}
+	if d.consume("GTt") {
+		enc, err := d.encoding(true)
+		return "transaction clone for " + enc, err
+	}
+	if d.consume("GV") {
+		n, err := d.name()
+		return "guard variable for " + n.text, err
+	}
+	if d.consume("Th") || d.consume("Tv") {
+		virtual := d.s[d.pos-1] == 'v'
+		if err := d.callOffset(); err != nil {
+			return "", err
+		}
+		if virtual {
+			if err := d.callOffset(); err != nil {
+				return "", err
+			}
+		}
+		enc, err := d.encoding(true)
+		if virtual {
+			return "virtual thunk to " + enc, err
+		}
+		return "non-virtual thunk to " + enc, err
+	}
+	return d.encoding(true)
+}
+
+// callOffset skips the [n]<number>_ adjustment of a thunk.
+func (d *demangler) callOffset() error {
+	d.consume("n")
+	if _, err := d.number(); err != nil || !d.consume("_") {
+		return errMangled
+	}
+	return nil
+}
+
+// name is a decoded <name>, with what the function type needs to know.
+type name struct {
+	text string
+	// template is set when the name ends with template arguments, the
+	// function type then starts with the return type.
+	template bool
+	// ctor is set for constructors, destructors and conversion operators,
+	// which have no return type.
+	ctor   bool
+	suffix string // Qualifiers of a member function: " const", " &"...
+	// std is set for a bare standard abbreviation, which is not a
+	// substitution candidate.
+	std bool
+}
+
+// encoding decodes a name with its function type, withReturn selects
+// whether the return type of a template function is printed.
+func (d *demangler) encoding(withReturn bool) (string, error) {
+	n, err := d.name()
+	if err != nil {
+		return "", err
+	}
+	if d.pos == len(d.s) || d.peek() == 'E' {
+		return n.text, nil
+	}
+	if n.template {
+		d.templateArgs = d.nameArgs
+	}
+	ret := ""
+	if n.template && !n.ctor {
+		if ret, err = d.returnType(); err != nil {
+			return "", err
+		}
+		ret += " "
+		if !withReturn {
+			ret = ""
+		}
+	}
+	params, err := d.params()
+	if err != nil {
+		return "", err
+	}
+	return ret + n.text + params + n.suffix, nil
+}
+
+// params decodes the parameter types up to the end of the function type.
+func (d *demangler) params() (string, error) {
+	var params []string
+	if d.pos == len(d.s) || d.peek() == '.' {
+		return "", errMangled
+	}
+	for d.pos < len(d.s) && d.peek() != 'E' && d.peek() != '.' {
+		t, err := d.types()
+		if err != nil {
+			return "", err
+		}
+		params = append(params, t...)
+	}
+	if len(params) == 1 && params[0] == "void" {
+		return "()", nil
+	}
+	return "(" + strings.Join(params, ", ") + ")", nil
+}
+
+func (d *demangler) name() (name, error) {
+	switch d.peek() {
+	case 'N':
+		return d.nestedName()
+	case 'Z':
+		return d.localName()
+	case 'S':
+		var text string
+		fromSubst := false
+		if d.peekAt(1) == 't' {
+			d.pos += 2
+			n, err := d.unqualifiedName("std")
+			if err != nil {
+				return name{}, err
+			}
+			text = "std::" + n.text
+		} else {
+			var err error
+			var std bool
+			if text, _, std, err = d.substitution(); err != nil {
+				return name{}, err
+			}
+			fromSubst = true
+			if d.peek() != 'I' {
+				return name{text: text, std: std}, nil
+			}
+		}
+		if d.peek() != 'I' {
+			return name{text: text}, nil
+		}
+		if !fromSubst {
+			d.subs = append(d.subs, ctype{left: text})
+		}
+		args, err := d.templateArgList()
+		return name{text: appendArgs(text, args), template: true}, err
+	}
+	n, err := d.unqualifiedName("")
+	if err != nil {
+		return name{}, err
+	}
+	if d.peek() == 'I' {
+		d.subs = append(d.subs, ctype{left: n.text})
+		args, err := d.templateArgList()
+		return name{text: appendArgs(n.text, args), template: true, ctor: n.ctor}, err
+	}
+	return n, nil
+}
+
+// nestedName decodes N [CV-qualifiers] [ref-qualifier] <prefix> E, every
+// prefix but the whole name is a substitution candidate.
+func (d *demangler) nestedName() (name, error) {
+	d.pos++
+	var suffix string
+	for {
+		switch {
+		case d.consume("r"):
+			suffix += " restrict"
+			continue
+		case d.consume("V"):
+			suffix += " volatile"
+			continue
+		case d.consume("K"):
+			suffix += " const"
+			continue
+		}
+		break
+	}
+	if d.consume("R") {
+		suffix += " &"
+	} else if d.consume("O") {
+		suffix += " &&"
+	}
+	var n name
+	last := "" // Last unqualified name, the class of a constructor.
+	for {
+		c := d.peek()
+		if c == 'E' {
+			d.pos++
+			break
+		}
+		var part name
+		var err error
+		switch {
+		case c == 'S':
+			if d.peekAt(1) == 't' {
+				d.pos += 2
+				part, last = name{text: "std"}, "std"
+			} else {
+				var text string
+				text, last, _, err = d.substitution()
+				part = name{text: text}
+			}
+		case c == 'I':
+			if n.text == "" {
+				return name{}, errMangled
+			}
+			var args string
+			args, err = d.templateArgList()
+			n.text = appendArgs(n.text, args)
+			n.template = true
+		case c == 'T':
+			var t ctype
+			t, err = d.templateParam()
+			part, last = name{text: t.String()}, t.String()
+		default:
+			part, err = d.unqualifiedName(last)
+			if !part.ctor {
+				// 构造函数的名字不带ABI标签
+				last = part.text
+				if tag := strings.Index(last, "[abi:"); tag > 0 {
+					last = last[:tag]
+				}
+			}
+		}
+		if err != nil {
+			return name{}, err
+		}
+		if c != 'I' {
+			if n.text != "" {
+				part.text = n.text + "::" + part.text
+			}
+			n = name{text: part.text, ctor: part.ctor}
+		}
+		if c != 'S' && d.peek() != 'E' {
+			d.subs = append(d.subs, ctype{left: n.text})
+		}
+		if d.pos >= len(d.s) {
+			return name{}, errMangled
+		}
+	}
+	n.suffix = suffix
+	return n, nil
+}
+
+// appendArgs appends template arguments to a name, with a space after an
+// operator ending in '<' so that operator<< <T> stays readable.
+func appendArgs(name, args string) string {
+	if strings.HasSuffix(name, "<") {
+		return name + " " + args
+	}
+	return name + args
+}
+
+// lastComponent returns the last name of a qualified name, without its
+// template arguments.
+func lastComponent(s string) string {
+	if i := strings.IndexByte(s, '<'); i >= 0 {
+		s = s[:i]
+	}
+	if i := strings.LastIndex(s, "::"); i >= 0 {
+		s = s[i+2:]
+	}
+	return s
+}
+
+// localName decodes Z <function encoding> E <entity name> [<discriminator>].
+func (d *demangler) localName() (name, error) {
+	d.pos++
+	// 外层函数不输出返回类型
+	fn, err := d.encoding(false)
+	if err != nil || !d.consume("E") {
+		return name{}, errMangled
+	}
+	var n name
+	if d.consume("s") {
+		n = name{text: "string literal"}
+	} else if n, err = d.name(); err != nil {
+		return name{}, err
+	}
+	if d.consume("_") {
+		if _, err := d.number(); err != nil {
+			return name{}, err
+		}
+	}
+	n.text = fn + "::" + n.text
+	return n, nil
+}
+
+var operatorNames = map[string]string{
+	"nw": "new", "na": "new[]", "dl": "delete", "da": "delete[]",
+	"ps": "+", "ng": "-", "ad": "&", "de": "*", "co": "~",
+	"pl": "+", "mi": "-", "ml": "*", "dv": "/", "rm": "%",
+	"an": "&", "or": "|", "eo": "^", "aS": "=", "pL": "+=",
+	"mI": "-=", "mL": "*=", "dV": "/=", "rM": "%=", "aN": "&=",
+	"oR": "|=", "eO": "^=", "ls": "<<", "rs": ">>", "lS": "<<=",
+	"rS": ">>=", "eq": "==", "ne": "!=", "lt": "<", "gt": ">",
+	"le": "<=", "ge": ">=", "ss": "<=>", "nt": "!", "aa": "&&",
+	"oo": "||", "pp": "++", "mm": "--", "cm": ",", "pm": "->*",
+	"pt": "->", "cl": "()", "ix": "[]",
+}
+
+// unqualifiedName decodes a source name, an operator, a constructor or a
+// destructor of the class named class, followed by its ABI tags.
+func (d *demangler) unqualifiedName(class string) (name, error) {
+	var n name
+	c := d.peek()
+	switch {
+	case c >= '0' && c <= '9':
+		text, err := d.sourceName()
+		if err != nil {
+			return name{}, err
+		}
+		n.text = text
+	case c == 'C' && d.peekAt(1) >= '1' && d.peekAt(1) <= '5' || c == 'D' && d.peekAt(1) >= '0' && d.peekAt(1) <= '5':
+		if class == "" {
+			return name{}, errMangled
+		}
+		d.pos += 2
+		n.text, n.ctor = class, true
+		if c == 'D' {
+			n.text = "~" + class
+		}
+	case c == 'c' && d.peekAt(1) == 'v':
+		d.pos += 2
+		t, err := d.typeString()
+		if err != nil {
+			return name{}, err
+		}
+		n.text, n.ctor = "operator "+t, true
+	case c >= 'a' && c <= 'z' && d.pos+2 <= len(d.s):
+		op, ok := operatorNames[d.s[d.pos:d.pos+2]]
+		if !ok {
+			return name{}, errMangled
+		}
+		d.pos += 2
+		if op[0] >= 'a' && op[0] <= 'z' {
+			op = " " + op
+		}
+		n.text = "operator" + op
+	default:
+		return name{}, errMangled
+	}
+	for d.consume("B") {
+		tag, err := d.sourceName()
+		if err != nil {
+			return name{}, err
+		}
+		n.text += "[abi:" + tag + "]"
+	}
+	return n, nil
+}
+
+func (d *demangler) sourceName() (string, error) {
+	n, err := d.number()
+	if err != nil || n <= 0 || d.pos+n > len(d.s) {
+		return "", errMangled
+	}
+	s := d.s[d.pos : d.pos+n]
+	d.pos += n
+	if strings.HasPrefix(s, "_GLOBAL_") && len(s) > 9 && (s[8] == '.' || s[8] == '_' || s[8] == '$') && s[9] == 'N' {
+		return "(anonymous namespace)", nil
+	}
+	return s, nil
+}
+
+// standardSubstitution is an abbreviation of the std namespace, the full
+// expansion is used when it names the class of a constructor or destructor
+// and in verbose mode.
+type standardSubstitution struct {
+	simple, full, class string
+}
+
+var standardSubstitutions = map[byte]standardSubstitution{
+	't': {"std", "std", ""},
+	'a': {"std::allocator", "std::allocator", "allocator"},
+	'b': {"std::basic_string", "std::basic_string", "basic_string"},
+	's': {"std::string", "std::basic_string<char, std::char_traits<char>, std::allocator<char> >", "basic_string"},
+	'i': {"std::istream", "std::basic_istream<char, std::char_traits<char> >", "basic_istream"},
+	'o': {"std::ostream", "std::basic_ostream<char, std::char_traits<char> >", "basic_ostream"},
+	'd': {"std::iostream", "std::basic_iostream<char, std::char_traits<char> >", "basic_iostream"},
+}
+
+// substitution decodes S_, S<seq-id>_ and the standard abbreviations. It
+// returns the substituted text, the class name a constructor would use and
+// whether it was a standard abbreviation.
+func (d *demangler) substitution() (string, string, bool, error) {
+	d.pos++
+	c := d.peek()
+	if s, ok := standardSubstitutions[c]; ok {
+		d.pos++
+		if next := d.peek(); next == 'C' || next == 'D' || d.verbose {
+			return s.full, s.class, true, nil
+		}
+		return s.simple, s.class, true, nil
+	}
+	t, err := d.substitutionRef()
+	if err != nil {
+		return "", "", false, err
+	}
+	text := t.String()
+	return text, lastComponent(text), false, nil
+}
+
+// substitutionRef decodes the <seq-id>_ following S, S_ is the first
+// candidate and S0_ the second.
+func (d *demangler) substitutionRef() (ctype, error) {
+	id := 0
+	if c := d.peek(); c != '_' {
+		for c = d.peek(); c != '_'; c = d.peek() {
+			switch {
+			case c >= '0' && c <= '9':
+				id = id*36 + int(c-'0')
+			case c >= 'A' && c <= 'Z':
+				id = id*36 + int(c-'A') + 10
+			default:
+				return ctype{}, errMangled
+			}
+			d.pos++
+		}
+		id++
+	}
+	d.pos++
+	if id >= len(d.subs) {
+		return ctype{}, errMangled
+	}
+	return d.subs[id], nil
+}
+
+func (d *demangler) templateParam() (ctype, error) {
+	d.pos++
+	id := 0
+	if d.peek() != '_' {
+		n, err := d.number()
+		if err != nil {
+			return ctype{}, err
+		}
+		id = n + 1
+	}
+	if !d.consume("_") || id >= len(d.templateArgs) {
+		return ctype{}, errMangled
+	}
+	if arg := d.templateArgs[id]; arg.pack != nil {
+		switch {
+		case d.packProbe:
+			d.packLen = len(arg.pack)
+			return ctype{}, nil
+		case d.packIndex >= 0 && d.packIndex < len(arg.pack):
+			return arg.pack[d.packIndex], nil
+		}
+		return ctype{}, errMangled
+	}
+	return d.templateArgs[id], nil
+}
+
+// templateArgList decodes I <template-arg>+ E as <a, b>.
+func (d *demangler) templateArgList() (string, error) {
+	d.pos++
+	d.depth++
+	defer func() { d.depth-- }()
+	var args []string
+	var types []ctype
+	emptyLast := false
+	for !d.consume("E") {
+		if d.pos >= len(d.s) {
+			return "", errMangled
+		}
+		var arg ctype
+		var err error
+		switch d.peek() {
+		case 'L':
+			arg.left, err = d.literal()
+		case 'J':
+			arg, err = d.argPack()
+		default:
+			arg, err = d.typ()
+		}
+		if err != nil {
+			return "", err
+		}
+		// 空的参数包不占位置
+		emptyLast = arg.pack != nil && len(arg.pack) == 0 && len(args) != 0
+		if arg.pack == nil || len(arg.pack) != 0 {
+			args = append(args, arg.String())
+		}
+		types = append(types, arg)
+	}
+	if d.depth == 1 {
+		d.nameArgs = types
+	}
+	s := "<" + strings.Join(args, ", ")
+	// c++filt在连续的>之间加空格，但最后是空参数包时它删掉的", "
+	// 让它以为前面是空格
+	if strings.HasSuffix(s, ">") && !emptyLast {
+		s += " "
+	}
+	return s + ">", nil
+}
+
+// argPack decodes J <template-arg>* E, an argument pack.
+func (d *demangler) argPack() (ctype, error) {
+	d.pos++
+	pack := ctype{pack: []ctype{}}
+	var names []string
+	for !d.consume("E") {
+		if d.pos >= len(d.s) {
+			return ctype{}, errMangled
+		}
+		var arg ctype
+		var err error
+		if d.peek() == 'L' {
+			arg.left, err = d.literal()
+		} else {
+			arg, err = d.typ()
+		}
+		if err != nil {
+			return ctype{}, err
+		}
+		pack.pack = append(pack.pack, arg)
+		names = append(names, arg.String())
+	}
+	pack.left = strings.Join(names, ", ")
+	return pack, nil
+}
+
+var literalSuffixes = map[byte]string{'i': "", 'j': "u", 'l': "l", 'm': "ul", 'x': "ll", 'y': "ull"}
+
+// literal decodes L <type> <value> E.
+func (d *demangler) literal() (string, error) {
+	d.pos++
+	if d.peek() == '_' && d.peekAt(1) == 'Z' {
+		d.pos += 2
+		enc, err := d.encoding(true)
+		if err != nil || !d.consume("E") {
+			return "", errMangled
+		}
+		return enc, nil
+	}
+	c := d.peek()
+	t, ok := builtinTypes[c]
+	if ok {
+		d.pos++
+	} else {
+		// 枚举等类型的常量输出为 (type)value
+		var err error
+		if t, err = d.typeString(); err != nil {
+			return "", err
+		}
+		c = 0
+	}
+	neg := d.consume("n")
+	v, err := d.number()
+	if err != nil || !d.consume("E") {
+		return "", errMangled
+	}
+	value := strconv.Itoa(v)
+	if neg {
+		value = "-" + value
+	}
+	if c == 'b' && (v == 0 || v == 1) && !neg {
+		return [...]string{"false", "true"}[v], nil
+	}
+	if suffix, ok := literalSuffixes[c]; ok {
+		return value + suffix, nil
+	}
+	return "(" + t + ")" + value, nil
+}
+
+var builtinTypes = map[byte]string{
+	'v': "void", 'w': "wchar_t", 'b': "bool", 'c': "char", 'a': "signed char",
+	'h': "unsigned char", 's': "short", 't': "unsigned short", 'i': "int",
+	'j': "unsigned int", 'l': "long", 'm': "unsigned long", 'x': "long long",
+	'y': "unsigned long long", 'n': "__int128", 'o': "unsigned __int128",
+	'f': "float", 'd': "double", 'e': "long double", 'g': "__float128", 'z': "...",
+}
+
+var builtinDTypes = map[byte]string{
+	'n': "decltype(nullptr)", 'i': "char32_t", 's': "char16_t", 'u': "char8_t",
+	'a': "auto", 'c': "decltype(auto)", 'd': "decimal64", 'e': "decimal128",
+	'f': "decimal32", 'h': "half",
+}
+
+// ctype is a decoded type printed as left + right. Function and array
+// types have a right part, a pointer to them goes between the two inside
+// parentheses: void (*)(int).
+type ctype struct {
+	left, right string
+	paren       bool    // The declarator parentheses are already there.
+	pack        []ctype // The elements of an argument pack, non nil for a pack.
+	ref         string  // "&" or "&&" for a reference type.
+}
+
+func (t ctype) String() string { return t.left + t.right }
+
+// declarator adds a pointer, reference or qualifier to a type.
+func (t ctype) declarator(op string) ctype {
+	if t.right != "" && !t.paren {
+		// 返回函数指针的函数：int (*(*)(int))()，括号紧跟在(和*之后，
+		// 成员指针前总有空格：int (* (A::*)())()
+		if strings.HasPrefix(op, " ") || !strings.HasSuffix(t.left, "(") && !strings.HasSuffix(t.left, "*") {
+			t.left = strings.TrimSuffix(t.left, " ") + " "
+		}
+		t.left += "("
+		if strings.HasPrefix(t.right, "[") {
+			t.right = " " + t.right
+		}
+		t.right = ")" + t.right
+		t.paren = true
+		op = strings.TrimPrefix(op, " ")
+	}
+	t.left += op
+	t.ref = ""
+	if op == "&" || op == "&&" {
+		t.ref = op
+	}
+	return t
+}
+
+// qualify adds a cv qualifier, which applies to the elements of an array
+// and is not repeated on an already qualified template parameter.
+func (t ctype) qualify(q string) ctype {
+	switch {
+	case strings.HasPrefix(t.right, "[") && !t.paren:
+		t.left = strings.TrimSuffix(t.left, " ") + q + " "
+	case t.right == "" && strings.HasSuffix(t.left, q):
+	default:
+		t = t.declarator(q)
+	}
+	return t
+}
+
+func (d *demangler) typeString() (string, error) {
+	t, err := d.typ()
+	return t.String(), err
+}
+
+// returnType decodes the return type of a template function, one needing
+// a declarator around the function name is not supported.
+func (d *demangler) returnType() (string, error) {
+	t, err := d.typ()
+	if err == nil && t.right != "" {
+		err = errMangled
+	}
+	return t.left, err
+}
+
+// functionType decodes F [Y] <return type> <parameter types> [<ref>] E.
+// A returned function pointer wraps the parameters: int (*(int))().
+func (d *demangler) functionType() (ctype, error) {
+	d.pos++
+	d.consume("Y")
+	ret, err := d.typ()
+	if err != nil {
+		return ctype{}, err
+	}
+	var params []string
+	for !d.consume("E") {
+		if d.pos >= len(d.s) || (d.peek() == 'R' || d.peek() == 'O') && d.peekAt(1) == 'E' {
+			return ctype{}, errMangled
+		}
+		p, err := d.types()
+		if err != nil {
+			return ctype{}, err
+		}
+		params = append(params, p...)
+	}
+	if len(params) == 1 && params[0] == "void" {
+		params = nil
+	}
+	list := "(" + strings.Join(params, ", ") + ")"
+	if ret.right != "" {
+		return ctype{left: ret.left, right: list + ret.right}, nil
+	}
+	return ctype{left: ret.left + " ", right: list}, nil
+}
+
+// types decodes a type of a list, a pack expansion Dp <type> gives one
+// type per element of the argument pack it refers to.
+func (d *demangler) types() ([]string, error) {
+	if !d.consume("Dp") {
+		t, err := d.typeString()
+		return []string{t}, err
+	}
+	if d.packIndex >= 0 || d.packProbe {
+		return nil, errMangled
+	}
+	// 先试解析一遍得到参数包的长度，再对每个元素重新解析
+	start, subs := d.pos, len(d.subs)
+	d.packProbe, d.packLen = true, -1
+	_, err := d.typ()
+	d.packProbe = false
+	if err != nil || d.packLen < 0 {
+		return nil, errMangled
+	}
+	end := d.pos
+	var list []string
+	for i := 0; i < d.packLen; i++ {
+		d.pos, d.subs, d.packIndex = start, d.subs[:subs], i
+		t, err := d.typeString()
+		d.packIndex = -1
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, t)
+	}
+	d.pos = end
+	return list, nil
+}
+
+// typ decodes a <type>, every type but the builtin ones and the plain
+// substitutions becomes a substitution candidate.
+func (d *demangler) typ() (ctype, error) {
+	c := d.peek()
+	if t, ok := builtinTypes[c]; ok {
+		d.pos++
+		return ctype{left: t}, nil
+	}
+	var t ctype
+	var err error
+	switch c {
+	case 'D':
+		dt, ok := builtinDTypes[d.peekAt(1)]
+		if !ok {
+			return ctype{}, errMangled
+		}
+		d.pos += 2
+		return ctype{left: dt}, nil
+	case 'K', 'V', 'r':
+		// 连续的限定符合成一个类型，按const、volatile、restrict的顺序输出
+		quals := map[byte]bool{}
+		for c = d.peek(); c == 'K' || c == 'V' || c == 'r'; c = d.peek() {
+			quals[c] = true
+			d.pos++
+		}
+		function := d.peek() == 'F'
+		if t, err = d.typ(); err != nil {
+			return ctype{}, err
+		}
+		for _, q := range []struct {
+			code byte
+			text string
+		}{{'K', " const"}, {'V', " volatile"}, {'r', " restrict"}} {
+			switch {
+			case !quals[q.code]:
+			case function:
+				// 限定的函数类型：int () const
+				t.right += q.text
+			default:
+				t = t.qualify(q.text)
+			}
+		}
+	case 'P', 'R', 'O':
+		d.pos++
+		if t, err = d.typ(); err != nil {
+			return ctype{}, err
+		}
+		// 引用折叠：T为X&或X&&时，T&都是X&，T&&保持原样
+		if c != 'P' && t.ref != "" {
+			if c == 'R' && t.ref == "&&" {
+				t.left, t.ref = strings.TrimSuffix(t.left, "&&")+"&", "&"
+			}
+			break
+		}
+		t = t.declarator(map[byte]string{'P': "*", 'R': "&", 'O': "&&"}[c])
+	case 'F':
+		if t, err = d.functionType(); err != nil {
+			return ctype{}, err
+		}
+	case 'A':
+		d.pos++
+		n, err := d.number()
+		if err != nil || !d.consume("_") {
+			return ctype{}, errMangled
+		}
+		elem, err := d.typ()
+		if err != nil || elem.right != "" {
+			return ctype{}, errMangled
+		}
+		t = ctype{left: elem.left + " ", right: "[" + strconv.Itoa(n) + "]"}
+	case 'M':
+		d.pos++
+		class, err := d.typeString()
+		if err != nil {
+			return ctype{}, err
+		}
+		if t, err = d.typ(); err != nil {
+			return ctype{}, err
+		}
+		t = t.declarator(" " + class + "::*")
+	case 'T':
+		if t, err = d.templateParam(); err != nil {
+			return ctype{}, err
+		}
+		if d.peek() == 'I' {
+			d.subs = append(d.subs, t)
+			args, err := d.templateArgList()
+			if err != nil {
+				return ctype{}, err
+			}
+			t.left += args
+		}
+	case 'S':
+		next := d.peekAt(1)
+		if next >= '0' && next <= '9' || next == '_' || next >= 'A' && next <= 'Z' {
+			d.pos++
+			if t, err = d.substitutionRef(); err != nil {
+				return ctype{}, err
+			}
+			if d.peek() != 'I' {
+				return t, nil
+			}
+			args, err := d.templateArgList()
+			if err != nil {
+				return ctype{}, err
+			}
+			t = ctype{left: appendArgs(t.String(), args)}
+			break
+		}
+		fallthrough
+	case 'N', 'Z', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		n, err := d.name()
+		if err != nil || n.std {
+			return ctype{left: n.text}, err
+		}
+		t = ctype{left: n.text}
+	default:
+		return ctype{}, errMangled
+	}
+	d.subs = append(d.subs, t)
+	return t, nil
+}
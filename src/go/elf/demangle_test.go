@@ -0,0 +1,93 @@
+package elf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The expected names are the output of nm -C (binutils 2.40) on an object
+// defining the mangled names, most of which come from the exports of
+// libstdc++ and libLLVM. nm keeps the abbreviations of std short, unlike
+// c++filt, see TestDemangleVerbose.
+func TestDemangle(t *testing.T) {
+	for _, c := range []struct{ mangled, want string }{
+		// 不是C++名字或无法解析的保持原样
+		{"main", "main"},
+		{"_Z", "_Z"},
+		{"_ZN1A", "_ZN1A"},
+		{"_ZN1AC2Ev", "A::A()"},
+		{"_ZN1AD1Ev", "A::~A()"},
+		{"_ZN1AcvPKcEv", "A::operator char const*()"},
+		{"_ZNKSi6sentrycvbEv", "std::istream::sentry::operator bool() const"},
+		{"_ZNSolsEDn", "std::ostream::operator<<(decltype(nullptr))"},
+		{"_ZNKSs11_M_disjunctEPKc", "std::string::_M_disjunct(char const*) const"},
+		{"_ZNKSt7__cxx118numpunctIcE12do_falsenameEv", "std::__cxx11::numpunct<char>::do_falsename() const"},
+		{"_ZNKSt3_V214error_category10_M_messageB5cxx11Ei", "std::_V2::error_category::_M_message[abi:cxx11](int) const"},
+		{"_ZN12_GLOBAL__N_13fooEv", "(anonymous namespace)::foo()"},
+		{"_Z1fPVKc", "f(char const volatile*)"},
+		{"_Z1fDh", "f(half)"},
+		// 特殊名字
+		{"_ZTVN10__cxxabiv116__enum_type_infoE", "vtable for __cxxabiv1::__enum_type_info"},
+		{"_ZGVNSt10moneypunctIcLb0EE2idE", "guard variable for std::moneypunct<char, false>::id"},
+		{"_ZThn16_NSdD0Ev", "non-virtual thunk to std::basic_iostream<char, std::char_traits<char> >::~basic_iostream()"},
+		{"_ZTv0_n24_NSdD0Ev", "virtual thunk to std::basic_iostream<char, std::char_traits<char> >::~basic_iostream()"},
+		{"_ZGTtNSt11logic_errorD0Ev", "transaction clone for std::logic_error::~logic_error()"},
+		// 模板、字面量与局部名字
+		{"_Z1fIiEvT_", "void f<int>(int)"},
+		{"_ZNSt12__shared_ptrINSt10filesystem4_DirELN9__gnu_cxx12_Lock_policyE2EEC1Ev",
+			"std::__shared_ptr<std::filesystem::_Dir, (__gnu_cxx::_Lock_policy)2>::__shared_ptr()"},
+		{"_ZZNSt8__detail18__to_chars_10_implIjEEvPcjT_E8__digits",
+			"std::__detail::__to_chars_10_impl<unsigned int>(char*, unsigned int, unsigned int)::__digits"},
+		{"_ZZ4mainE1x_0", "main::x"},
+		// 函数指针、成员指针与数组
+		{"_ZN9__gnu_cxx6__poolILb1EE13_M_initializeEPFvPvE", "__gnu_cxx::__pool<true>::_M_initialize(void (*)(void*))"},
+		{"_ZNSolsEPFRSt9basic_iosIcSt11char_traitsIcEES3_E",
+			"std::ostream::operator<<(std::basic_ios<char, std::char_traits<char> >& (*)(std::basic_ios<char, std::char_traits<char> >&))"},
+		{"_Z1fPFPFivEiE", "f(int (*(*)(int))())"},
+		{"_Z1fPFPFPFivEcEiE", "f(int (*(*(*)(int))(char))())"},
+		{"_Z1fRFPFivEvE", "f(int (*(&)())())"},
+		{"_Z1fPFRFvvEvE", "f(void (& (*)())())"},
+		{"_Z1fPFPA3_ivE", "f(int (*(*)()) [3])"},
+		{"_Z1fM1AFPFivEvE", "f(int (* (A::*)())())"},
+		{"_Z1fPFPKFivEvE", "f(int (*(*)())() const)"},
+		{"_Z1fM1AFivE", "f(int (A::*)())"},
+		{"_Z1fM1AKFivE", "f(int (A::*)() const)"},
+		{"_Z1fM1Ai", "f(int A::*)"},
+		{"_Z1fPA10_i", "f(int (*) [10])"},
+		{"_Z1fRKA3_i", "f(int const (&) [3])"},
+		// 参数包与引用折叠
+		{"_Z1fIJEEvDpT_", "void f<>()"},
+		{"_ZNSt6vectorIN4llvm7PatternESaIS1_EE12emplace_backIJS1_EEEvDpOT_",
+			"void std::vector<llvm::Pattern, std::allocator<llvm::Pattern> >::emplace_back<llvm::Pattern>(llvm::Pattern&&)"},
+		{"_ZN4llvm10make_errorINS_8DWPErrorEJRA36_KcEEENS_5ErrorEDpOT0_",
+			"llvm::Error llvm::make_error<llvm::DWPError, char const (&) [36]>(char const (&) [36])"},
+		{"_ZTVN4llvm6detail9PassModelINS_8FunctionENS_12VerifierPassENS_17PreservedAnalysesENS_15AnalysisManagerIS2_JEEEJEEE",
+			"vtable for llvm::detail::PassModel<llvm::Function, llvm::VerifierPass, llvm::PreservedAnalyses, llvm::AnalysisManager<llvm::Function>>"},
+		// 版本与克隆后缀
+		{"_ZNSs6insertEN9__gnu_cxx17__normal_iteratorIPcSsEEc@@GLIBCXX_3.4",
+			"std::string::insert(__gnu_cxx::__normal_iterator<char*, std::string>, char)@@GLIBCXX_3.4"},
+		{"_Z3foov.isra.0.cold", "foo() [clone .isra.0] [clone .cold]"},
+	} {
+		assert.Equal(t, c.want, Demangle(c.mangled), c.mangled)
+	}
+}
+
+// The expected names are the output of c++filt (binutils 2.40), which
+// expands the abbreviations of std.
+func TestDemangleVerbose(t *testing.T) {
+	for _, c := range []struct{ mangled, want string }{
+		{"_ZNKSi6sentrycvbEv", "std::basic_istream<char, std::char_traits<char> >::sentry::operator bool() const"},
+		{"_ZNSolsEDn", "std::basic_ostream<char, std::char_traits<char> >::operator<<(decltype(nullptr))"},
+		{"_ZNKSs11_M_disjunctEPKc", "std::basic_string<char, std::char_traits<char>, std::allocator<char> >::_M_disjunct(char const*) const"},
+		{"_Z1fPFPFivEiE", "f(int (*(*)(int))())"},
+		{"_Z1fNSs8iteratorE", "f(std::basic_string<char, std::char_traits<char>, std::allocator<char> >::iterator)"},
+		{"_ZTVSo", "vtable for std::basic_ostream<char, std::char_traits<char> >"},
+		{"_ZNSdD0Ev", "std::basic_iostream<char, std::char_traits<char> >::~basic_iostream()"},
+		{"_ZNSaIcE8allocateEm", "std::allocator<char>::allocate(unsigned long)"},
+		{"_ZNSs6insertEN9__gnu_cxx17__normal_iteratorIPcSsEEc",
+			"std::basic_string<char, std::char_traits<char>, std::allocator<char> >::insert(__gnu_cxx::__normal_iterator<char*, std::basic_string<char, std::char_traits<char>, std::allocator<char> > >, char)"},
+	} {
+		assert.Equal(t, c.want, DemangleVerbose(c.mangled), c.mangled)
+	}
+}
@@ -0,0 +1,265 @@
+package elf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// DumpOptions selects how the bytes of a section are read by the readelf
+// style -x, -R and -p dumps.
+type DumpOptions struct {
+	// Relocate applies the relocations targeting the section before it is
+	// shown (readelf -R). Only relocatable files are affected.
+	Relocate bool
+	// Decompress inflates SHF_COMPRESSED sections and the older .zdebug
+	// "ZLIB" sections (readelf -z). Without it the bytes are shown as they
+	// are stored in the file.
+	Decompress bool
+}
+
+// LookupSection resolves a readelf style section argument, either a
+// section name or its index, and returns the section index.
+func (p *Parser) LookupSection(arg string) (int, error) {
+	sections := p.F.Sections()
+	if idx, err := strconv.Atoi(arg); err == nil {
+		if idx < 0 || idx >= len(sections) {
+			return 0, fmt.Errorf("section %d was not dumped because it does not exist", idx)
+		}
+		return idx, nil
+	}
+	for i, s := range sections {
+		if s.SectionName == arg {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("section '%s' was not dumped because it does not exist", arg)
+}
+
+// section returns the section at index i.
+func (p *Parser) section(i int) (*ELF64Section, error) {
+	sections := p.F.Sections()
+	if i < 0 || i >= len(sections) {
+		return nil, fmt.Errorf("section %d does not exist", i)
+	}
+	return sections[i], nil
+}
+
+// SectionContents returns the bytes of section index i as selected by o.
+// SHT_NOBITS sections have no bytes in the file and return nothing.
+func (p *Parser) SectionContents(i int, o DumpOptions) ([]byte, error) {
+	s, err := p.section(i)
+	if err != nil || SectionType(s.Type) == SHT_NOBITS {
+		return nil, err
+	}
+	var data []byte
+	if o.Decompress {
+		// 节头的大小不可信，读取之前先与文件大小核对
+		if err = s.checkBounds(p.F.size); err == nil {
+			data, err = s.Data()
+		}
+		if err == nil {
+			data, err = decompressZdebug(data)
+		}
+	} else {
+		data, err = s.rawData(p.F.size)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("section '%s': %w", s.SectionName, err)
+	}
+	if o.Relocate {
+		if err := p.relocateSection(i, data); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// decompressZdebug inflates the pre SHF_COMPRESSED format of the .zdebug
+// sections: "ZLIB", the big endian uncompressed size and a zlib stream.
+// Data that does not start with the magic is returned unchanged.
+func decompressZdebug(data []byte) ([]byte, error) {
+	if len(data) <= 12 || string(data[:4]) != "ZLIB" {
+		return data, nil
+	}
+	size := binary.BigEndian.Uint64(data[4:12])
+	zr, err := zlib.NewReader(bytes.NewReader(data[12:]))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	// 头部记录的大小不可信，用它做上限而不是直接分配
+	var out bytes.Buffer
+	if _, err := io.Copy(&out, io.LimitReader(zr, int64(size))); err != nil {
+		return nil, err
+	}
+	if uint64(out.Len()) != size {
+		return nil, errors.New("truncated ZLIB section data")
+	}
+	return out.Bytes(), nil
+}
+
+// hasNoData reports the sections readelf refuses to dump.
+func hasNoData(s *ELF64Section) bool {
+	return SectionType(s.Type) == SHT_NOBITS || s.ELF64SectionHeader.Size == 0
+}
+
+/*
+[root@rockylinux-ebpf ~/parser-elf/example]# readelf -x .debug_info go-relocation-test-gcc441-x86-64.obj
+
+Hex dump of section '.debug_info':
+ NOTE: This section has relocations against it, but these have NOT been applied to this dump.
+  0x00000000 4f000000 02000000 00000801 00000000 O...............
+  0x00000010 01000000 00000000 00000000 00000000 ................
+*/
+// WriteHexDump writes section index i as hex bytes like readelf -x, or -R
+// when o.Relocate is set. Addresses start at sh_addr.
+func (p *Parser) WriteHexDump(w io.Writer, i int, o DumpOptions) error {
+	s, err := p.section(i)
+	if err != nil {
+		return err
+	}
+	ew := &errWriter{w: w}
+	if hasNoData(s) {
+		ew.printf("Section '%s' has no data to dump.\n", s.SectionName)
+		return ew.err
+	}
+	data, err := p.SectionContents(i, o)
+	if err != nil {
+		return err
+	}
+	ew.printf("\nHex dump of section '%s':\n", s.SectionName)
+	if !o.Relocate && len(p.relocationSections(i)) != 0 {
+		ew.printf(" NOTE: This section has relocations against it, but these have NOT been applied to this dump.\n")
+	}
+	var line bytes.Buffer
+	for off := 0; off < len(data); off += 16 {
+		line.Reset()
+		fmt.Fprintf(&line, "  0x%.8x ", s.Addr+uint64(off))
+		for j := off; j < off+16; j++ {
+			if j < len(data) {
+				fmt.Fprintf(&line, "%.2x", data[j])
+			} else {
+				line.WriteString("  ")
+			}
+			if j%4 == 3 {
+				line.WriteByte(' ')
+			}
+		}
+		for j := off; j < off+16 && j < len(data); j++ {
+			if data[j] >= 0x20 && data[j] < 0x7f {
+				line.WriteByte(data[j])
+			} else {
+				line.WriteByte('.')
+			}
+		}
+		ew.printf("%s\n", line.Bytes())
+	}
+	ew.printf("\n")
+	return ew.err
+}
+
+/*
+[root@rockylinux-ebpf ~/parser-elf/example]# readelf -p .comment gcc-amd64-linux-exec
+
+String dump of section '.comment':
+  [     1]  GCC: (GNU) 4.1.0 (SUSE Linux)
+*/
+// WriteStringDump writes the printable strings of section index i like
+// readelf -p. Control characters are shown as ^X and a newline ends the
+// line, the rest of such a string is printed on an indented line.
+func (p *Parser) WriteStringDump(w io.Writer, i int, o DumpOptions) error {
+	s, err := p.section(i)
+	if err != nil {
+		return err
+	}
+	ew := &errWriter{w: w}
+	if hasNoData(s) {
+		ew.printf("Section '%s' has no data to dump.\n", s.SectionName)
+		return ew.err
+	}
+	data, err := p.SectionContents(i, o)
+	if err != nil {
+		return err
+	}
+	ew.printf("\nString dump of section '%s':\n", s.SectionName)
+	if !o.Relocate && len(p.relocationSections(i)) != 0 {
+		ew.printf("  Note: This section has relocations against it, but these have NOT been applied to this dump.\n")
+	}
+	found, continuing := false, false
+	var line bytes.Buffer
+	for off := 0; off < len(data); {
+		// 字符串只从可打印字符开始，前面的控制字符和高位字节都跳过
+		if data[off] < 0x20 || data[off] >= 0x7f {
+			off++
+			continue
+		}
+		line.Reset()
+		if continuing {
+			line.WriteString("            ")
+		} else {
+			fmt.Fprintf(&line, "  [%6x]  ", off)
+		}
+		continuing = false
+		var c byte
+		for off < len(data) {
+			c = data[off]
+			off++
+			if c == 0 {
+				break
+			}
+			if c == '\n' {
+				line.WriteString("\\n")
+				continuing = off < len(data) && data[off] != 0
+				break
+			}
+			if c < 0x20 || c == 0x7f {
+				line.WriteByte('^')
+				line.WriteByte(c + 0x40)
+			} else {
+				line.WriteByte(c)
+			}
+		}
+		line.WriteByte('\n')
+		ew.printf("%s", line.Bytes())
+		found = true
+	}
+	if !found {
+		ew.printf("  No strings found in this section.")
+	}
+	ew.printf("\n")
+	return ew.err
+}
+
+// ExtractSection writes the contents of section index i, decompressed,
+// like objcopy -O binary -j. SHT_NOBITS sections have no contents, so
+// nothing is written for them. Unlike objcopy the sections that are not
+// SHF_ALLOC are written too.
+func (p *Parser) ExtractSection(w io.Writer, i int) error {
+	data, err := p.SectionContents(i, DumpOptions{Decompress: true})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// ExtractSegment writes the p_filesz bytes of program header index i as
+// they are stored in the file. The zero-fill past p_filesz is not part of
+// the file and is not written.
+func (p *Parser) ExtractSegment(w io.Writer, i int) error {
+	progs := p.F.ProgramHeaders()
+	if i < 0 || i >= len(progs) {
+		return fmt.Errorf("segment %d does not exist", i)
+	}
+	ph := progs[i]
+	if ph.Off > uint64(p.F.size) || ph.Filesz > uint64(p.F.size)-ph.Off {
+		return fmt.Errorf("segment %d extends past the end of the file", i)
+	}
+	_, err := io.Copy(w, io.NewSectionReader(p.F.r, int64(ph.Off), int64(ph.Filesz)))
+	return err
+}
@@ -0,0 +1,228 @@
+package elf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The golden files are the output of GNU readelf dumping every section:
+//
+//	readelf [-z] -x|-R|-p 0 -x|-R|-p 1 ... <file> > <file>.<hex|relocated|strings>[.z].golden
+func TestWriteSectionDumpGolden(t *testing.T) {
+	goldens, err := filepath.Glob(path.Join("testdata", "dump", "*.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, goldens)
+	for _, golden := range goldens {
+		name := strings.TrimSuffix(filepath.Base(golden), ".golden")
+		var o DumpOptions
+		if strings.HasSuffix(name, ".z") {
+			o.Decompress = true
+			name = strings.TrimSuffix(name, ".z")
+		}
+		kind := path.Ext(name)
+		name = strings.TrimSuffix(name, kind)
+		o.Relocate = kind == ".relocated"
+		t.Run(filepath.Base(golden), func(t *testing.T) {
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := parseFile(t, path.Join(exampleDir, name))
+			var out bytes.Buffer
+			for i := range p.F.Sections() {
+				if kind == ".strings" {
+					err = p.WriteStringDump(&out, i, o)
+				} else {
+					err = p.WriteHexDump(&out, i, o)
+				}
+				if !assert.NoError(t, err) {
+					return
+				}
+			}
+			assert.Equal(t, string(want), out.String())
+		})
+	}
+}
+
+func TestLookupSection(t *testing.T) {
+	p := parseFile(t, path.Join(exampleDir, "gcc-amd64-linux-exec"))
+	i, err := p.LookupSection(".interp")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, i)
+	i, err = p.LookupSection("1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, i)
+	_, err = p.LookupSection(".nope")
+	assert.EqualError(t, err, "section '.nope' was not dumped because it does not exist")
+	_, err = p.LookupSection("99")
+	assert.EqualError(t, err, "section 99 was not dumped because it does not exist")
+}
+
+func TestSectionContents(t *testing.T) {
+	for _, tc := range []struct {
+		file, section string
+		raw, size     int
+	}{
+		// SHF_COMPRESSED, 32 and 64-bit
+		{"compressed-32.obj", ".debug_info", 0x84, 0xb4},
+		{"compressed-64.obj", ".debug_info", 0x72, 0xba},
+		// .zdebug ZLIB header
+		{"zdebug-test-gcc484-x86-64.obj", ".zdebug_str", 0xaf, 0xed},
+		{"gcc-amd64-linux-exec", ".interp", 0x1c, 0x1c},
+		{"gcc-amd64-linux-exec", ".bss", 0, 0},
+	} {
+		p := parseFile(t, path.Join(exampleDir, tc.file))
+		i, err := p.LookupSection(tc.section)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		raw, err := p.SectionContents(i, DumpOptions{})
+		assert.NoError(t, err)
+		assert.Len(t, raw, tc.raw, "%s %s", tc.file, tc.section)
+		data, err := p.SectionContents(i, DumpOptions{Decompress: true})
+		assert.NoError(t, err)
+		assert.Len(t, data, tc.size, "%s %s", tc.file, tc.section)
+
+		var out bytes.Buffer
+		assert.NoError(t, p.ExtractSection(&out, i))
+		assert.Equal(t, data, out.Bytes())
+	}
+}
+
+func TestSectionContentsRelocate(t *testing.T) {
+	// readelf -r: R_X86_64_32 .debug_str + 0x21 at 0x15 of .debug_info
+	p := parseFile(t, path.Join(exampleDir, "go-relocation-test-gcc441-x86-64.obj"))
+	i, err := p.LookupSection(".debug_info")
+	if !assert.NoError(t, err) {
+		return
+	}
+	data, err := p.SectionContents(i, DumpOptions{Relocate: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x21, 0, 0, 0}, data[0x15:0x19])
+
+	// 已链接的文件不再处理重定位
+	p = parseFile(t, path.Join(exampleDir, "gcc-amd64-linux-exec"))
+	i, err = p.LookupSection(".got")
+	if !assert.NoError(t, err) {
+		return
+	}
+	raw, err := p.SectionContents(i, DumpOptions{})
+	assert.NoError(t, err)
+	data, err = p.SectionContents(i, DumpOptions{Relocate: true})
+	assert.NoError(t, err)
+	assert.Equal(t, raw, data)
+}
+
+func TestExtractSegment(t *testing.T) {
+	p := parseFile(t, path.Join(exampleDir, "gcc-amd64-linux-exec"))
+	for i, ph := range p.F.ProgramHeaders() {
+		var out bytes.Buffer
+		assert.NoError(t, p.ExtractSegment(&out, i))
+		assert.Equal(t, int(ph.Filesz), out.Len())
+	}
+	// PT_INTERP的内容就是.interp节
+	var out bytes.Buffer
+	assert.NoError(t, p.ExtractSegment(&out, 1))
+	assert.Equal(t, "/lib64/ld-linux-x86-64.so.2\x00", out.String())
+	assert.Error(t, p.ExtractSegment(&out, 99))
+}
+
+func TestHexDumpData(t *testing.T) {
+	p := parseFile(t, path.Join(exampleDir, "gcc-386-freebsd-exec"))
+	s := p.F.Get32SectionByName(".interp")
+	if !assert.NotNil(t, s) {
+		return
+	}
+	dump, err := s.HexDumpData()
+	assert.NoError(t, err)
+	assert.Equal(t, "00000000  2f 6c 69 62 65 78 65 63  2f 6c 64 2d 65 6c 66 2e  |/libexec/ld-elf.|\n"+
+		"00000010  73 6f 2e 31 00                                    |so.1.|\n", dump)
+}
+
+func TestDumpTablesPastEOF(t *testing.T) {
+	p := parseFile(t, path.Join(exampleDir, "gcc-amd64-linux-exec"))
+	for _, dump := range []func() error{p.DumpSectionHeaders, p.DumpRelaDynSection, p.DumpRelaPltSection, p.DumpGotSection, p.DumpGotPltSection} {
+		assert.NoError(t, dump())
+	}
+	p.CloseFile()
+
+	// 节的内容超出文件时返回错误，而不是panic或按sh_size分配内存
+	le := binary.LittleEndian
+	for i, dump := range map[int]func(*Parser) error{
+		9:  (*Parser).DumpRelaDynSection,
+		10: (*Parser).DumpRelaPltSection,
+		22: (*Parser).DumpGotSection,
+		23: (*Parser).DumpGotPltSection,
+	} {
+		p := mutatedExec(t, func(data []byte, f *Parser) {
+			le.PutUint64(data[le.Uint64(data[0x28:])+uint64(i)*64+0x20:], 1<<40)
+		})
+		err := dump(p)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "extends past the end of the file")
+		}
+		p.CloseFile()
+	}
+
+	// 节名无效时Parse已经放弃节头表，这里直接修改解析结果
+	p = parseFile(t, path.Join(exampleDir, "gcc-amd64-linux-exec"))
+	defer p.CloseFile()
+	p.F.SectionHeaders64[1].Name = 0xffff
+	assert.EqualError(t, p.DumpSectionHeaders(), "section 1: invalid name offset 0xffff in the section header strings table")
+	p.F.Header64.Shstrndx = 99
+	assert.EqualError(t, p.DumpSectionHeaders(), "section header string table index 99 is out of range")
+}
+
+func TestSectionContentsMalformed(t *testing.T) {
+	// sh_size越过文件末尾：-x、-p和导出都返回错误，不按sh_size分配
+	le := binary.LittleEndian
+	p := mutatedExec(t, func(data []byte, f *Parser) {
+		le.PutUint64(data[le.Uint64(data[0x28:])+26*64+0x20:], 1<<40)
+	})
+	defer p.CloseFile()
+	assert.Equal(t, ".comment", p.F.Sections()[26].SectionName)
+	want := "section '.comment': contents [0x898, +0x10000000000) extend past the end of the file"
+	assert.EqualError(t, p.WriteHexDump(ioutil.Discard, 26, DumpOptions{}), want)
+	assert.EqualError(t, p.WriteStringDump(ioutil.Discard, 26, DumpOptions{Decompress: true}), want)
+	assert.EqualError(t, p.ExtractSection(ioutil.Discard, 26), want)
+
+	// ch_size(压缩头中解压后的大小)被改大
+	data, err := ioutil.ReadFile(path.Join(exampleDir, "compressed-64.obj"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	le.PutUint64(data[0x68+8:], 1<<40)
+	p, err = NewBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	i, err := p.LookupSection(".debug_info")
+	assert.NoError(t, err)
+	assert.EqualError(t, p.ExtractSection(ioutil.Discard, i),
+		"section '.debug_info': uncompressed size 0x10000000000 is larger than 0x72 compressed bytes can inflate to")
+	// 在比例之内但比实际数据大的ch_size只是上限，Data读到多少返回多少
+	raw, err := p.SectionContents(i, DumpOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, raw, 0x72)
+	le.PutUint64(data[0x68+8:], 0x1000)
+	p, _ = NewBytes(data)
+	if err := p.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	out, err := p.F.Sections()[i].Data()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.Len(t, out, 0xba)
+}
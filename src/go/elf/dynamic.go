@@ -0,0 +1,351 @@
+// Package elf : dynamic.go decodes the dynamic linking information reached
+// through the PT_DYNAMIC segment, it does not depend on the section headers
+// and is used to rebuild packed or sstrip'ed binaries.
+package elf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DynamicEntry is a class independent representation of an Elf_Dyn entry.
+type DynamicEntry struct {
+	Tag DynTag `json:"tag"`
+	Val uint64 `json:"value"`
+}
+
+// Relocation is a class independent representation of a Rel/Rela entry
+// referenced by the dynamic table.
+type Relocation struct {
+	Off       uint64 `json:"offset"`
+	Info      uint64 `json:"info"`
+	Type      uint32 `json:"type"`
+	Sym       uint32 `json:"symbol_index"` // Index into the dynamic symbol table.
+	Addend    int64  `json:"addend"`
+	HasAddend bool   `json:"has_addend"` // True for Rela entries.
+	// Table is the dynamic tag the entry was found through, DT_JMPREL for
+	// PLT relocations, DT_RELA or DT_REL otherwise. It is DT_NULL for
+	// relocations read from a section.
+	Table DynTag `json:"table"`
+}
+
+// ELFDynamic holds the information decoded from the dynamic table.
+type ELFDynamic struct {
+	DynamicEntries []DynamicEntry `json:",omitempty"`
+	Needed         []string       `json:",omitempty"`
+	DynRelocations []Relocation   `json:",omitempty"`
+	// FromSegments is set when the section header table was unusable and
+	// symbols, versions and relocations were rebuilt from PT_DYNAMIC.
+	FromSegments bool `json:",omitempty"`
+}
+
+// DynValue returns the value of the first dynamic entry with the given tag.
+func (f *File) DynValue(tag DynTag) (uint64, bool) {
+	for _, d := range f.DynamicEntries {
+		if d.Tag == tag {
+			return d.Val, true
+		}
+	}
+	return 0, false
+}
+
+// dynamicData returns the raw content of the dynamic table, the PT_DYNAMIC
+// segment is preferred and the SHT_DYNAMIC section is used as a fallback.
+func (p *Parser) dynamicData() ([]byte, error) {
+	for _, ph := range p.F.ProgramHeaders() {
+		if ProgType(ph.Type) != PT_DYNAMIC {
+			continue
+		}
+		// p_filesz未经校验，超出文件的段在分配之前就拒绝
+		if ph.Off > uint64(p.F.size) || ph.Filesz > uint64(p.F.size)-ph.Off {
+			return nil, fmt.Errorf("PT_DYNAMIC segment at offset %#x of %#x bytes overflows the file", ph.Off, ph.Filesz)
+		}
+		data := make([]byte, ph.Filesz)
+		n, err := p.fs.ReadAt(data, int64(ph.Off))
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		return data[:n], nil
+	}
+	if s := p.F.SectionByType(SHT_DYNAMIC); s != nil {
+		return s.Data()
+	}
+	return nil, ErrNoDynamic
+}
+
+// ParseDynamic decodes the dynamic table, the needed libraries and the
+// dynamic relocations.
+// 动态表以DT_NULL结尾，表项数量需要遍历得出
+func (p *Parser) ParseDynamic() error {
+	data, err := p.dynamicData()
+	if err != nil {
+		return err
+	}
+	r := bytes.NewReader(data)
+	var entries []DynamicEntry
+	for r.Len() > 0 {
+		var d DynamicEntry
+		switch p.F.Class() {
+		case ELFCLASS32:
+			var dyn ELF32DynamicTableEntry
+			if err := binary.Read(r, p.F.ByteOrder(), &dyn); err != nil {
+				return err
+			}
+			d = DynamicEntry{Tag: DynTag(dyn.Tag), Val: uint64(dyn.Val)}
+		case ELFCLASS64:
+			var dyn ELF64DynamicTableEntry
+			if err := binary.Read(r, p.F.ByteOrder(), &dyn); err != nil {
+				return err
+			}
+			d = DynamicEntry{Tag: DynTag(dyn.Tag), Val: dyn.Val}
+		default:
+			return ErrBadELFClass
+		}
+		entries = append(entries, d)
+		if d.Tag == DT_NULL {
+			break
+		}
+	}
+	p.F.DynamicEntries = entries
+
+	// DT_NEEDED的值是DT_STRTAB字符串表中的偏移
+	strtab, err := p.dynamicStringTable()
+	if err == nil {
+		p.F.Needed = nil
+		for _, d := range entries {
+			if d.Tag != DT_NEEDED {
+				continue
+			}
+			name, _ := getString(strtab, int(d.Val))
+			p.F.Needed = append(p.F.Needed, name)
+		}
+	}
+
+	relocs, err := p.dynamicRelocations()
+	if err != nil {
+		return err
+	}
+	p.F.DynRelocations = relocs
+	return nil
+}
+
+// dynamicStringTable reads the string table given by DT_STRTAB and DT_STRSZ.
+func (p *Parser) dynamicStringTable() ([]byte, error) {
+	addr, ok := p.F.DynValue(DT_STRTAB)
+	if !ok {
+		return nil, errors.New("dynamic table has no DT_STRTAB entry")
+	}
+	size, ok := p.F.DynValue(DT_STRSZ)
+	if !ok {
+		return p.F.fileImage(addr)
+	}
+	return p.F.ReadVirtual(addr, int(size))
+}
+
+// dynamicRelocations decodes the tables referenced by DT_RELA, DT_REL and DT_JMPREL.
+func (p *Parser) dynamicRelocations() ([]Relocation, error) {
+	var relocs []Relocation
+	tables := []struct {
+		addr, size DynTag
+		rela       bool
+	}{
+		{DT_RELA, DT_RELASZ, true},
+		{DT_REL, DT_RELSZ, false},
+	}
+	for _, t := range tables {
+		addr, ok := p.F.DynValue(t.addr)
+		if !ok {
+			continue
+		}
+		size, _ := p.F.DynValue(t.size)
+		r, err := p.readRelocations(addr, size, t.rela, t.addr)
+		if err != nil {
+			return nil, err
+		}
+		relocs = append(relocs, r...)
+	}
+	if addr, ok := p.F.DynValue(DT_JMPREL); ok {
+		size, _ := p.F.DynValue(DT_PLTRELSZ)
+		pltrel, _ := p.F.DynValue(DT_PLTREL)
+		r, err := p.readRelocations(addr, size, DynTag(pltrel) == DT_RELA, DT_JMPREL)
+		if err != nil {
+			return nil, err
+		}
+		relocs = append(relocs, r...)
+	}
+	return relocs, nil
+}
+
+// readRelocations decodes size bytes of Rel or Rela entries at the virtual address addr.
+func (p *Parser) readRelocations(addr, size uint64, rela bool, table DynTag) ([]Relocation, error) {
+	if size == 0 {
+		return nil, nil
+	}
+	data, err := p.F.ReadVirtual(addr, int(size))
+	if err != nil {
+		return nil, err
+	}
+	return p.decodeRelocations(data, rela, table), nil
+}
+
+// ParseDynamicFromSegments rebuilds the dynamic symbols, the needed libraries,
+// the symbol versions and the dynamic relocations without any section header.
+// 节头被剥离时，通过PT_DYNAMIC找到动态表，再经由PT_LOAD把DT_*地址转换为文件偏移
+func (p *Parser) ParseDynamicFromSegments() error {
+	err := p.ParseDynamic()
+	if err != nil {
+		return err
+	}
+	p.F.FromSegments = true
+	strtab, err := p.dynamicStringTable()
+	if err != nil {
+		return err
+	}
+	symtab, ok := p.F.DynValue(DT_SYMTAB)
+	if !ok {
+		return nil
+	}
+	count, err := p.dynamicSymbolCount()
+	if err != nil {
+		return err
+	}
+	symSize := uint64(Sym64Size)
+	if p.F.Class() == ELFCLASS32 {
+		symSize = Sym32Size
+	}
+	if ent, ok := p.F.DynValue(DT_SYMENT); ok && ent != 0 {
+		if ent < symSize {
+			return fmt.Errorf("DT_SYMENT %d is smaller than a symbol", ent)
+		}
+		symSize = ent
+	}
+	// 符号数量来自哈希表，不可信，符号表不可能比文件还大
+	if count > uint64(p.F.size)/symSize {
+		return fmt.Errorf("%d dynamic symbols of %d bytes do not fit in the file", count, symSize)
+	}
+	data, err := p.F.ReadVirtual(symtab, int(count*symSize))
+	if err != nil {
+		return err
+	}
+	namedSymbols := make([]Symbol, count)
+	if p.F.Class() == ELFCLASS64 {
+		symbols := make([]ELF64SymbolTableEntry, count)
+		for i := range symbols {
+			if err := binary.Read(bytes.NewReader(data[uint64(i)*symSize:]), p.F.ByteOrder(), &symbols[i]); err != nil {
+				return fmt.Errorf("dynamic symbol %d: %w", i, err)
+			}
+			sym := symbols[i]
+			name, _ := getString(strtab, int(sym.Name))
+			namedSymbols[i] = Symbol{Name: name, Info: sym.Info, Other: sym.Other,
+				Index: SectionIndex(sym.Shndx), Value: sym.Value, Size: sym.Size}
+		}
+		p.F.Symbols64 = symbols
+	} else {
+		symbols := make([]ELF32SymbolTableEntry, count)
+		for i := range symbols {
+			if err := binary.Read(bytes.NewReader(data[uint64(i)*symSize:]), p.F.ByteOrder(), &symbols[i]); err != nil {
+				return fmt.Errorf("dynamic symbol %d: %w", i, err)
+			}
+			sym := symbols[i]
+			name, _ := getString(strtab, int(sym.Name))
+			namedSymbols[i] = Symbol{Name: name, Info: sym.Info, Other: sym.Other,
+				Index: SectionIndex(sym.Shndx), Value: uint64(sym.Value), Size: uint64(sym.Size)}
+		}
+		p.F.Symbols32 = symbols
+	}
+
+	// DT_VERSYM与符号表一一对应，每项2字节；DT_VERNEED没有记录大小，读到段末尾为止
+	versym, hasVersym := p.F.DynValue(DT_VERSYM)
+	verneed, hasVerneed := p.F.DynValue(DT_VERNEED)
+	if hasVersym && hasVerneed {
+		symVersions, err := p.F.ReadVirtual(versym, int(count*2))
+		if err == nil {
+			need, err := p.F.fileImage(verneed)
+			if err == nil {
+				p.F.GNUVersion = p.parseGNUVersionNeed(need, strtab)
+				p.F.GNUVersionSym = symVersions
+				for i := range namedSymbols {
+					namedSymbols[i].Library, namedSymbols[i].Version = p.gnuVersion(i - 1)
+				}
+			}
+		}
+	}
+	p.F.NamedSymbols = namedSymbols
+	return nil
+}
+
+// dynamicSymbolCount computes the number of dynamic symbols, the dynamic
+// table does not record it so it is recovered from the hash tables.
+func (p *Parser) dynamicSymbolCount() (uint64, error) {
+	// DT_HASH的nchain就是符号数量
+	if addr, ok := p.F.DynValue(DT_HASH); ok {
+		hdr, err := p.F.ReadVirtual(addr, 8)
+		if err == nil {
+			return uint64(p.F.ByteOrder().Uint32(hdr[4:])), nil
+		}
+	}
+	// DT_GNU_HASH只记录了导出符号，需要找到最大的bucket并沿着chain走到结尾
+	if addr, ok := p.F.DynValue(DT_GNU_HASH); ok {
+		return p.gnuHashSymbolCount(addr)
+	}
+	// 没有哈希表时，按照链接器通常的布局，.dynsym紧跟着.dynstr
+	symtab, _ := p.F.DynValue(DT_SYMTAB)
+	strtab, _ := p.F.DynValue(DT_STRTAB)
+	symSize := uint64(Sym64Size)
+	if p.F.Class() == ELFCLASS32 {
+		symSize = Sym32Size
+	}
+	if strtab > symtab {
+		return (strtab - symtab) / symSize, nil
+	}
+	return 0, errors.New("cannot determine the number of dynamic symbols")
+}
+
+// gnuHashSymbolCount walks a DT_GNU_HASH table to find the highest symbol index.
+func (p *Parser) gnuHashSymbolCount(addr uint64) (uint64, error) {
+	hdr, err := p.F.ReadVirtual(addr, 16)
+	if err != nil {
+		return 0, err
+	}
+	bo := p.F.ByteOrder()
+	nbuckets := uint64(bo.Uint32(hdr[0:]))
+	symoffset := uint64(bo.Uint32(hdr[4:]))
+	bloomSize := uint64(bo.Uint32(hdr[8:]))
+	wordSize := uint64(8)
+	if p.F.Class() == ELFCLASS32 {
+		wordSize = 4
+	}
+	if nbuckets > uint64(p.F.size)/4 || bloomSize > uint64(p.F.size)/wordSize {
+		return 0, errors.New("DT_GNU_HASH table does not fit in the file")
+	}
+	bucketsAddr := addr + 16 + bloomSize*wordSize
+	buckets, err := p.F.ReadVirtual(bucketsAddr, int(nbuckets*4))
+	if err != nil {
+		return 0, err
+	}
+	var last uint64
+	for i := uint64(0); i < nbuckets; i++ {
+		if b := uint64(bo.Uint32(buckets[i*4:])); b > last {
+			last = b
+		}
+	}
+	if last < symoffset {
+		return symoffset, nil
+	}
+	// 链只在映射的文件内容中查找，没有结束标记的链到映射末尾为止
+	chains, err := p.F.fileImage(bucketsAddr + nbuckets*4)
+	if err != nil {
+		return 0, err
+	}
+	for i := (last - symoffset) * 4; i+4 <= uint64(len(chains)); i += 4 {
+		// 最低位为1表示链结束
+		if bo.Uint32(chains[i:])&1 == 1 {
+			return last + 1, nil
+		}
+		last++
+	}
+	return 0, errors.New("DT_GNU_HASH chain runs past the end of its mapping")
+}
@@ -0,0 +1,161 @@
+package elf
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// exampleDir holds the sample binaries shipped with the repository.
+const exampleDir = "../../../example"
+
+// stripSectionHeaders zeroes e_shoff, e_shnum and e_shstrndx the same way
+// sstrip does, leaving only the program headers to work with.
+func stripSectionHeaders(t *testing.T, data []byte) []byte {
+	out := append([]byte(nil), data...)
+	switch Class(out[EI_CLASS]) {
+	case ELFCLASS32:
+		copy(out[0x20:0x24], make([]byte, 4))
+		copy(out[0x30:0x34], make([]byte, 4))
+	case ELFCLASS64:
+		copy(out[0x28:0x30], make([]byte, 8))
+		copy(out[0x3c:0x40], make([]byte, 4))
+	default:
+		t.Fatal("unexpected ELF class")
+	}
+	return out
+}
+
+func TestParseFromSegments(t *testing.T) {
+	for _, name := range []string{"gcc-amd64-linux-exec", "gcc-386-freebsd-exec"} {
+		t.Run(name, func(t *testing.T) {
+			data, err := ioutil.ReadFile(path.Join(exampleDir, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			full, err := NewBytes(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := full.Parse(); err != nil {
+				t.Fatal("failed to parse with section headers :", err)
+			}
+			assert.False(t, full.F.FromSegments)
+
+			stripped, err := NewBytes(stripSectionHeaders(t, data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := stripped.Parse(); err != nil {
+				t.Fatal("failed to parse without section headers :", err)
+			}
+			assert.True(t, stripped.F.FromSegments)
+			assert.Empty(t, stripped.F.Sections())
+			assert.Equal(t, []string{"libc.so.6"}, stripped.F.Needed)
+			assert.Equal(t, full.F.DynamicEntries, stripped.F.DynamicEntries)
+			assert.Equal(t, full.F.DynRelocations, stripped.F.DynRelocations)
+			assert.Equal(t, full.F.NamedSymbols, stripped.F.NamedSymbols)
+		})
+	}
+}
+
+// setDynamicFilesz overwrites p_filesz of the PT_DYNAMIC program header of a
+// little endian ELF64 file.
+func setDynamicFilesz(t *testing.T, data []byte, filesz uint64) {
+	le := binary.LittleEndian
+	phoff, phnum := le.Uint64(data[0x20:]), int(le.Uint16(data[0x38:]))
+	for i := 0; i < phnum; i++ {
+		ph := data[phoff+uint64(i)*56:]
+		if ProgType(le.Uint32(ph)) == PT_DYNAMIC {
+			le.PutUint64(ph[0x20:], filesz)
+			return
+		}
+	}
+	t.Fatal("no PT_DYNAMIC program header")
+}
+
+func TestParseDynamicMalformed(t *testing.T) {
+	data, err := ioutil.ReadFile(path.Join(exampleDir, "gcc-amd64-linux-exec"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// p_filesz越过文件末尾时返回错误而不是按其大小分配
+	bad := append([]byte(nil), data...)
+	setDynamicFilesz(t, bad, 0x7fffffffffffffff)
+	p, err := NewBytes(bad)
+	assert.NoError(t, err)
+	assert.Error(t, p.Parse())
+	stripped, _ := NewBytes(stripSectionHeaders(t, bad))
+	assert.Error(t, stripped.Parse())
+
+	// DT_HASH的nchain被改大，符号表超出文件
+	full, _ := NewBytes(data)
+	assert.NoError(t, full.Parse())
+	addr, ok := full.F.DynValue(DT_HASH)
+	assert.True(t, ok)
+	off, err := full.F.OffsetForVaddr(addr)
+	assert.NoError(t, err)
+	bad = stripSectionHeaders(t, data)
+	binary.LittleEndian.PutUint32(bad[off+4:], 0xffffffff)
+	p, _ = NewBytes(bad)
+	assert.Error(t, p.Parse())
+}
+
+// setShstrtabSize overwrites sh_size of the section header string table.
+func setShstrtabSize(t *testing.T, data []byte, size uint64) {
+	var bo binary.ByteOrder = binary.LittleEndian
+	if Data(data[EI_DATA]) == ELFDATA2MSB {
+		bo = binary.BigEndian
+	}
+	switch Class(data[EI_CLASS]) {
+	case ELFCLASS32:
+		shoff, shstrndx := bo.Uint32(data[0x20:]), uint32(bo.Uint16(data[0x32:]))
+		bo.PutUint32(data[shoff+shstrndx*40+0x14:], uint32(size))
+	case ELFCLASS64:
+		shoff, shstrndx := bo.Uint64(data[0x28:]), uint64(bo.Uint16(data[0x3e:]))
+		bo.PutUint64(data[shoff+shstrndx*64+0x20:], size)
+	default:
+		t.Fatal("unexpected ELF class")
+	}
+}
+
+func TestParseGarbageShstrtab(t *testing.T) {
+	// sh_size被改大的字符表不能按其大小分配内存，节头作废，退回到程序头
+	for _, name := range []string{"gcc-amd64-linux-exec", "gcc-386-freebsd-exec"} {
+		data, err := ioutil.ReadFile(path.Join(exampleDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		setShstrtabSize(t, data, 0xfffffff0)
+		p, err := NewBytes(data)
+		assert.NoError(t, err)
+		if !assert.NoError(t, p.Parse(), name) {
+			continue
+		}
+		assert.True(t, p.F.FromSegments, name)
+		assert.Equal(t, []string{"libc.so.6"}, p.F.Needed)
+		if assert.NotEmpty(t, p.ParseErrors) {
+			assert.Contains(t, p.ParseErrors[0].Error(), "section header string table: contents [")
+		}
+	}
+	// 目标文件没有程序头，宽松模式下同样不会崩溃
+	for _, name := range []string{"go-relocation-test-gcc441-x86-64.obj", "go-relocation-test-gcc482-ppc64le.obj",
+		"go-relocation-test-gcc492-mips64.obj", "go-relocation-test-gcc441-x86.obj", "go-relocation-test-clang-x86.obj",
+		"go-relocation-test-gcc482-aarch64.obj", "gcc-amd64-openbsd-debug-with-rela.obj"} {
+		data, err := ioutil.ReadFile(path.Join(exampleDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		setShstrtabSize(t, data, 1<<63+5)
+		p, err := NewBytes(data)
+		assert.NoError(t, err)
+		assert.Error(t, p.Parse(), name)
+		p, _ = NewBytes(data)
+		p.Lenient = true
+		assert.NoError(t, p.Parse(), name)
+		assert.Empty(t, p.F.Sections(), name)
+	}
+}
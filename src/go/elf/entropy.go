@@ -0,0 +1,238 @@
+// Package elf : entropy.go measures the Shannon entropy of the sections,
+// the segments and sliding windows of the file. Compressed or encrypted
+// bytes come close to 8 bits per byte while code and data stay well below,
+// which is the quickest signal of a packed file.
+package elf
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ViewEntropy is the kind of the view built by EntropyView, it is not part
+// of AllViews.
+const ViewEntropy ViewKind = "entropy"
+
+const (
+	// DefaultEntropyWindow is the window size used when none is given.
+	DefaultEntropyWindow = 4096
+	// HighEntropy is the entropy above which bytes are taken as compressed
+	// or encrypted, in bits per byte.
+	HighEntropy = 7.2
+)
+
+// ShannonEntropy returns the entropy of data in bits per byte, from 0 for
+// a single repeated byte to 8 for uniformly distributed bytes.
+func ShannonEntropy(data []byte) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+	n := float64(len(data))
+	e := 0.0
+	for _, c := range counts {
+		if c != 0 {
+			f := float64(c) / n
+			e -= f * math.Log2(f)
+		}
+	}
+	return e
+}
+
+// RegionEntropy is the entropy of a section, a segment or a window.
+type RegionEntropy struct {
+	// Index is the section, program header or window index.
+	Index int `json:"index"`
+	// Name is the section name or the segment type, empty for windows.
+	Name    string  `json:"name,omitempty"`
+	Offset  uint64  `json:"offset"`
+	Size    uint64  `json:"size"`
+	Entropy float64 `json:"entropy"`
+	// Error is set for a section whose contents cannot be read, like one
+	// past the end of the file, its entropy is then 0.
+	Error string `json:"error,omitempty"`
+}
+
+// EntropyReport is the entropy of the whole file and of its parts.
+type EntropyReport struct {
+	File float64 `json:"file"`
+	// Sections have the entropy of their decompressed contents, the
+	// SHT_NOBITS sections are left out.
+	Sections []RegionEntropy `json:"sections"`
+	// Segments have the entropy of their p_filesz bytes.
+	Segments []RegionEntropy `json:"segments"`
+	// Windows cover the file with windows of Window bytes starting every
+	// Window/2 bytes, the last one may be shorter.
+	Window  int             `json:"window"`
+	Windows []RegionEntropy `json:"windows"`
+}
+
+// readRange returns the file bytes [off, off+size), cut at the end of the
+// file.
+func (p *Parser) readRange(off, size uint64) []byte {
+	if off >= uint64(p.F.size) {
+		return nil
+	}
+	if size > uint64(p.F.size)-off {
+		size = uint64(p.F.size) - off
+	}
+	data := make([]byte, size)
+	n, _ := p.F.r.ReadAt(data, int64(off))
+	return data[:n]
+}
+
+// segmentEntropies returns the entropy of the program headers with file
+// contents.
+func (p *Parser) segmentEntropies() []RegionEntropy {
+	segments := []RegionEntropy{}
+	for i, ph := range p.F.ProgramHeaders() {
+		if ph.Filesz == 0 {
+			continue
+		}
+		data := p.readRange(ph.Off, ph.Filesz)
+		segments = append(segments, RegionEntropy{Index: i, Name: ProgType(ph.Type).String(),
+			Offset: ph.Off, Size: uint64(len(data)), Entropy: ShannonEntropy(data)})
+	}
+	return segments
+}
+
+// Entropy measures the entropy of the file, its sections and segments and
+// of sliding windows of window bytes, DefaultEntropyWindow when window is 0.
+func (p *Parser) Entropy(window int) (*EntropyReport, error) {
+	if window == 0 {
+		window = DefaultEntropyWindow
+	}
+	if window < 2 {
+		return nil, fmt.Errorf("entropy window of %d bytes is too small", window)
+	}
+	r := &EntropyReport{Sections: []RegionEntropy{}, Window: window, Windows: []RegionEntropy{}}
+	for i, s := range p.F.Sections() {
+		if i == 0 || SectionType(s.Type) == SHT_NOBITS || s.ELF64SectionHeader.Size == 0 {
+			continue
+		}
+		// 节头的大小不可信，先与文件大小核对，再读取内容
+		err := s.checkBounds(p.F.size)
+		var data []byte
+		if err == nil {
+			data, err = s.Data()
+		}
+		if err != nil {
+			// 一个节头损坏不影响其余部分的统计
+			r.Sections = append(r.Sections, RegionEntropy{Index: i, Name: s.SectionName,
+				Offset: s.Off, Size: s.ELF64SectionHeader.Size, Error: err.Error()})
+			continue
+		}
+		r.Sections = append(r.Sections, RegionEntropy{Index: i, Name: s.SectionName,
+			Offset: s.Off, Size: uint64(len(data)), Entropy: ShannonEntropy(data)})
+	}
+	r.Segments = p.segmentEntropies()
+	// 整个文件的统计在窗口遍历时累加，只读一遍文件
+	var counts [256]int
+	step := uint64(window / 2)
+	size := uint64(p.F.size)
+	for off := uint64(0); off < size; off += step {
+		data := p.readRange(off, uint64(window))
+		r.Windows = append(r.Windows, RegionEntropy{Index: len(r.Windows), Offset: off,
+			Size: uint64(len(data)), Entropy: ShannonEntropy(data)})
+		n := len(data)
+		if n > int(step) && off+uint64(n) < size {
+			n = int(step)
+		}
+		for _, b := range data[:n] {
+			counts[b]++
+		}
+		if off+uint64(len(data)) >= size {
+			break
+		}
+	}
+	for _, c := range counts {
+		if c != 0 {
+			f := float64(c) / float64(size)
+			r.File -= f * math.Log2(f)
+		}
+	}
+	return r, nil
+}
+
+// entropyString formats an entropy for the views.
+func entropyString(e float64) string {
+	return fmt.Sprintf("%.3f", e)
+}
+
+// EntropyView builds the view of the entropy of the file with windows of
+// window bytes, followed by the packer verdict. Only the windows above
+// HighEntropy are listed.
+func (p *Parser) EntropyView(window int) (*View, error) {
+	r, err := p.Entropy(window)
+	if err != nil {
+		return nil, err
+	}
+	v := &View{Kind: ViewEntropy}
+	t := v.addTable("File", "Size", "Entropy")
+	t.addRow(decString(uint64(p.F.size)), entropyString(r.File))
+	t = v.addTable("Sections", "Nr", "Name", "Offset", "Size", "Entropy")
+	for _, s := range r.Sections {
+		e := entropyString(s.Entropy)
+		if s.Error != "" {
+			e = "error: " + s.Error
+		}
+		t.addRow(decString(uint64(s.Index)), s.Name, hexString(s.Offset), hexString(s.Size), e)
+	}
+	t = v.addTable("Segments", "Nr", "Type", "Offset", "Size", "Entropy")
+	for _, s := range r.Segments {
+		t.addRow(decString(uint64(s.Index)), s.Name, hexString(s.Offset), hexString(s.Size), entropyString(s.Entropy))
+	}
+	t = v.addTable("High entropy windows", "Offset", "Size", "Entropy")
+	for _, w := range r.Windows {
+		if w.Entropy >= HighEntropy {
+			t.addRow(hexString(w.Offset), hexString(w.Size), entropyString(w.Entropy))
+		}
+	}
+	pr, err := p.DetectPacker()
+	if err != nil {
+		return nil, err
+	}
+	t = v.addTable("Packer", "Packed", "Packer")
+	t.addRow(yesNo(pr.Packed), orNone(pr.Packer))
+	t = v.addTable("Evidence", "Rule", "Offset", "Message")
+	for _, f := range pr.Evidence {
+		t.addRow(f.RuleID, hexString(f.Offset), f.Message)
+	}
+	return v, nil
+}
+
+// WriteEntropy writes the entropy of the file and the packer verdict to w.
+// FormatJSON writes the EntropyReport and the PackerReport themselves, the
+// other formats render EntropyView.
+func (p *Parser) WriteEntropy(w io.Writer, format Format, window int) error {
+	if format == FormatJSON {
+		r, err := p.Entropy(window)
+		if err != nil {
+			return err
+		}
+		pr, err := p.DetectPacker()
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Entropy *EntropyReport `json:"entropy"`
+			Packer  *PackerReport  `json:"packer"`
+		}{r, pr})
+	}
+	v, err := p.EntropyView(window)
+	if err != nil {
+		return err
+	}
+	r, err := NewRenderer(format)
+	if err != nil {
+		return err
+	}
+	return r.Render(w, []*View{v})
+}
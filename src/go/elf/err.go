@@ -9,3 +9,18 @@ var ErrNoSymbols = errors.New("no symbol section")
 
 // ErrBadELFClass is returned if the ELF class is unknown.
 var ErrBadELFClass = errors.New("bad elf class")
+
+// ErrNoDynamic is returned by Parser.ParseDynamic if the binary has no
+// PT_DYNAMIC segment nor a SHT_DYNAMIC section.
+var ErrNoDynamic = errors.New("no dynamic segment")
+
+// ErrUnmapped is returned by the address translation helpers when a virtual
+// address is not covered by any loadable segment.
+var ErrUnmapped = errors.New("address is not mapped")
+
+// ErrNotInFile is returned when a virtual address is mapped but only exists
+// in memory (.bss like zero-fill past p_filesz), so it has no file offset.
+var ErrNotInFile = errors.New("address is only present in memory")
+
+// ErrNoVersion is returned when the binary carries no GNU symbol versioning.
+var ErrNoVersion = errors.New("no version information")
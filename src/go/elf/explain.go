@@ -0,0 +1,195 @@
+// Package elf : explain.go implements the explain mode: every header field,
+// section type, segment type, dynamic tag and symbol attribute of a file is
+// listed with its decoded value, a short explanation and the clause of the
+// specification defining it. The texts live in flags_explain.go.
+package elf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Lang selects the language of the explanations.
+type Lang string
+
+const (
+	LangEN Lang = "en"
+	LangZH Lang = "zh"
+)
+
+// ViewExplain is the kind of the view built by ExplainView, it is not part
+// of AllViews.
+const ViewExplain ViewKind = "explain"
+
+// ErrUnknownLang is returned for a language other than LangEN and LangZH.
+var ErrUnknownLang = errors.New("unknown explain language")
+
+// pick returns the text of the language.
+func (l Lang) pick(en, zh string) string {
+	if l == LangZH {
+		return zh
+	}
+	return en
+}
+
+func (e explanation) text(l Lang) string { return l.pick(e.en, e.zh) }
+
+// explain looks the explanation of v up, values without one get a
+// placeholder text and no reference.
+func explain(table []explanation, v uint32) (explanation, bool) {
+	for _, e := range table {
+		if e.flag == v {
+			return e, true
+		}
+	}
+	return explanation{flag: v, en: "No explanation available.", zh: "暂无说明。"}, false
+}
+
+func fieldExplain(name string) fieldExplanation {
+	for _, f := range headerFieldExplanations {
+		if f.name == name {
+			return f
+		}
+	}
+	return fieldExplanation{name: name}
+}
+
+// ExplainView builds the explain view in the language lang.
+func (p *Parser) ExplainView(lang Lang) (*View, error) {
+	if lang != LangEN && lang != LangZH {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownLang, lang)
+	}
+	if p.F == nil || !IsValidELFClass(p.F.Class()) {
+		return nil, ErrBadELFClass
+	}
+	v := &View{Kind: ViewExplain}
+	columns := []string{lang.pick("Item", "项"), lang.pick("Value", "值"), lang.pick("Explanation", "说明"), lang.pick("Reference", "出处")}
+
+	// ELF头部：字段本身的说明之后再接取值的说明
+	h := p.F.rawHeader()
+	t := v.addTable(lang.pick("ELF Header", "ELF头部"), columns...)
+	header := []struct {
+		field string
+		value string
+		table []explanation
+		raw   uint32
+	}{
+		{"e_ident[EI_MAG]", fmt.Sprintf("%q", h.Ident[:4]), nil, 0},
+		{"e_ident[EI_CLASS]", constantName(Class(h.Ident[EI_CLASS]).String()), classExplanations, uint32(h.Ident[EI_CLASS])},
+		{"e_ident[EI_DATA]", constantName(Data(h.Ident[EI_DATA]).String()), dataExplanations, uint32(h.Ident[EI_DATA])},
+		{"e_ident[EI_VERSION]", "EV_" + Version(h.Ident[EI_VERSION]).String(), nil, 0},
+		{"e_ident[EI_OSABI]", jsonEnum(uint64(h.Ident[EI_OSABI]), osABIStrings).Name, nil, 0},
+		{"e_ident[EI_ABIVERSION]", decString(uint64(h.Ident[EI_ABIVERSION])), nil, 0},
+		{"e_type", jsonEnum(uint64(h.Type), typeStrings).Name, typeExplanations, uint32(h.Type)},
+		{"e_machine", jsonEnum(uint64(h.Machine), machineStrings).Name, nil, 0},
+		{"e_version", decString(uint64(h.Version)), nil, 0},
+		{"e_entry", hexString(h.Entry), nil, 0},
+		{"e_phoff", decString(h.Phoff), nil, 0},
+		{"e_shoff", decString(h.Shoff), nil, 0},
+		{"e_flags", hexString(uint64(h.Flags)), nil, 0},
+		{"e_ehsize", decString(uint64(h.Ehsize)), nil, 0},
+		{"e_phentsize", decString(uint64(h.Phentsize)), nil, 0},
+		{"e_phnum", decString(uint64(h.Phnum)), nil, 0},
+		{"e_shentsize", decString(uint64(h.Shentsize)), nil, 0},
+		{"e_shnum", decString(uint64(h.Shnum)), nil, 0},
+		{"e_shstrndx", decString(uint64(h.Shstrndx)), nil, 0},
+	}
+	for _, f := range header {
+		e := fieldExplain(f.field)
+		text := lang.pick(e.en, e.zh)
+		if f.table != nil {
+			if ve, ok := explain(f.table, f.raw); ok {
+				text += lang.pick(" ", "") + ve.text(lang)
+			}
+		}
+		t.addRow(f.field, f.value, text, e.ref)
+	}
+
+	if sections := p.F.Sections(); len(sections) != 0 {
+		t = v.addTable(lang.pick("Section Types", "节类型"), columns...)
+		for i, s := range sections {
+			e, _ := explain(sectionTypeExplanations, s.Type)
+			t.addRow(fmt.Sprintf("[%d] %s", i, s.SectionName), jsonEnum(uint64(s.Type), sectionTypeStrings).Name, e.text(lang), e.ref)
+		}
+	}
+	if phs := p.F.ProgramHeaders(); len(phs) != 0 {
+		t = v.addTable(lang.pick("Segment Types", "段类型"), columns...)
+		for i, ph := range phs {
+			e, _ := explain(programTypeExplanations, ph.Type)
+			t.addRow(fmt.Sprintf("[%d]", i), jsonEnum(uint64(ph.Type), programTypeStrings).Name, e.text(lang), e.ref)
+		}
+	}
+	if len(p.F.DynamicEntries) != 0 {
+		t = v.addTable(lang.pick("Dynamic Tags", "动态标签"), columns...)
+		for i, d := range p.F.DynamicEntries {
+			e, _ := explain(dynTagExplanations, uint32(d.Tag))
+			t.addRow(fmt.Sprintf("[%d]", i), jsonEnum(uint64(d.Tag), dtStrings).Name, e.text(lang), e.ref)
+		}
+	}
+	p.explainSymbols(v, lang)
+	return v, nil
+}
+
+// explainSymbols adds the table of the symbol attributes used by the file,
+// each distinct value is explained once with the number of symbols using it.
+func (p *Parser) explainSymbols(v *View, lang Lang) {
+	type attribute struct {
+		field string
+		order int
+		value uint32
+	}
+	counts := map[attribute]int{}
+	for _, typ := range []SectionType{SHT_SYMTAB, SHT_DYNSYM} {
+		symbols, err := p.Symbols(typ)
+		if err != nil {
+			continue
+		}
+		for _, sym := range symbols {
+			counts[attribute{"st_info (type)", 0, uint32(ST_TYPE(sym.Info))}]++
+			counts[attribute{"st_info (bind)", 1, uint32(ST_BIND(sym.Info))}]++
+			counts[attribute{"st_other (visibility)", 2, uint32(ST_VISIBILITY(sym.Other))}]++
+			// 普通的节索引没有特殊含义，只解释SHN_*保留值
+			if sym.Index == SHN_UNDEF || sym.Index >= SHN_LORESERVE {
+				counts[attribute{"st_shndx", 3, uint32(sym.Index)}]++
+			}
+		}
+	}
+	if len(counts) == 0 {
+		return
+	}
+	attrs := make([]attribute, 0, len(counts))
+	for a := range counts {
+		attrs = append(attrs, a)
+	}
+	sort.Slice(attrs, func(i, j int) bool {
+		if attrs[i].order != attrs[j].order {
+			return attrs[i].order < attrs[j].order
+		}
+		return attrs[i].value < attrs[j].value
+	})
+	t := v.addTable(lang.pick("Symbol Attributes", "符号属性"),
+		lang.pick("Attribute", "属性"), lang.pick("Value", "值"), lang.pick("Symbols", "符号数"),
+		lang.pick("Explanation", "说明"), lang.pick("Reference", "出处"))
+	tables := [][]explanation{symbolTypeExplanations, symbolBindExplanations, symbolVisibilityExplanations, sectionIndexExplanations}
+	names := [][]flagName{sttStrings, stbStrings, stvStrings, sectionIndexStrings}
+	for _, a := range attrs {
+		e, _ := explain(tables[a.order], a.value)
+		name := jsonEnum(uint64(a.value), names[a.order]).Name
+		t.addRow(a.field, name, decString(uint64(counts[a])), e.text(lang), e.ref)
+	}
+}
+
+// WriteExplain renders the explain view in the language lang to w.
+func (p *Parser) WriteExplain(w io.Writer, format Format, lang Lang) error {
+	r, err := NewRenderer(format)
+	if err != nil {
+		return err
+	}
+	v, err := p.ExplainView(lang)
+	if err != nil {
+		return err
+	}
+	return r.Render(w, []*View{v})
+}
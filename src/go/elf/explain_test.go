@@ -0,0 +1,84 @@
+package elf
+
+import (
+	"bytes"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestExplanationTables checks that every explanation is translated and
+// names a value known to the stringer tables of flags.go.
+func TestExplanationTables(t *testing.T) {
+	for _, f := range headerFieldExplanations {
+		assert.NotEmpty(t, f.en, f.name)
+		assert.NotEmpty(t, f.zh, f.name)
+		assert.NotEmpty(t, f.ref, f.name)
+	}
+	tables := []struct {
+		explanations []explanation
+		names        []flagName
+	}{
+		{classExplanations, classStrings},
+		{dataExplanations, dataStrings},
+		{typeExplanations, typeStrings},
+		{sectionTypeExplanations, sectionTypeStrings},
+		{programTypeExplanations, programTypeStrings},
+		{dynTagExplanations, dtStrings},
+		{symbolTypeExplanations, sttStrings},
+		{symbolBindExplanations, stbStrings},
+		{symbolVisibilityExplanations, stvStrings},
+		{sectionIndexExplanations, sectionIndexStrings},
+	}
+	for _, table := range tables {
+		for _, e := range table.explanations {
+			name := stringify(e.flag, table.names, false)
+			assert.NotEmpty(t, e.en, name)
+			assert.NotEmpty(t, e.zh, name)
+			assert.NotEmpty(t, e.ref, name)
+			known := false
+			for _, n := range table.names {
+				known = known || n.flag == e.flag
+			}
+			assert.True(t, known, name)
+		}
+	}
+}
+
+func TestExplainView(t *testing.T) {
+	p, err := New(path.Join(exampleDir, "gcc-amd64-linux-exec"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.CloseFile()
+	if err := p.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	en, err := p.ExplainView(LangEN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zh, err := p.ExplainView(LangZH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(en.Tables), len(zh.Tables))
+	assert.Equal(t, []string{"ELF Header", "Section Types", "Segment Types", "Dynamic Tags", "Symbol Attributes"},
+		[]string{en.Tables[0].Title, en.Tables[1].Title, en.Tables[2].Title, en.Tables[3].Title, en.Tables[4].Title})
+	assert.Equal(t, []string{"e_type", "ET_EXEC", "Object file type. Executable loaded at the fixed addresses of its segments.", refHeader}, en.Tables[0].Rows[6])
+	assert.Equal(t, []string{"e_type", "ET_EXEC", "目标文件类型。可执行文件，按段中的固定地址装载。", refHeader}, zh.Tables[0].Rows[6])
+	assert.Equal(t, []string{"[25] .bss", "SHT_NOBITS", "不占用文件空间，在内存中以0填充（.bss）。", refSections}, zh.Tables[1].Rows[25])
+	// 每个取值都应该有说明
+	for _, table := range en.Tables {
+		for _, row := range table.Rows {
+			assert.NotContains(t, row, "No explanation available.", table.Title)
+		}
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, p.WriteExplain(&out, FormatText, LangZH))
+	assert.Contains(t, out.String(), "符号属性:")
+	_, err = p.ExplainView("fr")
+	assert.ErrorIs(t, err, ErrUnknownLang)
+}
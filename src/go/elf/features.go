@@ -0,0 +1,652 @@
+// Package elf : features.go extracts the fixed feature schema of the
+// malware classifiers, an EMBER like set of header fields, section
+// histograms, hashed imports, dynamic tag counts, string statistics,
+// segment permissions and note presence. Features is the named form,
+// Vector flattens it in the order fixed by FeatureVersion.
+package elf
+
+import (
+	"bytes"
+	"encoding/json"
+	"hash/fnv"
+	"io"
+	"math"
+	"math/bits"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FeatureVersion is the version of the feature schema, it changes whenever
+// a feature is added, removed, moved or computed differently.
+const FeatureVersion = 1
+
+// ViewFeatures is the kind of the view built by FeaturesView, it is not
+// part of AllViews.
+const ViewFeatures ViewKind = "features"
+
+const (
+	// sizeBuckets is the number of buckets of the section size histogram,
+	// each one covers a factor of 4 of sizes.
+	sizeBuckets = 16
+	// entropyBuckets is the number of buckets of the section entropy
+	// histogram, each one covers 1 bit per byte.
+	entropyBuckets = 8
+	// sectionFlagBits is the number of generic section flags counted,
+	// SHF_WRITE to SHF_COMPRESSED.
+	sectionFlagBits = 12
+	// importBuckets and libraryBuckets are the numbers of buckets the
+	// imported symbols and the needed libraries are hashed into.
+	importBuckets  = 256
+	libraryBuckets = 32
+	// printableBuckets is the number of printable characters of the
+	// string histogram, 0x20 to 0x7f like EMBER.
+	printableBuckets = 96
+	// minStringLength is the shortest run of printable characters taken
+	// as a string.
+	minStringLength = 5
+)
+
+// Features is the feature set of a file, every histogram and bucket list
+// has a fixed length so that the vector layout only depends on
+// FeatureVersion.
+type Features struct {
+	Version  int             `json:"version"`
+	Header   HeaderFeatures  `json:"header"`
+	Sections SectionFeatures `json:"sections"`
+	Imports  ImportFeatures  `json:"imports"`
+	Dynamic  DynamicFeatures `json:"dynamic"`
+	Strings  StringFeatures  `json:"strings"`
+	Segments SegmentFeatures `json:"segments"`
+	Notes    NoteFeatures    `json:"notes"`
+}
+
+// HeaderFeatures are the ELF header fields and whole file measures.
+type HeaderFeatures struct {
+	FileSize   uint64  `json:"file_size"`
+	Entropy    float64 `json:"entropy"`
+	Class      uint8   `json:"class"`
+	Data       uint8   `json:"data"`
+	OSABI      uint8   `json:"osabi"`
+	ABIVersion uint8   `json:"abi_version"`
+	Type       uint16  `json:"type"`
+	Machine    uint16  `json:"machine"`
+	Version    uint32  `json:"version"`
+	Flags      uint32  `json:"flags"`
+	EntryPoint uint64  `json:"entry_point"`
+	// EntryExecutable is set when the entry point is in an executable
+	// PT_LOAD segment.
+	EntryExecutable  bool   `json:"entry_executable"`
+	NumSections      uint16 `json:"number_of_sections"`
+	NumSegments      uint16 `json:"number_of_segments"`
+	HasSymbolTable   bool   `json:"has_symbol_table"`
+	HasSectionHeader bool   `json:"has_section_headers"`
+}
+
+// SectionFeatures summarize the section headers. Sizes count every
+// section, entropies only the ones with contents. The sections whose
+// contents cannot be read are only counted by Invalid.
+type SectionFeatures struct {
+	Count              int     `json:"count"`
+	Invalid            int     `json:"invalid"`
+	TotalSize          uint64  `json:"total_size"`
+	MeanEntropy        float64 `json:"mean_entropy"`
+	MaxEntropy         float64 `json:"max_entropy"`
+	Executable         int     `json:"executable"`
+	WritableExecutable int     `json:"writable_executable"`
+	// SizeHistogram bucket i counts the sections whose size needs 2i or
+	// 2i+1 bits, the last bucket also holds the larger ones.
+	SizeHistogram [sizeBuckets]int `json:"size_histogram"`
+	// EntropyHistogram bucket i counts the sections of i to i+1 bits per
+	// byte, 8 falls in the last one.
+	EntropyHistogram [entropyBuckets]int `json:"entropy_histogram"`
+	// FlagHistogram bucket i counts the sections with flag 1<<i set.
+	FlagHistogram [sectionFlagBits]int `json:"flag_histogram"`
+}
+
+// ImportFeatures hash the imported symbols and the needed libraries into
+// buckets with FNV-1a of their lower case names.
+type ImportFeatures struct {
+	Count          int                 `json:"count"`
+	Libraries      int                 `json:"libraries"`
+	Exports        int                 `json:"exports"`
+	Buckets        [importBuckets]int  `json:"buckets"`
+	LibraryBuckets [libraryBuckets]int `json:"library_buckets"`
+}
+
+// DynamicFeatures count the dynamic entries by tag, the keys of Tags are
+// the lower case names of featureDynTags and are always all present.
+type DynamicFeatures struct {
+	Entries int            `json:"entries"`
+	Tags    map[string]int `json:"tags"`
+	// Other counts the entries with a tag outside featureDynTags.
+	Other int `json:"other"`
+}
+
+// StringFeatures are the statistics of the runs of at least
+// minStringLength printable characters of the file.
+type StringFeatures struct {
+	Count         int     `json:"count"`
+	AverageLength float64 `json:"average_length"`
+	Printables    int     `json:"printables"`
+	// PrintableDistribution is the share of each character 0x20 to 0x7f
+	// in the strings, Entropy the entropy of this distribution.
+	PrintableDistribution [printableBuckets]float64 `json:"printable_distribution"`
+	Entropy               float64                   `json:"entropy"`
+	Paths                 int                       `json:"paths"`
+	URLs                  int                       `json:"urls"`
+	IPAddresses           int                       `json:"ip_addresses"`
+	Shells                int                       `json:"shells"`
+	// ELFMagic counts the "\x7fELF" byte sequences of the whole file, the
+	// one of the header included.
+	ELFMagic int `json:"elf_magic"`
+}
+
+// SegmentFeatures summarize the program headers.
+type SegmentFeatures struct {
+	Count int `json:"count"`
+	Load  int `json:"load"`
+	// LoadPermissions counts the PT_LOAD segments by their PF_R, PF_W and
+	// PF_X flags, indexed by the flags: none, x, w, wx, r, rx, rw, rwx.
+	LoadPermissions [8]int  `json:"load_permissions"`
+	LoadFileSize    uint64  `json:"load_file_size"`
+	LoadMemorySize  uint64  `json:"load_memory_size"`
+	MaxLoadEntropy  float64 `json:"max_load_entropy"`
+	Interp          bool    `json:"interp"`
+	Dynamic         bool    `json:"dynamic"`
+	TLS             bool    `json:"tls"`
+	GNUStack        bool    `json:"gnu_stack"`
+	ExecutableStack bool    `json:"executable_stack"`
+	GNURelro        bool    `json:"gnu_relro"`
+	GNUEHFrame      bool    `json:"gnu_eh_frame"`
+	GNUProperty     bool    `json:"gnu_property"`
+}
+
+// NoteFeatures record which notes the file has.
+type NoteFeatures struct {
+	Count          int  `json:"count"`
+	GNUABITag      bool `json:"gnu_abi_tag"`
+	GNUBuildID     bool `json:"gnu_build_id"`
+	GNUGoldVersion bool `json:"gnu_gold_version"`
+	GNUProperty    bool `json:"gnu_property"`
+	GoBuildID      bool `json:"go_build_id"`
+	FreeBSDABITag  bool `json:"freebsd_abi_tag"`
+	AndroidIdent   bool `json:"android_ident"`
+	Core           bool `json:"core"`
+	Other          int  `json:"other"`
+}
+
+// featureDynTags are the dynamic tags counted one by one, in vector order.
+var featureDynTags = []DynTag{
+	DT_NEEDED, DT_PLTRELSZ, DT_PLTGOT, DT_HASH, DT_STRTAB, DT_SYMTAB, DT_RELA,
+	DT_RELASZ, DT_RELAENT, DT_STRSZ, DT_SYMENT, DT_INIT, DT_FINI, DT_SONAME,
+	DT_RPATH, DT_SYMBOLIC, DT_REL, DT_RELSZ, DT_RELENT, DT_PLTREL, DT_DEBUG,
+	DT_TEXTREL, DT_JMPREL, DT_BIND_NOW, DT_INIT_ARRAY, DT_FINI_ARRAY,
+	DT_INIT_ARRAYSZ, DT_FINI_ARRAYSZ, DT_RUNPATH, DT_FLAGS, DT_PREINIT_ARRAY,
+	DT_PREINIT_ARRAYSZ, DT_GNU_HASH, DT_VERSYM, DT_RELACOUNT, DT_RELCOUNT,
+	DT_FLAGS_1, DT_VERDEF, DT_VERDEFNUM, DT_VERNEED, DT_VERNEEDNUM,
+}
+
+// loadPermissionNames names the LoadPermissions buckets.
+var loadPermissionNames = [8]string{"none", "x", "w", "wx", "r", "rx", "rw", "rwx"}
+
+var (
+	// featurePaths are the directories whose paths are counted.
+	featurePaths = regexp.MustCompile(`/(bin|sbin|usr|etc|tmp|dev|proc|sys|var|lib|lib64|home|root|opt|run)/`)
+	featureURLs  = regexp.MustCompile(`(?i)\b(https?|ftp)://`)
+	featureIPs   = regexp.MustCompile(`\b(25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])(\.(25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])){3}\b`)
+	featureShell = regexp.MustCompile(`/bin/(ba|da|z|k|c|tc)?sh\b|\bbusybox\b`)
+)
+
+// dynTagFeatureName returns the key of tag in DynamicFeatures.Tags.
+func dynTagFeatureName(tag DynTag) string {
+	// DT_PREINIT_ARRAY与DT_ENCODING同值，String返回的是ENCODING
+	if tag == DT_PREINIT_ARRAY {
+		return "preinit_array"
+	}
+	return strings.ToLower(tag.String())
+}
+
+// Features extracts the feature set of the file. The result only depends
+// on the file contents, the same file always gives the same features. The
+// sections and notes which cannot be read are left out, so that the
+// malformed files still get a vector.
+func (f *File) Features() (*Features, error) {
+	fe := &Features{Version: FeatureVersion}
+	p := &Parser{F: f}
+	data := p.readRange(0, uint64(f.size))
+	f.headerFeatures(&fe.Header, data)
+	f.sectionFeatures(&fe.Sections)
+	if err := f.importFeatures(&fe.Imports); err != nil {
+		return nil, err
+	}
+	f.dynamicFeatures(&fe.Dynamic)
+	stringFeatures(&fe.Strings, data)
+	f.segmentFeatures(&fe.Segments)
+	f.noteFeatures(&fe.Notes)
+	return fe, nil
+}
+
+func (f *File) headerFeatures(h *HeaderFeatures, data []byte) {
+	hdr := f.rawHeader()
+	h.FileSize = uint64(f.size)
+	h.Entropy = ShannonEntropy(data)
+	h.Class = hdr.Ident[EI_CLASS]
+	h.Data = hdr.Ident[EI_DATA]
+	h.OSABI = hdr.Ident[EI_OSABI]
+	h.ABIVersion = hdr.Ident[EI_ABIVERSION]
+	h.Type = hdr.Type
+	h.Machine = hdr.Machine
+	h.Version = hdr.Version
+	h.Flags = hdr.Flags
+	h.EntryPoint = hdr.Entry
+	h.NumSections = hdr.Shnum
+	h.NumSegments = hdr.Phnum
+	h.HasSectionHeader = len(f.Sections()) != 0
+	for _, ph := range f.ProgramHeaders() {
+		if ProgType(ph.Type) == PT_LOAD && ProgFlag(ph.Flags)&PF_X != 0 &&
+			hdr.Entry >= ph.Vaddr && hdr.Entry-ph.Vaddr < ph.Memsz {
+			h.EntryExecutable = true
+		}
+	}
+	for _, s := range f.Sections() {
+		if SectionType(s.Type) == SHT_SYMTAB {
+			h.HasSymbolTable = true
+		}
+	}
+}
+
+func (f *File) sectionFeatures(s *SectionFeatures) {
+	entropies := 0
+	for i, sec := range f.Sections() {
+		if i == 0 && SectionType(sec.Type) == SHT_NULL {
+			continue
+		}
+		size := sec.ELF64SectionHeader.Size
+		var content []byte
+		if SectionType(sec.Type) != SHT_NOBITS && size != 0 {
+			// 节头的大小不可信，先与文件大小核对，不按sh_size分配内存
+			err := sec.checkBounds(f.size)
+			if err == nil {
+				content, err = sec.Data()
+			}
+			if err != nil {
+				// 内容读不出来的节(如越过文件末尾)只计数，不参与其余统计
+				s.Invalid++
+				continue
+			}
+		}
+		s.Count++
+		s.TotalSize += size
+		bucket := bits.Len64(size) / 2
+		if bucket >= sizeBuckets {
+			bucket = sizeBuckets - 1
+		}
+		s.SizeHistogram[bucket]++
+		flags := SectionFlag(sec.Flags)
+		for b := 0; b < sectionFlagBits; b++ {
+			if flags&(1<<uint(b)) != 0 {
+				s.FlagHistogram[b]++
+			}
+		}
+		if flags&SHF_EXECINSTR != 0 {
+			s.Executable++
+			if flags&SHF_WRITE != 0 {
+				s.WritableExecutable++
+			}
+		}
+		if SectionType(sec.Type) == SHT_NOBITS || size == 0 {
+			continue
+		}
+		e := ShannonEntropy(content)
+		bucket = int(e)
+		if bucket >= entropyBuckets {
+			bucket = entropyBuckets - 1
+		}
+		s.EntropyHistogram[bucket]++
+		s.MeanEntropy += e
+		s.MaxEntropy = math.Max(s.MaxEntropy, e)
+		entropies++
+	}
+	if entropies != 0 {
+		s.MeanEntropy /= float64(entropies)
+	}
+}
+
+// featureBucket hashes name into one of n buckets.
+func featureBucket(name string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(strings.ToLower(name)))
+	return int(h.Sum32() % uint32(n))
+}
+
+func (f *File) importFeatures(imp *ImportFeatures) error {
+	syms, err := f.fileSymbols(SHT_DYNSYM)
+	if err != nil {
+		return err
+	}
+	seen := map[string]bool{}
+	for _, s := range syms {
+		bind := ST_BIND(s.Info)
+		if s.Name == "" || (bind != STB_GLOBAL && bind != STB_WEAK) {
+			continue
+		}
+		if s.Index != SHN_UNDEF {
+			imp.Exports++
+			continue
+		}
+		// 版本不参与哈希，不同glibc版本链接出的同一程序落在相同的桶里
+		name := s.Name
+		if i := strings.IndexByte(name, '@'); i > 0 {
+			name = name[:i]
+		}
+		if !seen[name] {
+			seen[name] = true
+			imp.Count++
+			imp.Buckets[featureBucket(name, importBuckets)]++
+		}
+	}
+	libs := map[string]bool{}
+	for _, lib := range f.Needed {
+		if !libs[lib] {
+			libs[lib] = true
+			imp.Libraries++
+			imp.LibraryBuckets[featureBucket(lib, libraryBuckets)]++
+		}
+	}
+	return nil
+}
+
+func (f *File) dynamicFeatures(d *DynamicFeatures) {
+	d.Tags = make(map[string]int, len(featureDynTags))
+	known := make(map[DynTag]bool, len(featureDynTags))
+	for _, tag := range featureDynTags {
+		d.Tags[dynTagFeatureName(tag)] = 0
+		known[tag] = true
+	}
+	for _, e := range f.DynamicEntries {
+		if e.Tag == DT_NULL {
+			// 表以DT_NULL结尾，其后的填充项不计
+			break
+		}
+		d.Entries++
+		if known[e.Tag] {
+			d.Tags[dynTagFeatureName(e.Tag)]++
+		} else {
+			d.Other++
+		}
+	}
+}
+
+func stringFeatures(s *StringFeatures, data []byte) {
+	s.ELFMagic = bytes.Count(data, []byte(ELFMAG))
+	var counts [printableBuckets]int
+	start := -1
+	for i := 0; i <= len(data); i++ {
+		if i < len(data) && data[i] >= 0x20 && data[i] <= 0x7f {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 && i-start >= minStringLength {
+			str := data[start:i]
+			s.Count++
+			s.Printables += len(str)
+			for _, c := range str {
+				counts[c-0x20]++
+			}
+			if featurePaths.Match(str) {
+				s.Paths++
+			}
+			if featureURLs.Match(str) {
+				s.URLs++
+			}
+			if featureIPs.Match(str) {
+				s.IPAddresses++
+			}
+			if featureShell.Match(str) {
+				s.Shells++
+			}
+		}
+		start = -1
+	}
+	if s.Count == 0 {
+		return
+	}
+	s.AverageLength = float64(s.Printables) / float64(s.Count)
+	for i, c := range counts {
+		if c == 0 {
+			continue
+		}
+		share := float64(c) / float64(s.Printables)
+		s.PrintableDistribution[i] = share
+		s.Entropy -= share * math.Log2(share)
+	}
+}
+
+func (f *File) segmentFeatures(s *SegmentFeatures) {
+	p := &Parser{F: f}
+	for _, ph := range f.ProgramHeaders() {
+		s.Count++
+		switch ProgType(ph.Type) {
+		case PT_LOAD:
+			s.Load++
+			s.LoadPermissions[ph.Flags&uint32(PF_R|PF_W|PF_X)]++
+			s.LoadFileSize += ph.Filesz
+			s.LoadMemorySize += ph.Memsz
+			if ph.Filesz != 0 {
+				s.MaxLoadEntropy = math.Max(s.MaxLoadEntropy, ShannonEntropy(p.readRange(ph.Off, ph.Filesz)))
+			}
+		case PT_INTERP:
+			s.Interp = true
+		case PT_DYNAMIC:
+			s.Dynamic = true
+		case PT_TLS:
+			s.TLS = true
+		case PT_GNU_STACK:
+			s.GNUStack = true
+			s.ExecutableStack = ProgFlag(ph.Flags)&PF_X != 0
+		case PT_GNU_RELRO:
+			s.GNURelro = true
+		case PT_GNU_EH_FRAME:
+			s.GNUEHFrame = true
+		case PT_GNU_PROPERTY:
+			s.GNUProperty = true
+		}
+	}
+}
+
+func (f *File) noteFeatures(n *NoteFeatures) {
+	// 损坏的note之前解码出的note仍然计入
+	notes, _ := (&Parser{F: f}).Notes()
+	for _, note := range notes {
+		n.Count++
+		switch {
+		case note.Name == "GNU" && note.Type == NT_GNU_ABI_TAG:
+			n.GNUABITag = true
+		case note.Name == "GNU" && note.Type == NT_GNU_BUILD_ID:
+			n.GNUBuildID = true
+		case note.Name == "GNU" && note.Type == NT_GNU_GOLD_VERSION:
+			n.GNUGoldVersion = true
+		case note.Name == "GNU" && note.Type == NT_GNU_PROPERTY_TYPE_0:
+			n.GNUProperty = true
+		case note.Name == "Go" && note.Type == 4:
+			n.GoBuildID = true
+		case note.Name == "FreeBSD" && note.Type == 1:
+			n.FreeBSDABITag = true
+		case note.Name == "Android" && note.Type == 1:
+			n.AndroidIdent = true
+		case note.Name == "CORE" || note.Name == "LINUX":
+			n.Core = true
+		default:
+			n.Other++
+		}
+	}
+}
+
+func boolFeature(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// visit calls fn with the name and the value of every feature, in vector
+// order.
+func (fe *Features) visit(fn func(name string, v float64)) {
+	h := &fe.Header
+	fn("version", float64(fe.Version))
+	fn("header.file_size", float64(h.FileSize))
+	fn("header.entropy", h.Entropy)
+	fn("header.class", float64(h.Class))
+	fn("header.data", float64(h.Data))
+	fn("header.osabi", float64(h.OSABI))
+	fn("header.abi_version", float64(h.ABIVersion))
+	fn("header.type", float64(h.Type))
+	fn("header.machine", float64(h.Machine))
+	fn("header.version", float64(h.Version))
+	fn("header.flags", float64(h.Flags))
+	fn("header.entry_point", float64(h.EntryPoint))
+	fn("header.entry_executable", boolFeature(h.EntryExecutable))
+	fn("header.number_of_sections", float64(h.NumSections))
+	fn("header.number_of_segments", float64(h.NumSegments))
+	fn("header.has_symbol_table", boolFeature(h.HasSymbolTable))
+	fn("header.has_section_headers", boolFeature(h.HasSectionHeader))
+
+	s := &fe.Sections
+	fn("sections.count", float64(s.Count))
+	fn("sections.invalid", float64(s.Invalid))
+	fn("sections.total_size", float64(s.TotalSize))
+	fn("sections.mean_entropy", s.MeanEntropy)
+	fn("sections.max_entropy", s.MaxEntropy)
+	fn("sections.executable", float64(s.Executable))
+	fn("sections.writable_executable", float64(s.WritableExecutable))
+	for i, c := range s.SizeHistogram {
+		fn("sections.size_histogram."+strconv.Itoa(i), float64(c))
+	}
+	for i, c := range s.EntropyHistogram {
+		fn("sections.entropy_histogram."+strconv.Itoa(i), float64(c))
+	}
+	for i, c := range s.FlagHistogram {
+		fn("sections.flag_histogram."+strconv.Itoa(i), float64(c))
+	}
+
+	imp := &fe.Imports
+	fn("imports.count", float64(imp.Count))
+	fn("imports.libraries", float64(imp.Libraries))
+	fn("imports.exports", float64(imp.Exports))
+	for i, c := range imp.Buckets {
+		fn("imports.buckets."+strconv.Itoa(i), float64(c))
+	}
+	for i, c := range imp.LibraryBuckets {
+		fn("imports.library_buckets."+strconv.Itoa(i), float64(c))
+	}
+
+	d := &fe.Dynamic
+	fn("dynamic.entries", float64(d.Entries))
+	for _, tag := range featureDynTags {
+		name := dynTagFeatureName(tag)
+		fn("dynamic.tags."+name, float64(d.Tags[name]))
+	}
+	fn("dynamic.other", float64(d.Other))
+
+	str := &fe.Strings
+	fn("strings.count", float64(str.Count))
+	fn("strings.average_length", str.AverageLength)
+	fn("strings.printables", float64(str.Printables))
+	for i, share := range str.PrintableDistribution {
+		fn("strings.printable_distribution."+strconv.Itoa(i), share)
+	}
+	fn("strings.entropy", str.Entropy)
+	fn("strings.paths", float64(str.Paths))
+	fn("strings.urls", float64(str.URLs))
+	fn("strings.ip_addresses", float64(str.IPAddresses))
+	fn("strings.shells", float64(str.Shells))
+	fn("strings.elf_magic", float64(str.ELFMagic))
+
+	seg := &fe.Segments
+	fn("segments.count", float64(seg.Count))
+	fn("segments.load", float64(seg.Load))
+	for i, c := range seg.LoadPermissions {
+		fn("segments.load_permissions."+loadPermissionNames[i], float64(c))
+	}
+	fn("segments.load_file_size", float64(seg.LoadFileSize))
+	fn("segments.load_memory_size", float64(seg.LoadMemorySize))
+	fn("segments.max_load_entropy", seg.MaxLoadEntropy)
+	fn("segments.interp", boolFeature(seg.Interp))
+	fn("segments.dynamic", boolFeature(seg.Dynamic))
+	fn("segments.tls", boolFeature(seg.TLS))
+	fn("segments.gnu_stack", boolFeature(seg.GNUStack))
+	fn("segments.executable_stack", boolFeature(seg.ExecutableStack))
+	fn("segments.gnu_relro", boolFeature(seg.GNURelro))
+	fn("segments.gnu_eh_frame", boolFeature(seg.GNUEHFrame))
+	fn("segments.gnu_property", boolFeature(seg.GNUProperty))
+
+	n := &fe.Notes
+	fn("notes.count", float64(n.Count))
+	fn("notes.gnu_abi_tag", boolFeature(n.GNUABITag))
+	fn("notes.gnu_build_id", boolFeature(n.GNUBuildID))
+	fn("notes.gnu_gold_version", boolFeature(n.GNUGoldVersion))
+	fn("notes.gnu_property", boolFeature(n.GNUProperty))
+	fn("notes.go_build_id", boolFeature(n.GoBuildID))
+	fn("notes.freebsd_abi_tag", boolFeature(n.FreeBSDABITag))
+	fn("notes.android_ident", boolFeature(n.AndroidIdent))
+	fn("notes.core", boolFeature(n.Core))
+	fn("notes.other", float64(n.Other))
+}
+
+// Vector returns the features as numbers, booleans are 0 or 1. The
+// position of each feature is the one of its name in FeatureNames.
+func (fe *Features) Vector() []float64 {
+	var v []float64
+	fe.visit(func(_ string, x float64) { v = append(v, x) })
+	return v
+}
+
+// FeatureNames returns the names of the values of Vector for the schema
+// of FeatureVersion, the JSON path of each feature joined with dots.
+func FeatureNames() []string {
+	var names []string
+	(&Features{}).visit(func(name string, _ float64) { names = append(names, name) })
+	return names
+}
+
+// FeaturesView builds the view of the features, one row per vector value.
+func (p *Parser) FeaturesView() (*View, error) {
+	fe, err := p.F.Features()
+	if err != nil {
+		return nil, err
+	}
+	v := &View{Kind: ViewFeatures}
+	t := v.addTable("Features (version "+strconv.Itoa(fe.Version)+")", "Index", "Name", "Value")
+	i := 0
+	fe.visit(func(name string, x float64) {
+		t.addRow(strconv.Itoa(i), name, strconv.FormatFloat(x, 'g', -1, 64))
+		i++
+	})
+	return v, nil
+}
+
+// WriteFeatures writes the features to w. FormatJSON writes the Features
+// themselves, the other formats render FeaturesView.
+func (p *Parser) WriteFeatures(w io.Writer, format Format) error {
+	if format == FormatJSON {
+		fe, err := p.F.Features()
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(fe)
+	}
+	rd, err := NewRenderer(format)
+	if err != nil {
+		return err
+	}
+	v, err := p.FeaturesView()
+	if err != nil {
+		return err
+	}
+	return rd.Render(w, []*View{v})
+}
@@ -0,0 +1,167 @@
+package elf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeatureNames(t *testing.T) {
+	names := FeatureNames()
+	// 向量布局改变时必须同时修改FeatureVersion
+	assert.Equal(t, 1, FeatureVersion)
+	assert.Len(t, names, 530)
+	seen := map[string]bool{}
+	for _, name := range names {
+		assert.False(t, seen[name], name)
+		seen[name] = true
+	}
+	assert.Equal(t, "version", names[0])
+	assert.True(t, seen["dynamic.tags.preinit_array"])
+	assert.True(t, seen["segments.load_permissions.rwx"])
+	assert.True(t, seen["imports.buckets.255"])
+}
+
+func TestFeatures(t *testing.T) {
+	p := parseFile(t, path.Join(exampleDir, "gcc-amd64-linux-exec"))
+	defer p.CloseFile()
+	fe, err := p.F.Features()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, FeatureVersion, fe.Version)
+	assert.Equal(t, uint64(8844), fe.Header.FileSize)
+	assert.Equal(t, uint16(62), fe.Header.Machine)
+	assert.Equal(t, uint64(0x4003e0), fe.Header.EntryPoint)
+	assert.True(t, fe.Header.EntryExecutable)
+	assert.True(t, fe.Header.HasSymbolTable)
+
+	assert.Equal(t, 36, fe.Sections.Count)
+	assert.Equal(t, 4, fe.Sections.Executable)
+	assert.Equal(t, 0, fe.Sections.WritableExecutable)
+	assert.Equal(t, 4, fe.Sections.FlagHistogram[2])
+	sum := 0
+	for _, c := range fe.Sections.SizeHistogram {
+		sum += c
+	}
+	assert.Equal(t, fe.Sections.Count, sum)
+
+	// puts、__libc_start_main和弱引用的__gmon_start__
+	assert.Equal(t, 3, fe.Imports.Count)
+	assert.Equal(t, 1, fe.Imports.Libraries)
+	assert.Equal(t, 1, fe.Imports.Buckets[featureBucket("puts", importBuckets)])
+	assert.Equal(t, 1, fe.Imports.LibraryBuckets[featureBucket("libc.so.6", libraryBuckets)])
+
+	assert.Equal(t, 20, fe.Dynamic.Entries)
+	assert.Equal(t, 1, fe.Dynamic.Tags["needed"])
+	assert.Equal(t, 0, fe.Dynamic.Tags["preinit_array"])
+	assert.Len(t, fe.Dynamic.Tags, len(featureDynTags))
+
+	assert.Equal(t, 1, fe.Strings.ELFMagic)
+	assert.Equal(t, 1, fe.Strings.Paths) // /lib64/ld-linux-x86-64.so.2
+	assert.InDelta(t, float64(fe.Strings.Printables)/float64(fe.Strings.Count), fe.Strings.AverageLength, 1e-9)
+
+	assert.Equal(t, 2, fe.Segments.Load)
+	assert.Equal(t, [8]int{5: 1, 6: 1}, fe.Segments.LoadPermissions)
+	assert.True(t, fe.Segments.Interp)
+	assert.False(t, fe.Segments.ExecutableStack)
+
+	assert.Equal(t, NoteFeatures{Count: 1, GNUABITag: true}, fe.Notes)
+
+	// 同一输入的向量和JSON完全相同
+	v := fe.Vector()
+	assert.Len(t, v, len(FeatureNames()))
+	assert.Equal(t, float64(FeatureVersion), v[0])
+	again, err := p.F.Features()
+	assert.NoError(t, err)
+	assert.Equal(t, v, again.Vector())
+	a, _ := json.Marshal(fe)
+	b, _ := json.Marshal(again)
+	assert.Equal(t, string(a), string(b))
+}
+
+func TestFeaturesHardened(t *testing.T) {
+	p := parseFile(t, path.Join(exampleDir, "gcc-amd64-linux-hardened"))
+	defer p.CloseFile()
+	fe, err := p.F.Features()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 26, fe.Dynamic.Entries)
+	assert.Equal(t, [8]int{4: 2, 5: 1, 6: 1}, fe.Segments.LoadPermissions)
+	assert.True(t, fe.Segments.GNURelro)
+	assert.True(t, fe.Segments.GNUStack)
+	assert.Equal(t, NoteFeatures{Count: 3, GNUABITag: true, GNUBuildID: true, GNUProperty: true}, fe.Notes)
+
+	var buf bytes.Buffer
+	assert.NoError(t, p.WriteFeatures(&buf, FormatText))
+	assert.Contains(t, buf.String(), "segments.gnu_relro")
+}
+
+func TestFeaturesGolden(t *testing.T) {
+	// 名字和值逐项对照，特征的增删、换位或算法改变都会在这里失败
+	golden, err := ioutil.ReadFile(path.Join("testdata", "features", "gcc-amd64-linux-exec.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := parseFile(t, path.Join(exampleDir, "gcc-amd64-linux-exec"))
+	defer p.CloseFile()
+	fe, err := p.F.Features()
+	if !assert.NoError(t, err) {
+		return
+	}
+	var names []string
+	var values []float64
+	for _, line := range strings.Split(strings.TrimSuffix(string(golden), "\n"), "\n") {
+		fields := strings.Fields(line)
+		if !assert.Len(t, fields, 2, line) {
+			return
+		}
+		v, err := strconv.ParseFloat(fields[1], 64)
+		assert.NoError(t, err, line)
+		names = append(names, fields[0])
+		values = append(values, v)
+	}
+	assert.Equal(t, names, FeatureNames())
+	assert.Equal(t, values, fe.Vector())
+}
+
+func TestFeaturesSectionPastEOF(t *testing.T) {
+	p := mutatedExec(t, func(data []byte, f *Parser) {
+		le := binary.LittleEndian
+		// 第29节(.debug_info)的sh_offset指向文件之外
+		le.PutUint64(data[le.Uint64(data[0x28:])+29*64+0x18:], 0x100000)
+	})
+	assert.Equal(t, ".debug_info", p.F.Sections()[29].SectionName)
+	fe, err := p.F.Features()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 1, fe.Sections.Invalid)
+	assert.Equal(t, 35, fe.Sections.Count)
+	assert.Len(t, fe.Vector(), len(FeatureNames()))
+}
+
+func TestFeaturesSectionInflatedSize(t *testing.T) {
+	p := mutatedExec(t, func(data []byte, f *Parser) {
+		le := binary.LittleEndian
+		// 第27节(.debug_aranges)的sh_size改为1<<40
+		le.PutUint64(data[le.Uint64(data[0x28:])+27*64+0x20:], 1<<40)
+	})
+	defer p.CloseFile()
+	assert.Equal(t, ".debug_aranges", p.F.Sections()[27].SectionName)
+	fe, err := p.F.Features()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 1, fe.Sections.Invalid)
+	assert.Equal(t, 35, fe.Sections.Count)
+	assert.Len(t, fe.Vector(), len(FeatureNames()))
+}
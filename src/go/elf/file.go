@@ -3,6 +3,7 @@ package elf
 import (
 	"encoding/binary"
 	"errors"
+	"io"
 )
 
 // FileIdent is a representation of the raw ident array (first 16 bytes of an ELF file)
@@ -65,6 +66,10 @@ type File struct {
 	ELFBin32   `json:",omitempty"`
 	ELFBin64   `json:",omitempty"`
 	ELFSymbols `json:",omitempty"`
+	ELFDynamic `json:",omitempty"`
+	// r gives access to the raw file content, size is the file length.
+	r    io.ReaderAt
+	size int64
 }
 
 func NewBinaryFile() *File {
@@ -76,6 +81,19 @@ func (f *File) Class() Class {
 	return f.Ident.Class
 }
 
+// rawHeader returns the ELF header as read from the file, widened to the
+// 64-bit layout.
+func (f *File) rawHeader() ELF64Header {
+	if f.Class() != ELFCLASS32 {
+		return f.Header64
+	}
+	h := f.Header32
+	return ELF64Header{Ident: h.Ident, Type: h.Type, Machine: h.Machine, Version: h.Version,
+		Entry: uint64(h.Entry), Phoff: uint64(h.Phoff), Shoff: uint64(h.Shoff), Flags: h.Flags,
+		Ehsize: h.Ehsize, Phentsize: h.Phentsize, Phnum: h.Phnum, Shentsize: h.Shentsize,
+		Shnum: h.Shnum, Shstrndx: h.Shstrndx}
+}
+
 // ByteOrder returns byte order of the binary.
 func (f *File) ByteOrder() binary.ByteOrder {
 	return f.Ident.ByteOrder
@@ -95,7 +113,7 @@ func (f *File) SectionNames() []string {
 		}
 		return sectionNames
 	} else if len(f.Sections32) != 0 {
-		sectionNames := make([]string, len(f.Sections64))
+		sectionNames := make([]string, len(f.Sections32))
 		for i, s := range f.Sections32 {
 			sectionNames[i] = s.SectionName
 		}
@@ -152,14 +170,96 @@ func (f *File) Get64SectionByName(name string) *ELF64Section {
 }
 
 
+// ProgramHeaders returns the program header table regardless of the ELF class,
+// 32-bit entries are widened to the 64-bit layout.
+// 屏蔽ELF32/ELF64差异，后续按段处理的逻辑只需要写一份
+func (f *File) ProgramHeaders() []ELF64ProgramHeader {
+	if f.Class() == ELFCLASS32 {
+		progs := make([]ELF64ProgramHeader, len(f.ProgramHeaders32))
+		for i, ph := range f.ProgramHeaders32 {
+			progs[i] = ELF64ProgramHeader{
+				Type:   ph.Type,
+				Flags:  ph.Flags,
+				Off:    uint64(ph.Off),
+				Vaddr:  uint64(ph.Vaddr),
+				Paddr:  uint64(ph.Paddr),
+				Filesz: uint64(ph.Filesz),
+				Memsz:  uint64(ph.Memsz),
+				Align:  uint64(ph.Align),
+			}
+		}
+		return progs
+	}
+	return f.ProgramHeaders64
+}
+
+// Sections returns the parsed sections regardless of the ELF class,
+// 32-bit sections are widened to ELF64Section and share the same reader.
+func (f *File) Sections() []*ELF64Section {
+	if f.Class() == ELFCLASS32 {
+		sections := make([]*ELF64Section, len(f.Sections32))
+		for i, s := range f.Sections32 {
+			sections[i] = s.widen()
+		}
+		return sections
+	}
+	return f.Sections64
+}
+
+// widen converts a 32-bit section to its 64-bit representation.
+func (s *ELF32Section) widen() *ELF64Section {
+	return &ELF64Section{
+		ELF64SectionHeader: ELF64SectionHeader{
+			Name:      s.Name,
+			Type:      s.Type,
+			Flags:     uint64(s.Flags),
+			Addr:      uint64(s.Addr),
+			Off:       uint64(s.Off),
+			Size:      uint64(s.ELF32SectionHeader.Size),
+			Link:      s.Link,
+			Info:      s.Info,
+			AddrAlign: uint64(s.AddrAlign),
+			EntSize:   uint64(s.EntSize),
+		},
+		compressionType:   s.compressionType,
+		compressionOffset: s.compressionOffset,
+		SectionName:       s.SectionName,
+		Size:              uint64(s.Size),
+		sr:                s.sr,
+	}
+}
+
+// SectionByType returns the first section with the given type regardless of
+// the ELF class (nil otherwise).
+func (f *File) SectionByType(t SectionType) *ELF64Section {
+	for _, s := range f.Sections() {
+		if s.Type == uint32(t) {
+			return s
+		}
+	}
+	return nil
+}
+
+// SectionByName returns the first section with the given name regardless of
+// the ELF class (nil otherwise).
+func (f *File) SectionByName(name string) *ELF64Section {
+	for _, s := range f.Sections() {
+		if s.SectionName == name {
+			return s
+		}
+	}
+	return nil
+}
+
 // stringTable reads and returns the string table given by the
 // specified link value.
 // 将给定link（节索引）的数据解析为字节数组，返回数据、错误
 func (f *File) stringTable(link uint32) ([]byte, error) {
-	if link <= 0 || link >= uint32(len(f.Sections64)) {
+	sections := f.Sections()
+	if link <= 0 || link >= uint32(len(sections)) {
 		return nil, errors.New("section has invalid string table link")
 	}
-	return f.Sections64[link].Data()
+	return sections[link].Data()
 }
 
 // getString extracts a string from an ELF string table.
@@ -0,0 +1,243 @@
+// Package elf : findings.go defines the findings the security checks
+// produce: a rule ID, a severity, a message and the place of the file it
+// is about. They are exported as SARIF by sarif.go.
+package elf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity is the level of a finding, the values are the SARIF levels.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityNote    Severity = "note"
+)
+
+// Finding is one problem found in a file.
+type Finding struct {
+	RuleID   string   `json:"rule_id"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	// Offset and Size are the file bytes the finding is about, Size is 0
+	// when the finding is about a whole structure of unknown size.
+	Offset uint64 `json:"offset"`
+	Size   uint64 `json:"size,omitempty"`
+	// Section is the name of the section the finding is about or holding
+	// Offset, empty for the headers and the bytes outside of any section.
+	Section string `json:"section,omitempty"`
+}
+
+// FindingRule describes a rule of the findings.
+type FindingRule struct {
+	ID          string
+	Severity    Severity
+	Description string
+}
+
+// findingRules is the catalogue of the hardening rules, the packer,
+// anomaly and loadability rules are in packerRules, anomalyRules and
+// loadRules and the policy rules are named after the check of the policy
+// instead.
+var findingRules = []FindingRule{
+	{"hardening/relro", SeverityWarning, "The relocated data is not made read-only after relocation (RELRO)."},
+	{"hardening/nx", SeverityError, "The stack is executable."},
+	{"hardening/pie", SeverityWarning, "The executable is not position independent, ASLR cannot move it."},
+	{"hardening/canary", SeverityWarning, "The file is not built with a stack protector."},
+	{"hardening/fortify", SeverityNote, "The file calls functions that have a fortified __*_chk version."},
+	{"hardening/rpath", SeverityWarning, "DT_RPATH is set, it is searched before LD_LIBRARY_PATH."},
+	{"hardening/runpath", SeverityNote, "DT_RUNPATH is set."},
+	{"hardening/wx-segment", SeverityError, "A loadable segment is both writable and executable."},
+}
+
+// LookupFindingRule returns the rule id of the catalogue. The policy/<check>
+// rules are built on the fly.
+func LookupFindingRule(id string) (FindingRule, bool) {
+	for _, rules := range [][]FindingRule{findingRules, packerRules} {
+		for _, r := range rules {
+			if r.ID == id {
+				return r, true
+			}
+		}
+	}
+	for _, rules := range [][]anomalyRule{anomalyRules, loadRules} {
+		for _, r := range rules {
+			if r.ID == id {
+				return r.FindingRule, true
+			}
+		}
+	}
+	if check := strings.TrimPrefix(id, policyRulePrefix); check != id && check != "" {
+		return FindingRule{ID: id, Severity: SeverityError, Description: "The file violates the '" + check + "' requirement of the policy."}, true
+	}
+	return FindingRule{}, false
+}
+
+// finding builds a finding of the catalogue rule id at off.
+func (p *Parser) finding(id string, off, size uint64, format string, args ...interface{}) Finding {
+	r, _ := LookupFindingRule(id)
+	f := Finding{RuleID: id, Severity: r.Severity, Message: fmt.Sprintf(format, args...), Offset: off, Size: size}
+	if s, err := p.F.SectionForOffset(off); err == nil {
+		f.Section = s.SectionName
+	}
+	return f
+}
+
+// programHeaderLocation returns the file bytes of program header i.
+func (p *Parser) programHeaderLocation(i int) (uint64, uint64) {
+	h := p.F.rawHeader()
+	return h.Phoff + uint64(i)*uint64(h.Phentsize), uint64(h.Phentsize)
+}
+
+// dynamicLocation returns the file bytes of the first entry tag of the
+// dynamic table, or of the whole table when the tag is absent.
+func (p *Parser) dynamicLocation(tag DynTag) (uint64, uint64) {
+	off, size, found := uint64(0), uint64(0), false
+	for _, ph := range p.F.ProgramHeaders() {
+		if ProgType(ph.Type) == PT_DYNAMIC {
+			off, size, found = ph.Off, ph.Filesz, true
+			break
+		}
+	}
+	if s := p.F.SectionByType(SHT_DYNAMIC); s != nil && !found {
+		off, size, found = s.Off, s.ELF64SectionHeader.Size, true
+	}
+	if !found {
+		return 0, 0
+	}
+	entsize := uint64(16)
+	if p.F.Class() == ELFCLASS32 {
+		entsize = 8
+	}
+	for i, d := range p.F.DynamicEntries {
+		if d.Tag == tag {
+			return off + uint64(i)*entsize, entsize
+		}
+	}
+	return off, size
+}
+
+// sectionLocation returns the file bytes of s, nothing when s is nil.
+func (p *Parser) sectionLocation(s *ELF64Section) (uint64, uint64) {
+	if s == nil {
+		return 0, 0
+	}
+	return s.Off, s.ELF64SectionHeader.Size
+}
+
+// stackLocation returns the file bytes deciding whether the stack is
+// executable: the PT_GNU_STACK program header, the .note.GNU-stack section
+// of relocatable files, or the program header table when there is none.
+func (p *Parser) stackLocation(h *HardeningReport) (uint64, uint64) {
+	if h.PIE == PIERel {
+		return p.sectionLocation(p.F.SectionByName(".note.GNU-stack"))
+	}
+	for i, ph := range p.F.ProgramHeaders() {
+		if ProgType(ph.Type) == PT_GNU_STACK {
+			return p.programHeaderLocation(i)
+		}
+	}
+	hdr := p.F.rawHeader()
+	return hdr.Phoff, uint64(hdr.Phnum) * uint64(hdr.Phentsize)
+}
+
+// propertyNoteLocation returns the file bytes of the first GNU property
+// note, nothing when there is none.
+func (p *Parser) propertyNoteLocation() (uint64, uint64) {
+	notes, _ := p.Notes()
+	for _, n := range notes {
+		if n.Name == "GNU" && n.Type == NT_GNU_PROPERTY_TYPE_0 {
+			// 12字节的note头部加上4字节的名字"GNU\0"
+			return n.Offset, 12 + 4 + uint64(len(n.Desc))
+		}
+	}
+	return 0, 0
+}
+
+// importLocation returns the file bytes of the undefined symbol name in
+// the dynamic symbol table, or the symbol table when there is none.
+func (p *Parser) importLocation(name string) (uint64, uint64) {
+	for _, typ := range []SectionType{SHT_DYNSYM, SHT_SYMTAB} {
+		s := p.F.SectionByType(typ)
+		syms, err := p.Symbols(typ)
+		if s == nil || err != nil {
+			continue
+		}
+		entsize := uint64(p.symbolEntrySize())
+		for i, sym := range syms {
+			if sym.Index == SHN_UNDEF && sym.Name == name {
+				return s.Off + uint64(i)*entsize, entsize
+			}
+		}
+	}
+	return 0, 0
+}
+
+// HardeningFindings turns the weaknesses of the Hardening report into
+// findings. Relocatable objects are not linked yet, only their stack and
+// imports are checked.
+func (p *Parser) HardeningFindings() ([]Finding, error) {
+	h, err := p.Hardening()
+	if err != nil {
+		return nil, err
+	}
+	var findings []Finding
+	add := func(f Finding) { findings = append(findings, f) }
+	linked := h.PIE != PIERel
+	if linked && h.RELRO != RELROFull {
+		off, size := p.dynamicLocation(DT_FLAGS)
+		msg := "no PT_GNU_RELRO segment"
+		if h.RELRO == RELROPartial {
+			msg = "partial RELRO, the file is not linked with -z now"
+		}
+		add(p.finding("hardening/relro", off, size, "%s", msg))
+	}
+	if !h.NX {
+		off, size := p.stackLocation(h)
+		add(p.finding("hardening/nx", off, size, "%s", execStackReason(h)))
+	}
+	if h.PIE == PIENo {
+		// e_type位于e_ident之后
+		add(p.finding("hardening/pie", EI_NIDENT, 2, "the file type is ET_EXEC"))
+	}
+	if !h.Canary && linked {
+		off, size := p.sectionLocation(p.F.SectionByType(SHT_DYNSYM))
+		add(p.finding("hardening/canary", off, size, "__stack_chk_fail is not imported"))
+	}
+	for _, fn := range h.Unfortified {
+		off, size := p.importLocation(fn)
+		add(p.finding("hardening/fortify", off, size, "%s is called without the __%s_chk checks", fn, fn))
+	}
+	if h.RPath != "" {
+		off, size := p.dynamicLocation(DT_RPATH)
+		add(p.finding("hardening/rpath", off, size, "DT_RPATH is set to %q", h.RPath))
+	}
+	if h.RunPath != "" {
+		off, size := p.dynamicLocation(DT_RUNPATH)
+		add(p.finding("hardening/runpath", off, size, "DT_RUNPATH is set to %q", h.RunPath))
+	}
+	for _, i := range h.WXSegments {
+		off, size := p.programHeaderLocation(i)
+		add(p.finding("hardening/wx-segment", off, size, "segment %d is writable and executable", i))
+	}
+	return findings, nil
+}
+
+// Findings runs every check producing findings on the file: the
+// hardening checks, the anomaly rules and, for executables and shared
+// objects, the loadability checks.
+func (p *Parser) Findings() ([]Finding, error) {
+	findings, err := p.HardeningFindings()
+	if err != nil {
+		return nil, err
+	}
+	findings = append(findings, p.Anomalies()...)
+	switch Type(p.F.rawHeader().Type) {
+	case ET_EXEC, ET_DYN:
+		findings = append(findings, p.Loadability()...)
+	}
+	return findings, nil
+}
@@ -0,0 +1,116 @@
+package elf
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHardeningFindings(t *testing.T) {
+	p := parseFile(t, path.Join(exampleDir, "gcc-amd64-linux-exec"))
+	defer p.CloseFile()
+	findings, err := p.HardeningFindings()
+	assert.NoError(t, err)
+	assert.Equal(t, []Finding{
+		{RuleID: "hardening/relro", Severity: SeverityWarning, Message: "no PT_GNU_RELRO segment", Offset: 0x6b0, Size: 0x1a0, Section: ".dynamic"},
+		{RuleID: "hardening/pie", Severity: SeverityWarning, Message: "the file type is ET_EXEC", Offset: 0x10, Size: 2},
+		{RuleID: "hardening/canary", Severity: SeverityWarning, Message: "__stack_chk_fail is not imported", Offset: 0x288, Size: 0x60, Section: ".dynsym"},
+	}, findings)
+
+	// 没有PT_GNU_STACK时指向整个程序头表
+	p = parseFile(t, path.Join(exampleDir, "gcc-386-freebsd-exec"))
+	defer p.CloseFile()
+	findings, err = p.HardeningFindings()
+	assert.NoError(t, err)
+	if assert.Len(t, findings, 5) {
+		assert.Equal(t, Finding{RuleID: "hardening/nx", Severity: SeverityError, Message: "no PT_GNU_STACK segment, the stack defaults to executable", Offset: 0x34, Size: 0xa0}, findings[1])
+		assert.Equal(t, "hardening/fortify", findings[4].RuleID)
+		assert.Equal(t, ".dynsym", findings[4].Section)
+	}
+
+	p = parseFile(t, path.Join(exampleDir, "gcc-amd64-linux-hardened"))
+	defer p.CloseFile()
+	findings, err = p.HardeningFindings()
+	assert.NoError(t, err)
+	if assert.Len(t, findings, 1) {
+		assert.Equal(t, "read is called without the __read_chk checks", findings[0].Message)
+	}
+}
+
+func TestLookupFindingRule(t *testing.T) {
+	r, ok := LookupFindingRule("hardening/wx-segment")
+	assert.True(t, ok)
+	assert.Equal(t, SeverityError, r.Severity)
+	r, ok = LookupFindingRule("policy/no_rpath")
+	assert.True(t, ok)
+	assert.Equal(t, "The file violates the 'no_rpath' requirement of the policy.", r.Description)
+	_, ok = LookupFindingRule("policy/")
+	assert.False(t, ok)
+	_, ok = LookupFindingRule("nope")
+	assert.False(t, ok)
+}
+
+func TestWriteSARIF(t *testing.T) {
+	files := []FileFindings{
+		{Path: "bin/a", Findings: []Finding{
+			{RuleID: "hardening/nx", Severity: SeverityError, Message: "the stack is executable", Offset: 0x40, Size: 0x38},
+			{RuleID: "policy/banned_imports", Severity: SeverityError, Message: "imports banned function gets", Offset: 0x300, Size: 0x18, Section: ".dynsym"},
+		}},
+		{Path: "/usr/bin/my tool", Findings: []Finding{
+			{RuleID: "hardening/nx", Severity: SeverityError, Message: "the stack is executable", Offset: 0x40},
+		}},
+		{Path: "bin/clean"},
+	}
+	var out bytes.Buffer
+	assert.NoError(t, WriteSARIF(&out, files))
+
+	var log struct {
+		Schema  string `json:"$schema"`
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []map[string]interface{} `json:"results"`
+		} `json:"runs"`
+	}
+	if !assert.NoError(t, json.Unmarshal(out.Bytes(), &log)) {
+		return
+	}
+	assert.Equal(t, "2.1.0", log.Version)
+	assert.Contains(t, log.Schema, "sarif-2.1.0")
+	if !assert.Len(t, log.Runs, 1) {
+		return
+	}
+	run := log.Runs[0]
+	assert.Equal(t, "parser-elf", run.Tool.Driver.Name)
+	if assert.Len(t, run.Tool.Driver.Rules, 2) {
+		assert.Equal(t, "hardening/nx", run.Tool.Driver.Rules[0].ID)
+		assert.Equal(t, "policy/banned_imports", run.Tool.Driver.Rules[1].ID)
+	}
+	if !assert.Len(t, run.Results, 3) {
+		return
+	}
+	assert.EqualValues(t, 1, run.Results[1]["ruleIndex"])
+	loc := run.Results[1]["locations"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"artifactLocation": map[string]interface{}{"uri": "bin/a"},
+		"region":           map[string]interface{}{"byteOffset": 768.0, "byteLength": 24.0},
+	}, loc["physicalLocation"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": ".dynsym", "kind": "section"}}, loc["logicalLocations"])
+
+	loc = run.Results[2]["locations"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"artifactLocation": map[string]interface{}{"uri": "file:///usr/bin/my%20tool"},
+		"region":           map[string]interface{}{"byteOffset": 64.0},
+	}, loc["physicalLocation"])
+	assert.NotContains(t, loc, "logicalLocations")
+}
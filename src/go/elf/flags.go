@@ -628,6 +628,7 @@ const (
 	SHT_PREINIT_ARRAY  SectionType = 16         // Pre-initialization function ptrs.
 	SHT_GROUP          SectionType = 17         // Section group.
 	SHT_SYMTAB_SHNDX   SectionType = 18         // Section indexes (see SHN_XINDEX).
+	SHT_RELR           SectionType = 19         // Relative relocations, packed.
 	SHT_LOOS           SectionType = 0x60000000 // First of OS specific semantics
 	SHT_GNU_ATTRIBUTES SectionType = 0x6ffffff5 // GNU object attributes
 	SHT_GNU_HASH       SectionType = 0x6ffffff6 // GNU hash table
@@ -637,6 +638,7 @@ const (
 	SHT_GNU_VERSYM     SectionType = 0x6fffffff // GNU version symbol table
 	SHT_HIOS           SectionType = 0x6fffffff // Last of OS specific semantics
 	SHT_LOPROC         SectionType = 0x70000000 // reserved range for processor
+	SHT_ARM_ATTRIBUTES SectionType = 0x70000003 // ARM (and RISC-V) build attributes
 	SHT_HIPROC         SectionType = 0x7fffffff // specific section header types
 	SHT_LOUSER         SectionType = 0x80000000 // reserved range for application
 	SHT_HIUSER         SectionType = 0xffffffff // specific indexes
@@ -660,6 +662,7 @@ var sectionTypeStrings = []flagName{
 	{16, "SHT_PREINIT_ARRAY"},
 	{17, "SHT_GROUP"},
 	{18, "SHT_SYMTAB_SHNDX"},
+	{19, "SHT_RELR"},
 	{0x60000000, "SHT_LOOS"},
 	{0x6ffffff5, "SHT_GNU_ATTRIBUTES"},
 	{0x6ffffff6, "SHT_GNU_HASH"},
@@ -754,6 +757,7 @@ const (
 	PT_GNU_STACK         ProgType = 0x6474e551 // Stack flags
 	PT_GNU_RELRO         ProgType = 0x6474e552 // Read only after relocs
 	PT_GNU_PROPERTY      ProgType = 0x6474e553 // GNU property
+	PT_GNU_SFRAME        ProgType = 0x6474e554 // SFrame stack trace information
 	PT_GNU_MBIND_LO      ProgType = 0x6474e555 // Mbind segments start
 	PT_GNU_MBIND_HI      ProgType = 0x6474f554 // Mbind segments finish
 	PT_PAX_FLAGS         ProgType = 0x65041580 // PAX flags
@@ -790,6 +794,7 @@ var programTypeStrings = []flagName{
 	{0x6474e551, "PT_GNU_STACK"},
 	{0x6474e552, "PT_GNU_RELRO"},
 	{0x6474e553, "PT_GNU_PROPERTY"},
+	{0x6474e554, "PT_GNU_SFRAME"},
 	{0x65041580, "PT_PAX_FLAGS"},
 	{0x65a3dbe6, "PT_OPENBSD_RANDOMIZE"},
 	{0x65a3dbe7, "PT_OPENBSD_WXNEEDED"},
@@ -1080,6 +1085,80 @@ var dflagStrings = []flagName{
 func (df DynFlag) String() string   { return matchFlagName(uint32(df), dflagStrings, false) }
 func (df DynFlag) GoString() string { return matchFlagName(uint32(df), dflagStrings, true) }
 
+// DT_FLAGS_1 values.
+type DynFlag1 uint32
+
+const (
+	DF_1_NOW        DynFlag1 = 0x00000001 /* Perform complete relocation processing. */
+	DF_1_GLOBAL     DynFlag1 = 0x00000002 /* Unused. */
+	DF_1_GROUP      DynFlag1 = 0x00000004 /* Part of a group, dependencies are resolved within it. */
+	DF_1_NODELETE   DynFlag1 = 0x00000008 /* The object cannot be unloaded. */
+	DF_1_LOADFLTR   DynFlag1 = 0x00000010 /* Filtees are loaded immediately. */
+	DF_1_INITFIRST  DynFlag1 = 0x00000020 /* Initialize before any other object. */
+	DF_1_NOOPEN     DynFlag1 = 0x00000040 /* The object cannot be dlopen()ed. */
+	DF_1_ORIGIN     DynFlag1 = 0x00000080 /* $ORIGIN must be handled. */
+	DF_1_DIRECT     DynFlag1 = 0x00000100 /* Direct binding enabled. */
+	DF_1_TRANS      DynFlag1 = 0x00000200
+	DF_1_INTERPOSE  DynFlag1 = 0x00000400 /* Interposes symbols of the objects loaded after it. */
+	DF_1_NODEFLIB   DynFlag1 = 0x00000800 /* Ignore the default library search path. */
+	DF_1_NODUMP     DynFlag1 = 0x00001000 /* Not dumpable with dldump. */
+	DF_1_CONFALT    DynFlag1 = 0x00002000 /* Configuration alternative created. */
+	DF_1_ENDFILTEE  DynFlag1 = 0x00004000 /* Filtee terminates the filter search. */
+	DF_1_DISPRELDNE DynFlag1 = 0x00008000 /* Displacement relocation done. */
+	DF_1_DISPRELPND DynFlag1 = 0x00010000 /* Displacement relocation pending. */
+	DF_1_NODIRECT   DynFlag1 = 0x00020000 /* The object has non-direct bindings. */
+	DF_1_IGNMULDEF  DynFlag1 = 0x00040000
+	DF_1_NOKSYMS    DynFlag1 = 0x00080000
+	DF_1_NOHDR      DynFlag1 = 0x00100000
+	DF_1_EDITED     DynFlag1 = 0x00200000 /* The object has been modified after being built. */
+	DF_1_NORELOC    DynFlag1 = 0x00400000
+	DF_1_SYMINTPOSE DynFlag1 = 0x00800000 /* Individual symbols may be interposed. */
+	DF_1_GLOBAUDIT  DynFlag1 = 0x01000000 /* Global auditing is required. */
+	DF_1_SINGLETON  DynFlag1 = 0x02000000 /* Singleton symbols are used. */
+	DF_1_STUB       DynFlag1 = 0x04000000
+	DF_1_PIE        DynFlag1 = 0x08000000 /* The object is a position independent executable. */
+	DF_1_KMOD       DynFlag1 = 0x10000000
+	DF_1_WEAKFILTER DynFlag1 = 0x20000000
+	DF_1_NOCOMMON   DynFlag1 = 0x40000000
+)
+
+var dflag1Strings = []flagName{
+	{0x00000001, "DF_1_NOW"},
+	{0x00000002, "DF_1_GLOBAL"},
+	{0x00000004, "DF_1_GROUP"},
+	{0x00000008, "DF_1_NODELETE"},
+	{0x00000010, "DF_1_LOADFLTR"},
+	{0x00000020, "DF_1_INITFIRST"},
+	{0x00000040, "DF_1_NOOPEN"},
+	{0x00000080, "DF_1_ORIGIN"},
+	{0x00000100, "DF_1_DIRECT"},
+	{0x00000200, "DF_1_TRANS"},
+	{0x00000400, "DF_1_INTERPOSE"},
+	{0x00000800, "DF_1_NODEFLIB"},
+	{0x00001000, "DF_1_NODUMP"},
+	{0x00002000, "DF_1_CONFALT"},
+	{0x00004000, "DF_1_ENDFILTEE"},
+	{0x00008000, "DF_1_DISPRELDNE"},
+	{0x00010000, "DF_1_DISPRELPND"},
+	{0x00020000, "DF_1_NODIRECT"},
+	{0x00040000, "DF_1_IGNMULDEF"},
+	{0x00080000, "DF_1_NOKSYMS"},
+	{0x00100000, "DF_1_NOHDR"},
+	{0x00200000, "DF_1_EDITED"},
+	{0x00400000, "DF_1_NORELOC"},
+	{0x00800000, "DF_1_SYMINTPOSE"},
+	{0x01000000, "DF_1_GLOBAUDIT"},
+	{0x02000000, "DF_1_SINGLETON"},
+	{0x04000000, "DF_1_STUB"},
+	{0x08000000, "DF_1_PIE"},
+	{0x10000000, "DF_1_KMOD"},
+	{0x20000000, "DF_1_WEAKFILTER"},
+	{0x40000000, "DF_1_NOCOMMON"},
+}
+
+func (df DynFlag1) String() string   { return matchFlagName(uint32(df), dflag1Strings, false) }
+func (df DynFlag1) GoString() string { return matchFlagName(uint32(df), dflag1Strings, true) }
+
 // NType values; used in core files.
 type NType int
 
@@ -1098,6 +1177,23 @@ var ntypeStrings = []flagName{
 func (i NType) String() string   { return stringify(uint32(i), ntypeStrings, false) }
 func (i NType) GoString() string { return stringify(uint32(i), ntypeStrings, true) }
 
+// GNU note types, found in notes whose owner is "GNU".
+const (
+	NT_GNU_ABI_TAG         NType = 1 /* ABI information. */
+	NT_GNU_HWCAP           NType = 2 /* Synthetic hwcap information. */
+	NT_GNU_BUILD_ID        NType = 3 /* Build ID generated by ld --build-id. */
+	NT_GNU_GOLD_VERSION    NType = 4 /* Version of gold. */
+	NT_GNU_PROPERTY_TYPE_0 NType = 5 /* Program property. */
+)
+
+var gnuNtypeStrings = []flagName{
+	{1, "NT_GNU_ABI_TAG"},
+	{2, "NT_GNU_HWCAP"},
+	{3, "NT_GNU_BUILD_ID"},
+	{4, "NT_GNU_GOLD_VERSION"},
+	{5, "NT_GNU_PROPERTY_TYPE_0"},
+}
+
 /* Symbol Binding - ELFNN_ST_BIND - st_info */
 type SymBind int
 
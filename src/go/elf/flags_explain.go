@@ -0,0 +1,197 @@
+// Package elf : flags_explain.go holds the texts of the explain mode, one
+// short explanation per header field and per value of the enums of
+// flags.go, in English and in Chinese, with the clause of the gABI (or of
+// the GNU extensions) defining it. Processor specific values are left out,
+// the same number means different things on each machine.
+package elf
+
+// References to the specifications, the gABI clauses follow the chapter
+// titles of https://refspecs.linuxbase.org/elf/gabi4+/contents.html.
+const (
+	refIdent    = "gABI ch.4, ELF Identification"
+	refHeader   = "gABI ch.4, ELF Header"
+	refSections = "gABI ch.4, Sections (sh_type)"
+	refSpecial  = "gABI ch.4, Special Sections"
+	refSymbols  = "gABI ch.4, Symbol Table"
+	refSegments = "gABI ch.5, Program Header (p_type)"
+	refDynamic  = "gABI ch.5, Dynamic Section"
+	refGNU      = "LSB Core, ELF extensions (GNU)"
+)
+
+// explanation is the explain mode text of a value, like flagName it is
+// looked up by the numeric value of the constant.
+type explanation struct {
+	flag uint32
+	en   string
+	zh   string
+	ref  string
+}
+
+// fieldExplanation is the explain mode text of a header field.
+type fieldExplanation struct {
+	name string
+	en   string
+	zh   string
+	ref  string
+}
+
+var headerFieldExplanations = []fieldExplanation{
+	{"e_ident[EI_MAG]", "Magic number 0x7f 'E' 'L' 'F' identifying the file as ELF.", "魔数 0x7f 'E' 'L' 'F'，标识这是一个ELF文件。", refIdent},
+	{"e_ident[EI_CLASS]", "File class, the width of addresses, offsets and sizes.", "文件类别，决定地址、偏移和大小字段的宽度。", refIdent},
+	{"e_ident[EI_DATA]", "Data encoding, the byte order of every multi-byte field.", "数据编码，即所有多字节字段的字节序。", refIdent},
+	{"e_ident[EI_VERSION]", "ELF header version, must be EV_CURRENT.", "ELF头部版本，必须为EV_CURRENT。", refIdent},
+	{"e_ident[EI_OSABI]", "Operating system or ABI extensions the object relies on.", "目标文件依赖的操作系统或ABI扩展。", refIdent},
+	{"e_ident[EI_ABIVERSION]", "Version of the ABI named by EI_OSABI, usually 0.", "EI_OSABI所指ABI的版本，通常为0。", refIdent},
+	{"e_type", "Object file type.", "目标文件类型。", refHeader},
+	{"e_machine", "Architecture the object is built for.", "目标文件所针对的体系结构。", refHeader},
+	{"e_version", "Object file version, 1 (EV_CURRENT).", "目标文件版本，为1（EV_CURRENT）。", refHeader},
+	{"e_entry", "Virtual address control is transferred to when the process starts, 0 if none.", "进程启动时跳转执行的虚拟地址，没有入口时为0。", refHeader},
+	{"e_phoff", "File offset of the program header table, 0 if absent.", "程序头表在文件中的偏移，没有时为0。", refHeader},
+	{"e_shoff", "File offset of the section header table, 0 if absent.", "节头表在文件中的偏移，没有时为0。", refHeader},
+	{"e_flags", "Processor specific flags.", "处理器相关的标志。", refHeader},
+	{"e_ehsize", "Size of this ELF header in bytes.", "ELF头部本身的字节大小。", refHeader},
+	{"e_phentsize", "Size of one program header table entry.", "程序头表中每个表项的大小。", refHeader},
+	{"e_phnum", "Number of program header table entries.", "程序头表的表项个数。", refHeader},
+	{"e_shentsize", "Size of one section header table entry.", "节头表中每个表项的大小。", refHeader},
+	{"e_shnum", "Number of section header table entries.", "节头表的表项个数。", refHeader},
+	{"e_shstrndx", "Index of the section holding the section names (.shstrtab).", "保存节名字符串的节（.shstrtab）的索引。", refHeader},
+}
+
+var classExplanations = []explanation{
+	{uint32(ELFCLASSNONE), "Invalid class.", "无效的类别。", refIdent},
+	{uint32(ELFCLASS32), "32-bit object, addresses and offsets are 4 bytes.", "32位目标文件，地址和偏移为4字节。", refIdent},
+	{uint32(ELFCLASS64), "64-bit object, addresses and offsets are 8 bytes.", "64位目标文件，地址和偏移为8字节。", refIdent},
+}
+
+var dataExplanations = []explanation{
+	{uint32(ELFDATANONE), "Invalid data encoding.", "无效的数据编码。", refIdent},
+	{uint32(ELFDATA2LSB), "Two's complement, little endian.", "补码表示，小端字节序。", refIdent},
+	{uint32(ELFDATA2MSB), "Two's complement, big endian.", "补码表示，大端字节序。", refIdent},
+}
+
+var typeExplanations = []explanation{
+	{uint32(ET_NONE), "No file type.", "未知文件类型。", refHeader},
+	{uint32(ET_REL), "Relocatable object (.o), input of the link editor.", "可重定位文件（.o），作为链接器的输入。", refHeader},
+	{uint32(ET_EXEC), "Executable loaded at the fixed addresses of its segments.", "可执行文件，按段中的固定地址装载。", refHeader},
+	{uint32(ET_DYN), "Shared object, a library or a position independent executable loaded at any address.", "共享目标文件，即动态库或可装载到任意地址的位置无关可执行文件（PIE）。", refHeader},
+	{uint32(ET_CORE), "Core dump of a process.", "进程的核心转储文件。", refHeader},
+}
+
+var sectionTypeExplanations = []explanation{
+	{uint32(SHT_NULL), "Inactive header without an associated section, always entry 0.", "未使用的节头，没有对应的节，总是第0项。", refSections},
+	{uint32(SHT_PROGBITS), "Data whose format is defined by the program: code, read-only data, initialized data.", "格式由程序决定的数据：代码、只读数据、已初始化数据等。", refSections},
+	{uint32(SHT_SYMTAB), "Complete symbol table for link editing, may be stripped.", "供链接使用的完整符号表，可被strip删除。", refSections},
+	{uint32(SHT_STRTAB), "String table, NUL terminated strings referenced by offset.", "字符串表，以偏移引用的以NUL结尾的字符串。", refSections},
+	{uint32(SHT_RELA), "Relocation entries with explicit addends.", "带显式加数（addend）的重定位表项。", refSections},
+	{uint32(SHT_HASH), "SysV symbol hash table used by the dynamic linker.", "动态链接器使用的SysV符号哈希表。", refSections},
+	{uint32(SHT_DYNAMIC), "Dynamic linking information, an array of Elf_Dyn.", "动态链接信息，由Elf_Dyn组成的数组。", refSections},
+	{uint32(SHT_NOTE), "Notes, vendor specific information such as the build ID or the ABI tag.", "注释信息，如构建ID、ABI标签等厂商信息。", refSections},
+	{uint32(SHT_NOBITS), "Occupies no file space, zero filled in memory (.bss).", "不占用文件空间，在内存中以0填充（.bss）。", refSections},
+	{uint32(SHT_REL), "Relocation entries without explicit addends.", "不带显式加数的重定位表项。", refSections},
+	{uint32(SHT_SHLIB), "Reserved with unspecified semantics.", "保留类型，语义未定义。", refSections},
+	{uint32(SHT_DYNSYM), "Minimal symbol table used for dynamic linking.", "动态链接所需的最小符号表。", refSections},
+	{uint32(SHT_INIT_ARRAY), "Array of pointers to initialization functions.", "初始化函数指针数组。", refSections},
+	{uint32(SHT_FINI_ARRAY), "Array of pointers to termination functions.", "终止函数指针数组。", refSections},
+	{uint32(SHT_PREINIT_ARRAY), "Array of functions called before all other initialization functions.", "在其他初始化函数之前调用的函数指针数组。", refSections},
+	{uint32(SHT_GROUP), "Section group, sections the link editor keeps or discards together (COMDAT).", "节组，链接器整体保留或丢弃的一组节（COMDAT）。", refSections},
+	{uint32(SHT_SYMTAB_SHNDX), "Extended section indexes of the symbols whose st_shndx is SHN_XINDEX.", "st_shndx为SHN_XINDEX的符号的扩展节索引。", refSections},
+	{uint32(SHT_RELR), "Relative relocations packed as addresses and bitmaps (-z pack-relative-relocs).", "以地址和位图压缩存放的相对重定位（-z pack-relative-relocs）。", refSections},
+	{uint32(SHT_GNU_ATTRIBUTES), "GNU object attributes.", "GNU目标文件属性。", refGNU},
+	{uint32(SHT_GNU_HASH), "GNU symbol hash table with a Bloom filter, faster than SHT_HASH.", "带Bloom过滤器的GNU符号哈希表，比SHT_HASH更快。", refGNU},
+	{uint32(SHT_GNU_LIBLIST), "Prelink library list.", "prelink使用的库列表。", refGNU},
+	{uint32(SHT_GNU_VERDEF), "Symbol versions defined by this object.", "本目标文件定义的符号版本。", refGNU},
+	{uint32(SHT_GNU_VERNEED), "Symbol versions required from other objects.", "需要其他目标文件提供的符号版本。", refGNU},
+	{uint32(SHT_GNU_VERSYM), "Version index of each dynamic symbol.", "每个动态符号对应的版本索引。", refGNU},
+}
+
+var programTypeExplanations = []explanation{
+	{uint32(PT_NULL), "Unused entry.", "未使用的表项。", refSegments},
+	{uint32(PT_LOAD), "Loadable segment, mapped from the file at p_vaddr, memory past p_filesz is zeroed.", "可装载段，从文件映射到p_vaddr，超出p_filesz的内存清零。", refSegments},
+	{uint32(PT_DYNAMIC), "Dynamic linking information (the .dynamic table).", "动态链接信息（.dynamic表）。", refSegments},
+	{uint32(PT_INTERP), "Path of the program interpreter (dynamic linker) to run.", "需要调用的程序解释器（动态链接器）路径。", refSegments},
+	{uint32(PT_NOTE), "Location of notes.", "注释信息所在位置。", refSegments},
+	{uint32(PT_SHLIB), "Reserved with unspecified semantics.", "保留类型，语义未定义。", refSegments},
+	{uint32(PT_PHDR), "Location of the program header table itself in memory.", "程序头表自身在内存中的位置。", refSegments},
+	{uint32(PT_TLS), "Thread local storage template.", "线程局部存储（TLS）的初始化模板。", refSegments},
+	{uint32(PT_GNU_EH_FRAME), "Exception handling frame index (.eh_frame_hdr).", "异常处理帧索引（.eh_frame_hdr）。", refGNU},
+	{uint32(PT_GNU_STACK), "Stack permissions, an executable stack needs PF_X.", "栈的访问权限，可执行栈需要PF_X。", refGNU},
+	{uint32(PT_GNU_RELRO), "Made read-only after relocation (RELRO).", "重定位完成后设为只读的区域（RELRO）。", refGNU},
+	{uint32(PT_GNU_PROPERTY), "GNU program properties such as CET or BTI.", "GNU程序属性，如CET、BTI等。", refGNU},
+}
+
+var dynTagExplanations = []explanation{
+	{uint32(DT_NULL), "Marks the end of the dynamic array.", "动态数组的结束标记。", refDynamic},
+	{uint32(DT_NEEDED), "Name of a needed library, an offset into the dynamic string table.", "依赖库的名字，是动态字符串表中的偏移。", refDynamic},
+	{uint32(DT_PLTRELSZ), "Total size of the PLT relocations.", "PLT重定位表项的总大小。", refDynamic},
+	{uint32(DT_PLTGOT), "Address of the PLT and/or GOT.", "PLT或GOT的地址。", refDynamic},
+	{uint32(DT_HASH), "Address of the SysV symbol hash table.", "SysV符号哈希表的地址。", refDynamic},
+	{uint32(DT_STRTAB), "Address of the dynamic string table.", "动态字符串表的地址。", refDynamic},
+	{uint32(DT_SYMTAB), "Address of the dynamic symbol table.", "动态符号表的地址。", refDynamic},
+	{uint32(DT_RELA), "Address of the Rela relocation table.", "Rela重定位表的地址。", refDynamic},
+	{uint32(DT_RELASZ), "Total size of the Rela relocation table.", "Rela重定位表的总大小。", refDynamic},
+	{uint32(DT_RELAENT), "Size of one Rela entry.", "每个Rela表项的大小。", refDynamic},
+	{uint32(DT_STRSZ), "Size of the dynamic string table.", "动态字符串表的大小。", refDynamic},
+	{uint32(DT_SYMENT), "Size of one dynamic symbol.", "每个动态符号表项的大小。", refDynamic},
+	{uint32(DT_INIT), "Address of the initialization function.", "初始化函数的地址。", refDynamic},
+	{uint32(DT_FINI), "Address of the termination function.", "终止函数的地址。", refDynamic},
+	{uint32(DT_SONAME), "Shared object name, an offset into the dynamic string table.", "共享库的名字（soname），是动态字符串表中的偏移。", refDynamic},
+	{uint32(DT_RPATH), "Library search path (deprecated, see DT_RUNPATH).", "库搜索路径（已废弃，见DT_RUNPATH）。", refDynamic},
+	{uint32(DT_SYMBOLIC), "Resolve symbols in this object first.", "优先在本目标文件中解析符号。", refDynamic},
+	{uint32(DT_REL), "Address of the Rel relocation table.", "Rel重定位表的地址。", refDynamic},
+	{uint32(DT_RELSZ), "Total size of the Rel relocation table.", "Rel重定位表的总大小。", refDynamic},
+	{uint32(DT_RELENT), "Size of one Rel entry.", "每个Rel表项的大小。", refDynamic},
+	{uint32(DT_PLTREL), "Type of the PLT relocations, DT_REL or DT_RELA.", "PLT重定位的类型，DT_REL或DT_RELA。", refDynamic},
+	{uint32(DT_DEBUG), "Used by debuggers, filled in at run time.", "供调试器使用，运行时填写。", refDynamic},
+	{uint32(DT_TEXTREL), "Relocations may modify a non-writable segment.", "存在修改不可写段的重定位。", refDynamic},
+	{uint32(DT_JMPREL), "Address of the PLT relocations.", "PLT重定位表的地址。", refDynamic},
+	{uint32(DT_BIND_NOW), "Process all relocations before transferring control (no lazy binding).", "在移交控制前处理全部重定位（不使用延迟绑定）。", refDynamic},
+	{uint32(DT_INIT_ARRAY), "Address of the array of initialization functions.", "初始化函数指针数组的地址。", refDynamic},
+	{uint32(DT_FINI_ARRAY), "Address of the array of termination functions.", "终止函数指针数组的地址。", refDynamic},
+	{uint32(DT_INIT_ARRAYSZ), "Size of DT_INIT_ARRAY in bytes.", "DT_INIT_ARRAY的字节大小。", refDynamic},
+	{uint32(DT_FINI_ARRAYSZ), "Size of DT_FINI_ARRAY in bytes.", "DT_FINI_ARRAY的字节大小。", refDynamic},
+	{uint32(DT_RUNPATH), "Library search path, an offset into the dynamic string table.", "库搜索路径，是动态字符串表中的偏移。", refDynamic},
+	{uint32(DT_FLAGS), "DF_* flags of this object.", "本目标文件的DF_*标志。", refDynamic},
+	{uint32(DT_PREINIT_ARRAY), "Address of the array of pre-initialization functions.", "预初始化函数指针数组的地址。", refDynamic},
+	{uint32(DT_PREINIT_ARRAYSZ), "Size of DT_PREINIT_ARRAY in bytes.", "DT_PREINIT_ARRAY的字节大小。", refDynamic},
+	{uint32(DT_GNU_HASH), "Address of the GNU symbol hash table.", "GNU符号哈希表的地址。", refGNU},
+	{uint32(DT_VERSYM), "Address of the symbol version index table (.gnu.version).", "符号版本索引表（.gnu.version）的地址。", refGNU},
+	{uint32(DT_RELACOUNT), "Number of R_*_RELATIVE relocations at the start of DT_RELA.", "DT_RELA开头的R_*_RELATIVE重定位的个数。", refGNU},
+	{uint32(DT_RELCOUNT), "Number of R_*_RELATIVE relocations at the start of DT_REL.", "DT_REL开头的R_*_RELATIVE重定位的个数。", refGNU},
+	{uint32(DT_FLAGS_1), "DF_1_* flags such as NOW or PIE.", "DF_1_*标志，如NOW、PIE等。", refGNU},
+	{uint32(DT_VERDEF), "Address of the version definitions.", "版本定义表的地址。", refGNU},
+	{uint32(DT_VERDEFNUM), "Number of version definitions.", "版本定义的个数。", refGNU},
+	{uint32(DT_VERNEED), "Address of the version requirements.", "版本需求表的地址。", refGNU},
+	{uint32(DT_VERNEEDNUM), "Number of version requirements.", "版本需求的个数。", refGNU},
+}
+
+var symbolTypeExplanations = []explanation{
+	{uint32(STT_NOTYPE), "Type not specified.", "未指定类型。", refSymbols},
+	{uint32(STT_OBJECT), "Data object such as a variable or an array.", "数据对象，如变量、数组等。", refSymbols},
+	{uint32(STT_FUNC), "Function or other executable code.", "函数或其他可执行代码。", refSymbols},
+	{uint32(STT_SECTION), "Symbol standing for a section, used by relocations.", "代表某个节的符号，供重定位使用。", refSymbols},
+	{uint32(STT_FILE), "Name of the source file of the object.", "目标文件对应的源文件名。", refSymbols},
+	{uint32(STT_COMMON), "Uninitialized common block.", "未初始化的公共块（common）。", refSymbols},
+	{uint32(STT_TLS), "Thread local storage entity, the value is an offset in the TLS block.", "线程局部存储对象，值为TLS块中的偏移。", refSymbols},
+	{uint32(STT_LOOS), "STT_GNU_IFUNC: indirect function, the value is a resolver returning the implementation.", "STT_GNU_IFUNC：间接函数，其值是返回实际实现的解析函数。", refGNU},
+}
+
+var symbolBindExplanations = []explanation{
+	{uint32(STB_LOCAL), "Not visible outside the object.", "在目标文件外部不可见。", refSymbols},
+	{uint32(STB_GLOBAL), "Visible to all objects being combined.", "对所有参与链接的目标文件可见。", refSymbols},
+	{uint32(STB_WEAK), "Global with lower precedence, may stay undefined.", "优先级较低的全局符号，可以不被定义。", refSymbols},
+	{uint32(STB_LOOS), "STB_GNU_UNIQUE: a single definition is used in the whole process.", "STB_GNU_UNIQUE：整个进程中只使用一个定义。", refGNU},
+}
+
+var symbolVisibilityExplanations = []explanation{
+	{uint32(STV_DEFAULT), "Visibility given by the binding, may be preempted.", "可见性由绑定属性决定，可被抢占。", refSymbols},
+	{uint32(STV_INTERNAL), "Processor specific hidden class.", "处理器相关的隐藏类别。", refSymbols},
+	{uint32(STV_HIDDEN), "Not visible to other components.", "对其他组件不可见。", refSymbols},
+	{uint32(STV_PROTECTED), "Visible to other components but not preemptable.", "对其他组件可见，但不可被抢占。", refSymbols},
+}
+
+var sectionIndexExplanations = []explanation{
+	{uint32(SHN_UNDEF), "Undefined, the symbol is defined in another object.", "未定义，符号在其他目标文件中定义。", refSpecial},
+	{uint32(SHN_ABS), "Absolute value, not affected by relocation.", "绝对值，不受重定位影响。", refSpecial},
+	{uint32(SHN_COMMON), "Common block not yet allocated, the value is its alignment.", "尚未分配的公共块，值为其对齐要求。", refSpecial},
+	{uint32(SHN_XINDEX), "The real index is in the SHT_SYMTAB_SHNDX section.", "实际索引保存在SHT_SYMTAB_SHNDX节中。", refSpecial},
+}
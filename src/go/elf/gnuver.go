@@ -3,7 +3,6 @@ package elf
 
 import (
 	"errors"
-	"fmt"
 )
 
 // GNUVersion holds the version information
@@ -35,14 +34,32 @@ func (p *Parser) ParseGNUVersionTable(str []byte) error {
 	// GNU 依赖版本信息存放在.gnu.version_r节中
 	// .gnu.version_r 表示二进制程序实际依赖的库文件版本
 	// SHT_GNU_VERNEED GNU version needs section
-	gnuVersionNeedSection := p.F.Get64SectionByType(SHT_GNU_VERNEED)
+	gnuVersionNeedSection := p.F.SectionByType(SHT_GNU_VERNEED)
 	if gnuVersionNeedSection == nil {
 		return errors.New("no gnu verneed section in file")
 	}
 	// 获取.gnu.version_r节的数据，这个节数据什么规律？
 	// .gnu.version_r节的字符串数据存放在.dynstr节数据中
 	gnuVersionNeedSectionData, _ := gnuVersionNeedSection.Data()
+	gnuVersionNeed := p.parseGNUVersionNeed(gnuVersionNeedSectionData, str)
 
+	// Versym parallels symbol table, indexing into verneed.
+	// GNU库依赖符号信息
+	gnuVersionSymSection := p.F.SectionByType(SHT_GNU_VERSYM)
+	if gnuVersionSymSection == nil {
+		return errors.New("no gnu versym section in file")
+	}
+	gnuVersionSymSectionData, _ := gnuVersionSymSection.Data()
+	p.F.GNUVersion = gnuVersionNeed
+	p.F.GNUVersionSym = gnuVersionSymSectionData
+	return nil
+
+}
+
+// parseGNUVersionNeed decodes a chain of Elf_Verneed/Elf_Vernaux records,
+// the result is indexed by the version index found in the versym table.
+// 节头存在时数据来自.gnu.version_r，节头被剥离时来自DT_VERNEED指向的内存
+func (p *Parser) parseGNUVersionNeed(data []byte, str []byte) []GNUVersion {
 	var gnuVersionNeed []GNUVersion
 	i := 0
 	// typedef struct
@@ -54,22 +71,21 @@ func (p *Parser) ParseGNUVersionTable(str []byte) error {
 	//  Elf64_Word	vn_next;		/* Offset in bytes to next verneed entry */
 	//} Elf64_Verneed;
 	for {
-		if i+16 > len(gnuVersionNeedSectionData) {
+		if i+16 > len(data) {
 			break
 		}
-		vers := p.F.ByteOrder().Uint16(gnuVersionNeedSectionData[i : i+2])
+		vers := p.F.ByteOrder().Uint16(data[i : i+2])
 		if vers != 1 {
 			break
 		}
 		/* 如果cnt!=0，则表示有辅助信息，因此还需要一个循环去处理 */
-		cnt := p.F.ByteOrder().Uint16(gnuVersionNeedSectionData[i+2 : i+4])
-		fmt.Println("cnt:" + fmt.Sprintf("%d", cnt))
+		cnt := p.F.ByteOrder().Uint16(data[i+2 : i+4])
 		/* 文件名称，在.dynstr的偏移量 */
-		fileoff := p.F.ByteOrder().Uint32(gnuVersionNeedSectionData[i+4 : i+8])
+		fileoff := p.F.ByteOrder().Uint32(data[i+4 : i+8])
 		/* 到vernaux array偏移量 */
-		aux := p.F.ByteOrder().Uint32(gnuVersionNeedSectionData[i+8 : i+12])
+		aux := p.F.ByteOrder().Uint32(data[i+8 : i+12])
 		/* 到下一个条目的偏移量，相当于指向下一个节点的指针 */
-		next := p.F.ByteOrder().Uint32(gnuVersionNeedSectionData[i+12 : i+16])
+		next := p.F.ByteOrder().Uint32(data[i+12 : i+16])
 		// 从.dynstr字符串节指定偏移中提取string
 		file, _ := getString(str, int(fileoff))
 
@@ -85,12 +101,12 @@ func (p *Parser) ParseGNUVersionTable(str []byte) error {
 		//} Elf64_Vernaux;
 		j := i + int(aux)
 		for c := 0; c < int(cnt); c++ {
-			if j+16 > len(gnuVersionNeedSectionData) {
+			if j+16 > len(data) {
 				break
 			}
-			other := p.F.ByteOrder().Uint16(gnuVersionNeedSectionData[j+6 : j+8])
-			nameoff := p.F.ByteOrder().Uint32(gnuVersionNeedSectionData[j+8 : j+12])
-			next := p.F.ByteOrder().Uint32(gnuVersionNeedSectionData[j+12 : j+16])
+			other := p.F.ByteOrder().Uint16(data[j+6 : j+8])
+			nameoff := p.F.ByteOrder().Uint32(data[j+8 : j+12])
+			next := p.F.ByteOrder().Uint32(data[j+12 : j+16])
 			// 从.dynstr字符串节指定偏移中提取string
 			name, _ = getString(str, int(nameoff))
 			ndx := int(other)
@@ -109,19 +125,8 @@ func (p *Parser) ParseGNUVersionTable(str []byte) error {
 			break
 		}
 		i += int(next)
-
 	}
-	// Versym parallels symbol table, indexing into verneed.
-	// GNU库依赖符号信息
-	gnuVersionSymSection := p.F.Get64SectionByType(SHT_GNU_VERSYM)
-	if gnuVersionSymSection == nil {
-		return errors.New("no gnu versym section in file")
-	}
-	gnuVersionSymSectionData, _ := gnuVersionSymSection.Data()
-	p.F.GNUVersion = gnuVersionNeed
-	p.F.GNUVersionSym = gnuVersionSymSectionData
-	return nil
-
+	return gnuVersionNeed
 }
 
 // gnuVersion adds Library and Version information to namedSymbol,
@@ -139,3 +144,153 @@ func (p *Parser) gnuVersion(i int) (string, string) {
 	n := &p.F.GNUVersion[j]
 	return n.File, n.Name
 }
+
+// GNUVersionNeed is an Elf_Verneed record together with its Elf_Vernaux entries.
+type GNUVersionNeed struct {
+	Offset  uint64              `json:"offset"` // Offset of the record in the section.
+	Version uint16              `json:"version"`
+	File    string              `json:"file"`
+	Aux     []GNUVersionNeedAux `json:"aux"`
+}
+
+// GNUVersionNeedAux is an Elf_Vernaux entry.
+type GNUVersionNeedAux struct {
+	Offset uint64 `json:"offset"`
+	Hash   uint32 `json:"hash"`
+	Flags  uint16 `json:"flags"`
+	Other  uint16 `json:"index"` // Version index referenced from the versym table.
+	Name   string `json:"name"`
+}
+
+// GNUVersionDef is an Elf_Verdef record together with the names of its
+// Elf_Verdaux entries, the first name is the version itself, the others its parents.
+type GNUVersionDef struct {
+	Offset  uint64   `json:"offset"`
+	Version uint16   `json:"version"`
+	Flags   uint16   `json:"flags"`
+	Index   uint16   `json:"index"`
+	Hash    uint32   `json:"hash"`
+	Names   []string `json:"names"`
+	// AuxOffsets holds the section offset of each Elf_Verdaux entry.
+	AuxOffsets []uint64 `json:"aux_offsets"`
+}
+
+// versionData returns the content of a version section, or the memory
+// pointed by the dynamic tag when the section headers are gone.
+func (p *Parser) versionData(typ SectionType, tag DynTag) ([]byte, []byte, error) {
+	if s := p.F.SectionByType(typ); s != nil {
+		data, err := s.Data()
+		if err != nil {
+			return nil, nil, err
+		}
+		str, err := p.F.stringTable(s.Link)
+		return data, str, err
+	}
+	addr, ok := p.F.DynValue(tag)
+	if !ok {
+		return nil, nil, ErrNoVersion
+	}
+	data, err := p.F.fileImage(addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	str, err := p.dynamicStringTable()
+	return data, str, err
+}
+
+// VersionNeeds decodes the version requirements (.gnu.version_r).
+func (p *Parser) VersionNeeds() ([]GNUVersionNeed, error) {
+	data, str, err := p.versionData(SHT_GNU_VERNEED, DT_VERNEED)
+	if err != nil {
+		return nil, err
+	}
+	bo := p.F.ByteOrder()
+	var needs []GNUVersionNeed
+	for i := 0; i+16 <= len(data); {
+		need := GNUVersionNeed{Offset: uint64(i), Version: bo.Uint16(data[i:])}
+		cnt := int(bo.Uint16(data[i+2:]))
+		need.File, _ = getString(str, int(bo.Uint32(data[i+4:])))
+		aux := int(bo.Uint32(data[i+8:]))
+		next := int(bo.Uint32(data[i+12:]))
+		for c, j := 0, i+aux; c < cnt && j+16 <= len(data); c++ {
+			a := GNUVersionNeedAux{
+				Offset: uint64(j),
+				Hash:   bo.Uint32(data[j:]),
+				Flags:  bo.Uint16(data[j+4:]),
+				Other:  bo.Uint16(data[j+6:]),
+			}
+			a.Name, _ = getString(str, int(bo.Uint32(data[j+8:])))
+			need.Aux = append(need.Aux, a)
+			auxNext := int(bo.Uint32(data[j+12:]))
+			if auxNext == 0 {
+				break
+			}
+			j += auxNext
+		}
+		needs = append(needs, need)
+		if next == 0 {
+			break
+		}
+		i += next
+	}
+	return needs, nil
+}
+
+// VersionDefs decodes the version definitions (.gnu.version_d).
+func (p *Parser) VersionDefs() ([]GNUVersionDef, error) {
+	data, str, err := p.versionData(SHT_GNU_VERDEF, DT_VERDEF)
+	if err != nil {
+		return nil, err
+	}
+	bo := p.F.ByteOrder()
+	var defs []GNUVersionDef
+	for i := 0; i+20 <= len(data); {
+		def := GNUVersionDef{
+			Offset:  uint64(i),
+			Version: bo.Uint16(data[i:]),
+			Flags:   bo.Uint16(data[i+2:]),
+			Index:   bo.Uint16(data[i+4:]),
+			Hash:    bo.Uint32(data[i+8:]),
+		}
+		cnt := int(bo.Uint16(data[i+6:]))
+		aux := int(bo.Uint32(data[i+12:]))
+		next := int(bo.Uint32(data[i+16:]))
+		for c, j := 0, i+aux; c < cnt && j+8 <= len(data); c++ {
+			name, _ := getString(str, int(bo.Uint32(data[j:])))
+			def.Names = append(def.Names, name)
+			def.AuxOffsets = append(def.AuxOffsets, uint64(j))
+			auxNext := int(bo.Uint32(data[j+4:]))
+			if auxNext == 0 {
+				break
+			}
+			j += auxNext
+		}
+		defs = append(defs, def)
+		if next == 0 {
+			break
+		}
+		i += next
+	}
+	return defs, nil
+}
+
+// VersionSymbols returns the versym table (.gnu.version), one index per dynamic symbol.
+func (p *Parser) VersionSymbols() ([]uint16, error) {
+	var data []byte
+	if s := p.F.SectionByType(SHT_GNU_VERSYM); s != nil {
+		d, err := s.Data()
+		if err != nil {
+			return nil, err
+		}
+		data = d
+	} else if p.F.FromSegments && len(p.F.GNUVersionSym) != 0 {
+		data = p.F.GNUVersionSym
+	} else {
+		return nil, ErrNoVersion
+	}
+	versym := make([]uint16, len(data)/2)
+	for i := range versym {
+		versym[i] = p.F.ByteOrder().Uint16(data[i*2:])
+	}
+	return versym, nil
+}
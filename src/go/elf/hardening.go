@@ -0,0 +1,311 @@
+// Package elf : hardening.go collects the hardening facts checksec reports:
+// RELRO, NX, PIE, stack canary, FORTIFY, RPATH/RUNPATH, the CET and
+// BTI/PAC properties and the writable and executable segments.
+package elf
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ViewHardening is the kind of the view built by HardeningView, it is not
+// part of AllViews.
+const ViewHardening ViewKind = "hardening"
+
+// RELROLevel is how much of the relocated data is made read-only after
+// the dynamic linker is done with it.
+type RELROLevel string
+
+const (
+	RELRONone    RELROLevel = "none"
+	RELROPartial RELROLevel = "partial" // PT_GNU_RELRO, the PLT GOT stays writable for lazy binding
+	RELROFull    RELROLevel = "full"    // PT_GNU_RELRO and bind now
+)
+
+// PIEKind tells whether the file can be loaded at any address.
+type PIEKind string
+
+const (
+	PIENo  PIEKind = "no"  // ET_EXEC
+	PIEYes PIEKind = "yes" // ET_DYN with DF_1_PIE or PT_INTERP
+	PIEDSO PIEKind = "dso" // ET_DYN shared object
+	PIERel PIEKind = "rel" // ET_REL, decided at link time
+)
+
+// FortifyStatus compares the fortified __*_chk imports with the plain
+// functions that have a fortified version.
+type FortifyStatus string
+
+const (
+	FortifyNone    FortifyStatus = "none"    // no function that could be fortified is used
+	FortifyNo      FortifyStatus = "no"      // only unfortified calls
+	FortifyPartial FortifyStatus = "partial" // both
+	FortifyYes     FortifyStatus = "yes"     // only fortified calls
+)
+
+// HardeningReport holds the hardening facts of a file.
+type HardeningReport struct {
+	RELRO RELROLevel `json:"relro"`
+	// GNUStack is set when the file has a PT_GNU_STACK segment, or a
+	// .note.GNU-stack section for relocatable files. Without it the stack
+	// is executable on most architectures.
+	GNUStack bool          `json:"gnu_stack"`
+	NX       bool          `json:"nx"`
+	PIE      PIEKind       `json:"pie"`
+	Canary   bool          `json:"canary"`
+	Fortify  FortifyStatus `json:"fortify"`
+	// Fortified and Unfortified list the names of the imported functions
+	// with and without the __*_chk checks, without the prefix and suffix.
+	Fortified   []string `json:"fortified"`
+	Unfortified []string `json:"unfortified"`
+	RPath       string   `json:"rpath,omitempty"`
+	RunPath     string   `json:"runpath,omitempty"`
+	// IBT and SHSTK are the x86 CET features, BTI and PAC the AArch64 ones,
+	// read from the NT_GNU_PROPERTY_TYPE_0 notes.
+	IBT   bool `json:"ibt"`
+	SHSTK bool `json:"shstk"`
+	BTI   bool `json:"bti"`
+	PAC   bool `json:"pac"`
+	// WXSegments are the indexes of the PT_LOAD segments both writable and
+	// executable.
+	WXSegments []int `json:"wx_segments"`
+}
+
+// fortifiable lists the glibc functions having a __<name>_chk version.
+var fortifiable = map[string]bool{
+	"asprintf": true, "confstr": true, "dprintf": true, "explicit_bzero": true,
+	"fdelt": true, "fgets": true, "fgets_unlocked": true, "fgetws": true,
+	"fgetws_unlocked": true, "fprintf": true, "fread": true, "fread_unlocked": true,
+	"fwprintf": true, "getcwd": true, "getdomainname": true, "getgroups": true,
+	"gethostname": true, "getlogin_r": true, "gets": true, "getwd": true,
+	"mbsnrtowcs": true, "mbsrtowcs": true, "mbstowcs": true, "memcpy": true,
+	"memmove": true, "mempcpy": true, "memset": true, "obstack_printf": true,
+	"obstack_vprintf": true, "poll": true, "ppoll": true, "pread": true,
+	"pread64": true, "printf": true, "read": true, "readlink": true,
+	"readlinkat": true, "realpath": true, "recv": true, "recvfrom": true,
+	"snprintf": true, "sprintf": true, "stpcpy": true, "stpncpy": true,
+	"strcat": true, "strcpy": true, "strncat": true, "strncpy": true,
+	"swprintf": true, "syslog": true, "ttyname_r": true, "vasprintf": true,
+	"vdprintf": true, "vfprintf": true, "vfwprintf": true, "vprintf": true,
+	"vsnprintf": true, "vsprintf": true, "vswprintf": true, "vsyslog": true,
+	"vwprintf": true, "wcpcpy": true, "wcpncpy": true, "wcrtomb": true,
+	"wcscat": true, "wcscpy": true, "wcsncat": true, "wcsncpy": true,
+	"wcsnrtombs": true, "wcsrtombs": true, "wcstombs": true, "wctomb": true,
+	"wmemcpy": true, "wmemmove": true, "wmempcpy": true, "wmemset": true,
+	"wprintf": true,
+}
+
+// GNU property types and feature bits, see the Linux x86-64 and AArch64
+// psABI supplements.
+const (
+	gnuPropertyX86Feature1And     = 0xc0000002
+	gnuPropertyAArch64Feature1And = 0xc0000000
+
+	gnuPropertyX86FeatureIBT     = 0x1
+	gnuPropertyX86FeatureSHSTK   = 0x2
+	gnuPropertyAArch64FeatureBTI = 0x1
+	gnuPropertyAArch64FeaturePAC = 0x2
+)
+
+// Hardening collects the hardening facts of the file.
+func (p *Parser) Hardening() (*HardeningReport, error) {
+	if p.F == nil || !IsValidELFClass(p.F.Class()) {
+		return nil, ErrBadELFClass
+	}
+	r := &HardeningReport{RELRO: RELRONone, PIE: PIENo, Fortified: []string{}, Unfortified: []string{}, WXSegments: []int{}}
+	interp := false
+	for i, ph := range p.F.ProgramHeaders() {
+		flags := ProgFlag(ph.Flags)
+		switch ProgType(ph.Type) {
+		case PT_GNU_STACK:
+			r.GNUStack, r.NX = true, flags&PF_X == 0
+		case PT_GNU_RELRO:
+			r.RELRO = RELROPartial
+		case PT_INTERP:
+			interp = true
+		case PT_LOAD:
+			if flags&(PF_W|PF_X) == PF_W|PF_X {
+				r.WXSegments = append(r.WXSegments, i)
+			}
+		}
+	}
+	// 立即绑定后GOT在启动时即可整体设为只读
+	if r.RELRO == RELROPartial {
+		flags, _ := p.F.DynValue(DT_FLAGS)
+		flags1, _ := p.F.DynValue(DT_FLAGS_1)
+		_, now := p.F.DynValue(DT_BIND_NOW)
+		if now || DynFlag(flags)&DF_BIND_NOW != 0 || DynFlag1(flags1)&DF_1_NOW != 0 {
+			r.RELRO = RELROFull
+		}
+	}
+	switch Type(p.F.rawHeader().Type) {
+	case ET_DYN:
+		r.PIE = PIEDSO
+		flags1, _ := p.F.DynValue(DT_FLAGS_1)
+		if interp || DynFlag1(flags1)&DF_1_PIE != 0 {
+			r.PIE = PIEYes
+		}
+	case ET_REL:
+		r.PIE = PIERel
+		// 目标文件没有段，由.note.GNU-stack节告诉链接器栈是否需要可执行
+		for _, s := range p.F.Sections() {
+			if s.SectionName == ".note.GNU-stack" {
+				r.GNUStack, r.NX = true, SectionFlag(s.Flags)&SHF_EXECINSTR == 0
+			}
+		}
+	}
+
+	imports := p.importedSymbols()
+	r.Canary = imports["__stack_chk_fail"] || imports["__stack_chk_fail_local"] || imports["__stack_chk_guard"]
+	for name := range imports {
+		if fn := strings.TrimSuffix(strings.TrimPrefix(name, "__"), "_chk"); len(fn) == len(name)-6 && fortifiable[fn] {
+			r.Fortified = append(r.Fortified, fn)
+		} else if fortifiable[name] {
+			r.Unfortified = append(r.Unfortified, name)
+		}
+	}
+	sort.Strings(r.Fortified)
+	sort.Strings(r.Unfortified)
+	switch {
+	case len(r.Fortified) != 0 && len(r.Unfortified) != 0:
+		r.Fortify = FortifyPartial
+	case len(r.Fortified) != 0:
+		r.Fortify = FortifyYes
+	case len(r.Unfortified) != 0:
+		r.Fortify = FortifyNo
+	default:
+		r.Fortify = FortifyNone
+	}
+	r.RPath, _ = p.dynString(DT_RPATH)
+	r.RunPath, _ = p.dynString(DT_RUNPATH)
+	p.gnuPropertyFeatures(r)
+	return r, nil
+}
+
+// importedSymbols returns the names of the undefined symbols of the dynamic
+// symbol table, or of the symbol table when there is none.
+func (p *Parser) importedSymbols() map[string]bool {
+	syms, err := p.Symbols(SHT_DYNSYM)
+	if err != nil {
+		syms, _ = p.Symbols(SHT_SYMTAB)
+	}
+	imports := map[string]bool{}
+	for _, s := range syms {
+		if s.Index == SHN_UNDEF && s.Name != "" {
+			imports[s.Name] = true
+		}
+	}
+	return imports
+}
+
+// gnuPropertyFeatures sets the CET and BTI/PAC features of r from the
+// GNU_PROPERTY_X86_FEATURE_1_AND and GNU_PROPERTY_AARCH64_FEATURE_1_AND
+// properties.
+func (p *Parser) gnuPropertyFeatures(r *HardeningReport) {
+	notes, _ := p.Notes()
+	bo := p.F.ByteOrder()
+	align := 8
+	if p.F.Class() == ELFCLASS32 {
+		align = 4
+	}
+	machine := Machine(p.F.rawHeader().Machine)
+	for _, n := range notes {
+		if n.Name != "GNU" || n.Type != NT_GNU_PROPERTY_TYPE_0 {
+			continue
+		}
+		desc := n.Desc
+		for pos := 0; len(desc)-pos >= 8; {
+			typ, datasz := bo.Uint32(desc[pos:]), int(bo.Uint32(desc[pos+4:]))
+			pos += 8
+			if datasz > len(desc)-pos {
+				break
+			}
+			if datasz == 4 {
+				bits := bo.Uint32(desc[pos:])
+				switch {
+				case typ == gnuPropertyX86Feature1And && (machine == EM_X86_64 || machine == EM_386):
+					r.IBT = r.IBT || bits&gnuPropertyX86FeatureIBT != 0
+					r.SHSTK = r.SHSTK || bits&gnuPropertyX86FeatureSHSTK != 0
+				case typ == gnuPropertyAArch64Feature1And && machine == EM_AARCH64:
+					r.BTI = r.BTI || bits&gnuPropertyAArch64FeatureBTI != 0
+					r.PAC = r.PAC || bits&gnuPropertyAArch64FeaturePAC != 0
+				}
+			}
+			pos += (datasz + align - 1) &^ (align - 1)
+		}
+	}
+}
+
+// yesNo formats a boolean fact.
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// orNone formats an empty fact as none.
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// HardeningView builds the view of the hardening facts.
+func (p *Parser) HardeningView() (*View, error) {
+	r, err := p.Hardening()
+	if err != nil {
+		return nil, err
+	}
+	v := &View{Kind: ViewHardening}
+	t := v.addTable("Hardening", "Check", "Value")
+	t.addRow("RELRO", string(r.RELRO))
+	nx := yesNo(r.NX)
+	if !r.GNUStack {
+		nx += " (no PT_GNU_STACK)"
+	}
+	t.addRow("NX", nx)
+	t.addRow("PIE", string(r.PIE))
+	t.addRow("Stack canary", yesNo(r.Canary))
+	t.addRow("FORTIFY", string(r.Fortify))
+	t.addRow("Fortified", orNone(strings.Join(r.Fortified, " ")))
+	t.addRow("Unfortified", orNone(strings.Join(r.Unfortified, " ")))
+	t.addRow("RPATH", orNone(r.RPath))
+	t.addRow("RUNPATH", orNone(r.RunPath))
+	t.addRow("IBT", yesNo(r.IBT))
+	t.addRow("SHSTK", yesNo(r.SHSTK))
+	t.addRow("BTI", yesNo(r.BTI))
+	t.addRow("PAC", yesNo(r.PAC))
+	wx := make([]string, len(r.WXSegments))
+	for i, s := range r.WXSegments {
+		wx[i] = decString(uint64(s))
+	}
+	t.addRow("W+X segments", orNone(strings.Join(wx, " ")))
+	return v, nil
+}
+
+// WriteHardening writes the hardening facts to w. FormatJSON writes the
+// HardeningReport itself, the other formats render HardeningView.
+func (p *Parser) WriteHardening(w io.Writer, format Format) error {
+	if format == FormatJSON {
+		r, err := p.Hardening()
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+	rd, err := NewRenderer(format)
+	if err != nil {
+		return err
+	}
+	v, err := p.HardeningView()
+	if err != nil {
+		return err
+	}
+	return rd.Render(w, []*View{v})
+}
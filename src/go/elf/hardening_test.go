@@ -0,0 +1,62 @@
+package elf
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHardening(t *testing.T) {
+	for _, tc := range []struct {
+		file string
+		want HardeningReport
+	}{
+		// example/hardened.c, built with every protection gcc offers
+		{"gcc-amd64-linux-hardened", HardeningReport{
+			RELRO: RELROFull, GNUStack: true, NX: true, PIE: PIEYes, Canary: true,
+			Fortify: FortifyPartial, Fortified: []string{"printf", "strcpy"}, Unfortified: []string{"read"},
+			IBT: true, SHSTK: true, WXSegments: []int{},
+		}},
+		{"gcc-amd64-linux-exec", HardeningReport{
+			RELRO: RELRONone, GNUStack: true, NX: true, PIE: PIENo,
+			Fortify: FortifyNone, Fortified: []string{}, Unfortified: []string{}, WXSegments: []int{},
+		}},
+		// 没有PT_GNU_STACK，栈默认可执行
+		{"gcc-386-freebsd-exec", HardeningReport{
+			RELRO: RELRONone, PIE: PIENo,
+			Fortify: FortifyNo, Fortified: []string{}, Unfortified: []string{"printf"}, WXSegments: []int{},
+		}},
+		{"go-relocation-test-gcc441-x86-64.obj", HardeningReport{
+			RELRO: RELRONone, GNUStack: true, NX: true, PIE: PIERel,
+			Fortify: FortifyNone, Fortified: []string{}, Unfortified: []string{}, WXSegments: []int{},
+		}},
+	} {
+		p := parseFile(t, path.Join(exampleDir, tc.file))
+		r, err := p.Hardening()
+		if assert.NoError(t, err, tc.file) {
+			assert.Equal(t, tc.want, *r, tc.file)
+		}
+		p.CloseFile()
+	}
+}
+
+func TestWriteHardening(t *testing.T) {
+	p := parseFile(t, path.Join(exampleDir, "gcc-amd64-linux-hardened"))
+	defer p.CloseFile()
+	var out bytes.Buffer
+	assert.NoError(t, p.WriteHardening(&out, FormatJSON))
+	var doc map[string]interface{}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &doc))
+	assert.Equal(t, "full", doc["relro"])
+	assert.Equal(t, true, doc["ibt"])
+	assert.NotContains(t, doc, "rpath")
+
+	out.Reset()
+	assert.NoError(t, p.WriteHardening(&out, FormatText))
+	assert.Contains(t, out.String(), "Hardening:\n")
+	assert.Contains(t, out.String(), "  Unfortified  read\n")
+	assert.Error(t, p.WriteHardening(&out, "xml"))
+}
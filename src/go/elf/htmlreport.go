@@ -0,0 +1,310 @@
+// Package elf : htmlreport.go writes a single self-contained HTML page
+// describing a file: the header facts, sortable section and segment tables,
+// searchable symbol tables, the needed libraries, the security properties,
+// the layout map and a collapsible annotated hexdump of every section. The
+// style sheet and the script are embedded so the page works offline.
+package elf
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// htmlHexLimit bounds the bytes of each section shown in the report.
+const htmlHexLimit = 4096
+
+const htmlReportHead = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.5em; }
+h2 { font-size: 1.2em; border-bottom: 1px solid #ccc; margin-top: 2em; }
+h3 { font-size: 1em; }
+table { border-collapse: collapse; font-family: monospace; font-size: 12px; margin-bottom: 1em; }
+th, td { border: 1px solid #ddd; padding: 2px 6px; text-align: left; white-space: nowrap; }
+th { background: #f0f0f0; }
+table.sortable th { cursor: pointer; }
+table.sortable th.asc::after { content: " \25b2"; }
+table.sortable th.desc::after { content: " \25bc"; }
+tr:nth-child(even) td { background: #fafafa; }
+td.bad { color: #b00; font-weight: bold; }
+td.good { color: #070; }
+pre { font-size: 12px; background: #f8f8f8; padding: 0.5em; overflow-x: auto; }
+details summary { cursor: pointer; font-family: monospace; }
+input.search { width: 30em; margin-bottom: 0.5em; }
+nav a { margin-right: 1em; }
+</style>
+</head>
+<body>
+<h1>%s</h1>
+<nav><a href="#header">Header</a><a href="#security">Security</a><a href="#dependencies">Dependencies</a><a href="#sections">Sections</a><a href="#segments">Segments</a><a href="#symbols">Symbols</a><a href="#layout">Layout</a><a href="#hexdump">Hexdump</a></nav>
+`
+
+// 表头点击排序：0x开头的按十六进制、纯数字按十进制比较，其余按字符串比较
+const htmlReportScript = `<script>
+function cellValue(td) {
+  var s = td.textContent.trim();
+  if (/^0x[0-9a-f]+$/i.test(s)) return parseInt(s, 16);
+  if (/^-?[0-9]+$/.test(s)) return parseInt(s, 10);
+  return s.toLowerCase();
+}
+document.querySelectorAll("table.sortable").forEach(function (table) {
+  table.querySelectorAll("th").forEach(function (th, col) {
+    th.addEventListener("click", function () {
+      var asc = !th.classList.contains("asc");
+      table.querySelectorAll("th").forEach(function (h) { h.classList.remove("asc", "desc"); });
+      th.classList.add(asc ? "asc" : "desc");
+      var body = table.tBodies[0];
+      var rows = Array.prototype.slice.call(body.rows);
+      rows.sort(function (a, b) {
+        var x = cellValue(a.cells[col]), y = cellValue(b.cells[col]);
+        if (typeof x !== typeof y) { x = String(x); y = String(y); }
+        return (x < y ? -1 : x > y ? 1 : 0) * (asc ? 1 : -1);
+      });
+      rows.forEach(function (r) { body.appendChild(r); });
+    });
+  });
+});
+document.querySelectorAll("input.search").forEach(function (input) {
+  input.addEventListener("input", function () {
+    var needle = input.value.toLowerCase();
+    document.querySelectorAll(input.dataset.target + " tbody tr").forEach(function (tr) {
+      tr.style.display = tr.textContent.toLowerCase().indexOf(needle) >= 0 ? "" : "none";
+    });
+  });
+});
+</script>
+`
+
+// htmlTable writes a table of a view, class is the optional class of the
+// table element.
+func htmlTable(ew *errWriter, t *Table, class string) {
+	if t.Title != "" {
+		ew.printf("<h3>%s</h3>\n", html.EscapeString(t.Title))
+	}
+	if class != "" {
+		class = " class=\"" + class + "\""
+	}
+	ew.printf("<table%s>\n<thead><tr>", class)
+	for _, c := range t.Columns {
+		ew.printf("<th>%s</th>", html.EscapeString(c))
+	}
+	ew.printf("</tr></thead>\n<tbody>\n")
+	for _, row := range t.Rows {
+		ew.printf("<tr>")
+		for _, cell := range row {
+			ew.printf("<td>%s</td>", html.EscapeString(cell))
+		}
+		ew.printf("</tr>\n")
+	}
+	ew.printf("</tbody>\n</table>\n")
+}
+
+// htmlView writes every table of a view, or its note when it has none.
+func htmlView(ew *errWriter, v *View, class string) {
+	if v.Note != "" {
+		ew.printf("<p>%s</p>\n", html.EscapeString(v.Note))
+	}
+	for _, t := range v.Tables {
+		htmlTable(ew, t, class)
+	}
+}
+
+// securityFact is a protection of the file, status is the CSS class of
+// the value: "good", "bad" or empty when the fact is informational.
+type securityFact struct {
+	name, value, status string
+}
+
+// securityFacts summarizes the protections of the file.
+func (p *Parser) securityFacts() []securityFact {
+	r, err := p.Hardening()
+	if err != nil {
+		return nil
+	}
+	nx := securityFact{"NX", "no PT_GNU_STACK, executable stack", "bad"}
+	switch {
+	case r.NX:
+		nx.value, nx.status = "enabled", "good"
+	case r.GNUStack:
+		nx.value = "disabled, executable stack"
+	}
+	relro := securityFact{"RELRO", string(r.RELRO), "bad"}
+	switch r.RELRO {
+	case RELROPartial:
+		relro.status = ""
+	case RELROFull:
+		relro.status = "good"
+	}
+	pie := securityFact{"PIE", "no", "bad"}
+	switch r.PIE {
+	case PIEYes:
+		pie.value, pie.status = "yes", "good"
+	case PIEDSO:
+		pie.value, pie.status = "shared object", ""
+	case PIERel:
+		// 目标文件没有段，这些属性要等链接后才确定
+		for _, f := range []*securityFact{&nx, &relro, &pie} {
+			f.value, f.status = "not applicable, relocatable object", ""
+		}
+	}
+	canary := securityFact{"Stack canary", "no", "bad"}
+	if r.Canary {
+		canary.value, canary.status = "yes", "good"
+	}
+	fortify := securityFact{"FORTIFY", "no fortifiable calls", ""}
+	switch r.Fortify {
+	case FortifyYes:
+		fortify.value, fortify.status = "yes", "good"
+	case FortifyPartial:
+		fortify.value = "partial, unfortified: " + strings.Join(r.Unfortified, ", ")
+	case FortifyNo:
+		fortify.value, fortify.status = "no", "bad"
+	}
+	facts := []securityFact{nx, relro, pie, canary, fortify, {"RPATH", orNone(r.RPath), ""}, {"RUNPATH", orNone(r.RunPath), ""}}
+	var cfi []string
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{{"IBT", r.IBT}, {"SHSTK", r.SHSTK}, {"BTI", r.BTI}, {"PAC", r.PAC}} {
+		if f.set {
+			cfi = append(cfi, f.name)
+		}
+	}
+	cf := securityFact{"Control flow protection", "none", ""}
+	if len(cfi) != 0 {
+		cf.value, cf.status = strings.Join(cfi, ", "), "good"
+	}
+	wx := securityFact{"W+X segments", "none", "good"}
+	for i, s := range r.WXSegments {
+		if i == 0 {
+			wx.value, wx.status = "", "bad"
+		} else {
+			wx.value += ", "
+		}
+		wx.value += decString(uint64(s))
+	}
+	return append(facts, cf, wx)
+}
+
+// dynString returns the string of a dynamic entry pointing into the
+// dynamic string table.
+func (p *Parser) dynString(tag DynTag) (string, bool) {
+	off, ok := p.F.DynValue(tag)
+	if !ok {
+		return "", false
+	}
+	strtab, err := p.dynamicStringTable()
+	if err != nil {
+		return "", false
+	}
+	return getString(strtab, int(off))
+}
+
+// dependencyTable lists the DT_SONAME and DT_NEEDED entries.
+func (p *Parser) dependencyTable() *Table {
+	t := &Table{Columns: []string{"Tag", "Name"}}
+	if soname, ok := p.dynString(DT_SONAME); ok {
+		t.addRow("SONAME", soname)
+	}
+	for _, lib := range p.F.Needed {
+		t.addRow("NEEDED", lib)
+	}
+	return t
+}
+
+// WriteHTMLReport writes the report of the file as a standalone HTML page,
+// title is shown as the page heading, usually the file name.
+func (p *Parser) WriteHTMLReport(w io.Writer, title string) error {
+	if p.F == nil || !IsValidELFClass(p.F.Class()) {
+		return ErrBadELFClass
+	}
+	views, err := p.BuildViews(ViewHeader, ViewSections, ViewSegments, ViewDynamic, ViewSymbols, ViewRelocations)
+	if err != nil {
+		return err
+	}
+	layout, err := p.Layout()
+	if err != nil {
+		return err
+	}
+	ew := &errWriter{w: w}
+	ew.printf(htmlReportHead, html.EscapeString(title), html.EscapeString(title))
+
+	ew.printf("<h2 id=\"header\">Header</h2>\n")
+	htmlView(ew, views[0], "")
+	ew.printf("<h2 id=\"security\">Security properties</h2>\n")
+	ew.printf("<table>\n<thead><tr><th>Property</th><th>Value</th></tr></thead>\n<tbody>\n")
+	for _, f := range p.securityFacts() {
+		class := ""
+		if f.status != "" {
+			class = " class=\"" + f.status + "\""
+		}
+		ew.printf("<tr><td>%s</td><td%s>%s</td></tr>\n", f.name, class, html.EscapeString(f.value))
+	}
+	ew.printf("</tbody>\n</table>\n")
+
+	ew.printf("<h2 id=\"dependencies\">Dependencies</h2>\n")
+	if deps := p.dependencyTable(); len(deps.Rows) != 0 {
+		htmlTable(ew, deps, "")
+	} else {
+		ew.printf("<p>There are no needed libraries in this file.</p>\n")
+	}
+	ew.printf("<h2 id=\"sections\">Sections</h2>\n")
+	htmlView(ew, views[1], "sortable")
+	ew.printf("<h2 id=\"segments\">Segments</h2>\n")
+	htmlView(ew, views[2], "sortable")
+	ew.printf("<h2 id=\"dynamic\">Dynamic section</h2>\n")
+	htmlView(ew, views[3], "")
+
+	ew.printf("<h2 id=\"symbols\">Symbols</h2>\n")
+	ew.printf("<input class=\"search\" type=\"search\" placeholder=\"Search symbols\" data-target=\"#symbols-tables\">\n<div id=\"symbols-tables\">\n")
+	htmlView(ew, views[4], "sortable")
+	ew.printf("</div>\n")
+	ew.printf("<h2 id=\"relocations\">Relocations</h2>\n")
+	htmlView(ew, views[5], "sortable")
+
+	ew.printf("<h2 id=\"layout\">Layout</h2>\n")
+	if ew.err == nil {
+		ew.err = layout.WriteSVG(w)
+	}
+
+	ew.printf("<h2 id=\"hexdump\">Hexdump</h2>\n")
+	h := p.F.rawHeader()
+	p.htmlHexDump(ew, "ELF header and program headers", 0, uint64(h.Ehsize)+uint64(h.Phnum)*uint64(h.Phentsize))
+	for i, s := range p.F.Sections() {
+		if SectionType(s.Type) == SHT_NOBITS || s.Size == 0 {
+			continue
+		}
+		p.htmlHexDump(ew, fmt.Sprintf("[%d] %s", i, s.SectionName), s.Off, s.Size)
+	}
+	ew.printf("%s</body>\n</html>\n", htmlReportScript)
+	return ew.err
+}
+
+// htmlHexDump writes the annotated hexdump of a range as a collapsed block,
+// only the first htmlHexLimit bytes are dumped.
+func (p *Parser) htmlHexDump(ew *errWriter, name string, off, size uint64) {
+	if ew.err != nil || off >= uint64(p.F.size) {
+		return
+	}
+	summary := fmt.Sprintf("%s @ 0x%x, %d bytes", name, off, size)
+	if size > htmlHexLimit {
+		size = htmlHexLimit
+		summary += fmt.Sprintf(" (first %d shown)", htmlHexLimit)
+	}
+	if off+size > uint64(p.F.size) {
+		size = uint64(p.F.size) - off
+	}
+	var b bytes.Buffer
+	if err := p.WriteAnnotatedHexDump(&b, off, size); err != nil {
+		ew.err = err
+		return
+	}
+	ew.printf("<details><summary>%s</summary>\n<pre>%s</pre></details>\n", html.EscapeString(summary), html.EscapeString(b.String()))
+}
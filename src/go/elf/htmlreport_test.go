@@ -0,0 +1,47 @@
+package elf
+
+import (
+	"bytes"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteHTMLReport(t *testing.T) {
+	p := parseFile(t, path.Join(exampleDir, "gcc-amd64-linux-exec"))
+	defer p.CloseFile()
+	assert.Equal(t, []securityFact{
+		{"NX", "enabled", "good"},
+		{"RELRO", "none", "bad"},
+		{"PIE", "no", "bad"},
+		{"Stack canary", "no", "bad"},
+		{"FORTIFY", "no fortifiable calls", ""},
+		{"RPATH", "none", ""},
+		{"RUNPATH", "none", ""},
+		{"Control flow protection", "none", ""},
+		{"W+X segments", "none", "good"},
+	}, p.securityFacts())
+
+	var out bytes.Buffer
+	assert.NoError(t, p.WriteHTMLReport(&out, "<exec>"))
+	page := out.String()
+	assert.True(t, strings.HasPrefix(page, "<!DOCTYPE html>"))
+	assert.True(t, strings.HasSuffix(page, "</html>\n"))
+	assert.Contains(t, page, "<title>&lt;exec&gt;</title>")
+	assert.Contains(t, page, "<tr><td>NEEDED</td><td>libc.so.6</td></tr>")
+	assert.Contains(t, page, "<table class=\"sortable\">")
+	assert.Contains(t, page, "<svg ")
+	assert.Contains(t, page, "<details><summary>[13] .text @ 0x")
+	// 报告必须能离线查看，不引用任何外部资源
+	assert.NotContains(t, page, "src=")
+	assert.NotContains(t, page, "<link")
+}
+
+func TestSecurityFactsObject(t *testing.T) {
+	p := parseFile(t, path.Join(exampleDir, "go-relocation-test-gcc441-x86-64.obj"))
+	defer p.CloseFile()
+	facts := p.securityFacts()
+	assert.Equal(t, securityFact{"PIE", "not applicable, relocatable object", ""}, facts[2])
+}
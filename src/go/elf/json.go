@@ -1,54 +1,428 @@
+// Package elf : json.go builds the versioned JSON document of DumpJSON,
+// its layout is described by schema/elf.schema.json.
 package elf
 
 import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
-	"errors"
+	"io"
+	"strconv"
 	"strings"
 )
 
-// DumpJSON marshals the entire binary representation into JSON Format.
-func (p *Parser) DumpJSON() (string, error) {
+// JSONSchemaVersion is the schema_version of the documents written by
+// DumpJSON. The minor number grows when fields are added, the major one
+// when a field is removed or changes meaning.
+const JSONSchemaVersion = "1.1"
 
-	var jsonOutput strings.Builder
+// JSONEnum is an enumerated value, Name is the constant name of the value
+// (SHT_PROGBITS, EM_X86_64...) and Value its number.
+type JSONEnum struct {
+	Name  string `json:"name"`
+	Value uint64 `json:"value"`
+}
 
-	switch p.F.Class() {
-	case ELFCLASS32:
-		jsonBin, err := json.MarshalIndent(p.F.ELFBin32, "", "  ")
-		if err != nil {
-			return "", err
+// JSONFlags is a bit set, Names lists the set flags, bits without a name
+// are listed in hex.
+type JSONFlags struct {
+	Names []string `json:"names"`
+	Value uint64   `json:"value"`
+}
+
+// JSONDocument is the document written by DumpJSON.
+type JSONDocument struct {
+	SchemaVersion string           `json:"schema_version"`
+	Ident         JSONIdent        `json:"ident"`
+	Header        JSONHeader       `json:"header"`
+	Sections      []JSONSection    `json:"sections"`
+	Segments      []JSONSegment    `json:"segments"`
+	Dynamic       []JSONDynamic    `json:"dynamic"`
+	Symbols       []JSONSymbol     `json:"symbols"`
+	Relocations   []JSONRelocation `json:"relocations"`
+	Notes         []JSONNote       `json:"notes"`
+	Hashes        JSONHashes       `json:"hashes"`
+}
+
+// JSONIdent is e_ident.
+type JSONIdent struct {
+	Magic      string   `json:"magic"`
+	Class      JSONEnum `json:"class"`
+	Data       JSONEnum `json:"data"`
+	Version    JSONEnum `json:"version"`
+	OSABI      JSONEnum `json:"osabi"`
+	ABIVersion uint8    `json:"abi_version"`
+}
+
+// JSONHeader holds the ELF header fields following e_ident.
+type JSONHeader struct {
+	Type      JSONEnum `json:"type"`
+	Machine   JSONEnum `json:"machine"`
+	Version   uint32   `json:"version"`
+	Entry     uint64   `json:"entry"`
+	Phoff     uint64   `json:"phoff"`
+	Shoff     uint64   `json:"shoff"`
+	Flags     uint32   `json:"flags"`
+	Ehsize    uint16   `json:"ehsize"`
+	Phentsize uint16   `json:"phentsize"`
+	Phnum     uint16   `json:"phnum"`
+	Shentsize uint16   `json:"shentsize"`
+	Shnum     uint16   `json:"shnum"`
+	Shstrndx  uint16   `json:"shstrndx"`
+}
+
+// JSONSection is a section header with its decoded name.
+type JSONSection struct {
+	Index     int       `json:"index"`
+	Name      string    `json:"name"`
+	Type      JSONEnum  `json:"type"`
+	Flags     JSONFlags `json:"flags"`
+	Addr      uint64    `json:"addr"`
+	Offset    uint64    `json:"offset"`
+	Size      uint64    `json:"size"`
+	Link      uint32    `json:"link"`
+	Info      uint32    `json:"info"`
+	AddrAlign uint64    `json:"addralign"`
+	EntSize   uint64    `json:"entsize"`
+}
+
+// JSONSegment is a program header and the sections it holds.
+type JSONSegment struct {
+	Index    int       `json:"index"`
+	Type     JSONEnum  `json:"type"`
+	Flags    JSONFlags `json:"flags"`
+	Offset   uint64    `json:"offset"`
+	Vaddr    uint64    `json:"vaddr"`
+	Paddr    uint64    `json:"paddr"`
+	Filesz   uint64    `json:"filesz"`
+	Memsz    uint64    `json:"memsz"`
+	Align    uint64    `json:"align"`
+	Sections []string  `json:"sections"`
+}
+
+// JSONDynamic is a dynamic entry, String is set for the entries whose
+// value is an offset into the dynamic string table.
+type JSONDynamic struct {
+	Tag    JSONEnum `json:"tag"`
+	Value  uint64   `json:"value"`
+	String string   `json:"string,omitempty"`
+}
+
+// JSONSymbol is an entry of .dynsym or .symtab, Table tells which.
+type JSONSymbol struct {
+	Table        string   `json:"table"`
+	Index        int      `json:"index"`
+	Name         string   `json:"name"`
+	Value        uint64   `json:"value"`
+	Size         uint64   `json:"size"`
+	Type         JSONEnum `json:"type"`
+	Bind         JSONEnum `json:"bind"`
+	Visibility   JSONEnum `json:"visibility"`
+	SectionIndex uint16   `json:"section_index"`
+	// Section is the name of the section or the SHN_* reserved index.
+	Section string `json:"section"`
+	Version string `json:"version,omitempty"`
+	Library string `json:"library,omitempty"`
+}
+
+// JSONRelocation is a Rel or Rela entry, Addend is absent for Rel entries.
+type JSONRelocation struct {
+	Section     string   `json:"section"`
+	Offset      uint64   `json:"offset"`
+	Info        uint64   `json:"info"`
+	Type        JSONEnum `json:"type"`
+	SymbolIndex uint32   `json:"symbol_index"`
+	Symbol      string   `json:"symbol,omitempty"`
+	Addend      *int64   `json:"addend,omitempty"`
+}
+
+// JSONNote is a note, Desc is hex encoded.
+type JSONNote struct {
+	Owner   string   `json:"owner"`
+	Type    JSONEnum `json:"type"`
+	Desc    string   `json:"desc"`
+	Offset  uint64   `json:"offset"`
+	Section string   `json:"section,omitempty"`
+}
+
+// JSONDigests holds the digests of the whole file.
+type JSONDigests struct {
+	MD5    string `json:"md5"`
+	SHA1   string `json:"sha1"`
+	SHA256 string `json:"sha256"`
+}
+
+// JSONHashes holds the digests of the whole file and its similarity
+// hashes (since 1.1).
+type JSONHashes struct {
+	JSONDigests
+	SimilarityHashes
+}
+
+func jsonEnum(v uint64, names []flagName) JSONEnum {
+	return JSONEnum{Name: constantName(stringify(uint32(v), names, false)), Value: v}
+}
+
+// constantName extracts the constant name from the decorated names of some
+// tables, "DYN (Shared object file) (ET_DYN)[0x0003]" gives ET_DYN.
+func constantName(s string) string {
+	for {
+		i := strings.IndexByte(s, '[')
+		j := strings.IndexByte(s, ']')
+		if i < 0 || j < i {
+			break
 		}
-		jsonSymbols, err := json.MarshalIndent(p.F.ELFSymbols, "", " ")
-		if err != nil {
-			return "", err
+		s = s[:i] + s[j+1:]
+	}
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndexByte(s, '('); i >= 0 && strings.HasSuffix(s, ")") {
+		s = s[i+1 : len(s)-1]
+	}
+	return s
+}
+
+func jsonFlags(v uint64, names []flagName) JSONFlags {
+	f := JSONFlags{Names: []string{}, Value: v}
+	for _, n := range names {
+		if uint64(n.flag)&v == uint64(n.flag) && n.flag != 0 {
+			f.Names = append(f.Names, n.name)
+			v &^= uint64(n.flag)
 		}
-		_, err = jsonOutput.Write(jsonBin)
-		if err != nil {
-			return "", err
+	}
+	if v != 0 {
+		f.Names = append(f.Names, "0x"+strconv.FormatUint(v, 16))
+	}
+	return f
+}
+
+// JSONDocument builds the document written by DumpJSON.
+func (p *Parser) JSONDocument() (*JSONDocument, error) {
+	if !IsValidELFClass(p.F.Class()) {
+		return nil, ErrBadELFClass
+	}
+	h := p.F.rawHeader()
+	doc := &JSONDocument{
+		SchemaVersion: JSONSchemaVersion,
+		Ident:         jsonIdent(h),
+		Header:        jsonHeaderFields(h),
+		Sections:      []JSONSection{},
+		Segments:      []JSONSegment{},
+		Dynamic:       []JSONDynamic{},
+		Symbols:       []JSONSymbol{},
+		Relocations:   []JSONRelocation{},
+		Notes:         []JSONNote{},
+	}
+	sections := p.F.Sections()
+	for i, s := range sections {
+		doc.Sections = append(doc.Sections, jsonSection(i, s))
+	}
+	for i, ph := range p.F.ProgramHeaders() {
+		doc.Segments = append(doc.Segments, p.jsonSegment(i, ph))
+	}
+	strtab, _ := p.dynamicStringTable()
+	for _, d := range p.F.DynamicEntries {
+		e := JSONDynamic{Tag: jsonEnum(uint64(d.Tag), dtStrings), Value: d.Val}
+		switch d.Tag {
+		case DT_NEEDED, DT_SONAME, DT_RPATH, DT_RUNPATH:
+			e.String, _ = getString(strtab, int(d.Val))
 		}
-		_, err = jsonOutput.Write(jsonSymbols)
-		if err != nil {
-			return "", err
+		doc.Dynamic = append(doc.Dynamic, e)
+	}
+	for _, typ := range []SectionType{SHT_DYNSYM, SHT_SYMTAB} {
+		symbols, err := p.Symbols(typ)
+		if err == ErrNoSymbols {
+			continue
 		}
-		return jsonOutput.String(), nil
-	case ELFCLASS64:
-		jsonBin, err := json.MarshalIndent(p.F.ELFBin64, "", "  ")
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		jsonSymbols, err := json.MarshalIndent(p.F.ELFSymbols, "", " ")
-		if err != nil {
-			return "", err
+		table := ".dynsym"
+		if s := p.F.SectionByType(typ); s != nil {
+			table = s.SectionName
 		}
-		_, err = jsonOutput.Write(jsonBin)
-		if err != nil {
-			return "", err
+		for i, sym := range symbols {
+			doc.Symbols = append(doc.Symbols, p.jsonSymbol(table, i, sym))
 		}
-		_, err = jsonOutput.Write(jsonSymbols)
+	}
+	if err := p.jsonRelocations(doc); err != nil {
+		return nil, err
+	}
+	notes, err := p.Notes()
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range notes {
+		doc.Notes = append(doc.Notes, jsonNote(n))
+	}
+	doc.Hashes, err = p.fileHashes()
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func jsonIdent(h ELF64Header) JSONIdent {
+	return JSONIdent{
+		Magic:      hex.EncodeToString(h.Ident[:4]),
+		Class:      jsonEnum(uint64(h.Ident[EI_CLASS]), classStrings),
+		Data:       jsonEnum(uint64(h.Ident[EI_DATA]), dataStrings),
+		Version:    JSONEnum{Name: "EV_" + Version(h.Ident[EI_VERSION]).String(), Value: uint64(h.Ident[EI_VERSION])},
+		OSABI:      jsonEnum(uint64(h.Ident[EI_OSABI]), osABIStrings),
+		ABIVersion: h.Ident[EI_ABIVERSION],
+	}
+}
+
+func jsonHeaderFields(h ELF64Header) JSONHeader {
+	return JSONHeader{
+		Type:    jsonEnum(uint64(h.Type), typeStrings),
+		Machine: jsonEnum(uint64(h.Machine), machineStrings),
+		Version: h.Version, Entry: h.Entry, Phoff: h.Phoff, Shoff: h.Shoff, Flags: h.Flags,
+		Ehsize: h.Ehsize, Phentsize: h.Phentsize, Phnum: h.Phnum,
+		Shentsize: h.Shentsize, Shnum: h.Shnum, Shstrndx: h.Shstrndx,
+	}
+}
+
+func jsonSection(i int, s *ELF64Section) JSONSection {
+	return JSONSection{
+		Index: i, Name: s.SectionName,
+		Type:  jsonEnum(uint64(s.Type), sectionTypeStrings),
+		Flags: jsonFlags(s.Flags, sectionFlagStrings),
+		Addr:  s.Addr, Offset: s.Off, Size: s.Size, Link: s.Link, Info: s.Info,
+		AddrAlign: s.AddrAlign, EntSize: s.EntSize,
+	}
+}
+
+func (p *Parser) jsonSegment(i int, ph ELF64ProgramHeader) JSONSegment {
+	seg := JSONSegment{
+		Index:  i,
+		Type:   jsonEnum(uint64(ph.Type), programTypeStrings),
+		Flags:  jsonFlags(uint64(ph.Flags), programFlagStrings),
+		Offset: ph.Off, Vaddr: ph.Vaddr, Paddr: ph.Paddr, Filesz: ph.Filesz, Memsz: ph.Memsz,
+		Align: ph.Align, Sections: []string{},
+	}
+	for _, s := range p.F.SegmentSections(ph) {
+		seg.Sections = append(seg.Sections, s.SectionName)
+	}
+	return seg
+}
+
+func (p *Parser) jsonSymbol(table string, i int, sym Symbol) JSONSymbol {
+	return JSONSymbol{
+		Table: table, Index: i, Name: sym.Name, Value: sym.Value, Size: sym.Size,
+		Type:         jsonEnum(uint64(ST_TYPE(sym.Info)), sttStrings),
+		Bind:         jsonEnum(uint64(ST_BIND(sym.Info)), stbStrings),
+		Visibility:   jsonEnum(uint64(ST_VISIBILITY(sym.Other)), stvStrings),
+		SectionIndex: uint16(sym.Index),
+		Section:      p.symbolSection(sym.Index),
+		Version:      sym.Version,
+		Library:      sym.Library,
+	}
+}
+
+func (p *Parser) jsonRelocation(section string, r Relocation, symbol string) JSONRelocation {
+	e := JSONRelocation{
+		Section: section, Offset: r.Off, Info: r.Info,
+		Type:        JSONEnum{Name: p.F.RelocTypeString(r.Type), Value: uint64(r.Type)},
+		SymbolIndex: r.Sym,
+		Symbol:      symbol,
+	}
+	if r.HasAddend {
+		addend := r.Addend
+		e.Addend = &addend
+	}
+	return e
+}
+
+func jsonNote(n Note) JSONNote {
+	return JSONNote{
+		Owner: n.Name, Type: JSONEnum{Name: n.TypeString(), Value: uint64(n.Type)},
+		Desc: hex.EncodeToString(n.Desc), Offset: n.Offset, Section: n.Section,
+	}
+}
+
+// symbolSection names the section a symbol is defined in.
+func (p *Parser) symbolSection(idx SectionIndex) string {
+	sections := p.F.Sections()
+	if idx != SHN_UNDEF && idx < SHN_LORESERVE && int(idx) < len(sections) {
+		return sections[idx].SectionName
+	}
+	return constantName(idx.String())
+}
+
+func (p *Parser) jsonRelocations(doc *JSONDocument) error {
+	add := func(section string, relocs []Relocation, symbols []Symbol) {
+		for _, r := range relocs {
+			name := ""
+			if int(r.Sym) < len(symbols) {
+				name = symbols[r.Sym].Name
+			}
+			doc.Relocations = append(doc.Relocations, p.jsonRelocation(section, r, name))
+		}
+	}
+	sections := p.F.Sections()
+	found := false
+	for _, s := range sections {
+		if typ := SectionType(s.Type); typ != SHT_REL && typ != SHT_RELA {
+			continue
+		}
+		found = true
+		relocs, err := p.SectionRelocations(s)
 		if err != nil {
-			return "", err
+			return err
+		}
+		var symbols []Symbol
+		if s.Link != 0 && int(s.Link) < len(sections) {
+			symbols, _ = p.SectionSymbols(sections[s.Link])
 		}
-		return jsonOutput.String(), nil
-	default:
-		return "", errors.New("unsupported ELF Class")
+		add(s.SectionName, relocs, symbols)
+	}
+	if !found && p.F.FromSegments {
+		symbols, _ := p.Symbols(SHT_DYNSYM)
+		add("", p.F.DynRelocations, symbols)
+	}
+	return nil
+}
+
+// fileDigests computes the digests of the file, reading it in a stream.
+func (p *Parser) fileDigests() (JSONDigests, error) {
+	m, s1, s256 := md5.New(), sha1.New(), sha256.New()
+	_, err := io.Copy(io.MultiWriter(m, s1, s256), io.NewSectionReader(p.F.r, 0, p.F.size))
+	if err != nil {
+		return JSONDigests{}, err
+	}
+	return JSONDigests{
+		MD5:    hex.EncodeToString(m.Sum(nil)),
+		SHA1:   hex.EncodeToString(s1.Sum(nil)),
+		SHA256: hex.EncodeToString(s256.Sum(nil)),
+	}, nil
+}
+
+// fileHashes computes the digests and the similarity hashes of the file.
+// The similarity hashes hold the whole file in memory.
+func (p *Parser) fileHashes() (JSONHashes, error) {
+	digests, err := p.fileDigests()
+	if err != nil {
+		return JSONHashes{}, err
+	}
+	similarity, err := p.F.SimilarityHashes()
+	if err != nil {
+		return JSONHashes{}, err
+	}
+	return JSONHashes{JSONDigests: digests, SimilarityHashes: similarity}, nil
+}
+
+// DumpJSON marshals the file into a single JSON document following
+// schema/elf.schema.json.
+func (p *Parser) DumpJSON() (string, error) {
+	doc, err := p.JSONDocument()
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", err
 	}
+	return string(data), nil
 }
@@ -0,0 +1,161 @@
+package elf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// validateSchema checks v against the subset of JSON Schema used by
+// schema/elf.schema.json: type, const, pattern, minimum, properties,
+// required, additionalProperties, items and local $ref.
+func validateSchema(root, schema map[string]interface{}, v interface{}, at string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/$defs/")
+		def, ok := root["$defs"].(map[string]interface{})[name].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: unresolved $ref %s", at, ref)
+		}
+		return validateSchema(root, def, v, at)
+	}
+	if c, ok := schema["const"]; ok && fmt.Sprint(c) != fmt.Sprint(v) {
+		return fmt.Errorf("%s: %v is not %v", at, v, c)
+	}
+	switch schema["type"] {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: not an object", at)
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		for _, r := range schema["required"].([]interface{}) {
+			if _, ok := obj[r.(string)]; !ok {
+				return fmt.Errorf("%s: missing %s", at, r)
+			}
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			sub, ok := props[k].(map[string]interface{})
+			if !ok {
+				if schema["additionalProperties"] == false {
+					return fmt.Errorf("%s: unexpected property %s", at, k)
+				}
+				continue
+			}
+			if err := validateSchema(root, sub, obj[k], at+"."+k); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: not an array", at)
+		}
+		items := schema["items"].(map[string]interface{})
+		for i, e := range arr {
+			if err := validateSchema(root, items, e, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s: not a string", at)
+		}
+		if pattern, ok := schema["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(s) {
+			return fmt.Errorf("%s: %q does not match %s", at, s, pattern)
+		}
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok || strings.ContainsAny(n.String(), ".eE") {
+			return fmt.Errorf("%s: %v is not an integer", at, v)
+		}
+		if _, ok := schema["minimum"]; ok && strings.HasPrefix(n.String(), "-") {
+			return fmt.Errorf("%s: %v is negative", at, v)
+		}
+	}
+	return nil
+}
+
+func decodeJSON(t *testing.T, data []byte) interface{} {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestDumpJSONSchema(t *testing.T) {
+	data, err := ioutil.ReadFile(path.Join("schema", "elf.schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	// schema_version的取值必须与代码中的常量一致
+	assert.Equal(t, JSONSchemaVersion, schema["properties"].(map[string]interface{})["schema_version"].(map[string]interface{})["const"])
+
+	files, err := ioutil.ReadDir(exampleDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fi := range files {
+		name := fi.Name()
+		if strings.HasSuffix(name, ".c") || strings.HasSuffix(name, ".gz") {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			// foo等样本不是合法的ELF文件，跳过
+			p, err := New(path.Join(exampleDir, name))
+			if err != nil {
+				t.Skip(err)
+			}
+			defer p.CloseFile()
+			if err := p.Parse(); err != nil {
+				t.Skip(err)
+			}
+			out, err := p.DumpJSON()
+			if assert.NoError(t, err) {
+				assert.NoError(t, validateSchema(schema, schema, decodeJSON(t, []byte(out)), "$"))
+			}
+		})
+	}
+}
+
+// TestDumpJSONCompatibility compares the document of a sample against a
+// checked in copy, a difference means the schema version has to change.
+func TestDumpJSONCompatibility(t *testing.T) {
+	p, err := New(path.Join(exampleDir, "gcc-amd64-linux-exec"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.CloseFile()
+	if err := p.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	out, err := p.DumpJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ioutil.ReadFile(path.Join("testdata", "json", "gcc-amd64-linux-exec.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, decodeJSON(t, want), decodeJSON(t, []byte(out)))
+}
@@ -0,0 +1,287 @@
+// Package elf : layout.go builds the map of a file: the byte ranges taken
+// by the ELF header, the program header table, each section and the section
+// header table, the gaps and overlaps between them and the trailing overlay,
+// plus the virtual memory image of the PT_LOAD segments. The map is written
+// as an ASCII bar chart, as SVG or as JSON.
+package elf
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Kinds of the regions of a file layout.
+const (
+	RegionHeader  = "header"
+	RegionPHDRs   = "phdrs"
+	RegionSection = "section"
+	RegionSHDRs   = "shdrs"
+	RegionGap     = "gap"
+	RegionOverlay = "overlay"
+)
+
+// LayoutRegion is a byte range of the file.
+type LayoutRegion struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Offset uint64 `json:"offset"`
+	Size   uint64 `json:"size"`
+}
+
+// End returns the offset following the region.
+func (r LayoutRegion) End() uint64 { return r.Offset + r.Size }
+
+// LayoutOverlap reports two regions sharing bytes of the file.
+type LayoutOverlap struct {
+	First  string `json:"first"`
+	Second string `json:"second"`
+	Offset uint64 `json:"offset"`
+	Size   uint64 `json:"size"`
+}
+
+// MemoryRegion is a PT_LOAD segment of the memory image, the bytes past
+// Filesz are zero filled.
+type MemoryRegion struct {
+	Segment int    `json:"segment"`
+	Vaddr   uint64 `json:"vaddr"`
+	Memsz   uint64 `json:"memsz"`
+	Filesz  uint64 `json:"filesz"`
+	Offset  uint64 `json:"offset"`
+	Flags   string `json:"flags"`
+}
+
+// FileLayout is the physical and virtual map of a file.
+type FileLayout struct {
+	FileSize uint64          `json:"file_size"`
+	Regions  []LayoutRegion  `json:"regions"` // Sorted by offset, gaps included.
+	Overlaps []LayoutOverlap `json:"overlaps"`
+	Memory   []MemoryRegion  `json:"memory"`
+}
+
+// Layout builds the map of the file.
+func (p *Parser) Layout() (*FileLayout, error) {
+	if p.F == nil || !IsValidELFClass(p.F.Class()) {
+		return nil, ErrBadELFClass
+	}
+	h := p.F.rawHeader()
+	l := &FileLayout{FileSize: uint64(p.F.size), Overlaps: []LayoutOverlap{}, Memory: []MemoryRegion{}}
+	var regions []LayoutRegion
+	regions = append(regions, LayoutRegion{RegionHeader, "ELF header", 0, uint64(h.Ehsize)})
+	if h.Phnum != 0 {
+		regions = append(regions, LayoutRegion{RegionPHDRs, "program headers", h.Phoff, uint64(h.Phnum) * uint64(h.Phentsize)})
+	}
+	for i, s := range p.F.Sections() {
+		// SHT_NOBITS的节不占文件空间
+		if SectionType(s.Type) == SHT_NULL || SectionType(s.Type) == SHT_NOBITS || s.Size == 0 {
+			continue
+		}
+		regions = append(regions, LayoutRegion{RegionSection, fmt.Sprintf("[%d] %s", i, s.SectionName), s.Off, s.Size})
+	}
+	if h.Shnum != 0 {
+		regions = append(regions, LayoutRegion{RegionSHDRs, "section headers", h.Shoff, uint64(h.Shnum) * uint64(h.Shentsize)})
+	}
+	sort.SliceStable(regions, func(i, j int) bool { return regions[i].Offset < regions[j].Offset })
+
+	// 文件中被ELF结构描述的最远位置，之后的数据是附加在文件尾部的overlay
+	end := uint64(0)
+	for _, r := range regions {
+		if r.End() > end {
+			end = r.End()
+		}
+	}
+	for i, ph := range p.F.ProgramHeaders() {
+		if ph.Off+ph.Filesz > end && ph.Filesz != 0 {
+			end = ph.Off + ph.Filesz
+		}
+		if ProgType(ph.Type) == PT_LOAD {
+			l.Memory = append(l.Memory, MemoryRegion{Segment: i, Vaddr: ph.Vaddr, Memsz: ph.Memsz, Filesz: ph.Filesz, Offset: ph.Off, Flags: segmentFlags(ph.Flags)})
+		}
+	}
+
+	pos := uint64(0)
+	for i, r := range regions {
+		if r.Offset > pos {
+			l.Regions = append(l.Regions, LayoutRegion{RegionGap, "gap", pos, r.Offset - pos})
+		}
+		l.Regions = append(l.Regions, r)
+		// 与之前所有仍未结束的区域比较，找出重叠
+		for _, prev := range regions[:i] {
+			if prev.End() > r.Offset && r.Size != 0 {
+				size := prev.End() - r.Offset
+				if r.End() < prev.End() {
+					size = r.Size
+				}
+				l.Overlaps = append(l.Overlaps, LayoutOverlap{prev.Name, r.Name, r.Offset, size})
+			}
+		}
+		if r.End() > pos {
+			pos = r.End()
+		}
+	}
+	if end > l.FileSize {
+		end = l.FileSize
+	}
+	if end > pos {
+		l.Regions = append(l.Regions, LayoutRegion{RegionGap, "gap", pos, end - pos})
+		pos = end
+	}
+	if l.FileSize > pos {
+		l.Regions = append(l.Regions, LayoutRegion{RegionOverlay, "overlay", pos, l.FileSize - pos})
+	}
+	return l, nil
+}
+
+// bar draws [off, off+size) of a total long range on width columns, at
+// least one column is used so that small regions stay visible.
+func bar(off, size, total uint64, width int, fill byte) string {
+	b := []byte(strings.Repeat(" ", width))
+	if total == 0 {
+		return string(b)
+	}
+	from := int(off * uint64(width) / total)
+	to := int((off + size) * uint64(width) / total)
+	if to <= from {
+		to = from + 1
+	}
+	for i := from; i < to && i < width; i++ {
+		b[i] = fill
+	}
+	return string(b)
+}
+
+// layoutWidth is the number of columns of the bars of WriteText.
+const layoutWidth = 50
+
+// WriteText writes the layout as an ASCII bar chart, one line per region.
+// Sections and headers are drawn with '#', gaps with '.', the overlay with
+// '~'. In the memory view '#' is backed by the file and '+' is zero filled.
+func (l *FileLayout) WriteText(w io.Writer) error {
+	ew := &errWriter{w: w}
+	ew.printf("File layout (%d bytes):\n", l.FileSize)
+	for _, r := range l.Regions {
+		fill := byte('#')
+		switch r.Kind {
+		case RegionGap:
+			fill = '.'
+		case RegionOverlay:
+			fill = '~'
+		}
+		ew.printf("  0x%08x-0x%08x %8d |%s| %s\n", r.Offset, r.End(), r.Size, bar(r.Offset, r.Size, l.FileSize, layoutWidth, fill), r.Name)
+	}
+	if len(l.Overlaps) != 0 {
+		ew.printf("\nOverlaps:\n")
+		for _, o := range l.Overlaps {
+			ew.printf("  0x%08x-0x%08x %8d %s / %s\n", o.Offset, o.Offset+o.Size, o.Size, o.First, o.Second)
+		}
+	}
+	if len(l.Memory) != 0 {
+		lo, hi := l.memoryRange()
+		ew.printf("\nMemory layout (PT_LOAD):\n")
+		for _, m := range l.Memory {
+			b := []byte(bar(m.Vaddr-lo, m.Memsz, hi-lo, layoutWidth, '+'))
+			file := bar(m.Vaddr-lo, m.Filesz, hi-lo, layoutWidth, '#')
+			if m.Filesz != 0 {
+				for i := range b {
+					if file[i] == '#' {
+						b[i] = '#'
+					}
+				}
+			}
+			ew.printf("  0x%08x-0x%08x %s |%s| [%d] filesz 0x%x memsz 0x%x\n", m.Vaddr, m.Vaddr+m.Memsz, m.Flags, b, m.Segment, m.Filesz, m.Memsz)
+		}
+	}
+	return ew.err
+}
+
+// memoryRange returns the lowest and the highest address of the segments.
+func (l *FileLayout) memoryRange() (uint64, uint64) {
+	lo, hi := ^uint64(0), uint64(0)
+	for _, m := range l.Memory {
+		if m.Vaddr < lo {
+			lo = m.Vaddr
+		}
+		if m.Vaddr+m.Memsz > hi {
+			hi = m.Vaddr + m.Memsz
+		}
+	}
+	return lo, hi
+}
+
+// WriteJSON writes the layout as an indented JSON document.
+func (l *FileLayout) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(l)
+}
+
+// SVG geometry: each region is a row with its label on the left and a bar
+// scaled on the file (or memory image) size on the right.
+const (
+	svgRow   = 18
+	svgLabel = 360
+	svgBar   = 600
+)
+
+var svgColors = map[string]string{
+	RegionHeader:  "#d62728",
+	RegionPHDRs:   "#ff7f0e",
+	RegionSection: "#1f77b4",
+	RegionSHDRs:   "#2ca02c",
+	RegionGap:     "#c7c7c7",
+	RegionOverlay: "#9467bd",
+}
+
+// svgLoadColor is the color of the PT_LOAD segments of the memory view.
+const svgLoadColor = "#17becf"
+
+// svgBarRect returns the x and the width of a bar, at least one pixel wide.
+func svgBarRect(off, size, total uint64) (uint64, uint64) {
+	if total == 0 {
+		return svgLabel, 1
+	}
+	x := svgLabel + off*svgBar/total
+	width := size * svgBar / total
+	if width == 0 {
+		width = 1
+	}
+	return x, width
+}
+
+// WriteSVG writes the layout as a standalone SVG image.
+func (l *FileLayout) WriteSVG(w io.Writer) error {
+	ew := &errWriter{w: w}
+	rows := len(l.Regions) + len(l.Memory) + 3
+	ew.printf("<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" font-family=\"monospace\" font-size=\"12\" xml:space=\"preserve\">\n", svgLabel+svgBar+10, rows*svgRow+10)
+	y := svgRow
+	ew.printf("<text x=\"0\" y=\"%d\" font-weight=\"bold\">File layout (%d bytes)</text>\n", y, l.FileSize)
+	for _, r := range l.Regions {
+		y += svgRow
+		x, width := svgBarRect(r.Offset, r.Size, l.FileSize)
+		label := html.EscapeString(fmt.Sprintf("0x%08x %8d %s", r.Offset, r.Size, r.Name))
+		ew.printf("<text x=\"0\" y=\"%d\">%s</text>\n", y, label)
+		ew.printf("<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"%s\"><title>%s</title></rect>\n", x, y-svgRow+5, width, svgRow-4, svgColors[r.Kind], label)
+	}
+	if len(l.Memory) != 0 {
+		lo, hi := l.memoryRange()
+		y += 2 * svgRow
+		ew.printf("<text x=\"0\" y=\"%d\" font-weight=\"bold\">Memory layout (PT_LOAD)</text>\n", y)
+		for _, m := range l.Memory {
+			y += svgRow
+			label := html.EscapeString(fmt.Sprintf("0x%08x %s [%d]", m.Vaddr, m.Flags, m.Segment))
+			ew.printf("<text x=\"0\" y=\"%d\">%s</text>\n", y, label)
+			// 文件支撑的部分实心，只在内存中存在的（.bss）部分半透明
+			x, width := svgBarRect(m.Vaddr-lo, m.Memsz, hi-lo)
+			ew.printf("<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"%s\" fill-opacity=\"0.35\"><title>%s memsz 0x%x</title></rect>\n", x, y-svgRow+5, width, svgRow-4, svgLoadColor, label, m.Memsz)
+			if m.Filesz != 0 {
+				x, width = svgBarRect(m.Vaddr-lo, m.Filesz, hi-lo)
+				ew.printf("<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"%s\"><title>%s filesz 0x%x</title></rect>\n", x, y-svgRow+5, width, svgRow-4, svgLoadColor, label, m.Filesz)
+			}
+		}
+	}
+	ew.printf("</svg>\n")
+	return ew.err
+}
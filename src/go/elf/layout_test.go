@@ -0,0 +1,103 @@
+package elf
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parseFile(t *testing.T, name string) *Parser {
+	p, err := New(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Parse(); err != nil {
+		p.CloseFile()
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLayout(t *testing.T) {
+	p := parseFile(t, path.Join(exampleDir, "gcc-amd64-linux-exec"))
+	defer p.CloseFile()
+	l, err := p.Layout()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 区域首尾相接，覆盖整个文件
+	pos := uint64(0)
+	for _, r := range l.Regions {
+		if r.Offset >= pos {
+			assert.Equal(t, pos, r.Offset, r.Name)
+		}
+		if r.End() > pos {
+			pos = r.End()
+		}
+	}
+	assert.Equal(t, l.FileSize, pos)
+	assert.Equal(t, LayoutRegion{RegionHeader, "ELF header", 0, 64}, l.Regions[0])
+	assert.Equal(t, LayoutRegion{RegionPHDRs, "program headers", 64, 448}, l.Regions[1])
+	assert.Contains(t, l.Regions, LayoutRegion{RegionSHDRs, "section headers", 0x1060, 37 * 64})
+	assert.Contains(t, l.Regions, LayoutRegion{RegionGap, "gap", 0x23c, 4})
+	assert.Empty(t, l.Overlaps)
+	assert.Equal(t, []MemoryRegion{
+		{Segment: 2, Vaddr: 0x400000, Memsz: 0x684, Filesz: 0x684, Offset: 0, Flags: "R E"},
+		{Segment: 3, Vaddr: 0x600688, Memsz: 0x218, Filesz: 0x210, Offset: 0x688, Flags: "RW "},
+	}, l.Memory)
+
+	var out bytes.Buffer
+	assert.NoError(t, l.WriteText(&out))
+	assert.Contains(t, out.String(), "  0x00001060-0x000019a0     2368 |                       ##############             | section headers\n")
+
+	out.Reset()
+	assert.NoError(t, l.WriteJSON(&out))
+	var back FileLayout
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &back))
+	assert.Equal(t, *l, back)
+
+	out.Reset()
+	assert.NoError(t, l.WriteSVG(&out))
+	dec := xml.NewDecoder(&out)
+	for {
+		_, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(t, err) {
+			break
+		}
+	}
+}
+
+func TestLayoutOverlay(t *testing.T) {
+	data, err := ioutil.ReadFile(path.Join(exampleDir, "gcc-386-freebsd-exec"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "layout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "overlay")
+	if err := ioutil.WriteFile(name, append(data, strings.Repeat("overlay!", 16)...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p := parseFile(t, name)
+	defer p.CloseFile()
+	l, err := p.Layout()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, LayoutRegion{RegionOverlay, "overlay", uint64(len(data)), 128}, l.Regions[len(l.Regions)-1])
+}
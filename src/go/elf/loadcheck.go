@@ -0,0 +1,450 @@
+// Package elf : loadcheck.go replays the checks the Linux kernel
+// (fs/binfmt_elf.c load_elf_binary) and the glibc dynamic loader
+// (elf/dl-load.c _dl_map_object_from_fd) make before running a file, so
+// that a hand made or post-processed binary is rejected here rather than
+// by execve. Each failed check is a finding naming the loader, the check
+// and the value it stumbles on.
+package elf
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// ViewLoadability is the kind of the view built by LoadabilityView, it is
+// not part of AllViews.
+const ViewLoadability ViewKind = "loadability"
+
+const (
+	// loadPageSize is the smallest page size of the Linux ports, an offset
+	// misaligned to it is misaligned on every kernel.
+	loadPageSize = 0x1000
+	// loadMinAddr is the default vm.mmap_min_addr, nothing is mapped below.
+	loadMinAddr = 0x10000
+	// loadMaxPhdrs is the size limit of the program header table of
+	// load_elf_phdrs.
+	loadMaxPhdrs = 65536
+	// loadPathMax is PATH_MAX, the longest PT_INTERP the kernel accepts.
+	loadPathMax = 4096
+)
+
+// loadRules is the catalogue of the loadability checks, in the order the
+// kernel and then ld.so make them. The rules have the shape of the anomaly
+// rules.
+var loadRules = []anomalyRule{
+	{FindingRule{"load/type", SeverityError, "load_elf_binary only runs ET_EXEC and ET_DYN files."}, (*Parser).checkLoadType},
+	{FindingRule{"load/phentsize", SeverityError, "load_elf_phdrs and ld.so require e_phentsize to be sizeof(Elf_Phdr)."}, (*Parser).checkLoadPhentsize},
+	{FindingRule{"load/phnum", SeverityError, "load_elf_phdrs requires 1 to 64KiB of program headers, read whole from the file."}, (*Parser).checkLoadPhnum},
+	{FindingRule{"load/interp", SeverityError, "load_elf_binary requires PT_INTERP to be a NUL terminated path of 2 to PATH_MAX bytes."}, (*Parser).checkLoadInterp},
+	{FindingRule{"load/filesz", SeverityError, "load_elf_binary requires p_filesz <= p_memsz for PT_LOAD segments."}, (*Parser).checkLoadFilesz},
+	{FindingRule{"load/page-offset", SeverityError, "elf_map and ld.so require p_offset and p_vaddr of PT_LOAD segments to be congruent modulo the page size."}, (*Parser).checkLoadPageOffset},
+	{FindingRule{"load/align", SeverityWarning, "The gABI wants p_align of a PT_LOAD segment to be 0, 1 or a power of two with p_offset % p_align == p_vaddr % p_align."}, (*Parser).checkLoadAlign},
+	{FindingRule{"load/order", SeverityError, "PT_LOAD segments must be sorted by p_vaddr, the loaders reserve the span from the first to the last one."}, (*Parser).checkLoadOrder},
+	{FindingRule{"load/address-space", SeverityError, "The PT_LOAD segments must fit between vm.mmap_min_addr and TASK_SIZE."}, (*Parser).checkLoadAddressSpace},
+	{FindingRule{"load/segment-past-eof", SeverityWarning, "A PT_LOAD segment maps bytes past the end of the file, touching them raises SIGBUS."}, (*Parser).checkLoadPastEOF},
+	{FindingRule{"load/phdr", SeverityError, "The program header table must be mapped by a PT_LOAD segment for ld.so to read it through AT_PHDR."}, (*Parser).checkLoadPhdr},
+	{FindingRule{"load/tls", SeverityError, "ld.so sets up the thread local storage from a single PT_TLS with p_filesz <= p_memsz, its image inside a PT_LOAD and, as the gABI wants, a power of two p_align."}, (*Parser).checkLoadTLS},
+	{FindingRule{"load/ident", SeverityError, "ld.so's open_verify rejects an e_ident or e_version it does not support."}, (*Parser).checkLoadIdent},
+	{FindingRule{"load/no-load", SeverityError, "ld.so rejects an object without PT_LOAD segments."}, (*Parser).checkLoadNoLoad},
+	{FindingRule{"load/no-dynamic", SeverityWarning, "ld.so rejects a shared object without PT_DYNAMIC."}, (*Parser).checkLoadNoDynamic},
+	{FindingRule{"load/needed-without-interp", SeverityWarning, "The executable needs libraries but has no PT_INTERP to load them."}, (*Parser).checkLoadNeeded},
+}
+
+// loadFatal are the rules whose failure stops load_elf_binary before the
+// segments are looked at, the later rules are not run after them.
+var loadFatal = map[string]bool{"load/type": true, "load/phentsize": true, "load/phnum": true}
+
+// LoadabilityRules returns the catalogue of the loadability rules.
+func LoadabilityRules() []FindingRule {
+	rules := make([]FindingRule, len(loadRules))
+	for i, r := range loadRules {
+		rules[i] = r.FindingRule
+	}
+	return rules
+}
+
+// Loadability runs the loadability checks on the file, the findings are
+// returned in the order of the catalogue. The checks stop after a failed
+// check of the file type or of the program header table. A file without findings passes
+// every check the kernel and ld.so make on the file itself; the checks
+// depending on the machine running it (e_machine, the interpreter, the
+// libraries) are not made.
+func (p *Parser) Loadability() []Finding {
+	findings := []Finding{}
+	if p.F == nil || !IsValidELFClass(p.F.Class()) {
+		return findings
+	}
+	for _, r := range loadRules {
+		r.check(p, func(off, size uint64, section string, format string, args ...interface{}) {
+			f := p.finding(r.ID, off, size, format, args...)
+			if section != "" {
+				f.Section = section
+			}
+			findings = append(findings, f)
+		})
+		// 文件类型或程序头表本身不对时，内核不会再看各个段
+		if len(findings) != 0 && loadFatal[r.ID] {
+			break
+		}
+	}
+	return findings
+}
+
+// phdrSize returns sizeof(Elf_Phdr) of the class of the file.
+func (p *Parser) phdrSize() uint64 {
+	if p.F.Class() == ELFCLASS32 {
+		return 32
+	}
+	return 56
+}
+
+// taskSize returns the TASK_SIZE of the user address space the file is
+// loaded in, the largest of the kernels running the machine.
+func (p *Parser) taskSize() uint64 {
+	if p.F.Class() == ELFCLASS32 {
+		// 32位进程在64位内核上几乎可以使用整个4GB
+		return 0xffffe000
+	}
+	if Machine(p.F.rawHeader().Machine) == EM_AARCH64 {
+		return 1 << 48
+	}
+	return 0x7ffffffff000
+}
+
+// loadSegments returns the indexes of the PT_LOAD program headers.
+func loadSegments(progs []ELF64ProgramHeader) []int {
+	var loads []int
+	for i, ph := range progs {
+		if ProgType(ph.Type) == PT_LOAD {
+			loads = append(loads, i)
+		}
+	}
+	return loads
+}
+
+// loadedBy returns the PT_LOAD segment mapping the file bytes [off, off+size)
+// or -1.
+func loadedBy(progs []ELF64ProgramHeader, off, size uint64) int {
+	for i, ph := range progs {
+		if ProgType(ph.Type) == PT_LOAD && off >= ph.Off && off+size <= ph.Off+ph.Filesz {
+			return i
+		}
+	}
+	return -1
+}
+
+func (p *Parser) checkLoadType(report anomalyReport) {
+	switch t := Type(p.F.rawHeader().Type); t {
+	case ET_EXEC, ET_DYN:
+	default:
+		report(EI_NIDENT, 2, "", "e_type is %s, execve fails with ENOEXEC", t)
+	}
+}
+
+func (p *Parser) checkLoadPhentsize(report anomalyReport) {
+	h := p.F.rawHeader()
+	if uint64(h.Phentsize) != p.phdrSize() {
+		off, _ := p.headerFieldLocation(0x2a, 0x36)
+		report(off, 2, "", "e_phentsize is %d instead of %d, execve fails with ENOEXEC and ld.so rejects the file",
+			h.Phentsize, p.phdrSize())
+	}
+}
+
+func (p *Parser) checkLoadPhnum(report anomalyReport) {
+	h := p.F.rawHeader()
+	size := uint64(h.Phnum) * p.phdrSize()
+	off, _ := p.headerFieldLocation(0x2c, 0x38)
+	switch {
+	case h.Phnum == 0:
+		report(off, 2, "", "e_phnum is 0, execve fails with ENOEXEC")
+	case size > loadMaxPhdrs:
+		report(off, 2, "", "e_phnum %d makes a program header table of %d bytes, more than %d, execve fails with ENOEXEC",
+			h.Phnum, size, loadMaxPhdrs)
+	case h.Phoff+size > uint64(p.F.size):
+		off, size := p.headerFieldLocation(0x1c, 0x20)
+		report(off, size, "", "the program header table [%#x, %#x) ends past the end of the file %#x, execve fails with EIO",
+			h.Phoff, h.Phoff+uint64(h.Phnum)*p.phdrSize(), p.F.size)
+	}
+}
+
+func (p *Parser) checkLoadInterp(report anomalyReport) {
+	for i, ph := range p.F.ProgramHeaders() {
+		if ProgType(ph.Type) != PT_INTERP {
+			continue
+		}
+		off, size := p.programHeaderLocation(i)
+		if ph.Filesz < 2 || ph.Filesz > loadPathMax {
+			report(off, size, "", "segment %d: PT_INTERP p_filesz is %d, not within [2, %d], execve fails with ENOEXEC", i, ph.Filesz, loadPathMax)
+			return
+		}
+		data := make([]byte, ph.Filesz)
+		if n, _ := p.fs.ReadAt(data, int64(ph.Off)); uint64(n) != ph.Filesz {
+			report(off, size, "", "segment %d: PT_INTERP [%#x, %#x) ends past the end of the file, execve fails with EIO", i, ph.Off, ph.Off+ph.Filesz)
+			return
+		}
+		if data[len(data)-1] != 0 {
+			report(ph.Off+ph.Filesz-1, 1, "", "segment %d: PT_INTERP %q is not NUL terminated, execve fails with ENOEXEC", i, data)
+		} else if j := bytes.IndexByte(data, 0); j < len(data)-1 {
+			// 内核按C字符串使用，多余的部分被截断
+			report(ph.Off+uint64(j), 1, "", "segment %d: PT_INTERP is cut at offset %d by a NUL, the kernel runs %q", i, j, data[:j])
+		}
+		// load_elf_binary只使用第一个PT_INTERP
+		return
+	}
+}
+
+func (p *Parser) checkLoadFilesz(report anomalyReport) {
+	for i, ph := range p.F.ProgramHeaders() {
+		if ProgType(ph.Type) == PT_LOAD && ph.Filesz > ph.Memsz {
+			off, size := p.programHeaderLocation(i)
+			report(off, size, "", "segment %d: p_filesz %#x is larger than p_memsz %#x, execve fails with EINVAL", i, ph.Filesz, ph.Memsz)
+		}
+	}
+}
+
+func (p *Parser) checkLoadPageOffset(report anomalyReport) {
+	for i, ph := range p.F.ProgramHeaders() {
+		if ProgType(ph.Type) == PT_LOAD && (ph.Vaddr-ph.Off)%loadPageSize != 0 {
+			off, size := p.programHeaderLocation(i)
+			report(off, size, "", "segment %d: p_offset %#x and p_vaddr %#x differ modulo the page size %#x, mmap fails with EINVAL and ld.so rejects the file",
+				i, ph.Off, ph.Vaddr, loadPageSize)
+		}
+	}
+}
+
+// 这是gABI的要求，内核不检查，ld.so各版本的做法也不同，只作为警告
+func (p *Parser) checkLoadAlign(report anomalyReport) {
+	for i, ph := range p.F.ProgramHeaders() {
+		if ProgType(ph.Type) != PT_LOAD || ph.Align <= 1 {
+			continue
+		}
+		off, size := p.programHeaderLocation(i)
+		if ph.Align&(ph.Align-1) != 0 {
+			report(off, size, "", "segment %d: p_align %#x is not 0, 1 or a power of two as the gABI wants", i, ph.Align)
+		} else if ph.Off%ph.Align != ph.Vaddr%ph.Align {
+			report(off, size, "", "segment %d: p_offset %#x %% p_align %#x is %#x but p_vaddr %#x %% p_align is %#x, the gABI wants them equal",
+				i, ph.Off, ph.Align, ph.Off%ph.Align, ph.Vaddr, ph.Vaddr%ph.Align)
+		}
+	}
+}
+
+func (p *Parser) checkLoadOrder(report anomalyReport) {
+	progs := p.F.ProgramHeaders()
+	loads := loadSegments(progs)
+	for k := 1; k < len(loads); k++ {
+		prev, i := loads[k-1], loads[k]
+		if progs[i].Vaddr < progs[prev].Vaddr {
+			off, size := p.programHeaderLocation(i)
+			report(off, size, "", "segment %d: p_vaddr %#x is below p_vaddr %#x of the previous PT_LOAD segment %d, it is mapped outside of the reserved span",
+				i, progs[i].Vaddr, progs[prev].Vaddr, prev)
+		}
+	}
+}
+
+func (p *Parser) checkLoadAddressSpace(report anomalyReport) {
+	progs := p.F.ProgramHeaders()
+	loads := loadSegments(progs)
+	if len(loads) == 0 {
+		return
+	}
+	task := p.taskSize()
+	if Type(p.F.rawHeader().Type) == ET_DYN {
+		// 位置无关的文件整体移动，只有总跨度受限
+		first, last := progs[loads[0]], progs[loads[len(loads)-1]]
+		start := first.Vaddr &^ (loadPageSize - 1)
+		if end := last.Vaddr + last.Memsz; end < start || end-start > task {
+			off, size := p.programHeaderLocation(loads[len(loads)-1])
+			report(off, size, "", "the PT_LOAD segments span [%#x, %#x), more than TASK_SIZE %#x, execve fails with EINVAL",
+				start, end, task)
+		}
+		return
+	}
+	for _, i := range loads {
+		ph := progs[i]
+		off, size := p.programHeaderLocation(i)
+		if start := ph.Vaddr &^ (loadPageSize - 1); start < loadMinAddr {
+			report(off, size, "", "segment %d: p_vaddr %#x is mapped at %#x, below vm.mmap_min_addr %#x, execve fails with EPERM",
+				i, ph.Vaddr, start, loadMinAddr)
+		}
+		if end := ph.Vaddr + ph.Memsz; end < ph.Vaddr || end > task {
+			report(off, size, "", "segment %d: [%#x, %#x) ends above TASK_SIZE %#x, execve fails with EINVAL", i, ph.Vaddr, end, task)
+		}
+	}
+}
+
+func (p *Parser) checkLoadPastEOF(report anomalyReport) {
+	for i, ph := range p.F.ProgramHeaders() {
+		if ProgType(ph.Type) == PT_LOAD && ph.Off+ph.Filesz > uint64(p.F.size) {
+			off, size := p.programHeaderLocation(i)
+			report(off, size, "", "segment %d: [%#x, %#x) ends past the end of the file %#x", i, ph.Off, ph.Off+ph.Filesz, p.F.size)
+		}
+	}
+}
+
+func (p *Parser) checkLoadPhdr(report anomalyReport) {
+	progs := p.F.ProgramHeaders()
+	h := p.F.rawHeader()
+	interp := false
+	for i, ph := range progs {
+		switch ProgType(ph.Type) {
+		case PT_INTERP:
+			interp = true
+		case PT_PHDR:
+			off, size := p.programHeaderLocation(i)
+			// PT_PHDR给出程序头在内存中的位置，ld.so用它计算加载偏移
+			covered := false
+			for _, load := range progs {
+				if ProgType(load.Type) == PT_LOAD && ph.Vaddr >= load.Vaddr && ph.Vaddr+ph.Memsz <= load.Vaddr+load.Memsz {
+					covered = true
+					break
+				}
+			}
+			if !covered {
+				report(off, size, "", "segment %d: PT_PHDR [%#x, %#x) is not covered by a PT_LOAD segment, ld.so reads the program headers from unmapped memory",
+					i, ph.Vaddr, ph.Vaddr+ph.Memsz)
+			}
+			for _, load := range progs[:i] {
+				if ProgType(load.Type) == PT_LOAD {
+					report(off, size, "", "segment %d: PT_PHDR follows a PT_LOAD segment, it must precede them", i)
+					break
+				}
+			}
+			return
+		}
+	}
+	// 没有PT_PHDR时，内核用映射e_phoff的PT_LOAD计算AT_PHDR
+	size := uint64(h.Phnum) * uint64(h.Phentsize)
+	if interp && size != 0 && loadedBy(progs, h.Phoff, size) < 0 {
+		off, fsize := p.headerFieldLocation(0x1c, 0x20)
+		report(off, fsize, "", "the program header table [%#x, %#x) is not mapped by a PT_LOAD segment, AT_PHDR points to unmapped memory",
+			h.Phoff, h.Phoff+size)
+	}
+}
+
+func (p *Parser) checkLoadTLS(report anomalyReport) {
+	progs := p.F.ProgramHeaders()
+	first := -1
+	for i, ph := range progs {
+		if ProgType(ph.Type) != PT_TLS {
+			continue
+		}
+		off, size := p.programHeaderLocation(i)
+		if first >= 0 {
+			report(off, size, "", "segment %d: second PT_TLS segment, ld.so only uses one of segments %d and %d", i, first, i)
+			continue
+		}
+		first = i
+		if ph.Align > 1 && ph.Align&(ph.Align-1) != 0 {
+			report(off, size, "", "segment %d: PT_TLS p_align %#x is not 0, 1 or a power of two as the gABI wants", i, ph.Align)
+		}
+		if ph.Filesz > ph.Memsz {
+			report(off, size, "", "segment %d: PT_TLS p_filesz %#x is larger than p_memsz %#x", i, ph.Filesz, ph.Memsz)
+		}
+		if ph.Filesz != 0 && loadedBy(progs, ph.Off, ph.Filesz) < 0 {
+			report(off, size, "", "segment %d: PT_TLS image [%#x, %#x) is not inside a PT_LOAD segment, ld.so copies it from unmapped memory",
+				i, ph.Off, ph.Off+ph.Filesz)
+		}
+	}
+}
+
+func (p *Parser) checkLoadIdent(report anomalyReport) {
+	h := p.F.rawHeader()
+	ident := h.Ident
+	if Version(ident[EI_VERSION]) != EV_CURRENT {
+		report(EI_VERSION, 1, "", "EI_VERSION is %d instead of EV_CURRENT, ld.so rejects the file", ident[EI_VERSION])
+	}
+	switch osabi := OSABI(ident[EI_OSABI]); osabi {
+	case ELFOSABI_NONE, ELFOSABI_LINUX:
+		// ld.so接受的ABI版本：SYSV只能为0，GNU为0到LIBC_ABI_MAX
+		if osabi == ELFOSABI_NONE && ident[EI_ABIVERSION] != 0 {
+			report(EI_ABIVERSION, 1, "", "EI_ABIVERSION is %d for ELFOSABI_NONE, ld.so rejects the file", ident[EI_ABIVERSION])
+		}
+	default:
+		report(EI_OSABI, 1, "", "EI_OSABI is %d, neither ELFOSABI_NONE nor ELFOSABI_LINUX, ld.so rejects the file", ident[EI_OSABI])
+	}
+	for _, b := range ident[EI_PAD:] {
+		if b != 0 {
+			report(EI_PAD, EI_NIDENT-EI_PAD, "", "e_ident padding is % x instead of zeros, ld.so rejects the file", ident[EI_PAD:])
+			break
+		}
+	}
+	if Version(h.Version) != EV_CURRENT {
+		report(0x14, 4, "", "e_version is %d instead of EV_CURRENT, ld.so rejects the file", h.Version)
+	}
+}
+
+func (p *Parser) checkLoadNoLoad(report anomalyReport) {
+	if len(loadSegments(p.F.ProgramHeaders())) == 0 {
+		h := p.F.rawHeader()
+		report(h.Phoff, uint64(h.Phnum)*uint64(h.Phentsize), "", "no PT_LOAD segment, ld.so rejects the file")
+	}
+}
+
+// hasSegment reports whether the file has a program header of type typ.
+func (p *Parser) hasSegment(typ ProgType) bool {
+	for _, ph := range p.F.ProgramHeaders() {
+		if ProgType(ph.Type) == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// isPIE reports whether the ET_DYN file is marked as an executable.
+func (p *Parser) isPIE() bool {
+	flags1, _ := p.F.DynValue(DT_FLAGS_1)
+	return DynFlag1(flags1)&DF_1_PIE != 0
+}
+
+func (p *Parser) checkLoadNoDynamic(report anomalyReport) {
+	// 没有PT_INTERP的ET_DYN是共享库（或static-pie），都要由ld.so处理动态段
+	if Type(p.F.rawHeader().Type) != ET_DYN || p.hasSegment(PT_INTERP) || p.hasSegment(PT_DYNAMIC) {
+		return
+	}
+	h := p.F.rawHeader()
+	report(h.Phoff, uint64(h.Phnum)*uint64(h.Phentsize), "", "ET_DYN file without PT_INTERP nor PT_DYNAMIC, ld.so rejects it as a shared object")
+}
+
+func (p *Parser) checkLoadNeeded(report anomalyReport) {
+	exec := Type(p.F.rawHeader().Type) == ET_EXEC || p.isPIE()
+	if !exec || p.hasSegment(PT_INTERP) || len(p.F.DynamicEntries) == 0 {
+		return
+	}
+	if _, ok := p.F.DynValue(DT_NEEDED); ok {
+		off, size := p.dynamicLocation(DT_NEEDED)
+		report(off, size, "", "the executable needs %s but has no PT_INTERP, the kernel starts it without loading its libraries",
+			strings.Join(p.F.Needed, ", "))
+	}
+}
+
+// LoadabilityView builds the view of the loadability checks.
+func (p *Parser) LoadabilityView() *View {
+	v := &View{Kind: ViewLoadability}
+	findings := p.Loadability()
+	if len(findings) == 0 {
+		v.Note = "The kernel and ld.so accept the file."
+		return v
+	}
+	t := v.addTable("Loadability", "Rule", "Severity", "Offset", "Message")
+	for _, f := range findings {
+		t.addRow(f.RuleID, string(f.Severity), hexString(f.Offset), f.Message)
+	}
+	return v
+}
+
+// WriteLoadability writes the loadability findings to w. FormatJSON writes
+// the findings themselves, the other formats render LoadabilityView.
+func (p *Parser) WriteLoadability(w io.Writer, format Format) error {
+	if format == FormatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(p.Loadability())
+	}
+	r, err := NewRenderer(format)
+	if err != nil {
+		return err
+	}
+	return r.Render(w, []*View{p.LoadabilityView()})
+}
@@ -0,0 +1,129 @@
+package elf
+
+import (
+	"encoding/binary"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadabilityClean(t *testing.T) {
+	for _, name := range []string{"gcc-amd64-linux-exec", "gcc-amd64-linux-hardened"} {
+		p := parseFile(t, path.Join(exampleDir, name))
+		assert.Empty(t, p.Loadability(), name)
+		p.CloseFile()
+	}
+}
+
+func TestLoadabilityFiles(t *testing.T) {
+	p := parseFile(t, path.Join(exampleDir, "go-relocation-test-gcc441-x86-64.obj"))
+	assert.Equal(t, []Finding{
+		{RuleID: "load/type", Severity: SeverityError, Message: "e_type is ET_REL, execve fails with ENOEXEC", Offset: 0x10, Size: 2},
+	}, p.Loadability())
+	p.CloseFile()
+
+	p = parseFile(t, path.Join(exampleDir, "gcc-386-freebsd-exec"))
+	assert.Equal(t, []Finding{
+		{RuleID: "load/ident", Severity: SeverityError, Message: "EI_OSABI is 9, neither ELFOSABI_NONE nor ELFOSABI_LINUX, ld.so rejects the file", Offset: 7, Size: 1},
+	}, p.Loadability())
+	p.CloseFile()
+
+	// objcopy --only-keep-debug留下的程序头不能加载
+	p = parseFile(t, path.Join(exampleDir, "go-relocation-test-gcc930-ranges-no-rela-x86-64"))
+	findings := p.Loadability()
+	assert.Equal(t, "segment 1: PT_INTERP p_filesz is 0, not within [2, 4096], execve fails with ENOEXEC", findings[0].Message)
+	assert.Equal(t, "segment 3: p_offset 0x0 and p_vaddr 0x448 differ modulo the page size 0x1000, mmap fails with EINVAL and ld.so rejects the file", findings[1].Message)
+	assert.Equal(t, []string{"load/interp", "load/page-offset", "load/page-offset", "load/page-offset", "load/page-offset",
+		"load/page-offset", "load/page-offset", "load/page-offset", "load/page-offset"}, ruleIDs(findings))
+	p.CloseFile()
+}
+
+func TestLoadability(t *testing.T) {
+	le := binary.LittleEndian
+	phdr := func(f *Parser, i int) uint64 {
+		off, _ := f.programHeaderLocation(i)
+		return off
+	}
+	for _, tc := range []struct {
+		name   string
+		mutate func(data []byte, f *Parser)
+		want   []Finding
+	}{
+		{"interp without NUL", func(data []byte, f *Parser) { data[0x200+0x1b] = 'x' }, []Finding{
+			{RuleID: "load/interp", Severity: SeverityError, Message: "segment 1: PT_INTERP \"/lib64/ld-linux-x86-64.so.2x\" is not NUL terminated, execve fails with ENOEXEC", Offset: 0x21b, Size: 1, Section: ".interp"},
+		}},
+		{"interp cut", func(data []byte, f *Parser) { data[0x200+0x6] = 0 }, []Finding{
+			{RuleID: "load/interp", Severity: SeverityError, Message: "segment 1: PT_INTERP is cut at offset 6 by a NUL, the kernel runs \"/lib64\"", Offset: 0x206, Size: 1, Section: ".interp"},
+		}},
+		{"phentsize", func(data []byte, f *Parser) { le.PutUint16(data[0x36:], 64) }, []Finding{
+			{RuleID: "load/phentsize", Severity: SeverityError, Message: "e_phentsize is 64 instead of 56, execve fails with ENOEXEC and ld.so rejects the file", Offset: 0x36, Size: 2},
+		}},
+		{"filesz", func(data []byte, f *Parser) { le.PutUint64(data[phdr(f, 3)+32:], 0x300) }, []Finding{
+			{RuleID: "load/filesz", Severity: SeverityError, Message: "segment 3: p_filesz 0x300 is larger than p_memsz 0x218, execve fails with EINVAL", Offset: 0xe8, Size: 56},
+		}},
+		{"page offset", func(data []byte, f *Parser) { le.PutUint64(data[phdr(f, 3)+8:], 0x689) }, []Finding{
+			{RuleID: "load/page-offset", Severity: SeverityError, Message: "segment 3: p_offset 0x689 and p_vaddr 0x600688 differ modulo the page size 0x1000, mmap fails with EINVAL and ld.so rejects the file", Offset: 0xe8, Size: 56},
+			{RuleID: "load/align", Severity: SeverityWarning, Message: "segment 3: p_offset 0x689 % p_align 0x200000 is 0x689 but p_vaddr 0x600688 % p_align is 0x688, the gABI wants them equal", Offset: 0xe8, Size: 56},
+		}},
+		{"align", func(data []byte, f *Parser) { le.PutUint64(data[phdr(f, 3)+48:], 0x300000) }, []Finding{
+			{RuleID: "load/align", Severity: SeverityWarning, Message: "segment 3: p_align 0x300000 is not 0, 1 or a power of two as the gABI wants", Offset: 0xe8, Size: 56},
+		}},
+		{"order", func(data []byte, f *Parser) { le.PutUint64(data[phdr(f, 3)+16:], 0x200688) }, []Finding{
+			{RuleID: "load/order", Severity: SeverityError, Message: "segment 3: p_vaddr 0x200688 is below p_vaddr 0x400000 of the previous PT_LOAD segment 2, it is mapped outside of the reserved span", Offset: 0xe8, Size: 56},
+		}},
+		{"low address", func(data []byte, f *Parser) { le.PutUint64(data[phdr(f, 2)+16:], 0) }, []Finding{
+			{RuleID: "load/address-space", Severity: SeverityError, Message: "segment 2: p_vaddr 0x0 is mapped at 0x0, below vm.mmap_min_addr 0x10000, execve fails with EPERM", Offset: 0xb0, Size: 56},
+			{RuleID: "load/phdr", Severity: SeverityError, Message: "segment 0: PT_PHDR [0x400040, 0x400200) is not covered by a PT_LOAD segment, ld.so reads the program headers from unmapped memory", Offset: 0x40, Size: 56},
+		}},
+		{"high address", func(data []byte, f *Parser) { le.PutUint64(data[phdr(f, 3)+40:], 0x7fffffffffff) }, []Finding{
+			{RuleID: "load/address-space", Severity: SeverityError, Message: "segment 3: [0x600688, 0x800000600687) ends above TASK_SIZE 0x7ffffffff000, execve fails with EINVAL", Offset: 0xe8, Size: 56},
+		}},
+		{"past eof", func(data []byte, f *Parser) {
+			le.PutUint64(data[phdr(f, 3)+32:], 0x2000)
+			le.PutUint64(data[phdr(f, 3)+40:], 0x2000)
+		}, []Finding{
+			{RuleID: "load/segment-past-eof", Severity: SeverityWarning, Message: "segment 3: [0x688, 0x2688) ends past the end of the file 0x228c", Offset: 0xe8, Size: 56},
+		}},
+		{"phdr after load", func(data []byte, f *Parser) {
+			// 交换PT_PHDR和第一个PT_LOAD
+			a, b := phdr(f, 0), phdr(f, 2)
+			tmp := append([]byte(nil), data[a:a+56]...)
+			copy(data[a:a+56], data[b:b+56])
+			copy(data[b:b+56], tmp)
+		}, []Finding{
+			{RuleID: "load/phdr", Severity: SeverityError, Message: "segment 2: PT_PHDR follows a PT_LOAD segment, it must precede them", Offset: 0xb0, Size: 56},
+		}},
+		{"tls", func(data []byte, f *Parser) {
+			for _, i := range []int{6, 7} {
+				le.PutUint32(data[phdr(f, i):], uint32(PT_TLS))
+			}
+			le.PutUint64(data[phdr(f, 6)+48:], 12)
+		}, []Finding{
+			{RuleID: "load/tls", Severity: SeverityError, Message: "segment 6: PT_TLS p_align 0xc is not 0, 1 or a power of two as the gABI wants", Offset: 0x190, Size: 56},
+			{RuleID: "load/tls", Severity: SeverityError, Message: "segment 7: second PT_TLS segment, ld.so only uses one of segments 6 and 7", Offset: 0x1c8, Size: 56},
+		}},
+		{"ident", func(data []byte, f *Parser) { data[EI_ABIVERSION] = 1; le.PutUint32(data[0x14:], 2) }, []Finding{
+			{RuleID: "load/ident", Severity: SeverityError, Message: "EI_ABIVERSION is 1 for ELFOSABI_NONE, ld.so rejects the file", Offset: 8, Size: 1},
+			{RuleID: "load/ident", Severity: SeverityError, Message: "e_version is 2 instead of EV_CURRENT, ld.so rejects the file", Offset: 0x14, Size: 4},
+		}},
+		{"no interp", func(data []byte, f *Parser) { le.PutUint32(data[phdr(f, 1):], uint32(PT_NULL)) }, []Finding{
+			{RuleID: "load/needed-without-interp", Severity: SeverityWarning, Message: "the executable needs libc.so.6 but has no PT_INTERP, the kernel starts it without loading its libraries", Offset: 0x6b0, Size: 16, Section: ".dynamic"},
+		}},
+		{"library without dynamic", func(data []byte, f *Parser) {
+			le.PutUint16(data[EI_NIDENT:], uint16(ET_DYN))
+			le.PutUint32(data[phdr(f, 1):], uint32(PT_NULL))
+			le.PutUint32(data[phdr(f, 4):], uint32(PT_NULL))
+		}, []Finding{
+			{RuleID: "load/no-dynamic", Severity: SeverityWarning, Message: "ET_DYN file without PT_INTERP nor PT_DYNAMIC, ld.so rejects it as a shared object", Offset: 0x40, Size: 0x1c0},
+		}},
+	} {
+		p := mutatedExec(t, tc.mutate)
+		assert.Equal(t, tc.want, p.Loadability(), tc.name)
+	}
+
+	r, ok := LookupFindingRule("load/order")
+	assert.True(t, ok)
+	assert.Equal(t, SeverityError, r.Severity)
+	assert.Len(t, LoadabilityRules(), len(loadRules))
+}
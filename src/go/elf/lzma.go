@@ -0,0 +1,292 @@
+// Package elf : lzma.go decompresses raw LZMA streams, the streams UPX
+// writes for its LZMA method. The decoder follows the LZMA specification
+// of the LZMA SDK, the whole output buffer serves as the dictionary.
+package elf
+
+import "errors"
+
+// errLZMACorrupt is returned for a stream that does not decode to the
+// expected size.
+var errLZMACorrupt = errors.New("corrupt LZMA stream")
+
+const (
+	lzmaNumStates      = 12
+	lzmaPosBitsMax     = 4
+	lzmaProbInit       = 1024
+	lzmaEndPosModel    = 14
+	lzmaNumFullDists   = 128
+	lzmaNumAlignBits   = 4
+	lzmaNumLenToPos    = 4
+	lzmaMatchMinLen    = 2
+	lzmaTopValue       = 1 << 24
+	lzmaNumBitModelBit = 11
+	lzmaNumMoveBits    = 5
+)
+
+type lzmaRangeDecoder struct {
+	src  []byte
+	pos  int
+	rng  uint32
+	code uint32
+	err  error
+}
+
+func (rd *lzmaRangeDecoder) next() uint32 {
+	if rd.pos >= len(rd.src) {
+		rd.err = errLZMACorrupt
+		return 0
+	}
+	b := rd.src[rd.pos]
+	rd.pos++
+	return uint32(b)
+}
+
+func (rd *lzmaRangeDecoder) init() {
+	// 第一个字节总是0
+	if rd.next() != 0 {
+		rd.err = errLZMACorrupt
+	}
+	rd.rng = 0xffffffff
+	for i := 0; i < 4; i++ {
+		rd.code = rd.code<<8 | rd.next()
+	}
+}
+
+func (rd *lzmaRangeDecoder) normalize() {
+	if rd.rng < lzmaTopValue {
+		rd.rng <<= 8
+		rd.code = rd.code<<8 | rd.next()
+	}
+}
+
+func (rd *lzmaRangeDecoder) bit(prob *uint16) uint32 {
+	bound := (rd.rng >> lzmaNumBitModelBit) * uint32(*prob)
+	var b uint32
+	if rd.code < bound {
+		*prob += ((1 << lzmaNumBitModelBit) - *prob) >> lzmaNumMoveBits
+		rd.rng = bound
+	} else {
+		*prob -= *prob >> lzmaNumMoveBits
+		rd.code -= bound
+		rd.rng -= bound
+		b = 1
+	}
+	rd.normalize()
+	return b
+}
+
+func (rd *lzmaRangeDecoder) direct(n uint) uint32 {
+	var res uint32
+	for ; n > 0; n-- {
+		rd.rng >>= 1
+		rd.code -= rd.rng
+		t := 0 - (rd.code >> 31)
+		rd.code += rd.rng & t
+		rd.normalize()
+		res = res<<1 + t + 1
+	}
+	return res
+}
+
+// bitTree decodes n bits most significant first with probs[1:1<<n].
+func (rd *lzmaRangeDecoder) bitTree(probs []uint16, n uint) uint32 {
+	m := uint32(1)
+	for i := uint(0); i < n; i++ {
+		m = m<<1 + rd.bit(&probs[m])
+	}
+	return m - 1<<n
+}
+
+// reverseBitTree decodes n bits least significant first.
+func (rd *lzmaRangeDecoder) reverseBitTree(probs []uint16, n uint) uint32 {
+	m, sym := uint32(1), uint32(0)
+	for i := uint(0); i < n; i++ {
+		b := rd.bit(&probs[m])
+		m = m<<1 + b
+		sym |= b << i
+	}
+	return sym
+}
+
+func newProbs(n int) []uint16 {
+	p := make([]uint16, n)
+	for i := range p {
+		p[i] = lzmaProbInit
+	}
+	return p
+}
+
+type lzmaLenDecoder struct {
+	choice, choice2 uint16
+	low, mid        [1 << lzmaPosBitsMax][]uint16
+	high            []uint16
+}
+
+func newLZMALenDecoder() *lzmaLenDecoder {
+	d := &lzmaLenDecoder{choice: lzmaProbInit, choice2: lzmaProbInit, high: newProbs(1 << 8)}
+	for i := range d.low {
+		d.low[i] = newProbs(1 << 3)
+		d.mid[i] = newProbs(1 << 3)
+	}
+	return d
+}
+
+func (d *lzmaLenDecoder) decode(rd *lzmaRangeDecoder, posState uint32) uint32 {
+	if rd.bit(&d.choice) == 0 {
+		return rd.bitTree(d.low[posState], 3)
+	}
+	if rd.bit(&d.choice2) == 0 {
+		return 8 + rd.bitTree(d.mid[posState], 3)
+	}
+	return 16 + rd.bitTree(d.high, 8)
+}
+
+// lzmaDecompress decodes a raw LZMA stream of the lc, lp and pb properties
+// into size bytes. The end marker is accepted but not required.
+func lzmaDecompress(src []byte, lc, lp, pb uint, size int) ([]byte, error) {
+	if lc > 8 || lp > 4 || pb > 4 {
+		return nil, errLZMACorrupt
+	}
+	rd := &lzmaRangeDecoder{src: src}
+	rd.init()
+	literals := newProbs(0x300 << (lc + lp))
+	posSlots := make([][]uint16, lzmaNumLenToPos)
+	for i := range posSlots {
+		posSlots[i] = newProbs(1 << 6)
+	}
+	posDecoders := newProbs(1 + lzmaNumFullDists - lzmaEndPosModel)
+	align := newProbs(1 << lzmaNumAlignBits)
+	isMatch := newProbs(lzmaNumStates << lzmaPosBitsMax)
+	isRep := newProbs(lzmaNumStates)
+	isRepG0 := newProbs(lzmaNumStates)
+	isRepG1 := newProbs(lzmaNumStates)
+	isRepG2 := newProbs(lzmaNumStates)
+	isRep0Long := newProbs(lzmaNumStates << lzmaPosBitsMax)
+	lenDecoder, repLenDecoder := newLZMALenDecoder(), newLZMALenDecoder()
+
+	out := make([]byte, 0, size)
+	var state, rep0, rep1, rep2, rep3 uint32
+	pbMask := uint32(1)<<pb - 1
+	lpMask := uint32(1)<<lp - 1
+	for len(out) < size && rd.err == nil {
+		posState := uint32(len(out)) & pbMask
+		if rd.bit(&isMatch[state<<lzmaPosBitsMax+posState]) == 0 {
+			// 字面量，状态>=7时上一个操作是匹配，按匹配字节解码
+			prev := uint32(0)
+			if len(out) > 0 {
+				prev = uint32(out[len(out)-1])
+			}
+			litState := (uint32(len(out))&lpMask)<<lc + prev>>(8-lc)
+			probs := literals[0x300*litState:]
+			sym := uint32(1)
+			if state >= 7 {
+				if int(rep0) >= len(out) {
+					return nil, errLZMACorrupt
+				}
+				match := uint32(out[len(out)-int(rep0)-1])
+				for sym < 0x100 {
+					matchBit := (match >> 7) & 1
+					match <<= 1
+					b := rd.bit(&probs[(1+matchBit)<<8+sym])
+					sym = sym<<1 | b
+					if matchBit != b {
+						break
+					}
+				}
+			}
+			for sym < 0x100 {
+				sym = sym<<1 | rd.bit(&probs[sym])
+			}
+			out = append(out, byte(sym))
+			switch {
+			case state < 4:
+				state = 0
+			case state < 10:
+				state -= 3
+			default:
+				state -= 6
+			}
+			continue
+		}
+		var n uint32
+		if rd.bit(&isRep[state]) != 0 {
+			if len(out) == 0 {
+				return nil, errLZMACorrupt
+			}
+			if rd.bit(&isRepG0[state]) == 0 {
+				if rd.bit(&isRep0Long[state<<lzmaPosBitsMax+posState]) == 0 {
+					// 短重复：只复制一个字节
+					if state < 7 {
+						state = 9
+					} else {
+						state = 11
+					}
+					out = append(out, out[len(out)-int(rep0)-1])
+					continue
+				}
+			} else {
+				var dist uint32
+				if rd.bit(&isRepG1[state]) == 0 {
+					dist = rep1
+				} else {
+					if rd.bit(&isRepG2[state]) == 0 {
+						dist = rep2
+					} else {
+						dist = rep3
+						rep3 = rep2
+					}
+					rep2 = rep1
+				}
+				rep1 = rep0
+				rep0 = dist
+			}
+			n = repLenDecoder.decode(rd, posState)
+			if state < 7 {
+				state = 8
+			} else {
+				state = 11
+			}
+		} else {
+			rep3, rep2, rep1 = rep2, rep1, rep0
+			n = lenDecoder.decode(rd, posState)
+			if state < 7 {
+				state = 7
+			} else {
+				state = 10
+			}
+			lenState := n
+			if lenState > lzmaNumLenToPos-1 {
+				lenState = lzmaNumLenToPos - 1
+			}
+			slot := rd.bitTree(posSlots[lenState], 6)
+			if slot < 4 {
+				rep0 = slot
+			} else {
+				direct := uint(slot>>1) - 1
+				rep0 = (2 | slot&1) << direct
+				if slot < lzmaEndPosModel {
+					rep0 += rd.reverseBitTree(posDecoders[rep0-slot:], direct)
+				} else {
+					rep0 += rd.direct(direct-lzmaNumAlignBits) << lzmaNumAlignBits
+					rep0 += rd.reverseBitTree(align, lzmaNumAlignBits)
+				}
+			}
+			if rep0 == 0xffffffff {
+				// 结束标记
+				break
+			}
+		}
+		n += lzmaMatchMinLen
+		if int(rep0) >= len(out) || len(out)+int(n) > size {
+			return nil, errLZMACorrupt
+		}
+		from := len(out) - int(rep0) - 1
+		for i := 0; i < int(n); i++ {
+			out = append(out, out[from+i])
+		}
+	}
+	if rd.err != nil || len(out) != size {
+		return nil, errLZMACorrupt
+	}
+	return out, nil
+}
@@ -0,0 +1,309 @@
+// Package elf : ndjson.go streams the file as newline delimited JSON, one
+// record per line. Symbol and relocation tables are read entry by entry
+// straight from the file so the memory used does not grow with their size.
+package elf
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+)
+
+// NDJSON record kinds, the "record" field of every line.
+const (
+	RecordHeader     = "header"
+	RecordSection    = "section"
+	RecordSegment    = "segment"
+	RecordSymbol     = "symbol"
+	RecordRelocation = "relocation"
+	RecordNote       = "note"
+)
+
+// ndjsonHeader is the first record of the stream.
+// 相似性哈希要把整个文件读入内存，流式输出中只给出摘要
+type ndjsonHeader struct {
+	Record        string      `json:"record"`
+	SchemaVersion string      `json:"schema_version"`
+	Ident         JSONIdent   `json:"ident"`
+	Header        JSONHeader  `json:"header"`
+	Hashes        JSONDigests `json:"hashes"`
+}
+
+type ndjsonSection struct {
+	Record string `json:"record"`
+	JSONSection
+}
+
+type ndjsonSegment struct {
+	Record string `json:"record"`
+	JSONSegment
+}
+
+type ndjsonSymbol struct {
+	Record string `json:"record"`
+	JSONSymbol
+}
+
+type ndjsonRelocation struct {
+	Record string `json:"record"`
+	JSONRelocation
+}
+
+type ndjsonNote struct {
+	Record string `json:"record"`
+	JSONNote
+}
+
+// ndjsonChunk is the number of table entries read from the file at once.
+const ndjsonChunk = 512
+
+// WriteNDJSON writes a header record followed by one record per section,
+// segment, symbol, relocation and note. The records use the field names of
+// the DumpJSON document plus a "record" field naming their kind. The hashes
+// of the header record are only the digests, the similarity hashes need the
+// whole file in memory and are left to DumpJSON.
+func (p *Parser) WriteNDJSON(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	// json.Encoder在每个对象后输出换行，正好是NDJSON的格式
+	enc := json.NewEncoder(bw)
+	doc, err := p.jsonHeader()
+	if err != nil {
+		return err
+	}
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	sections := p.F.Sections()
+	for i, s := range sections {
+		if err := enc.Encode(ndjsonSection{RecordSection, jsonSection(i, s)}); err != nil {
+			return err
+		}
+	}
+	for i, ph := range p.F.ProgramHeaders() {
+		if err := enc.Encode(ndjsonSegment{RecordSegment, p.jsonSegment(i, ph)}); err != nil {
+			return err
+		}
+	}
+	if err := p.streamSymbols(enc); err != nil {
+		return err
+	}
+	if err := p.streamRelocations(enc); err != nil {
+		return err
+	}
+	notes, err := p.Notes()
+	if err != nil {
+		return err
+	}
+	for _, n := range notes {
+		if err := enc.Encode(ndjsonNote{RecordNote, jsonNote(n)}); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func (p *Parser) jsonHeader() (*ndjsonHeader, error) {
+	if !IsValidELFClass(p.F.Class()) {
+		return nil, ErrBadELFClass
+	}
+	hashes, err := p.fileDigests()
+	if err != nil {
+		return nil, err
+	}
+	h := p.F.rawHeader()
+	return &ndjsonHeader{
+		Record:        RecordHeader,
+		SchemaVersion: JSONSchemaVersion,
+		Ident:         jsonIdent(h),
+		Header:        jsonHeaderFields(h),
+		Hashes:        hashes,
+	}, nil
+}
+
+// symbolEntrySize returns the size of an Elf_Sym of the file class.
+func (p *Parser) symbolEntrySize() int {
+	if p.F.Class() == ELFCLASS32 {
+		return Sym32Size
+	}
+	return Sym64Size
+}
+
+// decodeSymbol decodes one Elf_Sym entry, the name is left to the caller
+// and its string table offset is returned instead.
+func (p *Parser) decodeSymbol(data []byte) (Symbol, uint32) {
+	r := bytes.NewReader(data)
+	if p.F.Class() == ELFCLASS32 {
+		var e ELF32SymbolTableEntry
+		binary.Read(r, p.F.ByteOrder(), &e)
+		return Symbol{Info: e.Info, Other: e.Other, Index: SectionIndex(e.Shndx), Value: uint64(e.Value), Size: uint64(e.Size)}, e.Name
+	}
+	var e ELF64SymbolTableEntry
+	binary.Read(r, p.F.ByteOrder(), &e)
+	return Symbol{Info: e.Info, Other: e.Other, Index: SectionIndex(e.Shndx), Value: e.Value, Size: e.Size}, e.Name
+}
+
+// readString reads the NUL terminated string at offset off of the string
+// table section strtab without loading the table.
+func (p *Parser) readString(strtab *ELF64Section, off uint32) string {
+	if strtab == nil || uint64(off) >= strtab.Size {
+		return ""
+	}
+	var b []byte
+	buf := make([]byte, 64)
+	pos := strtab.Off + uint64(off)
+	end := strtab.Off + strtab.Size
+	for pos < end {
+		n := uint64(len(buf))
+		if end-pos < n {
+			n = end - pos
+		}
+		got, err := p.F.r.ReadAt(buf[:n], int64(pos))
+		if i := bytes.IndexByte(buf[:got], 0); i >= 0 {
+			return string(append(b, buf[:i]...))
+		}
+		b = append(b, buf[:got]...)
+		if err != nil || got == 0 {
+			break
+		}
+		pos += uint64(got)
+	}
+	return string(b)
+}
+
+// linkedSection returns the section sh_link points to.
+func (p *Parser) linkedSection(s *ELF64Section) *ELF64Section {
+	sections := p.F.Sections()
+	if s.Link == 0 || int(s.Link) >= len(sections) {
+		return nil
+	}
+	return sections[s.Link]
+}
+
+// eachTableChunk calls fn with up to ndjsonChunk entries of size entSize
+// at a time, read from the section s.
+func (p *Parser) eachTableChunk(s *ELF64Section, entSize int, fn func(data []byte) error) error {
+	if SectionType(s.Type) == SHT_NOBITS || entSize == 0 {
+		return nil
+	}
+	r := io.NewSectionReader(p.F.r, int64(s.Off), int64(s.Size))
+	buf := make([]byte, ndjsonChunk*entSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		n -= n % entSize
+		if n > 0 {
+			if err := fn(buf[:n]); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (p *Parser) streamSymbols(enc *json.Encoder) error {
+	found := false
+	entSize := p.symbolEntrySize()
+	for _, s := range p.F.Sections() {
+		typ := SectionType(s.Type)
+		if typ != SHT_DYNSYM && typ != SHT_SYMTAB {
+			continue
+		}
+		found = true
+		strtab := p.linkedSection(s)
+		index := 0
+		err := p.eachTableChunk(s, entSize, func(data []byte) error {
+			for i := 0; i < len(data); i += entSize {
+				sym, name := p.decodeSymbol(data[i : i+entSize])
+				sym.Name = p.readString(strtab, name)
+				if typ == SHT_DYNSYM {
+					sym.Library, sym.Version = p.gnuVersion(index - 1)
+				}
+				if err := enc.Encode(ndjsonSymbol{RecordSymbol, p.jsonSymbol(s.SectionName, index, sym)}); err != nil {
+					return err
+				}
+				index++
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	// 节头被剥离时只有从PT_DYNAMIC重建的动态符号
+	if !found && p.F.FromSegments {
+		for i, sym := range p.F.NamedSymbols {
+			if err := enc.Encode(ndjsonSymbol{RecordSymbol, p.jsonSymbol(".dynsym", i, sym)}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// relocationEntrySize returns the size of an Elf_Rel or Elf_Rela entry.
+func (p *Parser) relocationEntrySize(rela bool) int {
+	switch {
+	case p.F.Class() == ELFCLASS32 && rela:
+		return 12
+	case p.F.Class() == ELFCLASS32:
+		return 8
+	case rela:
+		return 24
+	}
+	return 16
+}
+
+func (p *Parser) streamRelocations(enc *json.Encoder) error {
+	found := false
+	symSize := p.symbolEntrySize()
+	for _, s := range p.F.Sections() {
+		typ := SectionType(s.Type)
+		if typ != SHT_REL && typ != SHT_RELA {
+			continue
+		}
+		found = true
+		symtab := p.linkedSection(s)
+		var strtab *ELF64Section
+		if symtab != nil {
+			strtab = p.linkedSection(symtab)
+		}
+		rela := typ == SHT_RELA
+		err := p.eachTableChunk(s, p.relocationEntrySize(rela), func(data []byte) error {
+			for _, r := range p.decodeRelocations(data, rela, DT_NULL) {
+				name := ""
+				if symtab != nil && uint64(r.Sym+1)*uint64(symSize) <= symtab.Size {
+					entry := make([]byte, symSize)
+					if _, err := p.F.r.ReadAt(entry, int64(symtab.Off)+int64(r.Sym)*int64(symSize)); err == nil {
+						_, off := p.decodeSymbol(entry)
+						name = p.readString(strtab, off)
+					}
+				}
+				if err := enc.Encode(ndjsonRelocation{RecordRelocation, p.jsonRelocation(s.SectionName, r, name)}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if !found && p.F.FromSegments {
+		for _, r := range p.F.DynRelocations {
+			name := ""
+			if int(r.Sym) < len(p.F.NamedSymbols) {
+				name = p.F.NamedSymbols[r.Sym].Name
+			}
+			if err := enc.Encode(ndjsonRelocation{RecordRelocation, p.jsonRelocation("", r, name)}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package elf
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWriteNDJSON checks that the stream carries the same records as the
+// DumpJSON document.
+func TestWriteNDJSON(t *testing.T) {
+	for _, name := range []string{"gcc-amd64-linux-exec", "gcc-386-freebsd-exec", "go-relocation-test-gcc492-mips64.obj"} {
+		t.Run(name, func(t *testing.T) {
+			p, err := New(path.Join(exampleDir, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer p.CloseFile()
+			if err := p.Parse(); err != nil {
+				t.Fatal(err)
+			}
+			doc, err := p.JSONDocument()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := p.WriteNDJSON(&out); err != nil {
+				t.Fatal(err)
+			}
+
+			var header ndjsonHeader
+			// JSONDocument中的列表总是非nil的
+			sections := []JSONSection{}
+			segments := []JSONSegment{}
+			relocs := []JSONRelocation{}
+			notes := []JSONNote{}
+			symbols := map[string][]JSONSymbol{}
+			scanner := bufio.NewScanner(&out)
+			for line := 0; scanner.Scan(); line++ {
+				var kind struct {
+					Record string `json:"record"`
+				}
+				data := scanner.Bytes()
+				if !assert.NoError(t, json.Unmarshal(data, &kind)) {
+					return
+				}
+				if line == 0 {
+					assert.Equal(t, RecordHeader, kind.Record)
+				}
+				switch kind.Record {
+				case RecordHeader:
+					assert.NoError(t, json.Unmarshal(data, &header))
+					// 头记录只有摘要，没有要读入整个文件的相似性哈希
+					assert.NotContains(t, string(data), `"tlsh"`)
+				case RecordSection:
+					var r JSONSection
+					assert.NoError(t, json.Unmarshal(data, &r))
+					sections = append(sections, r)
+				case RecordSegment:
+					var r JSONSegment
+					assert.NoError(t, json.Unmarshal(data, &r))
+					segments = append(segments, r)
+				case RecordSymbol:
+					var r JSONSymbol
+					assert.NoError(t, json.Unmarshal(data, &r))
+					symbols[r.Table] = append(symbols[r.Table], r)
+				case RecordRelocation:
+					var r JSONRelocation
+					assert.NoError(t, json.Unmarshal(data, &r))
+					relocs = append(relocs, r)
+				case RecordNote:
+					var r JSONNote
+					assert.NoError(t, json.Unmarshal(data, &r))
+					notes = append(notes, r)
+				default:
+					t.Fatalf("unexpected record %q", kind.Record)
+				}
+			}
+			assert.NoError(t, scanner.Err())
+
+			assert.Equal(t, doc.Ident, header.Ident)
+			assert.Equal(t, doc.Header, header.Header)
+			assert.Equal(t, doc.Hashes.JSONDigests, header.Hashes)
+			assert.Equal(t, doc.Sections, sections)
+			assert.Equal(t, doc.Segments, segments)
+			assert.Equal(t, doc.Relocations, relocs)
+			assert.Equal(t, len(doc.Notes), len(notes))
+			want := map[string][]JSONSymbol{}
+			for _, s := range doc.Symbols {
+				want[s.Table] = append(want[s.Table], s)
+			}
+			assert.Equal(t, want, symbols)
+		})
+	}
+}
@@ -0,0 +1,455 @@
+// Package elf : nm.go lists the symbols of a file the way GNU nm does: one
+// letter per symbol giving its kind, sorted by name, address or size, in
+// the BSD, POSIX or System V layout. The letters follow bfd_decode_symclass
+// of binutils so the output can be compared with nm byte for byte.
+package elf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// NMSort is the order of the symbols in the listing.
+type NMSort int
+
+const (
+	NMSortName    NMSort = iota // By name, the default.
+	NMSortAddress               // By value, undefined symbols first (-n).
+	NMSortSize                  // By size, only the defined symbols with a size (--size-sort).
+	NMSortNone                  // In symbol table order (-p).
+)
+
+// NMFormat is the layout of the listing.
+type NMFormat string
+
+const (
+	NMFormatBSD   NMFormat = "bsd"
+	NMFormatPOSIX NMFormat = "posix"
+	NMFormatSysV  NMFormat = "sysv"
+)
+
+// ErrNMFormat is returned for a listing format other than bsd, posix and sysv.
+var ErrNMFormat = errors.New("unsupported nm format")
+
+// NMOptions selects and orders the symbols of an nm listing.
+type NMOptions struct {
+	Dynamic  bool // List .dynsym instead of .symtab (-D).
+	Demangle bool // Demangle the C++ names (-C).
+	Sort     NMSort
+	Reverse  bool // Reverse the order of the sort (-r).
+	// All lists the section and file symbols too (-a).
+	All           bool
+	DefinedOnly   bool
+	UndefinedOnly bool
+	ExternalOnly  bool // Only the global, weak and unique symbols (-g).
+	PrintSize     bool // Print the size of the BSD and POSIX lines (-S).
+	Format        NMFormat
+}
+
+// NMSymbol is a line of the listing.
+type NMSymbol struct {
+	// Name is the symbol name, with the @VERSION or @@VERSION of a
+	// dynamic symbol and demangled when asked for.
+	Name  string
+	Value uint64
+	Size  uint64
+	// Code is the nm letter: U undefined, T/t text, D/d data, B/b bss,
+	// R/r read only data, W/w weak, V/v weak object, C common, A/a
+	// absolute, i indirect function, u unique global, N debugging, n
+	// other read only, ? unknown. Upper case marks a global symbol.
+	Code byte
+	Type SymType
+	// Section is the name of the section of the symbol, *UND*, *ABS* or
+	// *COM*, empty for a section symbol.
+	Section string
+
+	raw string // Name as in the string table, the sort key.
+}
+
+// undefined reports whether the symbol has no value to print.
+func (s *NMSymbol) undefined() bool {
+	return s.Code == 'U' || s.Code == 'w' || s.Code == 'v'
+}
+
+// nmSectionCode returns the letter of a symbol defined in section s.
+func nmSectionCode(s *ELF64Section) byte {
+	// 只有这几个MSVC的节名有固定字母，其余都由节标志决定
+	for _, t := range []struct {
+		name string
+		code byte
+	}{{".drectve", 'i'}, {".edata", 'e'}, {".idata", 'i'}, {".pdata", 'p'}} {
+		if strings.HasPrefix(s.SectionName, t.name) {
+			rest := s.SectionName[len(t.name):]
+			if rest == "" || strings.IndexByte(".$0123456789", rest[0]) >= 0 {
+				return t.code
+			}
+		}
+	}
+	flags := SectionFlag(s.Flags)
+	nobits := SectionType(s.Type) == SHT_NOBITS
+	readonly := flags&SHF_WRITE == 0
+	switch {
+	case flags&SHF_EXECINSTR != 0:
+		return 't'
+	case flags&SHF_ALLOC != 0 && !nobits:
+		if readonly {
+			return 'r'
+		}
+		return 'd'
+	case nobits:
+		return 'b'
+	case isDebugSection(s.SectionName):
+		return 'N'
+	case readonly:
+		return 'n'
+	}
+	return '?'
+}
+
+// isDebugSection reports whether binutils treats a non allocated section
+// as debugging information.
+func isDebugSection(name string) bool {
+	for _, prefix := range []string{".debug", ".gnu.debuglto_.debug_", ".gnu.linkonce.wi.", ".zdebug", ".line", ".stab"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return name == ".gdb_index"
+}
+
+// nmSections returns the sections indexed by number, with nil for those
+// binutils does not represent: the symbol table, its string table, the
+// section name table and the relocations applied through the symbol table
+// of an object. Symbols defined in them are shown as absolute.
+func (p *Parser) nmSections() []*ELF64Section {
+	sections := append([]*ELF64Section(nil), p.F.Sections()...)
+	object := Type(p.F.rawHeader().Type) == ET_REL
+	symtab, strtab := uint32(0), uint32(0)
+	for i, s := range sections {
+		if SectionType(s.Type) == SHT_SYMTAB {
+			symtab, strtab = uint32(i), s.Link
+		}
+	}
+	for i, s := range sections {
+		switch SectionType(s.Type) {
+		case SHT_SYMTAB, SHT_SYMTAB_SHNDX:
+			sections[i] = nil
+		case SHT_STRTAB:
+			if i == int(p.F.rawHeader().Shstrndx) || symtab != 0 && uint32(i) == strtab {
+				sections[i] = nil
+			}
+		case SHT_REL, SHT_RELA:
+			if symtab != 0 && s.Link == symtab && s.Info != 0 && (object || SectionFlag(s.Flags)&SHF_ALLOC == 0) {
+				sections[i] = nil
+			}
+		}
+	}
+	return sections
+}
+
+// nmVersions holds what nm needs to name the versions of the dynamic
+// symbols.
+type nmVersions struct {
+	syms    []Symbol
+	versym  []uint16
+	defs    map[uint16]string
+	needs   map[uint16]string
+	baseDef bool // Index 1 is the file's own base version, not printed.
+}
+
+func (p *Parser) nmVersions(syms []Symbol) nmVersions {
+	v := nmVersions{syms: syms, defs: map[uint16]string{}, needs: map[uint16]string{}, baseDef: true}
+	var err error
+	if v.versym, err = p.VersionSymbols(); err != nil {
+		return v
+	}
+	defs, _ := p.VersionDefs()
+	for _, d := range defs {
+		if len(d.Names) != 0 {
+			v.defs[d.Index] = d.Names[0]
+		}
+		if d.Index == 1 {
+			v.baseDef = d.Flags&1 != 0 // VER_FLG_BASE
+		}
+	}
+	needs, _ := p.VersionNeeds()
+	for _, n := range needs {
+		for _, a := range n.Aux {
+			v.needs[a.Other] = a.Name
+		}
+	}
+	return v
+}
+
+// suffix returns the @VERSION or @@VERSION nm appends to dynamic symbol i,
+// @@ marks the default version of a definition.
+func (v *nmVersions) suffix(i int, s *NMSymbol) string {
+	if v.versym == nil {
+		// 没有版本表时只剩导入符号的版本可用
+		if v.syms[i].Version != "" {
+			return "@" + v.syms[i].Version
+		}
+		return ""
+	}
+	if i >= len(v.versym) {
+		return ""
+	}
+	index, hidden := v.versym[i]&0x7fff, v.versym[i]&0x8000 != 0
+	def, isDef := v.defs[index]
+	switch {
+	case index == 0 || index == 1 && v.baseDef:
+		return ""
+	case isDef:
+		// 版本定义本身对应的符号与版本同名，不再重复
+		if def == v.syms[i].Name {
+			return ""
+		}
+		if hidden || s.Section == "*UND*" {
+			return "@" + def
+		}
+		return "@@" + def
+	case v.needs[index] != "":
+		// 引用其他库的版本总用@，复制重定位过来的定义也一样
+		return "@" + v.needs[index]
+	}
+	return ""
+}
+
+// nmSymbol decodes the letter and the section of a symbol, sections comes
+// from nmSections and all holds every section.
+func nmSymbol(sym Symbol, sections, all []*ELF64Section) NMSymbol {
+	s := NMSymbol{Name: sym.Name, Value: sym.Value, Size: sym.Size, Type: ST_TYPE(sym.Info), raw: sym.Name}
+	bind := ST_BIND(sym.Info)
+	var section *ELF64Section
+	switch {
+	case sym.Index == SHN_UNDEF:
+		s.Section = "*UND*"
+	case sym.Index == SHN_COMMON:
+		s.Section = "*COM*"
+		// bfd把公共符号的大小当作它的值
+		s.Value = sym.Size
+	case int(sym.Index) < len(sections) && sections[sym.Index] != nil:
+		section = sections[sym.Index]
+		s.Section = section.SectionName
+	default:
+		s.Section = "*ABS*"
+	}
+	abs := s.Section == "*ABS*"
+	if s.Type == STT_SECTION {
+		s.Section = ""
+		if int(sym.Index) < len(all) {
+			s.Name, s.raw = all[sym.Index].SectionName, all[sym.Index].SectionName
+		}
+	}
+	switch {
+	case sym.Index == SHN_COMMON:
+		s.Code = 'C'
+	case sym.Index == SHN_UNDEF:
+		s.Code = 'U'
+		if bind == STB_WEAK {
+			s.Code = 'w'
+			if s.Type == STT_OBJECT {
+				s.Code = 'v'
+			}
+		}
+	case s.Type == STT_LOOS: // STT_GNU_IFUNC
+		s.Code = 'i'
+	case bind == STB_WEAK:
+		s.Code = 'W'
+		if s.Type == STT_OBJECT {
+			s.Code = 'V'
+		}
+	case bind == STB_LOOS: // STB_GNU_UNIQUE
+		s.Code = 'u'
+	case bind != STB_LOCAL && bind != STB_GLOBAL:
+		s.Code = '?'
+	default:
+		s.Code = '?'
+		if section != nil {
+			s.Code = nmSectionCode(section)
+		} else if abs {
+			s.Code = 'a'
+		}
+		if bind == STB_GLOBAL && s.Code != '?' {
+			s.Code -= 'a' - 'A'
+		}
+	}
+	return s
+}
+
+// NMSymbols returns the symbols of the listing selected by o, in order.
+func (p *Parser) NMSymbols(o NMOptions) ([]NMSymbol, error) {
+	typ := SHT_SYMTAB
+	if o.Dynamic {
+		typ = SHT_DYNSYM
+	}
+	syms, err := p.Symbols(typ)
+	if err != nil {
+		return nil, err
+	}
+	// 0号符号总是空的，nm从不列出；表中没有别的符号时才算没有符号，
+	// 过滤之后为空只是什么也不输出
+	if len(syms) <= 1 {
+		return nil, ErrNoSymbols
+	}
+	sections, all := p.nmSections(), p.F.Sections()
+	var versions nmVersions
+	if o.Dynamic {
+		versions = p.nmVersions(syms)
+	}
+	var list []NMSymbol
+	for i, sym := range syms {
+		if i == 0 {
+			continue
+		}
+		s := nmSymbol(sym, sections, all)
+		if !o.All && (s.Type == STT_SECTION || s.Type == STT_FILE) {
+			continue
+		}
+		switch {
+		case o.DefinedOnly && s.undefined(),
+			o.UndefinedOnly && !s.undefined(),
+			o.ExternalOnly && ST_BIND(sym.Info) == STB_LOCAL,
+			o.Sort == NMSortSize && (s.undefined() || s.Size == 0):
+			continue
+		}
+		if o.Dynamic {
+			s.Name += versions.suffix(i, &s)
+		}
+		if o.Demangle {
+			s.Name = Demangle(s.Name)
+		}
+		list = append(list, s)
+	}
+	less := func(a, b *NMSymbol) bool { return a.raw < b.raw }
+	switch o.Sort {
+	case NMSortNone:
+		return list, nil
+	case NMSortAddress:
+		less = func(a, b *NMSymbol) bool {
+			if a.undefined() != b.undefined() {
+				return a.undefined()
+			}
+			if !a.undefined() && a.Value != b.Value {
+				return a.Value < b.Value
+			}
+			return a.raw < b.raw
+		}
+	case NMSortSize:
+		// nm先按地址排好再按大小排，大小和名字都相同时保持地址顺序
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Value < list[j].Value })
+		less = func(a, b *NMSymbol) bool {
+			if a.Size != b.Size {
+				return a.Size < b.Size
+			}
+			return a.raw < b.raw
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if o.Reverse {
+			return less(&list[j], &list[i])
+		}
+		return less(&list[i], &list[j])
+	})
+	return list, nil
+}
+
+var nmTypeNames = map[SymType]string{
+	STT_NOTYPE: "NOTYPE", STT_OBJECT: "OBJECT", STT_FUNC: "FUNC", STT_SECTION: "SECTION",
+	STT_FILE: "FILE", STT_COMMON: "COMMON", STT_TLS: "TLS",
+}
+
+// nmTypeName is the type column of the System V layout, nm has no name
+// for STT_GNU_IFUNC.
+func nmTypeName(t SymType) string {
+	if name, ok := nmTypeNames[t]; ok {
+		return name
+	}
+	switch {
+	case t >= STT_LOPROC && t <= STT_HIPROC:
+		return fmt.Sprintf("<processor specific>: %d", t)
+	case t >= STT_LOOS && t <= STT_HIOS:
+		return fmt.Sprintf("<OS specific>: %d", t)
+	}
+	return fmt.Sprintf("<unknown>: %d", t)
+}
+
+// WriteNM writes the nm listing of the file, name is the file name shown
+// by the System V layout. A file without symbols gives ErrNoSymbols.
+func (p *Parser) WriteNM(w io.Writer, name string, o NMOptions) error {
+	if o.Format == "" {
+		o.Format = NMFormatBSD
+	}
+	if o.Format != NMFormatBSD && o.Format != NMFormatPOSIX && o.Format != NMFormatSysV {
+		return ErrNMFormat
+	}
+	width := 16
+	if p.F.Class() == ELFCLASS32 {
+		width = 8
+	}
+	ew := &errWriter{w: w}
+	// 与nm一样，即使没有符号也先写出表头
+	if o.Format == NMFormatSysV {
+		ew.printf("\n\nSymbols from %s:\n\n", name)
+		ew.printf("Name                  Value%s   Class        Type         Size%s     Line  Section\n\n",
+			strings.Repeat(" ", width-8), strings.Repeat(" ", width-8))
+	}
+	list, err := p.NMSymbols(o)
+	if err != nil {
+		return err
+	}
+	for i := range list {
+		s := &list[i]
+		switch o.Format {
+		case NMFormatBSD:
+			switch {
+			case s.undefined():
+				ew.printf("%*s ", width, "")
+			case o.Sort == NMSortSize && !o.PrintSize:
+				// 按大小排序又不要求-S时，nm在值的位置输出大小
+				ew.printf("%0*x ", width, s.Size)
+			default:
+				ew.printf("%0*x ", width, s.Value)
+			}
+			if o.PrintSize && s.Size != 0 && !s.undefined() {
+				ew.printf("%0*x ", width, s.Size)
+			}
+			ew.printf("%c %s\n", s.Code, s.Name)
+		case NMFormatPOSIX:
+			ew.printf("%s %c ", s.Name, s.Code)
+			if s.undefined() {
+				ew.printf("        \n")
+				continue
+			}
+			ew.printf("%x ", s.Value)
+			if s.Size != 0 {
+				ew.printf("%x", s.Size)
+			}
+			ew.printf("\n")
+		case NMFormatSysV:
+			ew.printf("%-20s|", s.Name)
+			if s.undefined() {
+				ew.printf("%*s", width, "")
+			} else {
+				ew.printf("%0*x", width, s.Value)
+			}
+			ew.printf("|   %c  |", s.Code)
+			// 节符号没有ELF的类型和节两栏
+			if s.Type == STT_SECTION {
+				ew.printf("%18s|%*s|     |\n", "", width, "")
+				continue
+			}
+			ew.printf("%18s|", nmTypeName(s.Type))
+			if s.Size != 0 {
+				ew.printf("%0*x", width, s.Size)
+			} else {
+				ew.printf("%*s", width, "")
+			}
+			ew.printf("|     |%s\n", s.Section)
+		}
+	}
+	return ew.err
+}
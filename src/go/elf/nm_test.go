@@ -0,0 +1,80 @@
+package elf
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The golden files are the output of GNU nm 2.40 for the sample binaries,
+// the part before .golden names the options:
+//
+//	bsd      nm -a -S <file>
+//	posix    nm -P -n <file>
+//	sysv     nm -f sysv <file>
+//	dynamic  nm -D <file>
+//	size     nm --size-sort -r <file>
+var nmGoldenOptions = map[string]NMOptions{
+	"bsd":     {All: true, PrintSize: true},
+	"posix":   {Format: NMFormatPOSIX, Sort: NMSortAddress},
+	"sysv":    {Format: NMFormatSysV},
+	"dynamic": {Dynamic: true},
+	"size":    {Sort: NMSortSize, Reverse: true},
+}
+
+func TestWriteNMGolden(t *testing.T) {
+	goldens, err := filepath.Glob(path.Join("testdata", "nm", "*.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, goldens)
+	for _, golden := range goldens {
+		name := strings.TrimSuffix(filepath.Base(golden), ".golden")
+		dot := strings.LastIndexByte(name, '.')
+		name, variant := name[:dot], name[dot+1:]
+		t.Run(filepath.Base(golden), func(t *testing.T) {
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := parseFile(t, path.Join(exampleDir, name))
+			defer p.CloseFile()
+			var out bytes.Buffer
+			if assert.NoError(t, p.WriteNM(&out, name, nmGoldenOptions[variant])) {
+				assert.Equal(t, string(want), out.String())
+			}
+		})
+	}
+}
+
+func TestNMSymbolCodes(t *testing.T) {
+	p := parseFile(t, path.Join(exampleDir, "gcc-amd64-linux-exec"))
+	defer p.CloseFile()
+	syms, err := p.NMSymbols(NMOptions{Dynamic: true})
+	assert.NoError(t, err)
+	var lines []string
+	for _, s := range syms {
+		lines = append(lines, string(s.Code)+" "+s.Name)
+	}
+	assert.Equal(t, []string{"w __gmon_start__", "U __libc_start_main@GLIBC_2.2.5", "U puts@GLIBC_2.2.5"}, lines)
+
+	syms, err = p.NMSymbols(NMOptions{DefinedOnly: true, ExternalOnly: true, Sort: NMSortAddress, Reverse: true})
+	assert.NoError(t, err)
+	lines = lines[:0]
+	for _, s := range syms[:5] {
+		lines = append(lines, string(s.Code)+" "+s.Name)
+	}
+	assert.Equal(t, []string{"A _end", "A _edata", "A __bss_start", "D __dso_handle", "W data_start"}, lines)
+
+	// 目标文件没有动态符号表
+	p = parseFile(t, path.Join(exampleDir, "go-relocation-test-gcc441-x86-64.obj"))
+	defer p.CloseFile()
+	_, err = p.NMSymbols(NMOptions{Dynamic: true})
+	assert.Equal(t, ErrNoSymbols, err)
+	assert.Equal(t, ErrNMFormat, p.WriteNM(&bytes.Buffer{}, "", NMOptions{Format: "xml"}))
+}
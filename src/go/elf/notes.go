@@ -0,0 +1,146 @@
+// Package elf : notes.go implements the decoding of SHT_NOTE sections and
+// PT_NOTE segments.
+package elf
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Note is a single entry of a note section or segment.
+// 每条note由 namesz/descsz/type 三个字 + 4字节(或8字节)对齐的name与desc组成
+type Note struct {
+	Name    string `json:"owner"`
+	Type    NType  `json:"type"`
+	Desc    []byte `json:"desc"`
+	Offset  uint64 `json:"offset"`  // File offset of the note header.
+	Section string `json:"section"` // Section name, empty when read from a segment.
+}
+
+// TypeString returns the name of the note type, which depends on its owner.
+func (n Note) TypeString() string {
+	switch n.Name {
+	case "GNU":
+		for _, t := range gnuNtypeStrings {
+			if NType(t.flag) == n.Type {
+				return t.name
+			}
+		}
+	case "CORE", "LINUX":
+		for _, t := range ntypeStrings {
+			if NType(t.flag) == n.Type {
+				return t.name
+			}
+		}
+	case "FreeBSD":
+		if n.Type == 1 {
+			return "NT_FREEBSD_ABI_TAG"
+		}
+	}
+	return "Unknown note type: (0x" + strconv.FormatUint(uint64(n.Type), 16) + ")"
+}
+
+// noteRange is a region of the file holding a list of notes.
+type noteRange struct {
+	section   string
+	off, size uint64
+	align     uint64
+	data      []byte
+	dataErr   error
+}
+
+// Notes decodes every note of the file. Note sections are used when the
+// file has some, the PT_NOTE segments otherwise and always for core files.
+func (p *Parser) Notes() ([]Note, error) {
+	ranges := p.noteRanges()
+	var notes []Note
+	for _, r := range ranges {
+		if r.dataErr != nil {
+			return notes, r.dataErr
+		}
+		n, err := p.decodeNotes(r)
+		notes = append(notes, n...)
+		if err != nil {
+			return notes, err
+		}
+	}
+	return notes, nil
+}
+
+// noteRanges returns the regions holding notes, in the order readelf -n visits them.
+func (p *Parser) noteRanges() []noteRange {
+	var ranges []noteRange
+	if Type(p.F.rawHeader().Type) != ET_CORE {
+		for _, s := range p.F.Sections() {
+			if SectionType(s.Type) != SHT_NOTE {
+				continue
+			}
+			data, err := s.Data()
+			ranges = append(ranges, noteRange{section: s.SectionName, off: s.Off, size: s.Size, align: s.AddrAlign, data: data, dataErr: err})
+		}
+	}
+	if len(ranges) != 0 {
+		return ranges
+	}
+	// 核心转储文件以及没有note节的文件，从PT_NOTE段中读取
+	for _, ph := range p.F.ProgramHeaders() {
+		if ProgType(ph.Type) != PT_NOTE {
+			continue
+		}
+		// 通过File读取，只有File的临时Parser(如Features)也能解码
+		data := p.readRange(ph.Off, ph.Filesz)
+		var err error
+		if uint64(len(data)) < ph.Filesz {
+			err = io.ErrUnexpectedEOF
+		}
+		ranges = append(ranges, noteRange{off: ph.Off, size: ph.Filesz, align: ph.Align, data: data, dataErr: err})
+	}
+	return ranges
+}
+
+// decodeNotes splits a note region into its entries.
+func (p *Parser) decodeNotes(r noteRange) ([]Note, error) {
+	// 8字节对齐的note（如.note.gnu.property）名字和描述按8字节对齐，其余按4字节
+	align := uint64(4)
+	if r.align == 8 {
+		align = 8
+	}
+	bo := p.F.ByteOrder()
+	var notes []Note
+	data := r.data
+	pos := uint64(0)
+	for pos+12 <= uint64(len(data)) {
+		namesz := uint64(bo.Uint32(data[pos:]))
+		descsz := uint64(bo.Uint32(data[pos+4:]))
+		typ := bo.Uint32(data[pos+8:])
+		nameOff := pos + 12
+		// desc相对note头部对齐，与binutils的ELF_NOTE_DESC_OFFSET一致
+		descOff := pos + alignUp(12+namesz, align)
+		end := descOff + descsz
+		if namesz > uint64(len(data)) || descsz > uint64(len(data)) || end > uint64(len(data)) {
+			return notes, fmt.Errorf("note at offset %#x overflows its container", r.off+pos)
+		}
+		name := data[nameOff : nameOff+namesz]
+		if namesz > 0 && name[namesz-1] == 0 {
+			name = name[:namesz-1]
+		}
+		notes = append(notes, Note{
+			Name:    string(name),
+			Type:    NType(typ),
+			Desc:    data[descOff:end],
+			Offset:  r.off + pos,
+			Section: r.section,
+		})
+		pos = alignUp(end, align)
+	}
+	return notes, nil
+}
+
+// alignUp rounds v up to a multiple of align (a power of two).
+func alignUp(v, align uint64) uint64 {
+	if align <= 1 {
+		return v
+	}
+	return (v + align - 1) &^ (align - 1)
+}
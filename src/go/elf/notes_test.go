@@ -0,0 +1,82 @@
+package elf
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotes(t *testing.T) {
+	p, err := New(path.Join(exampleDir, "gcc-amd64-linux-exec"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.CloseFile()
+	if err := p.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	notes, err := p.Notes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, notes, 1) {
+		assert.Equal(t, "GNU", notes[0].Name)
+		assert.Equal(t, ".note.ABI-tag", notes[0].Section)
+		assert.Equal(t, "NT_GNU_ABI_TAG", notes[0].TypeString())
+		// OS: Linux, ABI: 2.6.8
+		assert.Equal(t, []byte{0, 0, 0, 0, 2, 0, 0, 0, 6, 0, 0, 0, 8, 0, 0, 0}, notes[0].Desc)
+	}
+
+	// 节头被剥离后从PT_NOTE段读取
+	data, err := ioutil.ReadFile(path.Join(exampleDir, "gcc-amd64-linux-exec"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stripped, err := NewBytes(stripSectionHeaders(t, data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stripped.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	segmentNotes, err := stripped.Notes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, segmentNotes, 1) {
+		assert.Equal(t, notes[0].Desc, segmentNotes[0].Desc)
+		assert.Equal(t, notes[0].Offset, segmentNotes[0].Offset)
+		assert.Empty(t, segmentNotes[0].Section)
+	}
+}
+
+func TestVersionNeeds(t *testing.T) {
+	p, err := New(path.Join(exampleDir, "gcc-amd64-linux-exec"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.CloseFile()
+	if err := p.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	needs, err := p.VersionNeeds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, needs, 1) {
+		assert.Equal(t, "libc.so.6", needs[0].File)
+		if assert.Len(t, needs[0].Aux, 1) {
+			assert.Equal(t, "GLIBC_2.2.5", needs[0].Aux[0].Name)
+			assert.Equal(t, uint16(2), needs[0].Aux[0].Other)
+		}
+	}
+	versym, err := p.VersionSymbols()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []uint16{0, 0, 2, 2}, versym)
+	_, err = p.VersionDefs()
+	assert.ErrorIs(t, err, ErrNoVersion)
+}
@@ -0,0 +1,179 @@
+// Package elf : nrv.go decompresses the NRV2B, NRV2D and NRV2E streams of
+// the UCL library, the default compression methods of UPX. The streams mix
+// the literal and offset bytes with a bit buffer refilled 8, 16 or 32 bits
+// at a time, the most significant bit first.
+package elf
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// errNRVCorrupt is returned for a stream that does not decode to the
+// expected size.
+var errNRVCorrupt = errors.New("corrupt NRV stream")
+
+// nrvVariant is the NRV format of a stream.
+type nrvVariant int
+
+const (
+	nrv2b nrvVariant = iota
+	nrv2d
+	nrv2e
+)
+
+// nrvReader reads the interleaved bits and bytes of a stream.
+type nrvReader struct {
+	src   []byte
+	pos   int
+	width uint // bits loaded at a time: 8, 16 or 32
+	bb    uint32
+	bc    uint
+	err   error
+}
+
+func (r *nrvReader) byte() uint32 {
+	if r.pos >= len(r.src) {
+		r.err = errNRVCorrupt
+		return 0
+	}
+	b := r.src[r.pos]
+	r.pos++
+	return uint32(b)
+}
+
+func (r *nrvReader) bit() uint32 {
+	if r.bc == 0 {
+		n := int(r.width / 8)
+		if r.pos+n > len(r.src) {
+			r.err = errNRVCorrupt
+			return 0
+		}
+		switch r.width {
+		case 8:
+			r.bb = uint32(r.src[r.pos])
+		case 16:
+			r.bb = uint32(binary.LittleEndian.Uint16(r.src[r.pos:]))
+		default:
+			r.bb = binary.LittleEndian.Uint32(r.src[r.pos:])
+		}
+		r.pos += n
+		r.bc = r.width
+	}
+	r.bc--
+	return (r.bb >> r.bc) & 1
+}
+
+// gamma reads the Elias gamma like numbers of the format, a 1 followed by
+// data bits each followed by a stop bit.
+func (r *nrvReader) gamma() uint32 {
+	v := uint32(1)
+	for {
+		v = v*2 + r.bit()
+		if r.bit() == 1 || r.err != nil {
+			return v
+		}
+	}
+}
+
+// offsetGamma reads the offset numbers of NRV2D and NRV2E, which carry two
+// data bits per stop bit.
+func (r *nrvReader) offsetGamma() uint32 {
+	v := uint32(1)
+	for {
+		v = v*2 + r.bit()
+		if r.bit() == 1 || r.err != nil {
+			return v
+		}
+		v = (v-1)*2 + r.bit()
+	}
+}
+
+// nrvDecompress decompresses src into a buffer of size bytes. width is the
+// size of the bit buffer of the method.
+func nrvDecompress(variant nrvVariant, width uint, src []byte, size int) ([]byte, error) {
+	r := &nrvReader{src: src, width: width}
+	dst := make([]byte, 0, size)
+	lastOff := uint32(1)
+	for {
+		// 1位是字面量，0位开始一个匹配
+		for r.bit() == 1 {
+			if len(dst) >= size {
+				return nil, errNRVCorrupt
+			}
+			dst = append(dst, byte(r.byte()))
+		}
+		if r.err != nil {
+			return nil, r.err
+		}
+		var off, n uint32
+		if variant == nrv2b {
+			off = r.gamma()
+		} else {
+			off = r.offsetGamma()
+		}
+		if off == 2 {
+			off = lastOff
+			if variant != nrv2b {
+				n = r.bit()
+			}
+		} else {
+			off = (off-3)*256 + r.byte()
+			if off == 0xffffffff {
+				break
+			}
+			if variant != nrv2b {
+				n = (off ^ 0xffffffff) & 1
+				off >>= 1
+			}
+			off++
+			lastOff = off
+		}
+		switch variant {
+		case nrv2b:
+			n = r.bit()*2 + r.bit()
+			if n == 0 {
+				n = r.gamma() + 2
+			}
+			if off > 0xd00 {
+				n++
+			}
+		case nrv2d:
+			n = n*2 + r.bit()
+			if n == 0 {
+				n = r.gamma() + 2
+			}
+			if off > 0x500 {
+				n++
+			}
+		case nrv2e:
+			switch {
+			case n != 0:
+				n = 1 + r.bit()
+			case r.bit() == 1:
+				n = 3 + r.bit()
+			default:
+				n = r.gamma() + 3
+			}
+			if off > 0x500 {
+				n++
+			}
+		}
+		if r.err != nil {
+			return nil, r.err
+		}
+		// 匹配长度比编码的值多1
+		n++
+		if uint64(off) > uint64(len(dst)) || len(dst)+int(n) > size {
+			return nil, errNRVCorrupt
+		}
+		from := len(dst) - int(off)
+		for i := 0; i < int(n); i++ {
+			dst = append(dst, dst[from+i])
+		}
+	}
+	if len(dst) != size {
+		return nil, errNRVCorrupt
+	}
+	return dst, nil
+}
@@ -0,0 +1,315 @@
+// Package elf : packer.go detects packed files: the UPX structures and
+// stub strings, the Ezuri crypter stub, and the custom stubs which give
+// themselves away by a near empty section table next to a PT_LOAD segment
+// of compressed bytes.
+package elf
+
+import (
+	"bytes"
+	"debug/gosym"
+	"encoding/binary"
+	"fmt"
+)
+
+// PackerReport is the packer verdict of a file with the evidence it rests
+// on. The evidence is kept when the file is not found packed, a single
+// high entropy segment is not enough to call it packed.
+type PackerReport struct {
+	Packed bool `json:"packed"`
+	// Packer is UPX, Ezuri or custom, empty when the file is not packed.
+	Packer   string    `json:"packer,omitempty"`
+	Evidence []Finding `json:"evidence"`
+}
+
+// packerRules is the catalogue of the packer evidence.
+var packerRules = []FindingRule{
+	{"packer/upx-linfo", SeverityWarning, "The UPX l_info and p_info headers follow the program headers."},
+	{"packer/upx-packheader", SeverityWarning, "The file holds a UPX PackHeader."},
+	{"packer/upx-string", SeverityNote, "The file holds a string of the UPX stub."},
+	{"packer/ezuri", SeverityWarning, "The file is the Ezuri crypter stub, which decrypts and runs its payload from memory."},
+	{"packer/tiny-section-table", SeverityNote, "The file has almost no section headers."},
+	{"packer/high-entropy-segment", SeverityNote, "A PT_LOAD segment holds compressed or encrypted bytes."},
+}
+
+const (
+	// upxMagic is the magic of the l_info header and of the PackHeader.
+	upxMagic = "UPX!"
+	// lInfoSize and pInfoSize are the sizes of the UPX l_info and p_info
+	// headers.
+	lInfoSize = 12
+	pInfoSize = 12
+	// packHeaderSize is the size of the PackHeader of the ELF formats.
+	packHeaderSize = 32
+	// minPackedSegment is the smallest PT_LOAD segment whose entropy counts.
+	minPackedSegment = 1024
+)
+
+// upxFormats names the UPX formats of the ELF files.
+var upxFormats = map[uint8]string{
+	10:  "linux/i386",
+	12:  "linux/elf386",
+	20:  "linux/elfi386",
+	22:  "linux/amd64",
+	23:  "linux/arm",
+	25:  "bsd/elf386",
+	30:  "linux/mipsel",
+	42:  "linux/arm64",
+	132: "linux/ppc32",
+	133: "linux/armeb",
+	137: "linux/mips",
+}
+
+// upxMethods names the UPX compression methods.
+var upxMethods = map[uint8]string{
+	2:  "NRV2B_LE32",
+	3:  "NRV2B_8",
+	4:  "NRV2B_LE16",
+	5:  "NRV2D_LE32",
+	6:  "NRV2D_8",
+	7:  "NRV2D_LE16",
+	8:  "NRV2E_LE32",
+	9:  "NRV2E_8",
+	10: "NRV2E_LE16",
+	14: "LZMA",
+	15: "DEFLATE",
+}
+
+// upxFormatName returns the name of a UPX format.
+func upxFormatName(format uint8) string {
+	if s, ok := upxFormats[format]; ok {
+		return s
+	}
+	return fmt.Sprintf("format %d", format)
+}
+
+// upxMethodName returns the name of a UPX compression method.
+func upxMethodName(method uint8) string {
+	if s, ok := upxMethods[method]; ok {
+		return s
+	}
+	return fmt.Sprintf("method %d", method)
+}
+
+// upxStrings are the strings the UPX stubs carry.
+var upxStrings = []string{
+	"$Info: This file is packed with the UPX executable packer",
+	"$Id: UPX ",
+}
+
+// ezuriSymbols are the functions of the Ezuri stub, a Go program.
+var ezuriSymbols = []string{"main.runFromMemory", "main.aesDec"}
+
+// lInfo is the UPX l_info header, written right after the program headers
+// of the packed file. The fields use the byte order of the file.
+type lInfo struct {
+	Checksum uint32
+	Magic    [4]byte
+	LSize    uint16
+	Version  uint8
+	Format   uint8
+}
+
+// pInfo is the UPX p_info header following l_info.
+type pInfo struct {
+	ProgID    uint32
+	FileSize  uint32
+	BlockSize uint32
+}
+
+// packHeader is the UPX PackHeader stored near the end of the file, its
+// fields are little endian.
+type packHeader struct {
+	Magic          [4]byte
+	Version        uint8
+	Format         uint8
+	Method         uint8
+	Level          uint8
+	UAdler         uint32
+	CAdler         uint32
+	ULen           uint32
+	CLen           uint32
+	UFileSize      uint32
+	Filter         uint8
+	FilterCTO      uint8
+	NMru           uint8
+	HeaderChecksum uint8
+}
+
+// upxInfoOffset returns the offset of l_info, right after the program
+// header table.
+func (p *Parser) upxInfoOffset() uint64 {
+	h := p.F.rawHeader()
+	return h.Phoff + uint64(h.Phnum)*uint64(h.Phentsize)
+}
+
+// readUPXInfo reads the l_info and p_info headers at off.
+func (p *Parser) readUPXInfo(off uint64) (lInfo, pInfo, bool) {
+	var l lInfo
+	var pi pInfo
+	data := p.readRange(off, lInfoSize+pInfoSize)
+	if len(data) != lInfoSize+pInfoSize {
+		return l, pi, false
+	}
+	r := bytes.NewReader(data)
+	binary.Read(r, p.F.ByteOrder(), &l)
+	binary.Read(r, p.F.ByteOrder(), &pi)
+	return l, pi, true
+}
+
+// findPackHeader returns the offset of the last PackHeader of the file,
+// UPX writes it in the last bytes of the file.
+func (p *Parser) findPackHeader() (uint64, packHeader, bool) {
+	var ph packHeader
+	size := uint64(p.F.size)
+	start := uint64(0)
+	if size > 0x400 {
+		start = size - 0x400
+	}
+	tail := p.readRange(start, size-start)
+	i := bytes.LastIndex(tail, []byte(upxMagic))
+	if i < 0 || len(tail)-i < packHeaderSize {
+		return 0, ph, false
+	}
+	binary.Read(bytes.NewReader(tail[i:]), binary.LittleEndian, &ph)
+	// 格式和压缩方法都不认识时只是碰巧出现的"UPX!"
+	if upxFormats[ph.Format] == "" && upxMethods[ph.Method] == "" {
+		return 0, ph, false
+	}
+	return start + uint64(i), ph, true
+}
+
+// functionNames returns the names of the functions of the file: the
+// STT_FUNC symbols of both symbol tables and, for the Go programs stripped
+// of them, the functions of the .gopclntab table.
+func (p *Parser) functionNames() map[string]bool {
+	names := map[string]bool{}
+	for _, typ := range []SectionType{SHT_SYMTAB, SHT_DYNSYM} {
+		syms, _ := p.Symbols(typ)
+		for _, s := range syms {
+			if ST_TYPE(s.Info) == STT_FUNC {
+				names[s.Name] = true
+			}
+		}
+	}
+	for _, name := range p.goFunctionNames() {
+		names[name] = true
+	}
+	return names
+}
+
+// goFunctionNames decodes the function table of .gopclntab, nil when the
+// file has none or it cannot be decoded.
+func (p *Parser) goFunctionNames() (names []string) {
+	pcln, text := p.F.SectionByName(".gopclntab"), p.F.SectionByName(".text")
+	if pcln == nil || text == nil || SectionType(pcln.Type) == SHT_NOBITS || pcln.checkBounds(p.F.size) != nil {
+		return nil
+	}
+	data, err := pcln.Data()
+	if err != nil {
+		return nil
+	}
+	// debug/gosym信任表中的偏移，损坏的表可能让它越界
+	defer func() {
+		if recover() != nil {
+			names = nil
+		}
+	}()
+	table, err := gosym.NewTable(nil, gosym.NewLineTable(data, text.Addr))
+	if err != nil {
+		return nil
+	}
+	for _, fn := range table.Funcs {
+		names = append(names, fn.Name)
+	}
+	return names
+}
+
+// DetectPacker looks for the traces of the known packers and of custom
+// packer stubs. The file is packed when the UPX headers or the Ezuri stub
+// are found, or when a
+// high entropy PT_LOAD segment comes with a section table of at most two
+// sections.
+func (p *Parser) DetectPacker() (*PackerReport, error) {
+	r := &PackerReport{Evidence: []Finding{}}
+	add := func(id string, off, size uint64, format string, args ...interface{}) {
+		r.Evidence = append(r.Evidence, p.finding(id, off, size, format, args...))
+	}
+	data := p.readRange(0, uint64(p.F.size))
+
+	// UPX：程序头之后紧跟l_info和p_info，文件末尾还有PackHeader
+	upx := false
+	if off := p.upxInfoOffset(); off != 0 {
+		if l, pi, ok := p.readUPXInfo(off); ok && string(l.Magic[:]) == upxMagic {
+			upx = true
+			add("packer/upx-linfo", off, lInfoSize+pInfoSize, "l_info: UPX! magic, l_lsize %d, l_version %d, %s; p_info: unpacked size %d, block size %d",
+				l.LSize, l.Version, upxFormatName(l.Format), pi.FileSize, pi.BlockSize)
+		}
+	}
+	if off, h, ok := p.findPackHeader(); ok {
+		upx = true
+		add("packer/upx-packheader", off, packHeaderSize, "PackHeader: version %d, %s, %s level %d, %d bytes compressed to %d, unpacked file size %d",
+			h.Version, upxFormatName(h.Format), upxMethodName(h.Method), h.Level, h.ULen, h.CLen, h.UFileSize)
+	}
+	// 字符串可能只是被引用，单独出现不足以判定；节中的字符串是程序
+	// 自己的数据（如引用本包的程序），UPX的stub不在任何节中
+	for _, s := range upxStrings {
+		for start := 0; ; {
+			i := bytes.Index(data[start:], []byte(s))
+			if i < 0 {
+				break
+			}
+			i += start
+			start = i + len(s)
+			if _, err := p.F.SectionForOffset(uint64(i)); err == nil {
+				continue
+			}
+			end := bytes.IndexByte(data[i:], '\n')
+			if end < 0 || end > 120 {
+				end = len(s)
+			}
+			add("packer/upx-string", uint64(i), uint64(end), "UPX stub string %q", data[i:i+end])
+			break
+		}
+	}
+
+	// Ezuri：Go写的加载器，解密后用memfd_create从内存运行原程序
+	// 只认函数符号或Go的函数表，名字作为字符串出现在数据中不算
+	ezuri := true
+	names := p.functionNames()
+	for _, sym := range ezuriSymbols {
+		if !names[sym] {
+			ezuri = false
+		}
+	}
+	if ezuri {
+		add("packer/ezuri", 0, 0, "the file has the Ezuri stub functions %s and %s", ezuriSymbols[0], ezuriSymbols[1])
+	}
+
+	// 自定义加壳：节头表几乎为空，同时有高熵的PT_LOAD段
+	h := p.F.rawHeader()
+	tiny := h.Shnum <= 2
+	if tiny {
+		off, _ := p.headerFieldLocation(0x30, 0x3c)
+		add("packer/tiny-section-table", off, 2, "the file has %d section headers", h.Shnum)
+	}
+	high := false
+	for _, s := range p.segmentEntropies() {
+		if s.Name == PT_LOAD.String() && s.Size >= minPackedSegment && s.Entropy >= HighEntropy {
+			high = true
+			off, size := p.programHeaderLocation(s.Index)
+			add("packer/high-entropy-segment", off, size, "segment %d [%#x, %#x) has an entropy of %.3f bits per byte",
+				s.Index, s.Offset, s.Offset+s.Size, s.Entropy)
+		}
+	}
+
+	switch {
+	case upx:
+		r.Packer = "UPX"
+	case ezuri:
+		r.Packer = "Ezuri"
+	case tiny && high:
+		r.Packer = "custom"
+	}
+	r.Packed = r.Packer != ""
+	return r, nil
+}
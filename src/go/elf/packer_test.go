@@ -0,0 +1,241 @@
+package elf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// packedELF builds an x86-64 executable without section headers whose
+// only PT_LOAD segment is mostly random bytes, the way packer output
+// looks. With upx set the UPX l_info, p_info and PackHeader are added.
+func packedELF(t *testing.T, upx bool) []byte {
+	payload := make([]byte, 0x2000)
+	rand.New(rand.NewSource(1)).Read(payload)
+	var body bytes.Buffer
+	le := binary.LittleEndian
+	if upx {
+		binary.Write(&body, le, lInfo{Checksum: 0x12345678, Magic: [4]byte{'U', 'P', 'X', '!'}, LSize: 0x400, Version: 13, Format: 22})
+		binary.Write(&body, le, pInfo{FileSize: 0x5000, BlockSize: 0x5000})
+	}
+	body.Write(payload)
+	if upx {
+		binary.Write(&body, le, packHeader{Magic: [4]byte{'U', 'P', 'X', '!'}, Version: 13, Format: 22, Method: 14, Level: 8,
+			ULen: 0x5000, CLen: 0x2000, UFileSize: 0x5000})
+		body.Write([]byte{0, 0, 0, 0})
+	}
+	return stubELF(body.Bytes())
+}
+
+// stubELF prepends the ELF header and the program header of a single
+// PT_LOAD segment mapping the whole file to body, followed by the extra
+// program headers.
+func stubELF(body []byte, extra ...ELF64ProgramHeader) []byte {
+	const base = 0x400000
+	le := binary.LittleEndian
+	hdrSize := uint64(64 + 56*(1+len(extra)))
+	size := hdrSize + uint64(len(body))
+	h := ELF64Header{Type: uint16(ET_EXEC), Machine: uint16(EM_X86_64), Version: 1, Entry: base + hdrSize,
+		Phoff: 64, Ehsize: 64, Phentsize: 56, Phnum: uint16(1 + len(extra)), Shentsize: 64}
+	copy(h.Ident[:], []byte{0x7f, 'E', 'L', 'F', byte(ELFCLASS64), byte(ELFDATA2LSB), 1})
+	ph := ELF64ProgramHeader{Type: uint32(PT_LOAD), Flags: uint32(PF_R | PF_X), Vaddr: base, Paddr: base,
+		Filesz: size, Memsz: size, Align: 0x1000}
+	var out bytes.Buffer
+	binary.Write(&out, le, h)
+	binary.Write(&out, le, ph)
+	for _, e := range extra {
+		binary.Write(&out, le, e)
+	}
+	out.Write(body)
+	return out.Bytes()
+}
+
+func parsePacked(t *testing.T, data []byte) *Parser {
+	p, err := NewBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestShannonEntropy(t *testing.T) {
+	assert.Equal(t, 0.0, ShannonEntropy(nil))
+	assert.Equal(t, 0.0, ShannonEntropy([]byte("aaaa")))
+	assert.Equal(t, 1.0, ShannonEntropy([]byte("abab")))
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	assert.Equal(t, 8.0, ShannonEntropy(all))
+}
+
+func TestEntropy(t *testing.T) {
+	p := parseFile(t, path.Join(exampleDir, "gcc-amd64-linux-exec"))
+	defer p.CloseFile()
+	r, err := p.Entropy(0)
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultEntropyWindow, r.Window)
+	// 8844字节的文件：窗口起点0, 2048, 4096, 6144，最后一个到文件末尾
+	assert.Len(t, r.Windows, 4)
+	assert.Equal(t, RegionEntropy{Index: 3, Offset: 6144, Size: 8844 - 6144, Entropy: r.Windows[3].Entropy}, r.Windows[3])
+	// PT_GNU_STACK没有文件内容
+	assert.Len(t, r.Segments, 7)
+	assert.Equal(t, "PT_LOAD", r.Segments[2].Name)
+	assert.Equal(t, uint64(0x684), r.Segments[2].Size)
+	// .bss没有内容，不参与统计
+	for _, s := range r.Sections {
+		assert.NotEqual(t, ".bss", s.Name)
+	}
+	assert.Equal(t, ".interp", r.Sections[0].Name)
+	assert.InDelta(t, 3.941, r.Sections[0].Entropy, 0.001)
+	assert.InDelta(t, 3.622, r.File, 0.001)
+
+	// 窗口统计累加出的整个文件的熵与直接计算的一致
+	data := p.readRange(0, uint64(p.F.size))
+	assert.InDelta(t, ShannonEntropy(data), r.File, 1e-9)
+	r, err = p.Entropy(1000)
+	assert.NoError(t, err)
+	assert.InDelta(t, ShannonEntropy(data), r.File, 1e-9)
+	assert.Len(t, r.Windows, 17)
+
+	_, err = p.Entropy(1)
+	assert.Error(t, err)
+}
+
+func TestDetectPacker(t *testing.T) {
+	p := parseFile(t, path.Join(exampleDir, "gcc-amd64-linux-exec"))
+	r, err := p.DetectPacker()
+	assert.NoError(t, err)
+	assert.Equal(t, &PackerReport{Evidence: []Finding{}}, r)
+	p.CloseFile()
+
+	p = parsePacked(t, packedELF(t, false))
+	r, err = p.DetectPacker()
+	assert.NoError(t, err)
+	assert.True(t, r.Packed)
+	assert.Equal(t, "custom", r.Packer)
+	assert.Equal(t, []string{"packer/tiny-section-table", "packer/high-entropy-segment"}, ruleIDs(r.Evidence))
+	assert.Equal(t, "the file has 0 section headers", r.Evidence[0].Message)
+	assert.Equal(t, Finding{RuleID: "packer/high-entropy-segment", Severity: SeverityNote, Offset: 64, Size: 56,
+		Message: r.Evidence[1].Message}, r.Evidence[1])
+	assert.Contains(t, r.Evidence[1].Message, "segment 0 [0x0, 0x2078) has an entropy of 7.9")
+
+	p = parsePacked(t, packedELF(t, true))
+	r, err = p.DetectPacker()
+	assert.NoError(t, err)
+	assert.True(t, r.Packed)
+	assert.Equal(t, "UPX", r.Packer)
+	assert.Equal(t, []string{"packer/upx-linfo", "packer/upx-packheader", "packer/tiny-section-table", "packer/high-entropy-segment"}, ruleIDs(r.Evidence))
+	assert.Equal(t, Finding{RuleID: "packer/upx-linfo", Severity: SeverityWarning, Offset: 0x78, Size: 24,
+		Message: "l_info: UPX! magic, l_lsize 1024, l_version 13, linux/amd64; p_info: unpacked size 20480, block size 20480"}, r.Evidence[0])
+	assert.Equal(t, Finding{RuleID: "packer/upx-packheader", Severity: SeverityWarning, Offset: 0x2090, Size: 32,
+		Message: "PackHeader: version 13, linux/amd64, LZMA level 8, 20480 bytes compressed to 8192, unpacked file size 20480"}, r.Evidence[1])
+
+	// UPX的字符串只作为证据，被引用时不应判定为加壳
+	data, err := ioutil.ReadFile(path.Join(exampleDir, "gcc-amd64-linux-exec"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := "$Info: This file is packed with the UPX executable packer http://upx.sf.net $"
+	p = parsePacked(t, append(data, info+"\n"...))
+	r, err = p.DetectPacker()
+	assert.NoError(t, err)
+	assert.Equal(t, &PackerReport{Evidence: []Finding{
+		{RuleID: "packer/upx-string", Severity: SeverityNote, Message: fmt.Sprintf("UPX stub string %q", info), Offset: 8844, Size: uint64(len(info))},
+	}}, r)
+}
+
+func TestDetectPackerStrings(t *testing.T) {
+	// 链接了本包的程序带着UPX和Ezuri的名字作为数据，不是加壳的文件
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := parseFile(t, exe)
+	defer p.CloseFile()
+	r, err := p.DetectPacker()
+	assert.NoError(t, err)
+	assert.False(t, r.Packed)
+	assert.NotContains(t, ruleIDs(r.Evidence), "packer/upx-string")
+	// Go的函数表与符号表给出同样的函数
+	assert.Contains(t, p.goFunctionNames(), "parser-elf/elf.TestDetectPackerStrings")
+	assert.True(t, p.functionNames()["parser-elf/elf.(*Parser).DetectPacker"])
+}
+
+func TestEntropySectionPastEOF(t *testing.T) {
+	p := mutatedExec(t, func(data []byte, f *Parser) {
+		s := f.F.SectionByName(".comment")
+		i := 0
+		for j, sec := range f.F.Sections() {
+			if sec == s {
+				i = j
+			}
+		}
+		le := binary.LittleEndian
+		le.PutUint64(data[le.Uint64(data[0x28:])+uint64(i)*64+0x18:], 0x100000)
+	})
+	r, err := p.Entropy(0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	found := false
+	for _, s := range r.Sections {
+		if s.Name == ".comment" {
+			found = true
+			assert.Equal(t, uint64(0x100000), s.Offset)
+			assert.NotEmpty(t, s.Error)
+		} else {
+			assert.Empty(t, s.Error, s.Name)
+		}
+	}
+	assert.True(t, found)
+	var out bytes.Buffer
+	assert.NoError(t, p.WriteEntropy(&out, FormatText, 0))
+	assert.Contains(t, out.String(), "error: ")
+}
+
+func TestEntropySectionInflatedSize(t *testing.T) {
+	// sh_size改为1<<40的节与越过文件末尾的节一样报告，不按其大小分配内存
+	p := mutatedExec(t, func(data []byte, f *Parser) {
+		le := binary.LittleEndian
+		le.PutUint64(data[le.Uint64(data[0x28:])+27*64+0x20:], 1<<40)
+	})
+	defer p.CloseFile()
+	assert.Equal(t, ".debug_aranges", p.F.Sections()[27].SectionName)
+	r, err := p.Entropy(0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	found := false
+	for _, s := range r.Sections {
+		if s.Index == 27 {
+			found = true
+			assert.Equal(t, RegionEntropy{Index: 27, Name: ".debug_aranges", Offset: 0x9c0, Size: 1 << 40,
+				Error: "contents [0x9c0, +0x10000000000) extend past the end of the file"}, s)
+		} else {
+			assert.Empty(t, s.Error, s.Name)
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestWriteEntropy(t *testing.T) {
+	p := parsePacked(t, packedELF(t, true))
+	var out bytes.Buffer
+	assert.NoError(t, p.WriteEntropy(&out, FormatText, 0))
+	assert.Contains(t, out.String(), "packer/upx-linfo")
+	out.Reset()
+	assert.NoError(t, p.WriteEntropy(&out, FormatJSON, 0))
+	assert.Contains(t, out.String(), `"packer": "UPX"`)
+	assert.Contains(t, out.String(), `"window": 4096`)
+}
@@ -13,6 +13,12 @@ import (
 type Parser struct {
 	fs binstream.Stream
 	F  *File
+	// Lenient makes Parse keep going past the errors of the program
+	// headers, the symbols and the dynamic section of malformed files.
+	Lenient bool
+	// ParseErrors are the errors Parse recovered from, the unusable section
+	// header table and, in lenient mode, the skipped parts of the file.
+	ParseErrors []error
 }
 
 // New creates a new instance of parser.
@@ -26,7 +32,7 @@ func New(filename string) (*Parser, error) {
 	// Parser结构，将fs字节流内容提取填充到F结构中
 	p := &Parser{
 		fs: fs,
-		F:  &File{},
+		F:  &File{r: fs, size: int64(fs.Len())},
 	}
 	return p, nil
 }
@@ -39,7 +45,7 @@ func NewBytes(data []byte) (*Parser, error) {
 	}
 	p := &Parser{
 		fs: fs,
-		F:  &File{},
+		F:  &File{r: fs, size: int64(fs.Len())},
 	}
 	return p, nil
 }
@@ -61,24 +67,62 @@ func (p *Parser) Parse() error {
 	}
 	// 解析所有节头
 	err = p.ParseELFSectionHeaders(elfClass)
-	if err != nil {
-		return err
+	if err == nil {
+		// 解析所有节
+		err = p.ParseELFSections(elfClass)
 	}
-	// 解析所有节
-	err = p.ParseELFSections(elfClass)
 	if err != nil {
-		return err
+		// 节头被剥离（sstrip）或者被加壳程序破坏时，退回到基于程序头（段）的解析
+		return p.parseFromSegments(elfClass, err)
 	}
 	// 解析程序头
 	err = p.ParseELFProgramHeaders(elfClass)
-	if err != nil {
+	if err = p.lenientError("program headers", err); err != nil {
 		return err
 	}
 	// 解析所有符号表，指定为动态符号SHT_DYNSYM，而非SHT_SYMTAB
+	// 静态链接的程序与目标文件(.o)没有.dynsym，这不是错误
 	err = p.ParseELFSymbols(elfClass, SHT_DYNSYM)
-	if err != nil {
+	if err != nil && err != ErrNoSymbols {
+		if err = p.lenientError("dynamic symbols", err); err != nil {
+			return err
+		}
+	}
+	// 解析PT_DYNAMIC段中的动态链接信息
+	err = p.ParseDynamic()
+	if err != nil && err != ErrNoDynamic {
+		return p.lenientError("dynamic section", err)
+	}
+	return nil
+}
+
+// lenientError records err in ParseErrors and drops it in lenient mode.
+func (p *Parser) lenientError(part string, err error) error {
+	if err == nil || !p.Lenient {
 		return err
 	}
+	p.ParseErrors = append(p.ParseErrors, fmt.Errorf("%s: %w", part, err))
+	return nil
+}
+
+// parseFromSegments is the fallback used when the section header table is
+// missing or unusable, everything is rebuilt from the program headers.
+func (p *Parser) parseFromSegments(c Class, sectionErr error) error {
+	p.F.SectionHeaders32, p.F.Sections32 = nil, nil
+	p.F.SectionHeaders64, p.F.Sections64 = nil, nil
+	p.ParseErrors = append(p.ParseErrors, fmt.Errorf("section headers: %w", sectionErr))
+	err := p.ParseELFProgramHeaders(c)
+	if err = p.lenientError("program headers", err); err != nil {
+		return err
+	}
+	if len(p.F.ProgramHeaders()) == 0 && !p.Lenient {
+		// 既没有节头也没有程序头，没有任何可以解析的内容
+		return sectionErr
+	}
+	err = p.ParseDynamicFromSegments()
+	if err != nil && err != ErrNoDynamic {
+		return p.lenientError("dynamic segment", err)
+	}
 	return nil
 }
 
@@ -374,7 +418,14 @@ func (p *Parser) parseELFSections64() error {
 		return errors.New("binary has no sections")
 	}
 	// 获取节头相关的字符串信息。这个信息也是存放在一个特定的节中的，这个节叫Shstrndx
-	// 获取指定节的字符表
+	// 获取指定节的字符表，被篡改的Shstrndx可能越界
+	if int(p.F.Header64.Shstrndx) >= len(sections) {
+		return errors.New("section header string table index out of range")
+	}
+	// 节头可能是垃圾数据，读取前先确认字符表在文件之内，否则按sh_size分配内存会崩溃
+	if err := sections[p.F.Header64.Shstrndx].checkBounds(p.F.size); err != nil {
+		return fmt.Errorf("section header string table: %w", err)
+	}
 	shstrtab, err := sections[p.F.Header64.Shstrndx].Data()
 	if err != nil {
 		return errors.New("error reading the section header strings table " + err.Error())
@@ -436,14 +487,20 @@ func (p *Parser) parseELFSections32() error {
 	if len(sections) == 0 {
 		return errors.New("binary has no sections")
 	}
-	shstrtab, err := sections[p.F.Header64.Shstrndx].Data()
+	if int(p.F.Header32.Shstrndx) >= len(sections) {
+		return errors.New("section header string table index out of range")
+	}
+	if err := sections[p.F.Header32.Shstrndx].widen().checkBounds(p.F.size); err != nil {
+		return fmt.Errorf("section header string table: %w", err)
+	}
+	shstrtab, err := sections[p.F.Header32.Shstrndx].Data()
 	if err != nil {
 		return errors.New("error reading the section header strings table " + err.Error())
 	}
 
 	for i, s := range sections {
 		var ok bool
-		s.SectionName, ok = getString(shstrtab, int(p.F.SectionHeaders64[i].Name))
+		s.SectionName, ok = getString(shstrtab, int(p.F.SectionHeaders32[i].Name))
 		if !ok {
 			return errors.New("failed to parse string table")
 		}
@@ -484,6 +541,8 @@ func (p *Parser) parseELFProgramHeaders64() error {
 		// binary.Read 仍然跟游标有关，按大小端读取程序头元数据
 		err := binary.Read(p.fs, p.F.Ident.ByteOrder, &ph)
 		if err != nil {
+			// 保留已读到的程序头，宽松模式下仍可使用
+			p.F.ProgramHeaders64 = programHeaders[:i]
 			return err
 		}
 		// 每程序头都放在数组中
@@ -507,6 +566,7 @@ func (p *Parser) parseELFProgramHeaders32() error {
 		var ph ELF32ProgramHeader
 		err := binary.Read(p.fs, p.F.Ident.ByteOrder, &ph)
 		if err != nil {
+			p.F.ProgramHeaders32 = programHeaders[:i]
 			return err
 		}
 		programHeaders[i] = ph
@@ -537,7 +597,7 @@ func (p *Parser) ParseELFSymbols(c Class, typ SectionType) error {
 }
 
 func (p *Parser) getSymbols32(typ SectionType) ([]Symbol, []byte, error) {
-	symtabSection := p.F.Get64SectionByType(typ)
+	symtabSection := p.F.Get32SectionByType(typ)
 	if symtabSection == nil {
 		return nil, nil, ErrNoSymbols
 	}
@@ -553,9 +613,8 @@ func (p *Parser) getSymbols32(typ SectionType) ([]Symbol, []byte, error) {
 	if err != nil {
 		return nil, nil, errors.New("cannot load string table section")
 	}
-	// The first entry is all zeros.
-	var skip [Sym32Size]byte
-	symtab.Read(skip[:])
+	// 与getSymbols64保持一致，不跳过第0项：符号下标与readelf的Num、
+	// 重定位的r_sym以及.gnu.version的下标一一对应
 	symbols := make([]ELF32SymbolTableEntry, symtab.Len()/Sym32Size)
 	namedSymbols := make([]Symbol, symtab.Len()/Sym32Size)
 	i := 0
@@ -576,6 +635,14 @@ func (p *Parser) getSymbols32(typ SectionType) ([]Symbol, []byte, error) {
 		}
 		i++
 	}
+	if typ == SHT_DYNSYM {
+		err = p.ParseGNUVersionTable(strdata)
+		if err == nil {
+			for i := range namedSymbols {
+				namedSymbols[i].Library, namedSymbols[i].Version = p.gnuVersion(i - 1)
+			}
+		}
+	}
 	p.F.Symbols32 = symbols
 	p.F.NamedSymbols = namedSymbols
 	return namedSymbols, strdata, nil
@@ -656,3 +723,60 @@ func (p *Parser) getSymbols64(typ SectionType) ([]Symbol, []byte, error) {
 	p.F.NamedSymbols = namedSymbols
 	return namedSymbols, dynstrStringTable, nil
 }
+
+// Symbols decodes the symbols of the first section with the given type
+// (SHT_SYMTAB or SHT_DYNSYM) regardless of the ELF class, unlike
+// ParseELFSymbols it leaves the parsed state of the File untouched.
+// Without section headers the dynamic symbols rebuilt from PT_DYNAMIC are returned.
+func (p *Parser) Symbols(typ SectionType) ([]Symbol, error) {
+	s := p.F.SectionByType(typ)
+	if s == nil {
+		if typ == SHT_DYNSYM && p.F.FromSegments {
+			return p.F.NamedSymbols, nil
+		}
+		return nil, ErrNoSymbols
+	}
+	return p.SectionSymbols(s)
+}
+
+// SectionSymbols decodes the symbol table held by the section s.
+func (p *Parser) SectionSymbols(s *ELF64Section) ([]Symbol, error) {
+	data, err := s.Data()
+	if err != nil {
+		return nil, errors.New("cannot load symbol section")
+	}
+	symSize := Sym64Size
+	if p.F.Class() == ELFCLASS32 {
+		symSize = Sym32Size
+	}
+	if len(data)%symSize != 0 {
+		return nil, errors.New("length of symbol section is not a multiple of the symbol size")
+	}
+	strdata, err := p.F.stringTable(s.Link)
+	if err != nil {
+		return nil, errors.New("cannot load string table section")
+	}
+	symtab := bytes.NewReader(data)
+	symbols := make([]Symbol, 0, len(data)/symSize)
+	for symtab.Len() > 0 {
+		var sym Symbol
+		if p.F.Class() == ELFCLASS32 {
+			var e ELF32SymbolTableEntry
+			binary.Read(symtab, p.F.ByteOrder(), &e)
+			sym = Symbol{Info: e.Info, Other: e.Other, Index: SectionIndex(e.Shndx), Value: uint64(e.Value), Size: uint64(e.Size)}
+			sym.Name, _ = getString(strdata, int(e.Name))
+		} else {
+			var e ELF64SymbolTableEntry
+			binary.Read(symtab, p.F.ByteOrder(), &e)
+			sym = Symbol{Info: e.Info, Other: e.Other, Index: SectionIndex(e.Shndx), Value: e.Value, Size: e.Size}
+			sym.Name, _ = getString(strdata, int(e.Name))
+		}
+		symbols = append(symbols, sym)
+	}
+	if SectionType(s.Type) == SHT_DYNSYM && len(p.F.GNUVersionSym) != 0 {
+		for i := range symbols {
+			symbols[i].Library, symbols[i].Version = p.gnuVersion(i - 1)
+		}
+	}
+	return symbols, nil
+}
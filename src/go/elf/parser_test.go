@@ -1551,3 +1551,25 @@ func TestParser(t *testing.T) {
 	})
 
 }
+
+func TestSymbolsNullEntry(t *testing.T) {
+	// 两种class的符号表都保留第0项空符号，下标与readelf的Num一致
+	tests := []struct {
+		name  string
+		count int
+		sym1  string
+	}{
+		{"gcc-386-freebsd-exec", 17, "printf"},
+		{"gcc-amd64-linux-exec", 4, "__gmon_start__"},
+	}
+	for _, tt := range tests {
+		p := parseFile(t, path.Join(exampleDir, tt.name))
+		syms, err := p.Symbols(SHT_DYNSYM)
+		assert.NoError(t, err, tt.name)
+		if assert.Len(t, syms, tt.count, tt.name) {
+			assert.Equal(t, Symbol{Index: SHN_UNDEF}, syms[0], tt.name)
+			assert.Equal(t, tt.sym1, syms[1].Name, tt.name)
+		}
+		p.CloseFile()
+	}
+}
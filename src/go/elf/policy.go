@@ -0,0 +1,307 @@
+// Package elf : policy.go implements the hardening policy checker used to
+// gate releases: a policy lists rules, each rule names the files it applies
+// to with path globs and the hardening properties they must have.
+package elf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+/*
+rules:
+  - paths: ["/usr/bin/*", "/usr/sbin/*"]
+    relro: full
+    pie: true
+    nx: true
+  - paths: ["/**"]
+    no_rpath: true
+    no_exec_stack: true
+    banned_imports: [gets, strcpy, system]
+*/
+
+// Policy is a list of rules, every rule matching a file applies to it.
+type Policy struct {
+	Rules []PolicyRule `json:"rules" yaml:"rules"`
+}
+
+// PolicyRule declares the properties required from the files matching
+// Paths, a rule without paths applies to every file. The boolean
+// requirements are only checked when set.
+type PolicyRule struct {
+	// Paths are slash separated globs in the syntax of path.Match, a "**"
+	// element matches any number of directories.
+	Paths []string `json:"paths,omitempty" yaml:"paths,omitempty"`
+	// RELRO is the lowest accepted level, partial accepts full too.
+	RELRO RELROLevel `json:"relro,omitempty" yaml:"relro,omitempty"`
+	// PIE accepts position independent executables and shared objects.
+	PIE    bool `json:"pie,omitempty" yaml:"pie,omitempty"`
+	NX     bool `json:"nx,omitempty" yaml:"nx,omitempty"`
+	Canary bool `json:"canary,omitempty" yaml:"canary,omitempty"`
+	// Fortify rejects the calls to functions having a fortified version.
+	Fortify     bool `json:"fortify,omitempty" yaml:"fortify,omitempty"`
+	NoRPath     bool `json:"no_rpath,omitempty" yaml:"no_rpath,omitempty"`
+	NoRunPath   bool `json:"no_runpath,omitempty" yaml:"no_runpath,omitempty"`
+	NoExecStack bool `json:"no_exec_stack,omitempty" yaml:"no_exec_stack,omitempty"`
+	NoWXSegment bool `json:"no_wx_segment,omitempty" yaml:"no_wx_segment,omitempty"`
+	IBT         bool `json:"ibt,omitempty" yaml:"ibt,omitempty"`
+	SHSTK       bool `json:"shstk,omitempty" yaml:"shstk,omitempty"`
+	BTI         bool `json:"bti,omitempty" yaml:"bti,omitempty"`
+	PAC         bool `json:"pac,omitempty" yaml:"pac,omitempty"`
+	// BannedImports are function names the files must not import.
+	BannedImports []string `json:"banned_imports,omitempty" yaml:"banned_imports,omitempty"`
+}
+
+// PolicyViolation is a requirement of a rule a file does not meet or, with
+// NotApplicable set, a requirement that has no meaning for the file, such as
+// the stack properties of an eBPF object. The latter do not fail the file.
+type PolicyViolation struct {
+	Path string `json:"path"`
+	// Rule is the index of the rule in Policy.Rules.
+	Rule int `json:"rule"`
+	// Check names the requirement, it is the name of the policy field.
+	Check         string `json:"check"`
+	Message       string `json:"message"`
+	NotApplicable bool   `json:"not_applicable,omitempty"`
+	// Offset, Size and Section locate the violation like Finding does.
+	Offset  uint64 `json:"offset"`
+	Size    uint64 `json:"size,omitempty"`
+	Section string `json:"section,omitempty"`
+}
+
+func (v PolicyViolation) String() string {
+	if v.NotApplicable {
+		return fmt.Sprintf("%s: rule %d: %s: not applicable, %s", v.Path, v.Rule, v.Check, v.Message)
+	}
+	return fmt.Sprintf("%s: rule %d: %s: %s", v.Path, v.Rule, v.Check, v.Message)
+}
+
+// policyRulePrefix prefixes the check of a violation in its finding rule ID.
+const policyRulePrefix = "policy/"
+
+// Finding returns the violation as a finding of rule policy/<check>, a
+// note when the requirement is not applicable.
+func (v PolicyViolation) Finding() Finding {
+	f := Finding{
+		RuleID: policyRulePrefix + v.Check, Severity: SeverityError, Message: v.Message,
+		Offset: v.Offset, Size: v.Size, Section: v.Section,
+	}
+	if v.NotApplicable {
+		f.Severity, f.Message = SeverityNote, "not applicable, "+v.Message
+	}
+	return f
+}
+
+// ErrBadPolicy is returned by ParsePolicy for a policy that cannot be used.
+var ErrBadPolicy = errors.New("bad policy")
+
+// ParsePolicy decodes a YAML or JSON policy, JSON being a subset of YAML.
+// Unknown fields are rejected so that a misspelt requirement is not
+// silently ignored.
+func ParsePolicy(data []byte) (*Policy, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	pol := &Policy{}
+	if err := dec.Decode(pol); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadPolicy, err)
+	}
+	for i, r := range pol.Rules {
+		switch r.RELRO {
+		case "", RELRONone, RELROPartial, RELROFull:
+		default:
+			return nil, fmt.Errorf("%w: rule %d: unknown RELRO level %q", ErrBadPolicy, i, r.RELRO)
+		}
+		for _, g := range r.Paths {
+			if _, err := path.Match(g, ""); err != nil {
+				return nil, fmt.Errorf("%w: rule %d: bad path glob %q", ErrBadPolicy, i, g)
+			}
+		}
+	}
+	return pol, nil
+}
+
+// matchGlob matches a slash separated name against a glob of path.Match
+// where a "**" element matches zero or more elements.
+func matchGlob(glob, name string) bool {
+	return matchElems(strings.Split(glob, "/"), strings.Split(name, "/"))
+}
+
+func matchElems(glob, name []string) bool {
+	for len(glob) != 0 {
+		if glob[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchElems(glob[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(glob[0], name[0]); !ok {
+			return false
+		}
+		glob, name = glob[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// Matches reports whether the rule applies to the slash separated name.
+func (r *PolicyRule) Matches(name string) bool {
+	if len(r.Paths) == 0 {
+		return true
+	}
+	for _, g := range r.Paths {
+		if matchGlob(g, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// relroRank orders the RELRO levels.
+var relroRank = map[RELROLevel]int{RELRONone: 0, RELROPartial: 1, RELROFull: 2}
+
+// execStackReason explains why the stack of a file is executable.
+func execStackReason(h *HardeningReport) string {
+	switch {
+	case h.GNUStack:
+		return "the stack is executable"
+	case h.PIE == PIERel:
+		return "no .note.GNU-stack section, the stack defaults to executable"
+	}
+	return "no PT_GNU_STACK segment, the stack defaults to executable"
+}
+
+// notProcessReason explains why the file is never run as a process, making
+// the requirements on the process (stack, RELRO, PIE, control flow
+// protection) meaningless, it returns "" for the files that are. The
+// offset and size locate the header field telling so.
+func (p *Parser) notProcessReason() (string, uint64, uint64) {
+	h := p.F.rawHeader()
+	if Machine(h.Machine) == EM_BPF {
+		return "eBPF programs are run by the kernel, not as a process", 0x12, 2
+	}
+	switch t := Type(h.Type); t {
+	case ET_EXEC, ET_DYN, ET_REL:
+		return "", 0, 0
+	default:
+		return fmt.Sprintf("%s files are not run as a process", t), EI_NIDENT, 2
+	}
+}
+
+// CheckPolicy evaluates the rules of pol matching name against the file
+// and returns the violations, in rule order. The requirements on the
+// process are returned as not applicable for the files never run as one.
+func (p *Parser) CheckPolicy(pol *Policy, name string) ([]PolicyViolation, error) {
+	h, err := p.Hardening()
+	if err != nil {
+		return nil, err
+	}
+	var imports map[string]bool
+	var violations []PolicyViolation
+	reason, reasonOff, reasonSize := p.notProcessReason()
+	process := reason == ""
+	for i := range pol.Rules {
+		r := &pol.Rules[i]
+		if !r.Matches(name) {
+			continue
+		}
+		fail := func(check string, off, size uint64, format string, args ...interface{}) {
+			v := PolicyViolation{Path: name, Rule: i, Check: check, Message: fmt.Sprintf(format, args...), Offset: off, Size: size}
+			if s, err := p.F.SectionForOffset(off); err == nil {
+				v.Section = s.SectionName
+			}
+			violations = append(violations, v)
+		}
+		if !process {
+			// 不以进程运行的文件，进程相关的要求无从谈起，逐项报告为不适用
+			for _, f := range []struct {
+				check    string
+				required bool
+			}{
+				{"relro", r.RELRO != ""}, {"pie", r.PIE}, {"nx", r.NX}, {"no_exec_stack", r.NoExecStack}, {"canary", r.Canary},
+				{"ibt", r.IBT}, {"shstk", r.SHSTK}, {"bti", r.BTI}, {"pac", r.PAC},
+			} {
+				if f.required {
+					violations = append(violations, PolicyViolation{Path: name, Rule: i, Check: f.check, Message: reason,
+						Offset: reasonOff, Size: reasonSize, NotApplicable: true})
+				}
+			}
+		}
+		// 目标文件还没有链接，RELRO和PIE要等链接后才确定
+		linked := process && h.PIE != PIERel
+		if r.RELRO != "" && linked && relroRank[h.RELRO] < relroRank[r.RELRO] {
+			off, size := p.dynamicLocation(DT_FLAGS)
+			fail("relro", off, size, "RELRO is %s, %s is required", h.RELRO, r.RELRO)
+		}
+		if r.PIE && linked && h.PIE == PIENo {
+			fail("pie", EI_NIDENT, 2, "not a position independent executable")
+		}
+		if r.NX && process && !h.NX {
+			off, size := p.stackLocation(h)
+			fail("nx", off, size, "%s", execStackReason(h))
+		}
+		if r.NoExecStack && process && !h.NX {
+			off, size := p.stackLocation(h)
+			fail("no_exec_stack", off, size, "%s", execStackReason(h))
+		}
+		if r.Canary && process && !h.Canary {
+			off, size := p.sectionLocation(p.F.SectionByType(SHT_DYNSYM))
+			fail("canary", off, size, "no stack protector, __stack_chk_fail is not imported")
+		}
+		if r.Fortify && len(h.Unfortified) != 0 {
+			off, size := p.importLocation(h.Unfortified[0])
+			fail("fortify", off, size, "unfortified calls to %s", strings.Join(h.Unfortified, ", "))
+		}
+		if r.NoRPath && h.RPath != "" {
+			off, size := p.dynamicLocation(DT_RPATH)
+			fail("no_rpath", off, size, "DT_RPATH is set to %q", h.RPath)
+		}
+		if r.NoRunPath && h.RunPath != "" {
+			off, size := p.dynamicLocation(DT_RUNPATH)
+			fail("no_runpath", off, size, "DT_RUNPATH is set to %q", h.RunPath)
+		}
+		if r.NoWXSegment {
+			for _, s := range h.WXSegments {
+				off, size := p.programHeaderLocation(s)
+				fail("no_wx_segment", off, size, "segment %d is writable and executable", s)
+			}
+		}
+		for _, f := range []struct {
+			check, name string
+			required    bool
+			set         bool
+		}{
+			{"ibt", "IBT", r.IBT, h.IBT},
+			{"shstk", "SHSTK", r.SHSTK, h.SHSTK},
+			{"bti", "BTI", r.BTI, h.BTI},
+			{"pac", "PAC", r.PAC, h.PAC},
+		} {
+			if f.required && process && !f.set {
+				off, size := p.propertyNoteLocation()
+				fail(f.check, off, size, "%s is not enabled in the GNU properties", f.name)
+			}
+		}
+		if len(r.BannedImports) != 0 && imports == nil {
+			imports = p.importedSymbols()
+		}
+		for _, fn := range r.BannedImports {
+			// 加固版本__<fn>_chk仍是同一个函数
+			if imports[fn] {
+				off, size := p.importLocation(fn)
+				fail("banned_imports", off, size, "imports banned function %s", fn)
+			} else if imports["__"+fn+"_chk"] {
+				off, size := p.importLocation("__" + fn + "_chk")
+				fail("banned_imports", off, size, "imports banned function %s as __%s_chk", fn, fn)
+			}
+		}
+	}
+	return violations, nil
+}
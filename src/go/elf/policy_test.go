@@ -0,0 +1,120 @@
+package elf
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func loadPolicy(t *testing.T, name string) *Policy {
+	data, err := ioutil.ReadFile(path.Join("testdata", "policy", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pol, err := ParsePolicy(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pol
+}
+
+func TestParsePolicy(t *testing.T) {
+	// JSON与YAML写法得到同一个策略
+	assert.Equal(t, loadPolicy(t, "release.yaml"), loadPolicy(t, "release.json"))
+
+	for _, bad := range []string{
+		"rules:\n  - relro: total\n",
+		"rules:\n  - paths: ['[']\n",
+		"rules:\n  - no_rpaths: true\n",
+		"rules: [",
+	} {
+		_, err := ParsePolicy([]byte(bad))
+		assert.ErrorIs(t, err, ErrBadPolicy, bad)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	for _, tc := range []struct {
+		glob, name string
+		match      bool
+	}{
+		{"/usr/bin/*", "/usr/bin/ls", true},
+		{"/usr/bin/*", "/usr/bin/x/ls", false},
+		{"/usr/**", "/usr/bin/x/ls", true},
+		{"/usr/**/ls", "/usr/ls", true},
+		{"**/*.so", "lib/libc.so", true},
+		{"**/*.so", "lib/libc.so.6", false},
+	} {
+		assert.Equal(t, tc.match, matchGlob(tc.glob, tc.name), "%s %s", tc.glob, tc.name)
+	}
+}
+
+func TestCheckPolicy(t *testing.T) {
+	pol := loadPolicy(t, "release.yaml")
+	for _, tc := range []struct {
+		file string
+		want []PolicyViolation
+	}{
+		// __strcpy_chk是.dynsym的第6项
+		{"gcc-amd64-linux-hardened", []PolicyViolation{
+			{Rule: 1, Check: "banned_imports", Message: "imports banned function strcpy as __strcpy_chk",
+				Offset: 0x468, Size: 0x18, Section: ".dynsym"},
+		}},
+		{"gcc-amd64-linux-exec", []PolicyViolation{
+			{Rule: 0, Check: "relro", Message: "RELRO is none, full is required",
+				Offset: 0x6b0, Size: 0x1a0, Section: ".dynamic"},
+			{Rule: 0, Check: "pie", Message: "not a position independent executable",
+				Offset: 0x10, Size: 2},
+			{Rule: 0, Check: "canary", Message: "no stack protector, __stack_chk_fail is not imported",
+				Offset: 0x288, Size: 0x60, Section: ".dynsym"},
+		}},
+		// 第一条规则不匹配，没有PT_GNU_STACK
+		{"gcc-386-freebsd-exec", []PolicyViolation{
+			{Rule: 1, Check: "no_exec_stack", Message: "no PT_GNU_STACK segment, the stack defaults to executable",
+				Offset: 0x34, Size: 0xa0},
+		}},
+		{"go-relocation-test-gcc441-x86-64.obj", nil},
+		// eBPF对象没有PT_GNU_STACK也不算违反，栈的要求不适用
+		{"xdp_fw.elf", []PolicyViolation{
+			{Rule: 1, Check: "no_exec_stack", Message: "eBPF programs are run by the kernel, not as a process",
+				Offset: 0x12, Size: 2, NotApplicable: true},
+		}},
+	} {
+		name := "/opt/" + tc.file
+		p := parseFile(t, path.Join(exampleDir, tc.file))
+		got, err := p.CheckPolicy(pol, name)
+		assert.NoError(t, err)
+		for i := range tc.want {
+			tc.want[i].Path = name
+		}
+		assert.Equal(t, tc.want, got, tc.file)
+		p.CloseFile()
+	}
+}
+
+func TestCheckPolicyNotApplicable(t *testing.T) {
+	pol, err := ParsePolicy([]byte("rules:\n  - {relro: full, pie: true, nx: true, no_exec_stack: true, canary: true, ibt: true, no_rpath: true}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 改为ET_CORE，进程相关的要求逐项报告为不适用，其余照常检查
+	p := mutatedExec(t, func(data []byte, f *Parser) {
+		binary.LittleEndian.PutUint16(data[EI_NIDENT:], uint16(ET_CORE))
+	})
+	defer p.CloseFile()
+	got, err := p.CheckPolicy(pol, "core")
+	assert.NoError(t, err)
+	var checks []string
+	for _, v := range got {
+		assert.True(t, v.NotApplicable, v.Check)
+		assert.Equal(t, "ET_CORE files are not run as a process", v.Message)
+		checks = append(checks, v.Check)
+	}
+	assert.Equal(t, []string{"relro", "pie", "nx", "no_exec_stack", "canary", "ibt"}, checks)
+	assert.Equal(t, "core: rule 0: nx: not applicable, ET_CORE files are not run as a process", got[2].String())
+	assert.Equal(t, Finding{RuleID: "policy/nx", Severity: SeverityNote, Message: "not applicable, ET_CORE files are not run as a process",
+		Offset: EI_NIDENT, Size: 2}, got[2].Finding())
+}
@@ -1,10 +1,11 @@
 package elf
 
 import (
-	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
+	"os"
+	"strings"
 )
 
 // DumpJSON marshals the entire binary representation into JSON Format.
@@ -119,34 +120,44 @@ Key to Flags:
   C (compressed), x (unknown), o (OS specific), E (exclude),
   l (large), p (processor specific)
 */
-func (p *Parser) DumpSectionHeaders() {
+func (p *Parser) DumpSectionHeaders() error {
 	PrintSeparator()
+	if len(p.F.Sections()) == 0 {
+		fmt.Println("There are no sections in this file.")
+		return nil
+	}
 	fmt.Println("Section Headers:")
 	fmt.Println(`  [Nr] Name                     Type            Address          Off    Size   ES Flg                      Lk Inf Al`)
 	switch p.F.Ident.Class {
 	case ELFCLASS32:
+		if int(p.F.Header32.Shstrndx) >= len(p.F.Sections32) {
+			return fmt.Errorf("section header string table index %d is out of range", p.F.Header32.Shstrndx)
+		}
 		shstrtab, err := p.F.Sections32[p.F.Header32.Shstrndx].Data()
 		if err != nil {
-			panic("error reading the section header strings table " + err.Error())
+			return fmt.Errorf("error reading the section header strings table: %w", err)
 		}
 		for index, sh := range p.F.SectionHeaders32 {
 			SectionName, ok := getString(shstrtab, int(sh.Name))
 			if !ok {
-				panic("failed to parse string table")
+				return fmt.Errorf("section %d: invalid name offset 0x%x in the section header strings table", index, sh.Name)
 			}
 
-			fmt.Printf("  [%2d] %-24s %-15d %-.16x %-.6x %-.6x %-.2x %-3x %-2d %-3d %-2d %s\n",
-				index, SectionName, sh.Type, sh.Addr, sh.Off, sh.Size, sh.EntSize, sh.Flags, sh.Link, sh.Info, sh.AddrAlign)
+			fmt.Printf("  [%2d] %-24s %-15s %-.16x %-.6x %-.6x %-.2x %-24s %-2d %-3d %-2d\n",
+				index, SectionName, SectionType(sh.Type).String(), sh.Addr, sh.Off, sh.Size, sh.EntSize, SectionFlag(sh.Flags).String(), sh.Link, sh.Info, sh.AddrAlign)
 		}
 	case ELFCLASS64:
+		if int(p.F.Header64.Shstrndx) >= len(p.F.Sections64) {
+			return fmt.Errorf("section header string table index %d is out of range", p.F.Header64.Shstrndx)
+		}
 		shstrtab, err := p.F.Sections64[p.F.Header64.Shstrndx].Data()
 		if err != nil {
-			panic("error reading the section header strings table " + err.Error())
+			return fmt.Errorf("error reading the section header strings table: %w", err)
 		}
 		for index, sh := range p.F.SectionHeaders64 {
 			SectionName, ok := getString(shstrtab, int(sh.Name))
 			if !ok {
-				panic("failed to parse string table")
+				return fmt.Errorf("section %d: invalid name offset 0x%x in the section header strings table", index, sh.Name)
 			}
 
 			fmt.Printf("  [%2d] %-24s %-15s %-.16x %-.6x %-.6x %-.2x %-24s %-2d %-3d %-2d\n",
@@ -160,6 +171,7 @@ func (p *Parser) DumpSectionHeaders() {
   L (link order), O (extra OS processing required), G (group), T (TLS),
   C (compressed), x (unknown), o (OS specific), E (exclude),
   l (large), p (processor specific)`)
+	return nil
 }
 
 /*
@@ -220,51 +232,14 @@ func (p *Parser) DumpProgramHeaders() {
  Section to Segment mapping:
   Segment Sections...`)
 	// 映射关系参考: <https://stackoverflow.com/questions/23018496/where-is-the-section-to-segment-mapping-stored-in-elf-files>
-	switch p.F.Ident.Class {
-	case ELFCLASS32:
-		shstrtab, err := p.F.Sections32[p.F.Header32.Shstrndx].Data()
-		if err != nil {
-			panic("error reading the section header strings table " + err.Error())
-		}
-		for index, ph := range p.F.ProgramHeaders32 {
-			phBeginAddr := ph.Vaddr
-			phEndAddr := ph.Vaddr + ph.Memsz
-			sectionInProgram := ""
-			for _, sh := range p.F.SectionHeaders32 {
-				// 夹逼准则，左闭右开
-				if phBeginAddr <= sh.Addr && sh.Addr < phEndAddr {
-					SectionName, ok := getString(shstrtab, int(sh.Name))
-					if !ok {
-						panic("failed to parse string table")
-					}
-					sectionInProgram += SectionName + " "
-				}
-			}
-			fmt.Printf("  %.2d %s \n", index, sectionInProgram)
+	// 仅比较sh.Addr会把Addr为0的非SHF_ALLOC节算进第一个段、把.tbss算进RW LOAD
+	// SegmentSections 实现了binutils ELF_SECTION_IN_SEGMENT的规则，与readelf -lW保持一致
+	for index, ph := range p.F.ProgramHeaders() {
+		fmt.Printf("   %.2d     ", index)
+		for _, s := range p.F.SegmentSections(ph) {
+			fmt.Printf("%s ", s.SectionName)
 		}
-	case ELFCLASS64:
-		shstrtab, err := p.F.Sections64[p.F.Header64.Shstrndx].Data()
-		if err != nil {
-			panic("error reading the section header strings table " + err.Error())
-		}
-		for index, ph := range p.F.ProgramHeaders64 {
-			phBeginAddr := ph.Vaddr
-			phEndAddr := ph.Vaddr + ph.Memsz
-			sectionInProgram := ""
-			for _, sh := range p.F.SectionHeaders64 {
-				// 夹逼准则，左闭右开
-				if phBeginAddr <= sh.Addr && sh.Addr < phEndAddr {
-					SectionName, ok := getString(shstrtab, int(sh.Name))
-					if !ok {
-						panic("failed to parse string table")
-					}
-					sectionInProgram += SectionName + " "
-				}
-			}
-			fmt.Printf("  %.2d %s \n", index, sectionInProgram)
-		}
-	default:
-		fmt.Println("Unkown type")
+		fmt.Println()
 	}
 }
 
@@ -305,67 +280,38 @@ Dynamic section at offset 0x209d8 contains 29 entries:
 */
 func (p *Parser) DumpDynamicSection() {
 	PrintSeparator()
-	switch p.F.Ident.Class {
-	case ELFCLASS32:
-		for _, ph := range p.F.ProgramHeaders32 {
-			fmt.Printf("  %-18s 0x%.8x 0x%.16x 0x%.16x 0x%.8x 0x%.8x %-10s 0x%x \n",
-				ProgType(ph.Type).String(), ph.Off, ph.Vaddr, ph.Paddr, ph.Filesz, ph.Memsz, ProgFlag(ph.Flags).String(), ph.Align)
-		}
-	case ELFCLASS64:
-		sc := p.F.Get64SectionByType(SHT_DYNAMIC)
-		if sc == nil {
-			fmt.Println("No dynamic section found!")
-			return
-		}
-		//fmt.Printf("%#v\n", sc)
-		// DT_NULL Marks the end of the _DYNAMIC array. 只有遇到DT_NULL才算是数据结束，因此entries数值需要遍历一遍得出
-		// https://stackoverflow.com/questions/48214977/how-to-find-the-number-of-entries-in-the-dynamic-section-of-an-elf-file
-		// https://docs.oracle.com/cd/E23824_01/html/819-0690/chapter6-42444.html
-		data, err := sc.Data()
-		if err != nil {
-			panic("cannot load symbol section")
-		}
-		dynamictable := bytes.NewReader(data)
-		dynamics := make([]ELF64DynamicTableEntry, sc.Size/sc.EntSize)
-		var dyn ELF64DynamicTableEntry
-		binary.Read(dynamictable, p.F.ByteOrder(), &dyn)
-		num := 0
-		for DT_NULL != DynTag(dyn.Tag) {
-			dynamics[num] = ELF64DynamicTableEntry{
-				Tag: dyn.Tag,
-				Val: dyn.Val,
-			}
-			num++
-			binary.Read(dynamictable, p.F.ByteOrder(), &dyn)
-		}
-		fmt.Printf("Dynamic section at offset 0x%x contains %d entries:\n", sc.Addr, num+1)
-		fmt.Println("  Tag            Type                         Name/Value")
-		var strTableAddr uint64
-		for _, dyentry := range dynamics {
-			if DynTag(dyentry.Tag) == DT_STRTAB {
-				strTableAddr = dyentry.Val
-			}
+	// 动态表在Parse阶段由ParseDynamic从PT_DYNAMIC段解析，ELF32/ELF64共用一份
+	// DT_NULL Marks the end of the _DYNAMIC array. 只有遇到DT_NULL才算是数据结束，因此entries数值需要遍历一遍得出
+	// https://stackoverflow.com/questions/48214977/how-to-find-the-number-of-entries-in-the-dynamic-section-of-an-elf-file
+	// https://docs.oracle.com/cd/E23824_01/html/819-0690/chapter6-42444.html
+	if len(p.F.DynamicEntries) == 0 {
+		fmt.Println("No dynamic section found!")
+		return
+	}
+	var offset uint64
+	for _, ph := range p.F.ProgramHeaders() {
+		if ProgType(ph.Type) == PT_DYNAMIC {
+			offset = ph.Off
 		}
-		_ = strTableAddr
-		for _, dynentry := range dynamics {
-			if DynTag(dynentry.Tag) == DT_NEEDED {
-				strTableAddr = 0
-				//str := ""
-				//binary.Read(strTableAddr, p.F.ByteOrder(), &str)
-				fmt.Printf("%-.16x %-28s %s\n", dynentry.Tag, DynTag(dynentry.Tag).String())
-			} else {
-				fmt.Printf("%-.16x %-28s 0x%.16x\n", dynentry.Tag, DynTag(dynentry.Tag).String(), dynentry.Val)
-			}
+	}
+	fmt.Printf("Dynamic section at offset 0x%x contains %d entries:\n", offset, len(p.F.DynamicEntries))
+	fmt.Println("  Tag            Type                         Name/Value")
+	needed := 0
+	for _, dynentry := range p.F.DynamicEntries {
+		if dynentry.Tag == DT_NEEDED && needed < len(p.F.Needed) {
+			// DT_NEEDED 的值是 DT_STRTAB 字符串表中的偏移，已经在ParseDynamic中解析为库名
+			fmt.Printf("%-.16x %-28s Shared library: [%s]\n", dynentry.Tag, dynentry.Tag.String(), p.F.Needed[needed])
+			needed++
+		} else {
+			fmt.Printf("%-.16x %-28s 0x%.16x\n", dynentry.Tag, dynentry.Tag.String(), dynentry.Val)
 		}
-		// DT_NEEDED： 表示一个列表，列表里面以（NEEDED）为标志的项，就是当前库加载时要依赖的其它库。注意 DT_NEEDED 中的 DT 不是 DON'T 的意思。
-		// DT_NEEDED 字段的含义依据于链接命令：如果该库以绝对路径链接，那么存储全路径;- 否则存储库名称(或者soname，如果soname被设置)
-		//   DT_NEEDED 这个元素保存着以NULL结尾的字符串表的偏移量，那些字符串是所需库的名字。
-		//    该偏移量是以DT_STRTAB  为入口的表的索引。看“Shared  Object  Dependencies”
-		//    关于那些名字的更多信息。动态数组可能包含了多个这个类型的入口。那些
-		//    入口的相关顺序是重要的，虽然它们跟其他入口的关系是不重要的。
-	default:
-		fmt.Println("Unkown type")
 	}
+	// DT_NEEDED： 表示一个列表，列表里面以（NEEDED）为标志的项，就是当前库加载时要依赖的其它库。注意 DT_NEEDED 中的 DT 不是 DON'T 的意思。
+	// DT_NEEDED 字段的含义依据于链接命令：如果该库以绝对路径链接，那么存储全路径;- 否则存储库名称(或者soname，如果soname被设置)
+	//   DT_NEEDED 这个元素保存着以NULL结尾的字符串表的偏移量，那些字符串是所需库的名字。
+	//    该偏移量是以DT_STRTAB  为入口的表的索引。看“Shared  Object  Dependencies”
+	//    关于那些名字的更多信息。动态数组可能包含了多个这个类型的入口。那些
+	//    入口的相关顺序是重要的，虽然它们跟其他入口的关系是不重要的。
 }
 
 /*
@@ -459,18 +405,19 @@ func (p *Parser) DumpSymbolTable() {
 	}
 }
 
-func (p *Parser) DumpRelaDynSection() {
+func (p *Parser) DumpRelaDynSection() error {
 	PrintSeparator()
 	switch p.F.Ident.Class {
 	case ELFCLASS32:
-		p.DumpRelaDynSection32()
+		return p.DumpRelaDynSection32()
 	case ELFCLASS64:
-		p.DumpRelaDynSection64()
+		return p.DumpRelaDynSection64()
 	}
+	return nil
 }
 
-func (p *Parser) DumpRelaDynSection32() {
-
+func (p *Parser) DumpRelaDynSection32() error {
+	return nil
 }
 
 /*
@@ -496,14 +443,21 @@ Relocation section '.rela.plt' at offset 0x2b08 contains 112 entries:
 0000000000220f70  0000007a00000007 R_X86_64_JUMP_SLOT     0000000000000000 __ctype_b_loc@GLIBC_2.3 + 0
 0000000000220f78  0000007c00000007 R_X86_64_JUMP_SLOT     0000000000000000 __sprintf_chk@GLIBC_2.3.4 + 0
 */
-func (p *Parser) DumpRelaDynSection64() {
+func (p *Parser) DumpRelaDynSection64() error {
 	sectionHeader := p.F.Get64SectionByName(".rela.dyn")
+	if sectionHeader == nil || sectionHeader.EntSize == 0 {
+		fmt.Println("No .rela.dyn section found!")
+		return nil
+	}
 	//fmt.Printf("%s\n", sectionHeader.HexDumpData())
 	//sectionData, err := sectionHeader.Data()
 	//if err != nil {
 	//	fmt.Printf(err.Error())
 	//}
 
+	if err := p.checkDumpRange(".rela.dyn", uint64(sectionHeader.Off), uint64(sectionHeader.Size)); err != nil {
+		return err
+	}
 	entryNum := sectionHeader.Size / sectionHeader.EntSize
 	fmt.Printf("Relocation section '.rela.dyn' at offset 0x%x contains %d entries:\n", sectionHeader.Off, entryNum)
 	// 数据结构
@@ -518,11 +472,11 @@ func (p *Parser) DumpRelaDynSection64() {
 		offset := int64(sectionHeader.Off) + int64(i)*int64(sectionHeader.EntSize)
 		_, err := p.fs.Seek(offset, io.SeekStart)
 		if err != nil {
-			panic(err.Error())
+			return fmt.Errorf(".rela.dyn entry %d: %w", i, err)
 		}
 		var sh Rela64
 		if err := binary.Read(p.fs, p.F.Ident.ByteOrder, &sh); err != nil {
-			panic(err.Error())
+			return fmt.Errorf(".rela.dyn entry %d: %w", i, err)
 		}
 		dataRela64[i] = sh
 	}
@@ -531,23 +485,29 @@ func (p *Parser) DumpRelaDynSection64() {
 		// 000000000021ff70  0000000000000008 R_X86_64_RELATIVE                         5f30
 		fmt.Printf("%.16x %.16x %s %x [%d]\n", entry.Off, entry.Info, ReloType(R_TYPE64(entry.Info)).String(), entry.Addend, index+1)
 	}
+	return nil
 }
 
-func (p *Parser) DumpRelaPltSection() {
+func (p *Parser) DumpRelaPltSection() error {
 	PrintSeparator()
 	switch p.F.Ident.Class {
 	case ELFCLASS32:
-		p.DumpRelaPltSection32()
+		return p.DumpRelaPltSection32()
 	case ELFCLASS64:
-		p.DumpRelaPltSection64()
+		return p.DumpRelaPltSection64()
 	}
+	return nil
 }
-func (p *Parser) DumpRelaPltSection32() {
-
+func (p *Parser) DumpRelaPltSection32() error {
+	return nil
 }
 
-func (p *Parser) DumpRelaPltSection64() {
+func (p *Parser) DumpRelaPltSection64() error {
 	sectionHeader := p.F.Get64SectionByName(".rela.plt")
+	if sectionHeader == nil || sectionHeader.EntSize == 0 {
+		fmt.Println("No .rela.plt section found!")
+		return nil
+	}
 	//fmt.Printf("%s\n", sectionHeader.HexDumpData())
 	//sectionData, err := sectionHeader.Data()
 	//if err != nil {
@@ -555,6 +515,9 @@ func (p *Parser) DumpRelaPltSection64() {
 	//}
 	//
 	//
+	if err := p.checkDumpRange(".rela.plt", uint64(sectionHeader.Off), uint64(sectionHeader.Size)); err != nil {
+		return err
+	}
 	entryNum := sectionHeader.Size / sectionHeader.EntSize
 	fmt.Printf(" Relocation section '.rela.plt' at offset 0x%x contains %d entries:\n", sectionHeader.Off, entryNum)
 	// 数据结构
@@ -569,11 +532,11 @@ func (p *Parser) DumpRelaPltSection64() {
 		offset := int64(sectionHeader.Off) + int64(i)*int64(sectionHeader.EntSize)
 		_, err := p.fs.Seek(offset, io.SeekStart)
 		if err != nil {
-			panic(err.Error())
+			return fmt.Errorf(".rela.plt entry %d: %w", i, err)
 		}
 		var sh Rela64
 		if err := binary.Read(p.fs, p.F.Ident.ByteOrder, &sh); err != nil {
-			panic(err.Error())
+			return fmt.Errorf(".rela.plt entry %d: %w", i, err)
 		}
 		dataRela64[i] = sh
 	}
@@ -582,19 +545,28 @@ func (p *Parser) DumpRelaPltSection64() {
 		// 000000000021ff70  0000000000000008 R_X86_64_RELATIVE                         5f30
 		fmt.Printf("%.16x %.16x %-21s   %.16x %x  [%d]\n", entry.Off, entry.Info, ReloType(R_TYPE64(entry.Info)).String(), 0, entry.Addend, index+1)
 	}
+	return nil
 }
 
-func (p *Parser) DumpGotSection() {
+func (p *Parser) DumpGotSection() error {
 	PrintSeparator()
 	switch p.F.Ident.Class {
 	case ELFCLASS32:
-		p.DumpGotSection32()
+		return p.DumpGotSection32()
 	case ELFCLASS64:
-		p.DumpGotSection64()
+		return p.DumpGotSection64()
 	}
+	return nil
 }
-func (p *Parser) DumpGotSection32() {
+func (p *Parser) DumpGotSection32() error {
 	sectionHeader := p.F.Get32SectionByName(".got")
+	if sectionHeader == nil || sectionHeader.EntSize == 0 {
+		fmt.Println("No .got section found!")
+		return nil
+	}
+	if err := p.checkDumpRange(".got", uint64(sectionHeader.Off), uint64(sectionHeader.Size)); err != nil {
+		return err
+	}
 	entryNum := sectionHeader.Size / sectionHeader.EntSize
 	fmt.Printf(" Relocation section '.got' at offset 0x%x contains %d entries:\n", sectionHeader.Off, entryNum)
 	dataRela32 := make([]uint32, entryNum)
@@ -602,11 +574,11 @@ func (p *Parser) DumpGotSection32() {
 		offset := int32(sectionHeader.Off) + int32(i)*int32(sectionHeader.EntSize)
 		_, err := p.fs.Seek(int64(offset), io.SeekStart)
 		if err != nil {
-			panic(err.Error())
+			return fmt.Errorf(".got entry %d: %w", i, err)
 		}
 		var sh uint32
 		if err := binary.Read(p.fs, p.F.Ident.ByteOrder, &sh); err != nil {
-			panic(err.Error())
+			return fmt.Errorf(".got entry %d: %w", i, err)
 		}
 		dataRela32[i] = sh
 	}
@@ -615,10 +587,18 @@ func (p *Parser) DumpGotSection32() {
 		// 000000000021ff70  0000000000000008 R_X86_64_RELATIVE                         5f30
 		fmt.Printf("%.8x[%d]\n", entry, index+1)
 	}
+	return nil
 }
 
-func (p *Parser) DumpGotSection64() {
+func (p *Parser) DumpGotSection64() error {
 	sectionHeader := p.F.Get64SectionByName(".got")
+	if sectionHeader == nil || sectionHeader.EntSize == 0 {
+		fmt.Println("No .got section found!")
+		return nil
+	}
+	if err := p.checkDumpRange(".got", uint64(sectionHeader.Off), uint64(sectionHeader.Size)); err != nil {
+		return err
+	}
 	entryNum := sectionHeader.Size / sectionHeader.EntSize
 	fmt.Printf(" Got section '.got' at offset 0x%x contains %d entries:\n", sectionHeader.Off, entryNum)
 	dataRela64 := make([]uint64, entryNum)
@@ -626,11 +606,11 @@ func (p *Parser) DumpGotSection64() {
 		offset := int64(sectionHeader.Off) + int64(i)*int64(sectionHeader.EntSize)
 		_, err := p.fs.Seek(offset, io.SeekStart)
 		if err != nil {
-			panic(err.Error())
+			return fmt.Errorf(".got entry %d: %w", i, err)
 		}
 		var sh uint64
 		if err := binary.Read(p.fs, p.F.Ident.ByteOrder, &sh); err != nil {
-			panic(err.Error())
+			return fmt.Errorf(".got entry %d: %w", i, err)
 		}
 		dataRela64[i] = sh
 	}
@@ -649,30 +629,44 @@ func (p *Parser) DumpGotSection64() {
 
 		}
 	}
+	return nil
 }
 
 
+// checkDumpRange reports a section whose contents do not fit in the file,
+// before its entries are read one by one.
+func (p *Parser) checkDumpRange(name string, off, size uint64) error {
+	if off > uint64(p.F.size) || size > uint64(p.F.size)-off {
+		return fmt.Errorf("section '%s' [0x%x, +0x%x) extends past the end of the file", name, off, size)
+	}
+	return nil
+}
+
 // .got Section 存放外部全局变量的 GOT 表，非延迟绑定
 // .got.plt Section 存放外部函数的 GOT 表，例如 printf，采用延迟绑定
 
-func (p *Parser) DumpGotPltSection() {
+func (p *Parser) DumpGotPltSection() error {
 	PrintSeparator()
 	switch p.F.Ident.Class {
 	case ELFCLASS32:
-		p.DumpGotPltSection32()
+		return p.DumpGotPltSection32()
 	case ELFCLASS64:
-		p.DumpGotPltSection64()
+		return p.DumpGotPltSection64()
 	}
+	return nil
 }
-func (p *Parser) DumpGotPltSection32() {
-
+func (p *Parser) DumpGotPltSection32() error {
+	return nil
 }
 
-func (p *Parser) DumpGotPltSection64() {
+func (p *Parser) DumpGotPltSection64() error {
 	sectionHeader := p.F.Get64SectionByName(".got.plt")
-	if nil == sectionHeader {
-		fmt.Println("No .git.plt section found!")
-		return
+	if nil == sectionHeader || sectionHeader.EntSize == 0 {
+		fmt.Println("No .got.plt section found!")
+		return nil
+	}
+	if err := p.checkDumpRange(".got.plt", uint64(sectionHeader.Off), uint64(sectionHeader.Size)); err != nil {
+		return err
 	}
 	entryNum := sectionHeader.Size / sectionHeader.EntSize
 	fmt.Printf(" Got section '.got.plt' at offset 0x%x contains %d entries:\n", sectionHeader.Off, entryNum)
@@ -681,11 +675,11 @@ func (p *Parser) DumpGotPltSection64() {
 		offset := int64(sectionHeader.Off) + int64(i)*int64(sectionHeader.EntSize)
 		_, err := p.fs.Seek(offset, io.SeekStart)
 		if err != nil {
-			panic(err.Error())
+			return fmt.Errorf(".got.plt entry %d: %w", i, err)
 		}
 		var sh uint64
 		if err := binary.Read(p.fs, p.F.Ident.ByteOrder, &sh); err != nil {
-			panic(err.Error())
+			return fmt.Errorf(".got.plt entry %d: %w", i, err)
 		}
 		dataRela64[i] = sh
 	}
@@ -703,4 +697,376 @@ func (p *Parser) DumpGotPltSection64() {
 
 		}
 	}
+	return nil
+}
+
+/*
+[root@rockylinux-ebpf ~/parser-elf/example]# readelf -rW gcc-amd64-linux-exec
+
+Relocation section '.rela.dyn' at offset 0x318 contains 1 entry:
+    Offset             Info             Type               Symbol's Value  Symbol's Name + Addend
+0000000000600858  0000000100000006 R_X86_64_GLOB_DAT      0000000000000000 __gmon_start__ + 0
+
+Relocation section '.rela.plt' at offset 0x330 contains 2 entries:
+    Offset             Info             Type               Symbol's Value  Symbol's Name + Addend
+0000000000600878  0000000200000007 R_X86_64_JUMP_SLOT     0000000000000000 puts@GLIBC_2.2.5 + 0
+0000000000600880  0000000300000007 R_X86_64_JUMP_SLOT     0000000000000000 __libc_start_main@GLIBC_2.2.5 + 0
+*/
+// DumpRelocations prints every SHT_REL/SHT_RELA section, the relocations
+// rebuilt from PT_DYNAMIC are printed when the section headers are gone.
+func (p *Parser) DumpRelocations() {
+	PrintSeparator()
+	sections := p.F.Sections()
+	found := false
+	for _, s := range sections {
+		typ := SectionType(s.Type)
+		if typ != SHT_REL && typ != SHT_RELA {
+			continue
+		}
+		found = true
+		relocs, err := p.SectionRelocations(s)
+		if err != nil {
+			fmt.Printf("Relocation section '%s': %s\n", s.SectionName, err.Error())
+			continue
+		}
+		// sh_link 指向重定位项引用的符号表
+		var symbols []Symbol
+		if s.Link != 0 && int(s.Link) < len(sections) {
+			symbols, _ = p.SectionSymbols(sections[s.Link])
+		}
+		fmt.Printf("\nRelocation section '%s' at offset 0x%x contains %d %s:\n", s.SectionName, s.Off, len(relocs), plural(len(relocs), "entry", "entries"))
+		p.dumpRelocationTable(relocs, symbols, typ == SHT_RELA)
+	}
+	if !found && p.F.FromSegments && len(p.F.DynRelocations) != 0 {
+		found = true
+		symbols, _ := p.Symbols(SHT_DYNSYM)
+		fmt.Printf("\nDynamic relocations rebuilt from PT_DYNAMIC contain %d %s:\n", len(p.F.DynRelocations), plural(len(p.F.DynRelocations), "entry", "entries"))
+		p.dumpRelocationTable(p.F.DynRelocations, symbols, p.F.DynRelocations[0].HasAddend)
+	}
+	if !found {
+		fmt.Println("There are no relocations in this file.")
+	}
+}
+
+// dumpRelocationTable prints a list of relocations resolving the symbol names through symbols.
+func (p *Parser) dumpRelocationTable(relocs []Relocation, symbols []Symbol, rela bool) {
+	width := 16
+	if p.F.Class() == ELFCLASS32 {
+		width = 8
+	}
+	switch {
+	case width == 16 && rela:
+		fmt.Println("    Offset             Info             Type               Symbol's Value  Symbol's Name + Addend")
+	case width == 16:
+		fmt.Println("    Offset             Info             Type               Symbol's Value  Symbol's Name")
+	case rela:
+		fmt.Println(" Offset     Info    Type                Sym. Value  Symbol's Name + Addend")
+	default:
+		fmt.Println(" Offset     Info    Type                Sym. Value  Symbol's Name")
+	}
+	for _, r := range relocs {
+		fmt.Printf("%.*x  %.*x %-22s", width, r.Off, width, r.Info, p.F.RelocTypeString(r.Type))
+		if r.Sym != 0 && int(r.Sym) < len(symbols) {
+			sym := symbols[r.Sym]
+			name := sym.Name
+			if name == "" && ST_TYPE(sym.Info) == STT_SECTION && int(sym.Index) < len(p.F.Sections()) {
+				name = p.F.Sections()[sym.Index].SectionName
+			}
+			if sym.Version != "" {
+				name += "@" + sym.Version
+			}
+			fmt.Printf(" %.*x %s", width, sym.Value, name)
+			if rela {
+				if r.Addend < 0 {
+					fmt.Printf(" - %x", -r.Addend)
+				} else {
+					fmt.Printf(" + %x", r.Addend)
+				}
+			}
+		} else if rela {
+			fmt.Printf(" %*s %x", width, "", r.Addend)
+		}
+		fmt.Println()
+	}
+}
+
+// DumpSymbols prints the dynamic symbol table and, unless dynOnly is set,
+// the static symbol table (.symtab).
+func (p *Parser) DumpSymbols(dynOnly bool) {
+	PrintSeparator()
+	tables := []SectionType{SHT_DYNSYM}
+	if !dynOnly {
+		tables = append(tables, SHT_SYMTAB)
+	}
+	for _, typ := range tables {
+		symbols, err := p.Symbols(typ)
+		if err != nil {
+			continue
+		}
+		name := ".dynsym"
+		if s := p.F.SectionByType(typ); s != nil {
+			name = s.SectionName
+		}
+		fmt.Printf("\nSymbol table '%s' contains %d %s:\n", name, len(symbols), plural(len(symbols), "entry", "entries"))
+		fmt.Println("   Num:    Value          Size Type    Bind   Vis      Ndx Name")
+		for index, sym := range symbols {
+			symName := sym.Name
+			if sym.Version != "" {
+				symName += "@" + sym.Version
+			}
+			fmt.Printf("%6d: %.16x %5d %-7s %-6s %-8s %3s %s\n",
+				index,
+				sym.Value,
+				sym.Size,
+				ST_TYPE(sym.Info).ShortString(),
+				ST_BIND(sym.Info).ShortString(),
+				ST_VISIBILITY(sym.Other).ShortString(),
+				sym.Index.ShortString(),
+				symName,
+			)
+		}
+	}
+}
+
+/*
+[root@rockylinux-ebpf ~/parser-elf/src/go]# readelf -nW ls
+
+Displaying notes found in: .note.gnu.property
+  Owner                Data size 	Description
+  GNU                  0x00000010	NT_GNU_PROPERTY_TYPE_0
+*/
+// DumpNotes prints the notes of the file.
+func (p *Parser) DumpNotes() {
+	PrintSeparator()
+	notes, err := p.Notes()
+	if err != nil {
+		fmt.Println("Notes: " + err.Error())
+	}
+	current := "\x00"
+	for _, n := range notes {
+		if n.Section != current {
+			current = n.Section
+			if current != "" {
+				fmt.Printf("\nDisplaying notes found in: %s\n", current)
+			} else {
+				fmt.Printf("\nDisplaying notes found at file offset 0x%.8x\n", n.Offset)
+			}
+			fmt.Println("  Owner                Data size \tDescription")
+		}
+		fmt.Printf("  %-20s 0x%.8x\t%s\n", n.Name, len(n.Desc), n.TypeString())
+		if n.Name == "GNU" && n.Type == NT_GNU_BUILD_ID {
+			fmt.Printf("    Build ID: %x\n", n.Desc)
+		}
+	}
+}
+
+// DumpVersionInfo prints the version sections (.gnu.version, .gnu.version_d, .gnu.version_r).
+func (p *Parser) DumpVersionInfo() {
+	PrintSeparator()
+	printed := false
+	if versym, err := p.VersionSymbols(); err == nil {
+		printed = true
+		fmt.Printf("\nVersion symbols section '.gnu.version' contains %d %s:\n", len(versym), plural(len(versym), "entry", "entries"))
+		names := p.versionNames()
+		for i, v := range versym {
+			if i%4 == 0 {
+				if i != 0 {
+					fmt.Println()
+				}
+				fmt.Printf("  %03x:", i)
+			}
+			idx := v & 0x7fff
+			hidden := " "
+			if v&0x8000 != 0 {
+				hidden = "h"
+			}
+			switch idx {
+			case 0:
+				fmt.Printf("   0 (*local*)    ")
+			case 1:
+				fmt.Printf("   1 (*global*)   ")
+			default:
+				fmt.Printf(" %3x%s%-13s", idx, hidden, "("+names[idx]+")")
+			}
+		}
+		fmt.Println()
+	}
+	if defs, err := p.VersionDefs(); err == nil {
+		printed = true
+		fmt.Printf("\nVersion definition section '.gnu.version_d' contains %d %s:\n", len(defs), plural(len(defs), "entry", "entries"))
+		for _, d := range defs {
+			name := ""
+			if len(d.Names) != 0 {
+				name = d.Names[0]
+			}
+			fmt.Printf("  0x%04x: Rev: %d  Flags: %s  Index: %d  Cnt: %d  Name: %s\n", d.Offset, d.Version, versionFlags(d.Flags), d.Index, len(d.Names), name)
+			for i := 1; i < len(d.Names); i++ {
+				fmt.Printf("  0x%04x: Parent %d: %s\n", d.AuxOffsets[i], i, d.Names[i])
+			}
+		}
+	}
+	if needs, err := p.VersionNeeds(); err == nil {
+		printed = true
+		fmt.Printf("\nVersion needs section '.gnu.version_r' contains %d %s:\n", len(needs), plural(len(needs), "entry", "entries"))
+		for _, n := range needs {
+			fmt.Printf(" 0x%04x: Version: %d  File: %s  Cnt: %d\n", n.Offset, n.Version, n.File, len(n.Aux))
+			for _, a := range n.Aux {
+				fmt.Printf("  0x%04x:   Name: %s  Flags: %s  Version: %d\n", a.Offset, a.Name, versionFlags(a.Flags), a.Other)
+			}
+		}
+	}
+	if !printed {
+		fmt.Println("No version information found in this file.")
+	}
+}
+
+// versionNames maps version indexes to their names.
+func (p *Parser) versionNames() map[uint16]string {
+	names := make(map[uint16]string)
+	if defs, err := p.VersionDefs(); err == nil {
+		for _, d := range defs {
+			if len(d.Names) != 0 {
+				names[d.Index] = d.Names[0]
+			}
+		}
+	}
+	if needs, err := p.VersionNeeds(); err == nil {
+		for _, n := range needs {
+			for _, a := range n.Aux {
+				names[a.Other] = a.Name
+			}
+		}
+	}
+	return names
+}
+
+// versionFlags renders VER_FLG_* bits.
+func versionFlags(flags uint16) string {
+	if flags == 0 {
+		return "none"
+	}
+	var s []string
+	if flags&0x1 != 0 {
+		s = append(s, "BASE")
+	}
+	if flags&0x2 != 0 {
+		s = append(s, "WEAK")
+	}
+	if flags&0x4 != 0 {
+		s = append(s, "INFO")
+	}
+	if flags&^0x7 != 0 {
+		s = append(s, "<unknown>")
+	}
+	return strings.Join(s, " | ")
+}
+
+/*
+[root@rockylinux-ebpf ~/parser-elf/example]# readelf -x .interp gcc-amd64-linux-exec
+
+Hex dump of section '.interp':
+  0x00400200 2f6c6962 36342f6c 642d6c69 6e75782d /lib64/ld-linux-
+  0x00400210 7838362d 36342e73 6f2e3200          x86-64.so.2.
+*/
+// DumpHexSection prints the content of a section as hex bytes (-x).
+func (p *Parser) DumpHexSection(arg string) error {
+	i, err := p.LookupSection(arg)
+	if err != nil {
+		return err
+	}
+	return p.WriteHexDump(os.Stdout, i, DumpOptions{})
+}
+
+/*
+[root@rockylinux-ebpf ~/parser-elf/example]# readelf -p .comment gcc-amd64-linux-exec
+
+String dump of section '.comment':
+  [     1]  GCC: (GNU) 4.1.0 (SUSE Linux)
+*/
+// DumpStringSection prints the printable strings of a section (-p).
+func (p *Parser) DumpStringSection(arg string) error {
+	i, err := p.LookupSection(arg)
+	if err != nil {
+		return err
+	}
+	return p.WriteStringDump(os.Stdout, i, DumpOptions{})
+}
+
+// DumpArchSpecific prints the build attribute sections (-A).
+func (p *Parser) DumpArchSpecific() {
+	PrintSeparator()
+	attrs, err := p.BuildAttributes()
+	if err != nil {
+		fmt.Println("Attributes: " + err.Error())
+	}
+	if len(attrs) == 0 {
+		fmt.Println("No processor specific information found in this file.")
+		return
+	}
+	for _, a := range attrs {
+		fmt.Printf("Attribute Section: %s (%s)\n", a.Vendor, a.Section)
+		fmt.Println("File Attributes")
+		for _, t := range a.Tags {
+			if t.IsString {
+				fmt.Printf("  Tag_unknown_%d: \"%s\"\n", t.Tag, t.String)
+			} else {
+				fmt.Printf("  Tag_unknown_%d: %d (0x%x)\n", t.Tag, t.Value, t.Value)
+			}
+		}
+	}
+}
+
+/*
+[root@rockylinux-ebpf ~/parser-elf/src/go]# readelf -I ls
+
+Histogram for `.gnu.hash' bucket list length (total of 2 buckets):
+ Length  Number     % of total  Coverage
+      0  1          ( 50.0%)
+      1  1          ( 50.0%)    100.0%
+*/
+// DumpHistogram prints the bucket list length histogram of the hash tables (-I).
+func (p *Parser) DumpHistogram() {
+	PrintSeparator()
+	tables, err := p.HashTables()
+	if err != nil {
+		fmt.Println("Hash table: " + err.Error())
+	}
+	for _, t := range tables {
+		fmt.Printf("\nHistogram for `%s' bucket list length (total of %d %s):\n", t.Section, len(t.BucketLengths), plural(len(t.BucketLengths), "bucket", "buckets"))
+		maxLength := 0
+		for _, l := range t.BucketLengths {
+			if l > maxLength {
+				maxLength = l
+			}
+		}
+		counts := make([]int, maxLength+1)
+		for _, l := range t.BucketLengths {
+			counts[l]++
+		}
+		fmt.Println(" Length  Number     % of total  Coverage")
+		covered := 0
+		for length, n := range counts {
+			percent := 0.0
+			if len(t.BucketLengths) != 0 {
+				percent = float64(n) * 100 / float64(len(t.BucketLengths))
+			}
+			fmt.Printf("%7d  %-10d (%5.1f%%)", length, n, percent)
+			if length != 0 && t.Symbols != 0 {
+				covered += length * n
+				fmt.Printf("    %5.1f%%", float64(covered)*100/float64(t.Symbols))
+			}
+			fmt.Println()
+		}
+	}
+	if len(tables) == 0 {
+		fmt.Println("No hash table found in this file.")
+	}
+}
+
+// plural picks the singular or plural form of a word for n items.
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
 }
@@ -0,0 +1,1282 @@
+// Package elf : readelf_compat.go prints the file the way GNU readelf 2.38
+// and later does, byte for byte, for the -h -S -l -d -s -r -n -V views.
+// The Dump* functions of readelf.go keep their own, friendlier layout.
+package elf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ReadelfOptions selects the views printed by WriteReadelf, each field
+// matches the readelf option given in its comment.
+type ReadelfOptions struct {
+	FileHeader     bool // -h
+	SectionHeaders bool // -S
+	ProgramHeaders bool // -l
+	Dynamic        bool // -d
+	Symbols        bool // -s
+	DynSyms        bool // --dyn-syms
+	Relocs         bool // -r
+	Notes          bool // -n
+	VersionInfo    bool // -V
+	Wide           bool // -W
+}
+
+// readelfWriter holds what the views share while the output is produced.
+type readelfWriter struct {
+	p        *Parser
+	w        io.Writer
+	opts     ReadelfOptions
+	hdr      ELF64Header
+	is32     bool
+	sections []*ELF64Section
+	// dyn是动态表中DT_NULL之前(含)的表项，dynOff为其文件偏移
+	dyn    []DynamicEntry
+	dynOff uint64
+	dynstr []byte
+	interp string
+	err    error
+}
+
+// rawSymbol is a symbol together with its st_name offset, readelf tells
+// section symbols and version definitions apart with it.
+type rawSymbol struct {
+	Symbol
+	nameOff uint32
+}
+
+const (
+	symPublic = iota
+	symHidden
+	symUndefined
+)
+
+// WriteReadelf writes the requested views in the order and the format
+// used by readelf, the first write error is returned.
+func (p *Parser) WriteReadelf(w io.Writer, opts ReadelfOptions) error {
+	r := &readelfWriter{p: p, w: w, opts: opts, hdr: p.F.rawHeader(), is32: p.F.Class() == ELFCLASS32}
+	r.sections = p.F.Sections()
+	r.loadDynamic()
+	if opts.FileHeader {
+		r.fileHeader()
+	}
+	if opts.SectionHeaders {
+		r.sectionHeaders()
+	}
+	if opts.ProgramHeaders {
+		r.programHeaders()
+	}
+	if opts.Dynamic {
+		r.dynamic()
+	}
+	if opts.Relocs {
+		r.relocations()
+	}
+	if opts.Symbols || opts.DynSyms {
+		r.symbols()
+	}
+	if opts.VersionInfo {
+		r.versions()
+	}
+	if opts.Notes {
+		r.notes()
+	}
+	return r.err
+}
+
+func (r *readelfWriter) printf(format string, a ...interface{}) {
+	if r.err != nil {
+		return
+	}
+	_, r.err = fmt.Fprintf(r.w, format, a...)
+}
+
+// plural picks the singular form when n is one, like ngettext does.
+func (r *readelfWriter) plural(n uint64, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// longHex is the LONG_HEX mode of readelf's print_vma.
+func (r *readelfWriter) longHex(v uint64) string {
+	if r.is32 {
+		return fmt.Sprintf("%8.8x", uint32(v))
+	}
+	return fmt.Sprintf("%16.16x", v)
+}
+
+// altHex formats v like the C "%#x" conversion, which prints 0 without prefix.
+func altHex(v uint64) string {
+	if v == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("%#x", v)
+}
+
+// printSymbol mimics readelf's print_symbol: a negative width pads the name
+// with spaces, outside of wide mode names longer than the width are cut and
+// end with "[...]". Control characters are shown as ^X.
+func (r *readelfWriter) printSymbol(width int, name string) {
+	pad := false
+	if width < 0 {
+		width, pad = -width, true
+	} else if width == 0 {
+		return
+	}
+	remaining := int(^uint(0) >> 1)
+	dots := false
+	if !r.opts.Wide {
+		remaining = width
+		if len(name) > width {
+			remaining -= 5
+			if remaining < 0 {
+				remaining = 0
+			}
+			dots = true
+		}
+	}
+	var b strings.Builder
+	printed := 0
+	for i := 0; i < len(name) && remaining > 0; i++ {
+		c := name[i]
+		if c < 0x20 || c == 0x7f {
+			if remaining < 2 {
+				break
+			}
+			b.WriteByte('^')
+			b.WriteByte(c + 0x40)
+			remaining -= 2
+			printed += 2
+			continue
+		}
+		b.WriteByte(c)
+		remaining--
+		printed++
+	}
+	if dots {
+		b.WriteString("[...]")
+		printed += 5
+	}
+	if pad && printed < width {
+		b.WriteString(strings.Repeat(" ", width-printed))
+	}
+	r.printf("%s", b.String())
+}
+
+// printableName escapes the control characters of a section name.
+func printableName(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if c := name[i]; c < 0x20 || c == 0x7f {
+			b.WriteByte('^')
+			b.WriteByte(c + 0x40)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func (r *readelfWriter) osabi() uint8 { return r.hdr.Ident[7] }
+
+// sectionName returns the printable name of the section at index i.
+func (r *readelfWriter) sectionName(i uint32) string {
+	if int(i) >= len(r.sections) {
+		return "<corrupt>"
+	}
+	return printableName(r.sections[i].SectionName)
+}
+
+// loadDynamic locates the dynamic table like readelf: the PT_DYNAMIC
+// segment gives a first guess which is refined by the .dynamic section.
+func (r *readelfWriter) loadDynamic() {
+	var off, size uint64
+	found := false
+	for _, ph := range r.p.F.ProgramHeaders() {
+		switch ProgType(ph.Type) {
+		case PT_DYNAMIC:
+			if found {
+				continue
+			}
+			found = true
+			off, size = ph.Off, ph.Filesz
+			if len(r.sections) == 0 {
+				continue
+			}
+			s := r.p.F.SectionByName(".dynamic")
+			if s == nil || s.Size == 0 {
+				continue
+			}
+			if SectionType(s.Type) == SHT_NOBITS {
+				size = 0
+				continue
+			}
+			off, size = s.Off, s.Size
+		case PT_INTERP:
+			if ph.Filesz == 0 || ph.Off >= uint64(r.p.F.size) || ph.Filesz > uint64(r.p.F.size)-ph.Off {
+				continue
+			}
+			data := make([]byte, ph.Filesz)
+			n, _ := r.p.fs.ReadAt(data, int64(ph.Off))
+			// readelf用fscanf("%s")读取，遇到空白字符即停止
+			data = data[:n]
+			if i := bytes.IndexAny(data, "\x00 \t\n\v\f\r"); i >= 0 {
+				data = data[:i]
+			}
+			r.interp = string(data)
+		}
+	}
+	if size == 0 || off >= uint64(r.p.F.size) {
+		return
+	}
+	if off+size > uint64(r.p.F.size) {
+		size = uint64(r.p.F.size) - off
+	}
+	data := make([]byte, size)
+	n, _ := r.p.fs.ReadAt(data, int64(off))
+	data = data[:n]
+	entSize := 16
+	if r.is32 {
+		entSize = 8
+	}
+	bo := r.p.F.ByteOrder()
+	for i := 0; i+entSize <= len(data); i += entSize {
+		var d DynamicEntry
+		if r.is32 {
+			d = DynamicEntry{Tag: DynTag(int32(bo.Uint32(data[i:]))), Val: uint64(bo.Uint32(data[i+4:]))}
+		} else {
+			d = DynamicEntry{Tag: DynTag(bo.Uint64(data[i:])), Val: bo.Uint64(data[i+8:])}
+		}
+		r.dyn = append(r.dyn, d)
+		if d.Tag == DT_NULL {
+			break
+		}
+	}
+	r.dynOff = off
+	strtab, ok1 := r.dynValue(DT_STRTAB)
+	strsz, ok2 := r.dynValue(DT_STRSZ)
+	if ok1 && ok2 {
+		r.dynstr, _ = r.p.F.ReadVirtual(strtab, int(strsz))
+	}
+}
+
+func (r *readelfWriter) dynValue(tag DynTag) (uint64, bool) {
+	for _, d := range r.dyn {
+		if d.Tag == tag {
+			return d.Val, true
+		}
+	}
+	return 0, false
+}
+
+func (r *readelfWriter) isPIE() bool {
+	flags, _ := r.dynValue(DT_FLAGS_1)
+	return flags&0x08000000 != 0
+}
+
+func (r *readelfWriter) fileHeader() {
+	h := r.hdr
+	r.printf("ELF Header:\n  Magic:   ")
+	for _, b := range h.Ident {
+		r.printf("%2.2x ", b)
+	}
+	r.printf("\n")
+	class := map[uint8]string{0: "none", 1: "ELF32", 2: "ELF64"}[h.Ident[4]]
+	if h.Ident[4] > 2 {
+		class = fmt.Sprintf("<unknown: %x>", h.Ident[4])
+	}
+	data := map[uint8]string{0: "none", 1: "2's complement, little endian", 2: "2's complement, big endian"}[h.Ident[5]]
+	if h.Ident[5] > 2 {
+		data = fmt.Sprintf("<unknown: %x>", h.Ident[5])
+	}
+	version := ""
+	if h.Ident[6] == 1 {
+		version = " (current)"
+	} else if h.Ident[6] != 0 {
+		version = " <unknown>"
+	}
+	osabi, ok := readelfOSABINames[h.Ident[7]]
+	if !ok {
+		osabi = fmt.Sprintf("<unknown: %x>", h.Ident[7])
+	}
+	r.printf("  Class:                             %s\n", class)
+	r.printf("  Data:                              %s\n", data)
+	r.printf("  Version:                           %d%s\n", h.Ident[6], version)
+	r.printf("  OS/ABI:                            %s\n", osabi)
+	r.printf("  ABI Version:                       %d\n", h.Ident[8])
+	r.printf("  Type:                              %s\n", readelfFileType(h.Type, r.isPIE()))
+	r.printf("  Machine:                           %s\n", readelfMachineName(h.Machine))
+	r.printf("  Version:                           0x%x\n", h.Version)
+	r.printf("  Entry point address:               0x%x\n", h.Entry)
+	r.printf("  Start of program headers:          %d (bytes into file)\n", h.Phoff)
+	r.printf("  Start of section headers:          %d (bytes into file)\n", h.Shoff)
+	r.printf("  Flags:                             0x%x%s\n", h.Flags, readelfMachineFlags(h.Machine, h.Flags))
+	r.printf("  Size of this header:               %d (bytes)\n", h.Ehsize)
+	r.printf("  Size of program headers:           %d (bytes)\n", h.Phentsize)
+	r.printf("  Number of program headers:         %d\n", h.Phnum)
+	r.printf("  Size of section headers:           %d (bytes)\n", h.Shentsize)
+	r.printf("  Number of section headers:         %d\n", h.Shnum)
+	r.printf("  Section header string table index: %d", h.Shstrndx)
+	if h.Shstrndx != 0 && h.Shstrndx >= h.Shnum {
+		r.printf(" <corrupt: out of range>")
+	}
+	r.printf("\n")
+}
+
+func (r *readelfWriter) sectionHeaders() {
+	if len(r.sections) == 0 {
+		r.printf("\nThere are no sections in this file.\n")
+		return
+	}
+	if !r.opts.FileHeader {
+		r.printf("There %s %d section %s, starting at offset 0x%x:\n",
+			r.plural(uint64(len(r.sections)), "is", "are"), len(r.sections),
+			r.plural(uint64(len(r.sections)), "header", "headers"), r.hdr.Shoff)
+	}
+	r.printf("\nSection %s:\n", r.plural(uint64(len(r.sections)), "Header", "Headers"))
+	switch {
+	case r.is32:
+		r.printf("  [Nr] Name              Type            Addr     Off    Size   ES Flg Lk Inf Al\n")
+	case r.opts.Wide:
+		r.printf("  [Nr] Name              Type            Address          Off    Size   ES Flg Lk Inf Al\n")
+	default:
+		r.printf("  [Nr] Name              Type             Address           Offset\n")
+		r.printf("       Size              EntSize          Flags  Link  Info  Align\n")
+	}
+	typeFormat := " %-15.15s "
+	if r.opts.Wide {
+		typeFormat = " %-15s "
+	}
+	for i, s := range r.sections {
+		sh := s.ELF64SectionHeader
+		r.printf("  [%2d] ", i)
+		r.printSymbol(-17, printableName(s.SectionName))
+		r.printf(typeFormat, readelfSectionType(r.hdr.Machine, sh.Type))
+		flags := readelfSectionFlags(r.hdr.Machine, r.osabi(), sh.Flags)
+		switch {
+		case r.is32:
+			r.printf("%s %6.6x %6.6x %2.2x %3s %2d %3d %2d\n", r.longHex(sh.Addr), sh.Off, sh.Size,
+				sh.EntSize, flags, sh.Link, sh.Info, sh.AddrAlign)
+		case r.opts.Wide:
+			r.printf("%s %6.6x %6.6x %2.2x %3s %2d %3d %2d\n", r.longHex(sh.Addr), sh.Off, sh.Size,
+				sh.EntSize, flags, sh.Link, sh.Info, sh.AddrAlign)
+		default:
+			r.printf(" %s  %8.8x\n       %s  %s %3s      %2d   %3d     %d\n", r.longHex(sh.Addr), sh.Off,
+				r.longHex(sh.Size), r.longHex(sh.EntSize), flags, sh.Link, sh.Info, sh.AddrAlign)
+		}
+	}
+	r.printf("Key to Flags:\n" +
+		"  W (write), A (alloc), X (execute), M (merge), S (strings), I (info),\n" +
+		"  L (link order), O (extra OS processing required), G (group), T (TLS),\n" +
+		"  C (compressed), x (unknown), o (OS specific), E (exclude),\n  ")
+	osabi := OSABI(r.osabi())
+	if osabi == ELFOSABI_LINUX || osabi == ELFOSABI_FREEBSD {
+		r.printf("R (retain), ")
+	}
+	if osabi == ELFOSABI_LINUX || osabi == ELFOSABI_FREEBSD || osabi == ELFOSABI_NONE {
+		r.printf("D (mbind), ")
+	}
+	switch Machine(r.hdr.Machine) {
+	case EM_X86_64:
+		r.printf("l (large), ")
+	case EM_ARM:
+		r.printf("y (purecode), ")
+	case EM_PPC:
+		r.printf("v (VLE), ")
+	}
+	r.printf("p (processor specific)\n")
+}
+
+func (r *readelfWriter) programHeaders() {
+	phs := r.p.F.ProgramHeaders()
+	if len(phs) == 0 {
+		r.printf("\nThere are no program headers in this file.\n")
+		return
+	}
+	if !r.opts.FileHeader {
+		r.printf("\nElf file type is %s\n", readelfFileType(r.hdr.Type, r.isPIE()))
+		r.printf("Entry point 0x%x\n", r.hdr.Entry)
+		r.printf("There %s %d program %s, starting at offset %d\n",
+			r.plural(uint64(len(phs)), "is", "are"), len(phs),
+			r.plural(uint64(len(phs)), "header", "headers"), r.hdr.Phoff)
+	}
+	r.printf("\nProgram %s:\n", r.plural(uint64(len(phs)), "Header", "Headers"))
+	switch {
+	case r.is32:
+		r.printf("  Type           Offset   VirtAddr   PhysAddr   FileSiz MemSiz  Flg Align\n")
+	case r.opts.Wide:
+		r.printf("  Type           Offset   VirtAddr           PhysAddr           FileSiz  MemSiz   Flg Align\n")
+	default:
+		r.printf("  Type           Offset             VirtAddr           PhysAddr\n")
+		r.printf("                 FileSiz            MemSiz              Flags  Align\n")
+	}
+	for _, ph := range phs {
+		r.printf("  %-14.14s ", readelfSegmentType(r.hdr.Machine, ph.Type))
+		flags := []byte{' ', ' ', ' '}
+		if ph.Flags&uint32(PF_R) != 0 {
+			flags[0] = 'R'
+		}
+		if ph.Flags&uint32(PF_W) != 0 {
+			flags[1] = 'W'
+		}
+		if ph.Flags&uint32(PF_X) != 0 {
+			flags[2] = 'E'
+		}
+		switch {
+		case r.is32:
+			r.printf("0x%6.6x 0x%8.8x 0x%8.8x 0x%5.5x 0x%5.5x %s %s\n", ph.Off, ph.Vaddr, ph.Paddr,
+				ph.Filesz, ph.Memsz, flags, altHex(ph.Align))
+		case r.opts.Wide:
+			r.printf("0x%6.6x 0x%16.16x 0x%16.16x 0x%6.6x 0x%6.6x %s %s\n", ph.Off, ph.Vaddr, ph.Paddr,
+				ph.Filesz, ph.Memsz, flags, altHex(ph.Align))
+		default:
+			r.printf("0x%16.16x 0x%16.16x 0x%16.16x\n                 0x%16.16x 0x%16.16x  %s    0x%x\n",
+				ph.Off, ph.Vaddr, ph.Paddr, ph.Filesz, ph.Memsz, flags, ph.Align)
+		}
+		if ProgType(ph.Type) == PT_INTERP && r.interp != "" {
+			r.printf("      [Requesting program interpreter: %s]\n", r.interp)
+		}
+	}
+	if len(r.sections) == 0 {
+		return
+	}
+	r.printf("\n Section to Segment mapping:\n  Segment Sections...\n")
+	for i, ph := range phs {
+		r.printf("   %2.2d     ", i)
+		for _, s := range r.p.F.SegmentSections(ph) {
+			r.printf("%s ", printableName(s.SectionName))
+		}
+		r.printf("\n")
+	}
+}
+
+// dynamicName returns the string at offset off of the dynamic string table.
+func (r *readelfWriter) dynamicName(off uint64) (string, bool) {
+	if off >= uint64(len(r.dynstr)) {
+		return "", false
+	}
+	return getString(r.dynstr, int(off))
+}
+
+func (r *readelfWriter) dynamic() {
+	if len(r.dyn) == 0 {
+		r.printf("\nThere is no dynamic section in this file.\n")
+		return
+	}
+	n := uint64(len(r.dyn))
+	r.printf("\nDynamic section at offset 0x%x contains %d %s:\n", r.dynOff, n, r.plural(n, "entry", "entries"))
+	r.printf("  Tag        Type                         Name/Value\n")
+	width := 19
+	if r.is32 {
+		width = 27
+	}
+	for _, d := range r.dyn {
+		name := readelfDynTag(r.hdr.Machine, uint64(d.Tag))
+		if r.is32 {
+			r.printf(" 0x%8.8x", uint32(d.Tag))
+		} else {
+			r.printf(" 0x%16.16x", uint64(d.Tag))
+		}
+		// 与C的"%*s"一致：宽度为负时左对齐，至少输出一个空格
+		pad := width - len(name)
+		if pad < 0 {
+			pad = -pad
+		}
+		if pad < 1 {
+			pad = 1
+		}
+		r.printf(" (%s)%s", name, strings.Repeat(" ", pad))
+		r.printf("%s\n", r.dynamicValue(d))
+	}
+}
+
+// dynamicValue formats the Name/Value column of a dynamic entry.
+func (r *readelfWriter) dynamicValue(d DynamicEntry) string {
+	switch d.Tag {
+	case DT_NEEDED, DT_SONAME, DT_RPATH, DT_RUNPATH:
+		name, ok := r.dynamicName(d.Val)
+		if !ok {
+			return fmt.Sprintf("0x%x", d.Val)
+		}
+		switch d.Tag {
+		case DT_NEEDED:
+			if r.interp != "" && name == r.interp {
+				return "Shared library: [" + name + "] program interpreter"
+			}
+			return "Shared library: [" + name + "]"
+		case DT_SONAME:
+			return "Library soname: [" + name + "]"
+		case DT_RPATH:
+			return "Library rpath: [" + name + "]"
+		}
+		return "Library runpath: [" + name + "]"
+	case DT_FLAGS:
+		return readelfDynFlags(d.Val)
+	case DT_FLAGS_1:
+		return "Flags:" + readelfFlags(d.Val, readelfDF1Names)
+	case DT_PLTREL:
+		return readelfDynTag(r.hdr.Machine, d.Val)
+	case DT_BIND_NOW:
+		return ""
+	}
+	switch uint64(d.Tag) {
+	case 2, 8, 10, 18, 9, 37, 35, 11, 19, 0x6ffffdf9, 0x6ffffdfa, 0x6ffffdfb, 33, 27, 28, 0x6ffffdf6, 0x6ffffdf7:
+		return fmt.Sprintf("%d (bytes)", d.Val)
+	case 0x6ffffffd, 0x6fffffff, 0x6ffffff9, 0x6ffffffa:
+		return fmt.Sprintf("%d", d.Val)
+	case 0x6ffffdfd:
+		return "Flags:" + readelfFlags(d.Val, []string{"LAZY", "GROUPPERM"})
+	case 0x6ffffdfc:
+		return "Flags:" + readelfFlags(d.Val, []string{"PARINIT", "CONFEXP"})
+	case 0x7ffffffd, 0x7fffffff, 0x6ffffefa, 0x6ffffefb, 0x6ffffefc:
+		label := map[uint64]string{0x7ffffffd: "Auxiliary library", 0x7fffffff: "Filter library",
+			0x6ffffefa: "Configuration file", 0x6ffffefb: "Dependency audit library", 0x6ffffefc: "Audit library"}[uint64(d.Tag)]
+		if name, ok := r.dynamicName(d.Val); ok {
+			return label + ": [" + name + "]"
+		}
+		return fmt.Sprintf("%s: 0x%x", label, d.Val)
+	case 0x7ffffffe:
+		if name, ok := r.dynamicName(d.Val); ok {
+			return "Not needed object: [" + name + "]"
+		}
+	}
+	return fmt.Sprintf("0x%x", d.Val)
+}
+
+// readSymbols decodes the symbol table held by s with the st_name offsets.
+func (r *readelfWriter) readSymbols(s *ELF64Section) ([]rawSymbol, []byte) {
+	data, err := s.Data()
+	if err != nil {
+		return nil, nil
+	}
+	strtab, _ := r.p.F.stringTable(s.Link)
+	bo := r.p.F.ByteOrder()
+	size := Sym64Size
+	if r.is32 {
+		size = Sym32Size
+	}
+	var syms []rawSymbol
+	for rd := bytes.NewReader(data); rd.Len() >= size; {
+		var sym rawSymbol
+		if r.is32 {
+			var e ELF32SymbolTableEntry
+			binary.Read(rd, bo, &e)
+			sym = rawSymbol{Symbol{Info: e.Info, Other: e.Other, Index: SectionIndex(e.Shndx), Value: uint64(e.Value), Size: uint64(e.Size)}, e.Name}
+		} else {
+			var e ELF64SymbolTableEntry
+			binary.Read(rd, bo, &e)
+			sym = rawSymbol{Symbol{Info: e.Info, Other: e.Other, Index: SectionIndex(e.Shndx), Value: e.Value, Size: e.Size}, e.Name}
+		}
+		sym.Name, _ = getString(strtab, int(sym.nameOff))
+		syms = append(syms, sym)
+	}
+	return syms, strtab
+}
+
+// symbolVersion returns the version readelf appends to the dynamic symbol
+// si, following get_symbol_version_string of binutils.
+func (r *readelfWriter) symbolVersion(si int, sym rawSymbol) (string, int, uint16) {
+	if _, ok := r.dynValue(DT_VERSYM); !ok {
+		return "", 0, 0
+	}
+	versym, err := r.p.VersionSymbols()
+	if err != nil || si >= len(versym) {
+		return "", 0, 0
+	}
+	vers := versym[si]
+	if vers == 0 {
+		return "", 0, 0
+	}
+	info := symPublic
+	if vers&0x8000 != 0 {
+		info = symHidden
+	}
+	maxDef := uint16(0)
+	if _, ok := r.dynValue(DT_VERDEF); ok && sym.Index != 0 && vers != 0x8001 {
+		defs, _ := r.p.VersionDefs()
+		for _, d := range defs {
+			if d.Index > maxDef {
+				maxDef = d.Index
+			}
+			if d.Index != vers&0x7fff {
+				continue
+			}
+			if d.Index == 1 && d.Flags == 1 {
+				return "", 0, 0
+			}
+			if len(d.Names) != 0 && sym.Name != d.Names[0] {
+				return d.Names[0], info, 0
+			}
+			break
+		}
+	}
+	if _, ok := r.dynValue(DT_VERNEED); ok {
+		needs, _ := r.p.VersionNeeds()
+		for _, n := range needs {
+			for _, a := range n.Aux {
+				if a.Other == vers {
+					return a.Name, symUndefined, a.Other
+				}
+			}
+		}
+		if (maxDef != 0 || vers&0x7fff != 1) && vers&0x7fff > maxDef {
+			return "<corrupt>", info, 0
+		}
+	}
+	return "", 0, 0
+}
+
+func (r *readelfWriter) symbols() {
+	if len(r.sections) == 0 {
+		if r.opts.Symbols {
+			r.printf("\nDynamic symbol information is not available for displaying symbols.\n")
+		}
+		return
+	}
+	for _, s := range r.sections {
+		typ := SectionType(s.Type)
+		if typ != SHT_DYNSYM && (typ != SHT_SYMTAB || !r.opts.Symbols) {
+			continue
+		}
+		name := printableName(s.SectionName)
+		if s.EntSize == 0 {
+			r.printf("\nSymbol table '%s' has a sh_entsize of zero!\n", name)
+			continue
+		}
+		n := s.Size / s.EntSize
+		r.printf("\nSymbol table '%s' contains %d %s:\n", name, n, r.plural(n, "entry", "entries"))
+		if r.is32 {
+			r.printf("   Num:    Value  Size Type    Bind   Vis      Ndx Name\n")
+		} else {
+			r.printf("   Num:    Value          Size Type    Bind   Vis      Ndx Name\n")
+		}
+		syms, _ := r.readSymbols(s)
+		for i, sym := range syms {
+			r.symbol(i, sym, typ == SHT_DYNSYM)
+		}
+	}
+}
+
+// symbol prints one row of the symbol table.
+func (r *readelfWriter) symbol(si int, sym rawSymbol, dynamic bool) {
+	size := fmt.Sprintf("%5d", sym.Size)
+	if sym.Size > 99999 {
+		size = fmt.Sprintf("%#x", sym.Size)
+	}
+	r.printf("%6d: %s %s", si, r.longHex(sym.Value), size)
+	r.printf(" %-7s", readelfSymbolType(r.hdr.Machine, r.osabi(), sym.Info&0xf))
+	r.printf(" %-6s", readelfSymbolBind(r.osabi(), sym.Info>>4))
+	vis := sym.Other & 3
+	r.printf(" %-7s", readelfVisibility[vis])
+	if sym.Other^vis != 0 {
+		r.printf(" [%s] ", readelfSymbolOther(r.hdr.Machine, sym.Other^vis))
+	}
+	r.printf(" %4s ", readelfSymbolIndex(r.hdr.Machine, len(r.sections), uint16(sym.Index)))
+	name := sym.Name
+	if sym.Info&0xf == uint8(STT_SECTION) && int(sym.Index) < len(r.sections) && sym.nameOff == 0 {
+		name = r.sectionName(uint32(sym.Index))
+	}
+	version, info, other := "", 0, uint16(0)
+	if dynamic {
+		version, info, other = r.symbolVersion(si, sym)
+	}
+	avail := 21
+	if !r.opts.Wide && version != "" {
+		avail -= 1 + len(version)
+		if info == symUndefined {
+			avail -= len(fmt.Sprintf(" (%d)", other))
+		} else if info != symHidden {
+			avail--
+		}
+	}
+	r.printSymbol(avail, name)
+	switch {
+	case version == "":
+	case info == symUndefined:
+		r.printf("@%s (%d)", version, other)
+	case info == symHidden:
+		r.printf("@%s", version)
+	default:
+		r.printf("@@%s", version)
+	}
+	r.printf("\n")
+}
+
+// relocType returns the readelf name of a relocation type, false when
+// the type is unknown for the machine.
+func (r *readelfWriter) relocType(typ uint32) (string, bool) {
+	name := r.p.F.RelocTypeString(typ)
+	if alias, ok := readelfRelocAliases[name]; ok {
+		name = alias
+	}
+	if name == "" || strings.ContainsRune(name, '+') || name[0] >= '0' && name[0] <= '9' {
+		return "", false
+	}
+	return name, true
+}
+
+// readelfRelocAliases maps the relocation names of arch.go, which follow
+// the Go debug/elf spelling, to the binutils ones where they differ.
+var readelfRelocAliases = map[string]string{
+	"R_386_JMP_SLOT":    "R_386_JUMP_SLOT",
+	"R_X86_64_JMP_SLOT": "R_X86_64_JUMP_SLOT",
+}
+
+func (r *readelfWriter) relocations() {
+	found := false
+	for _, s := range r.sections {
+		typ := SectionType(s.Type)
+		if (typ != SHT_REL && typ != SHT_RELA && typ != SHT_RELR) || s.Size == 0 {
+			continue
+		}
+		found = true
+		ent := s.EntSize
+		if ent == 0 {
+			ent = map[bool]map[bool]uint64{true: {true: 12, false: 8}, false: {true: 24, false: 16}}[r.is32][typ == SHT_RELA]
+			if typ == SHT_RELR {
+				ent = 8
+				if r.is32 {
+					ent = 4
+				}
+			}
+		}
+		n := s.Size / ent
+		r.printf("\nRelocation section '%s' at offset 0x%x contains %d %s:\n", printableName(s.SectionName),
+			s.Off, n, r.plural(n, "entry", "entries"))
+		if typ == SHT_RELR {
+			r.relrOffsets(s)
+			continue
+		}
+		var syms []rawSymbol
+		dynamic := false
+		if s.Link != 0 && int(s.Link) < len(r.sections) {
+			symsec := r.sections[s.Link]
+			if t := SectionType(symsec.Type); t != SHT_SYMTAB && t != SHT_DYNSYM {
+				continue
+			}
+			syms, _ = r.readSymbols(symsec)
+			dynamic = SectionType(symsec.Type) == SHT_DYNSYM
+		}
+		relocs, err := r.p.SectionRelocations(s)
+		if err != nil {
+			continue
+		}
+		r.relocationTable(relocs, syms, typ == SHT_RELA, dynamic)
+	}
+	if !found {
+		r.printf("\nThere are no relocations in this file.\n")
+	}
+}
+
+// relrOffsets prints the addresses a SHT_RELR section relocates, it
+// follows dump_relr_relocations of readelf.
+func (r *readelfWriter) relrOffsets(s *ELF64Section) {
+	data, err := s.Data()
+	if err != nil {
+		return
+	}
+	bo := r.p.F.ByteOrder()
+	size := uint64(8)
+	if r.is32 {
+		size = 4
+	}
+	var offsets []uint64
+	// 偶数项是一个地址，奇数项是从上一地址开始的位图，每一位对应一个字
+	for where := uint64(0); uint64(len(data)) >= size; data = data[size:] {
+		var entry uint64
+		if r.is32 {
+			entry = uint64(bo.Uint32(data))
+		} else {
+			entry = bo.Uint64(data)
+		}
+		if entry&1 == 0 {
+			offsets = append(offsets, entry)
+			where = entry + size
+			continue
+		}
+		for i, bits := uint64(0), entry>>1; bits != 0; i, bits = i+1, bits>>1 {
+			if bits&1 != 0 {
+				offsets = append(offsets, where+i*size)
+			}
+		}
+		where += (size*8 - 1) * size
+	}
+	r.printf("  %d %s\n", len(offsets), r.plural(uint64(len(offsets)), "offset", "offsets"))
+	for _, off := range offsets {
+		r.printf("%s\n", r.longHex(off))
+	}
+}
+
+// relocationTable prints the relocations of one section, it follows
+// dump_relocations of readelf.
+func (r *readelfWriter) relocationTable(relocs []Relocation, syms []rawSymbol, rela, dynamic bool) {
+	addend := ""
+	if rela {
+		addend = " + Addend"
+	}
+	switch {
+	case r.is32 && r.opts.Wide:
+		r.printf(" Offset     Info    Type                Sym. Value  Symbol's Name%s\n", addend)
+	case r.is32:
+		r.printf(" Offset     Info    Type            Sym.Value  Sym. Name%s\n", addend)
+	case r.opts.Wide:
+		r.printf("    Offset             Info             Type               Symbol's Value  Symbol's Name%s\n", addend)
+	default:
+		r.printf("  Offset          Info           Type           Sym. Value    Sym. Name%s\n", addend)
+	}
+	mips64 := !r.is32 && Machine(r.hdr.Machine) == EM_MIPS
+	for _, rel := range relocs {
+		switch {
+		case r.is32:
+			r.printf("%8.8x  %8.8x ", uint32(rel.Off), uint32(rel.Info))
+		case r.opts.Wide:
+			r.printf("%16.16x  %16.16x ", rel.Off, rel.Info)
+		default:
+			r.printf("%12.12x  %12.12x ", rel.Off, rel.Info)
+		}
+		r.relocTypeColumn(rel.Type, r.opts.Wide)
+		if rel.Sym != 0 {
+			if int(rel.Sym) < len(syms) {
+				r.relocationSymbol(rel, syms[rel.Sym], dynamic, rela)
+			}
+		} else if rela {
+			pad := 20
+			if r.is32 {
+				pad = 12
+			}
+			r.printf("%s", strings.Repeat(" ", pad))
+			if rel.Addend < 0 {
+				r.printf("-%x", uint64(-rel.Addend))
+			} else {
+				r.printf("%x", uint64(rel.Addend))
+			}
+		}
+		if name, ok := r.relocType(rel.Type); ok && Machine(r.hdr.Machine) == EM_SPARCV9 && name == "R_SPARC_OLO10" {
+			r.printf(" + %x", rel.Info>>8&0xffffff)
+		}
+		r.printf("\n")
+		if mips64 {
+			r.printf("                    Type2: ")
+			r.relocTypeColumn(uint32(rel.Info>>8&0xff), false)
+			r.printf("\n                    Type3: ")
+			r.relocTypeColumn(uint32(rel.Info>>16&0xff), false)
+			r.printf("\n")
+		}
+	}
+}
+
+// relocTypeColumn prints the Type column, the MIPS Type2 and Type3 lines
+// keep the narrow width even in wide mode.
+func (r *readelfWriter) relocTypeColumn(typ uint32, wide bool) {
+	name, ok := r.relocType(typ)
+	switch {
+	case !ok:
+		r.printf("unrecognized: %-7x", typ)
+	case wide:
+		r.printf("%-22s", name)
+	default:
+		r.printf("%-17.17s", name)
+	}
+}
+
+// relocationSymbol prints the symbol value, name and addend of a relocation.
+func (r *readelfWriter) relocationSymbol(rel Relocation, sym rawSymbol, dynamic, rela bool) {
+	r.printf(" %s", r.longHex(sym.Value))
+	if r.is32 {
+		r.printf("   ")
+	} else {
+		r.printf(" ")
+	}
+	if sym.nameOff == 0 {
+		name := "<null>"
+		if sym.Info&0xf == uint8(STT_SECTION) {
+			switch idx := uint16(sym.Index); {
+			case int(idx) < len(r.sections):
+				name = r.sectionName(uint32(idx))
+			case idx == 0xfff1:
+				name = "ABS"
+			case idx == 0xfff2:
+				name = "COMMON"
+			default:
+				name = fmt.Sprintf("<section 0x%x>", idx)
+			}
+		}
+		r.printSymbol(22, name)
+	} else {
+		r.printSymbol(22, sym.Name)
+		if dynamic {
+			if version, info, _ := r.symbolVersion(int(rel.Sym), sym); version != "" {
+				if info == symPublic {
+					r.printf("@@%s", version)
+				} else {
+					r.printf("@%s", version)
+				}
+			}
+		}
+	}
+	if rela {
+		if rel.Addend < 0 {
+			r.printf(" - %x", uint64(-rel.Addend))
+		} else {
+			r.printf(" + %x", uint64(rel.Addend))
+		}
+	}
+}
+
+func (r *readelfWriter) versions() {
+	found := false
+	for _, s := range r.sections {
+		switch SectionType(s.Type) {
+		case SHT_GNU_VERSYM:
+			found = true
+			r.versym(s)
+		case SHT_GNU_VERNEED:
+			found = true
+			r.verneed(s)
+		case SHT_GNU_VERDEF:
+			found = true
+			r.verdef(s)
+		}
+	}
+	if !found {
+		r.printf("\nNo version information found in this file.\n")
+	}
+}
+
+// versionHeader prints the Addr/Offset/Link line shared by the version sections.
+func (r *readelfWriter) versionHeader(s *ELF64Section) {
+	r.printf(" Addr: 0x%016x  Offset: 0x%08x  Link: %d (%s)\n", s.Addr, s.Off, s.Link, r.sectionName(s.Link))
+}
+
+// versionOffset formats an offset like the C "%#06x" conversion.
+func versionOffset(v uint64) string {
+	if v == 0 {
+		return "000000"
+	}
+	return fmt.Sprintf("0x%04x", v)
+}
+
+func (r *readelfWriter) verdef(s *ELF64Section) {
+	n := uint64(s.Info)
+	r.printf("\nVersion definition section '%s' contains %d %s:\n", printableName(s.SectionName), n, r.plural(n, "entry", "entries"))
+	r.versionHeader(s)
+	defs, _ := r.p.VersionDefs()
+	for _, d := range defs {
+		r.printf("  %s: Rev: %d  Flags: %s  Index: %d  Cnt: %d  ", versionOffset(d.Offset), d.Version,
+			versionFlags(d.Flags), d.Index, len(d.Names))
+		if len(d.Names) == 0 {
+			r.printf("\n")
+			continue
+		}
+		r.printf("Name: %s\n", d.Names[0])
+		for j := 1; j < len(d.Names); j++ {
+			r.printf("  %s: Parent %d: %s\n", versionOffset(d.AuxOffsets[j]), j, d.Names[j])
+		}
+	}
+}
+
+func (r *readelfWriter) verneed(s *ELF64Section) {
+	n := uint64(s.Info)
+	r.printf("\nVersion needs section '%s' contains %d %s:\n", printableName(s.SectionName), n, r.plural(n, "entry", "entries"))
+	r.versionHeader(s)
+	needs, _ := r.p.VersionNeeds()
+	for _, need := range needs {
+		r.printf("  %s: Version: %d  File: %s  Cnt: %d\n", versionOffset(need.Offset), need.Version, need.File, len(need.Aux))
+		for _, a := range need.Aux {
+			r.printf("  %s:   Name: %s  Flags: %s  Version: %d\n", versionOffset(a.Offset), a.Name, versionFlags(a.Flags), a.Other)
+		}
+	}
+}
+
+func (r *readelfWriter) versym(s *ELF64Section) {
+	total := s.Size / 2
+	r.printf("\nVersion symbols section '%s' contains %d %s:\n", printableName(s.SectionName), total, r.plural(total, "entry", "entries"))
+	r.versionHeader(s)
+	versym, _ := r.p.VersionSymbols()
+	var needs []GNUVersionNeed
+	var defs []GNUVersionDef
+	if _, ok := r.dynValue(DT_VERNEED); ok {
+		needs, _ = r.p.VersionNeeds()
+	}
+	if _, ok := r.dynValue(DT_VERDEF); ok {
+		defs, _ = r.p.VersionDefs()
+	}
+	for cnt := 0; cnt < len(versym); cnt += 4 {
+		r.printf("  %03x:", cnt)
+		for j := 0; j < 4 && cnt+j < len(versym); j++ {
+			v := versym[cnt+j]
+			switch v {
+			case 0:
+				r.printf("   0 (*local*)    ")
+				continue
+			case 1:
+				r.printf("   1 (*global*)   ")
+				continue
+			}
+			h := byte(' ')
+			if v&0x8000 != 0 {
+				h = 'h'
+			}
+			cell := fmt.Sprintf("%4x%c", v&0x7fff, h)
+			name := ""
+			for _, n := range needs {
+				for _, a := range n.Aux {
+					if a.Other == v && name == "" {
+						name = a.Name
+					}
+				}
+			}
+			if v != 0x8001 {
+				for _, d := range defs {
+					if d.Index == v&0x7fff && len(d.Names) != 0 {
+						if name != "" {
+							name = "*both*"
+						} else {
+							name = d.Names[0]
+						}
+						break
+					}
+				}
+			}
+			if name != "" {
+				cell += fmt.Sprintf("(%s%-*s", name, 12-len(name), ")")
+			}
+			if len(cell) < 18 {
+				cell += strings.Repeat(" ", 18-len(cell))
+			}
+			r.printf("%s", cell)
+		}
+		r.printf("\n")
+	}
+}
+
+func (r *readelfWriter) notes() {
+	core := Type(r.hdr.Type) == ET_CORE
+	for _, nr := range r.p.noteRanges() {
+		if nr.dataErr != nil || nr.size == 0 {
+			continue
+		}
+		if nr.align > 8 || (nr.align > 4 && nr.align != 8) {
+			continue
+		}
+		if nr.section != "" {
+			r.printf("\nDisplaying notes found in: %s\n", printableName(nr.section))
+		} else {
+			r.printf("\nDisplaying notes found at file offset 0x%08x with length 0x%08x:\n", nr.off, nr.size)
+		}
+		r.printf("  %-20s %-10s\tDescription\n", "Owner", "Data size")
+		notes, _ := r.p.decodeNotes(nr)
+		for _, n := range notes {
+			r.note(n, core)
+		}
+	}
+}
+
+// note prints one note, the GNU notes have their description decoded.
+func (r *readelfWriter) note(n Note, core bool) {
+	r.printf("  ")
+	r.printSymbol(-20, n.Name)
+	sep := "\n"
+	if r.opts.Wide {
+		sep = "\t"
+	}
+	r.printf(" 0x%08x\t%s%s", len(n.Desc), readelfNoteType(n.Name, core, uint32(n.Type)), sep)
+	bo := r.p.F.ByteOrder()
+	desc := n.Desc
+	switch {
+	case strings.HasPrefix(n.Name, "GNU"):
+		switch n.Type {
+		case NT_GNU_BUILD_ID:
+			r.printf("    Build ID: %x\n", desc)
+		case NT_GNU_ABI_TAG:
+			if len(desc) < 16 {
+				r.printf("    <corrupt GNU_ABI_TAG>\n")
+				break
+			}
+			os := "Unknown"
+			if v := bo.Uint32(desc); v < uint32(len(gnuAbiTagOS)) {
+				os = gnuAbiTagOS[v]
+			}
+			r.printf("    OS: %s, ABI: %d.%d.%d\n", os, bo.Uint32(desc[4:]), bo.Uint32(desc[8:]), bo.Uint32(desc[12:]))
+		case NT_GNU_GOLD_VERSION:
+			if i := bytes.IndexByte(desc, 0); i >= 0 {
+				desc = desc[:i]
+			}
+			r.printf("    Version: %s\n", desc)
+		case NT_GNU_HWCAP:
+			r.printf("      Hardware Capabilities: ")
+			if len(desc) >= 8 {
+				r.printf("num entries: %d, enabled mask: %x\n", bo.Uint32(desc), bo.Uint32(desc[4:]))
+			}
+		case NT_GNU_PROPERTY_TYPE_0:
+			r.gnuProperties(desc)
+		default:
+			r.printf("    Description data: ")
+			for _, b := range desc {
+				r.printf("%02x ", b)
+			}
+			r.printf("\n")
+		}
+		return
+	case strings.HasPrefix(n.Name, "CORE"):
+		// 核心转储中除NT_FILE外的note不打印描述
+		if r.opts.Wide {
+			r.printf("\n")
+		}
+		return
+	}
+	if len(desc) != 0 {
+		r.printf("   description data: ")
+		for _, b := range desc {
+			r.printf("%02x ", b)
+		}
+		if !r.opts.Wide {
+			r.printf("\n")
+		}
+	}
+	if r.opts.Wide {
+		r.printf("\n")
+	}
+}
+
+// gnuProperties decodes a NT_GNU_PROPERTY_TYPE_0 note like print_gnu_property_note.
+func (r *readelfWriter) gnuProperties(desc []byte) {
+	r.printf("      Properties: ")
+	size := 8
+	if r.is32 {
+		size = 4
+	}
+	if len(desc) < 8 || len(desc)%size != 0 {
+		r.printf("<corrupt GNU_PROPERTY_TYPE, size = %#x>\n", len(desc))
+		return
+	}
+	bo := r.p.F.ByteOrder()
+	machine := Machine(r.hdr.Machine)
+	for pos := 0; pos < len(desc); {
+		if len(desc)-pos < 8 {
+			r.printf("<corrupt descsz: %#x>\n", len(desc))
+			break
+		}
+		typ, datasz := bo.Uint32(desc[pos:]), int(bo.Uint32(desc[pos+4:]))
+		pos += 8
+		if datasz > len(desc)-pos {
+			r.printf("<corrupt type (%#x) datasz: %#x>\n", typ, datasz)
+			break
+		}
+		data := desc[pos : pos+datasz]
+		if !r.gnuProperty(machine, typ, data) {
+			switch {
+			case typ < 0xc0000000:
+				r.printf("<unknown type %#x data: ", typ)
+			case typ < 0xe0000000:
+				r.printf("<processor-specific type %#x data: ", typ)
+			default:
+				r.printf("<application-specific type %#x data: ", typ)
+			}
+			for _, b := range data {
+				r.printf("%02x ", b)
+			}
+			r.printf(">")
+		}
+		pos += (datasz + size - 1) &^ (size - 1)
+		if pos >= len(desc) {
+			break
+		}
+		if r.opts.Wide {
+			r.printf(", ")
+		} else {
+			r.printf("\n\t")
+		}
+	}
+	r.printf("\n")
+}
+
+// gnuProperty prints a known GNU property, it returns false for the others.
+func (r *readelfWriter) gnuProperty(machine Machine, typ uint32, data []byte) bool {
+	bo := r.p.F.ByteOrder()
+	word := func(label string, names []string) bool {
+		if len(data) != 4 {
+			r.printf("%s<corrupt length: %#x> ", label, len(data))
+			return true
+		}
+		r.printf("%s%s", label, readelfBits(bo.Uint32(data), names))
+		return true
+	}
+	if typ >= 0xc0000000 && typ <= 0xdfffffff {
+		switch {
+		case machine == EM_X86_64 || machine == EM_386:
+			switch typ {
+			case 0xc0010002:
+				return word("x86 ISA used: ", x86ISANames)
+			case 0xc0008002:
+				return word("x86 ISA needed: ", x86ISANames)
+			case 0xc0000002:
+				return word("x86 feature: ", x86Feature1Names)
+			case 0xc0010001:
+				return word("x86 feature used: ", x86Feature2Names)
+			case 0xc0008001:
+				return word("x86 feature needed: ", x86Feature2Names)
+			case 0xc0000000:
+				return word("x86 ISA used: ", x86CompatISANames)
+			case 0xc0000001:
+				return word("x86 ISA needed: ", x86CompatISANames)
+			case 0xc0010000:
+				return word("x86 ISA used: ", x86Compat2ISANames)
+			case 0xc0008000:
+				return word("x86 ISA needed: ", x86Compat2ISANames)
+			}
+		case machine == EM_AARCH64 && typ == 0xc0000000:
+			r.printf("AArch64 feature: ")
+			if len(data) != 4 {
+				r.printf("<corrupt length: %#x> ", len(data))
+				return true
+			}
+			r.printf("%s", readelfBits(bo.Uint32(data), aarch64FeatureNames))
+			return true
+		}
+		return false
+	}
+	switch {
+	case typ == 1:
+		r.printf("stack size: ")
+		size := 8
+		if r.is32 {
+			size = 4
+		}
+		if len(data) != size {
+			r.printf("<corrupt length: %#x> ", len(data))
+		} else if size == 4 {
+			r.printf("%#x", bo.Uint32(data))
+		} else {
+			r.printf("%#x", bo.Uint64(data))
+		}
+		return true
+	case typ == 2:
+		r.printf("no copy on protected ")
+		if len(data) != 0 {
+			r.printf("<corrupt length: %#x> ", len(data))
+		}
+		return true
+	case typ == 0xb0008000:
+		return word("1_needed: ", []string{"indirect external access"})
+	case typ >= 0xb0000000 && typ <= 0xb000ffff:
+		if typ <= 0xb0007fff {
+			r.printf("UINT32_AND (%#x): ", typ)
+		} else {
+			r.printf("UINT32_OR (%#x): ", typ)
+		}
+		if len(data) != 4 {
+			r.printf("<corrupt length: %#x> ", len(data))
+		} else {
+			r.printf("%s", altHex(uint64(bo.Uint32(data))))
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package elf
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The golden files are the output of GNU readelf for every sample binary:
+//
+//	readelf -h -S -l -d -s -r -n -V [-W] <file> > <file>[.wide].golden
+func TestWriteReadelfGolden(t *testing.T) {
+	goldens, err := filepath.Glob(path.Join("testdata", "readelf", "*.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, goldens)
+	for _, golden := range goldens {
+		name := strings.TrimSuffix(filepath.Base(golden), ".golden")
+		wide := strings.HasSuffix(name, ".wide")
+		name = strings.TrimSuffix(name, ".wide")
+		t.Run(filepath.Base(golden), func(t *testing.T) {
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := New(path.Join(exampleDir, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer p.CloseFile()
+			if err := p.Parse(); err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			opts := ReadelfOptions{FileHeader: true, SectionHeaders: true, ProgramHeaders: true, Dynamic: true,
+				Symbols: true, Relocs: true, Notes: true, VersionInfo: true, Wide: wide}
+			if assert.NoError(t, p.WriteReadelf(&out, opts)) {
+				assert.Equal(t, string(want), out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,750 @@
+// Package elf : readelf_names.go holds the names GNU readelf prints for the
+// header fields, section and segment types, dynamic tags and flags. They
+// differ from the C constant names used by the stringers of flags.go.
+package elf
+
+import (
+	"fmt"
+	"strings"
+)
+
+var readelfOSABINames = map[uint8]string{
+	0:  "UNIX - System V",
+	1:  "UNIX - HP-UX",
+	2:  "UNIX - NetBSD",
+	3:  "UNIX - GNU",
+	6:  "UNIX - Solaris",
+	7:  "UNIX - AIX",
+	8:  "UNIX - IRIX",
+	9:  "UNIX - FreeBSD",
+	10: "UNIX - TRU64",
+	11: "Novell - Modesto",
+	12: "UNIX - OpenBSD",
+	13: "VMS - OpenVMS",
+	14: "HP - Non-Stop Kernel",
+	15: "AROS",
+	16: "FenixOS",
+	17: "Nuxi CloudABI",
+	18: "Stratus Technologies OpenVOS",
+}
+
+var readelfMachineNames = map[uint16]string{
+	0:      "None",
+	1:      "WE32100",
+	2:      "Sparc",
+	3:      "Intel 80386",
+	4:      "MC68000",
+	5:      "MC88000",
+	6:      "Intel MCU",
+	7:      "Intel 80860",
+	8:      "MIPS R3000",
+	9:      "IBM System/370",
+	10:     "MIPS R4000 big-endian",
+	15:     "HPPA",
+	18:     "Sparc v8+",
+	19:     "Intel 80960",
+	20:     "PowerPC",
+	21:     "PowerPC64",
+	22:     "IBM S/390",
+	23:     "SPU",
+	36:     "Renesas V850 (using RH850 ABI)",
+	37:     "Fujitsu FR20",
+	38:     "TRW RH32",
+	40:     "ARM",
+	41:     "Digital Alpha (old)",
+	42:     "Renesas / SuperH SH",
+	43:     "Sparc v9",
+	44:     "Siemens Tricore",
+	45:     "ARC",
+	46:     "Renesas H8/300",
+	47:     "Renesas H8/300H",
+	48:     "Renesas H8S",
+	49:     "Renesas H8/500",
+	50:     "Intel IA-64",
+	51:     "Stanford MIPS-X",
+	52:     "Motorola Coldfire",
+	62:     "Advanced Micro Devices X86-64",
+	83:     "Atmel AVR 8-bit microcontroller",
+	94:     "Tensilica Xtensa Processor",
+	105:    "Texas Instruments msp430 microcontroller",
+	183:    "AArch64",
+	190:    "NVIDIA CUDA architecture",
+	224:    "AMD GPU",
+	243:    "RISC-V",
+	247:    "Linux BPF",
+	258:    "LoongArch",
+	0x9026: "Alpha",
+}
+
+// readelfMachineName returns the machine name of the readelf file header.
+func readelfMachineName(m uint16) string {
+	if name, ok := readelfMachineNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("<unknown>: 0x%x", m)
+}
+
+// readelfFileType returns the file type of the readelf file header, pie
+// tells a position independent executable from a shared object.
+func readelfFileType(t uint16, pie bool) string {
+	switch Type(t) {
+	case ET_NONE:
+		return "NONE (None)"
+	case ET_REL:
+		return "REL (Relocatable file)"
+	case ET_EXEC:
+		return "EXEC (Executable file)"
+	case ET_DYN:
+		if pie {
+			return "DYN (Position-Independent Executable file)"
+		}
+		return "DYN (Shared object file)"
+	case ET_CORE:
+		return "CORE (Core file)"
+	}
+	switch {
+	case t >= 0xff00:
+		return fmt.Sprintf("Processor Specific: (%x)", t)
+	case t >= 0xfe00:
+		return fmt.Sprintf("OS Specific: (%x)", t)
+	}
+	return fmt.Sprintf("<unknown>: %x", t)
+}
+
+// readelfMachineFlags decodes e_flags the way readelf appends it to the
+// "Flags:" line of the file header.
+func readelfMachineFlags(machine uint16, flags uint32) string {
+	if flags == 0 {
+		return ""
+	}
+	var b strings.Builder
+	switch Machine(machine) {
+	case EM_ARM:
+		b.WriteString(armMachineFlags(flags))
+	case EM_MIPS, EM_MIPS_RS3_LE:
+		b.WriteString(mipsMachineFlags(flags))
+	case EM_PPC64:
+		if flags&3 != 0 {
+			fmt.Fprintf(&b, ", abiv%d", flags&3)
+		}
+	case EM_PPC:
+		if flags&0x80000000 != 0 {
+			b.WriteString(", emb")
+		}
+		if flags&0x10000 != 0 {
+			b.WriteString(", relocatable")
+		}
+		if flags&0x8000 != 0 {
+			b.WriteString(", relocatable-lib")
+		}
+	case EM_SPARCV9:
+		for _, f := range []flagName{{0x100, ", v8+"}, {0x200, ", ultrasparcI"}, {0x800, ", ultrasparcIII"}, {0x400, ", halr1"}, {0x800000, ", ledata"}} {
+			if flags&f.flag != 0 {
+				b.WriteString(f.name)
+			}
+		}
+		switch flags & 3 {
+		case 0:
+			b.WriteString(", tso")
+		case 1:
+			b.WriteString(", pso")
+		case 2:
+			b.WriteString(", rmo")
+		}
+	case EM_RISCV:
+		if flags&1 != 0 {
+			b.WriteString(", RVC")
+		}
+		if flags&8 != 0 {
+			b.WriteString(", RVE")
+		}
+		if flags&0x10 != 0 {
+			b.WriteString(", TSO")
+		}
+		b.WriteString([]string{", soft-float ABI", ", single-float ABI", ", double-float ABI", ", quad-float ABI"}[flags&6>>1])
+	case EM_S390:
+		if flags&1 != 0 {
+			b.WriteString(", highgprs")
+		}
+	}
+	return b.String()
+}
+
+// armMachineFlags decodes the ARM EABI version and flags.
+func armMachineFlags(flags uint32) string {
+	var b strings.Builder
+	eabi := flags & 0xff000000
+	flags &^= 0xff000000
+	unknown := false
+	if flags&0x01 != 0 {
+		b.WriteString(", relocatable executable")
+		flags &^= 0x01
+	}
+	if flags&0x20 != 0 {
+		b.WriteString(", position independent")
+		flags &^= 0x20
+	}
+	switch eabi {
+	case 0x04000000, 0x05000000:
+		fmt.Fprintf(&b, ", Version%d EABI", eabi>>24)
+		for flags != 0 {
+			flag := flags & -flags
+			flags &^= flag
+			switch {
+			case flag == 0x00800000:
+				b.WriteString(", BE8")
+			case flag == 0x00400000:
+				b.WriteString(", LE8")
+			case flag == 0x200 && eabi == 0x05000000:
+				b.WriteString(", soft-float ABI")
+			case flag == 0x400 && eabi == 0x05000000:
+				b.WriteString(", hard-float ABI")
+			default:
+				unknown = true
+			}
+		}
+	case 0:
+		b.WriteString(", GNU EABI")
+		gnu := []flagName{{0x04, ", interworking enabled"}, {0x08, ", uses APCS/26"}, {0x10, ", uses APCS/float"},
+			{0x40, ", 8 bit structure alignment"}, {0x80, ", uses new ABI"}, {0x100, ", uses old ABI"},
+			{0x200, ", software FP"}, {0x400, ", VFP"}, {0x800, ", Maverick FP"}}
+		for flags != 0 {
+			flag := flags & -flags
+			flags &^= flag
+			name := ""
+			for _, g := range gnu {
+				if g.flag == flag {
+					name = g.name
+				}
+			}
+			if name == "" {
+				unknown = true
+			}
+			b.WriteString(name)
+		}
+	default:
+		b.WriteString(", <unrecognized EABI>")
+		if flags != 0 {
+			unknown = true
+		}
+	}
+	if unknown {
+		b.WriteString(", <unknown>")
+	}
+	return b.String()
+}
+
+var mipsMachNames = map[uint32]string{
+	0x00810000: ", 3900", 0x00820000: ", 4010", 0x00830000: ", 4100", 0x00880000: ", 4111",
+	0x00870000: ", 4120", 0x00850000: ", 4650", 0x00910000: ", 5400", 0x00980000: ", 5500",
+	0x00920000: ", 5900", 0x008a0000: ", sb1", 0x00990000: ", 9000", 0x00a00000: ", loongson-2e",
+	0x00a10000: ", loongson-2f", 0x00a20000: ", gs464", 0x00a30000: ", gs464e", 0x00a40000: ", gs264e",
+	0x008b0000: ", octeon", 0x008d0000: ", octeon2", 0x008e0000: ", octeon3", 0x008c0000: ", xlr",
+	0x00890000: ", interaptiv-mr2", 0x00840000: ", allegrex",
+}
+
+var mipsArchNames = map[uint32]string{
+	0x00000000: ", mips1", 0x10000000: ", mips2", 0x20000000: ", mips3", 0x30000000: ", mips4",
+	0x40000000: ", mips5", 0x50000000: ", mips32", 0x70000000: ", mips32r2", 0x90000000: ", mips32r6",
+	0x60000000: ", mips64", 0x80000000: ", mips64r2", 0xa0000000: ", mips64r6",
+}
+
+// mipsMachineFlags decodes the MIPS processor, ABI and ISA flags.
+func mipsMachineFlags(flags uint32) string {
+	var b strings.Builder
+	for _, f := range []flagName{{1, ", noreorder"}, {2, ", pic"}, {4, ", cpic"}, {0x10, ", ugen_reserved"},
+		{0x20, ", abi2"}, {0x80, ", odk first"}, {0x100, ", 32bitmode"}, {0x400, ", nan2008"}, {0x200, ", fp64"}} {
+		if flags&f.flag != 0 {
+			b.WriteString(f.name)
+		}
+	}
+	if mach := flags & 0x00ff0000; mach != 0 {
+		if name, ok := mipsMachNames[mach]; ok {
+			b.WriteString(name)
+		} else {
+			b.WriteString(", unknown CPU")
+		}
+	}
+	switch flags & 0xf000 {
+	case 0:
+	case 0x1000:
+		b.WriteString(", o32")
+	case 0x2000:
+		b.WriteString(", o64")
+	case 0x3000:
+		b.WriteString(", eabi32")
+	case 0x4000:
+		b.WriteString(", eabi64")
+	default:
+		b.WriteString(", unknown ABI")
+	}
+	for _, f := range []flagName{{0x08000000, ", mdmx"}, {0x04000000, ", mips16"}, {0x02000000, ", micromips"}} {
+		if flags&f.flag != 0 {
+			b.WriteString(f.name)
+		}
+	}
+	if name, ok := mipsArchNames[flags&0xf0000000]; ok {
+		b.WriteString(name)
+	} else {
+		b.WriteString(", unknown ISA")
+	}
+	return b.String()
+}
+
+var readelfSectionTypes = map[uint32]string{
+	0: "NULL", 1: "PROGBITS", 2: "SYMTAB", 3: "STRTAB", 4: "RELA", 5: "HASH", 6: "DYNAMIC",
+	7: "NOTE", 8: "NOBITS", 9: "REL", 10: "SHLIB", 11: "DYNSYM", 14: "INIT_ARRAY",
+	15: "FINI_ARRAY", 16: "PREINIT_ARRAY", 17: "GROUP", 18: "SYMTAB SECTION INDICES", 19: "RELR",
+	0x6ffffff4: "GNU_SFRAME", 0x6ffffff5: "GNU_ATTRIBUTES", 0x6ffffff6: "GNU_HASH",
+	0x6ffffff7: "GNU_LIBLIST", 0x6ffffffd: "VERDEF", 0x6ffffffe: "VERNEED", 0x6fffffff: "VERSYM",
+	0x6ffffff0: "VERSYM", 0x6ffffffc: "VERDEF", 0x7ffffffd: "AUXILIARY", 0x7fffffff: "FILTER",
+}
+
+var mipsSectionTypes = []string{
+	"LIBLIST", "MSYM", "CONFLICT", "GPTAB", "UCODE", "DEBUG", "REGINFO", "PACKAGE", "PACKSYM",
+	"RELD", "", "IFACE", "CONTENT", "OPTIONS", "", "", "SHDR", "FDESC", "EXTSYM", "DENSE",
+	"PDESC", "LOCSYM", "AUXSYM", "OPTSYM", "LOCSTR", "LINE", "RFDESC", "DELTASYM", "DELTAINST",
+	"DELTACLASS", "DWARF", "DELTADECL", "SYMBOL_LIB", "EVENTS", "TRANSLATE", "PIXIE", "XLATE",
+	"XLATE_DEBUG", "WHIRL", "EH_REGION", "XLATE_OLD", "PDR_EXCEPTION", "ABIFLAGS", "XHASH",
+}
+
+// readelfSectionType returns the name readelf gives to a section type.
+func readelfSectionType(machine uint16, t uint32) string {
+	if name, ok := readelfSectionTypes[t]; ok {
+		return name
+	}
+	switch {
+	case t >= 0x70000000 && t <= 0x7fffffff:
+		off := t - 0x70000000
+		switch Machine(machine) {
+		case EM_MIPS, EM_MIPS_RS3_LE:
+			if off < uint32(len(mipsSectionTypes)) && mipsSectionTypes[off] != "" {
+				return "MIPS_" + mipsSectionTypes[off]
+			}
+		case EM_ARM:
+			if off >= 1 && off <= 5 {
+				return []string{"ARM_EXIDX", "ARM_PREEMPTMAP", "ARM_ATTRIBUTES", "ARM_DEBUGOVERLAY", "ARM_OVERLAYSECTION"}[off-1]
+			}
+		case EM_X86_64:
+			if off == 1 {
+				return "X86_64_UNWIND"
+			}
+		case EM_RISCV:
+			if off == 3 {
+				return "RISCV_ATTRIBUTES"
+			}
+		}
+		return fmt.Sprintf("LOPROC+%#x", off)
+	case t >= 0x60000000 && t <= 0x6fffffff:
+		return fmt.Sprintf("LOOS+%#x", t-0x60000000)
+	case t >= 0x80000000:
+		return fmt.Sprintf("LOUSER+%#x", t-0x80000000)
+	}
+	return fmt.Sprintf("%08x: <unknown>", t)
+}
+
+var readelfSegmentTypes = map[uint32]string{
+	0: "NULL", 1: "LOAD", 2: "DYNAMIC", 3: "INTERP", 4: "NOTE", 5: "SHLIB", 6: "PHDR", 7: "TLS",
+	0x6474e550: "GNU_EH_FRAME", 0x6474e551: "GNU_STACK", 0x6474e552: "GNU_RELRO",
+	0x6474e553: "GNU_PROPERTY", 0x6474e554: "GNU_SFRAME",
+	0x65a3dbe6: "OPENBSD_RANDOMIZE", 0x65a3dbe7: "OPENBSD_WXNEEDED", 0x65a41be6: "OPENBSD_BOOTDATA",
+}
+
+// readelfSegmentType returns the name readelf gives to a segment type.
+func readelfSegmentType(machine uint16, t uint32) string {
+	if name, ok := readelfSegmentTypes[t]; ok {
+		return name
+	}
+	switch {
+	case t >= 0x70000000 && t <= 0x7fffffff:
+		switch Machine(machine) {
+		case EM_ARM:
+			if t == 0x70000001 {
+				return "EXIDX"
+			}
+		case EM_AARCH64:
+			if t == 0x70000002 {
+				return "AARCH64_MEMTAG_MTE"
+			}
+		case EM_MIPS, EM_MIPS_RS3_LE:
+			if t <= 0x70000003 {
+				return []string{"REGINFO", "RTPROC", "OPTIONS", "ABIFLAGS"}[t-0x70000000]
+			}
+		case EM_RISCV:
+			if t == 0x70000003 {
+				return "RISCV_ATTRIBUTES"
+			}
+		}
+		return fmt.Sprintf("LOPROC+%#x", t-0x70000000)
+	case t >= 0x60000000 && t <= 0x6fffffff:
+		return fmt.Sprintf("LOOS+%#x", t-0x60000000)
+	}
+	return fmt.Sprintf("<unknown>: %x", t)
+}
+
+var readelfDynTags = map[uint64]string{
+	0: "NULL", 1: "NEEDED", 2: "PLTRELSZ", 3: "PLTGOT", 4: "HASH", 5: "STRTAB", 6: "SYMTAB",
+	7: "RELA", 8: "RELASZ", 9: "RELAENT", 10: "STRSZ", 11: "SYMENT", 12: "INIT", 13: "FINI",
+	14: "SONAME", 15: "RPATH", 16: "SYMBOLIC", 17: "REL", 18: "RELSZ", 19: "RELENT",
+	20: "PLTREL", 21: "DEBUG", 22: "TEXTREL", 23: "JMPREL", 24: "BIND_NOW", 25: "INIT_ARRAY",
+	26: "FINI_ARRAY", 27: "INIT_ARRAYSZ", 28: "FINI_ARRAYSZ", 29: "RUNPATH", 30: "FLAGS",
+	32: "PREINIT_ARRAY", 33: "PREINIT_ARRAYSZ", 34: "SYMTAB_SHNDX", 35: "RELRSZ", 36: "RELR",
+	37:         "RELRENT",
+	0x6ffffdf8: "CHECKSUM", 0x6ffffdf9: "PLTPADSZ", 0x6ffffdfa: "MOVEENT", 0x6ffffdfb: "MOVESZ",
+	0x6ffffdfc: "FEATURE", 0x6ffffdfd: "POSFLAG_1", 0x6ffffdfe: "SYMINSZ", 0x6ffffdff: "SYMINENT",
+	0x6ffffe00: "ADDRRNGLO", 0x6ffffefa: "CONFIG", 0x6ffffefb: "DEPAUDIT", 0x6ffffefc: "AUDIT",
+	0x6ffffefd: "PLTPAD", 0x6ffffefe: "MOVETAB", 0x6ffffeff: "SYMINFO", 0x6ffffff0: "VERSYM",
+	0x6ffffef7: "TLSDESC_GOT", 0x6ffffef6: "TLSDESC_PLT", 0x6ffffff9: "RELACOUNT",
+	0x6ffffffa: "RELCOUNT", 0x6ffffffb: "FLAGS_1", 0x6ffffffc: "VERDEF", 0x6ffffffd: "VERDEFNUM",
+	0x6ffffffe: "VERNEED", 0x6fffffff: "VERNEEDNUM", 0x7ffffffd: "AUXILIARY", 0x7ffffffe: "USED",
+	0x7fffffff: "FILTER", 0x6ffffdf5: "GNU_PRELINKED", 0x6ffffef8: "GNU_CONFLICT",
+	0x6ffffdf6: "GNU_CONFLICTSZ", 0x6ffffef9: "GNU_LIBLIST", 0x6ffffdf7: "GNU_LIBLISTSZ",
+	0x6ffffef5: "GNU_HASH", 0x6ffffdf4: "GNU_FLAGS_1",
+}
+
+// readelfDynTag returns the name readelf gives to a dynamic tag.
+func readelfDynTag(machine uint16, tag uint64) string {
+	if name, ok := readelfDynTags[tag]; ok {
+		return name
+	}
+	if tag >= 0x70000000 && tag <= 0x7fffffff {
+		var names map[uint64]string
+		switch Machine(machine) {
+		case EM_SPARCV9:
+			names = map[uint64]string{0x70000001: "SPARC_REGISTER"}
+		case EM_PPC:
+			names = map[uint64]string{0x70000000: "PPC_GOT", 0x70000001: "PPC_OPT"}
+		case EM_PPC64:
+			names = map[uint64]string{0x70000000: "PPC64_GLINK", 0x70000001: "PPC64_OPD", 0x70000002: "PPC64_OPDSZ", 0x70000003: "PPC64_OPT"}
+		case EM_AARCH64:
+			names = map[uint64]string{0x70000001: "AARCH64_BTI_PLT", 0x70000003: "AARCH64_PAC_PLT", 0x70000005: "AARCH64_VARIANT_PCS"}
+		case EM_RISCV:
+			names = map[uint64]string{0x70000001: "RISCV_VARIANT_CC"}
+		}
+		if name, ok := names[tag]; ok {
+			return name
+		}
+		return fmt.Sprintf("Processor Specific: %x", tag)
+	}
+	if tag >= 0x6000000d && tag <= 0x6ffff000 {
+		return fmt.Sprintf("Operating System specific: %x", tag)
+	}
+	return fmt.Sprintf("<unknown>: %x", tag)
+}
+
+var readelfDF1Names = []string{
+	"NOW", "GLOBAL", "GROUP", "NODELETE", "LOADFLTR", "INITFIRST", "NOOPEN", "ORIGIN", "DIRECT",
+	"TRANS", "INTERPOSE", "NODEFLIB", "NODUMP", "CONFALT", "ENDFILTEE", "DISPRELDNE", "DISPRELPND",
+	"NODIRECT", "IGNMULDEF", "NOKSYMS", "NOHDR", "EDITED", "NORELOC", "SYMINTPOSE", "GLOBAUDIT",
+	"SINGLETON", "STUB", "PIE", "KMOD", "WEAKFILTER", "NOCOMMON",
+}
+
+// readelfFlags prints the " NAME" list readelf uses for DT_FLAGS_1 like
+// entries, bits without a name are printed together in hex.
+func readelfFlags(val uint64, names []string) string {
+	if val == 0 {
+		return " None"
+	}
+	var b strings.Builder
+	for i, name := range names {
+		if val&(1<<uint(i)) != 0 {
+			b.WriteString(" " + name)
+			val &^= 1 << uint(i)
+		}
+	}
+	if val != 0 {
+		fmt.Fprintf(&b, " %x", val)
+	}
+	return b.String()
+}
+
+// readelfDynFlags prints the DT_FLAGS names.
+func readelfDynFlags(val uint64) string {
+	var s []string
+	for val != 0 {
+		flag := val & -val
+		val &^= flag
+		switch flag {
+		case 0x1:
+			s = append(s, "ORIGIN")
+		case 0x2:
+			s = append(s, "SYMBOLIC")
+		case 0x4:
+			s = append(s, "TEXTREL")
+		case 0x8:
+			s = append(s, "BIND_NOW")
+		case 0x10:
+			s = append(s, "STATIC_TLS")
+		default:
+			s = append(s, "unknown")
+		}
+	}
+	return strings.Join(s, " ")
+}
+
+// readelfSymbolType returns the symbol type column of readelf -s.
+func readelfSymbolType(machine uint16, osabi uint8, t uint8) string {
+	switch t {
+	case 0:
+		return "NOTYPE"
+	case 1:
+		return "OBJECT"
+	case 2:
+		return "FUNC"
+	case 3:
+		return "SECTION"
+	case 4:
+		return "FILE"
+	case 5:
+		return "COMMON"
+	case 6:
+		return "TLS"
+	}
+	switch {
+	case t >= 13:
+		if Machine(machine) == EM_ARM && t == 13 {
+			return "THUMB_FUNC"
+		}
+		if Machine(machine) == EM_SPARCV9 && t == 13 {
+			return "REGISTER"
+		}
+		return fmt.Sprintf("<processor specific>: %d", t)
+	case t >= 10:
+		if t == 10 && (OSABI(osabi) == ELFOSABI_LINUX || OSABI(osabi) == ELFOSABI_FREEBSD) {
+			return "IFUNC"
+		}
+		return fmt.Sprintf("<OS specific>: %d", t)
+	}
+	return fmt.Sprintf("<unknown>: %d", t)
+}
+
+// readelfSymbolBind returns the binding column of readelf -s.
+func readelfSymbolBind(osabi uint8, b uint8) string {
+	switch b {
+	case 0:
+		return "LOCAL"
+	case 1:
+		return "GLOBAL"
+	case 2:
+		return "WEAK"
+	}
+	switch {
+	case b >= 13:
+		return fmt.Sprintf("<processor specific>: %d", b)
+	case b >= 10:
+		if b == 10 && OSABI(osabi) == ELFOSABI_LINUX {
+			return "UNIQUE"
+		}
+		return fmt.Sprintf("<OS specific>: %d", b)
+	}
+	return fmt.Sprintf("<unknown>: %d", b)
+}
+
+// readelfSymbolOther describes the st_other bits beside the visibility.
+func readelfSymbolOther(machine uint16, other uint8) string {
+	switch Machine(machine) {
+	case EM_AARCH64:
+		if other == 0x80 {
+			return "VARIANT_PCS"
+		}
+	case EM_RISCV:
+		if other == 0x80 {
+			return "VARIANT_CC"
+		}
+	case EM_MIPS, EM_MIPS_RS3_LE:
+		switch other {
+		case 0x04:
+			return "OPTIONAL"
+		case 0x08:
+			return "MIPS PLT"
+		case 0x20:
+			return "MIPS PIC"
+		case 0x80:
+			return "MICROMIPS"
+		case 0xf0:
+			return "MIPS16"
+		}
+	}
+	return fmt.Sprintf("<other>: %x", other)
+}
+
+// readelfSymbolIndex returns the Ndx column of readelf -s.
+func readelfSymbolIndex(machine uint16, shnum int, idx uint16) string {
+	switch idx {
+	case 0:
+		return "UND"
+	case 0xfff1:
+		return "ABS"
+	case 0xfff2:
+		return "COM"
+	}
+	switch {
+	case Machine(machine) == EM_X86_64 && idx == 0xff02:
+		return "LARGE_COM"
+	case (Machine(machine) == EM_MIPS || Machine(machine) == EM_MIPS_RS3_LE) && idx == 0xff03:
+		return "SCOM"
+	case (Machine(machine) == EM_MIPS || Machine(machine) == EM_MIPS_RS3_LE) && idx == 0xff04:
+		return "SUND"
+	case idx >= 0xff00 && idx <= 0xff1f:
+		return fmt.Sprintf("PRC[0x%04x]", idx)
+	case idx >= 0xff20 && idx <= 0xff3f:
+		return fmt.Sprintf("OS [0x%04x]", idx)
+	case idx >= 0xff00:
+		return fmt.Sprintf("RSV[0x%04x]", idx)
+	case shnum != 0 && int(idx) >= shnum:
+		return fmt.Sprintf("bad section index[%3d]", idx)
+	}
+	return fmt.Sprintf("%3d", idx)
+}
+
+var readelfVisibility = []string{"DEFAULT", "INTERNAL", "HIDDEN", "PROTECTED"}
+
+// readelfSectionFlags returns the Flg column of readelf -S.
+func readelfSectionFlags(machine uint16, osabi uint8, flags uint64) string {
+	letters := map[uint64]byte{0x1: 'W', 0x2: 'A', 0x4: 'X', 0x10: 'M', 0x20: 'S', 0x40: 'I',
+		0x80: 'L', 0x100: 'O', 0x200: 'G', 0x400: 'T', 0x80000000: 'E', 0x800: 'C'}
+	var b []byte
+	for flags != 0 {
+		flag := flags & -flags
+		flags &^= flag
+		if c, ok := letters[flag]; ok {
+			b = append(b, c)
+			continue
+		}
+		switch {
+		case Machine(machine) == EM_X86_64 && flag == 0x10000000:
+			b = append(b, 'l')
+		case Machine(machine) == EM_ARM && flag == 0x20000000:
+			b = append(b, 'y')
+		case Machine(machine) == EM_PPC && flag == 0x10000000:
+			b = append(b, 'v')
+		case flag&0x0ff00000 != 0:
+			gnu := OSABI(osabi) == ELFOSABI_LINUX || OSABI(osabi) == ELFOSABI_FREEBSD
+			switch {
+			case gnu && flag == 0x200000:
+				b = append(b, 'R')
+			case (gnu || osabi == 0) && flag == 0x01000000:
+				b = append(b, 'D')
+			default:
+				b = append(b, 'o')
+				flags &^= 0x0ff00000
+			}
+		case flag&0xf0000000 != 0:
+			b = append(b, 'p')
+			flags &^= 0xf0000000
+		default:
+			b = append(b, 'x')
+		}
+	}
+	return string(b)
+}
+
+var gnuAbiTagOS = []string{"Linux", "Hurd", "Solaris", "FreeBSD", "NetBSD", "Syllable", "NaCl"}
+
+// readelfNoteType returns the note type column of readelf -n.
+func readelfNoteType(owner string, core bool, t uint32) string {
+	switch {
+	case owner == "":
+	case strings.HasPrefix(owner, "GNU"):
+		switch t {
+		case 1:
+			return "NT_GNU_ABI_TAG (ABI version tag)"
+		case 2:
+			return "NT_GNU_HWCAP (DSO-supplied software HWCAP info)"
+		case 3:
+			return "NT_GNU_BUILD_ID (unique build ID bitstring)"
+		case 4:
+			return "NT_GNU_GOLD_VERSION (gold version)"
+		case 5:
+			return "NT_GNU_PROPERTY_TYPE_0"
+		case 0x100:
+			return "NT_GNU_BUILD_ATTRIBUTE_OPEN"
+		case 0x101:
+			return "NT_GNU_BUILD_ATTRIBUTE_FUNC"
+		}
+		return fmt.Sprintf("Unknown note type: (0x%08x)", t)
+	case strings.HasPrefix(owner, "FreeBSD") && !core:
+		switch t {
+		case 1:
+			return "NT_FREEBSD_ABI_TAG"
+		case 2:
+			return "NT_FREEBSD_NOINIT_TAG"
+		case 3:
+			return "NT_FREEBSD_ARCH_TAG"
+		case 4:
+			return "NT_FREEBSD_FEATURE_CTL"
+		}
+	}
+	if core {
+		switch t {
+		case 1:
+			return "NT_PRSTATUS (prstatus structure)"
+		case 2:
+			return "NT_FPREGSET (floating point registers)"
+		case 3:
+			return "NT_PRPSINFO (prpsinfo structure)"
+		case 4:
+			return "NT_TASKSTRUCT (task structure)"
+		case 6:
+			return "NT_AUXV (auxiliary vector)"
+		case 0x46e62b7f:
+			return "NT_PRXFPREG (user_xfpregs structure)"
+		case 0x53494749:
+			return "NT_SIGINFO (siginfo_t data)"
+		case 0x46494c45:
+			return "NT_FILE (mapped files)"
+		case 0x202:
+			return "NT_X86_XSTATE (x86 XSAVE extended state)"
+		}
+	} else {
+		switch t {
+		case 1:
+			return "NT_VERSION (version)"
+		case 2:
+			return "NT_ARCH (architecture)"
+		case 0x100:
+			return "OPEN"
+		case 0x101:
+			return "func"
+		case 4:
+			if owner == "Go" {
+				return "GO BUILDID"
+			}
+		case 0xcafe1a7e:
+			return "FDO_PACKAGING_METADATA"
+		}
+	}
+	return fmt.Sprintf("Unknown note type: (0x%08x)", t)
+}
+
+// readelfBits joins the names of the bits set in mask, lowest bit first,
+// the way the GNU property decoders of readelf do.
+func readelfBits(mask uint32, names []string) string {
+	if mask == 0 {
+		return "<None>"
+	}
+	var s []string
+	for mask != 0 {
+		bit := mask & -mask
+		mask &^= bit
+		i := 0
+		for bit>>uint(i) != 1 {
+			i++
+		}
+		if i < len(names) && names[i] != "" {
+			s = append(s, names[i])
+		} else {
+			s = append(s, fmt.Sprintf("<unknown: %x>", bit))
+		}
+	}
+	return strings.Join(s, ", ")
+}
+
+var (
+	x86ISANames         = []string{"x86-64-baseline", "x86-64-v2", "x86-64-v3", "x86-64-v4"}
+	x86Feature1Names    = []string{"IBT", "SHSTK", "LAM_U48", "LAM_U57"}
+	x86Feature2Names    = []string{"x86", "x87", "MMX", "XMM", "YMM", "ZMM", "FXSR", "XSAVE", "XSAVEOPT", "XSAVEC", "TMM", "MASK"}
+	x86CompatISANames   = []string{"i486", "586", "686", "SSE", "SSE2", "SSE3", "SSSE3", "SSE4_1", "SSE4_2", "AVX", "AVX2", "AVX512F", "AVX512CD", "AVX512ER", "AVX512PF", "AVX512VL", "AVX512DQ", "AVX512BW"}
+	x86Compat2ISANames  = []string{"CMOV", "SSE", "SSE2", "SSE3", "SSSE3", "SSE4_1", "SSE4_2", "AVX", "AVX2", "FMA", "AVX512F", "AVX512CD", "AVX512ER", "AVX512PF", "AVX512VL", "AVX512DQ", "AVX512BW", "AVX512_4FMAPS", "AVX512_4VNNIW", "AVX512_BITALG", "AVX512_IFMA", "AVX512_VBMI", "AVX512_VBMI2", "AVX512_VNNI", "AVX512_BF16"}
+	aarch64FeatureNames = []string{"BTI", "PAC"}
+)
@@ -90,56 +90,359 @@ var RelaTypeStrings = []flagName{
 func (rt ReloType) String() string   { return stringify(uint32(rt), RelaTypeStrings, false) }
 func (rt ReloType) GoString() string { return stringify(uint32(rt), RelaTypeStrings, true) }
 
+// decodeRelocations decodes a table of Rel or Rela entries, a truncated
+// trailing entry is ignored.
+func (p *Parser) decodeRelocations(data []byte, rela bool, table DynTag) []Relocation {
+	r := bytes.NewReader(data)
+	var relocs []Relocation
+	for r.Len() > 0 {
+		rel := Relocation{HasAddend: rela, Table: table}
+		switch {
+		case p.F.Class() == ELFCLASS64 && rela:
+			var e Rela64
+			if err := binary.Read(r, p.F.ByteOrder(), &e); err != nil {
+				return relocs
+			}
+			rel.Off, rel.Info, rel.Addend = e.Off, e.Info, e.Addend
+		case p.F.Class() == ELFCLASS64:
+			var e Rel64
+			if err := binary.Read(r, p.F.ByteOrder(), &e); err != nil {
+				return relocs
+			}
+			rel.Off, rel.Info = e.Off, e.Info
+		case rela:
+			var e Rela32
+			if err := binary.Read(r, p.F.ByteOrder(), &e); err != nil {
+				return relocs
+			}
+			rel.Off, rel.Info, rel.Addend = uint64(e.Off), uint64(e.Info), int64(e.Addend)
+		default:
+			var e Rel32
+			if err := binary.Read(r, p.F.ByteOrder(), &e); err != nil {
+				return relocs
+			}
+			rel.Off, rel.Info = uint64(e.Off), uint64(e.Info)
+		}
+		machine := Machine(p.F.rawHeader().Machine)
+		switch {
+		case p.F.Class() == ELFCLASS64 && machine == EM_MIPS:
+			// MIPS64的r_info由32位符号索引和r_ssym、r_type3、r_type2、r_type四个字节组成，
+			// 小端文件中并不是一个小端的64位整数，需要重新排列
+			if p.F.Ident.Data == ELFDATA2LSB {
+				info := rel.Info
+				rel.Info = info<<32 | (info>>56)&0xff | (info>>40)&0xff00 |
+					(info>>24)&0xff0000 | (info>>8)&0xff000000
+			}
+			rel.Type, rel.Sym = uint32(rel.Info&0xff), R_SYM64(rel.Info)
+		case p.F.Class() == ELFCLASS64 && machine == EM_SPARCV9:
+			// 高24位是R_SPARC_OLO10使用的附加数据
+			rel.Type, rel.Sym = uint32(rel.Info&0xff), R_SYM64(rel.Info)
+		case p.F.Class() == ELFCLASS64:
+			rel.Type, rel.Sym = R_TYPE64(rel.Info), R_SYM64(rel.Info)
+		default:
+			rel.Type, rel.Sym = R_TYPE32(uint32(rel.Info)), R_SYM32(uint32(rel.Info))
+		}
+		relocs = append(relocs, rel)
+	}
+	return relocs
+}
+
+// SectionRelocations decodes the SHT_REL or SHT_RELA section s.
+func (p *Parser) SectionRelocations(s *ELF64Section) ([]Relocation, error) {
+	typ := SectionType(s.Type)
+	if typ != SHT_REL && typ != SHT_RELA {
+		return nil, errors.New("section " + s.SectionName + " is not a relocation section")
+	}
+	data, err := s.Data()
+	if err != nil {
+		return nil, err
+	}
+	return p.decodeRelocations(data, typ == SHT_RELA, DT_NULL), nil
+}
+
+// RelocTypeString returns the name of a relocation type for the machine of the file.
+func (f *File) RelocTypeString(typ uint32) string {
+	machine := Machine(f.Header64.Machine)
+	if f.Class() == ELFCLASS32 {
+		machine = Machine(f.Header32.Machine)
+	}
+	switch machine {
+	case EM_X86_64:
+		return R_X86_64(typ).String()
+	case EM_386:
+		return R_386(typ).String()
+	case EM_AARCH64:
+		return R_AARCH64(typ).String()
+	case EM_ARM:
+		return R_ARM(typ).String()
+	case EM_MIPS, EM_MIPS_RS3_LE:
+		return R_MIPS(typ).String()
+	case EM_PPC:
+		return R_PPC(typ).String()
+	case EM_PPC64:
+		return R_PPC64(typ).String()
+	case EM_RISCV:
+		return R_RISCV(typ).String()
+	case EM_S390:
+		return R_390(typ).String()
+	case EM_SPARC, EM_SPARCV9, EM_SPARC32PLUS:
+		return R_SPARC(typ).String()
+	case EM_ALPHA:
+		return R_ALPHA(typ).String()
+	case EM_BPF:
+		return R_BPF(typ).String()
+	}
+	return ReloType(typ).String()
+}
+
+// relocationKind describes a data relocation: the width of the patched
+// field in bits, whether the place address is subtracted from the value
+// and how the value is combined with the field.
+type relocationKind struct {
+	bits  int
+	pcrel bool
+	op    relocationOp
+}
+
+// relocationOp is how a relocation value is combined with its field.
+type relocationOp int
+
+const (
+	// relocStore writes the value, the REL addend being read from the field.
+	relocStore relocationOp = iota
+	// relocAdd and relocSub add the value to or subtract it from the
+	// field, RISC-V encodes label differences as an ADD/SUB pair.
+	relocAdd
+	relocSub
+)
+
+// relocationKinds lists, per machine, the absolute and PC relative data
+// relocations that can be applied without a linker. These are the ones
+// readelf -R knows, found in the debug sections of relocatable files.
+var relocationKinds = map[Machine]map[uint32]relocationKind{
+	EM_386: {
+		uint32(R_386_32):   {bits: 32},
+		uint32(R_386_PC32): {bits: 32, pcrel: true},
+	},
+	EM_X86_64: {
+		uint32(R_X86_64_32):   {bits: 32},
+		uint32(R_X86_64_PC32): {bits: 32, pcrel: true},
+		uint32(R_X86_64_64):   {bits: 64},
+		uint32(R_X86_64_PC64): {bits: 64, pcrel: true},
+	},
+	EM_AARCH64: {
+		uint32(R_AARCH64_ABS32):  {bits: 32},
+		uint32(R_AARCH64_PREL32): {bits: 32, pcrel: true},
+		uint32(R_AARCH64_ABS64):  {bits: 64},
+		uint32(R_AARCH64_PREL64): {bits: 64, pcrel: true},
+	},
+	EM_ARM: {
+		uint32(R_ARM_ABS32): {bits: 32},
+		uint32(R_ARM_REL32): {bits: 32, pcrel: true},
+	},
+	EM_MIPS: {
+		uint32(R_MIPS_32): {bits: 32},
+		uint32(R_MIPS_64): {bits: 64},
+	},
+	EM_PPC: {
+		uint32(R_PPC_ADDR32): {bits: 32},
+		uint32(R_PPC_REL32):  {bits: 32, pcrel: true},
+	},
+	EM_PPC64: {
+		uint32(R_PPC64_ADDR32): {bits: 32},
+		uint32(R_PPC64_REL32):  {bits: 32, pcrel: true},
+		uint32(R_PPC64_ADDR64): {bits: 64},
+		uint32(R_PPC64_REL64):  {bits: 64, pcrel: true},
+	},
+	EM_RISCV: {
+		uint32(R_RISCV_32):       {bits: 32},
+		uint32(R_RISCV_32_PCREL): {bits: 32, pcrel: true},
+		uint32(R_RISCV_64):       {bits: 64},
+		uint32(R_RISCV_ADD8):     {bits: 8, op: relocAdd},
+		uint32(R_RISCV_ADD16):    {bits: 16, op: relocAdd},
+		uint32(R_RISCV_ADD32):    {bits: 32, op: relocAdd},
+		uint32(R_RISCV_ADD64):    {bits: 64, op: relocAdd},
+		uint32(R_RISCV_SUB6):     {bits: 6, op: relocSub},
+		uint32(R_RISCV_SUB8):     {bits: 8, op: relocSub},
+		uint32(R_RISCV_SUB16):    {bits: 16, op: relocSub},
+		uint32(R_RISCV_SUB32):    {bits: 32, op: relocSub},
+		uint32(R_RISCV_SUB64):    {bits: 64, op: relocSub},
+		uint32(R_RISCV_SET6):     {bits: 6},
+		uint32(R_RISCV_SET8):     {bits: 8},
+		uint32(R_RISCV_SET16):    {bits: 16},
+		uint32(R_RISCV_SET32):    {bits: 32},
+	},
+	EM_S390: {
+		uint32(R_390_32):   {bits: 32},
+		uint32(R_390_PC32): {bits: 32, pcrel: true},
+		uint32(R_390_64):   {bits: 64},
+		uint32(R_390_PC64): {bits: 64, pcrel: true},
+	},
+	EM_SPARCV9: {
+		uint32(R_SPARC_32):     {bits: 32},
+		uint32(R_SPARC_UA32):   {bits: 32},
+		uint32(R_SPARC_DISP32): {bits: 32, pcrel: true},
+		uint32(R_SPARC_64):     {bits: 64},
+		uint32(R_SPARC_UA64):   {bits: 64},
+		uint32(R_SPARC_DISP64): {bits: 64, pcrel: true},
+	},
+	EM_ALPHA: {
+		uint32(R_ALPHA_REFLONG): {bits: 32},
+		uint32(R_ALPHA_REFQUAD): {bits: 64},
+	},
+}
+
+func init() {
+	// 32位SPARC与SPARC v9共用同一套重定位类型
+	relocationKinds[EM_SPARC] = relocationKinds[EM_SPARCV9]
+	relocationKinds[EM_SPARC32PLUS] = relocationKinds[EM_SPARCV9]
+	relocationKinds[EM_MIPS_RS3_LE] = relocationKinds[EM_MIPS]
+}
+
 // ApplyRelocations will apply relocations depending on the target binary.
 // This step essentially processes symbolic references to their definitions.
+// rels is the content of a SHT_RELA section against .symtab, only the data
+// relocations of relocationKinds are applied, the others are skipped.
 func (p *Parser) ApplyRelocations(dst []byte, rels []byte) error {
-	switch {
-	case p.F.Class() == ELFCLASS64 && p.F.Machine == EM_X86_64:
-		return p.applyRelocationsAMD64(dst, rels)
-	default:
-		return errors.New("not implemented")
+	machine := Machine(p.F.rawHeader().Machine)
+	if relocationKinds[machine] == nil {
+		return errors.New("relocations of " + machine.String() + " are not supported")
+	}
+	symtab := p.F.SectionByType(SHT_SYMTAB)
+	if symtab == nil {
+		return ErrNoSymbols
+	}
+	syms, err := p.tableSymbols(symtab)
+	if err != nil {
+		return err
+	}
+	p.applyRelocations(dst, p.decodeRelocations(rels, true, DT_NULL), syms)
+	return nil
+}
+
+// applyRelocations patches dst with the relocations rels resolved against
+// syms, the raw entries of the symbol table. Like readelf the result is
+// S + A, minus the place for PC relative types; Rel entries take the addend
+// from dst. Unknown types and out of range entries are skipped.
+func (p *Parser) applyRelocations(dst []byte, rels []Relocation, syms []Symbol) {
+	kinds := relocationKinds[Machine(p.F.rawHeader().Machine)]
+	for _, r := range rels {
+		k, ok := kinds[r.Type]
+		size := uint64(k.bits+7) / 8
+		if !ok || int(r.Sym) >= len(syms) || r.Off >= uint64(len(dst)) || size > uint64(len(dst))-r.Off {
+			continue
+		}
+		sym := syms[r.Sym]
+		// 文件名之类的符号没有地址可言，readelf同样跳过
+		if t := SymType(sym.Info & 0xf); t > STT_SECTION && t != STT_COMMON && t != STT_TLS {
+			continue
+		}
+		loc := dst[r.Off : r.Off+size]
+		field := p.relocationField(loc)
+		v := sym.Value + uint64(r.Addend)
+		if !r.HasAddend {
+			v += field
+		}
+		if k.pcrel {
+			v -= r.Off
+		}
+		switch k.op {
+		case relocAdd:
+			v = field + v
+		case relocSub:
+			v = field - v
+		}
+		if k.bits == 6 {
+			// 6位的字段是DW_CFA_advance_loc的低6位，高2位是操作码
+			v = field&^0x3f | v&0x3f
+		}
+		p.putRelocationField(loc, v)
 	}
 }
 
-// applyRelocationsAMD64 applies relocatons to dst where rels is a relocations section
-// for AMD64 (64-bit binaries & x86-64 machine types).
-func (p *Parser) applyRelocationsAMD64(dst []byte, rels []byte) error {
-	// 24 is the size of Rela64.
-	if len(rels)%24 != 0 {
-		return errors.New("length of relocation section is not a multiple of 24")
+// relocationField reads a relocation field of len(loc) bytes.
+func (p *Parser) relocationField(loc []byte) uint64 {
+	order := p.F.ByteOrder()
+	switch len(loc) {
+	case 1:
+		return uint64(loc[0])
+	case 2:
+		return uint64(order.Uint16(loc))
+	case 4:
+		return uint64(order.Uint32(loc))
+	}
+	return order.Uint64(loc)
+}
+
+// putRelocationField writes v, truncated, to a field of len(loc) bytes.
+func (p *Parser) putRelocationField(loc []byte, v uint64) {
+	order := p.F.ByteOrder()
+	switch len(loc) {
+	case 1:
+		loc[0] = byte(v)
+	case 2:
+		order.PutUint16(loc, uint16(v))
+	case 4:
+		order.PutUint32(loc, uint32(v))
+	default:
+		order.PutUint64(loc, v)
 	}
-	symbols, _, err := p.getSymbols64(SHT_SYMTAB)
+}
+
+// tableSymbols decodes every entry of the symbol table s, entry 0 included
+// so relocation symbol indexes can be used directly. Names are left empty.
+func (p *Parser) tableSymbols(s *ELF64Section) ([]Symbol, error) {
+	data, err := s.Data()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	size := p.symbolEntrySize()
+	syms := make([]Symbol, 0, len(data)/size)
+	for off := 0; off+size <= len(data); off += size {
+		sym, _ := p.decodeSymbol(data[off : off+size])
+		syms = append(syms, sym)
+	}
+	return syms, nil
+}
+
+// relocationSections returns the non-empty REL and RELA sections whose
+// sh_info names section index i.
+func (p *Parser) relocationSections(i int) []*ELF64Section {
+	var rels []*ELF64Section
+	for _, s := range p.F.Sections() {
+		typ := SectionType(s.Type)
+		if (typ == SHT_REL || typ == SHT_RELA) && int(s.Info) == i && s.ELF64SectionHeader.Size != 0 {
+			rels = append(rels, s)
+		}
 	}
-	b := bytes.NewReader(rels)
-	var rela Rela64
-	for b.Len() > 0 {
-		binary.Read(b, p.F.ByteOrder(), &rela)
-		symNo := rela.Info >> 32
-		t := R_X86_64(rela.Info & 0xffff)
-		if symNo == 0 || symNo > uint64(len(symbols)) {
+	return rels
+}
+
+// relocateSection applies the relocations targeting section index i to
+// data, its contents. Like readelf -R only relocatable files are processed,
+// the relocations of linked files have already been resolved by ld.
+func (p *Parser) relocateSection(i int, data []byte) error {
+	if Type(p.F.rawHeader().Type) != ET_REL {
+		return nil
+	}
+	sections := p.F.Sections()
+	for _, rs := range p.relocationSections(i) {
+		if int(rs.Link) >= len(sections) {
 			continue
 		}
-		sym := &symbols[symNo-1]
-
-		// There are relocations, so this must be a normal
-		// object file.  The code below handles only basic relocations
-		// of the form S + A (symbol plus addend).
-		switch t {
-		case R_X86_64_64:
-			if rela.Off+8 >= uint64(len(dst)) || rela.Addend < 0 {
-				continue
-			}
-			val64 := sym.Value + uint64(rela.Addend)
-			p.F.ByteOrder().PutUint64(dst[rela.Off:rela.Off+8], val64)
-		case R_X86_64_32:
-			if rela.Off+4 >= uint64(len(dst)) || rela.Addend < 0 {
-				continue
-			}
-			val32 := uint32(sym.Value) + uint32(rela.Addend)
-			p.F.ByteOrder().PutUint32(dst[rela.Off:rela.Off+4], val32)
+		symtab := sections[rs.Link]
+		if typ := SectionType(symtab.Type); typ != SHT_SYMTAB && typ != SHT_DYNSYM {
+			continue
+		}
+		syms, err := p.tableSymbols(symtab)
+		if err != nil {
+			return err
+		}
+		rels, err := p.SectionRelocations(rs)
+		if err != nil {
+			return err
 		}
+		p.applyRelocations(data, rels, syms)
 	}
 	return nil
 }
@@ -0,0 +1,240 @@
+// Package elf : render.go writes the views built by report.go to an
+// io.Writer as text, JSON, YAML, CSV or Markdown tables.
+package elf
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format names an output format of the renderers.
+type Format string
+
+const (
+	FormatText     Format = "text"
+	FormatJSON     Format = "json"
+	FormatYAML     Format = "yaml"
+	FormatCSV      Format = "csv"
+	FormatMarkdown Format = "markdown"
+)
+
+// ErrUnknownFormat is returned by NewRenderer for an unsupported format.
+var ErrUnknownFormat = errors.New("unknown output format")
+
+// Renderer writes views to w.
+type Renderer interface {
+	Render(w io.Writer, views []*View) error
+}
+
+// NewRenderer returns the renderer of the format, "md" is accepted as an
+// alias of markdown.
+func NewRenderer(format Format) (Renderer, error) {
+	switch format {
+	case FormatText, "":
+		return TextRenderer{}, nil
+	case FormatJSON:
+		return JSONRenderer{Indent: "  "}, nil
+	case FormatYAML:
+		return YAMLRenderer{}, nil
+	case FormatCSV:
+		return CSVRenderer{}, nil
+	case FormatMarkdown, "md":
+		return MarkdownRenderer{}, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+}
+
+// WriteReport builds the views (all of them when kinds is empty) and
+// renders them to w in the given format.
+func (p *Parser) WriteReport(w io.Writer, format Format, kinds ...ViewKind) error {
+	r, err := NewRenderer(format)
+	if err != nil {
+		return err
+	}
+	views, err := p.BuildViews(kinds...)
+	if err != nil {
+		return err
+	}
+	return r.Render(w, views)
+}
+
+// errWriter keeps the first write error so the renderers can write
+// without checking every call.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, a ...interface{}) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, a...)
+}
+
+// TextRenderer prints aligned columns, like the Dump* functions.
+type TextRenderer struct{}
+
+// Render implements Renderer.
+func (TextRenderer) Render(w io.Writer, views []*View) error {
+	ew := &errWriter{w: w}
+	for i, v := range views {
+		if i != 0 {
+			ew.printf("\n")
+		}
+		if v.Note != "" {
+			ew.printf("%s\n", v.Note)
+		}
+		for j, t := range v.Tables {
+			if j != 0 {
+				ew.printf("\n")
+			}
+			ew.printf("%s:\n", t.Title)
+			widths := make([]int, len(t.Columns))
+			for c, name := range t.Columns {
+				widths[c] = displayWidth(name)
+			}
+			for _, row := range t.Rows {
+				for c, cell := range row {
+					if c < len(widths) && displayWidth(cell) > widths[c] {
+						widths[c] = displayWidth(cell)
+					}
+				}
+			}
+			textRow(ew, widths, t.Columns)
+			for _, row := range t.Rows {
+				textRow(ew, widths, row)
+			}
+		}
+	}
+	return ew.err
+}
+
+// textRow prints one line, the last cell is not padded to avoid trailing blanks.
+func textRow(ew *errWriter, widths []int, cells []string) {
+	line := " "
+	for c, cell := range cells {
+		if c == len(cells)-1 || c >= len(widths) {
+			line += " " + cell
+		} else {
+			line += " " + cell + strings.Repeat(" ", widths[c]-displayWidth(cell))
+		}
+	}
+	ew.printf("%s\n", strings.TrimRight(line, " "))
+}
+
+// displayWidth returns the number of terminal columns of s, CJK characters
+// (the Chinese explanations) take two columns.
+func displayWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		switch {
+		case r >= 0x1100 && r <= 0x115f, r >= 0x2e80 && r <= 0xa4cf, r >= 0xac00 && r <= 0xd7a3,
+			r >= 0xf900 && r <= 0xfaff, r >= 0xfe30 && r <= 0xfe4f, r >= 0xff00 && r <= 0xff60, r >= 0xffe0 && r <= 0xffe6:
+			n += 2
+		default:
+			n++
+		}
+	}
+	return n
+}
+
+// JSONRenderer encodes the views as one JSON document {"views": [...]}.
+type JSONRenderer struct {
+	Indent string
+}
+
+// Render implements Renderer.
+func (r JSONRenderer) Render(w io.Writer, views []*View) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", r.Indent)
+	return enc.Encode(struct {
+		Views []*View `json:"views"`
+	}{views})
+}
+
+// YAMLRenderer encodes the views as one YAML document.
+type YAMLRenderer struct{}
+
+// Render implements Renderer.
+func (YAMLRenderer) Render(w io.Writer, views []*View) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(struct {
+		Views []*View `yaml:"views"`
+	}{views}); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// CSVRenderer writes every table with its own header line, the first two
+// fields of each record are the view and the table title so that the
+// tables stay apart once loaded.
+type CSVRenderer struct{}
+
+// Render implements Renderer.
+func (CSVRenderer) Render(w io.Writer, views []*View) error {
+	cw := csv.NewWriter(w)
+	for _, v := range views {
+		for _, t := range v.Tables {
+			if err := cw.Write(append([]string{"view", "table"}, t.Columns...)); err != nil {
+				return err
+			}
+			for _, row := range t.Rows {
+				if err := cw.Write(append([]string{string(v.Kind), t.Title}, row...)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// MarkdownRenderer writes a heading per view and a pipe table per table.
+type MarkdownRenderer struct{}
+
+// markdownCell escapes the characters which would break a pipe table.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+// Render implements Renderer.
+func (MarkdownRenderer) Render(w io.Writer, views []*View) error {
+	ew := &errWriter{w: w}
+	for i, v := range views {
+		if i != 0 {
+			ew.printf("\n")
+		}
+		ew.printf("## %s\n", v.Kind)
+		if v.Note != "" {
+			ew.printf("\n%s\n", markdownCell(v.Note))
+		}
+		for _, t := range v.Tables {
+			ew.printf("\n### %s\n\n", markdownCell(t.Title))
+			cells := make([]string, len(t.Columns))
+			for c, name := range t.Columns {
+				cells[c] = markdownCell(name)
+			}
+			ew.printf("| %s |\n", strings.Join(cells, " | "))
+			ew.printf("|%s\n", strings.Repeat(" --- |", len(t.Columns)))
+			for _, row := range t.Rows {
+				cells = cells[:0]
+				for _, cell := range row {
+					cells = append(cells, markdownCell(cell))
+				}
+				ew.printf("| %s |\n", strings.Join(cells, " | "))
+			}
+		}
+	}
+	return ew.err
+}
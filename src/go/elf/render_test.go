@@ -0,0 +1,85 @@
+package elf
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+// failingWriter fails every write, the renderers must report it.
+type failingWriter struct{}
+
+var errWrite = errors.New("write failed")
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errWrite }
+
+func TestRenderers(t *testing.T) {
+	p, err := New(path.Join(exampleDir, "gcc-amd64-linux-exec"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.CloseFile()
+	if err := p.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	views, err := p.BuildViews()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, views, len(AllViews))
+
+	relocs, err := p.BuildView(ViewRelocations)
+	if assert.NoError(t, err) && assert.Len(t, relocs.Tables, 2) {
+		assert.Equal(t, ".rela.plt", relocs.Tables[1].Title)
+		assert.Equal(t, []string{"0x0000000000600870", "0x0000000200000007", "R_X86_64_JUMP_SLOT",
+			"0x0000000000000000", "puts@GLIBC_2.2.5", "0"}, relocs.Tables[1].Rows[0])
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, p.WriteReport(&out, FormatText, ViewDynamic))
+	assert.Contains(t, out.String(), "  0x0000000000000001 NEEDED     libc.so.6\n")
+
+	out.Reset()
+	assert.NoError(t, p.WriteReport(&out, FormatJSON))
+	var doc struct {
+		Views []View `json:"views"`
+	}
+	if assert.NoError(t, json.Unmarshal(out.Bytes(), &doc)) && assert.Len(t, doc.Views, len(AllViews)) {
+		assert.Equal(t, ViewHeader, doc.Views[0].Kind)
+		assert.Equal(t, []string{"Type", "EXEC (Executable file)"}, doc.Views[0].Tables[0].Rows[6])
+	}
+
+	out.Reset()
+	assert.NoError(t, p.WriteReport(&out, FormatYAML, ViewSegments))
+	doc.Views = nil
+	if assert.NoError(t, yaml.Unmarshal(out.Bytes(), &doc)) && assert.Len(t, doc.Views, 1) {
+		assert.Equal(t, "INTERP", doc.Views[0].Tables[0].Rows[1][1])
+	}
+
+	out.Reset()
+	assert.NoError(t, p.WriteReport(&out, FormatCSV, ViewGOT))
+	records, err := csv.NewReader(&out).ReadAll()
+	if assert.NoError(t, err) && assert.Len(t, records, 8) {
+		assert.Equal(t, []string{"view", "table", "Index", "Address", "Value", "Description"}, records[0])
+		assert.Equal(t, []string{"got", ".got.plt", "0", "0x0000000000600858", "0x00000000006006b0", "address of .dynamic section"}, records[3])
+	}
+
+	out.Reset()
+	assert.NoError(t, p.WriteReport(&out, FormatMarkdown, ViewSections))
+	assert.True(t, strings.HasPrefix(out.String(), "## sections\n\n### Section Headers\n\n| Nr | Name |"))
+
+	for _, format := range []Format{FormatText, FormatJSON, FormatYAML, FormatCSV, FormatMarkdown} {
+		err := p.WriteReport(failingWriter{}, format, ViewHeader)
+		assert.Error(t, err, format)
+	}
+	assert.True(t, errors.Is(p.WriteReport(&out, "xml"), ErrUnknownFormat))
+	_, err = p.BuildView("strings")
+	assert.True(t, errors.Is(err, ErrUnknownView))
+}
@@ -0,0 +1,372 @@
+// Package elf : report.go builds the structured model behind the reports.
+// Each view (header, sections, segments, dynamic, symbols, relocations, GOT)
+// is turned into tables of cells which render.go writes in any format.
+package elf
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ViewKind names a report view.
+type ViewKind string
+
+const (
+	ViewHeader      ViewKind = "header"
+	ViewSections    ViewKind = "sections"
+	ViewSegments    ViewKind = "segments"
+	ViewDynamic     ViewKind = "dynamic"
+	ViewSymbols     ViewKind = "symbols"
+	ViewDynSyms     ViewKind = "dyn-syms"
+	ViewRelocations ViewKind = "relocations"
+	ViewGOT         ViewKind = "got"
+)
+
+// AllViews lists every view in the order they are reported.
+var AllViews = []ViewKind{ViewHeader, ViewSections, ViewSegments, ViewDynamic, ViewSymbols, ViewRelocations, ViewGOT}
+
+// Table is a titled grid of cells, every row has one cell per column.
+type Table struct {
+	Title   string     `json:"title" yaml:"title"`
+	Columns []string   `json:"columns" yaml:"columns"`
+	Rows    [][]string `json:"rows" yaml:"rows"`
+}
+
+// View is the structured model of one view, a view without data holds a
+// note explaining why and no table.
+type View struct {
+	Kind   ViewKind `json:"view" yaml:"view"`
+	Note   string   `json:"note,omitempty" yaml:"note,omitempty"`
+	Tables []*Table `json:"tables" yaml:"tables"`
+}
+
+// ErrUnknownView is returned when a view name is not one of AllViews.
+var ErrUnknownView = errors.New("unknown report view")
+
+func (v *View) addTable(title string, columns ...string) *Table {
+	t := &Table{Title: title, Columns: columns}
+	v.Tables = append(v.Tables, t)
+	return t
+}
+
+func (t *Table) addRow(cells ...string) {
+	t.Rows = append(t.Rows, cells)
+}
+
+// hexWidth formats an address or an offset with the width of the class.
+func (p *Parser) hexWidth(v uint64) string {
+	if p.F.Class() == ELFCLASS32 {
+		return fmt.Sprintf("0x%08x", v)
+	}
+	return fmt.Sprintf("0x%016x", v)
+}
+
+func hexString(v uint64) string { return "0x" + strconv.FormatUint(v, 16) }
+
+func decString(v uint64) string { return strconv.FormatUint(v, 10) }
+
+// BuildView builds the model of the view kind.
+func (p *Parser) BuildView(kind ViewKind) (*View, error) {
+	if p.F == nil {
+		return nil, ErrBadELFClass
+	}
+	v := &View{Kind: kind}
+	var err error
+	switch kind {
+	case ViewHeader:
+		err = p.headerView(v)
+	case ViewSections:
+		err = p.sectionsView(v)
+	case ViewSegments:
+		err = p.segmentsView(v)
+	case ViewDynamic:
+		err = p.dynamicView(v)
+	case ViewSymbols:
+		err = p.symbolsView(v, false)
+	case ViewDynSyms:
+		err = p.symbolsView(v, true)
+	case ViewRelocations:
+		err = p.relocationsView(v)
+	case ViewGOT:
+		err = p.gotView(v)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownView, kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// BuildViews builds the given views, AllViews when none is given.
+func (p *Parser) BuildViews(kinds ...ViewKind) ([]*View, error) {
+	if len(kinds) == 0 {
+		kinds = AllViews
+	}
+	views := make([]*View, 0, len(kinds))
+	for _, kind := range kinds {
+		v, err := p.BuildView(kind)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, v)
+	}
+	return views, nil
+}
+
+func (p *Parser) headerView(v *View) error {
+	if !IsValidELFClass(p.F.Class()) {
+		return ErrBadELFClass
+	}
+	h := p.F.rawHeader()
+	t := v.addTable("ELF Header", "Field", "Value")
+	magic := ""
+	for i, b := range h.Ident {
+		if i != 0 {
+			magic += " "
+		}
+		magic += fmt.Sprintf("%02x", b)
+	}
+	t.addRow("Magic", magic)
+	class := map[uint8]string{1: "ELF32", 2: "ELF64"}[h.Ident[EI_CLASS]]
+	data := map[uint8]string{1: "2's complement, little endian", 2: "2's complement, big endian"}[h.Ident[EI_DATA]]
+	osabi, ok := readelfOSABINames[h.Ident[EI_OSABI]]
+	if !ok {
+		osabi = fmt.Sprintf("<unknown: %x>", h.Ident[EI_OSABI])
+	}
+	// DF_1_PIE区分位置无关的可执行文件与共享库
+	flags1, _ := p.F.DynValue(DT_FLAGS_1)
+	t.addRow("Class", class)
+	t.addRow("Data", data)
+	t.addRow("Version", decString(uint64(h.Ident[EI_VERSION])))
+	t.addRow("OS/ABI", osabi)
+	t.addRow("ABI Version", decString(uint64(h.Ident[EI_ABIVERSION])))
+	t.addRow("Type", readelfFileType(h.Type, DynFlag1(flags1)&DF_1_PIE != 0))
+	t.addRow("Machine", readelfMachineName(h.Machine))
+	t.addRow("Entry point address", hexString(h.Entry))
+	t.addRow("Start of program headers", decString(h.Phoff))
+	t.addRow("Start of section headers", decString(h.Shoff))
+	t.addRow("Flags", hexString(uint64(h.Flags))+readelfMachineFlags(h.Machine, h.Flags))
+	t.addRow("Size of this header", decString(uint64(h.Ehsize)))
+	t.addRow("Size of program headers", decString(uint64(h.Phentsize)))
+	t.addRow("Number of program headers", decString(uint64(h.Phnum)))
+	t.addRow("Size of section headers", decString(uint64(h.Shentsize)))
+	t.addRow("Number of section headers", decString(uint64(h.Shnum)))
+	t.addRow("Section header string table index", decString(uint64(h.Shstrndx)))
+	return nil
+}
+
+func (p *Parser) sectionsView(v *View) error {
+	sections := p.F.Sections()
+	if len(sections) == 0 {
+		v.Note = "There are no sections in this file."
+		return nil
+	}
+	h := p.F.rawHeader()
+	t := v.addTable("Section Headers", "Nr", "Name", "Type", "Address", "Offset", "Size", "EntSize", "Flags", "Link", "Info", "Align")
+	for i, s := range sections {
+		t.addRow(decString(uint64(i)), s.SectionName, readelfSectionType(h.Machine, s.Type), p.hexWidth(s.Addr),
+			hexString(s.Off), hexString(s.Size), hexString(s.EntSize), readelfSectionFlags(h.Machine, h.Ident[EI_OSABI], s.Flags),
+			decString(uint64(s.Link)), decString(uint64(s.Info)), decString(s.AddrAlign))
+	}
+	return nil
+}
+
+// segmentFlags formats p_flags the way readelf does, "RWE" with spaces.
+func segmentFlags(flags uint32) string {
+	b := []byte("   ")
+	if flags&uint32(PF_R) != 0 {
+		b[0] = 'R'
+	}
+	if flags&uint32(PF_W) != 0 {
+		b[1] = 'W'
+	}
+	if flags&uint32(PF_X) != 0 {
+		b[2] = 'E'
+	}
+	return string(b)
+}
+
+func (p *Parser) segmentsView(v *View) error {
+	phdrs := p.F.ProgramHeaders()
+	if len(phdrs) == 0 {
+		v.Note = "There are no program headers in this file."
+		return nil
+	}
+	h := p.F.rawHeader()
+	t := v.addTable("Program Headers", "Nr", "Type", "Offset", "VirtAddr", "PhysAddr", "FileSiz", "MemSiz", "Flags", "Align", "Sections")
+	for i, ph := range phdrs {
+		names := ""
+		for _, s := range p.F.SegmentSections(ph) {
+			if names != "" {
+				names += " "
+			}
+			names += s.SectionName
+		}
+		t.addRow(decString(uint64(i)), readelfSegmentType(h.Machine, ph.Type), hexString(ph.Off), p.hexWidth(ph.Vaddr),
+			p.hexWidth(ph.Paddr), hexString(ph.Filesz), hexString(ph.Memsz), segmentFlags(ph.Flags), hexString(ph.Align), names)
+	}
+	return nil
+}
+
+func (p *Parser) dynamicView(v *View) error {
+	if len(p.F.DynamicEntries) == 0 {
+		v.Note = "There is no dynamic section in this file."
+		return nil
+	}
+	h := p.F.rawHeader()
+	strtab, _ := p.dynamicStringTable()
+	t := v.addTable("Dynamic Section", "Tag", "Type", "Value")
+	for _, d := range p.F.DynamicEntries {
+		value := hexString(d.Val)
+		switch d.Tag {
+		case DT_NEEDED, DT_SONAME, DT_RPATH, DT_RUNPATH:
+			if name, ok := getString(strtab, int(d.Val)); ok {
+				value = name
+			}
+		case DT_PLTRELSZ, DT_RELASZ, DT_RELAENT, DT_STRSZ, DT_SYMENT, DT_RELSZ, DT_RELENT,
+			DT_INIT_ARRAYSZ, DT_FINI_ARRAYSZ, DT_VERNEEDNUM, DT_VERDEFNUM:
+			value = decString(d.Val)
+		case DT_PLTREL:
+			value = readelfDynTag(h.Machine, d.Val)
+		case DT_FLAGS:
+			value = strings.TrimSpace(readelfDynFlags(d.Val))
+		case DT_FLAGS_1:
+			value = strings.TrimSpace(readelfFlags(d.Val, readelfDF1Names))
+		}
+		t.addRow(p.hexWidth(uint64(d.Tag)), readelfDynTag(h.Machine, uint64(d.Tag)), value)
+	}
+	return nil
+}
+
+func (p *Parser) symbolsView(v *View, dynOnly bool) error {
+	tables := []SectionType{SHT_DYNSYM}
+	if !dynOnly {
+		tables = append(tables, SHT_SYMTAB)
+	}
+	h := p.F.rawHeader()
+	shnum := len(p.F.Sections())
+	for _, typ := range tables {
+		symbols, err := p.Symbols(typ)
+		if err == ErrNoSymbols {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		title := ".dynsym"
+		if s := p.F.SectionByType(typ); s != nil {
+			title = s.SectionName
+		}
+		t := v.addTable(title, "Num", "Value", "Size", "Type", "Bind", "Vis", "Ndx", "Name")
+		for i, sym := range symbols {
+			name := sym.Name
+			if sym.Version != "" {
+				name += "@" + sym.Version
+			}
+			t.addRow(decString(uint64(i)), p.hexWidth(sym.Value), decString(sym.Size),
+				readelfSymbolType(h.Machine, h.Ident[EI_OSABI], sym.Info&0xf),
+				readelfSymbolBind(h.Ident[EI_OSABI], sym.Info>>4),
+				readelfVisibility[sym.Other&3],
+				strings.TrimSpace(readelfSymbolIndex(h.Machine, shnum, uint16(sym.Index))), name)
+		}
+	}
+	if len(v.Tables) == 0 {
+		v.Note = "There are no symbol tables in this file."
+	}
+	return nil
+}
+
+func (p *Parser) relocationsView(v *View) error {
+	sections := p.F.Sections()
+	for _, s := range sections {
+		typ := SectionType(s.Type)
+		if typ != SHT_REL && typ != SHT_RELA {
+			continue
+		}
+		relocs, err := p.SectionRelocations(s)
+		if err != nil {
+			return fmt.Errorf("relocation section '%s': %w", s.SectionName, err)
+		}
+		var symbols []Symbol
+		if s.Link != 0 && int(s.Link) < len(sections) {
+			symbols, _ = p.SectionSymbols(sections[s.Link])
+		}
+		p.relocationTable(v, s.SectionName, relocs, symbols)
+	}
+	if len(v.Tables) == 0 && p.F.FromSegments && len(p.F.DynRelocations) != 0 {
+		symbols, _ := p.Symbols(SHT_DYNSYM)
+		p.relocationTable(v, "PT_DYNAMIC", p.F.DynRelocations, symbols)
+	}
+	if len(v.Tables) == 0 {
+		v.Note = "There are no relocations in this file."
+	}
+	return nil
+}
+
+func (p *Parser) relocationTable(v *View, title string, relocs []Relocation, symbols []Symbol) {
+	sections := p.F.Sections()
+	t := v.addTable(title, "Offset", "Info", "Type", "Symbol Value", "Symbol Name", "Addend")
+	for _, r := range relocs {
+		value, name, addend := "", "", ""
+		if r.Sym != 0 && int(r.Sym) < len(symbols) {
+			sym := symbols[r.Sym]
+			name = sym.Name
+			if name == "" && ST_TYPE(sym.Info) == STT_SECTION && int(sym.Index) < len(sections) {
+				name = sections[sym.Index].SectionName
+			}
+			if sym.Version != "" {
+				name += "@" + sym.Version
+			}
+			value = p.hexWidth(sym.Value)
+		}
+		if r.HasAddend {
+			addend = strconv.FormatInt(r.Addend, 10)
+		}
+		typ := p.F.RelocTypeString(r.Type)
+		if alias, ok := readelfRelocAliases[typ]; ok {
+			typ = alias
+		}
+		t.addRow(p.hexWidth(r.Off), p.hexWidth(r.Info), typ, value, name, addend)
+	}
+}
+
+// gotSlots describes the reserved entries at the start of .got.plt.
+var gotSlots = []string{"address of .dynamic section", "address of link_map object", "address of _dl_runtime_resolve function"}
+
+func (p *Parser) gotView(v *View) error {
+	word := 8
+	if p.F.Class() == ELFCLASS32 {
+		word = 4
+	}
+	for _, name := range []string{".got", ".got.plt"} {
+		s := p.F.SectionByName(name)
+		if s == nil || SectionType(s.Type) == SHT_NOBITS {
+			continue
+		}
+		data, err := s.Data()
+		if err != nil {
+			return fmt.Errorf("section '%s': %w", name, err)
+		}
+		t := v.addTable(name, "Index", "Address", "Value", "Description")
+		for i := 0; i+word <= len(data); i += word {
+			var val uint64
+			if word == 4 {
+				val = uint64(p.F.ByteOrder().Uint32(data[i:]))
+			} else {
+				val = p.F.ByteOrder().Uint64(data[i:])
+			}
+			desc := ""
+			if n := i / word; name == ".got.plt" && n < len(gotSlots) {
+				desc = gotSlots[n]
+			}
+			t.addRow(decString(uint64(i/word)), p.hexWidth(s.Addr+uint64(i)), p.hexWidth(val), desc)
+		}
+	}
+	if len(v.Tables) == 0 {
+		v.Note = "There is no GOT in this file."
+	}
+	return nil
+}
@@ -0,0 +1,1113 @@
+// Package elf : rules.go parses pattern matching rules written in a subset
+// of the YARA language: hex, text and regular expression strings, and
+// conditions over the matches and the ELF structures. rulescan.go runs
+// them over a file.
+package elf
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/*
+rule ptrace_antidebug : linux antidebug {
+  meta:
+    description = "Detects ptrace(PTRACE_TRACEME) self tracing"
+    severity = "warning"
+  strings:
+    $call = { 31 FF [0-8] E8 ?? ?? ?? ?? }   // 字节、?通配符、[n-m]跳跃、(AA | BB CC)
+    $name = "ptrace" nocase ascii wide
+    $re = /ptrace_[a-z]+/ nocase           // 或者/ptrace_[a-z]+/i，s让.匹配换行
+  condition:
+    elf.machine == EM_X86_64 and elf.type != ET_REL
+    and imports("libc.so.6", "ptrace")
+    and ($call in section[".text"] or #name > 1)
+    and section[".text"].entropy < 7.0
+    and elf.entry_point in segment[2]
+}
+*/
+
+// ErrBadRule is returned by ParseRules for rules that cannot be used.
+var ErrBadRule = errors.New("bad rule")
+
+// RuleSet is a list of rules, a rule may use the result of the rules
+// before it in its condition.
+type RuleSet struct {
+	Rules []*Rule
+}
+
+// Rule is a named condition over the strings it declares and the file.
+type Rule struct {
+	Name string
+	Tags []string
+	// Private rules are only used by the conditions of other rules, they
+	// are not reported.
+	Private bool
+	Meta    map[string]string
+	Strings []*RuleString
+	cond    ruleExpr
+}
+
+// RuleString is a string of a rule: a hex pattern, a text or a regular
+// expression.
+type RuleString struct {
+	// ID is the identifier of the string, $ included.
+	ID string
+	// Source is the definition of the string as written in the rule.
+	Source string
+	// alternatives are the byte sequences of hex and text strings, one
+	// per encoding of a text string.
+	alternatives [][]patternToken
+	re           *regexp.Regexp
+}
+
+// patternToken is an element of a hex or text pattern: a byte of set,
+// a jump of min to max bytes (max < 0 for no limit) when set is nil, or a
+// choice between alts.
+type patternToken struct {
+	set      *[256]bool
+	min, max int
+	alts     [][]patternToken
+}
+
+// ruleTokenKind is the kind of a token of the rule language.
+type ruleTokenKind int
+
+const (
+	tokEOF ruleTokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokStringID // $a
+	tokCount    // #a
+	tokOffset   // @a
+	tokPunct
+)
+
+type ruleToken struct {
+	kind ruleTokenKind
+	text string
+	line int
+}
+
+// ruleLexer splits rules into tokens, the bodies of hex strings and
+// regular expressions are read by the parser with rawUntil.
+type ruleLexer struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (l *ruleLexer) skipSpace() error {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\n':
+			l.line++
+			l.pos++
+		case c == ' ' || c == '\t' || c == '\r':
+			l.pos++
+		case strings.HasPrefix(l.src[l.pos:], "//"):
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		case strings.HasPrefix(l.src[l.pos:], "/*"):
+			end := strings.Index(l.src[l.pos+2:], "*/")
+			if end < 0 {
+				return l.errorf("unterminated comment")
+			}
+			l.line += strings.Count(l.src[l.pos:l.pos+2+end], "\n")
+			l.pos += end + 4
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (l *ruleLexer) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: line %d: %s", ErrBadRule, l.line, fmt.Sprintf(format, args...))
+}
+
+func isIdentByte(c byte, first bool) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || !first && '0' <= c && c <= '9'
+}
+
+func (l *ruleLexer) next() (ruleToken, error) {
+	if err := l.skipSpace(); err != nil {
+		return ruleToken{}, err
+	}
+	if l.pos >= len(l.src) {
+		return ruleToken{kind: tokEOF, line: l.line}, nil
+	}
+	start, c := l.pos, l.src[l.pos]
+	tok := ruleToken{line: l.line}
+	switch {
+	case isIdentByte(c, true):
+		// elf.machine这样带点的名字作为一个标识符
+		for l.pos < len(l.src) && (isIdentByte(l.src[l.pos], false) ||
+			l.src[l.pos] == '.' && l.pos+1 < len(l.src) && isIdentByte(l.src[l.pos+1], true)) {
+			l.pos++
+		}
+		tok.kind = tokIdent
+	case '0' <= c && c <= '9':
+		l.pos++
+		for l.pos < len(l.src) && (isIdentByte(l.src[l.pos], false) ||
+			l.src[l.pos] == '.' && l.pos+1 < len(l.src) && '0' <= l.src[l.pos+1] && l.src[l.pos+1] <= '9') {
+			l.pos++
+		}
+		tok.kind = tokNumber
+	case c == '"':
+		l.pos++
+		for l.pos < len(l.src) && l.src[l.pos] != '"' {
+			if l.src[l.pos] == '\\' {
+				l.pos++
+			}
+			if l.pos < len(l.src) && l.src[l.pos] == '\n' {
+				return tok, l.errorf("unterminated string")
+			}
+			l.pos++
+		}
+		if l.pos >= len(l.src) {
+			return tok, l.errorf("unterminated string")
+		}
+		l.pos++
+		tok.kind = tokString
+		s, err := unquoteRuleString(l.src[start+1 : l.pos-1])
+		if err != nil {
+			return tok, l.errorf("%v", err)
+		}
+		tok.text = s
+		return tok, nil
+	case c == '$' || c == '#' || c == '@':
+		l.pos++
+		for l.pos < len(l.src) && (isIdentByte(l.src[l.pos], false) || l.src[l.pos] == '*') {
+			l.pos++
+		}
+		tok.kind = map[byte]ruleTokenKind{'$': tokStringID, '#': tokCount, '@': tokOffset}[c]
+	default:
+		tok.kind = tokPunct
+		for _, op := range []string{"==", "!=", "<=", ">=", ".."} {
+			if strings.HasPrefix(l.src[l.pos:], op) {
+				l.pos += len(op)
+				tok.text = op
+				return tok, nil
+			}
+		}
+		if !strings.ContainsRune("{}()[]:=,.<>+-*\\%|/", rune(c)) {
+			return tok, l.errorf("unexpected character %q", c)
+		}
+		l.pos++
+	}
+	tok.text = l.src[start:l.pos]
+	return tok, nil
+}
+
+// rawUntil returns the source up to the next end byte not escaped by a
+// backslash and skips the end byte.
+func (l *ruleLexer) rawUntil(end byte) (string, error) {
+	start := l.pos
+	for l.pos < len(l.src) && l.src[l.pos] != end {
+		switch l.src[l.pos] {
+		case '\\':
+			l.pos++
+		case '\n':
+			l.line++
+		}
+		l.pos++
+	}
+	if l.pos >= len(l.src) {
+		return "", l.errorf("missing %q", end)
+	}
+	l.pos++
+	return l.src[start : l.pos-1], nil
+}
+
+// unquoteRuleString decodes the escapes of a text string: \" \\ \n \t
+// \r and \xNN.
+func unquoteRuleString(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		if i >= len(s) {
+			return "", errors.New("bad escape at the end of a string")
+		}
+		switch s[i] {
+		case '"', '\\':
+			b.WriteByte(s[i])
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'x':
+			if i+3 > len(s) {
+				return "", errors.New("bad \\x escape")
+			}
+			v, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+			if err != nil {
+				return "", errors.New("bad \\x escape")
+			}
+			b.WriteByte(byte(v))
+			i += 2
+		default:
+			return "", fmt.Errorf("unknown escape \\%c", s[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// ruleParser builds the rules from the tokens, tok is the next token.
+type ruleParser struct {
+	lex *ruleLexer
+	tok ruleToken
+	// rules are the names of the rules already parsed.
+	rules map[string]bool
+	// strings are the identifiers of the strings of the current rule.
+	strings []string
+}
+
+func (p *ruleParser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *ruleParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: line %d: %s", ErrBadRule, p.tok.line, fmt.Sprintf(format, args...))
+}
+
+// is reports whether the next token is the punctuation or keyword s.
+func (p *ruleParser) is(s string) bool {
+	return (p.tok.kind == tokPunct || p.tok.kind == tokIdent) && p.tok.text == s
+}
+
+func (p *ruleParser) expect(s string) error {
+	if !p.is(s) {
+		return p.errorf("expected %q, found %q", s, p.tok.text)
+	}
+	return p.advance()
+}
+
+func (p *ruleParser) ident() (string, error) {
+	if p.tok.kind != tokIdent {
+		return "", p.errorf("expected an identifier, found %q", p.tok.text)
+	}
+	s := p.tok.text
+	return s, p.advance()
+}
+
+// ParseRules parses rules written in the YARA subset shown at the top of
+// rules.go. Modules, includes, external variables and the xor, base64 and
+// fullword modifiers are not supported.
+func ParseRules(src string) (*RuleSet, error) {
+	p := &ruleParser{lex: &ruleLexer{src: src, line: 1}, rules: map[string]bool{}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	rs := &RuleSet{}
+	for p.tok.kind != tokEOF {
+		r, err := p.rule()
+		if err != nil {
+			return nil, err
+		}
+		rs.Rules = append(rs.Rules, r)
+	}
+	return rs, nil
+}
+
+func (p *ruleParser) rule() (*Rule, error) {
+	r := &Rule{Meta: map[string]string{}}
+	if p.is("private") {
+		r.Private = true
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect("rule"); err != nil {
+		return nil, err
+	}
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	if p.rules[name] {
+		return nil, p.errorf("duplicate rule %s", name)
+	}
+	r.Name = name
+	if p.is(":") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		for p.tok.kind == tokIdent {
+			r.Tags = append(r.Tags, p.tok.text)
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	p.strings = nil
+	if p.is("meta") {
+		if err := p.meta(r); err != nil {
+			return nil, err
+		}
+	}
+	if p.is("strings") {
+		if err := p.ruleStrings(r); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect("condition"); err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	if r.cond, err = p.expr(); err != nil {
+		return nil, err
+	}
+	if err := p.expect("}"); err != nil {
+		return nil, err
+	}
+	p.rules[r.Name] = true
+	return r, nil
+}
+
+func (p *ruleParser) meta(r *Rule) error {
+	if err := p.advance(); err != nil {
+		return err
+	}
+	if err := p.expect(":"); err != nil {
+		return err
+	}
+	for p.tok.kind == tokIdent && !p.is("strings") && !p.is("condition") {
+		key := p.tok.text
+		if err := p.advance(); err != nil {
+			return err
+		}
+		if err := p.expect("="); err != nil {
+			return err
+		}
+		if p.tok.kind != tokString && p.tok.kind != tokNumber && !p.is("true") && !p.is("false") {
+			return p.errorf("bad value for meta %s", key)
+		}
+		r.Meta[key] = p.tok.text
+		if err := p.advance(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *ruleParser) ruleStrings(r *Rule) error {
+	if err := p.advance(); err != nil {
+		return err
+	}
+	if err := p.expect(":"); err != nil {
+		return err
+	}
+	for p.tok.kind == tokStringID {
+		s := &RuleString{ID: p.tok.text}
+		if len(s.ID) < 2 || strings.Contains(s.ID, "*") {
+			return p.errorf("bad string identifier %s", s.ID)
+		}
+		for _, id := range p.strings {
+			if id == s.ID {
+				return p.errorf("duplicate string %s", s.ID)
+			}
+		}
+		if err := p.advance(); err != nil {
+			return err
+		}
+		// 等号后的{和/由词法分析器读出，其后的内容直接读取
+		if !p.is("=") {
+			return p.errorf("expected \"=\" after %s", s.ID)
+		}
+		if err := p.advance(); err != nil {
+			return err
+		}
+		var err error
+		switch {
+		case p.is("{"):
+			err = p.hexString(s)
+		case p.is("/"):
+			err = p.regexString(s)
+		case p.tok.kind == tokString:
+			err = p.textString(s)
+		default:
+			err = p.errorf("bad definition of %s", s.ID)
+		}
+		if err != nil {
+			return err
+		}
+		r.Strings = append(r.Strings, s)
+		p.strings = append(p.strings, s.ID)
+	}
+	return nil
+}
+
+func (p *ruleParser) hexString(s *RuleString) error {
+	body, err := p.lex.rawUntil('}')
+	if err != nil {
+		return err
+	}
+	s.Source = "{" + body + "}"
+	toks, rest, err := parseHexTokens(strings.Join(strings.Fields(body), ""), false)
+	if err == nil && rest != "" {
+		err = errors.New("unbalanced parenthesis")
+	}
+	if err == nil && (len(toks) == 0 || toks[0].set == nil && toks[0].alts == nil || toks[len(toks)-1].set == nil && toks[len(toks)-1].alts == nil) {
+		err = errors.New("a hex string must start and end with bytes")
+	}
+	if err != nil {
+		return p.errorf("%s: %v", s.ID, err)
+	}
+	s.alternatives = [][]patternToken{toks}
+	return p.advance()
+}
+
+// parseHexTokens parses the body of a hex string without spaces. Inside
+// an alternative, parsing stops at "|" or ")" and the rest is returned.
+func parseHexTokens(s string, inAlt bool) ([]patternToken, string, error) {
+	var toks []patternToken
+	for s != "" {
+		switch s[0] {
+		case '|', ')':
+			if !inAlt {
+				return nil, "", fmt.Errorf("unexpected %q", s[0])
+			}
+			return toks, s, nil
+		case '(':
+			alt := patternToken{}
+			rest := s[1:]
+			for {
+				seq, r, err := parseHexTokens(rest, true)
+				if err != nil {
+					return nil, "", err
+				}
+				if len(seq) == 0 {
+					return nil, "", errors.New("empty alternative")
+				}
+				for _, t := range seq {
+					if t.set == nil && t.alts == nil {
+						return nil, "", errors.New("jumps are not allowed in alternatives")
+					}
+				}
+				alt.alts = append(alt.alts, seq)
+				if r == "" {
+					return nil, "", errors.New("unbalanced parenthesis")
+				}
+				rest = r[1:]
+				if r[0] == ')' {
+					break
+				}
+			}
+			toks = append(toks, alt)
+			s = rest
+		case '[':
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return nil, "", errors.New("unterminated jump")
+			}
+			jump, err := parseJump(s[1:end])
+			if err != nil {
+				return nil, "", err
+			}
+			toks = append(toks, jump)
+			s = s[end+1:]
+		default:
+			if len(s) < 2 {
+				return nil, "", errors.New("odd number of nibbles")
+			}
+			set, err := hexByteSet(s[0], s[1])
+			if err != nil {
+				return nil, "", err
+			}
+			toks = append(toks, patternToken{set: set})
+			s = s[2:]
+		}
+	}
+	return toks, "", nil
+}
+
+// parseJump parses the inside of [n], [n-m], [n-] or [-].
+func parseJump(s string) (patternToken, error) {
+	lo, hi := s, s
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		lo, hi = s[:i], s[i+1:]
+	}
+	t := patternToken{max: -1}
+	var err error
+	if lo != "" {
+		if t.min, err = strconv.Atoi(lo); err != nil || t.min < 0 {
+			return t, fmt.Errorf("bad jump [%s]", s)
+		}
+	}
+	if hi != "" {
+		if t.max, err = strconv.Atoi(hi); err != nil || t.max < t.min {
+			return t, fmt.Errorf("bad jump [%s]", s)
+		}
+	}
+	return t, nil
+}
+
+// hexByteSet returns the bytes matched by the two nibbles hi and lo, each
+// may be the ? wildcard.
+func hexByteSet(hi, lo byte) (*[256]bool, error) {
+	nibble := func(c byte) (byte, bool, error) {
+		if c == '?' {
+			return 0, true, nil
+		}
+		v, err := strconv.ParseUint(string(c), 16, 8)
+		if err != nil {
+			return 0, false, fmt.Errorf("bad hex digit %q", c)
+		}
+		return byte(v), false, nil
+	}
+	h, hw, err := nibble(hi)
+	if err != nil {
+		return nil, err
+	}
+	l, lw, err := nibble(lo)
+	if err != nil {
+		return nil, err
+	}
+	set := &[256]bool{}
+	for b := 0; b < 256; b++ {
+		if (hw || byte(b)>>4 == h) && (lw || byte(b)&0xf == l) {
+			set[b] = true
+		}
+	}
+	return set, nil
+}
+
+func (p *ruleParser) textString(s *RuleString) error {
+	text := p.tok.text
+	s.Source = strconv.Quote(text)
+	if text == "" {
+		return p.errorf("%s: empty string", s.ID)
+	}
+	if err := p.advance(); err != nil {
+		return err
+	}
+	var nocase, ascii, wide bool
+	// 修饰符之后可能紧跟condition:，它不是修饰符，结束循环后照常生成模式
+modifiers:
+	for p.tok.kind == tokIdent {
+		switch p.tok.text {
+		case "nocase":
+			nocase = true
+		case "ascii":
+			ascii = true
+		case "wide":
+			wide = true
+		default:
+			break modifiers
+		}
+		s.Source += " " + p.tok.text
+		if err := p.advance(); err != nil {
+			return err
+		}
+	}
+	if !wide {
+		ascii = true
+	}
+	seq := func(wide bool) []patternToken {
+		var toks []patternToken
+		for i := 0; i < len(text); i++ {
+			set := &[256]bool{}
+			c := text[i]
+			set[c] = true
+			if nocase && ('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z') {
+				set[c^0x20] = true
+			}
+			toks = append(toks, patternToken{set: set})
+			if wide {
+				toks = append(toks, patternToken{set: &[256]bool{0: true}})
+			}
+		}
+		return toks
+	}
+	if ascii {
+		s.alternatives = append(s.alternatives, seq(false))
+	}
+	if wide {
+		s.alternatives = append(s.alternatives, seq(true))
+	}
+	return nil
+}
+
+func (p *ruleParser) regexString(s *RuleString) error {
+	body, err := p.lex.rawUntil('/')
+	if err != nil {
+		return err
+	}
+	s.Source = "/" + body + "/"
+	// 修饰符紧跟在/之后
+	flags := ""
+	for p.lex.pos < len(p.lex.src) && strings.IndexByte("is", p.lex.src[p.lex.pos]) >= 0 {
+		flags += p.lex.src[p.lex.pos : p.lex.pos+1]
+		p.lex.pos++
+	}
+	s.Source += flags
+	if err := p.advance(); err != nil {
+		return err
+	}
+	if p.is("nocase") {
+		s.Source += " nocase"
+		if !strings.Contains(flags, "i") {
+			flags += "i"
+		}
+		if err := p.advance(); err != nil {
+			return err
+		}
+	}
+	prefix := ""
+	if flags != "" {
+		prefix = "(?" + flags + ")"
+	}
+	if s.re, err = regexp.Compile(prefix + body); err != nil {
+		return p.errorf("%s: %v", s.ID, err)
+	}
+	return nil
+}
+
+// The conditions, from the lowest precedence:
+//
+//	or, and, not, comparisons with in and at, + -, * \ %, unary -.
+func (p *ruleParser) expr() (ruleExpr, error) {
+	l, err := p.andExpr()
+	for err == nil && p.is("or") {
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		var r ruleExpr
+		if r, err = p.andExpr(); err == nil {
+			l = &binaryExpr{op: "or", l: l, r: r}
+		}
+	}
+	return l, err
+}
+
+func (p *ruleParser) andExpr() (ruleExpr, error) {
+	l, err := p.notExpr()
+	for err == nil && p.is("and") {
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		var r ruleExpr
+		if r, err = p.notExpr(); err == nil {
+			l = &binaryExpr{op: "and", l: l, r: r}
+		}
+	}
+	return l, err
+}
+
+func (p *ruleParser) notExpr() (ruleExpr, error) {
+	if p.is("not") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		e, err := p.notExpr()
+		return &notExpr{e: e}, err
+	}
+	return p.relExpr()
+}
+
+func (p *ruleParser) relExpr() (ruleExpr, error) {
+	l, err := p.addExpr()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case p.is("==") || p.is("!=") || p.is("<") || p.is("<=") || p.is(">") || p.is(">="):
+		op := p.tok.text
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		r, err := p.addExpr()
+		return &binaryExpr{op: op, l: l, r: r}, err
+	case p.is("in"):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		rng, err := p.rangeExpr()
+		return &inExpr{e: l, rng: rng}, err
+	case p.is("at"):
+		s, ok := l.(*stringExpr)
+		if !ok {
+			return nil, p.errorf("at needs a string")
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		off, err := p.addExpr()
+		return &atExpr{id: s.id, off: off}, err
+	}
+	return l, nil
+}
+
+// rangeExpr parses the right side of in: (lo..hi), section["name"] or
+// segment[index].
+func (p *ruleParser) rangeExpr() (ruleExpr, error) {
+	if p.is("section") || p.is("segment") {
+		e, err := p.primary()
+		if err != nil {
+			return nil, err
+		}
+		if r, ok := e.(*regionExpr); ok && r.attr == "" {
+			return r, nil
+		}
+		return nil, p.errorf("in needs a section or a segment, not one of its fields")
+	}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	lo, err := p.addExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(".."); err != nil {
+		return nil, err
+	}
+	hi, err := p.addExpr()
+	if err != nil {
+		return nil, err
+	}
+	return &numRangeExpr{lo: lo, hi: hi}, p.expect(")")
+}
+
+func (p *ruleParser) addExpr() (ruleExpr, error) {
+	l, err := p.mulExpr()
+	for err == nil && (p.is("+") || p.is("-")) {
+		op := p.tok.text
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		var r ruleExpr
+		if r, err = p.mulExpr(); err == nil {
+			l = &binaryExpr{op: op, l: l, r: r}
+		}
+	}
+	return l, err
+}
+
+func (p *ruleParser) mulExpr() (ruleExpr, error) {
+	l, err := p.unary()
+	for err == nil && (p.is("*") || p.is("\\") || p.is("%")) {
+		op := p.tok.text
+		if err = p.advance(); err != nil {
+			return nil, err
+		}
+		var r ruleExpr
+		if r, err = p.unary(); err == nil {
+			l = &binaryExpr{op: op, l: l, r: r}
+		}
+	}
+	return l, err
+}
+
+func (p *ruleParser) unary() (ruleExpr, error) {
+	if p.is("-") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		e, err := p.unary()
+		return &binaryExpr{op: "-", l: &constExpr{v: int64(0)}, r: e}, err
+	}
+	return p.primary()
+}
+
+// stringID checks that id names a string of the current rule.
+func (p *ruleParser) stringID(id string) (string, error) {
+	id = "$" + id[1:]
+	for _, s := range p.strings {
+		if s == id {
+			return id, nil
+		}
+	}
+	return "", p.errorf("undefined string %s", id)
+}
+
+// stringSet expands "them" or a list of string identifiers, $a* matching
+// every string prefixed by $a.
+func (p *ruleParser) stringSet() ([]string, error) {
+	if p.is("them") {
+		if len(p.strings) == 0 {
+			return nil, p.errorf("the rule has no strings")
+		}
+		return p.strings, p.advance()
+	}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var ids []string
+	for {
+		if p.tok.kind != tokStringID {
+			return nil, p.errorf("expected a string identifier, found %q", p.tok.text)
+		}
+		id := p.tok.text
+		if strings.HasSuffix(id, "*") {
+			n := len(ids)
+			for _, s := range p.strings {
+				if strings.HasPrefix(s, id[:len(id)-1]) {
+					ids = append(ids, s)
+				}
+			}
+			if len(ids) == n {
+				return nil, p.errorf("no string matches %s", id)
+			}
+		} else {
+			s, err := p.stringID(id)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, s)
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if !p.is(",") {
+			break
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	return ids, p.expect(")")
+}
+
+func (p *ruleParser) primary() (ruleExpr, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokNumber:
+		v, err := parseRuleNumber(tok.text)
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.is("of") {
+			n, ok := v.(int64)
+			if !ok {
+				return nil, p.errorf("bad count %s", tok.text)
+			}
+			return p.ofExpr(&constExpr{v: n}, "")
+		}
+		return &constExpr{v: v}, nil
+	case tokString:
+		return &constExpr{v: tok.text}, p.advance()
+	case tokStringID, tokCount, tokOffset:
+		id, err := p.stringID(tok.text)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		switch tok.kind {
+		case tokCount:
+			return &countExpr{id: id}, nil
+		case tokOffset:
+			var idx ruleExpr = &constExpr{v: int64(1)}
+			if p.is("[") {
+				if err := p.advance(); err != nil {
+					return nil, err
+				}
+				if idx, err = p.expr(); err != nil {
+					return nil, err
+				}
+				if err := p.expect("]"); err != nil {
+					return nil, err
+				}
+			}
+			return &offsetExpr{id: id, idx: idx}, nil
+		}
+		return &stringExpr{id: id}, nil
+	case tokPunct:
+		if tok.text != "(" {
+			break
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		return e, p.expect(")")
+	case tokIdent:
+		return p.identExpr()
+	}
+	return nil, p.errorf("unexpected %q", tok.text)
+}
+
+// ruleFields are the values of the file, entry_point being an alias of
+// elf.entry_point.
+var ruleFields = map[string]bool{
+	"filesize": true, "entry_point": true,
+	"elf.type": true, "elf.machine": true, "elf.class": true, "elf.data": true, "elf.osabi": true,
+	"elf.entry_point": true, "elf.flags": true, "elf.number_of_sections": true, "elf.number_of_segments": true,
+}
+
+// regionAttrs are the fields of sections and segments.
+var regionAttrs = map[string]map[string]bool{
+	"section": {"type": true, "flags": true, "address": true, "offset": true, "size": true, "entropy": true},
+	"segment": {"type": true, "flags": true, "offset": true, "virtual_address": true, "physical_address": true,
+		"file_size": true, "memory_size": true, "alignment": true, "entropy": true},
+}
+
+// ruleConstants are the names of the ELF constants usable in conditions,
+// the constant names of the tables of flags.go.
+var ruleConstants = func() map[string]int64 {
+	m := map[string]int64{}
+	for _, names := range [][]flagName{classStrings, dataStrings, osABIStrings, typeStrings, machineStrings,
+		sectionTypeStrings, sectionFlagStrings, programTypeStrings, programFlagStrings} {
+		for _, n := range names {
+			if name := constantName(n.name); name != "" {
+				if _, ok := m[name]; !ok {
+					m[name] = int64(n.flag)
+				}
+			}
+		}
+	}
+	return m
+}()
+
+func (p *ruleParser) identExpr() (ruleExpr, error) {
+	name := p.tok.text
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	switch {
+	case name == "true" || name == "false":
+		return &constExpr{v: name == "true"}, nil
+	case name == "any" || name == "all":
+		return p.ofExpr(nil, name)
+	case name == "section" || name == "segment":
+		return p.regionExpr(name)
+	case name == "imports":
+		return p.importsExpr()
+	case ruleFields[name]:
+		return &fieldExpr{name: strings.TrimPrefix(name, "elf.")}, nil
+	case p.rules[name]:
+		return &ruleRefExpr{name: name}, nil
+	}
+	if v, ok := ruleConstants[name]; ok {
+		return &constExpr{v: v}, nil
+	}
+	return nil, p.errorf("unknown identifier %s", name)
+}
+
+func (p *ruleParser) ofExpr(n ruleExpr, quant string) (ruleExpr, error) {
+	if err := p.expect("of"); err != nil {
+		return nil, err
+	}
+	ids, err := p.stringSet()
+	return &ofExpr{n: n, quant: quant, ids: ids}, err
+}
+
+func (p *ruleParser) regionExpr(kind string) (ruleExpr, error) {
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+	r := &regionExpr{kind: kind}
+	if kind == "section" {
+		if p.tok.kind != tokString {
+			return nil, p.errorf("section needs a name")
+		}
+		r.name = p.tok.text
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	} else {
+		idx, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		r.idx = idx
+	}
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	if p.is(".") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		attr, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		if !regionAttrs[kind][attr] {
+			return nil, p.errorf("%s has no field %s", kind, attr)
+		}
+		r.attr = attr
+	}
+	return r, nil
+}
+
+func (p *ruleParser) importsExpr() (ruleExpr, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var args []string
+	for p.tok.kind == tokString {
+		args = append(args, p.tok.text)
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if !p.is(",") {
+			break
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if len(args) < 1 || len(args) > 2 {
+		return nil, p.errorf("imports takes a symbol or a library and a symbol")
+	}
+	e := &importsExpr{symbol: args[len(args)-1]}
+	if len(args) == 2 {
+		e.library = args[0]
+	}
+	return e, p.expect(")")
+}
+
+// parseRuleNumber parses a decimal, 0x hex or floating number, an integer
+// may have a KB or MB suffix.
+func parseRuleNumber(s string) (interface{}, error) {
+	if strings.Contains(s, ".") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad number %s", s)
+		}
+		return f, nil
+	}
+	mult := int64(1)
+	switch {
+	case strings.HasSuffix(s, "KB"):
+		s, mult = s[:len(s)-2], 1024
+	case strings.HasSuffix(s, "MB"):
+		s, mult = s[:len(s)-2], 1024*1024
+	}
+	v, err := strconv.ParseInt(s, 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("bad number %s", s)
+	}
+	return v * mult, nil
+}
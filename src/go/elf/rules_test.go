@@ -0,0 +1,175 @@
+package elf
+
+import (
+	"errors"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRulesErrors(t *testing.T) {
+	tests := []string{
+		`rule a { condition: $x }`,
+		`rule a { condition: no_such_thing }`,
+		`rule a { strings: $a = { 4G } condition: $a }`,
+		`rule a { strings: $a = { [2] 41 } condition: $a }`,
+		`rule a { strings: $a = { 41 ( 42 | [1] 43 ) } condition: $a }`,
+		`rule a { strings: $a = "x" $a = "y" condition: $a }`,
+		`rule a { strings: $a = /(/ condition: $a }`,
+		`rule a { condition: true } rule a { condition: true }`,
+		`rule a { condition: section[".text"].bogus > 1 }`,
+		`rule a { condition: imports() }`,
+		`rule a { condition: true`,
+		`rule a { strings: $a = "unterminated condition: $a }`,
+	}
+	for _, src := range tests {
+		_, err := ParseRules(src)
+		assert.True(t, errors.Is(err, ErrBadRule), "%s: %v", src, err)
+	}
+}
+
+func TestSearchPattern(t *testing.T) {
+	buf := []byte("\x00\x41\x42\x43\x44\x41\x58\x58\x58\x44\x41\x42\x45")
+	tests := []struct {
+		hex  string
+		want []int // 起始偏移和长度
+	}{
+		{"{41 42}", []int{1, 2, 10, 2}},
+		{"{41 ?? 43}", []int{1, 3}},
+		{"{4? 4?}", []int{1, 2, 2, 2, 3, 2, 4, 2, 9, 2, 10, 2, 11, 2}},
+		{"{41 [1-3] 44}", []int{1, 4, 5, 5}},
+		{"{41 [-] 45}", []int{1, 12, 5, 8, 10, 3}},
+		{"{41 ( 42 43 | 58 ) ?? }", []int{1, 4, 5, 3}},
+		{"{41 [3] 44}", []int{5, 5}},
+		{"{41 [-] 44 [-] 45}", []int{1, 12, 5, 8}},
+		{"{41 ( 42 | 58 ) [0-2] 44}", []int{1, 4, 5, 5}},
+	}
+	for _, tt := range tests {
+		rs, err := ParseRules("rule r { strings: $a = " + tt.hex + " condition: $a }")
+		if !assert.NoError(t, err, tt.hex) {
+			continue
+		}
+		var got []int
+		searchPattern(rs.Rules[0].Strings[0].alternatives[0], buf, func(i, n int) bool {
+			got = append(got, i, n)
+			return true
+		})
+		assert.Equal(t, tt.want, got, tt.hex)
+	}
+}
+
+func TestSearchPatternJumps(t *testing.T) {
+	// 回溯匹配在这里要尝试平方级的跳转组合
+	buf := make([]byte, 1<<20)
+	rs, err := ParseRules(`rule r { strings: $a = { 00 [-] 00 [-] 7F 7F 7F 7F } condition: $a }`)
+	if !assert.NoError(t, err) {
+		return
+	}
+	n := 0
+	searchPattern(rs.Rules[0].Strings[0].alternatives[0], buf, func(i, l int) bool {
+		n++
+		return true
+	})
+	assert.Equal(t, 0, n)
+	copy(buf[len(buf)-4:], "\x7f\x7f\x7f\x7f")
+	searchPattern(rs.Rules[0].Strings[0].alternatives[0], buf, func(i, l int) bool {
+		n++
+		return true
+	})
+	assert.Equal(t, len(buf)-5, n)
+}
+
+func TestTrailingTextString(t *testing.T) {
+	// 最后一个字符串之后紧跟condition:，修饰符的解析不能跳过模式的生成
+	p := parseFile(t, path.Join(exampleDir, "gcc-amd64-linux-exec"))
+	defer p.CloseFile()
+	for _, src := range []string{
+		`rule t { strings: $a = "ELF" condition: $a at 1 }`,
+		`rule t { strings: $a = "elf" nocase condition: $a at 1 }`,
+		`rule t { strings: $a = "elf" nocase ascii condition: $a at 1 }`,
+		`rule t { strings: $b = "GCC" wide $a = "ELF" condition: $a and not $b }`,
+	} {
+		rs, err := ParseRules(src)
+		if !assert.NoError(t, err, src) {
+			continue
+		}
+		matches, err := p.ScanRules(rs, ScanOptions{})
+		assert.NoError(t, err)
+		assert.Len(t, matches, 1, src)
+	}
+}
+
+const testRules = `
+// 注释
+private rule is_x86_64 {
+  condition:
+    elf.machine == EM_X86_64 and elf.class == ELFCLASS64
+}
+
+rule linux_exec : linux exec {
+  meta:
+    description = "x86-64 executable built by GCC"
+    severity = "note"
+  strings:
+    $elf = { 7F 45 4C 46 02 01 }
+    $gcc = "gcc: (gnu)" nocase
+    $gccwide = "GCC" wide
+    $start = { 31 ED 49 89 D1 5E [2-6] 83 E4 F0 }
+    $ver = /GCC: \(GNU\) [0-9.]+ \(Ubuntu [0-9.]+-1ubuntu4\)/
+    $verlower = /gcc: \(gnu\) [0-9.]+ \(ubuntu [0-9.]+-1UBUNTU4\)/ nocase
+  condition:
+    is_x86_64 and elf.type == ET_EXEC and $elf at 0 and #gcc == 7 and not $gccwide
+    and $start in section[".text"] and @start[1] == 0x3e0 and $start at elf.entry_point - 0x400000
+    and #ver == 3 and #verlower == 3 and (all of ($elf, $g*) == false or 3 of them)
+    and imports("libc.so.6", "puts") and imports("__libc_start_main") and not imports("libc.so.6", "ptrace")
+    and entry_point in segment[2] and not (entry_point in segment[3]) and entry_point in section[".text"]
+    and section[".text"].entropy > 4.0 and section[".text"].size == 0x1b4
+    and segment[2].flags == PF_R + PF_X and segment[3].type == PT_LOAD
+    and filesize == 8844 and filesize \ 1KB == 8 and elf.number_of_segments == 8
+}
+
+rule undefined_field {
+  condition:
+    section[".nothing"].size == 0 or not (section[".nothing"].size != 0) or segment[8]
+}
+
+rule other_machine {
+  condition:
+    not is_x86_64 or elf.machine == EM_AARCH64
+}
+`
+
+func TestScanRules(t *testing.T) {
+	rs, err := ParseRules(testRules)
+	if !assert.NoError(t, err) {
+		return
+	}
+	p := parseFile(t, path.Join(exampleDir, "gcc-amd64-linux-exec"))
+	defer p.CloseFile()
+	matches, err := p.ScanRules(rs, ScanOptions{})
+	assert.NoError(t, err)
+	if !assert.Len(t, matches, 1) {
+		return
+	}
+	m := matches[0]
+	assert.Equal(t, "linux_exec", m.Rule)
+	assert.Equal(t, []string{"linux", "exec"}, m.Tags)
+	assert.Equal(t, StringMatch{ID: "$elf", Offset: 0, Length: 6}, m.Strings[0])
+	assert.Equal(t, StringMatch{ID: "$gcc", Offset: 0x899, Length: 10, Section: ".comment"}, m.Strings[1])
+	assert.Len(t, m.Strings, 1+7+1+3+3)
+	assert.Equal(t, Finding{RuleID: "rule/linux_exec", Severity: SeverityNote, Message: "x86-64 executable built by GCC",
+		Offset: 0, Size: 6}, m.Finding())
+
+	// 只在.text中查找时ELF头不在范围内
+	scoped, err := ParseRules(`rule start { strings: $elf = "\x7fELF" $start = { 31 ED } condition: $start and not $elf }`)
+	assert.NoError(t, err)
+	matches, err = p.ScanRules(scoped, ScanOptions{Sections: []string{".text"}})
+	assert.NoError(t, err)
+	assert.Len(t, matches, 1)
+	matches, err = p.ScanRules(scoped, ScanOptions{Segments: []int{2}})
+	assert.NoError(t, err)
+	assert.Len(t, matches, 0)
+	_, err = p.ScanRules(scoped, ScanOptions{Sections: []string{".nothing"}})
+	assert.Error(t, err)
+}
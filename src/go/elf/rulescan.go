@@ -0,0 +1,697 @@
+// Package elf : rulescan.go runs the rules of rules.go over a file: the
+// strings are searched in the file or in the sections and segments chosen
+// by ScanOptions, then the conditions are evaluated.
+package elf
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// maxStringMatches bounds the matches kept for one string.
+const maxStringMatches = 10000
+
+// ScanOptions restricts the bytes the strings are searched in, the whole
+// file is searched when both lists are empty.
+type ScanOptions struct {
+	// Sections are section names, Segments program header indexes.
+	Sections []string
+	Segments []int
+}
+
+// StringMatch is a match of a string of a rule.
+type StringMatch struct {
+	ID     string `json:"id"`
+	Offset uint64 `json:"offset"`
+	Length int    `json:"length"`
+	// Section is the section holding Offset, if any.
+	Section string `json:"section,omitempty"`
+}
+
+// RuleMatch is a rule whose condition is true for a file.
+type RuleMatch struct {
+	Rule    string            `json:"rule"`
+	Tags    []string          `json:"tags,omitempty"`
+	Meta    map[string]string `json:"meta,omitempty"`
+	Strings []StringMatch     `json:"strings"`
+}
+
+// rulePrefix prefixes the rule name of a match in its finding rule ID.
+const rulePrefix = "rule/"
+
+// Finding returns the match as a finding of rule rule/<name>. The severity
+// and the message come from the severity and description metadata of the
+// rule, warning and a generic message by default. The finding is located
+// at the first string match.
+func (m RuleMatch) Finding() Finding {
+	f := Finding{RuleID: rulePrefix + m.Rule, Severity: SeverityWarning, Message: m.Meta["description"]}
+	switch s := Severity(m.Meta["severity"]); s {
+	case SeverityError, SeverityWarning, SeverityNote:
+		f.Severity = s
+	}
+	if f.Message == "" {
+		f.Message = fmt.Sprintf("The file matches rule %s.", m.Rule)
+	}
+	if len(m.Strings) != 0 {
+		s := m.Strings[0]
+		f.Offset, f.Size, f.Section = s.Offset, uint64(s.Length), s.Section
+	}
+	return f
+}
+
+// fileRange is a range of file offsets.
+type fileRange struct {
+	off, size uint64
+}
+
+// scanRanges returns the file ranges selected by opts.
+func (p *Parser) scanRanges(opts ScanOptions) ([]fileRange, error) {
+	if len(opts.Sections) == 0 && len(opts.Segments) == 0 {
+		return []fileRange{{0, uint64(p.F.size)}}, nil
+	}
+	var ranges []fileRange
+	for _, name := range opts.Sections {
+		s := p.F.SectionByName(name)
+		if s == nil {
+			return nil, fmt.Errorf("no section %s", name)
+		}
+		if SectionType(s.Type) != SHT_NOBITS {
+			ranges = append(ranges, fileRange{s.Off, s.ELF64SectionHeader.Size})
+		}
+	}
+	phdrs := p.F.ProgramHeaders()
+	for _, i := range opts.Segments {
+		if i < 0 || i >= len(phdrs) {
+			return nil, fmt.Errorf("no segment %d", i)
+		}
+		ranges = append(ranges, fileRange{phdrs[i].Off, phdrs[i].Filesz})
+	}
+	return ranges, nil
+}
+
+// ScanRules evaluates the rules of rs over the file and returns the
+// matching rules that are not private, in the order of rs.
+func (p *Parser) ScanRules(rs *RuleSet, opts ScanOptions) ([]RuleMatch, error) {
+	ranges, err := p.scanRanges(opts)
+	if err != nil {
+		return nil, err
+	}
+	data := p.readRange(0, uint64(p.F.size))
+	ctx := &ruleContext{p: p, results: map[string]bool{}}
+	matches := []RuleMatch{}
+	for _, r := range rs.Rules {
+		ctx.matches = map[string][]StringMatch{}
+		var all []StringMatch
+		for _, s := range r.Strings {
+			ms := s.search(data, ranges)
+			for i := range ms {
+				if sec, err := p.F.SectionForOffset(ms[i].Offset); err == nil {
+					ms[i].Section = sec.SectionName
+				}
+			}
+			ctx.matches[s.ID] = ms
+			all = append(all, ms...)
+		}
+		ok := truthy(r.cond.eval(ctx))
+		ctx.results[r.Name] = ok
+		if !ok || r.Private {
+			continue
+		}
+		m := RuleMatch{Rule: r.Name, Tags: r.Tags, Meta: r.Meta, Strings: all}
+		if m.Strings == nil {
+			m.Strings = []StringMatch{}
+		}
+		matches = append(matches, m)
+	}
+	return matches, nil
+}
+
+// search returns the matches of s starting in ranges, ordered by offset.
+func (s *RuleString) search(data []byte, ranges []fileRange) []StringMatch {
+	seen := map[uint64]bool{}
+	var ms []StringMatch
+	add := func(off uint64, n int) bool {
+		if !seen[off] {
+			seen[off] = true
+			ms = append(ms, StringMatch{ID: s.ID, Offset: off, Length: n})
+		}
+		return len(ms) < maxStringMatches
+	}
+	for _, r := range ranges {
+		if r.off >= uint64(len(data)) {
+			continue
+		}
+		end := r.off + r.size
+		if end > uint64(len(data)) || end < r.off {
+			end = uint64(len(data))
+		}
+		buf := data[r.off:end]
+		if s.re != nil {
+			for _, loc := range s.re.FindAllIndex(buf, maxStringMatches) {
+				if !add(r.off+uint64(loc[0]), loc[1]-loc[0]) {
+					break
+				}
+			}
+			continue
+		}
+		for _, alt := range s.alternatives {
+			if !searchPattern(alt, buf, func(i, n int) bool { return add(r.off+uint64(i), n) }) {
+				break
+			}
+		}
+	}
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Offset < ms[j].Offset })
+	return ms
+}
+
+// searchPattern calls found for every offset of buf where toks match with
+// the length of the match, it stops when found returns false.
+func searchPattern(toks []patternToken, buf []byte, found func(i, n int) bool) bool {
+	for _, t := range toks {
+		if t.set == nil && t.alts == nil {
+			return searchJumps(toks, buf, found)
+		}
+	}
+	// 第一个标记只匹配一个字节时用IndexByte跳到候选位置
+	first := -1
+	if set := toks[0].set; set != nil {
+		for b := 0; b < 256; b++ {
+			if set[b] {
+				if first >= 0 {
+					first = -1
+					break
+				}
+				first = b
+			}
+		}
+	}
+	for i := 0; i < len(buf); i++ {
+		if first >= 0 {
+			j := bytes.IndexByte(buf[i:], byte(first))
+			if j < 0 {
+				return true
+			}
+			i += j
+		}
+		if end, ok := matchPattern(toks, buf, i); ok {
+			if !found(i, end-i) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchPattern matches toks, which have no jumps, at buf[i:] and returns
+// the end of the match.
+func matchPattern(toks []patternToken, buf []byte, i int) (int, bool) {
+	for k, t := range toks {
+		if t.set != nil {
+			if i >= len(buf) || !t.set[buf[i]] {
+				return 0, false
+			}
+			i++
+			continue
+		}
+		for _, alt := range t.alts {
+			if end, ok := matchPattern(alt, buf, i); ok {
+				if end, ok := matchPattern(toks[k+1:], buf, end); ok {
+					return end, true
+				}
+			}
+		}
+		return 0, false
+	}
+	return i, true
+}
+
+// searchJumps is searchPattern for the patterns with jumps. Trying the
+// jumps from every offset costs up to len(buf) per jump and offset, which
+// the scanned file controls. Instead the end of the match of each suffix
+// toks[k:] is computed for every offset at once, from the last token to
+// the first, in O(len(buf)*len(toks)). Jumps still take the shortest
+// length that lets the rest match.
+func searchJumps(toks []patternToken, buf []byte, found func(i, n int) bool) bool {
+	n := len(buf)
+	// end[p]是toks[k+1:]从p开始匹配的结束位置，-1表示不匹配
+	end := make([]int, n+1)
+	for p := range end {
+		end[p] = p
+	}
+	cur := make([]int, n+1)
+	var next []int
+	for k := len(toks) - 1; k >= 0; k-- {
+		t := toks[k]
+		switch {
+		case t.set != nil:
+			for p := 0; p <= n; p++ {
+				cur[p] = -1
+				if p < n && t.set[buf[p]] {
+					cur[p] = end[p+1]
+				}
+			}
+		case t.alts != nil:
+			for p := 0; p <= n; p++ {
+				cur[p] = -1
+				for _, alt := range t.alts {
+					if e, ok := matchPattern(alt, buf, p); ok && end[e] >= 0 {
+						cur[p] = end[e]
+						break
+					}
+				}
+			}
+		default:
+			// next[q]是q及之后第一个能继续匹配的位置
+			if next == nil {
+				next = make([]int, n+2)
+			}
+			next[n+1] = -1
+			for q := n; q >= 0; q-- {
+				next[q] = next[q+1]
+				if end[q] >= 0 {
+					next[q] = q
+				}
+			}
+			for p := 0; p <= n; p++ {
+				cur[p] = -1
+				if p+t.min > n {
+					continue
+				}
+				if q := next[p+t.min]; q >= 0 && (t.max < 0 || q-p <= t.max) {
+					cur[p] = end[q]
+				}
+			}
+		}
+		end, cur = cur, end
+	}
+	for i := 0; i < n; i++ {
+		if end[i] >= 0 && !found(i, end[i]-i) {
+			return false
+		}
+	}
+	return true
+}
+
+// ruleContext holds what the conditions of a rule are evaluated against.
+type ruleContext struct {
+	p       *Parser
+	matches map[string][]StringMatch
+	// results are the results of the rules already evaluated.
+	results map[string]bool
+}
+
+// ruleExpr is a node of a condition. The values are int64, float64,
+// string, bool, a region for section[...] and segment[...], or nil for
+// undefined, like the field of a missing section. Undefined is false and
+// makes the comparisons false.
+type ruleExpr interface {
+	eval(ctx *ruleContext) interface{}
+}
+
+type region struct {
+	off, size   uint64
+	addr, msize uint64
+}
+
+func truthy(v interface{}) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case int64:
+		return v != 0
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	case region:
+		return true
+	}
+	return false
+}
+
+// number returns v as an int64 or a float64.
+func number(v interface{}) (int64, float64, bool, bool) {
+	switch v := v.(type) {
+	case int64:
+		return v, float64(v), false, true
+	case float64:
+		return 0, v, true, true
+	case bool:
+		if v {
+			return 1, 1, false, true
+		}
+		return 0, 0, false, true
+	}
+	return 0, 0, false, false
+}
+
+type constExpr struct{ v interface{} }
+
+func (e *constExpr) eval(*ruleContext) interface{} { return e.v }
+
+type notExpr struct{ e ruleExpr }
+
+func (e *notExpr) eval(ctx *ruleContext) interface{} {
+	v := e.e.eval(ctx)
+	if v == nil {
+		return nil
+	}
+	return !truthy(v)
+}
+
+type binaryExpr struct {
+	op   string
+	l, r ruleExpr
+}
+
+func (e *binaryExpr) eval(ctx *ruleContext) interface{} {
+	switch e.op {
+	case "and":
+		return truthy(e.l.eval(ctx)) && truthy(e.r.eval(ctx))
+	case "or":
+		return truthy(e.l.eval(ctx)) || truthy(e.r.eval(ctx))
+	}
+	l, r := e.l.eval(ctx), e.r.eval(ctx)
+	if ls, ok := l.(string); ok {
+		rs, ok := r.(string)
+		switch {
+		case !ok:
+			return nil
+		case e.op == "==":
+			return ls == rs
+		case e.op == "!=":
+			return ls != rs
+		}
+		return nil
+	}
+	li, lf, lfloat, ok1 := number(l)
+	ri, rf, rfloat, ok2 := number(r)
+	if !ok1 || !ok2 {
+		return nil
+	}
+	if lfloat || rfloat {
+		switch e.op {
+		case "+":
+			return lf + rf
+		case "-":
+			return lf - rf
+		case "*":
+			return lf * rf
+		case "\\":
+			if rf == 0 {
+				return nil
+			}
+			return lf / rf
+		case "%":
+			return nil
+		}
+		return compare(e.op, lf < rf, lf == rf)
+	}
+	switch e.op {
+	case "+":
+		return li + ri
+	case "-":
+		return li - ri
+	case "*":
+		return li * ri
+	case "\\", "%":
+		if ri == 0 {
+			return nil
+		}
+		if e.op == "%" {
+			return li % ri
+		}
+		return li / ri
+	}
+	return compare(e.op, li < ri, li == ri)
+}
+
+func compare(op string, less, equal bool) bool {
+	switch op {
+	case "==":
+		return equal
+	case "!=":
+		return !equal
+	case "<":
+		return less
+	case "<=":
+		return less || equal
+	case ">":
+		return !less && !equal
+	}
+	return !less
+}
+
+type stringExpr struct{ id string }
+
+func (e *stringExpr) eval(ctx *ruleContext) interface{} { return len(ctx.matches[e.id]) != 0 }
+
+type countExpr struct{ id string }
+
+func (e *countExpr) eval(ctx *ruleContext) interface{} { return int64(len(ctx.matches[e.id])) }
+
+// offsetExpr is @a[i], the offset of the i-th match counted from 1.
+type offsetExpr struct {
+	id  string
+	idx ruleExpr
+}
+
+func (e *offsetExpr) eval(ctx *ruleContext) interface{} {
+	i, ok := e.idx.eval(ctx).(int64)
+	ms := ctx.matches[e.id]
+	if !ok || i < 1 || i > int64(len(ms)) {
+		return nil
+	}
+	return int64(ms[i-1].Offset)
+}
+
+type atExpr struct {
+	id  string
+	off ruleExpr
+}
+
+func (e *atExpr) eval(ctx *ruleContext) interface{} {
+	off, ok := e.off.eval(ctx).(int64)
+	if !ok {
+		return nil
+	}
+	for _, m := range ctx.matches[e.id] {
+		if int64(m.Offset) == off {
+			return true
+		}
+	}
+	return false
+}
+
+type numRangeExpr struct{ lo, hi ruleExpr }
+
+func (e *numRangeExpr) eval(*ruleContext) interface{} { return nil }
+
+// inExpr is "$a in range", true when a match starts in the range of file
+// offsets, or "value in range", true when the value is in the range of
+// numbers, or in the virtual addresses of a section or a segment.
+type inExpr struct {
+	e   ruleExpr
+	rng ruleExpr
+}
+
+func (e *inExpr) eval(ctx *ruleContext) interface{} {
+	var lo, hi uint64
+	switch rng := e.rng.(type) {
+	case *numRangeExpr:
+		l, ok1 := rng.lo.eval(ctx).(int64)
+		h, ok2 := rng.hi.eval(ctx).(int64)
+		if !ok1 || !ok2 || l < 0 || h < l {
+			return nil
+		}
+		lo, hi = uint64(l), uint64(h)+1
+	case *regionExpr:
+		r, ok := rng.eval(ctx).(region)
+		if !ok {
+			return nil
+		}
+		if s, ok := e.e.(*stringExpr); ok {
+			return stringIn(ctx.matches[s.id], r.off, r.off+r.size)
+		}
+		lo, hi = r.addr, r.addr+r.msize
+	}
+	if s, ok := e.e.(*stringExpr); ok {
+		return stringIn(ctx.matches[s.id], lo, hi)
+	}
+	v, ok := e.e.eval(ctx).(int64)
+	if !ok {
+		return nil
+	}
+	return uint64(v) >= lo && uint64(v) < hi
+}
+
+func stringIn(ms []StringMatch, lo, hi uint64) bool {
+	for _, m := range ms {
+		if m.Offset >= lo && m.Offset < hi {
+			return true
+		}
+	}
+	return false
+}
+
+// ofExpr is "any of", "all of" or "n of" a set of strings.
+type ofExpr struct {
+	n     ruleExpr
+	quant string
+	ids   []string
+}
+
+func (e *ofExpr) eval(ctx *ruleContext) interface{} {
+	count := int64(0)
+	for _, id := range e.ids {
+		if len(ctx.matches[id]) != 0 {
+			count++
+		}
+	}
+	switch e.quant {
+	case "any":
+		return count > 0
+	case "all":
+		return count == int64(len(e.ids))
+	}
+	n, ok := e.n.eval(ctx).(int64)
+	if !ok {
+		return nil
+	}
+	return count >= n
+}
+
+type ruleRefExpr struct{ name string }
+
+func (e *ruleRefExpr) eval(ctx *ruleContext) interface{} { return ctx.results[e.name] }
+
+type fieldExpr struct{ name string }
+
+func (e *fieldExpr) eval(ctx *ruleContext) interface{} {
+	f := ctx.p.F
+	h := f.rawHeader()
+	switch e.name {
+	case "filesize":
+		return f.size
+	case "entry_point":
+		return int64(h.Entry)
+	case "type":
+		return int64(h.Type)
+	case "machine":
+		return int64(h.Machine)
+	case "class":
+		return int64(h.Ident[EI_CLASS])
+	case "data":
+		return int64(h.Ident[EI_DATA])
+	case "osabi":
+		return int64(h.Ident[EI_OSABI])
+	case "flags":
+		return int64(h.Flags)
+	case "number_of_sections":
+		return int64(len(f.Sections()))
+	case "number_of_segments":
+		return int64(len(f.ProgramHeaders()))
+	}
+	return nil
+}
+
+// regionExpr is section["name"] or segment[index], with attr the field
+// read or empty for the region itself.
+type regionExpr struct {
+	kind string
+	name string
+	idx  ruleExpr
+	attr string
+}
+
+func (e *regionExpr) eval(ctx *ruleContext) interface{} {
+	f := ctx.p.F
+	if e.kind == "section" {
+		s := f.SectionByName(e.name)
+		if s == nil {
+			return nil
+		}
+		size := s.ELF64SectionHeader.Size
+		r := region{off: s.Off, size: size}
+		if SectionType(s.Type) == SHT_NOBITS {
+			r.size = 0
+		}
+		if SectionFlag(s.Flags)&SHF_ALLOC != 0 {
+			r.addr, r.msize = s.Addr, size
+		}
+		switch e.attr {
+		case "":
+			return r
+		case "type":
+			return int64(s.Type)
+		case "flags":
+			return int64(s.Flags)
+		case "address":
+			return int64(s.Addr)
+		case "offset":
+			return int64(s.Off)
+		case "size":
+			return int64(size)
+		}
+		return ShannonEntropy(ctx.p.readRange(r.off, r.size))
+	}
+	i, ok := e.idx.eval(ctx).(int64)
+	phdrs := f.ProgramHeaders()
+	if !ok || i < 0 || i >= int64(len(phdrs)) {
+		return nil
+	}
+	ph := phdrs[i]
+	switch e.attr {
+	case "":
+		return region{off: ph.Off, size: ph.Filesz, addr: ph.Vaddr, msize: ph.Memsz}
+	case "type":
+		return int64(ph.Type)
+	case "flags":
+		return int64(ph.Flags)
+	case "offset":
+		return int64(ph.Off)
+	case "virtual_address":
+		return int64(ph.Vaddr)
+	case "physical_address":
+		return int64(ph.Paddr)
+	case "file_size":
+		return int64(ph.Filesz)
+	case "memory_size":
+		return int64(ph.Memsz)
+	case "alignment":
+		return int64(ph.Align)
+	}
+	return ShannonEntropy(ctx.p.readRange(ph.Off, ph.Filesz))
+}
+
+// importsExpr is imports("library", "symbol") or imports("symbol"), true
+// when the symbol is an undefined symbol of the dynamic symbol table. The
+// library is the one of the version of the symbol, an unversioned symbol
+// is taken from any library of DT_NEEDED.
+type importsExpr struct {
+	library, symbol string
+}
+
+func (e *importsExpr) eval(ctx *ruleContext) interface{} {
+	syms, err := ctx.p.Symbols(SHT_DYNSYM)
+	if err != nil {
+		return false
+	}
+	for _, s := range syms {
+		if s.Index != SHN_UNDEF || s.Name != e.symbol {
+			continue
+		}
+		if e.library == "" || s.Library == e.library {
+			return true
+		}
+		if s.Library == "" {
+			for _, lib := range ctx.p.F.Needed {
+				if lib == e.library {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
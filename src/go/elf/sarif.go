@@ -0,0 +1,137 @@
+// Package elf : sarif.go exports findings as a SARIF 2.1.0 log so that the
+// ELF analysis results show up in code scanning dashboards.
+package elf
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"path/filepath"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifToolURI = "https://github.com/yifengyou/parser-elf"
+)
+
+// FileFindings are the findings of one file, Path is written as the
+// artifact location of its results.
+type FileFindings struct {
+	Path     string
+	Findings []Finding
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifRule struct {
+	ID                   string       `json:"id"`
+	ShortDescription     sarifMessage `json:"shortDescription"`
+	DefaultConfiguration struct {
+		Level Severity `json:"level"`
+	} `json:"defaultConfiguration"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     Severity        `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region struct {
+			ByteOffset uint64 `json:"byteOffset"`
+			ByteLength uint64 `json:"byteLength,omitempty"`
+		} `json:"region"`
+	} `json:"physicalLocation"`
+	// 节名没有对应的物理位置字段，作为逻辑位置给出
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifLogicalLocation struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+// sarifURI returns the artifact URI of a file name: relative names stay
+// relative, absolute ones become file URIs.
+func sarifURI(name string) string {
+	u := url.URL{Path: filepath.ToSlash(name)}
+	if filepath.IsAbs(name) {
+		u.Scheme = "file"
+	}
+	return u.String()
+}
+
+// WriteSARIF writes the findings of files as a SARIF log of a single run.
+// The rules of the driver are the rules used by the results, in the order
+// they first appear.
+func WriteSARIF(w io.Writer, files []FileFindings) error {
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: "parser-elf", InformationURI: sarifToolURI, Rules: []sarifRule{}}},
+		Results: []sarifResult{},
+	}
+	ruleIndex := map[string]int{}
+	for _, file := range files {
+		for _, f := range file.Findings {
+			idx, ok := ruleIndex[f.RuleID]
+			if !ok {
+				rule, _ := LookupFindingRule(f.RuleID)
+				if rule.Description == "" {
+					rule.Description = f.RuleID
+				}
+				r := sarifRule{ID: f.RuleID, ShortDescription: sarifMessage{rule.Description}}
+				r.DefaultConfiguration.Level = rule.Severity
+				if r.DefaultConfiguration.Level == "" {
+					r.DefaultConfiguration.Level = f.Severity
+				}
+				idx = len(run.Tool.Driver.Rules)
+				ruleIndex[f.RuleID] = idx
+				run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, r)
+			}
+			var loc sarifLocation
+			loc.PhysicalLocation.ArtifactLocation.URI = sarifURI(file.Path)
+			loc.PhysicalLocation.Region.ByteOffset = f.Offset
+			loc.PhysicalLocation.Region.ByteLength = f.Size
+			if f.Section != "" {
+				loc.LogicalLocations = []sarifLogicalLocation{{Name: f.Section, Kind: "section"}}
+			}
+			run.Results = append(run.Results, sarifResult{
+				RuleID: f.RuleID, RuleIndex: idx, Level: f.Severity,
+				Message: sarifMessage{f.Message}, Locations: []sarifLocation{loc},
+			})
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}})
+}
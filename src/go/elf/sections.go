@@ -1,6 +1,7 @@
 package elf
 
 import (
+	"bytes"
 	"compress/zlib"
 	"encoding/hex"
 	"fmt"
@@ -12,23 +13,7 @@ import (
 // Even if the section is stored compressed in the ELF file,
 // Data returns uncompressed data.
 func (s *ELF32Section) Data() ([]byte, error) {
-
-	var rs io.ReadSeeker
-	data := make([]byte, s.Size)
-
-	if s.Flags&uint32(SHF_COMPRESSED) == 0 {
-		rs = io.NewSectionReader(s.sr, 0, 1<<63-1)
-	} else if s.compressionType == COMPRESS_ZLIB {
-		rs = &readSeekerFromReader{
-			reset: func() (io.Reader, error) {
-				fr := io.NewSectionReader(s.sr, s.compressionOffset, int64(s.Size)-s.compressionOffset)
-				return zlib.NewReader(fr)
-			},
-			size: int64(s.Size),
-		}
-	}
-	n, err := io.ReadFull(rs, data)
-	return data[0:n], err
+	return s.widen().Data()
 }
 
 // Data reads and returns the contents of the ELF section.
@@ -37,36 +22,99 @@ func (s *ELF32Section) Data() ([]byte, error) {
 // 获取当前节数据，返回字节数组
 func (s *ELF64Section) Data() ([]byte, error) {
 
-	var rs io.ReadSeeker
-	data := make([]byte, s.Size)
-
+	var r io.Reader
 	if s.Flags&uint64(SHF_COMPRESSED) == 0 {
 		// s.sr 已经是读取的节的数据，如果么有压缩，直接完整读取即可
 		// 小骚的最大数 MaxInt64  = 1<<63 - 1
 		// io.NewSectionReader 遇到EOF会停下来
-		rs = io.NewSectionReader(s.sr, 0, math.MaxInt64)
-		// 其实s.sr已经是io.NewSectionReader读取的结果
-		// 但io.NewSectionReader可以嵌套多次，只需要实现了Read接口即可
+		r = io.NewSectionReader(s.sr, 0, math.MaxInt64)
 	} else if s.compressionType == COMPRESS_ZLIB {
-		// 如果节做了压缩，则需要解压
-		rs = &readSeekerFromReader{
-			reset: func() (io.Reader, error) {
-				fr := io.NewSectionReader(s.sr, s.compressionOffset, int64(s.Size)-s.compressionOffset)
-				return zlib.NewReader(fr)
-			},
-			size: int64(s.Size),
+		// 如果节做了压缩，则需要解压，压缩数据在压缩头之后
+		zr, err := zlib.NewReader(io.NewSectionReader(s.sr, s.compressionOffset, math.MaxInt64))
+		if err != nil {
+			return nil, err
 		}
+		defer zr.Close()
+		r = zr
+	} else {
+		// 目前只支持zlib，COMPRESS_ZSTD等其他算法直接报错
+		return nil, errUnsupportedCompression(s.SectionName, s.compressionType)
+	}
+	// 节头里的sh_size和ch_size来自文件，不可信：只用作上限，
+	// 实际读到(解压出)多少字节才分配多少，而不是按它们预先分配
+	limit := int64(math.MaxInt64)
+	if s.Size < math.MaxInt64 {
+		limit = int64(s.Size)
+	}
+	var out bytes.Buffer
+	n, err := io.Copy(&out, io.LimitReader(r, limit))
+	switch {
+	case err != nil:
+	case n == 0 && s.Size != 0:
+		err = io.EOF
+	case uint64(n) < s.Size:
+		err = io.ErrUnexpectedEOF
+	}
+	return out.Bytes(), err
+}
+
+// maxInflateRatio is the largest expansion of deflate, a byte of a zlib
+// stream never decompresses to more than 1032 bytes.
+const maxInflateRatio = 1032
+
+// inFile reports a section whose sh_offset+sh_size lies past the end of a
+// file of fileSize bytes.
+func (s *ELF64Section) inFile(fileSize int64) error {
+	size := uint64(fileSize)
+	if s.Off > size || s.ELF64SectionHeader.Size > size-s.Off {
+		return fmt.Errorf("contents [%#x, +%#x) extend past the end of the file", s.Off, s.ELF64SectionHeader.Size)
 	}
-	// func ReadFull(r Reader, buf []byte) (n int, err error)
-	// 读取字节切片，放到data中
-	n, err := io.ReadFull(rs, data)
-	return data[0:n], err
+	return nil
+}
+
+// checkBounds reports a section whose header cannot be trusted to read its
+// contents from a file of fileSize bytes: sh_offset+sh_size past the end of
+// the file, or a ch_size its compressed data cannot inflate to.
+func (s *ELF64Section) checkBounds(fileSize int64) error {
+	if err := s.inFile(fileSize); err != nil {
+		return err
+	}
+	if s.Flags&uint64(SHF_COMPRESSED) != 0 && s.Size/maxInflateRatio > s.ELF64SectionHeader.Size {
+		return fmt.Errorf("uncompressed size %#x is larger than %#x compressed bytes can inflate to", s.Size, s.ELF64SectionHeader.Size)
+	}
+	return nil
+}
+
+// errUnsupportedCompression reports a SHF_COMPRESSED section whose
+// ch_type cannot be decompressed.
+func errUnsupportedCompression(name string, ct CompressionType) error {
+	return fmt.Errorf("section '%s' uses unsupported compression type %d", name, uint32(ct))
+}
+
+// rawData returns the bytes of the section as stored in a file of fileSize
+// bytes, without decompression.
+func (s *ELF64Section) rawData(fileSize int64) ([]byte, error) {
+	if err := s.inFile(fileSize); err != nil {
+		return nil, err
+	}
+	data := make([]byte, s.sr.Size())
+	n, err := s.sr.ReadAt(data, 0)
+	if err == io.EOF && n == len(data) {
+		err = nil
+	}
+	return data[:n], err
+}
+
+// HexDumpData returns the contents of the section in the hex.Dump layout.
+func (s *ELF32Section) HexDumpData() (string, error) {
+	return s.widen().HexDumpData()
 }
 
-func (s *ELF64Section) HexDumpData() string {
+// HexDumpData returns the contents of the section in the hex.Dump layout.
+func (s *ELF64Section) HexDumpData() (string, error) {
 	data, err := s.Data()
 	if err != nil {
-		fmt.Printf("error" + err.Error())
+		return "", err
 	}
-	return hex.Dump(data)
+	return hex.Dump(data), nil
 }
@@ -0,0 +1,97 @@
+// Package elf : segment.go implements the section to segment mapping.
+package elf
+
+// tbssSpecial reports whether sh is a .tbss like section seen from a segment
+// other than PT_TLS, such a section takes no room in the segment.
+func tbssSpecial(sh ELF64SectionHeader, ph ELF64ProgramHeader) bool {
+	return sh.Flags&uint64(SHF_TLS) != 0 &&
+		SectionType(sh.Type) == SHT_NOBITS &&
+		ProgType(ph.Type) != PT_TLS
+}
+
+// SectionInSegment reports whether the section described by sh belongs to the
+// segment ph, it implements the ELF_SECTION_IN_SEGMENT_1 rules of binutils
+// (include/elf/internal.h). strict rejects sections starting right at the end
+// of the segment, it is what readelf uses for its mapping.
+func SectionInSegment(sh ELF64SectionHeader, ph ELF64ProgramHeader, strict bool) bool {
+	flags, typ := SectionFlag(sh.Flags), SectionType(sh.Type)
+	ptype := ProgType(ph.Type)
+	size := sh.Size
+	if tbssSpecial(sh, ph) {
+		size = 0
+	}
+
+	// 只有PT_LOAD、PT_GNU_RELRO与PT_TLS可以包含TLS节；PT_TLS只包含TLS节，PT_PHDR不包含任何节
+	if flags&SHF_TLS != 0 {
+		if ptype != PT_TLS && ptype != PT_GNU_RELRO && ptype != PT_LOAD {
+			return false
+		}
+	} else if ptype == PT_TLS || ptype == PT_PHDR {
+		return false
+	}
+
+	// PT_LOAD这类段只包含SHF_ALLOC节
+	if flags&SHF_ALLOC == 0 {
+		switch {
+		case ptype == PT_LOAD, ptype == PT_DYNAMIC, ptype == PT_GNU_EH_FRAME,
+			ptype == PT_GNU_STACK, ptype == PT_GNU_RELRO, ptype == PT_GNU_SFRAME,
+			ptype >= PT_GNU_MBIND_LO && ptype <= PT_GNU_MBIND_HI:
+			return false
+		}
+	}
+
+	// 除SHT_NOBITS外，节的文件偏移必须落在段内
+	if typ != SHT_NOBITS {
+		if sh.Off < ph.Off {
+			return false
+		}
+		if strict && sh.Off-ph.Off > ph.Filesz-1 {
+			return false
+		}
+		if sh.Off-ph.Off+size > ph.Filesz {
+			return false
+		}
+	}
+
+	// SHF_ALLOC节的虚拟地址必须落在段内
+	if flags&SHF_ALLOC != 0 {
+		if sh.Addr < ph.Vaddr {
+			return false
+		}
+		if strict && sh.Addr-ph.Vaddr > ph.Memsz-1 {
+			return false
+		}
+		if sh.Addr-ph.Vaddr+size > ph.Memsz {
+			return false
+		}
+	}
+
+	// PT_DYNAMIC与PT_NOTE的开头和结尾不能是大小为0的节
+	if (ptype == PT_DYNAMIC || ptype == PT_NOTE) && sh.Size == 0 && ph.Memsz != 0 {
+		inFile := typ == SHT_NOBITS || (sh.Off > ph.Off && sh.Off-ph.Off < ph.Filesz)
+		inMem := flags&SHF_ALLOC == 0 || (sh.Addr > ph.Vaddr && sh.Addr-ph.Vaddr < ph.Memsz)
+		if !inFile || !inMem {
+			return false
+		}
+	}
+	return true
+}
+
+// SegmentSections returns the sections mapped by the segment ph, in section
+// header order, following the rules readelf uses for its section to segment
+// mapping. The null section is never part of a segment.
+func (f *File) SegmentSections(ph ELF64ProgramHeader) []*ELF64Section {
+	var sections []*ELF64Section
+	for i, s := range f.Sections() {
+		if i == 0 {
+			continue
+		}
+		if tbssSpecial(s.ELF64SectionHeader, ph) {
+			continue
+		}
+		if SectionInSegment(s.ELF64SectionHeader, ph, true) {
+			sections = append(sections, s)
+		}
+	}
+	return sections
+}
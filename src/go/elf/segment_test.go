@@ -0,0 +1,71 @@
+package elf
+
+import (
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Section to segment mappings as printed by readelf -lW.
+func TestSegmentSections(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected []string
+	}{
+		{
+			name: "gcc-amd64-linux-exec",
+			expected: []string{
+				"",
+				".interp",
+				".interp .note.ABI-tag .hash .gnu.hash .dynsym .dynstr .gnu.version .gnu.version_r .rela.dyn .rela.plt .init .plt .text .fini .rodata .eh_frame_hdr .eh_frame",
+				".ctors .dtors .jcr .dynamic .got .got.plt .data .bss",
+				".dynamic",
+				".note.ABI-tag",
+				".eh_frame_hdr",
+				"",
+			},
+		},
+		{
+			name: "gcc-386-freebsd-exec",
+			expected: []string{
+				"",
+				".interp",
+				".interp .hash .dynsym .dynstr .rel.plt .init .plt .text .fini .rodata",
+				".data .eh_frame .dynamic .ctors .dtors .jcr .got .bss",
+				".dynamic",
+			},
+		},
+	}
+	for _, tt := range testCases {
+		p, err := New(path.Join(exampleDir, tt.name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Parse(); err != nil {
+			t.Fatal(err)
+		}
+		var mapping []string
+		for _, ph := range p.F.ProgramHeaders() {
+			var names []string
+			for _, s := range p.F.SegmentSections(ph) {
+				names = append(names, s.SectionName)
+			}
+			mapping = append(mapping, strings.Join(names, " "))
+		}
+		assert.Equal(t, tt.expected, mapping, tt.name)
+		p.CloseFile()
+	}
+}
+
+func TestSectionInSegmentTLS(t *testing.T) {
+	tbss := ELF64SectionHeader{Type: uint32(SHT_NOBITS), Flags: uint64(SHF_ALLOC | SHF_WRITE | SHF_TLS), Addr: 0x3df0, Off: 0x2df0, Size: 4}
+	tls := ELF64ProgramHeader{Type: uint32(PT_TLS), Off: 0x2dec, Vaddr: 0x3dec, Filesz: 4, Memsz: 8}
+	load := ELF64ProgramHeader{Type: uint32(PT_LOAD), Off: 0x2dec, Vaddr: 0x3dec, Filesz: 0x234, Memsz: 0x23c}
+	phdr := ELF64ProgramHeader{Type: uint32(PT_PHDR), Off: 0, Vaddr: 0, Filesz: 0x4000, Memsz: 0x4000}
+	assert.True(t, SectionInSegment(tbss, tls, true))
+	assert.False(t, tbssSpecial(tbss, tls))
+	assert.True(t, tbssSpecial(tbss, load), ".tbss takes no room in PT_LOAD")
+	assert.False(t, SectionInSegment(tbss, phdr, true), "PT_PHDR holds no section")
+}
@@ -0,0 +1,197 @@
+// Package elf : similarity.go computes the hashes used to cluster similar
+// files: telfhash, TLSH and ssdeep over the file and .text, and an import
+// hash over the imported symbols.
+package elf
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SimilarityHashes holds the similarity hashes of a file, a hash that
+// cannot be computed is empty (telfhash is "-" like the telfhash tool).
+type SimilarityHashes struct {
+	Telfhash   string `json:"telfhash"`
+	TLSH       string `json:"tlsh"`
+	TextTLSH   string `json:"text_tlsh"`
+	Ssdeep     string `json:"ssdeep"`
+	TextSsdeep string `json:"text_ssdeep"`
+	ImportHash string `json:"import_hash"`
+}
+
+// telfhashExcluded matches the symbol names telfhash leaves out, names
+// generated by the compilers and libc functions found in most binaries.
+var telfhashExcluded = regexp.MustCompile(`^[_\.].*$|^.*64$|^str.*$|^mem.*$`)
+
+var telfhashExcludedNames = map[string]bool{
+	"__libc_start_main": true,
+	"main":              true,
+	"abort":             true,
+	"cachectl":          true,
+	"cacheflush":        true,
+	"puts":              true,
+	"atol":              true,
+	"malloc_trim":       true,
+}
+
+// SimilarityHashes computes the similarity hashes of the file.
+func (f *File) SimilarityHashes() (SimilarityHashes, error) {
+	var h SimilarityHashes
+	var err error
+	if h.Telfhash, err = f.Telfhash(); err != nil {
+		return h, err
+	}
+	if h.ImportHash, err = f.ImportHash(); err != nil {
+		return h, err
+	}
+	p := &Parser{F: f}
+	data := p.readRange(0, uint64(f.size))
+	h.TLSH, h.Ssdeep = TLSH(data), Ssdeep(data)
+	if s := f.SectionByName(".text"); s != nil && SectionType(s.Type) != SHT_NOBITS {
+		text, err := s.Data()
+		if err != nil {
+			return h, err
+		}
+		h.TextTLSH, h.TextSsdeep = TLSH(text), Ssdeep(text)
+	}
+	return h, nil
+}
+
+// fileSymbols decodes the symbols of the first section with the given
+// type, nil when there is none.
+func (f *File) fileSymbols(typ SectionType) ([]Symbol, error) {
+	// 符号的解码只用到File，临时的Parser不读文件流
+	syms, err := (&Parser{F: f}).Symbols(typ)
+	if err == ErrNoSymbols {
+		return nil, nil
+	}
+	return syms, err
+}
+
+// Telfhash returns the telfhash of the file, the TLSH of the comma joined
+// names returned by telfhashNames, forced for short lists. "-" is returned
+// when no name is left or the list is too uniform to be hashed.
+func (f *File) Telfhash() (string, error) {
+	names, err := f.telfhashNames()
+	if err != nil {
+		return "", err
+	}
+	h := ""
+	if len(names) != 0 {
+		h = tlshDigest([]byte(strings.Join(names, ",")), true)
+	}
+	if h == "" {
+		return "-", nil
+	}
+	return strings.ToLower(h), nil
+}
+
+// telfhashNames returns the sorted lower case names of the global functions
+// of the symbol tables minus the excluded ones. When every name is filtered
+// out, the names of the symbols used by the relocations are taken instead.
+func (f *File) telfhashNames() ([]string, error) {
+	names := map[string]bool{}
+	add := func(name string) {
+		name = strings.ToLower(name)
+		if name != "" && !telfhashExcludedNames[name] && !telfhashExcluded.MatchString(name) {
+			names[name] = true
+		}
+	}
+	for _, typ := range []SectionType{SHT_SYMTAB, SHT_DYNSYM} {
+		syms, err := f.fileSymbols(typ)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range syms {
+			if ST_TYPE(s.Info) == STT_FUNC && ST_BIND(s.Info) == STB_GLOBAL && ST_VISIBILITY(s.Other) == STV_DEFAULT {
+				add(s.Name)
+			}
+		}
+	}
+	if len(names) == 0 {
+		// 去掉符号表的文件仍然通过重定位引用导入的符号
+		relocNames, err := f.relocationSymbolNames()
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range relocNames {
+			add(name)
+		}
+	}
+	list := make([]string, 0, len(names))
+	for name := range names {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list, nil
+}
+
+// relocationSymbolNames returns the names of the symbols referenced by the
+// relocation sections, or by the dynamic relocations of a file without
+// section headers.
+func (f *File) relocationSymbolNames() ([]string, error) {
+	p := &Parser{F: f}
+	var names []string
+	add := func(relocs []Relocation, symbols []Symbol) {
+		for _, r := range relocs {
+			if r.Sym != 0 && int(r.Sym) < len(symbols) {
+				names = append(names, symbols[r.Sym].Name)
+			}
+		}
+	}
+	sections := f.Sections()
+	found := false
+	for _, s := range sections {
+		if typ := SectionType(s.Type); typ != SHT_REL && typ != SHT_RELA {
+			continue
+		}
+		found = true
+		relocs, err := p.SectionRelocations(s)
+		if err != nil {
+			return nil, err
+		}
+		var symbols []Symbol
+		if s.Link != 0 && int(s.Link) < len(sections) {
+			symbols, _ = p.SectionSymbols(sections[s.Link])
+		}
+		add(relocs, symbols)
+	}
+	if !found && f.FromSegments {
+		add(f.DynRelocations, f.NamedSymbols)
+	}
+	return names, nil
+}
+
+// ImportHash returns the MD5 of the imported symbols of the dynamic symbol
+// table, the undefined global and weak symbols. Each one is written as
+// library:symbol:version in lower case, library and version coming from
+// the GNU version tables, the sorted list is joined with commas. An empty
+// string is returned for a file without imports.
+func (f *File) ImportHash() (string, error) {
+	syms, err := f.fileSymbols(SHT_DYNSYM)
+	if err != nil {
+		return "", err
+	}
+	seen := map[string]bool{}
+	var imports []string
+	for _, s := range syms {
+		bind := ST_BIND(s.Info)
+		if s.Name == "" || s.Index != SHN_UNDEF || (bind != STB_GLOBAL && bind != STB_WEAK) {
+			continue
+		}
+		imp := strings.ToLower(s.Library + ":" + s.Name + ":" + s.Version)
+		if !seen[imp] {
+			seen[imp] = true
+			imports = append(imports, imp)
+		}
+	}
+	if len(imports) == 0 {
+		return "", nil
+	}
+	sort.Strings(imports)
+	sum := md5.Sum([]byte(strings.Join(imports, ",")))
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,164 @@
+package elf
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSsdeep(t *testing.T) {
+	// 参考值由libfuzzy的Go移植glaslos/ssdeep计算
+	tests := []struct {
+		name, want string
+	}{
+		{"gcc-amd64-linux-exec", "96:GGANTBlKvw5a/oDunI30H8kbq4hMDZ24Tqox/0XhV6+yZ2AFp:GGANSwQ/MfQ8uqPVB0XT6tZfz"},
+		{"gcc-amd64-linux-hardened", "96:RxoKST/e9B+BXrVrAYxdaCw7/lBwRjBpviOBk:RXSSwJetjB+j/vi"},
+		{"gcc-386-freebsd-exec", "96:CcQ4Sx7/ST8hHtB4ccb55hYx/BkZGhmvwkqEMBEXtUPA32Sc:CcvSV/STUtB4ccb52kUOth2Sc"},
+		{"gcc-amd64-openbsd-debug-with-rela.obj", "96:bghW2/1zm3Hf+KrJ/jL9MAxV0Nx3BPS3z0A03E9LV4X4IbTBHQR:bnyOGwteC0LsP03SL9"},
+		{"go-relocation-test-gcc720-riscv64.obj", "96:ERIK3qmvqN0RSySjl5BXHaRxgPY5gEqie9ac+P7z7r660beAhZBJB8:ERy+quRZoXagQ55qiSah3660G"},
+		{"hello-world-core.gz", "192:IoHWGOQXluv7HsMCTDC2UPwHAu5j0cZ1BKzWKvr3XLOY7CUVFVuJf6Q1q4P2TO9:bWGOm8zHRyLAqp6XfRCiY/PIu"},
+		{"go-relocation-test-clang-x86.obj", "12:BI12GvvxVHcth2bs+pNrbsZuM+pFfzm9mQ6SjhVTPRwwy0oXaMyslmVHazR1EXMo:mIGvvPn50uMKFOjhd51eUaBT7oddJY8z"},
+		{"hello.c", "3:XBAjWhdLjE4Nk+rBGfRhnF5/hvYv:RAqXfE80RhnFh5C"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Ssdeep(readExample(t, tt.name)), tt.name)
+	}
+	assert.Equal(t, "3::", Ssdeep(nil))
+}
+
+func TestTLSH(t *testing.T) {
+	// 参考值由testdata/telfhash/telfhash.py计算，它按TLSH的tlsh_impl.cpp
+	// 独立转写，不使用本包的代码
+	exec := readExample(t, "gcc-amd64-linux-exec")
+	assert.Equal(t, "T18B02508397A5CC6FEC55073D69A74370737BE4F88682CB03164CA6BC0DD32E41E9AA49", TLSH(exec))
+	assert.Equal(t, "T148E0AB935177563ED64A82F9DC90EA303939689403C4FE0D54E4D23A1470A091A8C387",
+		TLSH(sectionData(t, "gcc-amd64-linux-exec", ".text")))
+	// 太短或太单调的输入没有摘要
+	assert.Equal(t, "", TLSH(exec[:tlshMinLength-1]))
+	assert.Equal(t, "", TLSH(make([]byte, 4096)))
+
+	// 改动少量字节，摘要只有少数半字节不同
+	changed := append([]byte{}, exec...)
+	for i := 0x1000; i < 0x1010; i++ {
+		changed[i] ^= 0xff
+	}
+	a, b := TLSH(exec), TLSH(changed)
+	assert.NotEqual(t, a, b)
+	diff := 0
+	for i := range a {
+		if a[i] != b[i] {
+			diff++
+		}
+	}
+	assert.Less(t, diff, 16)
+}
+
+func TestTelfhash(t *testing.T) {
+	tests := []struct {
+		name  string
+		names []string
+	}{
+		{"gcc-amd64-linux-exec", []string{"puts@@glibc_2.2.5"}},
+		{"gcc-amd64-linux-hardened", []string{"read", "read@glibc_2.2.5"}},
+		{"gcc-386-freebsd-exec", []string{"atexit", "exit", "printf"}},
+		{"go-relocation-test-gcc720-riscv64.obj", []string{}},
+	}
+	for _, tt := range tests {
+		p := parseFile(t, path.Join(exampleDir, tt.name))
+		names, err := p.F.telfhashNames()
+		assert.NoError(t, err)
+		assert.Equal(t, tt.names, names, tt.name)
+		// 名字太少时TLSH的桶不够，与telfhash一样返回"-"
+		h, err := p.F.Telfhash()
+		assert.NoError(t, err)
+		assert.Equal(t, "-", h, tt.name)
+		p.CloseFile()
+	}
+
+	// 导入了足够多函数的样本（testdata/telfhash/imports.c），参考值由
+	// telfhash.py按telfhash工具的做法用pyelftools读取符号后计算
+	p := parseFile(t, path.Join("testdata", "telfhash", "gcc-amd64-linux-imports"))
+	names, err := p.F.telfhashNames()
+	assert.NoError(t, err)
+	assert.Len(t, names, 68)
+	assert.Equal(t, []string{"accept", "accept@glibc_2.2.5", "atoi"}, names[:3])
+	h, err := p.F.Telfhash()
+	assert.NoError(t, err)
+	assert.Equal(t, "t14101eb9ca2611b38bef2c234006c0252f1c9c16f83704a908fe54bf7f3661e1e1d0667", h)
+	hashes, err := p.F.SimilarityHashes()
+	assert.NoError(t, err)
+	assert.Equal(t, "T1D882D007B3D0CE7AC9E9437404570A3492B79874EF729317261865F62D837C89E1EB5A", hashes.TLSH)
+	assert.Equal(t, "T1F8110E22B022A234D023B2B049FFD599569730F40731265F77A25A26BF4BFE2575AD23", hashes.TextTLSH)
+	p.CloseFile()
+
+	// 去掉符号后通过重定位找到引用的符号
+	p = parseFile(t, path.Join(exampleDir, "go-relocation-test-gcc930-ranges-with-rela-x86-64"))
+	defer p.CloseFile()
+	names, err = p.F.relocationSymbolNames()
+	assert.NoError(t, err)
+	assert.Contains(t, names, "__libc_start_main@@GLIBC_2.2.5")
+
+	list := "accept,bind,close,connect,execve,fork,getpid,kill,listen,open,ptrace,read,recv,send,socket,unlink,write"
+	assert.Equal(t, "t129b01200f5f37e10d9f3513e708887aec047926742d607244f484c81e43a05a2005b2b",
+		strings.ToLower(tlshDigest([]byte(list), true)))
+	// telfhash的列表不足50字节时也计算
+	short := []byte(list[:tlshMinLength-1])
+	assert.Equal(t, "", TLSH(short))
+	assert.Equal(t, "T11490024151727A604DF2006C1094428E0002565B06D302544F545454F465152104236B", tlshDigest(short, true))
+}
+
+func TestImportHash(t *testing.T) {
+	tests := []struct {
+		name    string
+		imports []string
+	}{
+		{"gcc-amd64-linux-exec", []string{
+			":__gmon_start__:",
+			"libc.so.6:__libc_start_main:glibc_2.2.5",
+			"libc.so.6:puts:glibc_2.2.5",
+		}},
+		{"gcc-amd64-linux-hardened", []string{
+			":__gmon_start__:",
+			":_itm_deregistertmclonetable:",
+			":_itm_registertmclonetable:",
+			"libc.so.6:__cxa_finalize:glibc_2.2.5",
+			"libc.so.6:__libc_start_main:glibc_2.34",
+			"libc.so.6:__printf_chk:glibc_2.3.4",
+			"libc.so.6:__stack_chk_fail:glibc_2.4",
+			"libc.so.6:__strcpy_chk:glibc_2.3.4",
+			"libc.so.6:read:glibc_2.2.5",
+		}},
+	}
+	for _, tt := range tests {
+		p := parseFile(t, path.Join(exampleDir, tt.name))
+		h, err := p.F.ImportHash()
+		assert.NoError(t, err)
+		sum := md5.Sum([]byte(strings.Join(tt.imports, ",")))
+		assert.Equal(t, hex.EncodeToString(sum[:]), h, tt.name)
+		p.CloseFile()
+	}
+	p := parseFile(t, path.Join(exampleDir, "go-relocation-test-gcc441-x86-64.obj"))
+	defer p.CloseFile()
+	h, err := p.F.ImportHash()
+	assert.NoError(t, err)
+	assert.Equal(t, "", h)
+}
+
+func TestSimilarityHashes(t *testing.T) {
+	p := parseFile(t, path.Join(exampleDir, "gcc-amd64-linux-hardened"))
+	defer p.CloseFile()
+	h, err := p.F.SimilarityHashes()
+	assert.NoError(t, err)
+	assert.Equal(t, SimilarityHashes{
+		Telfhash:   "-",
+		TLSH:       "T14B727507E3B1CE3FCCA8133C455B4B3132B5E8109B625733671461765E83B586D7AE9A",
+		TextTLSH:   "T1D8E0AB833121007CD580B114FA2359661FB0348201144635FB88853A9E89E38740C026",
+		Ssdeep:     "96:RxoKST/e9B+BXrVrAYxdaCw7/lBwRjBpviOBk:RXSSwJetjB+j/vi",
+		TextSsdeep: "12:T2RAzjBISKeQHO2Z/rbo4lCxbqG1v+lStlm:A2yeQHOODJpQmot4",
+		ImportHash: "024a478e5c0d14b4bed7b0960b2e5bbb",
+	}, h)
+}
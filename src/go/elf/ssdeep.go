@@ -0,0 +1,147 @@
+// Package elf : ssdeep.go computes the ssdeep context triggered piecewise
+// hash (spamsum) of Kornblum, the digests are those of fuzzy_hash_buf of
+// libfuzzy without flags: "blocksize:digest:digest".
+package elf
+
+import "strconv"
+
+const (
+	// ssdeepWindow is the size of the rolling hash window.
+	ssdeepWindow = 7
+	// ssdeepMinBlockSize is the smallest block size.
+	ssdeepMinBlockSize = 3
+	// ssdeepLength is the maximum length of the first digest.
+	ssdeepLength = 64
+	// ssdeepNumBlockHashes is the number of block sizes tried.
+	ssdeepNumBlockHashes = 31
+	// ssdeepHashInit is the initial value of the piece hashes, the low 6
+	// bits of the FNV like hash of spamsum are the only ones used.
+	ssdeepHashInit  = 0x27
+	ssdeepHashPrime = 0x93
+)
+
+const ssdeepBase64 = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// ssdeepRoll is the rolling hash deciding where pieces end.
+type ssdeepRoll struct {
+	window     [ssdeepWindow]byte
+	h1, h2, h3 uint32
+	n          uint32
+}
+
+func (r *ssdeepRoll) hash(c byte) {
+	r.h2 -= r.h1
+	r.h2 += ssdeepWindow * uint32(c)
+	r.h1 += uint32(c)
+	r.h1 -= uint32(r.window[r.n%ssdeepWindow])
+	r.window[r.n%ssdeepWindow] = c
+	r.n++
+	r.h3 <<= 5
+	r.h3 ^= uint32(c)
+}
+
+func (r *ssdeepRoll) sum() uint32 {
+	return r.h1 + r.h2 + r.h3
+}
+
+// ssdeepBlock is the digest under construction for one block size, h hashes
+// the current piece, halfh the piece of the digest truncated to 32
+// characters. tail is the character at the position past the last one,
+// written when the digest is full, halfTail the last character of halfh.
+type ssdeepBlock struct {
+	h, halfh       byte
+	digest         []byte
+	tail, halfTail byte
+}
+
+func ssdeepSumHash(c, h byte) byte {
+	return (h*ssdeepHashPrime ^ c) & 0x3f
+}
+
+func ssdeepBlockSize(i int) uint64 {
+	return ssdeepMinBlockSize << uint(i)
+}
+
+// Ssdeep returns the ssdeep digest of data.
+func Ssdeep(data []byte) string {
+	var roll ssdeepRoll
+	blocks := []*ssdeepBlock{{h: ssdeepHashInit, halfh: ssdeepHashInit}}
+	start := 0
+	total := uint64(len(data))
+	for _, c := range data {
+		roll.hash(c)
+		h := roll.sum()
+		for _, b := range blocks[start:] {
+			b.h = ssdeepSumHash(c, b.h)
+			b.halfh = ssdeepSumHash(c, b.halfh)
+		}
+		// 只有h ≡ -1 (mod 块大小) 时才是分块点，块大小翻倍时条件更严
+		for i := start; i < len(blocks); i++ {
+			bs := ssdeepBlockSize(i)
+			if uint64(h)%bs != bs-1 {
+				break
+			}
+			b := blocks[i]
+			if len(b.digest) == 0 && len(blocks) < ssdeepNumBlockHashes {
+				// 这个块大小第一次分块，从它派生下一个块大小
+				blocks = append(blocks, &ssdeepBlock{h: b.h, halfh: b.halfh})
+			}
+			b.tail = ssdeepBase64[b.h]
+			b.halfTail = ssdeepBase64[b.halfh]
+			if len(b.digest) < ssdeepLength-1 {
+				// 摘要满了之后最后几块合并成一块
+				b.digest = append(b.digest, b.tail)
+				b.tail = 0
+				b.h = ssdeepHashInit
+				if len(b.digest) < ssdeepLength/2 {
+					b.halfh = ssdeepHashInit
+					b.halfTail = 0
+				}
+				continue
+			}
+			// 较小的块大小不会再被选中时就不再计算
+			if len(blocks)-start >= 2 && ssdeepBlockSize(start)*ssdeepLength < total &&
+				len(blocks[start+1].digest) >= ssdeepLength/2 {
+				start++
+			}
+		}
+	}
+
+	// 先按输入长度估计块大小，再选摘要至少有32个字符的那个
+	bi := start
+	for ssdeepBlockSize(bi)*ssdeepLength < total && bi < ssdeepNumBlockHashes-1 {
+		bi++
+	}
+	for bi >= len(blocks) {
+		bi--
+	}
+	for bi > start && len(blocks[bi].digest) < ssdeepLength/2 {
+		bi--
+	}
+
+	h := roll.sum()
+	b := blocks[bi]
+	out := strconv.FormatUint(ssdeepBlockSize(bi), 10) + ":" + string(b.digest)
+	if h != 0 {
+		out += string(ssdeepBase64[b.h])
+	} else if b.tail != 0 {
+		out += string(b.tail)
+	}
+	out += ":"
+	if bi < len(blocks)-1 {
+		b = blocks[bi+1]
+		d := b.digest
+		if len(d) > ssdeepLength/2-1 {
+			d = d[:ssdeepLength/2-1]
+		}
+		out += string(d)
+		if h != 0 {
+			out += string(ssdeepBase64[b.halfh])
+		} else if b.halfTail != 0 {
+			out += string(b.halfTail)
+		}
+	} else if h != 0 {
+		out += string(ssdeepBase64[b.h])
+	}
+	return out
+}
@@ -0,0 +1,109 @@
+// Package elf : symhash.go decodes the symbol hash tables used by the
+// dynamic linker (SHT_HASH and SHT_GNU_HASH).
+package elf
+
+import "errors"
+
+// HashTable summarizes a symbol hash table.
+type HashTable struct {
+	Section string `json:"section"`
+	Type    SectionType
+	// BucketLengths holds the length of the chain hanging off each bucket.
+	BucketLengths []int `json:"bucket_lengths"`
+	// Symbols is the number of symbols reachable through the table.
+	Symbols int `json:"symbols"`
+}
+
+// HashTables decodes every SHT_HASH and SHT_GNU_HASH section of the file.
+func (p *Parser) HashTables() ([]HashTable, error) {
+	var tables []HashTable
+	for _, s := range p.F.Sections() {
+		var (
+			t   HashTable
+			err error
+		)
+		switch SectionType(s.Type) {
+		case SHT_HASH:
+			t, err = p.sysvHashTable(s)
+		case SHT_GNU_HASH:
+			t, err = p.gnuHashTable(s)
+		default:
+			continue
+		}
+		if err != nil {
+			return tables, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, nil
+}
+
+// sysvHashTable decodes a SHT_HASH section: nbucket, nchain, buckets, chains.
+func (p *Parser) sysvHashTable(s *ELF64Section) (HashTable, error) {
+	t := HashTable{Section: s.SectionName, Type: SHT_HASH}
+	data, err := s.Data()
+	if err != nil {
+		return t, err
+	}
+	if len(data) < 8 {
+		return t, errors.New("hash section is too small")
+	}
+	bo := p.F.ByteOrder()
+	nbucket := int(bo.Uint32(data))
+	nchain := int(bo.Uint32(data[4:]))
+	if 8+(nbucket+nchain)*4 > len(data) {
+		return t, errors.New("hash section is truncated")
+	}
+	word := func(i int) int { return int(bo.Uint32(data[8+i*4:])) }
+	t.BucketLengths = make([]int, nbucket)
+	for b := 0; b < nbucket; b++ {
+		// 链上的符号索引为0表示结束，步数上限为nchain防止环
+		for si, n := word(b), 0; si != 0 && si < nchain && n < nchain; si, n = word(nbucket+si), n+1 {
+			t.BucketLengths[b]++
+			t.Symbols++
+		}
+	}
+	return t, nil
+}
+
+// gnuHashTable decodes a SHT_GNU_HASH section: nbuckets, symoffset,
+// bloom_size, bloom_shift, bloom filter, buckets and the hash chain.
+func (p *Parser) gnuHashTable(s *ELF64Section) (HashTable, error) {
+	t := HashTable{Section: s.SectionName, Type: SHT_GNU_HASH}
+	data, err := s.Data()
+	if err != nil {
+		return t, err
+	}
+	if len(data) < 16 {
+		return t, errors.New("gnu hash section is too small")
+	}
+	bo := p.F.ByteOrder()
+	nbuckets := int(bo.Uint32(data))
+	symoffset := int(bo.Uint32(data[4:]))
+	bloomSize := int(bo.Uint32(data[8:]))
+	wordSize := 8
+	if p.F.Class() == ELFCLASS32 {
+		wordSize = 4
+	}
+	buckets := 16 + bloomSize*wordSize
+	chains := buckets + nbuckets*4
+	if chains > len(data) {
+		return t, errors.New("gnu hash section is truncated")
+	}
+	t.BucketLengths = make([]int, nbuckets)
+	for b := 0; b < nbuckets; b++ {
+		si := int(bo.Uint32(data[buckets+b*4:]))
+		if si < symoffset {
+			continue
+		}
+		for off := chains + (si-symoffset)*4; off+4 <= len(data); off += 4 {
+			t.BucketLengths[b]++
+			t.Symbols++
+			// 最低位为1表示链结束
+			if bo.Uint32(data[off:])&1 == 1 {
+				break
+			}
+		}
+	}
+	return t, nil
+}
@@ -0,0 +1,34 @@
+package elf
+
+import (
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Bucket list lengths as reported by readelf -I.
+func TestHashTables(t *testing.T) {
+	p, err := New(path.Join(exampleDir, "gcc-386-freebsd-exec"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.CloseFile()
+	if err := p.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	tables, err := p.HashTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, tables, 1) {
+		assert.Equal(t, SHT_HASH, tables[0].Type)
+		assert.Len(t, tables[0].BucketLengths, 17)
+		histogram := map[int]int{}
+		for _, l := range tables[0].BucketLengths {
+			histogram[l]++
+		}
+		assert.Equal(t, map[int]int{0: 6, 1: 6, 2: 5}, histogram)
+		assert.Equal(t, 16, tables[0].Symbols)
+	}
+}
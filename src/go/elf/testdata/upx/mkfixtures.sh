@@ -0,0 +1,22 @@
+#!/bin/sh
+# 用真实的upx生成UnpackUPX的测试样本：
+#   ./mkfixtures.sh ELF...
+# 每个输入按四种压缩方法各加壳一次，得到<名字>.<方法>.upx，
+# 解压后应得到的原文件的SHA-256写在同名的.sha256文件中。
+# 另外把所有"UPX!"魔数改成"ABC!"（恶意样本常用的篡改，使upx -d失败），
+# 得到<名字>.<方法>.tampered.upx。
+# amd64、i386、arm和aarch64的输入可以用对应的交叉编译器静态链接得到。
+set -e
+dir=$(dirname "$0")
+for f in "$@"; do
+	name=$(basename "$f")
+	for m in nrv2b nrv2d nrv2e lzma; do
+		out="$dir/$name.$m.upx"
+		rm -f "$out"
+		upx -q --"$m" -o "$out" "$f" >/dev/null
+		sha256sum "$f" | cut -d' ' -f1 >"$out.sha256"
+		tampered="$dir/$name.$m.tampered.upx"
+		LC_ALL=C sed 's/UPX!/ABC!/g' "$out" >"$tampered"
+		cp "$out.sha256" "$tampered.sha256"
+	done
+done
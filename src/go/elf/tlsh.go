@@ -0,0 +1,137 @@
+// Package elf : tlsh.go computes the TLSH locality sensitive hash of Trend
+// Micro, the 128 bucket variant with a 1 byte checksum written by the
+// reference implementation as a "T1" prefixed hex digest. Close inputs get
+// digests differing in few nibbles.
+package elf
+
+import (
+	"encoding/hex"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	// tlshWindow is the size of the sliding window.
+	tlshWindow = 5
+	// tlshBuckets is the number of buckets the digest is built from.
+	tlshBuckets = 128
+	// tlshCodeSize is the size of the body of the digest.
+	tlshCodeSize = tlshBuckets / 4
+	// tlshMinLength is the smallest input TLSH hashes.
+	tlshMinLength = 50
+)
+
+// tlshPearson is the Pearson permutation table of TLSH.
+var tlshPearson = [256]byte{
+	1, 87, 49, 12, 176, 178, 102, 166, 121, 193, 6, 84, 249, 230, 44, 163,
+	14, 197, 213, 181, 161, 85, 218, 80, 64, 239, 24, 226, 236, 142, 38, 200,
+	110, 177, 104, 103, 141, 253, 255, 50, 77, 101, 81, 18, 45, 96, 31, 222,
+	25, 107, 190, 70, 86, 237, 240, 34, 72, 242, 20, 214, 244, 227, 149, 235,
+	97, 234, 57, 22, 60, 250, 82, 175, 208, 5, 127, 199, 111, 62, 135, 248,
+	174, 169, 211, 58, 66, 154, 106, 195, 245, 171, 17, 187, 182, 179, 0, 243,
+	132, 56, 148, 75, 128, 133, 158, 100, 130, 126, 91, 13, 153, 246, 216, 219,
+	119, 68, 223, 78, 83, 88, 201, 99, 122, 11, 92, 32, 136, 114, 52, 10,
+	138, 30, 48, 183, 156, 35, 61, 26, 143, 74, 251, 94, 129, 162, 63, 152,
+	170, 7, 115, 167, 241, 206, 3, 150, 55, 59, 151, 220, 90, 53, 23, 131,
+	125, 173, 15, 238, 79, 95, 89, 16, 105, 137, 225, 224, 217, 160, 37, 123,
+	118, 73, 2, 157, 46, 116, 9, 145, 134, 228, 207, 212, 202, 215, 69, 229,
+	27, 188, 67, 124, 168, 252, 42, 4, 29, 108, 21, 247, 19, 205, 39, 203,
+	233, 40, 186, 147, 198, 192, 155, 33, 164, 191, 98, 204, 165, 180, 117, 76,
+	140, 36, 210, 172, 41, 54, 159, 8, 185, 232, 113, 196, 231, 47, 146, 120,
+	51, 65, 28, 144, 254, 221, 93, 189, 194, 139, 112, 43, 71, 109, 184, 209,
+}
+
+// tlshMapping hashes the salt and three bytes of the window to a bucket.
+func tlshMapping(salt, i, j, k byte) byte {
+	h := tlshPearson[salt]
+	h = tlshPearson[h^i]
+	h = tlshPearson[h^j]
+	return tlshPearson[h^k]
+}
+
+// tlshLength encodes the input length on a logarithmic scale.
+func tlshLength(n int) byte {
+	l := math.Log(float64(n))
+	var i int
+	switch {
+	case n <= 656:
+		i = int(math.Floor(l / 0.4054651))
+	case n <= 3199:
+		i = int(math.Floor(l/0.26236426 - 8.72777))
+	default:
+		i = int(math.Floor(l/0.095310180 - 62.5472))
+	}
+	return byte(i)
+}
+
+// swapNibbles swaps the two halves of b, the reference implementation
+// writes the header bytes of the digest that way.
+func swapNibbles(b byte) byte {
+	return b<<4 | b>>4
+}
+
+// TLSH returns the TLSH digest of data, empty when data is shorter than
+// 50 bytes or too uniform for half of the buckets to be filled.
+func TLSH(data []byte) string {
+	return tlshDigest(data, false)
+}
+
+// tlshDigest computes the digest, force drops the minimum length the way
+// the forcehash function of the reference implementation does.
+func tlshDigest(data []byte, force bool) string {
+	if len(data) < tlshMinLength && !force {
+		return ""
+	}
+	var buckets [256]uint32
+	var checksum byte
+	for i := tlshWindow - 1; i < len(data); i++ {
+		// 窗口内的字节：w0是当前字节，w4是最早的字节
+		w0, w1, w2, w3, w4 := data[i], data[i-1], data[i-2], data[i-3], data[i-4]
+		checksum = tlshMapping(0, w0, w1, checksum)
+		buckets[tlshMapping(2, w0, w1, w2)]++
+		buckets[tlshMapping(3, w0, w1, w3)]++
+		buckets[tlshMapping(5, w0, w2, w3)]++
+		buckets[tlshMapping(7, w0, w2, w4)]++
+		buckets[tlshMapping(11, w0, w1, w4)]++
+		buckets[tlshMapping(13, w0, w3, w4)]++
+	}
+
+	// 只用前128个桶，按四分位数把每个桶编码成2位
+	sorted := make([]uint32, tlshBuckets)
+	copy(sorted, buckets[:tlshBuckets])
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	q1, q2, q3 := sorted[tlshBuckets/4-1], sorted[tlshBuckets/2-1], sorted[tlshBuckets-tlshBuckets/4-1]
+	nonzero := 0
+	for _, c := range buckets[:tlshBuckets] {
+		if c > 0 {
+			nonzero++
+		}
+	}
+	if nonzero <= tlshBuckets/2 {
+		return ""
+	}
+	var code [tlshCodeSize]byte
+	for i := range code {
+		var h byte
+		for j := 0; j < 4; j++ {
+			switch k := buckets[4*i+j]; {
+			case q3 < k:
+				h += 3 << (uint(j) * 2)
+			case q2 < k:
+				h += 2 << (uint(j) * 2)
+			case q1 < k:
+				h += 1 << (uint(j) * 2)
+			}
+		}
+		code[i] = h
+	}
+	q1Ratio := byte(uint32(float32(q1*100)/float32(q3)) % 16)
+	q2Ratio := byte(uint32(float32(q2*100)/float32(q3)) % 16)
+
+	digest := []byte{swapNibbles(checksum), swapNibbles(tlshLength(len(data))), q1Ratio<<4 | q2Ratio}
+	for i := tlshCodeSize - 1; i >= 0; i-- {
+		digest = append(digest, code[i])
+	}
+	return "T1" + strings.ToUpper(hex.EncodeToString(digest))
+}
@@ -0,0 +1,513 @@
+// Package elf : upx.go unpacks the ELF executables packed by UPX without
+// the upx tool. The packed file keeps the l_info and p_info headers after
+// its program headers, followed by the compressed blocks of the original
+// file, each behind a b_info header: first the ELF and program headers,
+// then the PT_LOAD segments and last the gaps between them. The headers
+// malware authors tamper with to break upx -d are repaired when the layout
+// still gives their value away.
+package elf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/adler32"
+	"sort"
+)
+
+// ErrNotUPX is returned by UnpackUPX when the file has no UPX headers.
+var ErrNotUPX = errors.New("not a UPX packed file")
+
+// UPXResult is the unpacked image of a UPX packed file.
+type UPXResult struct {
+	// Parser has parsed Data.
+	Parser *Parser `json:"-"`
+	Data   []byte  `json:"-"`
+	// Format is the UPX format of the packed file.
+	Format string `json:"format"`
+	// Methods and Filters are the compression methods and the filters of
+	// the blocks, filters as their UPX id.
+	Methods []string `json:"methods"`
+	Filters []string `json:"filters"`
+	Blocks  int      `json:"blocks"`
+	// Notes are the repairs made to tampered headers and the checks which
+	// failed without stopping the unpacking.
+	Notes []string `json:"notes"`
+}
+
+const (
+	// bInfoSize is the size of the b_info header of a block.
+	bInfoSize = 12
+	// maxUPXFileSize bounds the unpacked size read from the headers.
+	maxUPXFileSize = 1 << 30
+)
+
+// bInfo is the UPX b_info header in front of every compressed block.
+type bInfo struct {
+	SzUnc  uint32
+	SzCpr  uint32
+	Method uint8
+	FtID   uint8
+	CTO8   uint8
+	Unused uint8
+}
+
+// upxNRVMethods maps the NRV methods to their variant and bit buffer width.
+var upxNRVMethods = map[uint8]struct {
+	variant nrvVariant
+	width   uint
+}{
+	2:  {nrv2b, 32},
+	3:  {nrv2b, 8},
+	4:  {nrv2b, 16},
+	5:  {nrv2d, 32},
+	6:  {nrv2d, 8},
+	7:  {nrv2d, 16},
+	8:  {nrv2e, 32},
+	9:  {nrv2e, 8},
+	10: {nrv2e, 16},
+}
+
+// upxMethodLZMA is the LZMA method of UPX.
+const upxMethodLZMA = 14
+
+// upxSupported reports whether UnpackUPX can decompress a method.
+func upxSupported(method uint8) bool {
+	_, ok := upxNRVMethods[method]
+	return ok || method == upxMethodLZMA
+}
+
+// upxDecompress decompresses a block of method into size bytes.
+func upxDecompress(method uint8, src []byte, size int) ([]byte, error) {
+	if m, ok := upxNRVMethods[method]; ok {
+		return nrvDecompress(m.variant, m.width, src, size)
+	}
+	if method != upxMethodLZMA {
+		return nil, fmt.Errorf("unsupported UPX method %s", upxMethodName(method))
+	}
+	// UPX在LZMA数据前加两个字节：(lc+lp)<<3|pb 和 lp<<4|lc
+	if len(src) < 3 {
+		return nil, errLZMACorrupt
+	}
+	pb, lp, lc := uint(src[0]&7), uint(src[1]>>4), uint(src[1]&15)
+	if pb >= 5 || lp >= 5 || lc >= 9 || uint(src[0]>>3) != lc+lp {
+		return nil, errLZMACorrupt
+	}
+	return lzmaDecompress(src[2:], lc, lp, pb, size)
+}
+
+// upxUnfilter undoes the filter ftid UPX applied to the code of a block
+// before compressing it. The x86 filters turned the relative targets of
+// the calls and jumps into absolute big endian ones marked by cto in the
+// top byte, the ARM filters the targets of BL.
+func upxUnfilter(ftid, cto uint8, b []byte) error {
+	switch ftid {
+	case 0x24, 0x25, 0x26, 0x46, 0x49:
+		// 0x24 e8，0x25 e9，其余 e8和e9，0x49 还有 0f 8x 的条件跳转；
+		// 与UPX的filter/ctok.h一样只看到 buf_len-5 之前，最后5个字节不处理
+		lastCall := 0
+		for ic := 0; ic+5 < len(b); ic++ {
+			op := b[ic]
+			isCall := (op == 0xe8 && ftid != 0x25) || (op == 0xe9 && ftid != 0x24)
+			isJcc := ftid == 0x49 && ic > 0 && ic != lastCall && b[ic-1] == 0x0f && op&0xf0 == 0x80
+			if !isCall && !isJcc || b[ic+1] != cto {
+				continue
+			}
+			jc := binary.BigEndian.Uint32(b[ic+1:])
+			binary.LittleEndian.PutUint32(b[ic+1:], jc-uint32(ic)-1-uint32(cto)<<24)
+			ic += 4
+			lastCall = ic + 1
+		}
+	case 0x50:
+		// ARM BL：低24位是以字为单位的偏移
+		for ic := 0; ic+4 <= len(b); ic += 4 {
+			if b[ic+3]&0x0f == 0x0b {
+				w := binary.LittleEndian.Uint32(b[ic:])
+				w = w&0xff000000 | (w-uint32(ic)/4)&0x00ffffff
+				binary.LittleEndian.PutUint32(b[ic:], w)
+			}
+		}
+	case 0x52:
+		// ARM64 BL：低26位是以字为单位的偏移
+		for ic := 0; ic+4 <= len(b); ic += 4 {
+			if b[ic+3]&0xfc == 0x94 {
+				w := binary.LittleEndian.Uint32(b[ic:])
+				w = w&0xfc000000 | (w-uint32(ic)/4)&0x03ffffff
+				binary.LittleEndian.PutUint32(b[ic:], w)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported UPX filter %#x", ftid)
+	}
+	return nil
+}
+
+// upxUnpacker walks the blocks of a packed file.
+type upxUnpacker struct {
+	data      []byte
+	order     binary.ByteOrder
+	pos       uint64
+	blockSize uint32
+	out       []byte
+	cAdler    hash.Hash32
+	uAdler    hash.Hash32
+	res       *UPXResult
+	methods   map[string]bool
+	filters   map[string]bool
+	// chains caches chained for the b_info offsets already walked.
+	chains map[uint64]bool
+}
+
+// readBInfo reads the b_info at off.
+func (u *upxUnpacker) readBInfo(off uint64) (bInfo, bool) {
+	var b bInfo
+	if off+bInfoSize > uint64(len(u.data)) {
+		return b, false
+	}
+	binary.Read(bytes.NewReader(u.data[off:off+bInfoSize]), u.order, &b)
+	return b, true
+}
+
+// plausible reports whether the b_info at off can start a block of at most
+// want bytes.
+func (u *upxUnpacker) plausible(off uint64, want uint64) bool {
+	b, ok := u.readBInfo(off)
+	if !ok || b.SzUnc == 0 || b.SzCpr == 0 || b.SzCpr > b.SzUnc || uint64(b.SzUnc) > want || b.SzUnc > u.blockSize {
+		return false
+	}
+	if off+bInfoSize+uint64(b.SzCpr) > uint64(len(u.data)) {
+		return false
+	}
+	return b.SzCpr == b.SzUnc || upxSupported(b.Method)
+}
+
+// block decompresses the block at off, returning it unfiltered.
+func (u *upxUnpacker) block(off uint64) (bInfo, []byte, error) {
+	b, _ := u.readBInfo(off)
+	src := u.data[off+bInfoSize : off+bInfoSize+uint64(b.SzCpr)]
+	if b.SzCpr == b.SzUnc {
+		// 压缩后没有变小的块原样存放
+		return b, append([]byte(nil), src...), nil
+	}
+	dst, err := upxDecompress(b.Method, src, int(b.SzUnc))
+	if err != nil {
+		return b, nil, fmt.Errorf("block at %#x: %s: %w", off, upxMethodName(b.Method), err)
+	}
+	if b.FtID != 0 {
+		if err := upxUnfilter(b.FtID, b.CTO8, dst); err != nil {
+			return b, nil, fmt.Errorf("block at %#x: %w", off, err)
+		}
+	}
+	return b, dst, nil
+}
+
+// chained reports whether following the sizes of the b_info headers from
+// off reaches the end marker, whose sz_unc is 0. The result is kept for
+// every b_info on the way.
+func (u *upxUnpacker) chained(off uint64) bool {
+	var path []uint64
+	ok := false
+	for {
+		if known, seen := u.chains[off]; seen {
+			ok = known
+			break
+		}
+		b, in := u.readBInfo(off)
+		if !in {
+			break
+		}
+		if b.SzUnc == 0 {
+			// 结束标记的魔数可能被篡改，只看sz_unc
+			ok = true
+			break
+		}
+		if !u.plausible(off, ^uint64(0)) {
+			break
+		}
+		path = append(path, off)
+		off += bInfoSize + uint64(b.SzCpr)
+	}
+	for _, o := range path {
+		u.chains[o] = ok
+	}
+	return ok
+}
+
+// next returns the next block of at most want bytes. When the b_info at
+// the current position is not one, the loader UPX puts between the
+// segments and the gaps is skipped to the first b_info whose sizes chain
+// the blocks after it up to the end marker, nothing is decompressed before
+// it is found.
+func (u *upxUnpacker) next(want uint64) (bInfo, []byte, error) {
+	if u.plausible(u.pos, want) {
+		return u.nextAt(u.pos)
+	}
+	for off := u.pos + 1; off+bInfoSize <= uint64(len(u.data)); off++ {
+		if u.plausible(off, want) && u.chained(off) {
+			return u.nextAt(off)
+		}
+	}
+	return bInfo{}, nil, fmt.Errorf("no b_info for the %d bytes at %#x", want, u.pos)
+}
+
+func (u *upxUnpacker) nextAt(off uint64) (bInfo, []byte, error) {
+	b, dst, err := u.block(off)
+	if err != nil {
+		return b, nil, err
+	}
+	u.pos = off + bInfoSize + uint64(b.SzCpr)
+	return b, dst, nil
+}
+
+// extent unpacks size bytes of the original file to offset off.
+func (u *upxUnpacker) extent(off, size uint64) error {
+	if off+size > uint64(len(u.out)) {
+		return fmt.Errorf("extent [%#x, %#x) is past the unpacked size %#x", off, off+size, len(u.out))
+	}
+	for size > 0 {
+		b, dst, err := u.next(size)
+		if err != nil {
+			return err
+		}
+		u.cAdler.Write(u.data[u.pos-uint64(b.SzCpr) : u.pos])
+		u.uAdler.Write(dst)
+		copy(u.out[off:], dst)
+		off += uint64(len(dst))
+		size -= uint64(len(dst))
+		u.res.Blocks++
+		if b.SzCpr < b.SzUnc {
+			u.methods[upxMethodName(b.Method)] = true
+			if b.FtID != 0 {
+				u.filters[fmt.Sprintf("%#x", b.FtID)] = true
+			}
+		}
+	}
+	return nil
+}
+
+// scanPackHeader looks for a PackHeader whose magic was overwritten, by
+// its checksum and a known format and method.
+func (p *Parser) scanPackHeader() (uint64, packHeader, bool) {
+	var ph packHeader
+	size := uint64(p.F.size)
+	start := uint64(0)
+	if size > 0x400 {
+		start = size - 0x400
+	}
+	tail := p.readRange(start, size-start)
+	for i := len(tail) - packHeaderSize; i >= 0; i-- {
+		h := tail[i : i+packHeaderSize]
+		if h[31] != packHeaderChecksum(h) || upxFormats[h[5]] == "" || !upxSupported(h[6]) {
+			continue
+		}
+		binary.Read(bytes.NewReader(h), binary.LittleEndian, &ph)
+		return start + uint64(i), ph, true
+	}
+	return 0, ph, false
+}
+
+// packHeaderChecksum returns the checksum UPX stores in the last byte of
+// the PackHeader h.
+func packHeaderChecksum(h []byte) uint8 {
+	sum := 0
+	for _, c := range h[4 : packHeaderSize-1] {
+		sum += int(c)
+	}
+	return uint8(sum % 251)
+}
+
+// findLoadGap returns the size of the file bytes following the PT_LOAD
+// phdrs[k] up to the next PT_LOAD or the end of the file, the bytes UPX
+// packs after the segments.
+func findLoadGap(phdrs []ELF64ProgramHeader, k int, fileSize uint64) uint64 {
+	if ProgType(phdrs[k].Type) != PT_LOAD {
+		return 0
+	}
+	hi := phdrs[k].Off + phdrs[k].Filesz
+	lo := fileSize
+	if lo < hi {
+		return 0
+	}
+	for j, ph := range phdrs {
+		if j != k && ProgType(ph.Type) == PT_LOAD && ph.Off >= hi && ph.Off < lo {
+			lo = ph.Off
+		}
+	}
+	return lo - hi
+}
+
+// upxHeaders decodes the ELF and program headers of the first block.
+func upxHeaders(data []byte) ([]ELF64ProgramHeader, error) {
+	p, err := NewBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.ParseIdent(); err != nil {
+		return nil, err
+	}
+	if err := p.ParseELFHeader(p.F.Ident.Class); err != nil {
+		return nil, err
+	}
+	if err := p.ParseELFProgramHeaders(p.F.Ident.Class); err != nil {
+		return nil, err
+	}
+	return p.F.ProgramHeaders(), nil
+}
+
+// UnpackUPX unpacks a file packed by UPX with the NRV2B, NRV2D, NRV2E or
+// LZMA methods and parses the unpacked file. A tampered l_info or end
+// marker magic, a PackHeader without its magic and a zeroed file or block
+// size in p_info are repaired and reported in the Notes of the result.
+// The packed PIE executables, whose stub has a PT_DYNAMIC segment, are
+// unpacked like the others. The packed shared libraries are not supported:
+// UPX leaves their headers and first PT_LOAD uncompressed, so their blocks
+// do not start with the ELF header and the unpacking fails.
+func (p *Parser) UnpackUPX() (*UPXResult, error) {
+	res := &UPXResult{Methods: []string{}, Filters: []string{}, Notes: []string{}}
+	note := func(format string, args ...interface{}) {
+		res.Notes = append(res.Notes, fmt.Sprintf(format, args...))
+	}
+	data := p.readRange(0, uint64(p.F.size))
+	order := p.F.ByteOrder()
+
+	// PackHeader在文件末尾，其后4字节是p_info的偏移
+	phOff, ph, havePH := p.findPackHeader()
+	if !havePH {
+		if phOff, ph, havePH = p.scanPackHeader(); havePH {
+			note("PackHeader magic %q at %#x restored", ph.Magic[:], phOff)
+		}
+	}
+	candidates := []uint64{p.upxInfoOffset()}
+	if havePH && phOff+packHeaderSize+4 <= uint64(len(data)) {
+		if overlay := uint64(order.Uint32(data[phOff+packHeaderSize:])); overlay >= lInfoSize && overlay != candidates[0]+lInfoSize {
+			candidates = append(candidates, overlay-lInfoSize)
+		}
+	}
+
+	// l_info：先找魔数完好的，再找后面跟着合理b_info的
+	u := &upxUnpacker{data: data, order: order, blockSize: ^uint32(0), res: res,
+		cAdler: adler32.New(), uAdler: adler32.New(), methods: map[string]bool{}, filters: map[string]bool{}, chains: map[uint64]bool{}}
+	lOff, found := uint64(0), false
+	var l lInfo
+	var pi pInfo
+	for _, tampered := range []bool{false, true} {
+		for _, off := range candidates {
+			cl, cpi, ok := p.readUPXInfo(off)
+			if !ok || (string(cl.Magic[:]) == upxMagic) == tampered || !u.plausible(off+lInfoSize+pInfoSize, 0x10000) {
+				continue
+			}
+			if tampered {
+				note("l_info magic %q at %#x restored", cl.Magic[:], off)
+			}
+			lOff, l, pi, found = off, cl, cpi, true
+			break
+		}
+		if found {
+			break
+		}
+	}
+	if !found {
+		return nil, ErrNotUPX
+	}
+	res.Format = upxFormatName(l.Format)
+
+	u.pos = lOff + lInfoSize + pInfoSize
+	first := u.pos
+	if pi.BlockSize != 0 {
+		u.blockSize = pi.BlockSize
+	} else {
+		note("zero p_info block size ignored")
+	}
+	fileSize := uint64(pi.FileSize)
+	if fileSize == 0 {
+		if havePH && ph.UFileSize != 0 {
+			fileSize = uint64(ph.UFileSize)
+			note("zero p_info file size replaced by the PackHeader size %d", fileSize)
+		} else {
+			// 所有块的解压大小之和就是原文件大小
+			for {
+				if b, ok := u.readBInfo(u.pos); !ok || b.SzUnc == 0 {
+					break
+				}
+				b, _, err := u.next(^uint64(0))
+				if err != nil {
+					break
+				}
+				fileSize += uint64(b.SzUnc)
+			}
+			u.pos = first
+			note("zero p_info file size replaced by the block sizes %d", fileSize)
+		}
+	}
+
+	if fileSize > maxUPXFileSize {
+		return nil, fmt.Errorf("unpacked size %d is too large", fileSize)
+	}
+
+	// 第一个块是原文件的ELF头和程序头，它同时是第一个PT_LOAD的开头
+	var phdrs []ELF64ProgramHeader
+	_, hdr, err := u.block(first)
+	if err == nil {
+		phdrs, err = upxHeaders(hdr)
+	}
+	if err != nil {
+		if p.hasSegment(PT_DYNAMIC) {
+			// 共享库的头部和第一个PT_LOAD没有压缩，第一个块不是ELF头
+			return nil, fmt.Errorf("unpacked ELF header: %w (UPX packed shared libraries are not supported)", err)
+		}
+		return nil, fmt.Errorf("unpacked ELF header: %w", err)
+	}
+	u.out = make([]byte, fileSize)
+	for _, ph := range phdrs {
+		if ProgType(ph.Type) == PT_LOAD {
+			if err := u.extent(ph.Off, ph.Filesz); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for k, ph := range phdrs {
+		if gap := findLoadGap(phdrs, k, fileSize); gap != 0 {
+			if err := u.extent(ph.Off+ph.Filesz, gap); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// 结束标记：sz_unc为0，sz_cpr为小端的"UPX!"
+	end := append([]byte{0, 0, 0, 0}, upxMagic...)
+	if b, ok := u.readBInfo(u.pos); ok && b.SzUnc == 0 {
+		if !bytes.Equal(data[u.pos:u.pos+8], end) {
+			note("end marker magic %q at %#x restored", data[u.pos+4:u.pos+8], u.pos)
+		}
+	} else if !bytes.Contains(data[u.pos:], end) {
+		note("end marker missing")
+	}
+	if havePH {
+		if ph.UAdler != u.uAdler.Sum32() || ph.CAdler != u.cAdler.Sum32() {
+			note("adler32 mismatch: PackHeader has %#08x/%#08x, the blocks give %#08x/%#08x",
+				ph.UAdler, ph.CAdler, u.uAdler.Sum32(), u.cAdler.Sum32())
+		}
+	}
+	for m := range u.methods {
+		res.Methods = append(res.Methods, m)
+	}
+	for f := range u.filters {
+		res.Filters = append(res.Filters, f)
+	}
+	sort.Strings(res.Methods)
+	sort.Strings(res.Filters)
+
+	res.Data = u.out
+	np, err := NewBytes(res.Data)
+	if err != nil {
+		return nil, err
+	}
+	np.Lenient = p.Lenient
+	if err := np.Parse(); err != nil {
+		return nil, fmt.Errorf("unpacked file: %w", err)
+	}
+	res.Parser = np
+	return res, nil
+}
@@ -4,14 +4,13 @@ go 1.17
 
 require (
 	github.com/saferwall/binstream v0.1.1
-	github.com/saferwall/elf v0.3.0
 	github.com/stretchr/testify v1.7.1
+	golang.org/x/sys v0.0.0-20210531080801-fdfd190a6549
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/edsrzf/mmap-go v1.0.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	golang.org/x/sys v0.0.0-20210531080801-fdfd190a6549 // indirect
-	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b // indirect
 )
@@ -7,48 +7,40 @@ import (
 )
 
 func main() {
-	// 实现ELF解析，类似readelf -a读取的结果
-	p, err := elf.New("gcc-amd64-linux-exec")
-	defer p.CloseFile()
+	// 实现ELF解析，类似readelf -a读取的结果，完整的readelf选项见cmd/goreadelf
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: main <elf-file>")
+		os.Exit(2)
+	}
+	p, err := elf.New(os.Args[1])
 	if err != nil {
 		panic(err)
 	}
+	defer p.CloseFile()
 	// 关键函数，Parse解析，fs binstream.Stream 内容解析填充到结构化 F  *File 中
 	err = p.Parse()
 	if err != nil {
 		panic(err)
 	}
 
-	// hexdump打印所有节内容（节数据区域、非节头、非程序头）
-	for index, sn := range p.F.Sections64 {
-		fmt.Println()
-		fmt.Printf("[ %d ] Name:[%s] Size:[%d] Offset:[0x%x]\n",
-			index,
-			sn.SectionName,
-			sn.Size, sn.Off,
-		)
-		fmt.Printf("%s", sn.HexDumpData())
-	}
-
-	//os.Exit(0)
+	// 节内容按需用goreadelf -x/-p/-R查看，goobjcopy导出原始数据
 	// ELF Header
 	p.DumpHeaderIndent()
 	p.DumpHeaderWithoutIndent()
-	p.DumpSectionHeaders()
+	if err := p.DumpSectionHeaders(); err != nil {
+		panic(err)
+	}
 	p.DumpProgramHeaders()
 	// 各类section挨个安排
 	p.DumpDynamicSection()
 	p.DumpSymbolTable()
-	// 打印可重定位信息
-	p.DumpRelaDynSection()
-	p.DumpRelaPltSection()
-	// 打印 全局偏移表(Global Offset Table)
-	p.DumpGotSection()
-	p.DumpGotPltSection()
-
-
+	// 打印可重定位信息和全局偏移表(Global Offset Table)
+	for _, dump := range []func() error{p.DumpRelaDynSection, p.DumpRelaPltSection, p.DumpGotSection, p.DumpGotPltSection} {
+		if err := dump(); err != nil {
+			panic(err)
+		}
+	}
 
-	os.Exit(0)
 	jsonFile, err := p.DumpJSON()
 	if err != nil {
 		panic(err)